@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/yourusername/trending-sound/internal/bot"
 	"github.com/yourusername/trending-sound/internal/config"
@@ -13,10 +18,11 @@ import (
 	"github.com/yourusername/trending-sound/internal/parser"
 	"github.com/yourusername/trending-sound/internal/scheduler"
 	"github.com/yourusername/trending-sound/internal/storage"
+	"github.com/yourusername/trending-sound/internal/version"
 )
 
 func main() {
-	log.Println("Starting TikTok Trending Sounds Bot...")
+	log.Printf("Starting TikTok Trending Sounds Bot %s...", version.String())
 
 	// 1. Load configuration
 	cfg, err := config.Load()
@@ -31,6 +37,10 @@ func main() {
 		log.Fatalf("Failed to create data directory: %v", err)
 	}
 
+	for from, to := range cfg.HostAliases {
+		storage.HostAliases[strings.ToLower(from)] = strings.ToLower(to)
+	}
+
 	// 3. Initialize database
 	dbPath := filepath.Join(cfg.DataDir, "sounds.db")
 	log.Printf("Initializing database at: %s", dbPath)
@@ -47,25 +57,96 @@ func main() {
 
 	log.Println("Database initialized successfully")
 
+	// Check for corrupted data left by buggy collectors
+	anomalies, err := db.ValidateData()
+	if err != nil {
+		log.Fatalf("Failed to validate data: %v", err)
+	}
+	for _, a := range anomalies {
+		log.Printf("WARNING: data anomaly %s: %d rows", a.Kind, a.Count)
+	}
+	if len(anomalies) > 0 && cfg.RepairOnStart {
+		log.Println("Repairing data (REPAIR_ON_START=true)...")
+		if err := db.RepairData(); err != nil {
+			log.Fatalf("Failed to repair data: %v", err)
+		}
+		log.Println("Data repaired")
+	}
+
 	// 4. Create parser (API-based for MVP)
 	log.Println("Initializing API parser...")
-	apiParser := parser.NewAPIParser()
-	log.Println("API parser initialized (using mock data for MVP)")
+	apiParser := parser.NewAPIParser(cfg.APITimeout)
+	apiParser.SetCategoryQueries(cfg.CategoryQueries)
+
+	// One limiter for every HTTP parser so their requests to a host share a budget
+	hostLimiter := parser.NewHostLimiter(cfg.ParserRateLimit)
+	apiParser.SetHostLimiter(hostLimiter)
+	apiParser.SetMockFallback(cfg.MockFallback)
+	if cfg.MockFallback {
+		log.Println("API parser initialized (mock data when the API returns nothing)")
+	} else {
+		log.Println("API parser initialized")
+	}
+
+	if cfg.ParserDebug {
+		dumpDir := ""
+		if cfg.ParserDumpRaw {
+			dumpDir = filepath.Join(cfg.DataDir, "parser-dumps")
+		}
+		apiParser.EnableDebugLogging(dumpDir)
+		log.Printf("Parser debug logging enabled (dump dir: %q)", dumpDir)
+	}
+
+	var soundParser parser.Parser = apiParser
+	if len(cfg.ParserRoutes) > 0 {
+		soundParser = newRoutingParser(cfg, apiParser)
+	}
+	if cfg.ParserCacheTTL > 0 {
+		log.Printf("Parser cache enabled with TTL %s", cfg.ParserCacheTTL)
+		soundParser = parser.NewCachedParser(soundParser, cfg.ParserCacheTTL)
+	}
 
 	// 5. Create detector
 	log.Println("Initializing trend detector...")
 	trendDetector := detector.New(db)
+	criteria := trendDetector.Criteria()
+	criteria.CrossNiche = cfg.CrossNiche
+	criteria.NewSoundsLast = cfg.NewSoundsLast
+	criteria.RequireSustained = cfg.RequireSustained
+	criteria.SustainedSamples = cfg.SustainedSamples
+	criteria.EWMAGrowth = cfg.EWMAGrowth
+	criteria.EWMAAlpha = cfg.EWMAAlpha
+	criteria.MaxGrowthPercent = cfg.MaxGrowthPercent
+	criteria.Genre = cfg.GenreFilter
+	trendDetector.SetCriteria(criteria)
+	trendDetector.SetCooldown(cfg.DetectCooldown)
+	trendDetector.SetDebugLogging(cfg.LogLevel == "debug")
 
 	// 6. Create Telegram bot
 	log.Println("Initializing Telegram bot...")
-	telegramBot, err := bot.New(cfg.TelegramBotToken, db, trendDetector)
+	telegramBot, err := bot.New(cfg, db, trendDetector)
 	if err != nil {
+		// log.Fatalf skips deferred calls, so the browser has to be closed first
+		soundParser.Close()
 		log.Fatalf("Failed to create Telegram bot: %v", err)
 	}
+	telegramBot.SetHostLimiter(hostLimiter)
+
+	// Tracked alert links record which niche a click came from, then redirect to TikTok
+	if cfg.ClickTrackingURL != "" {
+		clickServer := newClickServer(cfg.ClickTrackingAddr, telegramBot.ClickHandler())
+		go func() {
+			log.Printf("Click tracking listening on %s for %s", cfg.ClickTrackingAddr, cfg.ClickTrackingURL)
+			if err := clickServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("Click tracking server error: %v", err)
+			}
+		}()
+		defer shutdownClickServer(clickServer)
+	}
 
 	// 7. Create and start scheduler
 	log.Println("Initializing scheduler...")
-	sched := scheduler.New(apiParser, db, trendDetector, telegramBot)
+	sched := scheduler.New(cfg, soundParser, db, trendDetector, telegramBot)
 	sched.Start()
 	defer sched.Stop()
 
@@ -77,6 +158,7 @@ func main() {
 	go func() {
 		log.Println("Starting Telegram bot...")
 		if err := telegramBot.Start(); err != nil {
+			soundParser.Close()
 			log.Fatalf("Bot error: %v", err)
 		}
 	}()
@@ -86,7 +168,71 @@ func main() {
 	log.Println("Shutdown signal received, cleaning up...")
 
 	// Cleanup
-	apiParser.Close()
+	soundParser.Close()
 
 	log.Println("Bot stopped successfully")
 }
+
+// clickShutdownTimeout bounds how long shutdown waits for in-flight click redirects
+const clickShutdownTimeout = 5 * time.Second
+
+// newClickServer builds the public click tracking server. Its requests are tiny, so short read
+// timeouts keep slow or idle clients from holding connections open.
+func newClickServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+}
+
+// shutdownClickServer stops the click tracking server, letting in-flight redirects finish
+func shutdownClickServer(server *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), clickShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Error shutting down click tracking server: %v", err)
+	}
+}
+
+// newRoutingParser routes the categories in PARSER_ROUTES to their parser, falling back to the API
+// parser. Without a browser, categories routed to rod stay on the API parser.
+func newRoutingParser(cfg *config.Config, apiParser *parser.APIParser) parser.Parser {
+	parsers := map[string]parser.Parser{parser.SourceAPI: apiParser}
+	routes := make(map[string]string, len(cfg.ParserRoutes))
+	for category, name := range cfg.ParserRoutes {
+		routes[category] = name
+	}
+
+	needsRod := false
+	for _, name := range routes {
+		needsRod = needsRod || name == parser.SourceRod
+	}
+	if needsRod {
+		rodParser, err := parser.NewRodParser(cfg.RodMaxPages, cfg.RodTimeout)
+		if err != nil {
+			log.Printf("WARNING: rod parser unavailable, its categories use the API parser: %v", err)
+			for category, name := range routes {
+				if name == parser.SourceRod {
+					delete(routes, category)
+				}
+			}
+		} else {
+			rodParser.StartHealthCheck(parser.HealthCheckInterval)
+			parsers[parser.SourceRod] = rodParser
+		}
+	}
+
+	router, err := parser.NewRoutingParser(apiParser, parsers, routes)
+	if err != nil {
+		if rodParser, ok := parsers[parser.SourceRod]; ok {
+			rodParser.Close()
+		}
+		log.Fatalf("Failed to create parser routes: %v", err)
+	}
+	log.Printf("Parser routes: %v", routes)
+	return router
+}
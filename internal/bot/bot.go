@@ -2,10 +2,19 @@ package bot
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+	"unicode/utf8"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/trending-sound/internal/card"
+	"github.com/yourusername/trending-sound/internal/config"
 	"github.com/yourusername/trending-sound/internal/detector"
 	"github.com/yourusername/trending-sound/internal/parser"
 	"github.com/yourusername/trending-sound/internal/storage"
@@ -13,33 +22,78 @@ import (
 
 // Bot represents the Telegram bot
 type Bot struct {
-	api      *tgbotapi.BotAPI
+	api      *limitedAPI
+	cfg      *config.Config
 	storage  storage.Storage
 	detector *detector.TrendDetector
+	theme    theme
+	card     card.Layout
+	tiers    []growthTier // Growth tiers for /format tiered, highest first
+	features featureCache
+
+	detectFailures atomic.Int64 // Consecutive /trending detection failures, reset on success
+	hostLimiter    *parser.HostLimiter
+}
+
+// TelegramAPI is the part of the Telegram Bot API the bot calls
+type TelegramAPI interface {
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+	GetUpdates(config tgbotapi.UpdateConfig) ([]tgbotapi.Update, error)
 }
 
 // New creates a new Telegram bot instance
-func New(token string, s storage.Storage, d *detector.TrendDetector) (*Bot, error) {
-	api, err := tgbotapi.NewBotAPI(token)
+func New(cfg *config.Config, s storage.Storage, d *detector.TrendDetector) (*Bot, error) {
+	api, err := tgbotapi.NewBotAPI(cfg.TelegramBotToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create bot: %w", err)
 	}
 
 	log.Printf("Authorized on account %s", api.Self.UserName)
 
+	return NewWithAPI(cfg, s, d, api)
+}
+
+// NewWithAPI creates a bot that talks to Telegram through the given API
+func NewWithAPI(cfg *config.Config, s storage.Storage, d *detector.TrendDetector, api TelegramAPI) (*Bot, error) {
+	cardLayout := card.DefaultLayout()
+	cardLayout.FontPath = cfg.CardFontPath
+	if cfg.CardLineTemplate != "" {
+		if _, err := template.New("line").Parse(cfg.CardLineTemplate); err != nil {
+			return nil, fmt.Errorf("invalid CARD_LINE_TEMPLATE: %w", err)
+		}
+		cardLayout.LineTemplate = cfg.CardLineTemplate
+	}
+
+	tiers, err := newGrowthTiers(cfg.GrowthTiers)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Bot{
-		api:      api,
+		api:      &limitedAPI{TelegramAPI: api, limiter: newSendLimiter(cfg.SendRateLimit, cfg.SendRateWindow)},
+		cfg:      cfg,
 		storage:  s,
 		detector: d,
+		theme:    newTheme(cfg.MessageTheme),
+		card:     cardLayout,
+		tiers:    tiers,
 	}, nil
 }
 
+// SetHostLimiter gives the bot the parsers' shared rate limiter so /health can report it
+func (b *Bot) SetHostLimiter(limiter *parser.HostLimiter) {
+	b.hostLimiter = limiter
+}
+
 // Start starts the bot and begins listening for updates
 func (b *Bot) Start() error {
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+	// Keep Telegram's command menu in sync with the registry
+	if _, err := b.api.Request(tgbotapi.NewSetMyCommands(botMenuCommands()...)); err != nil {
+		log.Printf("Error setting bot commands: %v", err)
+	}
 
-	updates := b.api.GetUpdatesChan(u)
+	updates := b.pollUpdates()
 
 	log.Println("Bot started, listening for updates...")
 
@@ -48,6 +102,10 @@ func (b *Bot) Start() error {
 			b.handleMessage(update.Message)
 		} else if update.CallbackQuery != nil {
 			b.handleCallbackQuery(update.CallbackQuery)
+		} else if update.InlineQuery != nil {
+			b.handleInlineQuery(update.InlineQuery)
+		} else if update.PreCheckoutQuery != nil {
+			b.handlePreCheckoutQuery(update.PreCheckoutQuery)
 		}
 	}
 
@@ -56,26 +114,64 @@ func (b *Bot) Start() error {
 
 // handleMessage handles incoming messages
 func (b *Bot) handleMessage(message *tgbotapi.Message) {
+	// Payments are still recorded so a blocked user's money is never lost
+	if message.SuccessfulPayment != nil {
+		b.handleSuccessfulPayment(message)
+		return
+	}
+
+	if message.From != nil && b.isBlocked(message.From.ID) {
+		return
+	}
+
+	// Plain messages may be the next step of a multi-step command
 	if !message.IsCommand() {
+		b.handleConversation(message)
 		return
 	}
 
 	log.Printf("[%s] %s", message.From.UserName, message.Text)
 
-	switch message.Command() {
-	case "start":
-		b.handleStart(message)
-	case "niches":
-		b.handleNiches(message)
-	case "trending":
-		b.handleTrending(message)
-	case "premium":
-		b.handlePremium(message)
-	case "stats":
-		b.handleStats(message)
-	default:
-		msg := tgbotapi.NewMessage(message.Chat.ID, "Unknown command. Available commands: /start, /niches, /trending, /premium")
-		b.api.Send(msg)
+	b.dispatchCommand(message)
+}
+
+// isBlocked checks whether an admin blocked the Telegram user. Admins are never blocked.
+func (b *Bot) isBlocked(telegramID int64) bool {
+	if b.isAdmin(telegramID) {
+		return false
+	}
+	user, err := b.storage.GetUser(telegramID)
+	if err != nil {
+		return false
+	}
+	return user.Blocked
+}
+
+// isAdmin checks whether the Telegram user is configured as an admin
+func (b *Bot) isAdmin(telegramID int64) bool {
+	for _, id := range b.cfg.AdminIDs {
+		if id == telegramID {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyAdmins sends a plain-text message to every configured admin
+func (b *Bot) notifyAdmins(text string) {
+	for _, id := range b.cfg.AdminIDs {
+		if _, err := b.api.Send(tgbotapi.NewMessage(id, text)); err != nil {
+			log.Printf("Error notifying admin %d: %v", id, err)
+		}
+	}
+}
+
+// recordDetectFailure counts a failed detection and alerts admins once failures
+// reach DetectFailureAlertThreshold in a row
+func (b *Bot) recordDetectFailure(niche string, err error) {
+	failures := b.detectFailures.Add(1)
+	if threshold := int64(b.cfg.DetectFailureAlertThreshold); threshold > 0 && failures == threshold {
+		b.notifyAdmins(fmt.Sprintf("🚨 Trend detection failed %d times in a row. Last error (%s): %v", failures, niche, err))
 	}
 }
 
@@ -85,41 +181,435 @@ func (b *Bot) SendTrendingAlert(telegramID int64, category string, sounds []stor
 		return nil
 	}
 
-	message := formatTrendingMessage(category, sounds)
+	// Without the user's settings the alert falls back to the default order and format
+	user, _ := b.storage.GetUser(telegramID)
+	sounds, format := b.alertLayout(user, sounds)
 
-	msg := tgbotapi.NewMessage(telegramID, message)
-	msg.ParseMode = "Markdown"
+	// Share buttons keep the plain links: a forwarded tracked link would count as this user's click
+	message := format(nicheDisplayName(category, b.nicheLabels(telegramID)), b.trackedSounds(telegramID, category, sounds), b.theme)
+	message += b.freshnessLine(category)
+	keyboard := shareKeyboard(sounds, b.theme)
 
-	_, err := b.api.Send(msg)
-	return err
+	if err := b.sendLongMessageWithKeyboard(telegramID, message, "Markdown", &keyboard); err != nil {
+		return err
+	}
+
+	// Keep the rendered text so /last can resend it without re-detecting
+	if err := b.storage.SaveLastAlert(telegramID, category, message); err != nil {
+		log.Printf("Error saving last alert for %d: %v", telegramID, err)
+	}
+	return nil
 }
 
-// formatTrendingMessage formats trending sounds into a message
-func formatTrendingMessage(category string, sounds []storage.TrendingSound) string {
-	categoryName := parser.CategoryDisplayNames[category]
-	if categoryName == "" {
-		categoryName = category
+// PostChannelTrends posts trending sounds to a public channel in the default alert layout
+func (b *Bot) PostChannelTrends(channelID int64, category string, sounds []storage.TrendingSound) error {
+	if len(sounds) == 0 {
+		return nil
 	}
 
-	message := fmt.Sprintf("🔥 *Trending Sounds - %s*\n\n", categoryName)
+	message := formatTrendingMessage(nicheDisplayName(category, nil), sounds, b.theme)
+	message += b.freshnessLine(category)
+	keyboard := shareKeyboard(sounds, b.theme)
 
-	for i, ts := range sounds {
-		message += fmt.Sprintf("*%d. \"%s\"*", i+1, ts.Title)
-		if ts.Author != "" {
-			message += fmt.Sprintf(" by %s", ts.Author)
+	return b.sendLongMessageWithKeyboard(channelID, message, "Markdown", &keyboard)
+}
+
+// alertLayout returns sounds in the order the user sees them and the formatter for their alert format.
+// Detection stays growth-first; the user's preferred order and format only apply to what they see.
+// A nil user gets the defaults.
+func (b *Bot) alertLayout(user *storage.User, sounds []storage.TrendingSound) ([]storage.TrendingSound, func(string, []storage.TrendingSound, theme) string) {
+	if user == nil {
+		return sounds, formatTrendingMessage
+	}
+
+	sounds = sortTrendingSounds(sounds, user.AlertSort)
+	switch user.AlertFormat {
+	case storage.AlertFormatCompact:
+		return sounds, formatTrendingMessageCompact
+	case storage.AlertFormatTiered:
+		// Share buttons are numbered by position, so order sounds the way the tiers list them
+		return orderByTier(sounds, b.tiers), func(categoryName string, sounds []storage.TrendingSound, t theme) string {
+			return formatTrendingMessageTiered(categoryName, sounds, t, b.tiers)
+		}
+	}
+	return sounds, formatTrendingMessage
+}
+
+// SendDigest sends a user's coalesced alerts as one message with a section per niche, in the given
+// niche order. Niches without sounds are skipped; nothing is sent when all are empty.
+func (b *Bot) SendDigest(user *storage.User, niches []string, digest map[string][]storage.TrendingSound) error {
+	labels := b.nicheLabels(user.TelegramID)
+
+	var sections []string
+	for _, niche := range niches {
+		if len(digest[niche]) == 0 {
+			continue
+		}
+		sounds, format := b.alertLayout(user, digest[niche])
+		sounds = b.trackedSounds(user.TelegramID, niche, sounds)
+		sections = append(sections, format(nicheDisplayName(niche, labels), sounds, b.theme))
+	}
+	if len(sections) == 0 {
+		return nil
+	}
+
+	period := "daily"
+	if user.DigestFrequency == storage.DigestTwiceDaily {
+		period = "twice-daily"
+	}
+	message := fmt.Sprintf("📬 *Your %s digest*\n\n", period) + strings.Join(sections, "\n")
+
+	// No share buttons: they are numbered per niche, which is ambiguous across sections
+	return b.sendLongMessage(user.TelegramID, message, "Markdown")
+}
+
+// sendLongMessage sends a message, splitting it into several if it exceeds Telegram's limit
+func (b *Bot) sendLongMessage(chatID int64, text string, parseMode string) error {
+	return b.sendLongMessageWithKeyboard(chatID, text, parseMode, nil)
+}
+
+// sendLongMessageWithKeyboard is sendLongMessage with an inline keyboard attached to the last part
+func (b *Bot) sendLongMessageWithKeyboard(chatID int64, text string, parseMode string, keyboard *tgbotapi.InlineKeyboardMarkup) error {
+	parts := splitMessage(text, maxMessageLength)
+	for i, part := range parts {
+		msg := tgbotapi.NewMessage(chatID, part)
+		msg.ParseMode = parseMode
+		msg.DisableWebPagePreview = b.hasTrackedLink(part)
+		if keyboard != nil && i == len(parts)-1 {
+			msg.ReplyMarkup = *keyboard
+		}
+		if _, err := b.api.Send(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// editKeyboard replaces a message's inline keyboard. Telegram rejects edits that leave the markup
+// unchanged (e.g. a double-tapped button); those are treated as success.
+func (b *Bot) editKeyboard(edit tgbotapi.EditMessageReplyMarkupConfig) {
+	if _, err := b.api.Send(edit); err != nil && !isNotModified(err) {
+		log.Printf("Error editing keyboard in chat %d: %v", edit.ChatID, err)
+	}
+}
+
+// isNotModified reports whether err is Telegram's "message is not modified" response to an edit
+func isNotModified(err error) bool {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return strings.Contains(apiErr.Message, "message is not modified")
+}
+
+// maxMessageLength is Telegram's maximum message length in characters
+const maxMessageLength = 4096
+
+// splitMessage breaks text into chunks of at most limit characters.
+// Chunks are split at blank lines (sound boundaries) first, then at line breaks,
+// so Markdown links are never cut in half.
+func splitMessage(text string, limit int) []string {
+	if utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+
+	var chunks []string
+	var current string
+
+	flush := func() {
+		if strings.TrimSpace(current) != "" {
+			chunks = append(chunks, strings.TrimRight(current, "\n"))
+		}
+		current = ""
+	}
+
+	for _, block := range strings.SplitAfter(text, "\n\n") {
+		if utf8.RuneCountInString(current)+utf8.RuneCountInString(block) <= limit {
+			current += block
+			continue
+		}
+		flush()
+
+		if utf8.RuneCountInString(block) <= limit {
+			current = block
+			continue
+		}
+
+		// A single block is too long - fall back to splitting by lines
+		for _, line := range strings.SplitAfter(block, "\n") {
+			if utf8.RuneCountInString(current)+utf8.RuneCountInString(line) > limit {
+				flush()
+			}
+			for utf8.RuneCountInString(line) > limit {
+				runes := []rune(line)
+				cut := lineCut(runes, limit)
+				chunks = append(chunks, string(runes[:cut]))
+				line = string(runes[cut:])
+			}
+			current += line
+		}
+	}
+	flush()
+
+	return chunks
+}
+
+// lineCut returns where to cut a line longer than limit: just after the last space or closing
+// parenthesis that is outside a Markdown link, or at limit when there is no such place
+func lineCut(runes []rune, limit int) int {
+	const (
+		outside = iota
+		linkText
+		linkURL
+	)
+
+	cut := 0
+	state := outside
+	for i := 0; i < limit; i++ {
+		switch r := runes[i]; {
+		case r == '[' && state == outside:
+			state = linkText
+		case r == ']' && state == linkText:
+			state = outside
+			if i+1 < len(runes) && runes[i+1] == '(' {
+				state = linkURL
+			}
+		case r == ')' && state == linkURL:
+			state = outside
+			cut = i + 1
+		case (r == ' ' || r == ')') && state == outside:
+			cut = i + 1
 		}
-		message += "\n"
-		message += fmt.Sprintf("   📊 Uses: %s", formatNumber(ts.UsesCount))
-		if ts.GrowthPercent > 0 {
+	}
+
+	if cut == 0 {
+		return limit
+	}
+	return cut
+}
+
+// sortTrendingSounds returns sounds in the given alert sort order without modifying the input.
+// AlertSortGrowth and unknown orders keep the detector's order.
+func sortTrendingSounds(sounds []storage.TrendingSound, order string) []storage.TrendingSound {
+	var less func(a, b storage.TrendingSound) bool
+	switch order {
+	case storage.AlertSortUses:
+		less = func(a, b storage.TrendingSound) bool { return a.UsesCount > b.UsesCount }
+	case storage.AlertSortRecent:
+		less = func(a, b storage.TrendingSound) bool { return a.CreatedAt.After(b.CreatedAt) }
+	default:
+		return sounds
+	}
+
+	sorted := make([]storage.TrendingSound, len(sounds))
+	copy(sorted, sounds)
+	sort.SliceStable(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+	return sorted
+}
+
+// formatTrendingMessage formats trending sounds into a message under the given niche name
+func formatTrendingMessage(categoryName string, sounds []storage.TrendingSound, t theme) string {
+	message := fmt.Sprintf("%s *Trending Sounds - %s*\n\n", t[ThemeTitle], categoryName)
+
+	for i, ts := range sounds {
+		message += formatSoundEntry(i+1, ts, categoryName, t)
+	}
+
+	return message
+}
+
+// formatSoundEntry renders one sound of a detailed alert under the given number
+func formatSoundEntry(number int, ts storage.TrendingSound, categoryName string, t theme) string {
+	message := fmt.Sprintf("*%d. \"%s\"*", number, ts.Title)
+	if ts.Author != "" {
+		message += fmt.Sprintf(" by %s", ts.Author)
+	}
+	if ts.CrossNiche {
+		message += fmt.Sprintf(" %s Cross-niche", t[ThemeCrossNiche])
+	}
+	if ts.SeasonalPeriodDays > 0 {
+		message += fmt.Sprintf(" %s Spikes every %dd", t[ThemeSeasonal], ts.SeasonalPeriodDays)
+	}
+	message += "\n"
+	message += fmt.Sprintf("   %s Uses: %s", t[ThemeUses], formatNumber(ts.UsesCount))
+	if ts.IsNew {
+		message += fmt.Sprintf(" %s NEW", t[ThemeNew])
+	} else if ts.GrowthPercent > 0 {
+		message += fmt.Sprintf(" (+%.0f%%)", ts.GrowthPercent)
+	}
+	if ts.Rank > 0 {
+		message += fmt.Sprintf(" · #%d in %s", ts.Rank, categoryName)
+	}
+	message += "\n"
+	if meta := formatSoundMetadata(ts.Sound); meta != "" {
+		message += fmt.Sprintf("   %s %s\n", t[ThemeGenre], meta)
+	}
+	message += fmt.Sprintf("   %s [Listen](%s) · %s %d\n\n", t[ThemeLink], ts.URL, t[ThemeID], ts.ID)
+
+	return message
+}
+
+// gatheringDataMessage returns a "still gathering data" notice when a category has too little data
+// to tell whether anything is trending, or "" when there is enough. Fewer than MinDataPoints sounds,
+// or no sound with two history samples to compare, counts as too little.
+func (b *Bot) gatheringDataMessage(category string) string {
+	stats, err := b.storage.GetCategoryDataStats(category)
+	if err != nil {
+		log.Printf("Error getting data stats for %s: %v", category, err)
+		return ""
+	}
+	if stats.Sounds >= b.cfg.MinDataPoints && stats.Comparable > 0 {
+		return ""
+	}
+	return fmt.Sprintf("⏳ Still gathering data for %s. Trends show up after a few collections - check back soon!", parser.CategoryDisplayNames[category])
+}
+
+// freshnessLine tells how old a category's data is, or "" if that can't be determined
+func (b *Bot) freshnessLine(category string) string {
+	updatedAt, err := b.storage.GetCategoryLastUpdated(category)
+	if err != nil {
+		log.Printf("Error getting last update for %s: %v", category, err)
+		return ""
+	}
+	if updatedAt.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("🕒 _Data as of %s_\n", humanizeAge(time.Since(updatedAt)))
+}
+
+// humanizeAge renders how long ago something happened, e.g. "just now", "25m ago", "3h ago", "2d ago"
+func humanizeAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d/time.Hour))
+	default:
+		return fmt.Sprintf("%dd ago", int(d/(24*time.Hour)))
+	}
+}
+
+// formatTrendingMessageCompact formats trending sounds one line each, for users who prefer /format compact.
+// Each sound stays on its own line so splitMessage never cuts one in half.
+func formatTrendingMessageCompact(categoryName string, sounds []storage.TrendingSound, t theme) string {
+	message := fmt.Sprintf("%s *Trending Sounds - %s*\n\n", t[ThemeTitle], categoryName)
+
+	for i, ts := range sounds {
+		message += fmt.Sprintf("%d. [%s](%s) · %s", i+1, compactTitle(ts.Title), ts.URL, formatNumber(ts.UsesCount))
+		if ts.IsNew {
+			message += " " + t[ThemeNew]
+		} else if ts.GrowthPercent > 0 {
 			message += fmt.Sprintf(" (+%.0f%%)", ts.GrowthPercent)
 		}
-		message += "\n"
-		message += fmt.Sprintf("   🔗 [Listen](%s)\n\n", ts.URL)
+		if ts.CrossNiche {
+			message += " " + t[ThemeCrossNiche]
+		}
+		if ts.SeasonalPeriodDays > 0 {
+			message += " " + t[ThemeSeasonal]
+		}
+		message += fmt.Sprintf(" · %s %d\n", t[ThemeID], ts.ID)
 	}
 
 	return message
 }
 
+// maxCompactTitleLength caps titles in compact alerts so each sound fits on one line
+const maxCompactTitleLength = 40
+
+// compactTitle shortens a title for compact alerts and drops characters that would end the Markdown link text early
+func compactTitle(title string) string {
+	title = strings.NewReplacer("[", "(", "]", ")", "\n", " ").Replace(title)
+	if utf8.RuneCountInString(title) > maxCompactTitleLength {
+		title = string([]rune(title)[:maxCompactTitleLength-1]) + "…"
+	}
+	return title
+}
+
+// formatSoundMetadata renders a sound's genre and duration like "Pop · 0:30", skipping unknown parts
+func formatSoundMetadata(sound storage.Sound) string {
+	var parts []string
+	if sound.Genre != "" {
+		parts = append(parts, sound.Genre)
+	}
+	if sound.Duration > 0 {
+		parts = append(parts, fmt.Sprintf("%d:%02d", sound.Duration/60, sound.Duration%60))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// nicheDisplayName returns the user's label for a niche, falling back to its default display name
+func nicheDisplayName(category string, labels map[string]string) string {
+	if label := labels[category]; label != "" {
+		return label
+	}
+	if name := parser.CategoryDisplayNames[category]; name != "" {
+		return name
+	}
+	return category
+}
+
+// nicheLabels returns a user's custom niche names, or nil if they can't be loaded
+func (b *Bot) nicheLabels(telegramID int64) map[string]string {
+	labels, err := b.storage.GetNicheLabels(telegramID)
+	if err != nil {
+		log.Printf("Error getting niche labels for %d: %v", telegramID, err)
+		return nil
+	}
+	return labels
+}
+
+// AlertNiches returns the user's niches that are not muted. If mutes can't be loaded, all niches are returned.
+func (b *Bot) AlertNiches(user *storage.User) []string {
+	niches := GetUserNiches(user)
+	muted, err := b.storage.GetMutedNiches(user.TelegramID)
+	if err != nil {
+		log.Printf("Error getting muted niches for %d: %v", user.TelegramID, err)
+		return niches
+	}
+
+	var active []string
+	for _, niche := range niches {
+		if !muted[niche] {
+			active = append(active, niche)
+		}
+	}
+	return active
+}
+
+// shareQueryPrefix starts the inline query a Share button fills in, followed by the sound ID
+const shareQueryPrefix = "share "
+
+// shareKeyboard builds one Share button per sound, opening a chat picker with the sound's inline query
+func shareKeyboard(sounds []storage.TrendingSound, t theme) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, ts := range sounds {
+		button := tgbotapi.NewInlineKeyboardButtonSwitch(
+			fmt.Sprintf("%s Share #%d", t[ThemeShare], i+1),
+			fmt.Sprintf("%s%d", shareQueryPrefix, ts.ID),
+		)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// formatShareText formats a single sound for forwarding to another chat
+func formatShareText(sound storage.Sound, t theme) string {
+	return formatTrendingMessage(nicheDisplayName(sound.Category, nil), []storage.TrendingSound{{Sound: sound}}, t)
+}
+
+// SendMilestoneAlert notifies a user that a followed sound crossed a uses milestone
+func (b *Bot) SendMilestoneAlert(telegramID int64, sound storage.Sound, milestone int64) error {
+	text := fmt.Sprintf("%s *Milestone reached!*\n\n\"%s\" just passed %s uses (now %s).\n%s [Listen](%s)",
+		b.theme[ThemeMilestone], sound.Title, formatNumber(milestone), formatNumber(sound.UsesCount), b.theme[ThemeLink], sound.URL)
+
+	msg := tgbotapi.NewMessage(telegramID, text)
+	msg.ParseMode = "Markdown"
+	_, err := b.api.Send(msg)
+	return err
+}
+
 // formatNumber formats a number with K/M/B suffixes
 func formatNumber(n int64) string {
 	if n >= 1000000000 {
@@ -134,13 +624,101 @@ func formatNumber(n int64) string {
 	return fmt.Sprintf("%d", n)
 }
 
-// GetUserNiches returns the user's selected niches as a slice
+// Bounds for the premium /limit override
+const (
+	MinAlertLimit = 3
+	MaxAlertLimit = 20
+)
+
+// Bounds for the premium /threshold override, in percent
+const (
+	MinGrowthThreshold = 10.0
+	MaxGrowthThreshold = 1000.0
+)
+
+// MinGrowthFor returns the minimum growth used for a user's alerts in one niche:
+// the niche override if set, else the user's global threshold, else defaultGrowth.
+func MinGrowthFor(user *storage.User, nicheOverride, defaultGrowth float64) float64 {
+	if nicheOverride > 0 {
+		return nicheOverride
+	}
+	if user.MinGrowth > 0 {
+		return user.MinGrowth
+	}
+	return defaultGrowth
+}
+
+// Boosted returns whether the user's /boost is active at now
+func Boosted(user *storage.User, now time.Time) bool {
+	return now.Before(user.BoostUntil)
+}
+
+// BoostedMinGrowth lowers minGrowth by factor while the user's /boost is active, never below
+// MinGrowthThreshold. Once the boost ends it returns minGrowth unchanged, so there is nothing to revert.
+func BoostedMinGrowth(user *storage.User, minGrowth, factor float64, now time.Time) float64 {
+	if !Boosted(user, now) {
+		return minGrowth
+	}
+	if boosted := minGrowth * factor; boosted > MinGrowthThreshold {
+		return boosted
+	}
+	return MinGrowthThreshold
+}
+
+// MinUsesFor returns the minimum uses for a user's alerts: their /minuses floor clamped to
+// [defaultUses, maxUses], or defaultUses if unset. A floor above maxUses would rule out every sound.
+func MinUsesFor(user *storage.User, defaultUses, maxUses int64) int64 {
+	if user.MinUses <= defaultUses {
+		return defaultUses
+	}
+	if user.MinUses > maxUses {
+		return maxUses
+	}
+	return user.MinUses
+}
+
+// ResultLimit returns how many sounds the user sees per niche, set per tier by
+// FREE_RESULT_LIMIT and PREMIUM_RESULT_LIMIT. A premium user's /limit override wins over the tier default.
+func (b *Bot) ResultLimit(user *storage.User) int {
+	if user.IsPremium {
+		if user.AlertLimit > 0 {
+			return clampAlertLimit(user.AlertLimit)
+		}
+		return b.cfg.PremiumResultLimit
+	}
+	return b.cfg.FreeResultLimit
+}
+
+// clampAlertLimit keeps an alert limit within MinAlertLimit..MaxAlertLimit
+func clampAlertLimit(limit int) int {
+	if limit < MinAlertLimit {
+		return MinAlertLimit
+	}
+	if limit > MaxAlertLimit {
+		return MaxAlertLimit
+	}
+	return limit
+}
+
+// GetUserNiches returns the user's selected niches as a slice.
+// Unknown niches left over from stale data are dropped.
 func GetUserNiches(user *storage.User) []string {
 	var niches []string
 	if user.Niches != "" {
 		json.Unmarshal([]byte(user.Niches), &niches)
 	}
-	return niches
+	return FilterValidNiches(niches)
+}
+
+// FilterValidNiches removes niches that are not supported categories
+func FilterValidNiches(niches []string) []string {
+	var valid []string
+	for _, niche := range niches {
+		if parser.IsValidCategory(niche) {
+			valid = append(valid, niche)
+		}
+	}
+	return valid
 }
 
 // SetUserNiches sets the user's niches from a slice
@@ -0,0 +1,801 @@
+package bot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/trending-sound/internal/config"
+	"github.com/yourusername/trending-sound/internal/detector"
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+func TestMain(m *testing.M) {
+	// Storage reads migrations/init.sql relative to the repository root
+	if err := os.Chdir("../.."); err != nil {
+		panic(err)
+	}
+	os.Setenv("TELEGRAM_BOT_TOKEN", "test-token")
+	os.Exit(m.Run())
+}
+
+// fakeAPI records everything the bot sends instead of calling Telegram
+type fakeAPI struct {
+	mu       sync.Mutex
+	sent     []tgbotapi.Chattable
+	requests []tgbotapi.Chattable
+	sendErr  error // Returned by Send after recording the message
+}
+
+func (f *fakeAPI) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, c)
+	if f.sendErr != nil {
+		return tgbotapi.Message{}, f.sendErr
+	}
+	return tgbotapi.Message{MessageID: len(f.sent)}, nil
+}
+
+func (f *fakeAPI) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, c)
+	return &tgbotapi.APIResponse{Ok: true}, nil
+}
+
+func (f *fakeAPI) GetUpdates(tgbotapi.UpdateConfig) ([]tgbotapi.Update, error) {
+	return nil, nil
+}
+
+// texts returns the text of every message sent so far
+func (f *fakeAPI) texts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []string
+	for _, c := range f.sent {
+		switch m := c.(type) {
+		case tgbotapi.MessageConfig:
+			out = append(out, m.Text)
+		case tgbotapi.EditMessageTextConfig:
+			out = append(out, m.Text)
+		}
+	}
+	return out
+}
+
+// newTestConfig loads the default configuration with any overrides set in the environment
+func newTestConfig(t *testing.T, env map[string]string) *config.Config {
+	t.Helper()
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load() error: %v", err)
+	}
+	return cfg
+}
+
+// newTestStorage opens a fresh in-memory database with the full schema
+func newTestStorage(t *testing.T) *storage.SQLiteStorage {
+	t.Helper()
+	s, err := storage.NewSQLiteStorage(storage.MemoryPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() error: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if err := s.Init(); err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+	return s
+}
+
+// newTestBot builds a bot backed by in-memory storage and a fake Telegram API
+func newTestBot(t *testing.T, cfg *config.Config) (*Bot, *storage.SQLiteStorage, *fakeAPI) {
+	t.Helper()
+	if cfg == nil {
+		cfg = newTestConfig(t, nil)
+	}
+	s := newTestStorage(t)
+	api := &fakeAPI{}
+	b, err := NewWithAPI(cfg, s, detector.New(s), api)
+	if err != nil {
+		t.Fatalf("NewWithAPI() error: %v", err)
+	}
+	return b, s, api
+}
+
+// commandMessage builds a message carrying a bot command from the given user
+func commandMessage(from int64, text string) *tgbotapi.Message {
+	command, _, _ := strings.Cut(text, " ")
+	return &tgbotapi.Message{
+		From:     &tgbotapi.User{ID: from},
+		Chat:     &tgbotapi.Chat{ID: from},
+		Text:     text,
+		Entities: []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}},
+	}
+}
+
+// addUser registers a user following the given niches
+func addUser(t *testing.T, s *storage.SQLiteStorage, telegramID int64, niches ...string) {
+	t.Helper()
+	if err := s.CreateUser(telegramID); err != nil {
+		t.Fatalf("CreateUser() error: %v", err)
+	}
+	if err := s.UpdateUserNiches(telegramID, SetUserNiches(niches)); err != nil {
+		t.Fatalf("UpdateUserNiches() error: %v", err)
+	}
+}
+
+// seedTrending saves a sound in category that grew from 1,000 to 5,000 uses since 23 hours ago
+func seedTrending(t *testing.T, s *storage.SQLiteStorage, category, title string) *storage.Sound {
+	t.Helper()
+	now := time.Now()
+	sound := &storage.Sound{
+		Title:     title,
+		Author:    "author",
+		URL:       "https://www.tiktok.com/music/" + strings.ReplaceAll(title, " ", "-"),
+		UsesCount: 1000,
+		Category:  category,
+		CreatedAt: now.Add(-23 * time.Hour),
+		UpdatedAt: now.Add(-23 * time.Hour),
+	}
+	if err := s.SaveSound(sound); err != nil {
+		t.Fatalf("SaveSound() error: %v", err)
+	}
+	if _, err := s.SeedBaselineHistory(now.Add(-23 * time.Hour)); err != nil {
+		t.Fatalf("SeedBaselineHistory() error: %v", err)
+	}
+
+	sound.UsesCount = 5000
+	sound.UpdatedAt = now
+	if err := s.UpdateSound(sound); err != nil {
+		t.Fatalf("UpdateSound() error: %v", err)
+	}
+	if err := s.SaveSoundHistory(sound.ID, sound.UsesCount, ""); err != nil {
+		t.Fatalf("SaveSoundHistory() error: %v", err)
+	}
+	return sound
+}
+
+func TestResultLimit(t *testing.T) {
+	cfg := newTestConfig(t, map[string]string{"FREE_RESULT_LIMIT": "3", "PREMIUM_RESULT_LIMIT": "10"})
+	b, _, _ := newTestBot(t, cfg)
+
+	tests := []struct {
+		name string
+		user storage.User
+		want int
+	}{
+		{name: "free", user: storage.User{}, want: 3},
+		{name: "free override is ignored", user: storage.User{AlertLimit: 15}, want: 3},
+		{name: "premium", user: storage.User{IsPremium: true}, want: 10},
+		{name: "premium override", user: storage.User{IsPremium: true, AlertLimit: 15}, want: 15},
+		{name: "override clamped low", user: storage.User{IsPremium: true, AlertLimit: 1}, want: MinAlertLimit},
+		{name: "override clamped high", user: storage.User{IsPremium: true, AlertLimit: 99}, want: MaxAlertLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.ResultLimit(&tt.user); got != tt.want {
+				t.Errorf("ResultLimit() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResultLimitDefaults(t *testing.T) {
+	b, _, _ := newTestBot(t, nil)
+	for _, user := range []storage.User{{}, {IsPremium: true}} {
+		if got := b.ResultLimit(&user); got != 5 {
+			t.Errorf("ResultLimit(premium=%v) = %d, want the default of 5", user.IsPremium, got)
+		}
+	}
+}
+
+func TestMinGrowthFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     storage.User
+		override float64
+		want     float64
+	}{
+		{name: "default", want: 150},
+		{name: "global threshold", user: storage.User{MinGrowth: 300}, want: 300},
+		{name: "niche override wins", user: storage.User{MinGrowth: 300}, override: 80, want: 80},
+		{name: "niche override without global", override: 500, want: 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MinGrowthFor(&tt.user, tt.override, 150); got != tt.want {
+				t.Errorf("MinGrowthFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMinUsesFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		minUses int64
+		want    int64
+	}{
+		{name: "unset", want: 500},
+		{name: "below default", minUses: 100, want: 500},
+		{name: "in range", minUses: 5000, want: 5000},
+		{name: "above max is clamped", minUses: 50000, want: 30000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MinUsesFor(&storage.User{MinUses: tt.minUses}, 500, 30000); got != tt.want {
+				t.Errorf("MinUsesFor() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBoostedMinGrowth(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		boostUntil time.Time
+		minGrowth  float64
+		want       float64
+	}{
+		{name: "never boosted", minGrowth: 150, want: 150},
+		{name: "boost ended", boostUntil: now.Add(-time.Minute), minGrowth: 150, want: 150},
+		{name: "boost active", boostUntil: now.Add(time.Hour), minGrowth: 150, want: 75},
+		{name: "floored at the minimum threshold", boostUntil: now.Add(time.Hour), minGrowth: 12, want: MinGrowthThreshold},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &storage.User{BoostUntil: tt.boostUntil}
+			if got := BoostedMinGrowth(user, tt.minGrowth, 0.5, now); got != tt.want {
+				t.Errorf("BoostedMinGrowth() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatSoundEntryNew(t *testing.T) {
+	tests := []struct {
+		name  string
+		ts    storage.TrendingSound
+		want  string
+		avoid string
+	}{
+		{name: "new sound", ts: storage.TrendingSound{IsNew: true}, want: "🆕 NEW", avoid: "%)"},
+		{name: "growing sound", ts: storage.TrendingSound{GrowthPercent: 250}, want: "(+250%)", avoid: "NEW"},
+		{name: "no growth", ts: storage.TrendingSound{}, avoid: "(+"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.ts.Sound = storage.Sound{ID: 1, Title: "gym anthem", UsesCount: 800, URL: "https://www.tiktok.com/music/x-1"}
+			got := formatSoundEntry(1, tt.ts, "Fitness", newTheme(nil))
+			if tt.want != "" && !strings.Contains(got, tt.want) {
+				t.Errorf("formatSoundEntry() missing %q in:\n%s", tt.want, got)
+			}
+			if tt.avoid != "" && strings.Contains(got, tt.avoid) {
+				t.Errorf("formatSoundEntry() unexpectedly contains %q in:\n%s", tt.avoid, got)
+			}
+		})
+	}
+}
+
+func TestFilterValidNiches(t *testing.T) {
+	tests := []struct {
+		name   string
+		niches []string
+		want   []string
+	}{
+		{name: "all valid", niches: []string{"fitness", "gaming"}, want: []string{"fitness", "gaming"}},
+		{name: "unknown dropped", niches: []string{"fitness", "knitting"}, want: []string{"fitness"}},
+		{name: "case sensitive", niches: []string{"Fitness"}, want: nil},
+		{name: "crafted value", niches: []string{"fitness\"]"}, want: nil},
+		{name: "empty", niches: nil, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FilterValidNiches(tt.niches); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterValidNiches(%v) = %v, want %v", tt.niches, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetUserNiches(t *testing.T) {
+	tests := []struct {
+		name   string
+		niches string
+		want   []string
+	}{
+		{name: "stored niches", niches: `["fitness","gaming"]`, want: []string{"fitness", "gaming"}},
+		{name: "orphan niche dropped on read", niches: `["knitting","gaming"]`, want: []string{"gaming"}},
+		{name: "empty list", niches: `[]`, want: nil},
+		{name: "unset", niches: "", want: nil},
+		{name: "malformed", niches: `not json`, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &storage.User{Niches: tt.niches}
+			if got := GetUserNiches(user); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetUserNiches(%q) = %v, want %v", tt.niches, got, tt.want)
+			}
+		})
+	}
+}
+
+// soundBlock renders one sound the way alerts do
+func soundBlock(i int) string {
+	return fmt.Sprintf("%d. [Sound title number %d](https://www.tiktok.com/music/sound-%d)\n   📈 +150%% growth\n\n", i, i, i)
+}
+
+func TestSplitMessage(t *testing.T) {
+	var alert strings.Builder
+	alert.WriteString("🔥 *Trending Sounds - Fitness*\n\n")
+	for i := 1; i <= 80; i++ {
+		alert.WriteString(soundBlock(i))
+	}
+
+	var links []string
+	for i := 1; i <= 40; i++ {
+		links = append(links, fmt.Sprintf("[Song %d](https://www.tiktok.com/music/x-%d)", i, i))
+	}
+	oneLine := strings.Join(links, " ")
+
+	tests := []struct {
+		name       string
+		text       string
+		limit      int
+		wantChunks int // 0 means more than one
+	}{
+		{name: "short message is untouched", text: "hello", limit: maxMessageLength, wantChunks: 1},
+		{name: "exactly at the limit", text: strings.Repeat("a", 100), limit: 100, wantChunks: 1},
+		{name: "long alert splits at sound boundaries", text: alert.String(), limit: maxMessageLength},
+		{name: "small limit still keeps sounds whole", text: alert.String(), limit: 300},
+		{name: "single long line keeps links whole", text: oneLine, limit: 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := splitMessage(tt.text, tt.limit)
+
+			if tt.wantChunks > 0 && len(chunks) != tt.wantChunks {
+				t.Fatalf("got %d chunks, want %d", len(chunks), tt.wantChunks)
+			}
+			if tt.wantChunks == 0 && len(chunks) < 2 {
+				t.Fatalf("got %d chunks, want a split", len(chunks))
+			}
+
+			for i, chunk := range chunks {
+				if n := utf8.RuneCountInString(chunk); n > tt.limit {
+					t.Errorf("chunk %d has %d characters, over the limit of %d", i, n, tt.limit)
+				}
+				if strings.Count(chunk, "[") != strings.Count(chunk, "](") ||
+					strings.Count(chunk, "](") != strings.Count(chunk, ")") {
+					t.Errorf("chunk %d cuts a Markdown link:\n%s", i, chunk)
+				}
+			}
+
+			// Nothing but the whitespace at the cuts may be lost
+			squash := func(s string) string { return strings.Join(strings.Fields(s), "") }
+			if got, want := squash(strings.Join(chunks, "")), squash(tt.text); got != want {
+				t.Errorf("chunks lost text")
+			}
+		})
+	}
+}
+
+func TestLineCut(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		limit int
+		want  int
+	}{
+		{name: "last space", line: "aaa bbb ccc", limit: 9, want: 8},
+		{name: "after a link", line: "[a b](http://x)cccc", limit: 17, want: 15},
+		{name: "not inside link text", line: "x [a b c](http://x)", limit: 7, want: 2},
+		{name: "parenthesis in link text", line: "[a (b) c](http://x) d", limit: 10, want: 0},
+		{name: "no break point", line: "abcdefghij", limit: 5, want: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			if want == 0 {
+				want = tt.limit
+			}
+			if got := lineCut([]rune(tt.line), tt.limit); got != want {
+				t.Errorf("lineCut(%q, %d) = %d, want %d", tt.line, tt.limit, got, want)
+			}
+		})
+	}
+}
+
+func TestSendLongMessageWithKeyboard(t *testing.T) {
+	var long strings.Builder
+	for i := 1; i <= 80; i++ {
+		long.WriteString(soundBlock(i))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("x", "x")))
+
+	b, _, api := newTestBot(t, nil)
+	if err := b.sendLongMessageWithKeyboard(1, long.String(), "Markdown", &keyboard); err != nil {
+		t.Fatalf("sendLongMessageWithKeyboard() error: %v", err)
+	}
+
+	if len(api.sent) < 2 {
+		t.Fatalf("sent %d messages, want the text split", len(api.sent))
+	}
+	for i, c := range api.sent {
+		msg := c.(tgbotapi.MessageConfig)
+		if utf8.RuneCountInString(msg.Text) > maxMessageLength {
+			t.Errorf("message %d is over Telegram's limit", i)
+		}
+		if msg.ParseMode != "Markdown" {
+			t.Errorf("message %d parse mode = %q", i, msg.ParseMode)
+		}
+		if hasKeyboard := msg.ReplyMarkup != nil; hasKeyboard != (i == len(api.sent)-1) {
+			t.Errorf("message %d has keyboard = %v, want it only on the last part", i, hasKeyboard)
+		}
+	}
+}
+
+func TestShareKeyboard(t *testing.T) {
+	sounds := []storage.TrendingSound{{Sound: storage.Sound{ID: 7}}, {Sound: storage.Sound{ID: 42}}}
+
+	keyboard := shareKeyboard(sounds, newTheme(nil))
+
+	if len(keyboard.InlineKeyboard) != len(sounds) {
+		t.Fatalf("got %d rows, want one per sound", len(keyboard.InlineKeyboard))
+	}
+	for i, want := range []string{"share 7", "share 42"} {
+		button := keyboard.InlineKeyboard[i][0]
+		if button.SwitchInlineQuery == nil || *button.SwitchInlineQuery != want {
+			t.Errorf("button %d query = %v, want %q", i, button.SwitchInlineQuery, want)
+		}
+		if !strings.Contains(button.Text, fmt.Sprintf("Share #%d", i+1)) {
+			t.Errorf("button %d text = %q", i, button.Text)
+		}
+	}
+}
+
+func TestFormatSoundEntryRank(t *testing.T) {
+	tests := []struct {
+		name  string
+		rank  int
+		want  string
+		avoid string
+	}{
+		{name: "ranked", rank: 3, want: " · #3 in Fitness"},
+		{name: "rank not computed", rank: 0, avoid: "#0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := storage.TrendingSound{
+				Sound:         storage.Sound{ID: 1, Title: "gym anthem", UsesCount: 5000, URL: "https://www.tiktok.com/music/x-1"},
+				GrowthPercent: 150,
+				Rank:          tt.rank,
+			}
+			got := formatSoundEntry(1, ts, "Fitness", newTheme(nil))
+			if tt.want != "" && !strings.Contains(got, tt.want) {
+				t.Errorf("formatSoundEntry() missing %q in:\n%s", tt.want, got)
+			}
+			if tt.avoid != "" && strings.Contains(got, tt.avoid) {
+				t.Errorf("formatSoundEntry() unexpectedly contains %q in:\n%s", tt.avoid, got)
+			}
+		})
+	}
+}
+
+func TestSortTrendingSounds(t *testing.T) {
+	now := time.Now()
+	sound := func(title string, uses int64, age time.Duration) storage.TrendingSound {
+		return storage.TrendingSound{Sound: storage.Sound{Title: title, UsesCount: uses, CreatedAt: now.Add(-age)}}
+	}
+	// Detector order: fastest growth first
+	detected := []storage.TrendingSound{
+		sound("fast", 1000, 2*time.Hour),
+		sound("big", 9000, 48*time.Hour),
+		sound("fresh", 3000, time.Minute),
+	}
+
+	tests := []struct {
+		order string
+		want  []string
+	}{
+		{order: storage.AlertSortGrowth, want: []string{"fast", "big", "fresh"}},
+		{order: storage.AlertSortUses, want: []string{"big", "fresh", "fast"}},
+		{order: storage.AlertSortRecent, want: []string{"fresh", "fast", "big"}},
+		{order: "unknown", want: []string{"fast", "big", "fresh"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			var got []string
+			for _, ts := range sortTrendingSounds(detected, tt.order) {
+				got = append(got, ts.Title)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sortTrendingSounds(%q) = %v, want %v", tt.order, got, tt.want)
+			}
+			if detected[0].Title != "fast" || detected[1].Title != "big" {
+				t.Error("sortTrendingSounds() reordered the detector's slice")
+			}
+		})
+	}
+}
+
+func TestHumanizeAge(t *testing.T) {
+	tests := []struct {
+		age  time.Duration
+		want string
+	}{
+		{0, "just now"},
+		{59 * time.Second, "just now"},
+		{time.Minute, "1m ago"},
+		{25*time.Minute + 40*time.Second, "25m ago"},
+		{time.Hour, "1h ago"},
+		{23*time.Hour + 59*time.Minute, "23h ago"},
+		{24 * time.Hour, "1d ago"},
+		{80 * time.Hour, "3d ago"},
+	}
+
+	for _, tt := range tests {
+		if got := humanizeAge(tt.age); got != tt.want {
+			t.Errorf("humanizeAge(%s) = %q, want %q", tt.age, got, tt.want)
+		}
+	}
+}
+
+func TestFreshnessLine(t *testing.T) {
+	b, s, _ := newTestBot(t, nil)
+	sound := &storage.Sound{Title: "a", URL: "https://www.tiktok.com/music/a-1", UsesCount: 100, Category: "fitness", UpdatedAt: time.Now().Add(-25 * time.Minute)}
+	if err := s.SaveSound(sound); err != nil {
+		t.Fatalf("SaveSound() error: %v", err)
+	}
+
+	if got, want := b.freshnessLine("fitness"), "🕒 _Data as of 25m ago_\n"; got != want {
+		t.Errorf("freshnessLine(fitness) = %q, want %q", got, want)
+	}
+	if got := b.freshnessLine("gaming"); got != "" {
+		t.Errorf("freshnessLine(gaming) = %q, want empty for a niche without sounds", got)
+	}
+}
+
+func TestGatheringDataMessage(t *testing.T) {
+	tests := []struct {
+		name          string
+		minDataPoints string
+		trending      int // Sounds with two history samples
+		fresh         int // Sounds collected once
+		wantGathering bool
+	}{
+		{name: "empty niche", minDataPoints: "5", wantGathering: true},
+		{name: "too few sounds", minDataPoints: "5", trending: 4, wantGathering: true},
+		{name: "nothing comparable", minDataPoints: "5", fresh: 10, wantGathering: true},
+		{name: "enough data", minDataPoints: "5", trending: 1, fresh: 4, wantGathering: false},
+		{name: "lower threshold", minDataPoints: "1", trending: 1, wantGathering: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, _ := newTestBot(t, newTestConfig(t, map[string]string{"MIN_DATA_POINTS": tt.minDataPoints}))
+			for i := 0; i < tt.trending; i++ {
+				seedTrending(t, s, "fitness", fmt.Sprintf("trending %d", i))
+			}
+			for i := 0; i < tt.fresh; i++ {
+				sound := &storage.Sound{Title: "fresh", URL: fmt.Sprintf("https://www.tiktok.com/music/fresh-%d", i), UsesCount: 100, Category: "fitness"}
+				if err := storage.SaveSoundWithHistory(s, sound, storage.DedupByURL); err != nil {
+					t.Fatalf("SaveSoundWithHistory() error: %v", err)
+				}
+			}
+
+			got := b.gatheringDataMessage("fitness")
+			if gathering := got != ""; gathering != tt.wantGathering {
+				t.Errorf("gatheringDataMessage() = %q, want gathering %v", got, tt.wantGathering)
+			}
+		})
+	}
+}
+
+func TestFormatSoundMetadata(t *testing.T) {
+	tests := []struct {
+		name  string
+		sound storage.Sound
+		want  string
+	}{
+		{name: "both", sound: storage.Sound{Genre: "Pop", Duration: 30}, want: "Pop · 0:30"},
+		{name: "genre only", sound: storage.Sound{Genre: "Rock"}, want: "Rock"},
+		{name: "duration only", sound: storage.Sound{Duration: 125}, want: "2:05"},
+		{name: "neither", sound: storage.Sound{}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatSoundMetadata(tt.sound); got != tt.want {
+				t.Errorf("formatSoundMetadata() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatTrendingMessageMetadata(t *testing.T) {
+	sounds := []storage.TrendingSound{
+		{Sound: storage.Sound{Title: "tagged", Genre: "Pop", Duration: 30}, GrowthPercent: 200},
+		{Sound: storage.Sound{Title: "bare"}, GrowthPercent: 200},
+	}
+
+	msg := formatTrendingMessage("Fitness", sounds, newTheme(map[string]string{"genre": "🎧"}))
+	if !strings.Contains(msg, "🎧 Pop · 0:30") {
+		t.Errorf("message is missing the themed metadata line:\n%s", msg)
+	}
+	if strings.Count(msg, "🎧") != 1 {
+		t.Errorf("want a metadata line only for the tagged sound:\n%s", msg)
+	}
+}
+
+func TestFormatTrendingMessageSeasonal(t *testing.T) {
+	sounds := []storage.TrendingSound{
+		{Sound: storage.Sound{Title: "weekly"}, GrowthPercent: 200, SeasonalPeriodDays: 7},
+		{Sound: storage.Sound{Title: "one-off"}, GrowthPercent: 200},
+	}
+
+	msg := formatTrendingMessage("Fitness", sounds, newTheme(nil))
+	if !strings.Contains(msg, "🔁 Spikes every 7d") {
+		t.Errorf("message is missing the seasonal tag:\n%s", msg)
+	}
+	if strings.Count(msg, "Spikes every") != 1 {
+		t.Errorf("want the seasonal tag only on the seasonal sound:\n%s", msg)
+	}
+}
+
+func TestCompactTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{title: "gym anthem", want: "gym anthem"},
+		{title: "song [remix]", want: "song (remix)"},
+		{title: "two\nlines", want: "two lines"},
+		{title: strings.Repeat("a", 40), want: strings.Repeat("a", 40)},
+		{title: strings.Repeat("a", 41), want: strings.Repeat("a", 39) + "…"},
+		{title: strings.Repeat("я", 45), want: strings.Repeat("я", 39) + "…"},
+	}
+
+	for _, tt := range tests {
+		if got := compactTitle(tt.title); got != tt.want {
+			t.Errorf("compactTitle(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestFormatTrendingMessageCompact(t *testing.T) {
+	sounds := []storage.TrendingSound{
+		{Sound: storage.Sound{ID: 1, Title: "grower", URL: "https://a", UsesCount: 1500}, GrowthPercent: 250},
+		{Sound: storage.Sound{ID: 2, Title: "fresh", URL: "https://b", UsesCount: 200}, IsNew: true},
+		{Sound: storage.Sound{ID: 3, Title: "weekly", URL: "https://c", UsesCount: 900}, GrowthPercent: 180, CrossNiche: true, SeasonalPeriodDays: 7},
+	}
+
+	msg := formatTrendingMessageCompact("Fitness", sounds, newTheme(nil))
+
+	want := []string{
+		"1. [grower](https://a) · 1.5K (+250%) · 🆔 1\n",
+		"2. [fresh](https://b) · 200 🆕 · 🆔 2\n",
+		"3. [weekly](https://c) · 900 (+180%) 🌐 🔁 · 🆔 3\n",
+	}
+	for _, line := range want {
+		if !strings.Contains(msg, line) {
+			t.Errorf("message is missing %q:\n%s", line, msg)
+		}
+	}
+	// Header, blank line, then exactly one line per sound
+	if lines := strings.Count(msg, "\n"); lines != 2+len(sounds) {
+		t.Errorf("message has %d lines, want %d:\n%s", lines, 2+len(sounds), msg)
+	}
+}
+
+func TestIsNotModified(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "not modified", err: &tgbotapi.Error{Code: 400, Message: "Bad Request: message is not modified: specified new message content and reply markup are exactly the same"}, want: true},
+		{name: "wrapped", err: fmt.Errorf("edit: %w", &tgbotapi.Error{Code: 400, Message: "Bad Request: message is not modified"}), want: true},
+		{name: "other API error", err: &tgbotapi.Error{Code: 400, Message: "Bad Request: message to edit not found"}, want: false},
+		{name: "network error", err: errors.New("message is not modified"), want: false},
+		{name: "nil", err: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isNotModified(tt.err); got != tt.want {
+				t.Errorf("isNotModified() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditKeyboard(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantLog bool
+	}{
+		{name: "success", wantLog: false},
+		{name: "not modified", err: &tgbotapi.Error{Code: 400, Message: "Bad Request: message is not modified"}, wantLog: false},
+		{name: "other error", err: &tgbotapi.Error{Code: 400, Message: "Bad Request: message to edit not found"}, wantLog: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _, api := newTestBot(t, nil)
+			api.sendErr = tt.err
+
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+			b.editKeyboard(tgbotapi.NewEditMessageReplyMarkup(1, 10, createNichesKeyboard(nil, nil)))
+
+			if len(api.sent) != 1 {
+				t.Fatalf("sent %d edits, want 1", len(api.sent))
+			}
+			if got := strings.Contains(logs.String(), "Error editing keyboard"); got != tt.wantLog {
+				t.Errorf("logged an error = %v, want %v (log: %q)", got, tt.wantLog, logs.String())
+			}
+		})
+	}
+}
+
+func TestAlertNiches(t *testing.T) {
+	tests := []struct {
+		name  string
+		muted []string
+		want  []string
+	}{
+		{name: "nothing muted", want: []string{"fitness", "gaming", "tech"}},
+		{name: "one muted", muted: []string{"gaming"}, want: []string{"fitness", "tech"}},
+		{name: "unsubscribed niche muted", muted: []string{"beauty"}, want: []string{"fitness", "gaming", "tech"}},
+		{name: "all muted", muted: []string{"fitness", "gaming", "tech"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, _ := newTestBot(t, nil)
+			addUser(t, s, 1, "fitness", "gaming", "tech")
+			for _, niche := range tt.muted {
+				if err := s.MuteNiche(1, niche); err != nil {
+					t.Fatalf("MuteNiche() error: %v", err)
+				}
+			}
+			user, err := s.GetUser(1)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+
+			if got := b.AlertNiches(user); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AlertNiches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/yourusername/trending-sound/internal/parser"
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+// clickPath prefixes tracked alert links: /r/<telegram id>/<sound id>/<niche>/<signature>
+const clickPath = "/r/"
+
+// trackedSounds returns a copy of sounds whose links go through the click tracking server, so
+// opening one records engagement with the niche. Without CLICK_TRACKING_URL the sounds are returned as is.
+func (b *Bot) trackedSounds(telegramID int64, category string, sounds []storage.TrendingSound) []storage.TrendingSound {
+	if b.cfg.ClickTrackingURL == "" {
+		return sounds
+	}
+	tracked := make([]storage.TrendingSound, len(sounds))
+	for i, ts := range sounds {
+		ts.URL = fmt.Sprintf("%s%s%d/%d/%s/%s", b.cfg.ClickTrackingURL, clickPath,
+			telegramID, ts.ID, category, b.clickSignature(telegramID, ts.ID, category))
+		tracked[i] = ts
+	}
+	return tracked
+}
+
+// clickSignature signs a tracked link with the bot token so clicks can't be forged for other users
+func (b *Bot) clickSignature(telegramID, soundID int64, category string) string {
+	mac := hmac.New(sha256.New, []byte(b.cfg.TelegramBotToken))
+	fmt.Fprintf(mac, "%d/%d/%s", telegramID, soundID, category)
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// hasTrackedLink reports whether a message contains a tracked alert link. Such messages are sent
+// without a link preview, since Telegram's preview crawler would otherwise open the first link.
+func (b *Bot) hasTrackedLink(text string) bool {
+	return b.cfg.ClickTrackingURL != "" && strings.Contains(text, b.cfg.ClickTrackingURL+clickPath)
+}
+
+// ClickHandler serves tracked alert links: it records the first GET of each link as engagement with
+// the link's niche and redirects to the sound on TikTok. HEAD requests and repeat opens redirect without
+// counting. Unknown or tampered links get a 404.
+func (b *Bot) ClickHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		telegramID, soundID, category, ok := b.parseClickPath(r.URL.Path)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		sound, err := b.storage.GetSoundByID(soundID)
+		if errors.Is(err, storage.ErrSoundNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			log.Printf("Error getting clicked sound %d: %v", soundID, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			if _, err := b.storage.RecordAlertClick(telegramID, soundID, category); err != nil {
+				log.Printf("Error recording alert click: %v", err)
+			}
+		}
+
+		http.Redirect(w, r, sound.URL, http.StatusFound)
+	})
+}
+
+// parseClickPath extracts a tracked link's fields, reporting false for malformed or badly signed links
+func (b *Bot) parseClickPath(path string) (int64, int64, string, bool) {
+	rest, ok := strings.CutPrefix(path, clickPath)
+	if !ok {
+		return 0, 0, "", false
+	}
+	parts := strings.Split(rest, "/")
+	if len(parts) != 4 {
+		return 0, 0, "", false
+	}
+
+	telegramID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, "", false
+	}
+	soundID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, "", false
+	}
+	category := parts[2]
+	if !parser.IsValidCategory(category) {
+		return 0, 0, "", false
+	}
+
+	want := b.clickSignature(telegramID, soundID, category)
+	if !hmac.Equal([]byte(parts[3]), []byte(want)) {
+		return 0, 0, "", false
+	}
+	return telegramID, soundID, category, true
+}
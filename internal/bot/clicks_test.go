@@ -0,0 +1,149 @@
+package bot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+func TestTrackedSounds(t *testing.T) {
+	sounds := []storage.TrendingSound{{Sound: storage.Sound{ID: 5, URL: "https://www.tiktok.com/music/x-5"}}}
+
+	t.Run("disabled", func(t *testing.T) {
+		b, _, _ := newTestBot(t, nil)
+		got := b.trackedSounds(1, "fitness", sounds)
+		if got[0].URL != sounds[0].URL {
+			t.Errorf("URL = %q, want the plain link", got[0].URL)
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		cfg := newTestConfig(t, map[string]string{"CLICK_TRACKING_URL": "https://bot.example.com/"})
+		b, _, _ := newTestBot(t, cfg)
+		got := b.trackedSounds(1, "fitness", sounds)
+		if !strings.HasPrefix(got[0].URL, "https://bot.example.com/r/1/5/fitness/") {
+			t.Errorf("URL = %q, want a tracked link", got[0].URL)
+		}
+		if sounds[0].URL != "https://www.tiktok.com/music/x-5" {
+			t.Error("trackedSounds modified its input")
+		}
+	})
+}
+
+func TestClickHandler(t *testing.T) {
+	cfg := newTestConfig(t, map[string]string{"CLICK_TRACKING_URL": "https://bot.example.com"})
+	b, s, _ := newTestBot(t, cfg)
+	sound := &storage.Sound{Title: "gym anthem", URL: "https://www.tiktok.com/music/gym-anthem-1", Category: "fitness"}
+	if err := s.SaveSound(sound); err != nil {
+		t.Fatalf("SaveSound() error: %v", err)
+	}
+
+	tracked := func(telegramID int64, soundID int64, category string) string {
+		ts := b.trackedSounds(telegramID, category, []storage.TrendingSound{{Sound: storage.Sound{ID: soundID}}})
+		u, _ := url.Parse(ts[0].URL)
+		return u.Path
+	}
+	valid := tracked(7, sound.ID, "fitness")
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantClicks int
+	}{
+		{name: "preview crawler HEAD", method: http.MethodHead, path: valid, wantStatus: http.StatusFound},
+		{name: "valid link", path: valid, wantStatus: http.StatusFound, wantClicks: 1},
+		{name: "same link opened again", path: valid, wantStatus: http.StatusFound},
+		{name: "POST", method: http.MethodPost, path: valid, wantStatus: http.StatusMethodNotAllowed},
+		{name: "tampered signature", path: valid[:len(valid)-1] + "0", wantStatus: http.StatusNotFound},
+		{name: "link of another user", path: strings.Replace(valid, "/r/7/", "/r/8/", 1), wantStatus: http.StatusNotFound},
+		{name: "other niche", path: strings.Replace(valid, "/fitness/", "/gaming/", 1), wantStatus: http.StatusNotFound},
+		{name: "unknown sound", path: tracked(7, 999, "fitness"), wantStatus: http.StatusNotFound},
+		{name: "unknown niche", path: tracked(7, sound.ID, "knitting"), wantStatus: http.StatusNotFound},
+		{name: "malformed", path: "/r/7/x", wantStatus: http.StatusNotFound},
+		{name: "other path", path: "/", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, _ := s.GetUserNicheEngagement(7)
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			rec := httptest.NewRecorder()
+			b.ClickHandler().ServeHTTP(rec, httptest.NewRequest(method, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusFound {
+				if loc := rec.Header().Get("Location"); loc != sound.URL {
+					t.Errorf("Location = %q, want %q", loc, sound.URL)
+				}
+			}
+
+			after, _ := s.GetUserNicheEngagement(7)
+			if got := totalClicks(after) - totalClicks(before); got != tt.wantClicks {
+				t.Errorf("recorded %d clicks, want %d", got, tt.wantClicks)
+			}
+		})
+	}
+
+	if top := b.topEngagedNiche(7); top != "fitness" {
+		t.Errorf("topEngagedNiche() = %q after a click, want fitness", top)
+	}
+}
+
+func TestTrackedAlertsDisablePreview(t *testing.T) {
+	tests := []struct {
+		name        string
+		trackingURL string
+		want        bool
+	}{
+		{name: "plain links", want: false},
+		{name: "tracked links", trackingURL: "https://bot.example.com", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t, map[string]string{"CLICK_TRACKING_URL": tt.trackingURL})
+			b, s, api := newTestBot(t, cfg)
+			addUser(t, s, 1, "fitness")
+			sound := seedTrending(t, s, "fitness", "gym anthem")
+
+			if err := b.SendTrendingAlert(1, "fitness", []storage.TrendingSound{{Sound: *sound, GrowthPercent: 400}}); err != nil {
+				t.Fatalf("SendTrendingAlert() error: %v", err)
+			}
+
+			api.mu.Lock()
+			defer api.mu.Unlock()
+			if len(api.sent) == 0 {
+				t.Fatal("no alert sent")
+			}
+			msg, ok := api.sent[0].(tgbotapi.MessageConfig)
+			if !ok {
+				t.Fatalf("sent %T, want a message", api.sent[0])
+			}
+			if msg.DisableWebPagePreview != tt.want {
+				t.Errorf("DisableWebPagePreview = %v, want %v", msg.DisableWebPagePreview, tt.want)
+			}
+		})
+	}
+}
+
+// totalClicks sums engagement counts across niches
+func totalClicks(engagement []storage.NicheEngagement) int {
+	total := 0
+	for _, e := range engagement {
+		total += e.Count
+	}
+	return total
+}
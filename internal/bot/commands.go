@@ -0,0 +1,194 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+// commandTier controls who can use a command
+type commandTier int
+
+const (
+	tierAll commandTier = iota
+	tierPremium
+	tierAdmin
+)
+
+// command describes a bot command in the registry
+type command struct {
+	Name        string
+	Description string
+	Tier        commandTier
+	Handler     func(b *Bot, message *tgbotapi.Message)
+}
+
+// commands is the registry of all bot commands and the single source of truth
+// for dispatching, tier-gating and /help. Order here is the order shown in /help.
+// It is filled in init because /help itself reads the registry.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"start", "Start the bot and choose niches", tierAll, (*Bot).handleStart},
+		{"niches", "Select your niches", tierAll, (*Bot).handleNiches},
+		{"trending", "View current trending sounds", tierAll, (*Bot).handleTrending},
+		{"preview", "Preview trends for any niche: /preview <niche>", tierAll, (*Bot).handlePreview},
+		{"vs", "Compare two niches: /vs <niche> <niche>", tierAll, (*Bot).handleVs},
+		{"graduates", "Sounds that outgrew the trending range this week: /graduates [niche]", tierAll, (*Bot).handleGraduates},
+		{"popularauthors", "Top sound creators: /popularauthors [niche]", tierAll, (*Bot).handlePopularAuthors},
+		{"history_date", "What trended on a past date: /history_date <niche> <YYYY-MM-DD>", tierAll, (*Bot).handleHistoryDate},
+		{"last", "Resend your last alert: /last [niche]", tierAll, (*Bot).handleLast},
+		{"alerts", "Sounds from your recent alerts", tierAll, (*Bot).handleAlerts},
+		{"follow", "Get milestone alerts for a sound: /follow <ID>", tierAll, (*Bot).handleFollow},
+		{"nominate", "Suggest a sound to track: /nominate <url> [niche]", tierAll, (*Bot).handleNominate},
+		{"status", "Show your current settings", tierAll, (*Bot).handleStatus},
+		{"stats", "Show your statistics", tierAll, (*Bot).handleStats},
+		{"premium", "Upgrade to Premium", tierAll, (*Bot).handlePremium},
+		{"help", "Show this list of commands", tierAll, (*Bot).handleHelp},
+		{"cancel", "Cancel the command in progress", tierAll, (*Bot).handleCancel},
+		{"label", "Rename a niche for yourself: /label <niche> <name>", tierPremium, (*Bot).handleLabel},
+		{"card", "Trending sounds as an image: /card [niche]", tierPremium, (*Bot).handleCard},
+		{"mode", "Alert on growth or popularity: /mode <growth|popularity>", tierAll, (*Bot).handleMode},
+		{"sort", "Order sounds in alerts: /sort <growth|uses|recent>", tierAll, (*Bot).handleSort},
+		{"format", "Alert layout: /format <detailed|compact|tiered>", tierAll, (*Bot).handleFormat},
+		{"boost", "Catch earlier trends for a while: /boost [off]", tierAll, (*Bot).handleBoost},
+		{"frequency", "Alert delivery: /frequency <every|twice_daily|daily>", tierAll, (*Bot).handleFrequency},
+		{"mute_niche", "Pause alerts for one niche: /mute_niche <niche>", tierAll, (*Bot).handleMuteNiche},
+		{"unmute_niche", "Resume alerts for a muted niche: /unmute_niche <niche>", tierAll, (*Bot).handleUnmuteNiche},
+		{"region", "Whose trends you get: /region <region>", tierAll, (*Bot).handleRegion},
+		{"timezone", "When your day starts for the alert limit: /timezone <zone|reset>", tierAll, (*Bot).handleTimezone},
+		{"minuses", "Skip sounds with fewer uses in alerts: /minuses <count|reset>", tierAll, (*Bot).handleMinUses},
+		{"beta", "Try experimental features before release", tierAll, (*Bot).handleBeta},
+		{"limit", "Sounds per alert: /limit <3-20>", tierPremium, (*Bot).handleLimit},
+		{"threshold", "Min growth for alerts: /threshold [niche] <percent|reset>", tierPremium, (*Bot).handleThreshold},
+		{"repeats", "Also get sounds already alerted recently", tierPremium, (*Bot).handleRepeats},
+		{"instant", "Get alerts as soon as fresh data arrives", tierPremium, (*Bot).handleInstant},
+		{"raw", "Download a sound's full history as JSON: /raw <sound ID>", tierPremium, (*Bot).handleRaw},
+		{"pausealerts", "Pause all outbound alerts", tierAdmin, func(b *Bot, m *tgbotapi.Message) { b.handlePauseAlerts(m, true) }},
+		{"resumealerts", "Resume outbound alerts", tierAdmin, func(b *Bot, m *tgbotapi.Message) { b.handlePauseAlerts(m, false) }},
+		{"explain", "Explain detection for a niche: /explain <niche>", tierAdmin, (*Bot).handleExplain},
+		{"sources", "Sounds where parsers disagree: /sources [min %]", tierAdmin, (*Bot).handleSources},
+		{"gaps", "Sounds the collector missed: /gaps [niche] [hours]", tierAdmin, (*Bot).handleGaps},
+		{"latency", "Alert delivery latency: /latency [hours]", tierAdmin, (*Bot).handleLatency},
+		{"version", "Show the deployed build", tierAdmin, (*Bot).handleVersion},
+		{"schema", "Show the database schema fingerprint", tierAdmin, (*Bot).handleSchema},
+		{"health", "Collector rate limits and detection timing", tierAdmin, (*Bot).handleHealth},
+		{"betausers", "Count users enrolled in beta", tierAdmin, (*Bot).handleBetaUsers},
+		{"merge", "Merge a duplicate user: /merge <keep id> <duplicate id>", tierAdmin, (*Bot).handleMerge},
+		{"block", "Ignore a user and stop their alerts: /block <telegram id>", tierAdmin, func(b *Bot, m *tgbotapi.Message) { b.handleBlock(m, true) }},
+		{"unblock", "Unblock a user: /unblock <telegram id>", tierAdmin, func(b *Bot, m *tgbotapi.Message) { b.handleBlock(m, false) }},
+		{"broadcast", "Send a message to all users", tierAdmin, (*Bot).handleBroadcast},
+		{"flags", "List feature flags", tierAdmin, (*Bot).handleFlags},
+		{"flag", "Toggle a feature flag: /flag <name> <on|off>", tierAdmin, (*Bot).handleFlag},
+	}
+}
+
+// findCommand looks up a command by name
+func findCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// dispatchCommand runs a command after checking the user's tier
+func (b *Bot) dispatchCommand(message *tgbotapi.Message) {
+	cmd, ok := findCommand(message.Command())
+	if !ok {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Unknown command. Use /help to see available commands.")
+		b.api.Send(msg)
+		return
+	}
+
+	switch cmd.Tier {
+	case tierAdmin:
+		// Admin commands stay invisible to everyone else
+		if !b.isAdmin(message.From.ID) {
+			return
+		}
+	case tierPremium:
+		// Admins can use premium commands without a subscription
+		if b.isAdmin(message.From.ID) {
+			break
+		}
+		user, err := b.storage.GetUser(message.From.ID)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+			b.api.Send(msg)
+			return
+		}
+		if err != nil {
+			log.Printf("Error getting user: %v", err)
+			msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+			b.api.Send(msg)
+			return
+		}
+		if !user.IsPremium {
+			msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("💎 /%s is a Premium feature. Use /premium to upgrade!", cmd.Name))
+			b.api.Send(msg)
+			return
+		}
+	}
+
+	cmd.Handler(b, message)
+}
+
+// handleHelp handles the /help command
+func (b *Bot) handleHelp(message *tgbotapi.Message) {
+	isPremium := false
+	if user, err := b.storage.GetUser(message.From.ID); err == nil {
+		isPremium = user.IsPremium
+	}
+
+	// Admins can use premium commands, so they aren't shown locked
+	isAdmin := b.isAdmin(message.From.ID)
+	msg := tgbotapi.NewMessage(message.Chat.ID, renderHelp(isPremium || isAdmin, isAdmin))
+	b.api.Send(msg)
+}
+
+// renderHelp lists commands available to the user. Premium commands are shown
+// locked for free users; admin commands are only shown to admins.
+func renderHelp(isPremium, isAdmin bool) string {
+	text := "📱 Commands\n\n"
+	for _, c := range commands {
+		switch c.Tier {
+		case tierAll:
+			text += fmt.Sprintf("/%s - %s\n", c.Name, c.Description)
+		case tierPremium:
+			lock := ""
+			if !isPremium {
+				lock = "🔒 "
+			}
+			text += fmt.Sprintf("%s/%s - %s (Premium)\n", lock, c.Name, c.Description)
+		}
+	}
+
+	if isAdmin {
+		text += "\n🛠 Admin\n\n"
+		for _, c := range commands {
+			if c.Tier == tierAdmin {
+				text += fmt.Sprintf("/%s - %s\n", c.Name, c.Description)
+			}
+		}
+	}
+
+	return text
+}
+
+// botMenuCommands returns the public commands for Telegram's command menu
+func botMenuCommands() []tgbotapi.BotCommand {
+	var menu []tgbotapi.BotCommand
+	for _, c := range commands {
+		if c.Tier == tierAdmin {
+			continue
+		}
+		menu = append(menu, tgbotapi.BotCommand{Command: c.Name, Description: c.Description})
+	}
+	return menu
+}
@@ -0,0 +1,136 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommandRegistry(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, c := range commands {
+		if seen[c.Name] {
+			t.Errorf("command /%s registered twice", c.Name)
+		}
+		seen[c.Name] = true
+		if c.Description == "" {
+			t.Errorf("command /%s has no description", c.Name)
+		}
+		if c.Handler == nil {
+			t.Errorf("command /%s has no handler", c.Name)
+		}
+	}
+
+	for _, name := range []string{"start", "help", "limit", "flag"} {
+		if _, ok := findCommand(name); !ok {
+			t.Errorf("findCommand(%q) not found", name)
+		}
+	}
+	if _, ok := findCommand("nope"); ok {
+		t.Error("findCommand(\"nope\") found a command")
+	}
+}
+
+func TestRenderHelp(t *testing.T) {
+	tests := []struct {
+		name      string
+		isPremium bool
+		isAdmin   bool
+		want      []string
+		avoid     []string
+	}{
+		{
+			name:  "free user sees premium commands locked",
+			want:  []string{"/start - ", "🔒 /limit - ", "(Premium)"},
+			avoid: []string{"Admin", "/flag"},
+		},
+		{
+			name:      "premium user sees them unlocked",
+			isPremium: true,
+			want:      []string{"/start - ", "\n/limit - "},
+			avoid:     []string{"🔒", "Admin", "/flag"},
+		},
+		{
+			name:      "admin sees the admin section",
+			isPremium: true,
+			isAdmin:   true,
+			want:      []string{"🛠 Admin", "/flag - ", "/explain - "},
+			avoid:     []string{"🔒"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renderHelp(tt.isPremium, tt.isAdmin)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("renderHelp() missing %q in:\n%s", want, got)
+				}
+			}
+			for _, avoid := range tt.avoid {
+				if strings.Contains(got, avoid) {
+					t.Errorf("renderHelp() unexpectedly contains %q in:\n%s", avoid, got)
+				}
+			}
+		})
+	}
+}
+
+func TestBotMenuCommands(t *testing.T) {
+	menu := botMenuCommands()
+	if len(menu) == 0 {
+		t.Fatal("botMenuCommands() is empty")
+	}
+	for _, c := range menu {
+		if cmd, _ := findCommand(c.Command); cmd.Tier == tierAdmin {
+			t.Errorf("admin command /%s is in the public menu", c.Command)
+		}
+	}
+}
+
+func TestDispatchCommandTiers(t *testing.T) {
+	const admin, user = 1, 2
+
+	tests := []struct {
+		name    string
+		from    int64
+		premium bool
+		command string
+		want    string // empty means nothing is sent
+	}{
+		{name: "unknown command", from: user, command: "/nope", want: "Unknown command. Use /help"},
+		{name: "admin command hidden from users", from: user, command: "/flag cards on", want: ""},
+		{name: "admin command runs for admins", from: admin, command: "/flag", want: "Usage: /flag"},
+		{name: "premium command locked", from: user, command: "/limit 5", want: "💎 /limit is a Premium feature"},
+		{name: "premium command runs for premium users", from: user, premium: true, command: "/limit 5", want: "up to 5 sounds"},
+		{name: "premium command runs for admins", from: admin, command: "/limit 5", want: "up to 5 sounds"},
+		{name: "help lists commands", from: user, command: "/help", want: "📱 Commands"},
+		{name: "help shows premium commands unlocked for admins", from: admin, command: "/help", want: "\n/limit - "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t, map[string]string{"ADMIN_IDS": "1"})
+			b, s, api := newTestBot(t, cfg)
+			addUser(t, s, admin, "fitness")
+			addUser(t, s, user, "fitness")
+			if tt.premium {
+				if err := s.SetPremium(user, true); err != nil {
+					t.Fatalf("SetPremium() error: %v", err)
+				}
+			}
+
+			b.dispatchCommand(commandMessage(tt.from, tt.command))
+
+			texts := api.texts()
+			if tt.want == "" {
+				if len(texts) != 0 {
+					t.Errorf("sent %q, want nothing", texts)
+				}
+				return
+			}
+			if len(texts) == 0 || !strings.Contains(texts[0], tt.want) {
+				t.Errorf("replies = %q, want one containing %q", texts, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+// sessionTTL is how long a multi-step command waits for the user's next message
+const sessionTTL = 10 * time.Minute
+
+// Conversation states stored in a user's session
+const (
+	stateBroadcast = "broadcast" // Waiting for the text an admin wants to broadcast
+)
+
+// conversationHandlers routes a plain (non-command) message to the handler of the user's active conversation.
+// Handlers are responsible for clearing or advancing the session.
+var conversationHandlers = map[string]func(b *Bot, message *tgbotapi.Message, session *storage.Session){
+	stateBroadcast: (*Bot).handleBroadcastText,
+}
+
+// startConversation stores the state a follow-up message will be routed by
+func (b *Bot) startConversation(telegramID int64, state, payload string) error {
+	return b.storage.SetSession(telegramID, state, payload)
+}
+
+// handleConversation routes a non-command message to the user's active conversation, if any
+func (b *Bot) handleConversation(message *tgbotapi.Message) {
+	session, err := b.storage.GetSession(message.From.ID, sessionTTL)
+	if errors.Is(err, storage.ErrSessionNotFound) {
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting session: %v", err)
+		return
+	}
+
+	handler, ok := conversationHandlers[session.State]
+	if !ok {
+		log.Printf("Clearing session with unknown state %q for %d", session.State, message.From.ID)
+		if err := b.storage.ClearSession(message.From.ID); err != nil {
+			log.Printf("Error clearing session: %v", err)
+		}
+		return
+	}
+
+	handler(b, message, session)
+}
+
+// handleCancel handles the /cancel command ending any multi-step command in progress
+func (b *Bot) handleCancel(message *tgbotapi.Message) {
+	if err := b.storage.ClearSession(message.From.ID); err != nil {
+		log.Printf("Error clearing session: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, "Cancelled.")
+	b.api.Send(msg)
+}
+
+// handleBroadcast handles the admin /broadcast command, asking for the message to send to all users
+func (b *Bot) handleBroadcast(message *tgbotapi.Message) {
+	if err := b.startConversation(message.From.ID, stateBroadcast, ""); err != nil {
+		log.Printf("Error starting broadcast: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("📣 Send the message to broadcast to all users, or /cancel. This expires in %s.", sessionTTL))
+	b.api.Send(msg)
+}
+
+// handleBroadcastText sends the admin's follow-up message to every user
+func (b *Bot) handleBroadcastText(message *tgbotapi.Message, session *storage.Session) {
+	if err := b.storage.ClearSession(message.From.ID); err != nil {
+		log.Printf("Error clearing session: %v", err)
+	}
+
+	// Admin rights may have been revoked since the conversation started
+	if !b.isAdmin(message.From.ID) || message.Text == "" {
+		return
+	}
+
+	users, err := b.storage.GetAlertUsers()
+	if err != nil {
+		log.Printf("Error getting users for broadcast: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	sent := 0
+	for _, user := range users {
+		if _, err := b.api.Send(tgbotapi.NewMessage(user.TelegramID, message.Text)); err != nil {
+			log.Printf("Error broadcasting to %d: %v", user.TelegramID, err)
+			continue
+		}
+		sent++
+	}
+
+	log.Printf("Admin %d broadcast a message to %d of %d users", message.From.ID, sent, len(users))
+	msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("✅ Broadcast sent to %d of %d users.", sent, len(users)))
+	b.api.Send(msg)
+}
@@ -0,0 +1,120 @@
+package bot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+// textMessage builds a plain, non-command message from the given user
+func textMessage(from int64, text string) *tgbotapi.Message {
+	return &tgbotapi.Message{
+		From: &tgbotapi.User{ID: from},
+		Chat: &tgbotapi.Chat{ID: from},
+		Text: text,
+	}
+}
+
+// recipients returns the chat IDs that received text
+func (f *fakeAPI) recipients(text string) []int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var ids []int64
+	for _, c := range f.sent {
+		if m, ok := c.(tgbotapi.MessageConfig); ok && m.Text == text {
+			ids = append(ids, m.ChatID)
+		}
+	}
+	return ids
+}
+
+func TestBroadcastConversation(t *testing.T) {
+	const admin = 1
+
+	tests := []struct {
+		name           string
+		messages       []*tgbotapi.Message
+		wantRecipients int
+		wantSession    bool
+	}{
+		{
+			name:           "broadcast is sent to every user",
+			messages:       []*tgbotapi.Message{commandMessage(admin, "/broadcast"), textMessage(admin, "hello all")},
+			wantRecipients: 3,
+		},
+		{
+			name:        "waiting for the text",
+			messages:    []*tgbotapi.Message{commandMessage(admin, "/broadcast")},
+			wantSession: true,
+		},
+		{
+			name:     "cancel ends the conversation",
+			messages: []*tgbotapi.Message{commandMessage(admin, "/broadcast"), commandMessage(admin, "/cancel"), textMessage(admin, "hello all")},
+		},
+		{
+			name:     "plain message without a conversation is ignored",
+			messages: []*tgbotapi.Message{textMessage(admin, "hello all")},
+		},
+		{
+			name:     "non-admin cannot start a broadcast",
+			messages: []*tgbotapi.Message{commandMessage(2, "/broadcast"), textMessage(2, "hello all")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t, map[string]string{"ADMIN_IDS": "1"})
+			b, s, api := newTestBot(t, cfg)
+			for _, id := range []int64{admin, 2, 3} {
+				addUser(t, s, id, "fitness")
+			}
+
+			for _, m := range tt.messages {
+				b.handleMessage(m)
+			}
+
+			if got := api.recipients("hello all"); len(got) != tt.wantRecipients {
+				t.Errorf("broadcast reached %v, want %d users", got, tt.wantRecipients)
+			}
+			_, err := s.GetSession(admin, sessionTTL)
+			if hasSession := err == nil; hasSession != tt.wantSession {
+				t.Errorf("admin session active = %v (err %v), want %v", hasSession, err, tt.wantSession)
+			}
+		})
+	}
+}
+
+func TestBroadcastConfirmation(t *testing.T) {
+	cfg := newTestConfig(t, map[string]string{"ADMIN_IDS": "1"})
+	b, s, api := newTestBot(t, cfg)
+	addUser(t, s, 1, "fitness")
+	addUser(t, s, 2, "gaming")
+
+	b.handleMessage(commandMessage(1, "/broadcast"))
+	b.handleMessage(textMessage(1, "news"))
+
+	texts := api.texts()
+	if len(texts) == 0 || !strings.Contains(texts[len(texts)-1], "Broadcast sent to 2 of 2 users") {
+		t.Errorf("replies = %q, want a confirmation counting both users", texts)
+	}
+}
+
+func TestHandleConversationUnknownState(t *testing.T) {
+	b, s, api := newTestBot(t, nil)
+	addUser(t, s, 1, "fitness")
+	if err := s.SetSession(1, "removed-flow", ""); err != nil {
+		t.Fatalf("SetSession() error: %v", err)
+	}
+
+	b.handleMessage(textMessage(1, "hello"))
+
+	if _, err := s.GetSession(1, sessionTTL); !errors.Is(err, storage.ErrSessionNotFound) {
+		t.Errorf("GetSession() error = %v, want the unknown session cleared", err)
+	}
+	if texts := api.texts(); len(texts) != 0 {
+		t.Errorf("replies = %q, want none", texts)
+	}
+}
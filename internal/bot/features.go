@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Feature flags gating experimental code paths, toggled with /flag
+const (
+	FeatureCards      = "cards"       // /card image cards
+	FeatureCrossNiche = "cross_niche" // Cross-niche flagging in everyone's alerts, not just beta users'
+)
+
+// knownFeatures lists the flags /flags shows and /flag accepts
+var knownFeatures = []string{FeatureCards, FeatureCrossNiche}
+
+// featureCacheTTL is how long a flag value is trusted before it is read again, so a toggle
+// takes effect within this time without querying the database on every check
+const featureCacheTTL = 30 * time.Second
+
+// featureCache holds recently read feature flag values
+type featureCache struct {
+	mu      sync.Mutex
+	entries map[string]featureEntry
+}
+
+// featureEntry is a cached flag value and when it was read
+type featureEntry struct {
+	enabled  bool
+	loadedAt time.Time
+}
+
+// FeatureEnabled reports whether a feature flag is on, reading it at most once per featureCacheTTL.
+// If the read fails, the last known value is kept.
+func (b *Bot) FeatureEnabled(name string) bool {
+	b.features.mu.Lock()
+	defer b.features.mu.Unlock()
+
+	entry, ok := b.features.entries[name]
+	if ok && time.Since(entry.loadedAt) < featureCacheTTL {
+		return entry.enabled
+	}
+
+	enabled, err := b.storage.IsFeatureEnabled(name)
+	if err != nil {
+		log.Printf("Error reading feature flag %s: %v", name, err)
+		return entry.enabled
+	}
+
+	if b.features.entries == nil {
+		b.features.entries = make(map[string]featureEntry)
+	}
+	b.features.entries[name] = featureEntry{enabled: enabled, loadedAt: time.Now()}
+	return enabled
+}
+
+// forgetFeature drops a cached flag so the next check reads the new value
+func (b *Bot) forgetFeature(name string) {
+	b.features.mu.Lock()
+	defer b.features.mu.Unlock()
+	delete(b.features.entries, name)
+}
@@ -0,0 +1,40 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFeatureEnabledCache(t *testing.T) {
+	b, s, _ := newTestBot(t, nil)
+
+	if !b.FeatureEnabled(FeatureCards) {
+		t.Fatal("FeatureEnabled(cards) = false, want the seeded default on")
+	}
+
+	// A change made behind the cache's back isn't seen until the entry expires
+	if err := s.SetFeature(FeatureCards, false); err != nil {
+		t.Fatalf("SetFeature() error: %v", err)
+	}
+	if !b.FeatureEnabled(FeatureCards) {
+		t.Error("FeatureEnabled(cards) = false, want the cached value")
+	}
+
+	b.features.mu.Lock()
+	entry := b.features.entries[FeatureCards]
+	entry.loadedAt = time.Now().Add(-featureCacheTTL)
+	b.features.entries[FeatureCards] = entry
+	b.features.mu.Unlock()
+	if b.FeatureEnabled(FeatureCards) {
+		t.Error("FeatureEnabled(cards) after the cache expired = true, want false")
+	}
+
+	// forgetFeature makes the next check read the database
+	if err := s.SetFeature(FeatureCards, true); err != nil {
+		t.Fatalf("SetFeature() error: %v", err)
+	}
+	b.forgetFeature(FeatureCards)
+	if !b.FeatureEnabled(FeatureCards) {
+		t.Error("FeatureEnabled(cards) after forgetFeature = false, want true")
+	}
+}
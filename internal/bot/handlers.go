@@ -1,13 +1,24 @@
 package bot
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/trending-sound/internal/card"
+	"github.com/yourusername/trending-sound/internal/detector"
 	"github.com/yourusername/trending-sound/internal/parser"
 	"github.com/yourusername/trending-sound/internal/storage"
+	"github.com/yourusername/trending-sound/internal/version"
 )
 
 // handleStart handles the /start command
@@ -16,7 +27,7 @@ func (b *Bot) handleStart(message *tgbotapi.Message) {
 
 	// Check if user exists
 	user, err := b.storage.GetUser(telegramID)
-	if err != nil {
+	if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
 		log.Printf("Error getting user: %v", err)
 		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
 		b.api.Send(msg)
@@ -24,9 +35,9 @@ func (b *Bot) handleStart(message *tgbotapi.Message) {
 	}
 
 	// Create user if doesn't exist
-	if user == nil {
+	if errors.Is(err, storage.ErrUserNotFound) {
 		err := b.storage.CreateUser(telegramID)
-		if err != nil {
+		if err != nil && !errors.Is(err, storage.ErrDuplicate) {
 			log.Printf("Error creating user: %v", err)
 			msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
 			b.api.Send(msg)
@@ -34,6 +45,15 @@ func (b *Bot) handleStart(message *tgbotapi.Message) {
 		}
 	}
 
+	// Users who already picked niches don't need the onboarding again
+	if b.cfg.ShortWelcome && user != nil {
+		if niches := GetUserNiches(user); len(niches) > 0 {
+			msg := tgbotapi.NewMessage(message.Chat.ID, b.welcomeBackText(telegramID, niches))
+			b.api.Send(msg)
+			return
+		}
+	}
+
 	// Send welcome message
 	welcomeText := `👋 Welcome to TikTok Trending Sounds Tracker!
 
@@ -52,24 +72,39 @@ Let's get started! Choose your niches below:`
 
 	msg := tgbotapi.NewMessage(message.Chat.ID, welcomeText)
 	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = createNichesKeyboard([]string{})
+	msg.ReplyMarkup = createNichesKeyboard([]string{}, b.nicheLabels(telegramID))
 	b.api.Send(msg)
 }
 
+// welcomeBackText is the short /start reply for users who already set up their niches
+func (b *Bot) welcomeBackText(telegramID int64, niches []string) string {
+	labels := b.nicheLabels(telegramID)
+	names := make([]string, len(niches))
+	for i, niche := range niches {
+		names[i] = nicheDisplayName(niche, labels)
+	}
+
+	return fmt.Sprintf(`👋 Welcome back!
+
+🎯 You're tracking: %s
+
+/trending - See what's trending now
+/niches - Change your niches`, strings.Join(names, ", "))
+}
+
 // handleNiches handles the /niches command
 func (b *Bot) handleNiches(message *tgbotapi.Message) {
 	telegramID := message.From.ID
 
 	user, err := b.storage.GetUser(telegramID)
-	if err != nil {
-		log.Printf("Error getting user: %v", err)
-		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
 		b.api.Send(msg)
 		return
 	}
-
-	if user == nil {
-		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
 		b.api.Send(msg)
 		return
 	}
@@ -79,7 +114,7 @@ func (b *Bot) handleNiches(message *tgbotapi.Message) {
 	text := "📊 *Your Niches*\n\nSelect the niches you want to track:"
 	msg := tgbotapi.NewMessage(message.Chat.ID, text)
 	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = createNichesKeyboard(currentNiches)
+	msg.ReplyMarkup = createNichesKeyboard(currentNiches, b.nicheLabels(telegramID))
 	b.api.Send(msg)
 }
 
@@ -88,6 +123,11 @@ func (b *Bot) handleTrending(message *tgbotapi.Message) {
 	telegramID := message.From.ID
 
 	user, err := b.storage.GetUser(telegramID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+		b.api.Send(msg)
+		return
+	}
 	if err != nil {
 		log.Printf("Error getting user: %v", err)
 		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
@@ -95,15 +135,15 @@ func (b *Bot) handleTrending(message *tgbotapi.Message) {
 		return
 	}
 
-	if user == nil {
-		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+	niches := GetUserNiches(user)
+	if len(niches) == 0 {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "You haven't selected any niches yet. Use /niches to choose your interests.")
 		b.api.Send(msg)
 		return
 	}
-
-	niches := GetUserNiches(user)
+	niches = b.AlertNiches(user)
 	if len(niches) == 0 {
-		msg := tgbotapi.NewMessage(message.Chat.ID, "You haven't selected any niches yet. Use /niches to choose your interests.")
+		msg := tgbotapi.NewMessage(message.Chat.ID, "All your niches are muted. Use /unmute_niche <niche> to hear from one again.")
 		b.api.Send(msg)
 		return
 	}
@@ -114,16 +154,28 @@ func (b *Bot) handleTrending(message *tgbotapi.Message) {
 
 	// Get trending sounds for each niche
 	for _, niche := range niches {
-		trending, err := b.detector.DetectTrending(niche, 5)
+		if text := b.gatheringDataMessage(niche); text != "" {
+			msg := tgbotapi.NewMessage(message.Chat.ID, text)
+			b.api.Send(msg)
+			continue
+		}
+
+		criteria := b.detector.Criteria()
+		criteria.Region = user.Region
+		trending, err := b.detector.DetectTrendingWithCriteria(niche, b.ResultLimit(user), criteria)
 		if err != nil {
 			log.Printf("Error detecting trends for %s: %v", niche, err)
+			b.recordDetectFailure(niche, err)
+			msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("⚠️ %s: %s", parser.CategoryDisplayNames[niche], b.cfg.DetectErrorMessage))
+			b.api.Send(msg)
 			continue
 		}
+		b.detectFailures.Store(0)
 
 		// If no trending sounds found (no history yet), show top sounds
 		if len(trending) == 0 {
 			log.Printf("No trends for %s, showing top sounds instead", niche)
-			sounds, err := b.storage.GetSoundsByCategory(niche, 5)
+			sounds, err := b.storage.GetSoundsByCategory(niche, b.ResultLimit(user))
 			if err != nil || len(sounds) == 0 {
 				msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("No sounds found for %s yet. Try again in a few minutes!", parser.CategoryDisplayNames[niche]))
 				b.api.Send(msg)
@@ -144,9 +196,7 @@ func (b *Bot) handleTrending(message *tgbotapi.Message) {
 			message := fmt.Sprintf("🎵 *Top Sounds - %s*\n\n_Note: Trend data will be available after 24 hours_\n\n", categoryName)
 			message += formatTopSounds(topSounds)
 
-			msg := tgbotapi.NewMessage(telegramID, message)
-			msg.ParseMode = "Markdown"
-			b.api.Send(msg)
+			b.sendLongMessage(telegramID, message, "Markdown")
 			continue
 		}
 
@@ -158,12 +208,16 @@ func (b *Bot) handleTrending(message *tgbotapi.Message) {
 func (b *Bot) handleCallbackQuery(callback *tgbotapi.CallbackQuery) {
 	telegramID := callback.From.ID
 
+	if b.isBlocked(telegramID) {
+		return
+	}
+
 	// Answer callback to remove loading state
 	callbackConfig := tgbotapi.NewCallback(callback.ID, "")
 	b.api.Request(callbackConfig)
 
 	// Parse callback data
-	// Format: "niche:fitness" or "niche_done"
+	// Format: "niche:fitness", "niche_done", "niche_all" or "niche_clear"
 	parts := strings.Split(callback.Data, ":")
 
 	if parts[0] == "niche_done" {
@@ -172,8 +226,25 @@ func (b *Bot) handleCallbackQuery(callback *tgbotapi.CallbackQuery) {
 		return
 	}
 
+	if parts[0] == "niche_all" || parts[0] == "niche_clear" {
+		b.handleBulkNiches(callback, parts[0] == "niche_all")
+		return
+	}
+
 	// Handle premium activation
 	if parts[0] == "premium" && len(parts) == 2 && parts[1] == "activate" {
+		// Free activation is only offered while no payment provider is configured
+		if b.paymentsEnabled() {
+			msg := tgbotapi.NewMessage(callback.Message.Chat.ID, "Free activation has ended. Use /premium to upgrade.")
+			b.api.Send(msg)
+			return
+		}
+
+		if _, err := b.ensureUser(telegramID); err != nil {
+			log.Printf("Error getting user: %v", err)
+			return
+		}
+
 		// Activate premium for MVP testing
 		err := b.storage.SetPremium(telegramID, true)
 		if err != nil {
@@ -198,14 +269,16 @@ func (b *Bot) handleCallbackQuery(callback *tgbotapi.CallbackQuery) {
 
 	niche := parts[1]
 
-	// Get user
-	user, err := b.storage.GetUser(telegramID)
-	if err != nil {
-		log.Printf("Error getting user: %v", err)
+	// Ignore stale or crafted callbacks for unknown niches
+	if !parser.IsValidCategory(niche) {
+		log.Printf("Ignoring unknown niche in callback from %d: %q", telegramID, niche)
 		return
 	}
 
-	if user == nil {
+	// Get user, registering them if the keyboard reached them without /start
+	user, err := b.ensureUser(telegramID)
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
 		return
 	}
 
@@ -225,22 +298,75 @@ func (b *Bot) handleCallbackQuery(callback *tgbotapi.CallbackQuery) {
 	editMsg := tgbotapi.NewEditMessageReplyMarkup(
 		callback.Message.Chat.ID,
 		callback.Message.MessageID,
-		createNichesKeyboard(newNiches),
+		createNichesKeyboard(newNiches, b.nicheLabels(telegramID)),
+	)
+	b.editKeyboard(editMsg)
+}
+
+// handleBulkNiches selects or clears every niche at once for premium users; free users get an upsell
+func (b *Bot) handleBulkNiches(callback *tgbotapi.CallbackQuery, selectAll bool) {
+	telegramID := callback.From.ID
+
+	user, err := b.ensureUser(telegramID)
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		return
+	}
+
+	if !user.IsPremium {
+		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, "💎 Selecting all niches at once is a Premium feature. Use /premium to upgrade!")
+		b.api.Send(msg)
+		return
+	}
+
+	newNiches := bulkNiches(selectAll)
+	if err := b.storage.UpdateUserNiches(telegramID, SetUserNiches(newNiches)); err != nil {
+		log.Printf("Error updating user niches: %v", err)
+		return
+	}
+
+	editMsg := tgbotapi.NewEditMessageReplyMarkup(
+		callback.Message.Chat.ID,
+		callback.Message.MessageID,
+		createNichesKeyboard(newNiches, b.nicheLabels(telegramID)),
 	)
-	b.api.Send(editMsg)
+	b.editKeyboard(editMsg)
+}
+
+// bulkNiches returns every niche when selectAll is set, otherwise none
+func bulkNiches(selectAll bool) []string {
+	if !selectAll {
+		return nil
+	}
+	niches := make([]string, len(parser.Categories))
+	copy(niches, parser.Categories)
+	return niches
+}
+
+// ensureUser returns the user, creating them first if they never ran /start
+// (e.g. a keyboard reached them through a forwarded message or deep link)
+func (b *Bot) ensureUser(telegramID int64) (*storage.User, error) {
+	user, err := b.storage.GetUser(telegramID)
+	if !errors.Is(err, storage.ErrUserNotFound) {
+		return user, err
+	}
+
+	if err := b.storage.CreateUser(telegramID); err != nil && !errors.Is(err, storage.ErrDuplicate) {
+		return nil, err
+	}
+	log.Printf("Auto-created user %d from a callback without /start", telegramID)
+
+	return b.storage.GetUser(telegramID)
 }
 
-// createNichesKeyboard creates an inline keyboard for niche selection
-func createNichesKeyboard(selectedNiches []string) tgbotapi.InlineKeyboardMarkup {
+// createNichesKeyboard creates an inline keyboard for niche selection, showing the user's labels
+func createNichesKeyboard(selectedNiches []string, labels map[string]string) tgbotapi.InlineKeyboardMarkup {
 	var rows [][]tgbotapi.InlineKeyboardButton
 
 	// Create button for each niche (2 per row)
 	var currentRow []tgbotapi.InlineKeyboardButton
 	for i, category := range parser.Categories {
-		displayName := parser.CategoryDisplayNames[category]
-		if displayName == "" {
-			displayName = category
-		}
+		displayName := nicheDisplayName(category, labels)
 
 		// Add checkmark if selected
 		if contains(selectedNiches, category) {
@@ -257,6 +383,12 @@ func createNichesKeyboard(selectedNiches []string) tgbotapi.InlineKeyboardMarkup
 		}
 	}
 
+	// Bulk selection buttons (Premium)
+	rows = append(rows, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("☑️ Select All", "niche_all"),
+		tgbotapi.NewInlineKeyboardButtonData("✖️ Clear All", "niche_clear"),
+	})
+
 	// Add "Done" button
 	doneButton := tgbotapi.NewInlineKeyboardButtonData("✅ Done", "niche_done")
 	rows = append(rows, []tgbotapi.InlineKeyboardButton{doneButton})
@@ -303,21 +435,50 @@ func formatTopSounds(sounds []storage.TrendingSound) string {
 	return message
 }
 
+// handleInlineQuery answers "share <sound id>" inline queries created by the Share buttons on alerts
+func (b *Bot) handleInlineQuery(query *tgbotapi.InlineQuery) {
+	answer := tgbotapi.InlineConfig{
+		InlineQueryID: query.ID,
+		Results:       []interface{}{},
+		CacheTime:     60,
+	}
+
+	idText := strings.TrimSpace(strings.TrimPrefix(query.Query, strings.TrimSpace(shareQueryPrefix)))
+	soundID, err := strconv.ParseInt(idText, 10, 64)
+	if err == nil {
+		sound, err := b.storage.GetSoundByID(soundID)
+		switch {
+		case errors.Is(err, storage.ErrSoundNotFound):
+			answer.SwitchPMText = "Sound not found - open the bot"
+			answer.SwitchPMParameter = "start"
+		case err != nil:
+			log.Printf("Error getting sound %d for share: %v", soundID, err)
+		default:
+			result := tgbotapi.NewInlineQueryResultArticleMarkdown(idText, sound.Title, formatShareText(*sound, b.theme))
+			result.Description = fmt.Sprintf("%s uses", formatNumber(sound.UsesCount))
+			answer.Results = append(answer.Results, result)
+		}
+	}
+
+	if _, err := b.api.Request(answer); err != nil {
+		log.Printf("Error answering inline query: %v", err)
+	}
+}
+
 // handlePremium handles the /premium command
 func (b *Bot) handlePremium(message *tgbotapi.Message) {
 	telegramID := message.From.ID
 
 	user, err := b.storage.GetUser(telegramID)
-	if err != nil {
-		log.Printf("Error getting user: %v", err)
-		return
-	}
-
-	if user == nil {
+	if errors.Is(err, storage.ErrUserNotFound) {
 		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first.")
 		b.api.Send(msg)
 		return
 	}
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		return
+	}
 
 	if user.IsPremium {
 		text := `✨ You already have Premium!
@@ -345,9 +506,19 @@ Get unlimited access:
 ✅ Priority notifications
 ✅ 30 days history
 
-💰 Price: $4.99/month
+💰 Price: ` + formatPrice(b.cfg.PremiumPrice, b.cfg.PremiumCurrency) + `/month`
+
+	if b.paymentsEnabled() {
+		msg := tgbotapi.NewMessage(message.Chat.ID, text)
+		b.api.Send(msg)
+
+		if _, err := b.api.Send(b.premiumInvoice(message.Chat.ID)); err != nil {
+			log.Printf("Error sending premium invoice: %v", err)
+		}
+		return
+	}
 
-For MVP testing, use /premium_activate to activate for free!`
+	text += "\n\nFor MVP testing, activate it for free below!"
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
@@ -365,7 +536,7 @@ func (b *Bot) handleStats(message *tgbotapi.Message) {
 	telegramID := message.From.ID
 
 	user, err := b.storage.GetUser(telegramID)
-	if err != nil || user == nil {
+	if err != nil {
 		return
 	}
 
@@ -380,11 +551,21 @@ func (b *Bot) handleStats(message *tgbotapi.Message) {
 		status = "Premium 💎"
 	}
 
-	// Get total trending sounds count (example)
+	// Count trending sounds from the precomputed scores, capped at 10 per niche
 	totalTrending := 0
 	for _, niche := range niches {
-		trending, _ := b.detector.DetectTrending(niche, 10)
-		totalTrending += len(trending)
+		totalTrending += b.trendingCount(niche, 10)
+	}
+
+	// The score line is left out when it could not be computed
+	scoreText := b.trendScoreSummary(user, niches)
+	if scoreText != "" {
+		scoreText += "\n"
+	}
+
+	topNicheText := "🎯 Top niche: not enough activity yet"
+	if top := b.topEngagedNiche(telegramID); top != "" {
+		topNicheText = "🎯 Top niche: " + parser.CategoryDisplayNames[top]
 	}
 
 	text := fmt.Sprintf(`📊 Your Statistics
@@ -392,6 +573,7 @@ func (b *Bot) handleStats(message *tgbotapi.Message) {
 👤 Status: %s
 🎯 Niches: %s
 🔥 Trending now: %d sounds
+%s%s
 
 Join date: %s
 
@@ -399,8 +581,1987 @@ Use /premium to upgrade!`,
 		status,
 		nichesText,
 		totalTrending,
+		scoreText,
+		topNicheText,
 		user.CreatedAt.Format("Jan 02, 2006"))
 
 	msg := tgbotapi.NewMessage(message.Chat.ID, text)
 	b.api.Send(msg)
 }
+
+// handleStatus shows the user's current settings
+func (b *Bot) handleStatus(message *tgbotapi.Message) {
+	telegramID := message.From.ID
+
+	user, err := b.storage.GetUser(telegramID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, formatStatus(user))
+	b.api.Send(msg)
+}
+
+// formatStatus formats the user's settings for the /status command
+func formatStatus(user *storage.User) string {
+	niches := GetUserNiches(user)
+	nichesText := "None"
+	if len(niches) > 0 {
+		var names []string
+		for _, niche := range niches {
+			name := parser.CategoryDisplayNames[niche]
+			if name == "" {
+				name = niche
+			}
+			names = append(names, name)
+		}
+		nichesText = strings.Join(names, ", ")
+	}
+
+	status := "Free"
+	if user.IsPremium {
+		status = "Premium 💎"
+		if !user.PremiumExpiresAt.IsZero() {
+			status += " until " + user.PremiumExpiresAt.In(UserLocation(user)).Format("Jan 02, 2006")
+		}
+	}
+
+	region := user.Region
+	if storage.IsGlobalRegion(region) {
+		region = storage.RegionGlobal
+	}
+
+	return fmt.Sprintf(`⚙️ Your Settings
+
+🎯 Niches: %s
+👤 Plan: %s
+📈 Alert mode: %s
+🔀 Alert order: %s
+📝 Alert format: %s
+📬 Delivery: %s
+🌍 Region: %s
+🕒 Timezone: %s
+
+Use /niches to change your niches.`,
+		nichesText,
+		status,
+		user.AlertMode,
+		user.AlertSort,
+		user.AlertFormat,
+		user.DigestFrequency,
+		region,
+		timezoneName(user))
+}
+
+// handlePauseAlerts handles the admin /pausealerts and /resumealerts commands
+func (b *Bot) handlePauseAlerts(message *tgbotapi.Message, pause bool) {
+	if !b.isAdmin(message.From.ID) {
+		return
+	}
+
+	value := "false"
+	text := "▶️ Alerts resumed."
+	if pause {
+		value = "true"
+		text = "⏸ All alerts paused. Use /resumealerts to turn them back on."
+	}
+
+	if err := b.storage.SetSetting(storage.SettingAlertsPaused, value); err != nil {
+		log.Printf("Error updating alerts pause flag: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	log.Printf("Admin %d set alerts_paused=%s", message.From.ID, value)
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	b.api.Send(msg)
+}
+
+// trendingCount returns how many sounds (up to limit) are trending in a niche, reading precomputed
+// scores and falling back to live detection before the first scores are computed
+func (b *Bot) trendingCount(niche string, limit int) int {
+	scores, err := b.storage.GetTrendScores(niche)
+	if err != nil {
+		log.Printf("Error getting trend scores for %s: %v", niche, err)
+	}
+	if err != nil || len(scores) == 0 {
+		trending, _ := b.detector.DetectTrending(niche, limit)
+		return len(trending)
+	}
+
+	count := 0
+	for _, score := range scores {
+		if score.Trending {
+			count++
+		}
+	}
+	if count > limit {
+		count = limit
+	}
+	return count
+}
+
+// trendScoreSummary renders the user's weekly trend score and how it ranks against other users
+func (b *Bot) trendScoreSummary(user *storage.User, niches []string) string {
+	if len(niches) == 0 {
+		return "🏆 Trend score: select niches with /niches to get one"
+	}
+
+	// Compute each category score once and reuse it for every user
+	categoryScores := make(map[string]float64)
+	for _, category := range parser.Categories {
+		score, err := b.detector.CategoryTrendScore(category)
+		if err != nil {
+			log.Printf("Error computing trend score for %s: %v", category, err)
+			return ""
+		}
+		categoryScores[category] = score
+	}
+
+	userScore := sumScores(categoryScores, niches)
+
+	users, err := b.storage.GetAllUsers()
+	if err != nil {
+		log.Printf("Error getting users: %v", err)
+		return fmt.Sprintf("🏆 Trend score: %.0f", userScore)
+	}
+
+	var allScores []float64
+	for i := range users {
+		allScores = append(allScores, sumScores(categoryScores, GetUserNiches(&users[i])))
+	}
+
+	percentile := scorePercentile(userScore, allScores)
+	return fmt.Sprintf("🏆 Trend score: %.0f (top %.0f%%)\n%s", userScore, 100-percentile, motivationLine(percentile))
+}
+
+// sumScores adds up category scores for the given niches
+func sumScores(categoryScores map[string]float64, niches []string) float64 {
+	var total float64
+	for _, niche := range niches {
+		total += categoryScores[niche]
+	}
+	return total
+}
+
+// scorePercentile returns the percentage of scores strictly below the given score
+func scorePercentile(score float64, all []float64) float64 {
+	if len(all) == 0 {
+		return 0
+	}
+	below := 0
+	for _, s := range all {
+		if s < score {
+			below++
+		}
+	}
+	return float64(below) / float64(len(all)) * 100
+}
+
+// motivationLine picks an encouraging message based on the rank percentile
+func motivationLine(percentile float64) string {
+	switch {
+	case percentile >= 90:
+		return "🚀 Your niches are on fire this week!"
+	case percentile >= 60:
+		return "📈 Your niches are heating up!"
+	case percentile >= 30:
+		return "🎵 Steady week in your niches."
+	default:
+		return "🌱 Quiet week - try adding more niches with /niches"
+	}
+}
+
+// handlePreview handles the /preview <niche> command for sampling any niche
+func (b *Bot) handlePreview(message *tgbotapi.Message) {
+	telegramID := message.From.ID
+
+	user, err := b.storage.GetUser(telegramID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	niche, errText := parseNicheArg(message.CommandArguments())
+	if errText != "" {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Usage: /preview <niche>\n\n"+errText)
+		b.api.Send(msg)
+		return
+	}
+
+	if err := b.storage.RecordNicheEngagement(telegramID, niche); err != nil {
+		log.Printf("Error recording niche engagement: %v", err)
+	}
+
+	trending, err := b.detector.DetectTrending(niche, b.ResultLimit(user))
+	if err != nil {
+		log.Printf("Error detecting trends for %s: %v", niche, err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	if len(trending) == 0 {
+		text := b.gatheringDataMessage(niche)
+		if text == "" {
+			text = fmt.Sprintf("No trending sounds in %s right now. Try again later!", parser.CategoryDisplayNames[niche])
+		}
+		msg := tgbotapi.NewMessage(message.Chat.ID, text)
+		b.api.Send(msg)
+		return
+	}
+
+	b.SendTrendingAlert(message.Chat.ID, niche, trending)
+}
+
+// handleVs handles the /vs <niche> <niche> command comparing trend activity in two niches
+func (b *Bot) handleVs(message *tgbotapi.Message) {
+	usage := "Usage: /vs <niche> <niche>, e.g. /vs fitness beauty"
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) != 2 {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("%s\n\nValid niches: %s", usage, strings.Join(parser.Categories, ", ")))
+		b.api.Send(msg)
+		return
+	}
+
+	var niches [2]string
+	for i, arg := range args {
+		niche, errText := parseNicheArg(arg)
+		if errText != "" {
+			msg := tgbotapi.NewMessage(message.Chat.ID, usage+"\n\n"+errText)
+			b.api.Send(msg)
+			return
+		}
+		niches[i] = niche
+	}
+	if niches[0] == niches[1] {
+		msg := tgbotapi.NewMessage(message.Chat.ID, usage+"\n\nPick two different niches.")
+		b.api.Send(msg)
+		return
+	}
+
+	var analyses [2]*detector.TrendAnalysis
+	for i, niche := range niches {
+		analysis, err := b.detector.AnalyzeTrends(niche)
+		if err != nil {
+			log.Printf("Error analyzing trends for %s: %v", niche, err)
+			msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+			b.api.Send(msg)
+			return
+		}
+		analyses[i] = analysis
+	}
+
+	text := formatComparison(analyses[0], analyses[1], b.nicheLabels(message.From.ID))
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	b.api.Send(msg)
+}
+
+// formatComparison formats two niches' trend analyses side by side for /vs
+func formatComparison(a, b *detector.TrendAnalysis, labels map[string]string) string {
+	nameA := nicheDisplayName(a.Category, labels)
+	nameB := nicheDisplayName(b.Category, labels)
+
+	text := fmt.Sprintf("⚖️ %s vs %s\n\n", nameA, nameB)
+	text += fmt.Sprintf("📈 Trending sounds: %d vs %d\n", a.TrendingCount, b.TrendingCount)
+	text += fmt.Sprintf("🚀 Average growth: +%.0f%% vs +%.0f%%\n", a.AverageGrowth, b.AverageGrowth)
+	text += "\n🏆 Top sound\n"
+	text += fmt.Sprintf("%s: %s\n", nameA, comparisonTopSound(a.TopSound))
+	text += fmt.Sprintf("%s: %s\n", nameB, comparisonTopSound(b.TopSound))
+
+	// More trending sounds wins; average growth breaks a tie
+	switch {
+	case a.TrendingCount == 0 && b.TrendingCount == 0:
+		text += "\nNeither niche has trending sounds right now."
+	case a.TrendingCount > b.TrendingCount, a.TrendingCount == b.TrendingCount && a.AverageGrowth > b.AverageGrowth:
+		text += fmt.Sprintf("\n👉 %s is hotter right now.", nameA)
+	case a.TrendingCount < b.TrendingCount, a.AverageGrowth < b.AverageGrowth:
+		text += fmt.Sprintf("\n👉 %s is hotter right now.", nameB)
+	default:
+		text += "\n👉 Both niches are about even."
+	}
+	return text
+}
+
+// comparisonTopSound describes a niche's top sound in one line for /vs
+func comparisonTopSound(ts *storage.TrendingSound) string {
+	if ts == nil {
+		return "none"
+	}
+	text := fmt.Sprintf("%q", ts.Title)
+	if ts.Author != "" {
+		text += " by " + ts.Author
+	}
+	if ts.IsNew {
+		return text + " (new)"
+	}
+	return text + fmt.Sprintf(" (+%.0f%%)", ts.GrowthPercent)
+}
+
+// graduatesLimit is how many sounds /graduates lists
+const graduatesLimit = 10
+
+// handleGraduates handles the /graduates [niche] command listing sounds that recently outgrew the trending range
+func (b *Bot) handleGraduates(message *tgbotapi.Message) {
+	category := ""
+	if arg := message.CommandArguments(); arg != "" {
+		niche, errText := parseNicheArg(arg)
+		if errText != "" {
+			msg := tgbotapi.NewMessage(message.Chat.ID, "Usage: /graduates [niche]\n\n"+errText)
+			b.api.Send(msg)
+			return
+		}
+		category = niche
+	}
+
+	graduates, err := b.detector.Graduates(category, graduatesLimit)
+	if err != nil {
+		log.Printf("Error getting graduates: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	maxUses := formatNumber(b.detector.Criteria().MaxUsesCount)
+	if len(graduates) == 0 {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("No sounds passed %s uses this week. Check back later!", maxUses))
+		b.api.Send(msg)
+		return
+	}
+
+	labels := b.nicheLabels(message.From.ID)
+	text := fmt.Sprintf("🎓 Graduates: sounds that passed %s uses this week\n\n", maxUses)
+	for i, g := range graduates {
+		title := fmt.Sprintf("%q", g.Sound.Title)
+		if g.Sound.Author != "" {
+			title += " by " + g.Sound.Author
+		}
+		text += fmt.Sprintf("%d. %s\n   %s · %s → %s uses · passed %s\n   %s\n\n",
+			i+1, title, nicheDisplayName(g.Sound.Category, labels),
+			formatNumber(g.UsesBefore), formatNumber(g.Sound.UsesCount), g.CrossedAt.Format("Jan 02"), g.Sound.URL)
+	}
+	text += "Catch the next ones early with /trending."
+
+	b.sendLongMessage(message.Chat.ID, text, "")
+}
+
+// parseNicheArg validates a niche command argument.
+// It returns the niche, or a user-facing error listing the valid options.
+func parseNicheArg(arg string) (string, string) {
+	if category, ok := parser.ResolveCategory(arg); ok {
+		return category, ""
+	}
+
+	niche := strings.ToLower(strings.TrimSpace(arg))
+
+	var text string
+	if niche == "" {
+		text = "Please specify a niche."
+	} else {
+		text = fmt.Sprintf("Unknown niche %q.", niche)
+	}
+	return "", text + " Valid niches: " + strings.Join(parser.Categories, ", ")
+}
+
+// topEngagedNiche returns the valid niche the user engaged with most, or "" if there is no data
+func (b *Bot) topEngagedNiche(telegramID int64) string {
+	engagement, err := b.storage.GetUserNicheEngagement(telegramID)
+	if err != nil {
+		log.Printf("Error getting niche engagement: %v", err)
+		return ""
+	}
+	for _, e := range engagement {
+		if parser.IsValidCategory(e.Category) {
+			return e.Category
+		}
+	}
+	return ""
+}
+
+// PrioritizeNiches moves the user's most engaged niche to the front for premium users
+func (b *Bot) PrioritizeNiches(user *storage.User, niches []string) []string {
+	if !user.IsPremium {
+		return niches
+	}
+
+	top := b.topEngagedNiche(user.TelegramID)
+	if top == "" || !contains(niches, top) {
+		return niches
+	}
+
+	ordered := []string{top}
+	for _, niche := range niches {
+		if niche != top {
+			ordered = append(ordered, niche)
+		}
+	}
+	return ordered
+}
+
+// handlePopularAuthors handles the /popularauthors [niche] command
+func (b *Bot) handlePopularAuthors(message *tgbotapi.Message) {
+	category := ""
+	if arg := message.CommandArguments(); arg != "" {
+		niche, errText := parseNicheArg(arg)
+		if errText != "" {
+			msg := tgbotapi.NewMessage(message.Chat.ID, "Usage: /popularauthors [niche]\n\n"+errText)
+			b.api.Send(msg)
+			return
+		}
+		category = niche
+	}
+
+	authors, err := b.storage.GetTopAuthors(category, 10)
+	if err != nil {
+		log.Printf("Error getting top authors: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, formatAuthorLeaderboard(category, authors))
+	b.api.Send(msg)
+}
+
+// formatAuthorLeaderboard formats the author leaderboard message
+func formatAuthorLeaderboard(category string, authors []storage.AuthorStat) string {
+	scope := "All niches"
+	if category != "" {
+		scope = parser.CategoryDisplayNames[category]
+	}
+
+	if len(authors) == 0 {
+		return fmt.Sprintf("No sounds collected for %s yet. Try again later!", scope)
+	}
+
+	text := fmt.Sprintf("🎤 Top Sound Creators - %s\n\n", scope)
+	for i, a := range authors {
+		text += fmt.Sprintf("%d. %s - %s uses across %d sounds\n", i+1, a.Author, formatNumber(a.TotalUses), a.SoundCount)
+	}
+	return text
+}
+
+// handleMode handles the /mode [growth|popularity] command choosing what drives alerts
+func (b *Bot) handleMode(message *tgbotapi.Message) {
+	telegramID := message.From.ID
+
+	user, err := b.storage.GetUser(telegramID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	usage := fmt.Sprintf("Usage: /mode <%s|%s>\n\n%s - sounds growing fastest\n%s - most-used sounds right now",
+		storage.AlertModeGrowth, storage.AlertModePopularity, storage.AlertModeGrowth, storage.AlertModePopularity)
+
+	mode := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+	if mode == "" {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Your alerts are %s-based.\n\n%s", user.AlertMode, usage))
+		b.api.Send(msg)
+		return
+	}
+	if mode != storage.AlertModeGrowth && mode != storage.AlertModePopularity {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Unknown mode %q.\n\n%s", mode, usage))
+		b.api.Send(msg)
+		return
+	}
+
+	if err := b.storage.SetAlertMode(telegramID, mode); err != nil {
+		log.Printf("Error setting alert mode: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("✅ Your alerts are now %s-based.", mode))
+	b.api.Send(msg)
+}
+
+// handleSort handles the /sort <growth|uses|recent> command choosing the order of sounds in alerts
+func (b *Bot) handleSort(message *tgbotapi.Message) {
+	telegramID := message.From.ID
+
+	user, err := b.storage.GetUser(telegramID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	usage := fmt.Sprintf("Usage: /sort <%s|%s|%s>\n\n%s - fastest-growing first\n%s - most-used first\n%s - newest sounds first",
+		storage.AlertSortGrowth, storage.AlertSortUses, storage.AlertSortRecent,
+		storage.AlertSortGrowth, storage.AlertSortUses, storage.AlertSortRecent)
+
+	order := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+	if order == "" {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Your alerts are sorted by %s.\n\n%s", user.AlertSort, usage))
+		b.api.Send(msg)
+		return
+	}
+	if order != storage.AlertSortGrowth && order != storage.AlertSortUses && order != storage.AlertSortRecent {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Unknown order %q.\n\n%s", order, usage))
+		b.api.Send(msg)
+		return
+	}
+
+	if err := b.storage.SetAlertSort(telegramID, order); err != nil {
+		log.Printf("Error setting alert sort: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("✅ Your alerts are now sorted by %s.", order))
+	b.api.Send(msg)
+}
+
+// handleBoost handles the /boost [off] command temporarily lowering the user's growth threshold
+func (b *Bot) handleBoost(message *tgbotapi.Message) {
+	telegramID := message.From.ID
+
+	user, err := b.storage.GetUser(telegramID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	now := time.Now()
+	switch arg := strings.ToLower(strings.TrimSpace(message.CommandArguments())); arg {
+	case "":
+	case "off":
+		if !Boosted(user, now) {
+			msg := tgbotapi.NewMessage(message.Chat.ID, "No boost is active.")
+			b.api.Send(msg)
+			return
+		}
+		if err := b.storage.SetBoostUntil(telegramID, time.Time{}); err != nil {
+			log.Printf("Error ending boost: %v", err)
+			msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+			b.api.Send(msg)
+			return
+		}
+		msg := tgbotapi.NewMessage(message.Chat.ID, "✅ Boost ended. Alerts use your usual threshold again.")
+		b.api.Send(msg)
+		return
+	default:
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Usage: /boost to catch earlier trends for a while, /boost off to stop.")
+		b.api.Send(msg)
+		return
+	}
+
+	// Running /boost again restarts the period rather than stacking
+	until := now.Add(b.cfg.BoostDuration)
+	if err := b.storage.SetBoostUntil(telegramID, until); err != nil {
+		log.Printf("Error starting boost: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	minGrowth := MinGrowthFor(user, 0, b.detector.Criteria().MinGrowth)
+	user.BoostUntil = until
+	text := fmt.Sprintf("🚀 Boost on until %s (server time): alerts include sounds from +%.0f%% growth instead of +%.0f%%. It turns off by itself, or use /boost off.",
+		until.Format("Jan 2 15:04"), BoostedMinGrowth(user, minGrowth, b.cfg.BoostFactor, now), minGrowth)
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	b.api.Send(msg)
+}
+
+// handleMinUses handles the /minuses <count|reset> command setting the minimum uses for alerts
+func (b *Bot) handleMinUses(message *tgbotapi.Message) {
+	telegramID := message.From.ID
+
+	user, err := b.storage.GetUser(telegramID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	criteria := b.detector.Criteria()
+	usage := fmt.Sprintf("Usage: /minuses <count|reset>, e.g. /minuses 5000\n\nCounts are kept between %s and %s.",
+		formatNumber(criteria.MinUsesCount), formatNumber(criteria.MaxUsesCount))
+
+	arg := strings.TrimSpace(message.CommandArguments())
+	if arg == "" {
+		text := fmt.Sprintf("📊 Alerts only include sounds with at least %s uses.\n\n%s",
+			formatNumber(MinUsesFor(user, criteria.MinUsesCount, criteria.MaxUsesCount)), usage)
+		msg := tgbotapi.NewMessage(message.Chat.ID, text)
+		b.api.Send(msg)
+		return
+	}
+
+	// 0 clears the floor so the detector default applies
+	var minUses int64
+	if !strings.EqualFold(arg, "reset") {
+		minUses, err = strconv.ParseInt(arg, 10, 64)
+		if err != nil || minUses < 0 {
+			msg := tgbotapi.NewMessage(message.Chat.ID, "Please send a whole number of uses.\n\n"+usage)
+			b.api.Send(msg)
+			return
+		}
+	}
+
+	// Out-of-range counts are clamped; at the default there is nothing to store
+	floor := MinUsesFor(&storage.User{MinUses: minUses}, criteria.MinUsesCount, criteria.MaxUsesCount)
+	stored := floor
+	if floor == criteria.MinUsesCount {
+		stored = 0
+	}
+
+	if err := b.storage.SetUserMinUses(telegramID, stored); err != nil {
+		log.Printf("Error setting min uses: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("✅ Alerts will only include sounds with at least %s uses.", formatNumber(floor)))
+	b.api.Send(msg)
+}
+
+// handleFormat handles the /format command choosing between detailed and compact alerts
+func (b *Bot) handleFormat(message *tgbotapi.Message) {
+	telegramID := message.From.ID
+
+	user, err := b.storage.GetUser(telegramID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	usage := fmt.Sprintf("Usage: /format <%s|%s|%s>\n\n%s - several lines per sound with all details\n%s - one line per sound\n%s - detailed, grouped by how fast sounds grow",
+		storage.AlertFormatDetailed, storage.AlertFormatCompact, storage.AlertFormatTiered,
+		storage.AlertFormatDetailed, storage.AlertFormatCompact, storage.AlertFormatTiered)
+
+	format := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+	if format == "" {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Your alerts use the %s format.\n\n%s", user.AlertFormat, usage))
+		b.api.Send(msg)
+		return
+	}
+	if format != storage.AlertFormatDetailed && format != storage.AlertFormatCompact && format != storage.AlertFormatTiered {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Unknown format %q.\n\n%s", format, usage))
+		b.api.Send(msg)
+		return
+	}
+
+	if err := b.storage.SetAlertFormat(telegramID, format); err != nil {
+		log.Printf("Error setting alert format: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("✅ Your alerts now use the %s format.", format))
+	b.api.Send(msg)
+}
+
+// handleFrequency handles the /frequency <every|twice_daily|daily> command choosing how often alerts are delivered
+func (b *Bot) handleFrequency(message *tgbotapi.Message) {
+	telegramID := message.From.ID
+
+	user, err := b.storage.GetUser(telegramID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	usage := fmt.Sprintf("Usage: /frequency <%s|%s|%s>\n\n%s - a message per niche every alert round\n%s - everything in one message every 12 hours\n%s - everything in one message once a day",
+		storage.DigestEvery, storage.DigestTwiceDaily, storage.DigestDaily,
+		storage.DigestEvery, storage.DigestTwiceDaily, storage.DigestDaily)
+
+	frequency := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+	if frequency == "" {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Your alerts are delivered: %s.\n\n%s", user.DigestFrequency, usage))
+		b.api.Send(msg)
+		return
+	}
+	if frequency != storage.DigestEvery && frequency != storage.DigestTwiceDaily && frequency != storage.DigestDaily {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Unknown frequency %q.\n\n%s", frequency, usage))
+		b.api.Send(msg)
+		return
+	}
+
+	if err := b.storage.SetDigestFrequency(telegramID, frequency); err != nil {
+		log.Printf("Error setting digest frequency: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("✅ Your alerts are now delivered: %s.", frequency))
+	b.api.Send(msg)
+}
+
+// handleRegion handles the /region [region] command choosing whose trends the user gets
+func (b *Bot) handleRegion(message *tgbotapi.Message) {
+	telegramID := message.From.ID
+
+	user, err := b.storage.GetUser(telegramID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	usage := fmt.Sprintf("Usage: /region <region>\n\nAvailable regions: %s", strings.Join(b.cfg.Regions, ", "))
+
+	arg := strings.TrimSpace(message.CommandArguments())
+	if arg == "" {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("You get trends for region: %s.\n\n%s", user.Region, usage))
+		b.api.Send(msg)
+		return
+	}
+
+	region := ""
+	for _, r := range b.cfg.Regions {
+		if strings.EqualFold(r, arg) {
+			region = r
+		}
+	}
+	if region == "" {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Region %q isn't collected.\n\n%s", arg, usage))
+		b.api.Send(msg)
+		return
+	}
+
+	if err := b.storage.SetUserRegion(telegramID, region); err != nil {
+		log.Printf("Error setting user region: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("✅ You'll now get trends for region: %s.", region))
+	b.api.Send(msg)
+}
+
+// maxNicheLabelLength caps custom niche names so keyboards and headers stay readable
+const maxNicheLabelLength = 32
+
+// handleLabel handles the premium /label <niche> [name] command. Without a name the label is reset.
+func (b *Bot) handleLabel(message *tgbotapi.Message) {
+	usage := fmt.Sprintf("Usage: /label <niche> <name>, e.g. /label fitness Gym 💪\nSend /label <niche> without a name to reset it.\n\nValid niches: %s", strings.Join(parser.Categories, ", "))
+
+	nicheArg, label, _ := strings.Cut(strings.TrimSpace(message.CommandArguments()), " ")
+	if nicheArg == "" {
+		msg := tgbotapi.NewMessage(message.Chat.ID, usage)
+		b.api.Send(msg)
+		return
+	}
+
+	niche, errText := parseNicheArg(nicheArg)
+	if errText != "" {
+		msg := tgbotapi.NewMessage(message.Chat.ID, errText)
+		b.api.Send(msg)
+		return
+	}
+
+	// Labels are rendered inside Markdown headers, so markup characters would break them
+	label = strings.TrimSpace(label)
+	if utf8.RuneCountInString(label) > maxNicheLabelLength || strings.ContainsAny(label, "*_`[]") {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Labels can be up to %d characters and can't contain * _ ` [ ].", maxNicheLabelLength))
+		b.api.Send(msg)
+		return
+	}
+
+	if err := b.storage.SetNicheLabel(message.From.ID, niche, label); err != nil {
+		log.Printf("Error setting niche label: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	text := fmt.Sprintf("✅ %s will now show as \"%s\".", nicheDisplayName(niche, nil), label)
+	if label == "" {
+		text = fmt.Sprintf("✅ %s label reset.", nicheDisplayName(niche, nil))
+	}
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	b.api.Send(msg)
+}
+
+// handleMuteNiche handles the /mute_niche command, silencing one subscribed niche
+func (b *Bot) handleMuteNiche(message *tgbotapi.Message) {
+	b.setNicheMuted(message, true)
+}
+
+// handleUnmuteNiche handles the /unmute_niche command
+func (b *Bot) handleUnmuteNiche(message *tgbotapi.Message) {
+	b.setNicheMuted(message, false)
+}
+
+// setNicheMuted mutes or unmutes the niche given as the command argument
+func (b *Bot) setNicheMuted(message *tgbotapi.Message, mute bool) {
+	command := "/unmute_niche"
+	if mute {
+		command = "/mute_niche"
+	}
+
+	arg := strings.TrimSpace(message.CommandArguments())
+	if arg == "" {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Usage: %s <niche>\n\nValid niches: %s", command, strings.Join(parser.Categories, ", ")))
+		b.api.Send(msg)
+		return
+	}
+
+	niche, errText := parseNicheArg(arg)
+	if errText != "" {
+		msg := tgbotapi.NewMessage(message.Chat.ID, errText)
+		b.api.Send(msg)
+		return
+	}
+
+	telegramID := message.From.ID
+	user, err := b.storage.GetUser(telegramID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	labels := b.nicheLabels(telegramID)
+	if mute && !slices.Contains(GetUserNiches(user), niche) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("You're not subscribed to %s. Use /niches to manage your niches.", nicheDisplayName(niche, labels)))
+		b.api.Send(msg)
+		return
+	}
+
+	if mute {
+		err = b.storage.MuteNiche(telegramID, niche)
+	} else {
+		err = b.storage.UnmuteNiche(telegramID, niche)
+	}
+	if err != nil {
+		log.Printf("Error updating muted niche: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	text := fmt.Sprintf("🔕 %s muted. You stay subscribed, but won't get its alerts until you send /unmute_niche %s.", nicheDisplayName(niche, labels), niche)
+	if !mute {
+		text = fmt.Sprintf("🔔 %s unmuted. Its alerts are back on.", nicheDisplayName(niche, labels))
+	}
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	b.api.Send(msg)
+}
+
+// betaFeatures describes what /beta currently unlocks
+var betaFeatures = []string{
+	"🌐 Cross-niche alerts: sounds trending in several niches are flagged and shown first",
+}
+
+// BetaEnabled reports whether experimental features are active for the user
+func BetaEnabled(user *storage.User) bool {
+	return user.BetaEnrolled
+}
+
+// handleBeta handles the /beta command toggling enrollment in experimental features
+func (b *Bot) handleBeta(message *tgbotapi.Message) {
+	telegramID := message.From.ID
+
+	user, err := b.storage.GetUser(telegramID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	enrolled := !user.BetaEnrolled
+	if err := b.storage.SetBetaEnrolled(telegramID, enrolled); err != nil {
+		log.Printf("Error setting beta enrollment: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	text := "👋 You left the beta. Send /beta again to rejoin."
+	if enrolled {
+		text = "🧪 You joined the beta! Experimental features now active for you:\n\n" +
+			strings.Join(betaFeatures, "\n") +
+			"\n\nThey may change or break. Send /beta again to leave."
+	}
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	b.api.Send(msg)
+}
+
+// handleBetaUsers handles the admin /betausers command
+func (b *Bot) handleBetaUsers(message *tgbotapi.Message) {
+	count, err := b.storage.CountBetaUsers()
+	if err != nil {
+		log.Printf("Error counting beta users: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("🧪 %d users enrolled in beta.", count))
+	b.api.Send(msg)
+}
+
+// handleLimit handles the premium /limit <count> command
+func (b *Bot) handleLimit(message *tgbotapi.Message) {
+	telegramID := message.From.ID
+
+	user, err := b.storage.GetUser(telegramID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	arg := strings.TrimSpace(message.CommandArguments())
+	if arg == "" {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("You get %d sounds per alert.\n\nUsage: /limit <%d-%d>", b.ResultLimit(user), MinAlertLimit, MaxAlertLimit))
+		b.api.Send(msg)
+		return
+	}
+
+	limit, err := strconv.Atoi(arg)
+	if err != nil {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Please send a number between %d and %d, e.g. /limit 5", MinAlertLimit, MaxAlertLimit))
+		b.api.Send(msg)
+		return
+	}
+	limit = clampAlertLimit(limit)
+
+	if err := b.storage.SetAlertLimit(telegramID, limit); err != nil {
+		log.Printf("Error setting alert limit: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("✅ You'll now get up to %d sounds per alert.", limit))
+	b.api.Send(msg)
+}
+
+// handleThreshold handles the premium /threshold [niche] <percent|reset> command.
+// Without a niche it sets the user's global minimum growth; a niche value overrides it.
+func (b *Bot) handleThreshold(message *tgbotapi.Message) {
+	telegramID := message.From.ID
+
+	user, err := b.storage.GetUser(telegramID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	thresholds, err := b.storage.GetNicheMinGrowths(telegramID)
+	if err != nil {
+		log.Printf("Error getting thresholds: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	defaultGrowth := b.detector.Criteria().MinGrowth
+	usage := fmt.Sprintf("Usage: /threshold [niche] <%.0f-%.0f|reset>, e.g. /threshold fitness 200", MinGrowthThreshold, MaxGrowthThreshold)
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		text := fmt.Sprintf("📈 Alerts need at least +%.0f%% growth.\n", MinGrowthFor(user, 0, defaultGrowth))
+		for _, niche := range parser.Categories {
+			if growth, ok := thresholds[niche]; ok {
+				text += fmt.Sprintf("%s: +%.0f%%\n", nicheDisplayName(niche, nil), growth)
+			}
+		}
+		msg := tgbotapi.NewMessage(message.Chat.ID, text+"\n"+usage)
+		b.api.Send(msg)
+		return
+	}
+	if len(args) > 2 {
+		msg := tgbotapi.NewMessage(message.Chat.ID, usage)
+		b.api.Send(msg)
+		return
+	}
+
+	niche := ""
+	if len(args) == 2 {
+		var errText string
+		niche, errText = parseNicheArg(args[0])
+		if errText != "" {
+			msg := tgbotapi.NewMessage(message.Chat.ID, usage+"\n\n"+errText)
+			b.api.Send(msg)
+			return
+		}
+	}
+
+	// 0 clears the override so the next level applies
+	var growth float64
+	if value := strings.TrimSuffix(args[len(args)-1], "%"); !strings.EqualFold(value, "reset") {
+		growth, err = strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(growth) || growth < MinGrowthThreshold || growth > MaxGrowthThreshold {
+			msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Please send a percent between %.0f and %.0f.\n\n%s", MinGrowthThreshold, MaxGrowthThreshold, usage))
+			b.api.Send(msg)
+			return
+		}
+	}
+
+	if niche == "" {
+		err = b.storage.SetUserMinGrowth(telegramID, growth)
+	} else {
+		err = b.storage.SetNicheMinGrowth(telegramID, niche, growth)
+	}
+	if err != nil {
+		log.Printf("Error setting threshold: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	// Show the value that now applies, after falling back through the levels
+	if niche == "" {
+		user.MinGrowth = growth
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("✅ Alerts now need at least +%.0f%% growth.", MinGrowthFor(user, 0, defaultGrowth)))
+		b.api.Send(msg)
+		return
+	}
+	msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("✅ %s alerts now need at least +%.0f%% growth.", nicheDisplayName(niche, b.nicheLabels(telegramID)), MinGrowthFor(user, growth, defaultGrowth)))
+	b.api.Send(msg)
+}
+
+// handleRepeats handles the premium /repeats command toggling sounds already alerted within the dedup window
+func (b *Bot) handleRepeats(message *tgbotapi.Message) {
+	telegramID := message.From.ID
+
+	user, err := b.storage.GetUser(telegramID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	show := !user.ShowRepeats
+	if err := b.storage.SetShowRepeats(telegramID, show); err != nil {
+		log.Printf("Error setting show repeats: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	text := "🔁 You'll now also get sounds that were already alerted recently."
+	if !show {
+		text = "✅ You'll only get sounds that are newly trending."
+	}
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	b.api.Send(msg)
+}
+
+// InstantAlertsEnabled reports whether the user is alerted right after each collection instead of on the
+// alert schedule. It needs Premium and is off for digest users, who asked for batched delivery.
+func InstantAlertsEnabled(user *storage.User) bool {
+	return user.IsPremium && user.InstantAlerts && (user.DigestFrequency == "" || user.DigestFrequency == storage.DigestEvery)
+}
+
+// handleInstant handles the premium /instant command toggling alerts right after fresh data arrives
+func (b *Bot) handleInstant(message *tgbotapi.Message) {
+	telegramID := message.From.ID
+
+	user, err := b.storage.GetUser(telegramID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	instant := !user.InstantAlerts
+	if err := b.storage.SetInstantAlerts(telegramID, instant); err != nil {
+		log.Printf("Error setting instant alerts: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	text := "⚡ Instant alerts on: you'll hear about new trends in your niches as soon as fresh data is collected, instead of every 6 hours."
+	if user.DigestFrequency == storage.DigestTwiceDaily || user.DigestFrequency == storage.DigestDaily {
+		text += "\n\nThey start once you switch back from digests with /frequency every."
+	}
+	if !instant {
+		text = "✅ Instant alerts off: alerts arrive on the regular schedule again."
+	}
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	b.api.Send(msg)
+}
+
+// handleNominate handles the /nominate <url> [niche] command. The niche defaults to the user's first one.
+func (b *Bot) handleNominate(message *tgbotapi.Message) {
+	usage := "Usage: /nominate <url> [niche], e.g. /nominate https://www.tiktok.com/music/name-123 fitness"
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 || len(args) > 2 {
+		msg := tgbotapi.NewMessage(message.Chat.ID, usage)
+		b.api.Send(msg)
+		return
+	}
+	if !storage.IsTikTokMusicURL(args[0]) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "That doesn't look like a TikTok sound link. Open the sound in TikTok and copy its link.\n\n"+usage)
+		b.api.Send(msg)
+		return
+	}
+
+	user, err := b.storage.GetUser(message.From.ID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	var niche string
+	if len(args) == 2 {
+		var errText string
+		niche, errText = parseNicheArg(args[1])
+		if errText != "" {
+			msg := tgbotapi.NewMessage(message.Chat.ID, usage+"\n\n"+errText)
+			b.api.Send(msg)
+			return
+		}
+	} else if niches := GetUserNiches(user); len(niches) > 0 {
+		niche = niches[0]
+	} else {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Which niche is it? Add one: "+usage)
+		b.api.Send(msg)
+		return
+	}
+
+	err = b.storage.SaveNomination(user.TelegramID, args[0], niche)
+	if errors.Is(err, storage.ErrDuplicate) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "This sound has already been nominated. Thanks!")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error saving nomination: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("🙌 Thanks! I'll start tracking this sound in %s from the next collection.", nicheDisplayName(niche, b.nicheLabels(user.TelegramID))))
+	b.api.Send(msg)
+}
+
+// handleFollow handles the /follow <soundID> command
+func (b *Bot) handleFollow(message *tgbotapi.Message) {
+	telegramID := message.From.ID
+
+	soundID, err := strconv.ParseInt(strings.TrimSpace(message.CommandArguments()), 10, 64)
+	if err != nil {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Usage: /follow <sound ID>\n\nYou'll find the 🆔 under each sound in alerts.")
+		b.api.Send(msg)
+		return
+	}
+
+	sound, err := b.storage.GetSoundByID(soundID)
+	if errors.Is(err, storage.ErrSoundNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Sound %d not found.", soundID))
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting sound: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	// Milestones already passed don't trigger a notification
+	err = b.storage.FollowSound(telegramID, soundID, detector.HighestMilestone(sound.UsesCount))
+	if errors.Is(err, storage.ErrDuplicate) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("You're already following \"%s\".", sound.Title))
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error following sound: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("👀 Following \"%s\". I'll let you know when it hits the next milestone.", sound.Title))
+	b.api.Send(msg)
+}
+
+// rawSoundExport is the document sent by /raw
+type rawSoundExport struct {
+	Sound   *storage.Sound         `json:"sound"`
+	History []storage.SoundHistory `json:"history"`
+}
+
+// rawSoundJSON assembles a sound and its full history into an indented JSON document
+func rawSoundJSON(sound *storage.Sound, history []storage.SoundHistory) ([]byte, error) {
+	if history == nil {
+		history = []storage.SoundHistory{}
+	}
+	return json.MarshalIndent(rawSoundExport{Sound: sound, History: history}, "", "  ")
+}
+
+// handleRaw handles the /raw <soundID> command sending a sound's full history as a JSON file
+func (b *Bot) handleRaw(message *tgbotapi.Message) {
+	soundID, err := strconv.ParseInt(strings.TrimSpace(message.CommandArguments()), 10, 64)
+	if err != nil {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Usage: /raw <sound ID>\n\nYou'll find the 🆔 under each sound in alerts.")
+		b.api.Send(msg)
+		return
+	}
+
+	sound, err := b.storage.GetSoundByID(soundID)
+	if errors.Is(err, storage.ErrSoundNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Sound %d not found.", soundID))
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting sound: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	history, err := b.storage.GetFullSoundHistory(soundID)
+	if err != nil {
+		log.Printf("Error getting full sound history: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	data, err := rawSoundJSON(sound, history)
+	if err != nil {
+		log.Printf("Error encoding sound history: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	doc := tgbotapi.NewDocument(message.Chat.ID, tgbotapi.FileBytes{Name: fmt.Sprintf("sound-%d.json", soundID), Bytes: data})
+	doc.Caption = fmt.Sprintf("📄 \"%s\" · %d snapshots", sound.Title, len(history))
+	if _, err := b.api.Send(doc); err != nil {
+		log.Printf("Error sending raw history: %v", err)
+	}
+}
+
+// handleExplain handles the admin /explain <category> command showing why sounds are or aren't trending
+func (b *Bot) handleExplain(message *tgbotapi.Message) {
+	if !b.isAdmin(message.From.ID) {
+		return
+	}
+
+	niche, errText := parseNicheArg(message.CommandArguments())
+	if errText != "" {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Usage: /explain <niche>\n\n"+errText)
+		b.api.Send(msg)
+		return
+	}
+
+	_, traces, timing, err := b.detector.ExplainTrending(niche, b.cfg.PremiumResultLimit, b.detector.Criteria())
+	if err != nil {
+		log.Printf("Error explaining trends for %s: %v", niche, err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	b.sendLongMessage(message.Chat.ID, formatTraces(niche, traces)+"\n"+formatTiming(timing), "")
+}
+
+// formatTraces renders detection traces, included sounds first
+func formatTraces(category string, traces []detector.DetectionTrace) string {
+	if len(traces) == 0 {
+		return fmt.Sprintf("No sounds collected for %s yet.", parser.CategoryDisplayNames[category])
+	}
+
+	text := fmt.Sprintf("🔎 Detection trace - %s (%d sounds)\n\n", parser.CategoryDisplayNames[category], len(traces))
+	for _, included := range []bool{true, false} {
+		for _, t := range traces {
+			if t.Included != included {
+				continue
+			}
+			mark := "❌"
+			if t.Included {
+				mark = "✅"
+			}
+			text += fmt.Sprintf("%s [%d] %s - %s uses", mark, t.SoundID, t.Title, formatNumber(t.UsesCount))
+			if t.OldUsesCount > 0 {
+				text += fmt.Sprintf(" (was %s, %+.0f%%)", formatNumber(t.OldUsesCount), t.GrowthPercent)
+			}
+			text += fmt.Sprintf(": %s\n", t.Reason)
+		}
+	}
+	return text
+}
+
+// formatTiming renders a detection run's duration and counts on one line
+func formatTiming(t detector.DetectionTiming) string {
+	return fmt.Sprintf("⏱ %s: %s, scanned %d, candidates %d, returned %d\n",
+		nicheDisplayName(t.Category, nil), t.Duration.Round(time.Millisecond), t.Scanned, t.Candidates, t.Returned)
+}
+
+// handleSources handles the admin /sources [min diff %] command comparing parser outputs
+func (b *Bot) handleSources(message *tgbotapi.Message) {
+	minDiff := 20.0
+	if arg := strings.TrimSpace(message.CommandArguments()); arg != "" {
+		v, err := strconv.ParseFloat(strings.TrimSuffix(arg, "%"), 64)
+		if err != nil || v < 0 {
+			msg := tgbotapi.NewMessage(message.Chat.ID, "Usage: /sources [min difference %], e.g. /sources 20")
+			b.api.Send(msg)
+			return
+		}
+		minDiff = v
+	}
+
+	discrepancies, err := b.storage.GetSourceDiscrepancies(time.Now().Add(-24*time.Hour), minDiff)
+	if err != nil {
+		log.Printf("Error getting source discrepancies: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	if len(discrepancies) == 0 {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("No sounds where parsers disagree by %.0f%% or more in the last 24h.", minDiff))
+		b.api.Send(msg)
+		return
+	}
+
+	sort.Slice(discrepancies, func(i, j int) bool {
+		return discrepancies[i].DiffPercent > discrepancies[j].DiffPercent
+	})
+
+	text := fmt.Sprintf("⚖️ Parser discrepancies ≥ %.0f%% (last 24h)\n\n", minDiff)
+	for _, d := range discrepancies {
+		var sources []string
+		for source, uses := range d.Uses {
+			sources = append(sources, fmt.Sprintf("%s=%s", source, formatNumber(uses)))
+		}
+		sort.Strings(sources)
+		text += fmt.Sprintf("[%d] %s: %s (%.0f%%)\n", d.SoundID, d.Title, strings.Join(sources, ", "), d.DiffPercent)
+	}
+
+	b.sendLongMessage(message.Chat.ID, text, "")
+}
+
+// handleLast handles the /last [niche] command, resending the stored alert text
+func (b *Bot) handleLast(message *tgbotapi.Message) {
+	category := ""
+	if arg := message.CommandArguments(); arg != "" {
+		niche, errText := parseNicheArg(arg)
+		if errText != "" {
+			msg := tgbotapi.NewMessage(message.Chat.ID, "Usage: /last [niche]\n\n"+errText)
+			b.api.Send(msg)
+			return
+		}
+		category = niche
+	}
+
+	alert, err := b.storage.GetLastAlert(message.From.ID, category)
+	if errors.Is(err, storage.ErrAlertNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "You haven't received any alerts yet. Use /trending to see what's hot right now.")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting last alert: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	text := fmt.Sprintf("_Sent %s_\n\n%s", alert.SentAt.Format("Jan 02, 15:04"), alert.Message)
+	b.sendLongMessage(message.Chat.ID, text, "Markdown")
+}
+
+// alertHistoryLimit is how many past alert sounds /alerts lists
+const alertHistoryLimit = 20
+
+// handleAlerts handles the /alerts command listing the sounds recently sent to the user
+func (b *Bot) handleAlerts(message *tgbotapi.Message) {
+	history, err := b.storage.GetUserAlertHistory(message.From.ID, alertHistoryLimit)
+	if err != nil {
+		log.Printf("Error getting alert history: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+	if len(history) == 0 {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "You haven't received any alerts yet. Use /trending to see what's hot right now.")
+		b.api.Send(msg)
+		return
+	}
+
+	labels := b.nicheLabels(message.From.ID)
+	text := "🔔 Your recent alerts\n\n"
+	for _, alert := range history {
+		title := fmt.Sprintf("%q", alert.Title)
+		if alert.Author != "" {
+			title += " by " + alert.Author
+		}
+		// New sounds have no growth to show
+		if alert.GrowthPercent > 0 {
+			title += fmt.Sprintf(" (+%.0f%%)", alert.GrowthPercent)
+		}
+		text += fmt.Sprintf("%s · %s\n%s\n%s\n\n",
+			alert.SentAt.Format("Jan 02, 15:04"), nicheDisplayName(alert.Niche, labels), title, alert.URL)
+	}
+	text += "Use /last to see a full alert again."
+
+	b.sendLongMessage(message.Chat.ID, text, "")
+}
+
+// handleGaps handles the admin /gaps [niche] [hours] command listing sounds with no recent history
+func (b *Bot) handleGaps(message *tgbotapi.Message) {
+	category := ""
+	hours := 6
+	for _, arg := range strings.Fields(message.CommandArguments()) {
+		if v, err := strconv.Atoi(arg); err == nil && v > 0 {
+			hours = v
+			continue
+		}
+		niche, errText := parseNicheArg(arg)
+		if errText != "" {
+			msg := tgbotapi.NewMessage(message.Chat.ID, "Usage: /gaps [niche] [hours]\n\n"+errText)
+			b.api.Send(msg)
+			return
+		}
+		category = niche
+	}
+
+	sounds, err := b.storage.GetSoundsWithoutRecentHistory(category, hours)
+	if err != nil {
+		log.Printf("Error getting collection gaps: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	scope := "all niches"
+	if category != "" {
+		scope = category
+	}
+	if len(sounds) == 0 {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("No gaps: every sound in %s was collected in the last %dh.", scope, hours))
+		b.api.Send(msg)
+		return
+	}
+
+	text := fmt.Sprintf("🕳 %d sounds in %s with no history in the last %dh\n\n", len(sounds), scope, hours)
+	for _, sound := range sounds {
+		text += fmt.Sprintf("[%d] %s (%s) - last seen %s\n", sound.ID, sound.Title, sound.Category, sound.UpdatedAt.Format("Jan 02, 15:04"))
+	}
+
+	b.sendLongMessage(message.Chat.ID, text, "")
+}
+
+// handleLatency handles the admin /latency [hours] command reporting alert delivery percentiles
+func (b *Bot) handleLatency(message *tgbotapi.Message) {
+	hours := 24
+	if arg := strings.TrimSpace(message.CommandArguments()); arg != "" {
+		v, err := strconv.Atoi(arg)
+		if err != nil || v <= 0 {
+			msg := tgbotapi.NewMessage(message.Chat.ID, "Usage: /latency [hours], e.g. /latency 24")
+			b.api.Send(msg)
+			return
+		}
+		hours = v
+	}
+
+	latencies, err := b.storage.GetAlertLatencies(time.Now().Add(-time.Duration(hours) * time.Hour))
+	if err != nil {
+		log.Printf("Error getting alert latencies: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	if len(latencies) == 0 {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("No alerts delivered in the last %dh.", hours))
+		b.api.Send(msg)
+		return
+	}
+
+	text := fmt.Sprintf("⏱ Alert delivery latency (last %dh, %d alerts)\n\np50: %s\np95: %s\nmax: %s",
+		hours,
+		len(latencies),
+		latencyPercentile(latencies, 50).Round(time.Second),
+		latencyPercentile(latencies, 95).Round(time.Second),
+		latencyPercentile(latencies, 100).Round(time.Second))
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	b.api.Send(msg)
+}
+
+// latencyPercentile returns the nearest-rank p-th percentile of the samples
+func latencyPercentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+
+	sorted := make([]time.Duration, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// handleHistoryDate handles the /history_date <niche> <YYYY-MM-DD> command, reconstructing past trends
+func (b *Bot) handleHistoryDate(message *tgbotapi.Message) {
+	user, err := b.storage.GetUser(message.From.ID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	usage := "Usage: /history_date <niche> <YYYY-MM-DD>, e.g. /history_date fitness 2024-05-01"
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) != 2 {
+		msg := tgbotapi.NewMessage(message.Chat.ID, usage)
+		b.api.Send(msg)
+		return
+	}
+
+	niche, errText := parseNicheArg(args[0])
+	if errText != "" {
+		msg := tgbotapi.NewMessage(message.Chat.ID, usage+"\n\n"+errText)
+		b.api.Send(msg)
+		return
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", args[1], time.Local)
+	if err != nil {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Invalid date %q.\n\n%s", args[1], usage))
+		b.api.Send(msg)
+		return
+	}
+	if day.After(time.Now()) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "That date is in the future. Use /trending for what's hot now.")
+		b.api.Send(msg)
+		return
+	}
+
+	// Reconstruct trends as of the end of that day
+	trending, err := b.detector.DetectTrendingAsOf(niche, day.AddDate(0, 0, 1))
+	if errors.Is(err, detector.ErrNoHistoryAsOf) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("No data was collected for %s around %s.", nicheDisplayName(niche, nil), args[1]))
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error detecting trends for %s as of %s: %v", niche, args[1], err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	if len(trending) == 0 {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Nothing was trending in %s on %s.", nicheDisplayName(niche, nil), args[1]))
+		b.api.Send(msg)
+		return
+	}
+	if limit := b.ResultLimit(user); len(trending) > limit {
+		trending = trending[:limit]
+	}
+
+	text := fmt.Sprintf("📅 _As of %s_\n\n", args[1]) + formatTrendingMessage(nicheDisplayName(niche, b.nicheLabels(message.From.ID)), trending, b.theme)
+	b.sendLongMessage(message.Chat.ID, text, "Markdown")
+}
+
+// handleVersion handles the admin /version command
+func (b *Bot) handleVersion(message *tgbotapi.Message) {
+	msg := tgbotapi.NewMessage(message.Chat.ID, "🏷 "+version.String())
+	b.api.Send(msg)
+}
+
+// handleSchema handles the admin /schema command showing the database schema fingerprint
+func (b *Bot) handleSchema(message *tgbotapi.Message) {
+	fingerprint, err := b.storage.SchemaFingerprint()
+	if err != nil {
+		log.Printf("Error fingerprinting schema: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("🗄 Schema fingerprint:\n`%s`\n\nCompare it across environments to spot migration drift.", fingerprint))
+	msg.ParseMode = "Markdown"
+	b.api.Send(msg)
+}
+
+// handleHealth handles the admin /health command reporting the collector's per-host rate state
+func (b *Bot) handleHealth(message *tgbotapi.Message) {
+	var text string
+	switch {
+	case b.hostLimiter == nil:
+		text = "No collector rate limiter configured.\n"
+	case b.hostLimiter.RequestsPerMinute() == 0:
+		text = "🩺 Collector requests are not rate limited.\n"
+	default:
+		text = fmt.Sprintf("🩺 Collector limit: %d requests/min per host\n\n", b.hostLimiter.RequestsPerMinute())
+		states := b.hostLimiter.State()
+		if len(states) == 0 {
+			text += "No requests made yet.\n"
+		}
+		for _, state := range states {
+			wait := time.Until(state.NextSlot).Round(time.Second)
+			if wait < 0 {
+				wait = 0
+			}
+			text += fmt.Sprintf("%s - next slot in %s, %d waiting\n", state.Host, wait, state.Waiting)
+		}
+	}
+
+	// Latest detection run per niche, slowest first
+	if timings := b.detector.Timings(); len(timings) > 0 {
+		text += "\n🔎 Detection\n\n"
+		for _, t := range timings {
+			text += formatTiming(t)
+		}
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	b.api.Send(msg)
+}
+
+// handleCard handles the premium /card [niche] command, sending trending sounds as a PNG card
+func (b *Bot) handleCard(message *tgbotapi.Message) {
+	if !b.FeatureEnabled(FeatureCards) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Image cards are unavailable right now. Try /trending instead.")
+		b.api.Send(msg)
+		return
+	}
+
+	user, err := b.storage.GetUser(message.From.ID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	// Default to the user's first niche
+	var niche string
+	if arg := message.CommandArguments(); arg != "" {
+		var errText string
+		niche, errText = parseNicheArg(arg)
+		if errText != "" {
+			msg := tgbotapi.NewMessage(message.Chat.ID, "Usage: /card [niche]\n\n"+errText)
+			b.api.Send(msg)
+			return
+		}
+	} else if niches := GetUserNiches(user); len(niches) > 0 {
+		niche = niches[0]
+	} else {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Select a niche with /niches or use /card <niche>.")
+		b.api.Send(msg)
+		return
+	}
+
+	trending, err := b.detector.DetectTrending(niche, b.ResultLimit(user))
+	if err != nil {
+		log.Printf("Error detecting trends for %s: %v", niche, err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	name := nicheDisplayName(niche, b.nicheLabels(user.TelegramID))
+	if len(trending) == 0 {
+		text := b.gatheringDataMessage(niche)
+		if text == "" {
+			text = fmt.Sprintf("No trending sounds in %s right now. Try again later!", name)
+		}
+		msg := tgbotapi.NewMessage(message.Chat.ID, text)
+		b.api.Send(msg)
+		return
+	}
+
+	data, err := card.Render("Trending Sounds - "+name, trending, b.card)
+	if err != nil {
+		log.Printf("Error rendering card: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Couldn't create the card right now. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	photo := tgbotapi.NewPhoto(message.Chat.ID, tgbotapi.FileBytes{Name: "trending-" + niche + ".png", Bytes: data})
+	photo.Caption = fmt.Sprintf("🔥 Trending in %s", name)
+	if _, err := b.api.Send(photo); err != nil {
+		log.Printf("Error sending card: %v", err)
+	}
+}
+
+// handleBlock handles the admin /block and /unblock <telegram id> commands
+func (b *Bot) handleBlock(message *tgbotapi.Message, block bool) {
+	command := "/unblock"
+	if block {
+		command = "/block"
+	}
+
+	telegramID, err := strconv.ParseInt(strings.TrimSpace(message.CommandArguments()), 10, 64)
+	if err != nil {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Usage: %s <telegram id>", command))
+		b.api.Send(msg)
+		return
+	}
+	if block && b.isAdmin(telegramID) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Admins can't be blocked.")
+		b.api.Send(msg)
+		return
+	}
+
+	err = b.storage.SetBlocked(telegramID, block)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("User %d not found.", telegramID))
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error setting blocked for %d: %v", telegramID, err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	text := fmt.Sprintf("✅ User %d unblocked.", telegramID)
+	if block {
+		text = fmt.Sprintf("🚫 User %d blocked. The bot will ignore them and stop sending alerts.", telegramID)
+	}
+	log.Printf("Admin %d ran %s %d", message.From.ID, command, telegramID)
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	b.api.Send(msg)
+}
+
+// handleFlags handles the admin /flags command listing feature flags
+func (b *Bot) handleFlags(message *tgbotapi.Message) {
+	text := "🚩 Feature flags\n\n"
+	for _, name := range knownFeatures {
+		enabled, err := b.storage.IsFeatureEnabled(name)
+		if err != nil {
+			log.Printf("Error reading feature flag %s: %v", name, err)
+			msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+			b.api.Send(msg)
+			return
+		}
+		state := "off"
+		if enabled {
+			state = "on"
+		}
+		text += fmt.Sprintf("%s - %s\n", name, state)
+	}
+	text += "\nUse /flag <name> <on|off> to change one."
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	b.api.Send(msg)
+}
+
+// handleFlag handles the admin /flag <name> <on|off> command toggling a feature flag
+func (b *Bot) handleFlag(message *tgbotapi.Message) {
+	usage := fmt.Sprintf("Usage: /flag <name> <on|off>\n\nFlags: %s", strings.Join(knownFeatures, ", "))
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) != 2 {
+		msg := tgbotapi.NewMessage(message.Chat.ID, usage)
+		b.api.Send(msg)
+		return
+	}
+
+	name := strings.ToLower(args[0])
+	known := false
+	for _, f := range knownFeatures {
+		known = known || f == name
+	}
+	if !known {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Unknown flag %q.\n\n%s", name, usage))
+		b.api.Send(msg)
+		return
+	}
+
+	var enabled bool
+	switch strings.ToLower(args[1]) {
+	case "on":
+		enabled = true
+	case "off":
+	default:
+		msg := tgbotapi.NewMessage(message.Chat.ID, usage)
+		b.api.Send(msg)
+		return
+	}
+
+	if err := b.storage.SetFeature(name, enabled); err != nil {
+		log.Printf("Error setting feature flag %s: %v", name, err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+	b.forgetFeature(name)
+
+	log.Printf("Admin %d set feature flag %s to %v", message.From.ID, name, enabled)
+	msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("✅ %s is now %s.", name, args[1]))
+	b.api.Send(msg)
+}
+
+// handleMerge handles the admin /merge <keep id> <duplicate id> command
+func (b *Bot) handleMerge(message *tgbotapi.Message) {
+	args := strings.Fields(message.CommandArguments())
+	if len(args) != 2 {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Usage: /merge <keep telegram id> <duplicate telegram id>")
+		b.api.Send(msg)
+		return
+	}
+
+	keepID, keepErr := strconv.ParseInt(args[0], 10, 64)
+	mergeID, mergeErr := strconv.ParseInt(args[1], 10, 64)
+	if keepErr != nil || mergeErr != nil {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Telegram IDs must be numbers.\n\nUsage: /merge <keep telegram id> <duplicate telegram id>")
+		b.api.Send(msg)
+		return
+	}
+
+	err := b.storage.MergeUsers(keepID, mergeID)
+	if errors.Is(err, storage.ErrSameUser) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Both IDs are the same user.")
+		b.api.Send(msg)
+		return
+	}
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "One of the users was not found.")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error merging user %d into %d: %v", mergeID, keepID, err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	log.Printf("Admin %d merged user %d into %d", message.From.ID, mergeID, keepID)
+	msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("✅ Merged user %d into %d.", mergeID, keepID))
+	b.api.Send(msg)
+}
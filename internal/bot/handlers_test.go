@@ -0,0 +1,2395 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/trending-sound/internal/detector"
+	"github.com/yourusername/trending-sound/internal/parser"
+	"github.com/yourusername/trending-sound/internal/storage"
+	"github.com/yourusername/trending-sound/internal/version"
+)
+
+func TestFormatStatus(t *testing.T) {
+	tests := []struct {
+		name  string
+		user  storage.User
+		want  []string
+		avoid []string
+	}{
+		{
+			name: "populated premium user",
+			user: storage.User{
+				Niches:          `["fitness","gaming"]`,
+				IsPremium:       true,
+				AlertMode:       storage.AlertModePopularity,
+				AlertSort:       storage.AlertSortUses,
+				AlertFormat:     storage.AlertFormatCompact,
+				DigestFrequency: storage.DigestDaily,
+				Region:          "US",
+				Timezone:        "Europe/Berlin",
+			},
+			want: []string{
+				"🎯 Niches: Fitness, Gaming\n",
+				"👤 Plan: Premium 💎\n",
+				"📈 Alert mode: popularity\n",
+				"🔀 Alert order: uses\n",
+				"📝 Alert format: compact\n",
+				"📬 Delivery: daily\n",
+				"🌍 Region: US\n",
+				"🕒 Timezone: Europe/Berlin\n",
+			},
+		},
+		{
+			name: "free user without niches",
+			user: storage.User{
+				AlertMode:       storage.AlertModeGrowth,
+				AlertSort:       storage.AlertSortGrowth,
+				AlertFormat:     storage.AlertFormatDetailed,
+				DigestFrequency: storage.DigestEvery,
+				Region:          storage.RegionGlobal,
+			},
+			want:  []string{"🎯 Niches: None\n", "👤 Plan: Free\n", "🌍 Region: global\n", "🕒 Timezone: UTC\n"},
+			avoid: []string{"Premium"},
+		},
+		{
+			name: "premium with an expiry in the user's timezone",
+			user: storage.User{
+				IsPremium:        true,
+				Timezone:         "Asia/Tokyo",
+				PremiumExpiresAt: time.Date(2024, 5, 1, 22, 30, 0, 0, time.UTC),
+			},
+			want: []string{"👤 Plan: Premium 💎 until May 02, 2024\n"},
+		},
+		{
+			name:  "premium without an expiry",
+			user:  storage.User{IsPremium: true},
+			want:  []string{"👤 Plan: Premium 💎\n"},
+			avoid: []string{"until"},
+		},
+		{
+			name: "unset region shows the default",
+			user: storage.User{},
+			want: []string{"🌍 Region: global\n"},
+		},
+		{
+			name:  "stale niches are dropped",
+			user:  storage.User{Niches: `["fitness","knitting"]`},
+			want:  []string{"🎯 Niches: Fitness\n"},
+			avoid: []string{"knitting"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatStatus(&tt.user)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("formatStatus() missing %q in:\n%s", want, got)
+				}
+			}
+			for _, avoid := range tt.avoid {
+				if strings.Contains(got, avoid) {
+					t.Errorf("formatStatus() unexpectedly contains %q in:\n%s", avoid, got)
+				}
+			}
+		})
+	}
+}
+
+func TestNicheCallbackValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		data []string
+		want string
+	}{
+		{name: "valid niche is saved", data: []string{"niche:fitness"}, want: `["fitness"]`},
+		{name: "toggling twice clears it", data: []string{"niche:fitness", "niche:fitness"}, want: `[]`},
+		{name: "unknown niche is ignored", data: []string{"niche:knitting"}, want: ""},
+		{name: "unknown niche keeps existing ones", data: []string{"niche:gaming", "niche:knitting"}, want: `["gaming"]`},
+		{name: "crafted value is ignored", data: []string{`niche:fitness","x`}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, _ := newTestBot(t, nil)
+			const telegramID = 42
+
+			for _, data := range tt.data {
+				b.handleCallbackQuery(&tgbotapi.CallbackQuery{
+					ID:      "cb",
+					From:    &tgbotapi.User{ID: telegramID},
+					Message: &tgbotapi.Message{MessageID: 1, Chat: &tgbotapi.Chat{ID: telegramID}},
+					Data:    data,
+				})
+			}
+
+			user, err := s.GetUser(telegramID)
+			if tt.want == "" {
+				if err == nil && user.Niches != "" && user.Niches != "[]" {
+					t.Errorf("niches = %q, want none", user.Niches)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+			if user.Niches != tt.want {
+				t.Errorf("niches = %q, want %q", user.Niches, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallbackWithoutStart(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantNiches  string
+		wantPremium bool
+	}{
+		{name: "niche toggle", data: "niche:fitness", wantNiches: `["fitness"]`},
+		{name: "premium activation", data: "premium:activate", wantPremium: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, _ := newTestBot(t, nil)
+			const telegramID = 42
+
+			b.handleCallbackQuery(&tgbotapi.CallbackQuery{
+				ID:      "cb",
+				From:    &tgbotapi.User{ID: telegramID},
+				Message: &tgbotapi.Message{MessageID: 1, Chat: &tgbotapi.Chat{ID: telegramID}},
+				Data:    tt.data,
+			})
+
+			user, err := s.GetUser(telegramID)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+			if tt.wantNiches != "" && user.Niches != tt.wantNiches {
+				t.Errorf("niches = %q, want %q", user.Niches, tt.wantNiches)
+			}
+			if user.IsPremium != tt.wantPremium {
+				t.Errorf("IsPremium = %v, want %v", user.IsPremium, tt.wantPremium)
+			}
+		})
+	}
+}
+
+func TestEnsureUser(t *testing.T) {
+	b, s, _ := newTestBot(t, nil)
+	addUser(t, s, 1, "gaming")
+
+	// An existing user is returned untouched
+	user, err := b.ensureUser(1)
+	if err != nil || user.Niches != `["gaming"]` {
+		t.Fatalf("ensureUser(existing) = %+v, %v, want the gaming user", user, err)
+	}
+
+	// A new user is created once; calling again finds them
+	for i := 0; i < 2; i++ {
+		user, err := b.ensureUser(2)
+		if err != nil || user.TelegramID != 2 {
+			t.Fatalf("ensureUser(new) call %d = %+v, %v", i+1, user, err)
+		}
+	}
+}
+
+func TestHandlePauseAlerts(t *testing.T) {
+	const admin, stranger = 1, 2
+
+	tests := []struct {
+		name     string
+		from     int64
+		commands []bool // true for /pausealerts, false for /resumealerts
+		want     string
+	}{
+		{name: "admin pauses", from: admin, commands: []bool{true}, want: "true"},
+		{name: "admin resumes", from: admin, commands: []bool{true, false}, want: "false"},
+		{name: "non-admin is ignored", from: stranger, commands: []bool{true}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t, map[string]string{"ADMIN_IDS": "1"})
+			b, s, _ := newTestBot(t, cfg)
+
+			for _, pause := range tt.commands {
+				b.handlePauseAlerts(&tgbotapi.Message{
+					From: &tgbotapi.User{ID: tt.from},
+					Chat: &tgbotapi.Chat{ID: tt.from},
+				}, pause)
+			}
+
+			got, err := s.GetSetting(storage.SettingAlertsPaused)
+			if err != nil {
+				t.Fatalf("GetSetting() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("alerts_paused = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSumScores(t *testing.T) {
+	categoryScores := map[string]float64{"fitness": 150, "gaming": 50.5, "comedy": 0}
+
+	tests := []struct {
+		name   string
+		niches []string
+		want   float64
+	}{
+		{name: "no niches", niches: nil, want: 0},
+		{name: "single niche", niches: []string{"fitness"}, want: 150},
+		{name: "several niches", niches: []string{"fitness", "gaming", "comedy"}, want: 200.5},
+		{name: "niche without data", niches: []string{"fitness", "dance"}, want: 150},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sumScores(categoryScores, tt.niches); got != tt.want {
+				t.Errorf("sumScores(%v) = %v, want %v", tt.niches, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScorePercentile(t *testing.T) {
+	tests := []struct {
+		name  string
+		score float64
+		all   []float64
+		want  float64
+	}{
+		{name: "no users", score: 10, all: nil, want: 0},
+		{name: "highest", score: 300, all: []float64{0, 100, 200, 300}, want: 75},
+		{name: "lowest", score: 0, all: []float64{0, 100, 200, 300}, want: 0},
+		{name: "ties count as not below", score: 100, all: []float64{100, 100, 100, 100}, want: 0},
+		{name: "middle", score: 150, all: []float64{0, 100, 200, 300}, want: 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scorePercentile(tt.score, tt.all); got != tt.want {
+				t.Errorf("scorePercentile(%v, %v) = %v, want %v", tt.score, tt.all, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMotivationLine(t *testing.T) {
+	tests := []struct {
+		percentile float64
+		want       string
+	}{
+		{95, "on fire"},
+		{90, "on fire"},
+		{60, "heating up"},
+		{30, "Steady"},
+		{29.9, "Quiet week"},
+		{0, "Quiet week"},
+	}
+
+	for _, tt := range tests {
+		if got := motivationLine(tt.percentile); !strings.Contains(got, tt.want) {
+			t.Errorf("motivationLine(%v) = %q, want it to contain %q", tt.percentile, got, tt.want)
+		}
+	}
+}
+
+func TestHandleStatsScoreLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		niches []string
+		want   string
+	}{
+		{name: "no niches", niches: nil, want: "🏆 Trend score: select niches with /niches to get one\n🎯 Top niche"},
+		{name: "with niches", niches: []string{"fitness"}, want: "🏆 Trend score: 0 (top 100%)\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			const telegramID = 7
+			if err := s.CreateUser(telegramID); err != nil {
+				t.Fatalf("CreateUser() error: %v", err)
+			}
+			if err := s.UpdateUserNiches(telegramID, SetUserNiches(tt.niches)); err != nil {
+				t.Fatalf("UpdateUserNiches() error: %v", err)
+			}
+
+			b.handleStats(&tgbotapi.Message{From: &tgbotapi.User{ID: telegramID}, Chat: &tgbotapi.Chat{ID: telegramID}})
+
+			texts := api.texts()
+			if len(texts) != 1 {
+				t.Fatalf("sent %d messages, want 1", len(texts))
+			}
+			if !strings.Contains(texts[0], tt.want) {
+				t.Errorf("stats missing %q in:\n%s", tt.want, texts[0])
+			}
+			if !strings.Contains(texts[0], "sounds\n🏆") {
+				t.Errorf("stats has a gap before the score line:\n%s", texts[0])
+			}
+		})
+	}
+}
+
+func TestParseNicheArg(t *testing.T) {
+	tests := []struct {
+		arg       string
+		want      string
+		wantError string
+	}{
+		{arg: "fitness", want: "fitness"},
+		{arg: "  Gaming ", want: "gaming"},
+		{arg: "gym", want: "fitness"},
+		{arg: "Esports", want: "gaming"},
+		{arg: "", wantError: "Please specify a niche."},
+		{arg: "knitting", wantError: `Unknown niche "knitting".`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, errText := parseNicheArg(tt.arg)
+			if got != tt.want {
+				t.Errorf("parseNicheArg(%q) niche = %q, want %q", tt.arg, got, tt.want)
+			}
+			if tt.wantError == "" {
+				if errText != "" {
+					t.Errorf("parseNicheArg(%q) unexpected error %q", tt.arg, errText)
+				}
+				return
+			}
+			if !strings.HasPrefix(errText, tt.wantError) || !strings.Contains(errText, "Valid niches: ") {
+				t.Errorf("parseNicheArg(%q) error = %q, want %q with the valid niches", tt.arg, errText, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestHandlePreview(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		premium bool
+		want    []string
+		links   int
+	}{
+		{name: "missing niche", command: "/preview", want: []string{"Usage: /preview <niche>", "Please specify a niche.", "Valid niches: "}},
+		{name: "unknown niche", command: "/preview knitting", want: []string{`Unknown niche "knitting"`, "fitness"}},
+		{name: "niche without data", command: "/preview gaming", want: []string{"Gaming"}},
+		{name: "free tier is capped", command: "/preview fitness", links: 2},
+		{name: "premium tier gets more", command: "/preview fitness", premium: true, links: 4},
+		{name: "alias resolves to niche", command: "/preview gym", links: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t, map[string]string{"FREE_RESULT_LIMIT": "2", "PREMIUM_RESULT_LIMIT": "10"})
+			b, s, api := newTestBot(t, cfg)
+			const telegramID = 3
+			addUser(t, s, telegramID, "comedy")
+			if tt.premium {
+				if err := s.SetPremium(telegramID, true); err != nil {
+					t.Fatalf("SetPremium() error: %v", err)
+				}
+			}
+			for _, title := range []string{"one", "two", "three", "four"} {
+				seedTrending(t, s, "fitness", title)
+			}
+
+			b.handlePreview(commandMessage(telegramID, tt.command))
+
+			texts := api.texts()
+			if len(texts) != 1 {
+				t.Fatalf("sent %d messages, want 1: %q", len(texts), texts)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(texts[0], want) {
+					t.Errorf("reply missing %q in:\n%s", want, texts[0])
+				}
+			}
+			if tt.links > 0 {
+				if got := strings.Count(texts[0], "tiktok.com/music/"); got != tt.links {
+					t.Errorf("reply has %d sounds, want %d:\n%s", got, tt.links, texts[0])
+				}
+			}
+		})
+	}
+}
+
+func TestFormatComparison(t *testing.T) {
+	top := &storage.TrendingSound{Sound: storage.Sound{Title: "gym anthem", Author: "dj"}, GrowthPercent: 400}
+	fresh := &storage.TrendingSound{Sound: storage.Sound{Title: "fresh"}, IsNew: true}
+
+	tests := []struct {
+		name   string
+		a, b   detector.TrendAnalysis
+		labels map[string]string
+		want   []string
+	}{
+		{
+			name: "neither trending",
+			a:    detector.TrendAnalysis{Category: "fitness"},
+			b:    detector.TrendAnalysis{Category: "gaming"},
+			want: []string{"Fitness vs Gaming", "Trending sounds: 0 vs 0", "Fitness: none", "Neither niche has trending sounds"},
+		},
+		{
+			name: "more trending wins",
+			a:    detector.TrendAnalysis{Category: "fitness", TrendingCount: 3, AverageGrowth: 200, TopSound: top},
+			b:    detector.TrendAnalysis{Category: "gaming", TrendingCount: 1, AverageGrowth: 900, TopSound: fresh},
+			want: []string{"Trending sounds: 3 vs 1", "+200% vs +900%", `Fitness: "gym anthem" by dj (+400%)`, `Gaming: "fresh" (new)`, "Fitness is hotter"},
+		},
+		{
+			name: "growth breaks a tie",
+			a:    detector.TrendAnalysis{Category: "fitness", TrendingCount: 2, AverageGrowth: 200},
+			b:    detector.TrendAnalysis{Category: "gaming", TrendingCount: 2, AverageGrowth: 300},
+			want: []string{"Gaming is hotter"},
+		},
+		{
+			name: "even",
+			a:    detector.TrendAnalysis{Category: "fitness", TrendingCount: 2, AverageGrowth: 300},
+			b:    detector.TrendAnalysis{Category: "gaming", TrendingCount: 2, AverageGrowth: 300},
+			want: []string{"Both niches are about even"},
+		},
+		{
+			name:   "custom labels",
+			a:      detector.TrendAnalysis{Category: "fitness", TrendingCount: 1},
+			b:      detector.TrendAnalysis{Category: "gaming"},
+			labels: map[string]string{"fitness": "Gym"},
+			want:   []string{"Gym vs Gaming", "Gym is hotter"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatComparison(&tt.a, &tt.b, tt.labels)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("formatComparison() missing %q in:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleVs(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    []string
+	}{
+		{name: "missing niches", command: "/vs fitness", want: []string{"Usage: /vs <niche> <niche>", "Valid niches: "}},
+		{name: "unknown niche", command: "/vs fitness knitting", want: []string{"Usage: /vs", `Unknown niche "knitting"`}},
+		{name: "same niche", command: "/vs fitness gym", want: []string{"Pick two different niches."}},
+		{name: "compare", command: "/vs gaming fitness", want: []string{"Gaming vs Fitness", "Trending sounds: 0 vs 2", "Fitness is hotter"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			addUser(t, s, 1, "fitness")
+			seedTrending(t, s, "fitness", "one")
+			seedTrending(t, s, "fitness", "two")
+
+			b.handleVs(commandMessage(1, tt.command))
+
+			texts := api.texts()
+			if len(texts) != 1 {
+				t.Fatalf("sent %d messages, want 1: %q", len(texts), texts)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(texts[0], want) {
+					t.Errorf("reply missing %q in:\n%s", want, texts[0])
+				}
+			}
+		})
+	}
+}
+
+func TestHandleGraduates(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		graduate bool
+		want     []string
+		notWant  []string
+	}{
+		{name: "unknown niche", command: "/graduates knitting", want: []string{"Usage: /graduates [niche]", `Unknown niche "knitting"`}},
+		{name: "no graduates", command: "/graduates", want: []string{"No sounds passed 30.0K uses this week"}},
+		{name: "all niches", command: "/graduates", graduate: true, want: []string{"Graduates: sounds that passed 30.0K uses", `1. "big hit" by author`, "Fitness · 20.0K → 40.0K uses", "https://www.tiktok.com/music/big-hit"}},
+		{name: "other niche", command: "/graduates gaming", graduate: true, want: []string{"No sounds passed"}, notWant: []string{"big hit"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			addUser(t, s, 1, "fitness")
+			seedTrending(t, s, "fitness", "still trending")
+			if tt.graduate {
+				now := time.Now()
+				sound := &storage.Sound{Title: "big hit", Author: "author", URL: "https://www.tiktok.com/music/big-hit", UsesCount: 20000, Category: "fitness"}
+				if err := s.SaveSound(sound); err != nil {
+					t.Fatalf("SaveSound() error: %v", err)
+				}
+				if _, err := s.SeedBaselineHistory(now.Add(-23 * time.Hour)); err != nil {
+					t.Fatalf("SeedBaselineHistory() error: %v", err)
+				}
+				sound.UsesCount = 40000
+				if err := s.UpdateSound(sound); err != nil {
+					t.Fatalf("UpdateSound() error: %v", err)
+				}
+				if err := s.SaveSoundHistory(sound.ID, sound.UsesCount, ""); err != nil {
+					t.Fatalf("SaveSoundHistory() error: %v", err)
+				}
+			}
+
+			b.handleGraduates(commandMessage(1, tt.command))
+
+			got := strings.Join(api.texts(), "\n")
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("reply missing %q in:\n%s", want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("reply has %q in:\n%s", notWant, got)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatAuthorLeaderboard(t *testing.T) {
+	tests := []struct {
+		name     string
+		category string
+		authors  []storage.AuthorStat
+		want     []string
+	}{
+		{name: "empty", category: "fitness", want: []string{"No sounds collected for Fitness yet"}},
+		{name: "empty across niches", want: []string{"No sounds collected for All niches yet"}},
+		{
+			name:     "ranked",
+			category: "gaming",
+			authors:  []storage.AuthorStat{{Author: "Solo", SoundCount: 2, TotalUses: 15000}, {Author: "Unknown", SoundCount: 1, TotalUses: 900}},
+			want:     []string{"Top Sound Creators - Gaming", "1. Solo - 15.0K uses across 2 sounds\n", "2. Unknown - 900 uses across 1 sounds\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatAuthorLeaderboard(tt.category, tt.authors)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("formatAuthorLeaderboard() missing %q in:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		command   string
+		premium   bool
+		want      string
+		wantLimit int
+	}{
+		{name: "free user", command: "/limit 10", want: "Premium feature"},
+		{name: "no argument shows current", command: "/limit", premium: true, want: "You get 5 sounds per alert"},
+		{name: "not a number", command: "/limit lots", premium: true, want: "Please send a number between 3 and 20"},
+		{name: "saved", command: "/limit 12", premium: true, want: "up to 12 sounds", wantLimit: 12},
+		{name: "clamped low", command: "/limit 1", premium: true, want: "up to 3 sounds", wantLimit: 3},
+		{name: "clamped high", command: "/limit 500", premium: true, want: "up to 20 sounds", wantLimit: 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			const telegramID = 4
+			addUser(t, s, telegramID, "fitness")
+			if tt.premium {
+				if err := s.SetPremium(telegramID, true); err != nil {
+					t.Fatalf("SetPremium() error: %v", err)
+				}
+			}
+
+			b.handleMessage(commandMessage(telegramID, tt.command))
+
+			texts := api.texts()
+			if len(texts) != 1 || !strings.Contains(texts[0], tt.want) {
+				t.Fatalf("replies = %q, want one containing %q", texts, tt.want)
+			}
+			user, err := s.GetUser(telegramID)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+			if user.AlertLimit != tt.wantLimit {
+				t.Errorf("alert_limit = %d, want %d", user.AlertLimit, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestHandleThreshold(t *testing.T) {
+	tests := []struct {
+		name       string
+		command    string
+		premium    bool
+		want       string
+		wantGlobal float64
+		wantNiches map[string]float64
+	}{
+		{name: "free user", command: "/threshold 200", want: "Premium feature"},
+		{name: "no argument shows default", command: "/threshold", premium: true, want: "at least +150% growth"},
+		{name: "global", command: "/threshold 300", premium: true, want: "Alerts now need at least +300% growth", wantGlobal: 300},
+		{name: "percent sign", command: "/threshold 250%", premium: true, want: "at least +250%", wantGlobal: 250},
+		{name: "niche", command: "/threshold fitness 200", premium: true, want: "Fitness alerts now need at least +200% growth", wantNiches: map[string]float64{"fitness": 200}},
+		{name: "niche reset falls back to default", command: "/threshold fitness reset", premium: true, want: "Fitness alerts now need at least +150% growth"},
+		{name: "below range", command: "/threshold 5", premium: true, want: "between 10 and 1000"},
+		{name: "above range", command: "/threshold 5000", premium: true, want: "between 10 and 1000"},
+		{name: "unknown niche", command: "/threshold cooking 200", premium: true, want: "Usage: /threshold"},
+		{name: "too many arguments", command: "/threshold fitness 200 300", premium: true, want: "Usage: /threshold"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			const telegramID = 4
+			addUser(t, s, telegramID, "fitness")
+			if tt.premium {
+				if err := s.SetPremium(telegramID, true); err != nil {
+					t.Fatalf("SetPremium() error: %v", err)
+				}
+			}
+
+			b.handleMessage(commandMessage(telegramID, tt.command))
+
+			texts := api.texts()
+			if len(texts) != 1 || !strings.Contains(texts[0], tt.want) {
+				t.Fatalf("replies = %q, want one containing %q", texts, tt.want)
+			}
+			user, err := s.GetUser(telegramID)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+			if user.MinGrowth != tt.wantGlobal {
+				t.Errorf("min_growth = %v, want %v", user.MinGrowth, tt.wantGlobal)
+			}
+			niches, err := s.GetNicheMinGrowths(telegramID)
+			if err != nil {
+				t.Fatalf("GetNicheMinGrowths() error: %v", err)
+			}
+			if len(niches) != len(tt.wantNiches) || (len(niches) > 0 && !reflect.DeepEqual(niches, tt.wantNiches)) {
+				t.Errorf("niche thresholds = %v, want %v", niches, tt.wantNiches)
+			}
+		})
+	}
+}
+
+func TestHandleRepeats(t *testing.T) {
+	b, s, api := newTestBot(t, nil)
+	addUser(t, s, 1, "fitness")
+	if err := s.SetPremium(1, true); err != nil {
+		t.Fatalf("SetPremium() error: %v", err)
+	}
+
+	steps := []struct {
+		want     string
+		wantShow bool
+	}{
+		{want: "also get sounds that were already alerted", wantShow: true},
+		{want: "only get sounds that are newly trending", wantShow: false},
+	}
+	for i, step := range steps {
+		b.handleMessage(commandMessage(1, "/repeats"))
+
+		texts := api.texts()
+		if len(texts) != i+1 || !strings.Contains(texts[i], step.want) {
+			t.Fatalf("step %d: replies = %q, want the last containing %q", i, texts, step.want)
+		}
+		user, err := s.GetUser(1)
+		if err != nil {
+			t.Fatalf("GetUser() error: %v", err)
+		}
+		if user.ShowRepeats != step.wantShow {
+			t.Errorf("step %d: show_repeats = %v, want %v", i, user.ShowRepeats, step.wantShow)
+		}
+	}
+}
+
+func TestInstantAlertsEnabled(t *testing.T) {
+	tests := []struct {
+		name string
+		user storage.User
+		want bool
+	}{
+		{name: "premium opted in", user: storage.User{IsPremium: true, InstantAlerts: true}, want: true},
+		{name: "every cycle frequency", user: storage.User{IsPremium: true, InstantAlerts: true, DigestFrequency: storage.DigestEvery}, want: true},
+		{name: "not opted in", user: storage.User{IsPremium: true}},
+		{name: "needs premium", user: storage.User{InstantAlerts: true}},
+		{name: "digest user", user: storage.User{IsPremium: true, InstantAlerts: true, DigestFrequency: storage.DigestTwiceDaily}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InstantAlertsEnabled(&tt.user); got != tt.want {
+				t.Errorf("InstantAlertsEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleInstant(t *testing.T) {
+	tests := []struct {
+		name   string
+		digest string
+		want   []string
+	}{
+		{name: "every cycle", want: []string{"Instant alerts on", "Instant alerts off"}},
+		{name: "digest user", digest: storage.DigestDaily, want: []string{"They start once you switch back from digests", "Instant alerts off"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			addUser(t, s, 1, "fitness")
+			if err := s.SetPremium(1, true); err != nil {
+				t.Fatalf("SetPremium() error: %v", err)
+			}
+			if tt.digest != "" {
+				if err := s.SetDigestFrequency(1, tt.digest); err != nil {
+					t.Fatalf("SetDigestFrequency() error: %v", err)
+				}
+			}
+
+			for i, want := range tt.want {
+				b.handleMessage(commandMessage(1, "/instant"))
+
+				texts := api.texts()
+				if len(texts) != i+1 || !strings.Contains(texts[i], want) {
+					t.Fatalf("step %d: replies = %q, want the last containing %q", i, texts, want)
+				}
+				user, err := s.GetUser(1)
+				if err != nil {
+					t.Fatalf("GetUser() error: %v", err)
+				}
+				if wantOn := i == 0; user.InstantAlerts != wantOn {
+					t.Errorf("step %d: instant_alerts = %v, want %v", i, user.InstantAlerts, wantOn)
+				}
+			}
+		})
+	}
+}
+
+func TestRawSoundJSON(t *testing.T) {
+	sound := &storage.Sound{ID: 7, Title: "gym anthem", UsesCount: 300}
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		history []storage.SoundHistory
+		want    int
+	}{
+		{name: "no history is an empty list", history: nil, want: 0},
+		{name: "history", history: []storage.SoundHistory{{SoundID: 7, UsesCount: 100, RecordedAt: at}, {SoundID: 7, UsesCount: 300, RecordedAt: at.Add(time.Hour)}}, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := rawSoundJSON(sound, tt.history)
+			if err != nil {
+				t.Fatalf("rawSoundJSON() error: %v", err)
+			}
+			var got struct {
+				Sound   storage.Sound          `json:"sound"`
+				History []storage.SoundHistory `json:"history"`
+			}
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("rawSoundJSON() is not valid JSON: %v\n%s", err, data)
+			}
+			if got.Sound.ID != 7 || got.History == nil || len(got.History) != tt.want {
+				t.Errorf("rawSoundJSON() = %s, want sound 7 with %d snapshots", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleRaw(t *testing.T) {
+	const admin, user = 1, 2
+
+	tests := []struct {
+		name    string
+		from    int64
+		premium bool
+		args    string // %d is replaced by the seeded sound's ID
+		want    string // Reply text, or the document caption
+		wantDoc bool
+	}{
+		{name: "premium user gets the document", from: user, premium: true, args: "%d", want: "\"gym anthem\" · 2 snapshots", wantDoc: true},
+		{name: "admin without premium", from: admin, args: "%d", want: "2 snapshots", wantDoc: true},
+		{name: "free user", from: user, args: "%d", want: "Premium feature"},
+		{name: "malformed ID", from: user, premium: true, args: "abc", want: "Usage: /raw <sound ID>"},
+		{name: "unknown ID", from: user, premium: true, args: "999", want: "Sound 999 not found."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t, map[string]string{"ADMIN_IDS": "1"})
+			b, s, api := newTestBot(t, cfg)
+			addUser(t, s, admin, "fitness")
+			addUser(t, s, user, "fitness")
+			if err := s.SetPremium(user, tt.premium); err != nil {
+				t.Fatalf("SetPremium() error: %v", err)
+			}
+			sound := seedTrending(t, s, "fitness", "gym anthem")
+
+			args := tt.args
+			if strings.Contains(args, "%d") {
+				args = fmt.Sprintf(args, sound.ID)
+			}
+			b.dispatchCommand(commandMessage(tt.from, "/raw "+args))
+
+			var docs []tgbotapi.DocumentConfig
+			for _, c := range api.sent {
+				if doc, ok := c.(tgbotapi.DocumentConfig); ok {
+					docs = append(docs, doc)
+				}
+			}
+			if !tt.wantDoc {
+				texts := api.texts()
+				if len(docs) != 0 || len(texts) != 1 || !strings.Contains(texts[0], tt.want) {
+					t.Errorf("replies = %q with %d documents, want one text containing %q", texts, len(docs), tt.want)
+				}
+				return
+			}
+
+			if len(docs) != 1 || !strings.Contains(docs[0].Caption, tt.want) {
+				t.Fatalf("documents = %+v, want one captioned %q", docs, tt.want)
+			}
+			file, ok := docs[0].File.(tgbotapi.FileBytes)
+			if !ok || file.Name != fmt.Sprintf("sound-%d.json", sound.ID) || !bytes.Contains(file.Bytes, []byte(`"uses_count": 5000`)) {
+				t.Errorf("document file = %+v, want the sound's JSON", docs[0].File)
+			}
+		})
+	}
+}
+
+func TestHandleFollow(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands []string
+		want     string
+	}{
+		{name: "missing id", commands: []string{"/follow"}, want: "Usage: /follow <sound ID>"},
+		{name: "not a number", commands: []string{"/follow abc"}, want: "Usage: /follow <sound ID>"},
+		{name: "unknown sound", commands: []string{"/follow 999"}, want: "Sound 999 not found."},
+		{name: "followed", commands: []string{"/follow %d"}, want: `Following "gym anthem"`},
+		{name: "already following", commands: []string{"/follow %d", "/follow %d"}, want: `already following "gym anthem"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			const telegramID = 6
+			addUser(t, s, telegramID, "fitness")
+			sound := seedTrending(t, s, "fitness", "gym anthem")
+
+			for _, command := range tt.commands {
+				if strings.Contains(command, "%d") {
+					command = fmt.Sprintf(command, sound.ID)
+				}
+				b.handleFollow(commandMessage(telegramID, command))
+			}
+
+			texts := api.texts()
+			if last := texts[len(texts)-1]; !strings.Contains(last, tt.want) {
+				t.Errorf("reply = %q, want it to contain %q", last, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleExplain(t *testing.T) {
+	const admin, stranger = 1, 2
+
+	tests := []struct {
+		name    string
+		from    int64
+		command string
+		want    []string
+	}{
+		{name: "non-admin is ignored", from: stranger, command: "/explain fitness"},
+		{name: "unknown niche", from: admin, command: "/explain knitting", want: []string{"Usage: /explain <niche>"}},
+		{name: "empty niche", from: admin, command: "/explain gaming", want: []string{"No sounds collected for Gaming yet."}},
+		{name: "trace", from: admin, command: "/explain fitness", want: []string{"Detection trace - Fitness (1 sounds)", "✅", "gym anthem", "included", "scanned 1, candidates 1, returned 1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t, map[string]string{"ADMIN_IDS": "1"})
+			b, s, api := newTestBot(t, cfg)
+			seedTrending(t, s, "fitness", "gym anthem")
+
+			b.handleExplain(commandMessage(tt.from, tt.command))
+
+			texts := api.texts()
+			if tt.want == nil {
+				if len(texts) != 0 {
+					t.Errorf("sent %q, want nothing", texts)
+				}
+				return
+			}
+			got := strings.Join(texts, "\n")
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("reply missing %q in:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleLast(t *testing.T) {
+	tests := []struct {
+		name    string
+		alerts  []string // niches alerted, in order
+		command string
+		want    string
+		avoid   string
+	}{
+		{name: "no previous alert", command: "/last", want: "You haven't received any alerts yet."},
+		{name: "most recent across niches", alerts: []string{"fitness", "gaming"}, command: "/last", want: "gaming anthem", avoid: "fitness anthem"},
+		{name: "specific niche", alerts: []string{"fitness", "gaming"}, command: "/last fitness", want: "fitness anthem", avoid: "gaming anthem"},
+		{name: "niche never alerted", alerts: []string{"fitness"}, command: "/last gaming", want: "You haven't received any alerts yet."},
+		{name: "unknown niche", command: "/last knitting", want: "Usage: /last [niche]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			const telegramID = 8
+			addUser(t, s, telegramID, "fitness", "gaming")
+			for _, niche := range tt.alerts {
+				sound := seedTrending(t, s, niche, niche+" anthem")
+				ts := []storage.TrendingSound{{Sound: *sound, GrowthPercent: 400}}
+				if err := b.SendTrendingAlert(telegramID, niche, ts); err != nil {
+					t.Fatalf("SendTrendingAlert() error: %v", err)
+				}
+			}
+			sent := len(api.texts())
+
+			b.handleLast(commandMessage(telegramID, tt.command))
+
+			texts := api.texts()[sent:]
+			got := strings.Join(texts, "\n")
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("reply missing %q in:\n%s", tt.want, got)
+			}
+			if tt.avoid != "" && strings.Contains(got, tt.avoid) {
+				t.Errorf("reply unexpectedly contains %q in:\n%s", tt.avoid, got)
+			}
+		})
+	}
+}
+
+func TestHandleGaps(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    string
+	}{
+		{name: "default window finds the gap", command: "/gaps", want: "1 sounds in all niches with no history in the last 6h"},
+		{name: "niche and hours", command: "/gaps fitness 48", want: "1 sounds in fitness with no history in the last 48h"},
+		{name: "niche without gaps", command: "/gaps gaming", want: "No gaps: every sound in gaming was collected in the last 6h."},
+		{name: "unknown niche", command: "/gaps knitting", want: "Usage: /gaps [niche] [hours]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			seedTrending(t, s, "fitness", "collected")
+			if err := s.SaveSound(&storage.Sound{Title: "missed", URL: "https://www.tiktok.com/music/missed", UsesCount: 10, Category: "fitness"}); err != nil {
+				t.Fatalf("SaveSound() error: %v", err)
+			}
+
+			b.handleGaps(commandMessage(1, tt.command))
+
+			texts := api.texts()
+			if len(texts) != 1 || !strings.Contains(texts[0], tt.want) {
+				t.Errorf("replies = %q, want one containing %q", texts, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleInlineQuery(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       func(id int64) string
+		wantResults int
+		wantSwitch  bool
+	}{
+		{name: "known sound", query: func(id int64) string { return fmt.Sprintf("share %d", id) }, wantResults: 1},
+		{name: "unknown sound", query: func(id int64) string { return fmt.Sprintf("share %d", id+1000) }, wantSwitch: true},
+		{name: "not a share query", query: func(int64) string { return "hello" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			sound := seedTrending(t, s, "fitness", "gym anthem")
+
+			b.handleInlineQuery(&tgbotapi.InlineQuery{ID: "q1", Query: tt.query(sound.ID)})
+
+			if len(api.requests) != 1 {
+				t.Fatalf("made %d requests, want one inline answer", len(api.requests))
+			}
+			answer := api.requests[0].(tgbotapi.InlineConfig)
+			if answer.InlineQueryID != "q1" {
+				t.Errorf("InlineQueryID = %q, want q1", answer.InlineQueryID)
+			}
+			if len(answer.Results) != tt.wantResults {
+				t.Fatalf("got %d results, want %d", len(answer.Results), tt.wantResults)
+			}
+			if tt.wantResults > 0 {
+				article := answer.Results[0].(tgbotapi.InlineQueryResultArticle)
+				content := article.InputMessageContent.(tgbotapi.InputTextMessageContent)
+				if article.Title != "gym anthem" || !strings.Contains(content.Text, sound.URL) {
+					t.Errorf("result = %q / %q, want the shared sound", article.Title, content.Text)
+				}
+			}
+			if gotSwitch := answer.SwitchPMText != ""; gotSwitch != tt.wantSwitch {
+				t.Errorf("SwitchPMText = %q, want set = %v", answer.SwitchPMText, tt.wantSwitch)
+			}
+		})
+	}
+}
+
+func TestHandleMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		want     string
+		wantMode string
+	}{
+		{name: "show current", command: "/mode", want: "Your alerts are growth-based.", wantMode: storage.AlertModeGrowth},
+		{name: "popularity", command: "/mode popularity", want: "now popularity-based", wantMode: storage.AlertModePopularity},
+		{name: "case insensitive", command: "/mode Growth", want: "now growth-based", wantMode: storage.AlertModeGrowth},
+		{name: "unknown", command: "/mode random", want: `Unknown mode "random"`, wantMode: storage.AlertModeGrowth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			addUser(t, s, 1, "fitness")
+
+			b.handleMode(commandMessage(1, tt.command))
+
+			texts := api.texts()
+			if len(texts) != 1 || !strings.Contains(texts[0], tt.want) {
+				t.Errorf("replies = %q, want one containing %q", texts, tt.want)
+			}
+			user, err := s.GetUser(1)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+			if user.AlertMode != tt.wantMode {
+				t.Errorf("AlertMode = %q, want %q", user.AlertMode, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestNicheDisplayName(t *testing.T) {
+	tests := []struct {
+		name     string
+		category string
+		labels   map[string]string
+		want     string
+	}{
+		{name: "user label", category: "fitness", labels: map[string]string{"fitness": "Gym 💪"}, want: "Gym 💪"},
+		{name: "default name", category: "fitness", labels: map[string]string{"gaming": "Games"}, want: parser.CategoryDisplayNames["fitness"]},
+		{name: "no labels", category: "fitness", labels: nil, want: parser.CategoryDisplayNames["fitness"]},
+		{name: "unknown category", category: "knitting", labels: nil, want: "knitting"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nicheDisplayName(tt.category, tt.labels); got != tt.want {
+				t.Errorf("nicheDisplayName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleLabel(t *testing.T) {
+	tests := []struct {
+		name      string
+		existing  string
+		command   string
+		want      string
+		wantLabel string
+	}{
+		{name: "set", command: "/label fitness Gym 💪", want: `will now show as "Gym 💪"`, wantLabel: "Gym 💪"},
+		{name: "reset", existing: "Gym", command: "/label fitness", want: "label reset", wantLabel: ""},
+		{name: "markdown rejected", existing: "Gym", command: "/label fitness *bold*", want: "can't contain", wantLabel: "Gym"},
+		{name: "too long", command: "/label fitness " + strings.Repeat("a", maxNicheLabelLength+1), want: "Labels can be up to", wantLabel: ""},
+		{name: "unknown niche", command: "/label knitting Yarn", want: "knitting", wantLabel: ""},
+		{name: "usage", command: "/label", want: "Usage: /label", wantLabel: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			addUser(t, s, 1, "fitness")
+			if tt.existing != "" {
+				if err := s.SetNicheLabel(1, "fitness", tt.existing); err != nil {
+					t.Fatalf("SetNicheLabel() error: %v", err)
+				}
+			}
+
+			b.handleLabel(commandMessage(1, tt.command))
+
+			texts := api.texts()
+			if len(texts) != 1 || !strings.Contains(texts[0], tt.want) {
+				t.Errorf("replies = %q, want one containing %q", texts, tt.want)
+			}
+			labels, err := s.GetNicheLabels(1)
+			if err != nil {
+				t.Fatalf("GetNicheLabels() error: %v", err)
+			}
+			if labels["fitness"] != tt.wantLabel {
+				t.Errorf("fitness label = %q, want %q", labels["fitness"], tt.wantLabel)
+			}
+		})
+	}
+}
+
+func TestSendTrendingAlertUsesLabel(t *testing.T) {
+	b, s, api := newTestBot(t, nil)
+	addUser(t, s, 1, "fitness")
+	if err := s.SetNicheLabel(1, "fitness", "Gym"); err != nil {
+		t.Fatalf("SetNicheLabel() error: %v", err)
+	}
+	sound := seedTrending(t, s, "fitness", "gym anthem")
+
+	if err := b.SendTrendingAlert(1, "fitness", []storage.TrendingSound{{Sound: *sound, GrowthPercent: 400}}); err != nil {
+		t.Fatalf("SendTrendingAlert() error: %v", err)
+	}
+
+	texts := api.texts()
+	if len(texts) == 0 || !strings.Contains(texts[0], "Trending Sounds - Gym*") {
+		t.Errorf("alert = %q, want the user's label in the header", texts)
+	}
+}
+
+func TestHandleBeta(t *testing.T) {
+	tests := []struct {
+		name         string
+		enrolled     bool
+		want         string
+		wantEnrolled bool
+	}{
+		{name: "join", enrolled: false, want: "You joined the beta!", wantEnrolled: true},
+		{name: "leave", enrolled: true, want: "You left the beta.", wantEnrolled: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			addUser(t, s, 1, "fitness")
+			if err := s.SetBetaEnrolled(1, tt.enrolled); err != nil {
+				t.Fatalf("SetBetaEnrolled() error: %v", err)
+			}
+
+			b.handleBeta(commandMessage(1, "/beta"))
+
+			texts := api.texts()
+			if len(texts) != 1 || !strings.Contains(texts[0], tt.want) {
+				t.Errorf("replies = %q, want one containing %q", texts, tt.want)
+			}
+			user, err := s.GetUser(1)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+			if user.BetaEnrolled != tt.wantEnrolled || BetaEnabled(user) != tt.wantEnrolled {
+				t.Errorf("BetaEnrolled = %v, want %v", user.BetaEnrolled, tt.wantEnrolled)
+			}
+		})
+	}
+}
+
+func TestHandleBetaUsers(t *testing.T) {
+	b, s, api := newTestBot(t, nil)
+	addUser(t, s, 1, "fitness")
+	addUser(t, s, 2, "fitness")
+	if err := s.SetBetaEnrolled(2, true); err != nil {
+		t.Fatalf("SetBetaEnrolled() error: %v", err)
+	}
+
+	b.handleBetaUsers(commandMessage(1, "/betausers"))
+
+	texts := api.texts()
+	if len(texts) != 1 || !strings.Contains(texts[0], "1 users enrolled in beta") {
+		t.Errorf("replies = %q, want the enrollment count", texts)
+	}
+}
+
+func TestLatencyPercentile(t *testing.T) {
+	samples := []time.Duration{5 * time.Second, 1 * time.Second, 3 * time.Second, 2 * time.Second, 4 * time.Second}
+
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{p: 0, want: 1 * time.Second},
+		{p: 50, want: 3 * time.Second},
+		{p: 95, want: 5 * time.Second},
+		{p: 100, want: 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := latencyPercentile(samples, tt.p); got != tt.want {
+			t.Errorf("latencyPercentile(p%v) = %s, want %s", tt.p, got, tt.want)
+		}
+	}
+
+	if got := latencyPercentile(nil, 50); got != 0 {
+		t.Errorf("latencyPercentile(nil) = %s, want 0", got)
+	}
+	if samples[0] != 5*time.Second {
+		t.Error("latencyPercentile() reordered the caller's samples")
+	}
+}
+
+func TestHandleLatency(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		samples []time.Duration
+		want    string
+	}{
+		{name: "report", command: "/latency", samples: []time.Duration{time.Second, 2 * time.Second, 10 * time.Second}, want: "last 24h, 3 alerts)\n\np50: 2s\np95: 10s\nmax: 10s"},
+		{name: "custom window", command: "/latency 6", samples: []time.Duration{time.Second}, want: "last 6h, 1 alerts"},
+		{name: "no alerts", command: "/latency", want: "No alerts delivered in the last 24h."},
+		{name: "invalid hours", command: "/latency soon", want: "Usage: /latency [hours]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			for _, latency := range tt.samples {
+				if err := s.SaveAlertLatency(1, "fitness", latency); err != nil {
+					t.Fatalf("SaveAlertLatency() error: %v", err)
+				}
+			}
+
+			b.handleLatency(commandMessage(1, tt.command))
+
+			texts := api.texts()
+			if len(texts) != 1 || !strings.Contains(texts[0], tt.want) {
+				t.Errorf("replies = %q, want one containing %q", texts, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleHistoryDate(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    string
+	}{
+		{name: "missing date", command: "/history_date fitness", want: "Usage: /history_date"},
+		{name: "unknown niche", command: "/history_date knitting 2024-05-01", want: "knitting"},
+		{name: "invalid date", command: "/history_date fitness 05/01/2024", want: `Invalid date "05/01/2024"`},
+		{name: "future date", command: "/history_date fitness " + time.Now().AddDate(0, 0, 2).Format("2006-01-02"), want: "That date is in the future."},
+		{name: "no data", command: "/history_date fitness 2020-01-01", want: "No data was collected for"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			addUser(t, s, 1, "fitness")
+			seedTrending(t, s, "fitness", "gym anthem")
+
+			b.handleHistoryDate(commandMessage(1, tt.command))
+
+			texts := api.texts()
+			if len(texts) != 1 || !strings.Contains(texts[0], tt.want) {
+				t.Errorf("replies = %q, want one containing %q", texts, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleVersion(t *testing.T) {
+	b, _, api := newTestBot(t, nil)
+
+	b.handleVersion(commandMessage(1, "/version"))
+
+	texts := api.texts()
+	if len(texts) != 1 || !strings.Contains(texts[0], version.String()) {
+		t.Errorf("replies = %q, want the build version %q", texts, version.String())
+	}
+}
+
+func TestTrendingCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		scores []bool // Trending flag of each saved score; nil saves none
+		limit  int
+		want   int
+	}{
+		{name: "no scores falls back to detection", scores: nil, limit: 10, want: 1},
+		{name: "counts trending scores", scores: []bool{true, true, false}, limit: 10, want: 2},
+		{name: "capped at limit", scores: []bool{true, true, true}, limit: 2, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, _ := newTestBot(t, nil)
+			seedTrending(t, s, "fitness", "live hit")
+
+			var scores []storage.TrendScore
+			for i, trending := range tt.scores {
+				sound := &storage.Sound{Title: "scored", URL: fmt.Sprintf("https://www.tiktok.com/music/scored-%d", i), UsesCount: 1000, Category: "fitness"}
+				if err := s.SaveSound(sound); err != nil {
+					t.Fatalf("SaveSound() error: %v", err)
+				}
+				scores = append(scores, storage.TrendScore{SoundID: sound.ID, Trending: trending, ComputedAt: time.Now()})
+			}
+			if err := s.SaveTrendScores("fitness", scores); err != nil {
+				t.Fatalf("SaveTrendScores() error: %v", err)
+			}
+
+			if got := b.trendingCount("fitness", tt.limit); got != tt.want {
+				t.Errorf("trendingCount() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleCard(t *testing.T) {
+	tests := []struct {
+		name      string
+		register  bool
+		niches    []string
+		seed      string // Category to seed a trending sound in
+		text      string
+		wantPhoto bool
+		wantText  string
+	}{
+		{name: "unregistered", text: "/card", wantText: "Please use /start first"},
+		{name: "no niches", register: true, text: "/card", wantText: "Select a niche with /niches"},
+		{name: "unknown niche", register: true, text: "/card knitting", wantText: "Usage: /card [niche]"},
+		{name: "nothing trending", register: true, niches: []string{"fitness"}, text: "/card", wantText: "Still gathering data for Fitness"},
+		{name: "first niche", register: true, niches: []string{"gaming", "fitness"}, seed: "gaming", text: "/card", wantPhoto: true},
+		{name: "niche argument", register: true, seed: "beauty", text: "/card beauty", wantPhoto: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			const telegramID = 7
+			if tt.register {
+				addUser(t, s, telegramID, tt.niches...)
+			}
+			if tt.seed != "" {
+				seedTrending(t, s, tt.seed, "hit")
+			}
+
+			b.handleCard(commandMessage(telegramID, tt.text))
+
+			if len(api.sent) != 1 {
+				t.Fatalf("sent %d messages, want 1", len(api.sent))
+			}
+			photo, isPhoto := api.sent[0].(tgbotapi.PhotoConfig)
+			if isPhoto != tt.wantPhoto {
+				t.Fatalf("sent %T, want photo %v", api.sent[0], tt.wantPhoto)
+			}
+			if tt.wantPhoto {
+				file, ok := photo.File.(tgbotapi.FileBytes)
+				if !ok || !bytes.HasPrefix(file.Bytes, []byte("\x89PNG")) {
+					t.Errorf("photo file = %T, want PNG bytes", photo.File)
+				}
+				return
+			}
+			if texts := api.texts(); !strings.Contains(texts[0], tt.wantText) {
+				t.Errorf("reply = %q, want it to contain %q", texts[0], tt.wantText)
+			}
+		})
+	}
+}
+
+func TestHandleMerge(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		want       string
+		wantMerged bool
+	}{
+		{name: "no arguments", text: "/merge", want: "Usage: /merge"},
+		{name: "not numbers", text: "/merge a b", want: "Telegram IDs must be numbers"},
+		{name: "same user", text: "/merge 1 1", want: "Both IDs are the same user"},
+		{name: "unknown user", text: "/merge 1 9", want: "One of the users was not found"},
+		{name: "merged", text: "/merge 1 2", want: "Merged user 2 into 1", wantMerged: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			addUser(t, s, 1, "fitness")
+			addUser(t, s, 2, "gaming")
+
+			b.handleMerge(commandMessage(1, tt.text))
+
+			texts := api.texts()
+			if len(texts) != 1 || !strings.Contains(texts[0], tt.want) {
+				t.Fatalf("replies = %q, want one containing %q", texts, tt.want)
+			}
+			_, err := s.GetUser(2)
+			if merged := errors.Is(err, storage.ErrUserNotFound); merged != tt.wantMerged {
+				t.Errorf("duplicate removed = %v, want %v", merged, tt.wantMerged)
+			}
+		})
+	}
+}
+
+func TestHandleSort(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		want     string
+		wantSort string
+	}{
+		{name: "show current", command: "/sort", want: "Your alerts are sorted by growth.", wantSort: storage.AlertSortGrowth},
+		{name: "uses", command: "/sort uses", want: "now sorted by uses", wantSort: storage.AlertSortUses},
+		{name: "case insensitive", command: "/sort Recent", want: "now sorted by recent", wantSort: storage.AlertSortRecent},
+		{name: "unknown", command: "/sort random", want: `Unknown order "random"`, wantSort: storage.AlertSortGrowth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			addUser(t, s, 1, "fitness")
+
+			b.handleSort(commandMessage(1, tt.command))
+
+			texts := api.texts()
+			if len(texts) != 1 || !strings.Contains(texts[0], tt.want) {
+				t.Errorf("replies = %q, want one containing %q", texts, tt.want)
+			}
+			user, err := s.GetUser(1)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+			if user.AlertSort != tt.wantSort {
+				t.Errorf("AlertSort = %q, want %q", user.AlertSort, tt.wantSort)
+			}
+		})
+	}
+}
+
+func TestHandleFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		command    string
+		want       string
+		wantFormat string
+	}{
+		{name: "show current", command: "/format", want: "Your alerts use the detailed format.", wantFormat: storage.AlertFormatDetailed},
+		{name: "compact", command: "/format compact", want: "now use the compact format", wantFormat: storage.AlertFormatCompact},
+		{name: "case insensitive", command: "/format Compact", want: "now use the compact format", wantFormat: storage.AlertFormatCompact},
+		{name: "tiered", command: "/format tiered", want: "now use the tiered format", wantFormat: storage.AlertFormatTiered},
+		{name: "unknown", command: "/format tiny", want: `Unknown format "tiny"`, wantFormat: storage.AlertFormatDetailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			addUser(t, s, 1, "fitness")
+
+			b.handleFormat(commandMessage(1, tt.command))
+
+			texts := api.texts()
+			if len(texts) != 1 || !strings.Contains(texts[0], tt.want) {
+				t.Errorf("replies = %q, want one containing %q", texts, tt.want)
+			}
+			user, err := s.GetUser(1)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+			if user.AlertFormat != tt.wantFormat {
+				t.Errorf("AlertFormat = %q, want %q", user.AlertFormat, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestSendTrendingAlertFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: storage.AlertFormatDetailed, want: "*1. \"gym anthem\"* by author\n   📊 Uses: 5.0K (+400%)"},
+		{format: storage.AlertFormatCompact, want: "1. [gym anthem](https://www.tiktok.com/music/gym-anthem) · 5.0K (+400%) · 🆔 1\n"},
+		{format: storage.AlertFormatTiered, want: "*📈 Rising (150–500%)*\n\n*1. \"gym anthem\"* by author"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			addUser(t, s, 1, "fitness")
+			if err := s.SetAlertFormat(1, tt.format); err != nil {
+				t.Fatalf("SetAlertFormat() error: %v", err)
+			}
+			sound := seedTrending(t, s, "fitness", "gym anthem")
+
+			if err := b.SendTrendingAlert(1, "fitness", []storage.TrendingSound{{Sound: *sound, GrowthPercent: 400}}); err != nil {
+				t.Fatalf("SendTrendingAlert() error: %v", err)
+			}
+
+			texts := api.texts()
+			if len(texts) == 0 || !strings.Contains(texts[0], tt.want) {
+				t.Errorf("alert = %q, want it to contain %q", texts, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleNominate(t *testing.T) {
+	const url = "https://www.tiktok.com/music/gym-anthem-123"
+
+	tests := []struct {
+		name         string
+		register     bool
+		niches       []string
+		commands     []string
+		want         string
+		wantCategory string // Category of the stored nomination, empty for none
+	}{
+		{name: "no arguments", register: true, commands: []string{"/nominate"}, want: "Usage: /nominate"},
+		{name: "not a sound link", register: true, commands: []string{"/nominate https://example.com/x"}, want: "doesn't look like a TikTok sound link"},
+		{name: "unregistered", commands: []string{"/nominate " + url}, want: "Please use /start first"},
+		{name: "no niche", register: true, commands: []string{"/nominate " + url}, want: "Which niche is it?"},
+		{name: "unknown niche", register: true, commands: []string{"/nominate " + url + " knitting"}, want: "Usage: /nominate"},
+		{name: "first niche", register: true, niches: []string{"gaming"}, commands: []string{"/nominate " + url}, want: "tracking this sound in Gaming", wantCategory: "gaming"},
+		{name: "niche argument", register: true, commands: []string{"/nominate " + url + " fitness"}, want: "tracking this sound in Fitness", wantCategory: "fitness"},
+		{name: "already nominated", register: true, commands: []string{"/nominate " + url + " fitness", "/nominate " + url + "?lang=en beauty"}, want: "already been nominated", wantCategory: "fitness"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			if tt.register {
+				addUser(t, s, 1, tt.niches...)
+			}
+
+			for _, command := range tt.commands {
+				b.handleNominate(commandMessage(1, command))
+			}
+
+			texts := api.texts()
+			if len(texts) != len(tt.commands) || !strings.Contains(texts[len(texts)-1], tt.want) {
+				t.Errorf("replies = %q, want the last one to contain %q", texts, tt.want)
+			}
+			pending, err := s.GetPendingNominations()
+			if err != nil {
+				t.Fatalf("GetPendingNominations() error: %v", err)
+			}
+			if tt.wantCategory == "" {
+				if len(pending) != 0 {
+					t.Errorf("pending = %+v, want none", pending)
+				}
+				return
+			}
+			if len(pending) != 1 || pending[0].Category != tt.wantCategory {
+				t.Errorf("pending = %+v, want one in %s", pending, tt.wantCategory)
+			}
+		})
+	}
+}
+
+func TestBulkNichesCallback(t *testing.T) {
+	tests := []struct {
+		name       string
+		premium    bool
+		data       string
+		wantNiches []string
+		wantUpsell bool
+	}{
+		{name: "premium selects all", premium: true, data: "niche_all", wantNiches: parser.Categories},
+		{name: "premium clears all", premium: true, data: "niche_clear", wantNiches: nil},
+		{name: "free user gets upsell", premium: false, data: "niche_all", wantNiches: []string{"gaming"}, wantUpsell: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			const telegramID = 5
+			addUser(t, s, telegramID, "gaming")
+			if tt.premium {
+				if err := s.SetPremium(telegramID, true); err != nil {
+					t.Fatalf("SetPremium() error: %v", err)
+				}
+			}
+
+			b.handleCallbackQuery(&tgbotapi.CallbackQuery{
+				ID:      "cb",
+				From:    &tgbotapi.User{ID: telegramID},
+				Message: &tgbotapi.Message{MessageID: 1, Chat: &tgbotapi.Chat{ID: telegramID}},
+				Data:    tt.data,
+			})
+
+			user, err := s.GetUser(telegramID)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+			if got := GetUserNiches(user); len(got) != len(tt.wantNiches) || (len(got) > 0 && !reflect.DeepEqual(got, tt.wantNiches)) {
+				t.Errorf("niches = %v, want %v", got, tt.wantNiches)
+			}
+
+			if len(api.sent) != 1 {
+				t.Fatalf("sent %d messages, want 1", len(api.sent))
+			}
+			_, isEdit := api.sent[0].(tgbotapi.EditMessageReplyMarkupConfig)
+			if isEdit == tt.wantUpsell {
+				t.Errorf("sent %T, want upsell %v", api.sent[0], tt.wantUpsell)
+			}
+		})
+	}
+}
+
+func TestNichesKeyboardBulkButtons(t *testing.T) {
+	keyboard := createNichesKeyboard(nil, nil)
+	var data []string
+	for _, row := range keyboard.InlineKeyboard {
+		for _, button := range row {
+			if button.CallbackData != nil {
+				data = append(data, *button.CallbackData)
+			}
+		}
+	}
+	for _, want := range []string{"niche_all", "niche_clear", "niche_done"} {
+		found := false
+		for _, d := range data {
+			found = found || d == want
+		}
+		if !found {
+			t.Errorf("keyboard callbacks %v are missing %q", data, want)
+		}
+	}
+}
+
+func TestHandleSchema(t *testing.T) {
+	b, s, api := newTestBot(t, nil)
+	want, err := s.SchemaFingerprint()
+	if err != nil {
+		t.Fatalf("SchemaFingerprint() error: %v", err)
+	}
+
+	b.handleSchema(commandMessage(1, "/schema"))
+
+	texts := api.texts()
+	if len(texts) != 1 || !strings.Contains(texts[0], "`"+want+"`") {
+		t.Errorf("replies = %q, want the fingerprint %s", texts, want)
+	}
+}
+
+func TestHandleHealth(t *testing.T) {
+	tests := []struct {
+		name    string
+		limiter func() *parser.HostLimiter
+		want    []string
+	}{
+		{name: "no limiter", limiter: func() *parser.HostLimiter { return nil }, want: []string{"No collector rate limiter configured."}},
+		{name: "unlimited", limiter: func() *parser.HostLimiter { return parser.NewHostLimiter(0) }, want: []string{"not rate limited"}},
+		{name: "no requests yet", limiter: func() *parser.HostLimiter { return parser.NewHostLimiter(30) }, want: []string{"30 requests/min per host", "No requests made yet."}},
+		{
+			name: "per host state",
+			limiter: func() *parser.HostLimiter {
+				l := parser.NewHostLimiter(30)
+				l.Wait("www.tiktok.com", nil)
+				return l
+			},
+			want: []string{"www.tiktok.com - next slot in 2s, 0 waiting"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _, api := newTestBot(t, nil)
+			b.SetHostLimiter(tt.limiter())
+
+			b.handleHealth(commandMessage(1, "/health"))
+
+			texts := api.texts()
+			if len(texts) != 1 {
+				t.Fatalf("replies = %q, want one", texts)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(texts[0], want) {
+					t.Errorf("reply = %q, want it to contain %q", texts[0], want)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleHealthDetectionTiming(t *testing.T) {
+	b, s, api := newTestBot(t, nil)
+	seedTrending(t, s, "fitness", "gym anthem")
+
+	b.handleHealth(commandMessage(1, "/health"))
+	if texts := api.texts(); len(texts) != 1 || strings.Contains(texts[0], "Detection") {
+		t.Fatalf("replies before detection = %q, want one without detection timing", texts)
+	}
+
+	if _, err := b.detector.DetectTrending("fitness", 10); err != nil {
+		t.Fatalf("DetectTrending() error: %v", err)
+	}
+	b.handleHealth(commandMessage(1, "/health"))
+
+	texts := api.texts()
+	if len(texts) != 2 || !strings.Contains(texts[1], "🔎 Detection") || !strings.Contains(texts[1], "Fitness: ") ||
+		!strings.Contains(texts[1], "scanned 1, candidates 1, returned 1") {
+		t.Errorf("reply = %q, want the fitness detection timing", texts[len(texts)-1])
+	}
+}
+
+// failingDetection makes every trend detection fail while the rest of storage works
+type failingDetection struct {
+	*storage.SQLiteStorage
+}
+
+func (f failingDetection) GetAllSoundsWithHistory(string, int) ([]storage.Sound, map[int64]*storage.SoundHistory, error) {
+	return nil, nil, errors.New("database is locked")
+}
+
+func TestHandleTrendingDetectFailure(t *testing.T) {
+	tests := []struct {
+		name       string
+		threshold  string
+		attempts   int
+		wantAdmins int // Admin notifications expected in total
+	}{
+		{name: "below threshold", threshold: "3", attempts: 2, wantAdmins: 0},
+		{name: "reaches threshold once", threshold: "3", attempts: 5, wantAdmins: 1},
+		{name: "admin alerts disabled", threshold: "0", attempts: 5, wantAdmins: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t, map[string]string{
+				"ADMIN_IDS":                      "99",
+				"DETECT_FAILURE_ALERT_THRESHOLD": tt.threshold,
+				"DETECT_ERROR_MESSAGE":           "Trends are down.",
+				"MIN_DATA_POINTS":                "1",
+			})
+			s := newTestStorage(t)
+			api := &fakeAPI{}
+			b, err := NewWithAPI(cfg, s, detector.New(failingDetection{s}), api)
+			if err != nil {
+				t.Fatalf("NewWithAPI() error: %v", err)
+			}
+			addUser(t, s, 1, "fitness")
+			seedTrending(t, s, "fitness", "hit")
+
+			for i := 0; i < tt.attempts; i++ {
+				b.handleTrending(commandMessage(1, "/trending"))
+			}
+
+			userErrors, adminAlerts := 0, 0
+			for _, c := range api.sent {
+				msg, ok := c.(tgbotapi.MessageConfig)
+				if !ok {
+					continue
+				}
+				switch {
+				case msg.ChatID == 1 && msg.Text == "⚠️ Fitness: Trends are down.":
+					userErrors++
+				case msg.ChatID == 99 && strings.Contains(msg.Text, "database is locked"):
+					adminAlerts++
+				}
+			}
+			if userErrors != tt.attempts {
+				t.Errorf("user got %d error replies, want %d", userErrors, tt.attempts)
+			}
+			if adminAlerts != tt.wantAdmins {
+				t.Errorf("admins got %d alerts, want %d", adminAlerts, tt.wantAdmins)
+			}
+		})
+	}
+}
+
+func TestRecordDetectFailureResets(t *testing.T) {
+	b, _, api := newTestBot(t, newTestConfig(t, map[string]string{"ADMIN_IDS": "99", "DETECT_FAILURE_ALERT_THRESHOLD": "2"}))
+
+	// Two failures alert, a success resets the streak, two more alert again
+	b.recordDetectFailure("fitness", errors.New("boom"))
+	b.recordDetectFailure("fitness", errors.New("boom"))
+	b.detectFailures.Store(0)
+	b.recordDetectFailure("fitness", errors.New("boom"))
+	b.recordDetectFailure("fitness", errors.New("boom"))
+
+	if got := len(api.texts()); got != 2 {
+		t.Errorf("sent %d admin alerts, want 2", got)
+	}
+}
+
+func TestHandleStart(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		niches     []string // nil = new user
+		label      string
+		want       string
+		wantKeypad bool
+	}{
+		{name: "new user gets onboarding", want: "Choose your niches below", wantKeypad: true},
+		{name: "user without niches gets onboarding", niches: []string{}, want: "Choose your niches below", wantKeypad: true},
+		{name: "returning user gets short welcome", niches: []string{"fitness", "comedy"}, want: "tracking: Fitness, Comedy"},
+		{name: "short welcome uses labels", niches: []string{"fitness"}, label: "Gym", want: "tracking: Gym"},
+		{name: "short welcome disabled", env: map[string]string{"SHORT_WELCOME_FOR_RETURNING": "false"}, niches: []string{"fitness"}, want: "Choose your niches below", wantKeypad: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, newTestConfig(t, tt.env))
+			if tt.niches != nil {
+				addUser(t, s, 1, tt.niches...)
+			}
+			if tt.label != "" {
+				if err := s.SetNicheLabel(1, "fitness", tt.label); err != nil {
+					t.Fatalf("SetNicheLabel() error: %v", err)
+				}
+			}
+
+			b.handleStart(commandMessage(1, "/start"))
+
+			if len(api.sent) != 1 {
+				t.Fatalf("sent %d messages, want 1", len(api.sent))
+			}
+			msg, ok := api.sent[0].(tgbotapi.MessageConfig)
+			if !ok {
+				t.Fatalf("sent %T, want a message", api.sent[0])
+			}
+			if !strings.Contains(msg.Text, tt.want) {
+				t.Errorf("reply = %q, want it to contain %q", msg.Text, tt.want)
+			}
+			if gotKeypad := msg.ReplyMarkup != nil; gotKeypad != tt.wantKeypad {
+				t.Errorf("niche keyboard sent = %v, want %v", gotKeypad, tt.wantKeypad)
+			}
+			if _, err := s.GetUser(1); err != nil {
+				t.Errorf("GetUser() after /start error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHandleBlock(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		block       bool
+		want        string
+		wantBlocked bool
+	}{
+		{name: "no argument", text: "/block", block: true, want: "Usage: /block"},
+		{name: "unknown user", text: "/block 9", block: true, want: "User 9 not found"},
+		{name: "admin", text: "/block 1", block: true, want: "Admins can't be blocked"},
+		{name: "blocked", text: "/block 2", block: true, want: "User 2 blocked", wantBlocked: true},
+		{name: "unblocked", text: "/unblock 2", block: false, want: "User 2 unblocked"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, newTestConfig(t, map[string]string{"ADMIN_IDS": "1"}))
+			addUser(t, s, 1, "fitness")
+			addUser(t, s, 2, "fitness")
+			if !tt.block {
+				if err := s.SetBlocked(2, true); err != nil {
+					t.Fatalf("SetBlocked() error: %v", err)
+				}
+			}
+
+			b.handleBlock(commandMessage(1, tt.text), tt.block)
+
+			texts := api.texts()
+			if len(texts) != 1 || !strings.Contains(texts[0], tt.want) {
+				t.Fatalf("replies = %q, want one containing %q", texts, tt.want)
+			}
+			user, err := s.GetUser(2)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+			if user.Blocked != tt.wantBlocked {
+				t.Errorf("Blocked = %v, want %v", user.Blocked, tt.wantBlocked)
+			}
+		})
+	}
+}
+
+func TestBlockedUserIgnored(t *testing.T) {
+	tests := []struct {
+		name      string
+		from      int64
+		blocked   bool
+		wantReply bool
+	}{
+		{name: "not blocked", from: 2, wantReply: true},
+		{name: "blocked", from: 2, blocked: true, wantReply: false},
+		{name: "admins are never blocked", from: 1, blocked: true, wantReply: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, newTestConfig(t, map[string]string{"ADMIN_IDS": "1"}))
+			addUser(t, s, tt.from, "fitness")
+			if tt.blocked {
+				if err := s.SetBlocked(tt.from, true); err != nil {
+					t.Fatalf("SetBlocked() error: %v", err)
+				}
+			}
+
+			b.handleMessage(commandMessage(tt.from, "/version"))
+			b.handleCallbackQuery(&tgbotapi.CallbackQuery{ID: "cb", From: &tgbotapi.User{ID: tt.from}, Data: "niches_done"})
+
+			api.mu.Lock()
+			got := len(api.sent)+len(api.requests) > 0
+			api.mu.Unlock()
+			if got != tt.wantReply {
+				t.Errorf("bot responded = %v, want %v", got, tt.wantReply)
+			}
+		})
+	}
+}
+
+func TestHandleMinUses(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		stored  int64
+		want    string
+		wantMin int64
+	}{
+		{name: "show default", text: "/minuses", want: "at least 500 uses"},
+		{name: "show current", text: "/minuses", stored: 5000, want: "at least 5.0K uses", wantMin: 5000},
+		{name: "set", text: "/minuses 5000", want: "at least 5.0K uses", wantMin: 5000},
+		{name: "clamped to max", text: "/minuses 90000", want: "at least 30.0K uses", wantMin: 30000},
+		{name: "at default is cleared", text: "/minuses 500", stored: 5000, want: "at least 500 uses"},
+		{name: "reset", text: "/minuses reset", stored: 5000, want: "at least 500 uses"},
+		{name: "not a number", text: "/minuses lots", stored: 5000, want: "whole number", wantMin: 5000},
+		{name: "negative", text: "/minuses -5", want: "whole number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			addUser(t, s, 1, "fitness")
+			if err := s.SetUserMinUses(1, tt.stored); err != nil {
+				t.Fatalf("SetUserMinUses() error: %v", err)
+			}
+
+			b.handleMinUses(commandMessage(1, tt.text))
+
+			texts := api.texts()
+			if len(texts) != 1 || !strings.Contains(texts[0], tt.want) {
+				t.Fatalf("replies = %q, want one containing %q", texts, tt.want)
+			}
+			user, err := s.GetUser(1)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+			if user.MinUses != tt.wantMin {
+				t.Errorf("MinUses = %d, want %d", user.MinUses, tt.wantMin)
+			}
+		})
+	}
+}
+
+func TestHandleBoost(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		boosted     bool
+		want        string
+		wantBoosted bool
+	}{
+		{name: "start", text: "/boost", want: "from +75% growth instead of +150%", wantBoosted: true},
+		{name: "restart", text: "/boost", boosted: true, want: "Boost on until", wantBoosted: true},
+		{name: "off", text: "/boost off", boosted: true, want: "Boost ended", wantBoosted: false},
+		{name: "off without boost", text: "/boost off", want: "No boost is active", wantBoosted: false},
+		{name: "unknown argument", text: "/boost max", want: "Usage: /boost", wantBoosted: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			addUser(t, s, 1, "fitness")
+			if tt.boosted {
+				if err := s.SetBoostUntil(1, time.Now().Add(time.Hour)); err != nil {
+					t.Fatalf("SetBoostUntil() error: %v", err)
+				}
+			}
+
+			b.handleBoost(commandMessage(1, tt.text))
+
+			texts := api.texts()
+			if len(texts) != 1 || !strings.Contains(texts[0], tt.want) {
+				t.Fatalf("replies = %q, want one containing %q", texts, tt.want)
+			}
+			user, err := s.GetUser(1)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+			if got := Boosted(user, time.Now()); got != tt.wantBoosted {
+				t.Errorf("boosted = %v, want %v", got, tt.wantBoosted)
+			}
+			// A fresh or restarted boost lasts the full BOOST_DURATION
+			if tt.wantBoosted && time.Until(user.BoostUntil) < 23*time.Hour {
+				t.Errorf("boost ends in %s, want about 24h", time.Until(user.BoostUntil).Round(time.Minute))
+			}
+		})
+	}
+}
+
+func TestHandleFlag(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		want      string
+		wantCards bool
+	}{
+		{name: "no arguments", text: "/flag", want: "Usage: /flag", wantCards: true},
+		{name: "unknown flag", text: "/flag forecasts on", want: `Unknown flag "forecasts"`, wantCards: true},
+		{name: "bad state", text: "/flag cards maybe", want: "Usage: /flag", wantCards: true},
+		{name: "off", text: "/flag cards off", want: "cards is now off", wantCards: false},
+		{name: "case insensitive", text: "/flag Cards OFF", want: "cards is now OFF", wantCards: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			// Cache the current value so the test also checks that /flag forgets it
+			b.FeatureEnabled(FeatureCards)
+
+			b.handleFlag(commandMessage(1, tt.text))
+
+			texts := api.texts()
+			if len(texts) != 1 || !strings.Contains(texts[0], tt.want) {
+				t.Fatalf("replies = %q, want one containing %q", texts, tt.want)
+			}
+			if got, _ := s.IsFeatureEnabled(FeatureCards); got != tt.wantCards {
+				t.Errorf("stored cards flag = %v, want %v", got, tt.wantCards)
+			}
+			if got := b.FeatureEnabled(FeatureCards); got != tt.wantCards {
+				t.Errorf("FeatureEnabled(cards) = %v, want %v", got, tt.wantCards)
+			}
+		})
+	}
+}
+
+func TestHandleFlags(t *testing.T) {
+	b, s, api := newTestBot(t, nil)
+	if err := s.SetFeature(FeatureCrossNiche, true); err != nil {
+		t.Fatalf("SetFeature() error: %v", err)
+	}
+
+	b.handleFlags(commandMessage(1, "/flags"))
+
+	texts := api.texts()
+	if len(texts) != 1 {
+		t.Fatalf("replies = %q, want one", texts)
+	}
+	for _, want := range []string{"cards - on", "cross_niche - on"} {
+		if !strings.Contains(texts[0], want) {
+			t.Errorf("reply = %q, want it to contain %q", texts[0], want)
+		}
+	}
+}
+
+func TestHandleCardFlagOff(t *testing.T) {
+	b, s, api := newTestBot(t, nil)
+	addUser(t, s, 1, "fitness")
+	seedTrending(t, s, "fitness", "hit")
+	if err := s.SetFeature(FeatureCards, false); err != nil {
+		t.Fatalf("SetFeature() error: %v", err)
+	}
+
+	b.handleCard(commandMessage(1, "/card"))
+
+	if texts := api.texts(); len(texts) != 1 || !strings.Contains(texts[0], "Image cards are unavailable") {
+		t.Errorf("replies = %q, want cards reported unavailable", texts)
+	}
+}
+
+func TestHandleFrequency(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		want          string
+		wantFrequency string
+	}{
+		{name: "show current", text: "/frequency", want: "delivered: every", wantFrequency: storage.DigestEvery},
+		{name: "daily", text: "/frequency daily", want: "now delivered: daily", wantFrequency: storage.DigestDaily},
+		{name: "case insensitive", text: "/frequency Twice_Daily", want: "now delivered: twice_daily", wantFrequency: storage.DigestTwiceDaily},
+		{name: "unknown", text: "/frequency weekly", want: `Unknown frequency "weekly"`, wantFrequency: storage.DigestEvery},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			addUser(t, s, 1, "fitness")
+
+			b.handleFrequency(commandMessage(1, tt.text))
+
+			texts := api.texts()
+			if len(texts) != 1 || !strings.Contains(texts[0], tt.want) {
+				t.Fatalf("replies = %q, want one containing %q", texts, tt.want)
+			}
+			user, err := s.GetUser(1)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+			if user.DigestFrequency != tt.wantFrequency {
+				t.Errorf("DigestFrequency = %q, want %q", user.DigestFrequency, tt.wantFrequency)
+			}
+		})
+	}
+}
+
+func TestHandleRegion(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		want       string
+		wantRegion string
+	}{
+		{name: "show current", text: "/region", want: "You get trends for region: global", wantRegion: "global"},
+		{name: "collected region", text: "/region US", want: "now get trends for region: US", wantRegion: "US"},
+		{name: "case insensitive", text: "/region gb", want: "now get trends for region: GB", wantRegion: "GB"},
+		{name: "not collected", text: "/region FR", want: `Region "FR" isn't collected`, wantRegion: "global"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t, map[string]string{"REGIONS": "global,US,GB"})
+			b, s, api := newTestBot(t, cfg)
+			addUser(t, s, 1, "fitness")
+
+			b.handleRegion(commandMessage(1, tt.text))
+
+			texts := api.texts()
+			if len(texts) != 1 || !strings.Contains(texts[0], tt.want) {
+				t.Fatalf("replies = %q, want one containing %q", texts, tt.want)
+			}
+			user, err := s.GetUser(1)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+			if user.Region != tt.wantRegion {
+				t.Errorf("Region = %q, want %q", user.Region, tt.wantRegion)
+			}
+		})
+	}
+}
+
+func TestHandleTrendingRegion(t *testing.T) {
+	tests := []struct {
+		region  string
+		want    []string
+		notWant []string
+	}{
+		{region: "global", want: []string{"gym anthem", "us hit"}},
+		{region: "US", want: []string{"us hit"}, notWant: []string{"gym anthem"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.region, func(t *testing.T) {
+			b, s, api := newTestBot(t, newTestConfig(t, map[string]string{"MIN_DATA_POINTS": "1"}))
+			addUser(t, s, 1, "fitness")
+			seedTrending(t, s, "fitness", "gym anthem")
+			us := seedTrending(t, s, "fitness", "us hit")
+			if err := s.RecordSoundRegion(us.ID, "US"); err != nil {
+				t.Fatalf("RecordSoundRegion() error: %v", err)
+			}
+			if err := s.SetUserRegion(1, tt.region); err != nil {
+				t.Fatalf("SetUserRegion() error: %v", err)
+			}
+
+			b.handleTrending(commandMessage(1, "/trending"))
+
+			got := strings.Join(api.texts(), "\n")
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("reply missing %q in:\n%s", want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("reply has %q in:\n%s", notWant, got)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleMuteNiche(t *testing.T) {
+	b, s, api := newTestBot(t, newTestConfig(t, map[string]string{"MIN_DATA_POINTS": "1"}))
+	addUser(t, s, 1, "fitness", "gaming")
+	seedTrending(t, s, "fitness", "gym anthem")
+
+	steps := []struct {
+		text      string
+		want      string
+		wantMuted map[string]bool
+	}{
+		{text: "/mute_niche", want: "Usage: /mute_niche <niche>", wantMuted: map[string]bool{}},
+		{text: "/mute_niche knitting", want: `Unknown niche "knitting"`, wantMuted: map[string]bool{}},
+		{text: "/mute_niche tech", want: "You're not subscribed to Tech", wantMuted: map[string]bool{}},
+		{text: "/mute_niche gym", want: "Fitness muted", wantMuted: map[string]bool{"fitness": true}},
+		{text: "/mute_niche gaming", want: "Gaming muted", wantMuted: map[string]bool{"fitness": true, "gaming": true}},
+		{text: "/trending", want: "All your niches are muted", wantMuted: map[string]bool{"fitness": true, "gaming": true}},
+		{text: "/unmute_niche fitness", want: "Fitness unmuted", wantMuted: map[string]bool{"gaming": true}},
+		{text: "/trending", want: "gym anthem", wantMuted: map[string]bool{"gaming": true}},
+	}
+	for i, step := range steps {
+		before := len(api.texts())
+		b.handleMessage(commandMessage(1, step.text))
+
+		got := strings.Join(api.texts()[before:], "\n")
+		if !strings.Contains(got, step.want) {
+			t.Errorf("step %d %s: reply missing %q in:\n%s", i, step.text, step.want, got)
+		}
+		muted, err := s.GetMutedNiches(1)
+		if err != nil {
+			t.Fatalf("GetMutedNiches() error: %v", err)
+		}
+		if !reflect.DeepEqual(muted, step.wantMuted) {
+			t.Errorf("step %d %s: muted = %v, want %v", i, step.text, muted, step.wantMuted)
+		}
+	}
+}
+
+func TestSendDigest(t *testing.T) {
+	fitness := storage.TrendingSound{Sound: storage.Sound{ID: 1, Title: "gym anthem", URL: "https://a", UsesCount: 5000}, GrowthPercent: 400}
+	gaming := storage.TrendingSound{Sound: storage.Sound{ID: 2, Title: "boss theme", URL: "https://b", UsesCount: 3000}, GrowthPercent: 200}
+
+	tests := []struct {
+		name      string
+		frequency string
+		niches    []string
+		digest    map[string][]storage.TrendingSound
+		want      []string // In order
+		wantSent  bool
+	}{
+		{
+			name:      "section per niche in niche order",
+			frequency: storage.DigestDaily,
+			niches:    []string{"gaming", "fitness"},
+			digest:    map[string][]storage.TrendingSound{"fitness": {fitness}, "gaming": {gaming}},
+			want:      []string{"Your daily digest", "Gaming", "boss theme", "Fitness", "gym anthem"},
+			wantSent:  true,
+		},
+		{
+			name:      "empty niches are skipped",
+			frequency: storage.DigestTwiceDaily,
+			niches:    []string{"gaming", "fitness"},
+			digest:    map[string][]storage.TrendingSound{"fitness": {fitness}},
+			want:      []string{"Your twice-daily digest", "Fitness", "gym anthem"},
+			wantSent:  true,
+		},
+		{
+			name:      "nothing queued",
+			frequency: storage.DigestDaily,
+			niches:    []string{"fitness"},
+			digest:    map[string][]storage.TrendingSound{"gaming": {gaming}},
+			wantSent:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _, api := newTestBot(t, nil)
+			user := &storage.User{TelegramID: 1, DigestFrequency: tt.frequency}
+
+			if err := b.SendDigest(user, tt.niches, tt.digest); err != nil {
+				t.Fatalf("SendDigest() error: %v", err)
+			}
+
+			texts := api.texts()
+			if sent := len(texts) > 0; sent != tt.wantSent {
+				t.Fatalf("sent %q, want sent = %v", texts, tt.wantSent)
+			}
+			if !tt.wantSent {
+				return
+			}
+			msg := strings.Join(texts, "\n")
+			last := -1
+			for _, part := range tt.want {
+				i := strings.Index(msg, part)
+				if i < last {
+					t.Errorf("%q missing or out of order in:\n%s", part, msg)
+				}
+				last = i
+			}
+			if strings.Contains(msg, "Gaming") && len(tt.digest["gaming"]) == 0 {
+				t.Errorf("digest has a section for an empty niche:\n%s", msg)
+			}
+			if msg, ok := api.sent[0].(tgbotapi.MessageConfig); ok && msg.ReplyMarkup != nil {
+				t.Error("digest has share buttons, want none")
+			}
+		})
+	}
+}
+
+func TestHandleAlerts(t *testing.T) {
+	tests := []struct {
+		name   string
+		record bool
+		want   []string
+	}{
+		{name: "no alerts yet", want: []string{"You haven't received any alerts yet"}},
+		{name: "history", record: true, want: []string{"Your recent alerts", "Gym", `"gym anthem" by author (+400%)`, "https://www.tiktok.com/music/gym-anthem", "/last"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			addUser(t, s, 1, "fitness")
+			if err := s.SetNicheLabel(1, "fitness", "Gym"); err != nil {
+				t.Fatalf("SetNicheLabel() error: %v", err)
+			}
+			if tt.record {
+				sound := seedTrending(t, s, "fitness", "gym anthem")
+				if err := s.RecordSentAlert(1, "fitness", []storage.TrendingSound{{Sound: *sound, GrowthPercent: 400}}, time.Now()); err != nil {
+					t.Fatalf("RecordSentAlert() error: %v", err)
+				}
+			}
+
+			b.handleAlerts(commandMessage(1, "/alerts"))
+
+			got := strings.Join(api.texts(), "\n")
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("reply missing %q in:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
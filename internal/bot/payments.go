@@ -0,0 +1,90 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// premiumPeriod is how long one Premium purchase lasts
+const premiumPeriod = 30 * 24 * time.Hour
+
+// premiumPayload identifies Premium invoices in pre-checkout and payment updates
+const premiumPayload = "premium_30d"
+
+// paymentsEnabled reports whether real purchases are configured, disabling the free MVP activation
+func (b *Bot) paymentsEnabled() bool {
+	return b.cfg.PaymentProviderToken != ""
+}
+
+// formatPrice renders an amount in the currency's smallest units, e.g. 499 USD -> "4.99 USD"
+func formatPrice(amount int, currency string) string {
+	return fmt.Sprintf("%d.%02d %s", amount/100, amount%100, currency)
+}
+
+// premiumInvoice builds the Telegram invoice for one Premium period
+func (b *Bot) premiumInvoice(chatID int64) tgbotapi.InvoiceConfig {
+	prices := []tgbotapi.LabeledPrice{
+		{Label: "Premium (30 days)", Amount: b.cfg.PremiumPrice},
+	}
+	return tgbotapi.NewInvoice(
+		chatID,
+		"Trending Sounds Premium",
+		"30 days of Premium: all niches, alerts every 3 hours and top 10 sounds.",
+		premiumPayload,
+		b.cfg.PaymentProviderToken,
+		"premium",
+		b.cfg.PremiumCurrency,
+		prices,
+	)
+}
+
+// handlePreCheckoutQuery confirms a Premium checkout if the invoice matches the configured price
+func (b *Bot) handlePreCheckoutQuery(query *tgbotapi.PreCheckoutQuery) {
+	answer := tgbotapi.PreCheckoutConfig{PreCheckoutQueryID: query.ID, OK: true}
+
+	if query.InvoicePayload != premiumPayload || query.Currency != b.cfg.PremiumCurrency || query.TotalAmount != b.cfg.PremiumPrice {
+		log.Printf("Rejecting checkout from %d: payload %q, %s", query.From.ID, query.InvoicePayload, formatPrice(query.TotalAmount, query.Currency))
+		answer.OK = false
+		answer.ErrorMessage = "This offer has changed. Please use /premium to get a new invoice."
+	}
+
+	if _, err := b.api.Request(answer); err != nil {
+		log.Printf("Error answering pre-checkout query: %v", err)
+	}
+}
+
+// handleSuccessfulPayment grants Premium for one period after a completed purchase,
+// extending any Premium the user still has
+func (b *Bot) handleSuccessfulPayment(message *tgbotapi.Message) {
+	payment := message.SuccessfulPayment
+	if payment.InvoicePayload != premiumPayload {
+		log.Printf("Ignoring payment with unknown payload %q from %d", payment.InvoicePayload, message.From.ID)
+		return
+	}
+
+	telegramID := message.From.ID
+
+	log.Printf("Payment from %d: %s (charge %s)", telegramID, formatPrice(payment.TotalAmount, payment.Currency), payment.TelegramPaymentChargeID)
+
+	// A buyer who never ran /start has no row to update yet
+	if _, err := b.ensureUser(telegramID); err != nil {
+		log.Printf("Error getting user %d after payment: %v", telegramID, err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Payment received, but activation failed. Please contact support.")
+		b.api.Send(msg)
+		return
+	}
+
+	expiresAt, err := b.storage.GrantPremium(telegramID, premiumPeriod)
+	if err != nil {
+		log.Printf("Error granting premium to %d after payment: %v", telegramID, err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Payment received, but activation failed. Please contact support.")
+		b.api.Send(msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("🎉 Thank you! Premium is active until %s.\n\nUse /niches to select more niches!", expiresAt.Format("Jan 02, 2006")))
+	b.api.Send(msg)
+}
@@ -0,0 +1,192 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// paymentConfig enables Telegram Payments with a 4.99 EUR price
+var paymentConfig = map[string]string{
+	"PAYMENT_PROVIDER_TOKEN": "provider-token",
+	"PREMIUM_PRICE":          "499",
+	"PREMIUM_CURRENCY":       "EUR",
+}
+
+// paymentMessage builds a successful payment update from the given user
+func paymentMessage(from int64, payload string) *tgbotapi.Message {
+	return &tgbotapi.Message{
+		From: &tgbotapi.User{ID: from},
+		Chat: &tgbotapi.Chat{ID: from},
+		SuccessfulPayment: &tgbotapi.SuccessfulPayment{
+			Currency:                "EUR",
+			TotalAmount:             499,
+			InvoicePayload:          payload,
+			TelegramPaymentChargeID: "charge-1",
+		},
+	}
+}
+
+func TestFormatPrice(t *testing.T) {
+	tests := []struct {
+		amount   int
+		currency string
+		want     string
+	}{
+		{499, "USD", "4.99 USD"},
+		{1000, "EUR", "10.00 EUR"},
+		{5, "USD", "0.05 USD"},
+	}
+	for _, tt := range tests {
+		if got := formatPrice(tt.amount, tt.currency); got != tt.want {
+			t.Errorf("formatPrice(%d, %s) = %q, want %q", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestPremiumInvoice(t *testing.T) {
+	b, _, _ := newTestBot(t, newTestConfig(t, paymentConfig))
+
+	invoice := b.premiumInvoice(42)
+
+	if invoice.ChatID != 42 {
+		t.Errorf("ChatID = %d, want 42", invoice.ChatID)
+	}
+	if invoice.Payload != premiumPayload || invoice.ProviderToken != "provider-token" || invoice.Currency != "EUR" {
+		t.Errorf("invoice = payload %q, token %q, currency %q", invoice.Payload, invoice.ProviderToken, invoice.Currency)
+	}
+	if len(invoice.Prices) != 1 || invoice.Prices[0].Amount != 499 {
+		t.Errorf("Prices = %+v, want a single 499 price", invoice.Prices)
+	}
+}
+
+func TestHandlePremiumSendsInvoice(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		wantInvoice bool
+	}{
+		{name: "payments configured", env: paymentConfig, wantInvoice: true},
+		{name: "free activation", env: nil, wantInvoice: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, newTestConfig(t, tt.env))
+			addUser(t, s, 1, "fitness")
+
+			b.handlePremium(commandMessage(1, "/premium"))
+
+			gotInvoice := false
+			for _, c := range api.sent {
+				if _, ok := c.(tgbotapi.InvoiceConfig); ok {
+					gotInvoice = true
+				}
+			}
+			if gotInvoice != tt.wantInvoice {
+				t.Errorf("sent invoice = %v, want %v", gotInvoice, tt.wantInvoice)
+			}
+		})
+	}
+}
+
+func TestHandlePreCheckoutQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		currency string
+		amount   int
+		wantOK   bool
+	}{
+		{name: "matching invoice", payload: premiumPayload, currency: "EUR", amount: 499, wantOK: true},
+		{name: "old price", payload: premiumPayload, currency: "EUR", amount: 299, wantOK: false},
+		{name: "other currency", payload: premiumPayload, currency: "USD", amount: 499, wantOK: false},
+		{name: "unknown payload", payload: "lifetime", currency: "EUR", amount: 499, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, _, api := newTestBot(t, newTestConfig(t, paymentConfig))
+
+			b.handlePreCheckoutQuery(&tgbotapi.PreCheckoutQuery{
+				ID:             "checkout-1",
+				From:           &tgbotapi.User{ID: 1},
+				Currency:       tt.currency,
+				TotalAmount:    tt.amount,
+				InvoicePayload: tt.payload,
+			})
+
+			if len(api.requests) != 1 {
+				t.Fatalf("made %d requests, want one pre-checkout answer", len(api.requests))
+			}
+			answer := api.requests[0].(tgbotapi.PreCheckoutConfig)
+			if answer.PreCheckoutQueryID != "checkout-1" || answer.OK != tt.wantOK {
+				t.Errorf("answer = %q OK %v, want checkout-1 OK %v", answer.PreCheckoutQueryID, answer.OK, tt.wantOK)
+			}
+			if !tt.wantOK && answer.ErrorMessage == "" {
+				t.Error("rejected checkout has no error message")
+			}
+		})
+	}
+}
+
+func TestHandleSuccessfulPayment(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		registered  bool
+		expiry      time.Time // existing premium expiry, zero for none
+		payload     string
+		wantPremium bool
+		wantUntil   time.Time
+	}{
+		{name: "registered user", registered: true, payload: premiumPayload, wantPremium: true, wantUntil: now.Add(premiumPeriod)},
+		{name: "never ran /start", registered: false, payload: premiumPayload, wantPremium: true, wantUntil: now.Add(premiumPeriod)},
+		{name: "renewal extends expiry", registered: true, expiry: now.Add(10 * 24 * time.Hour), payload: premiumPayload, wantPremium: true, wantUntil: now.Add(10*24*time.Hour + premiumPeriod)},
+		{name: "unknown payload", registered: true, payload: "lifetime", wantPremium: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, newTestConfig(t, paymentConfig))
+			if tt.registered {
+				addUser(t, s, 1, "fitness")
+			}
+			if !tt.expiry.IsZero() {
+				if err := s.SetPremiumExpiry(1, tt.expiry); err != nil {
+					t.Fatalf("SetPremiumExpiry() error: %v", err)
+				}
+			}
+
+			// Payments arrive as plain messages, not commands
+			b.handleMessage(paymentMessage(1, tt.payload))
+
+			user, err := s.GetUser(1)
+			if tt.wantPremium {
+				if err != nil {
+					t.Fatalf("GetUser() error: %v", err)
+				}
+				if !user.IsPremium {
+					t.Error("user is not premium after paying")
+				}
+			} else if err == nil && user.IsPremium {
+				t.Error("unknown payload granted premium")
+			}
+
+			texts := api.texts()
+			if !tt.wantPremium {
+				if len(texts) != 0 {
+					t.Errorf("replies = %q, want none", texts)
+				}
+				return
+			}
+			want := "Premium is active until " + tt.wantUntil.Format("Jan 02, 2006")
+			if len(texts) != 1 || !strings.Contains(texts[0], want) {
+				t.Errorf("replies = %q, want one containing %q", texts, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package bot
+
+import (
+	"log"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// minPollBackoff is the first retry delay after a failed update fetch
+const minPollBackoff = time.Second
+
+// updateBuffer is how many fetched updates may wait for the handler loop
+const updateBuffer = 100
+
+// nextPollBackoff doubles the retry delay, starting at minPollBackoff and capped at max
+func nextPollBackoff(current, max time.Duration) time.Duration {
+	// A cap below the first delay would otherwise drop retries to zero and spin
+	if max < minPollBackoff {
+		max = minPollBackoff
+	}
+	if current < minPollBackoff {
+		return minPollBackoff
+	}
+	if next := current * 2; next < max {
+		return next
+	}
+	return max
+}
+
+// pollUpdates long-polls Telegram for updates and delivers them on the returned channel.
+// Unlike GetUpdatesChan, failed fetches back off exponentially and recovery is logged.
+func (b *Bot) pollUpdates() <-chan tgbotapi.Update {
+	ch := make(chan tgbotapi.Update, updateBuffer)
+
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = int(b.cfg.PollTimeout / time.Second)
+	if b.cfg.SkipPendingUpdates {
+		u.Offset = b.pendingUpdatesOffset()
+	}
+
+	go func() {
+		var backoff time.Duration
+		failures := 0
+		for {
+			updates, err := b.api.GetUpdates(u)
+			if err != nil {
+				failures++
+				backoff = nextPollBackoff(backoff, b.cfg.PollMaxBackoff)
+				log.Printf("Failed to get updates (attempt %d): %v. Retrying in %s", failures, err, backoff)
+				time.Sleep(backoff)
+				continue
+			}
+			if failures > 0 {
+				log.Printf("Update polling recovered after %d failed attempts", failures)
+				failures = 0
+				backoff = 0
+			}
+
+			for _, update := range updates {
+				if update.UpdateID >= u.Offset {
+					u.Offset = update.UpdateID + 1
+					ch <- update
+				}
+			}
+		}
+	}()
+
+	return ch
+}
+
+// pendingUpdatesOffset returns the offset just past the newest queued update,
+// so updates sent while the bot was down are skipped
+func (b *Bot) pendingUpdatesOffset() int {
+	u := tgbotapi.NewUpdate(-1)
+	u.Limit = 1
+	updates, err := b.api.GetUpdates(u)
+	if err != nil {
+		log.Printf("Error skipping pending updates: %v", err)
+		return 0
+	}
+	if len(updates) == 0 {
+		return 0
+	}
+
+	log.Printf("Skipping pending updates up to %d", updates[0].UpdateID)
+	return updates[0].UpdateID + 1
+}
@@ -0,0 +1,144 @@
+package bot
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestNextPollBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		current time.Duration
+		max     time.Duration
+		want    time.Duration
+	}{
+		{name: "first failure", current: 0, max: time.Minute, want: time.Second},
+		{name: "doubles", current: 4 * time.Second, max: time.Minute, want: 8 * time.Second},
+		{name: "capped", current: 40 * time.Second, max: time.Minute, want: time.Minute},
+		{name: "stays at cap", current: time.Minute, max: time.Minute, want: time.Minute},
+		{name: "cap below minimum", current: time.Second, max: 0, want: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextPollBackoff(tt.current, tt.max); got != tt.want {
+				t.Errorf("nextPollBackoff(%s, %s) = %s, want %s", tt.current, tt.max, got, tt.want)
+			}
+		})
+	}
+}
+
+// pollingAPI answers GetUpdates from a script, one entry per call, then blocks
+type pollingAPI struct {
+	fakeAPI
+	mu      sync.Mutex
+	script  []pollResult
+	offsets []int // Offset of every GetUpdates call
+	done    chan struct{}
+}
+
+type pollResult struct {
+	ids []int
+	err error
+}
+
+func (f *pollingAPI) GetUpdates(u tgbotapi.UpdateConfig) ([]tgbotapi.Update, error) {
+	f.mu.Lock()
+	f.offsets = append(f.offsets, u.Offset)
+	if len(f.script) == 0 {
+		f.mu.Unlock()
+		<-f.done
+		return nil, nil
+	}
+	next := f.script[0]
+	f.script = f.script[1:]
+	f.mu.Unlock()
+
+	var updates []tgbotapi.Update
+	for _, id := range next.ids {
+		updates = append(updates, tgbotapi.Update{UpdateID: id})
+	}
+	return updates, next.err
+}
+
+func TestPollUpdates(t *testing.T) {
+	tests := []struct {
+		name        string
+		skip        bool
+		script      []pollResult
+		wantIDs     []int
+		wantOffsets []int
+	}{
+		{
+			name:        "delivers in order and advances the offset",
+			script:      []pollResult{{ids: []int{5, 6}}, {ids: []int{7}}},
+			wantIDs:     []int{5, 6, 7},
+			wantOffsets: []int{0, 7, 8},
+		},
+		{
+			name:        "drops updates below the offset",
+			script:      []pollResult{{ids: []int{5}}, {ids: []int{4, 5, 6}}},
+			wantIDs:     []int{5, 6},
+			wantOffsets: []int{0, 6, 7},
+		},
+		{
+			name:        "retries after a failure",
+			script:      []pollResult{{err: errors.New("network down")}, {ids: []int{1}}},
+			wantIDs:     []int{1},
+			wantOffsets: []int{0, 0, 2},
+		},
+		{
+			name:        "skips pending updates",
+			skip:        true,
+			script:      []pollResult{{ids: []int{41}}, {ids: []int{42}}},
+			wantIDs:     []int{42},
+			wantOffsets: []int{-1, 42, 43},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t, map[string]string{"POLL_TIMEOUT": "1s"})
+			cfg.SkipPendingUpdates = tt.skip
+			b, _, _ := newTestBot(t, cfg)
+			api := &pollingAPI{script: tt.script, done: make(chan struct{})}
+			t.Cleanup(func() { close(api.done) })
+			b.api.TelegramAPI = api
+
+			updates := b.pollUpdates()
+
+			var got []int
+			for range tt.wantIDs {
+				select {
+				case update := <-updates:
+					got = append(got, update.UpdateID)
+				case <-time.After(3 * time.Second):
+					t.Fatalf("got updates %v, timed out waiting for %v", got, tt.wantIDs)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.wantIDs) {
+				t.Errorf("updates = %v, want %v", got, tt.wantIDs)
+			}
+
+			// Wait for the poller to block on the call after the script runs out
+			deadline := time.Now().Add(time.Second)
+			for {
+				api.mu.Lock()
+				offsets := append([]int(nil), api.offsets...)
+				api.mu.Unlock()
+				if len(offsets) >= len(tt.wantOffsets) || time.Now().After(deadline) {
+					if !reflect.DeepEqual(offsets, tt.wantOffsets) {
+						t.Errorf("GetUpdates offsets = %v, want %v", offsets, tt.wantOffsets)
+					}
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package bot
+
+import (
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// sendLimiter is a sliding-window limiter allowing at most limit sends in any window
+type sendLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	sent   []time.Time // Send times inside the current window, oldest first
+}
+
+// newSendLimiter creates a limiter; limit <= 0 or window <= 0 disables limiting
+func newSendLimiter(limit int, window time.Duration) *sendLimiter {
+	return &sendLimiter{limit: limit, window: window}
+}
+
+// Wait blocks until another send fits in the window, then records it
+func (l *sendLimiter) Wait() {
+	if l.limit <= 0 || l.window <= 0 {
+		return
+	}
+
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		cutoff := now.Add(-l.window)
+		for len(l.sent) > 0 && !l.sent[0].After(cutoff) {
+			l.sent = l.sent[1:]
+		}
+		if len(l.sent) < l.limit {
+			l.sent = append(l.sent, now)
+			l.mu.Unlock()
+			return
+		}
+		wait := l.sent[0].Sub(cutoff)
+		l.mu.Unlock()
+
+		time.Sleep(wait)
+	}
+}
+
+// limitedAPI wraps the Telegram API so every Send and Request, interactive or scheduled, shares one limiter
+type limitedAPI struct {
+	TelegramAPI
+	limiter *sendLimiter
+}
+
+// Send waits for the shared limiter before sending
+func (a *limitedAPI) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	a.limiter.Wait()
+	return a.TelegramAPI.Send(c)
+}
+
+// Request waits for the shared limiter too: callback answers, invoices and menu updates
+// count against Telegram's limits like any message
+func (a *limitedAPI) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	a.limiter.Wait()
+	return a.TelegramAPI.Request(c)
+}
@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// timedAPI records when each Send or Request reached Telegram
+type timedAPI struct {
+	fakeAPI
+	mu    sync.Mutex
+	calls []time.Time
+}
+
+func (f *timedAPI) record() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, time.Now())
+}
+
+func (f *timedAPI) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	f.record()
+	return f.fakeAPI.Send(c)
+}
+
+func (f *timedAPI) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	f.record()
+	return f.fakeAPI.Request(c)
+}
+
+func TestLimitedAPIConcurrent(t *testing.T) {
+	const limit, window = 3, 100 * time.Millisecond
+
+	tests := []struct {
+		name     string
+		sends    int
+		requests int
+	}{
+		{name: "sends", sends: 9},
+		{name: "requests", requests: 9},
+		{name: "sends and requests share the limit", sends: 5, requests: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &timedAPI{}
+			api := &limitedAPI{TelegramAPI: fake, limiter: newSendLimiter(limit, window)}
+
+			var wg sync.WaitGroup
+			for i := 0; i < tt.sends+tt.requests; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					if i < tt.sends {
+						api.Send(tgbotapi.NewMessage(1, "hi"))
+					} else {
+						api.Request(tgbotapi.NewCallback("cb", ""))
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			calls := fake.calls
+			if len(calls) != tt.sends+tt.requests {
+				t.Fatalf("made %d calls, want %d", len(calls), tt.sends+tt.requests)
+			}
+			sort.Slice(calls, func(i, j int) bool { return calls[i].Before(calls[j]) })
+			// Any limit+1 consecutive calls must span a window, less a little scheduling slack
+			for i := limit; i < len(calls); i++ {
+				if span := calls[i].Sub(calls[i-limit]); span < window-5*time.Millisecond {
+					t.Errorf("calls %d..%d within %v, want at most %d per %v", i-limit, i, span, limit, window)
+				}
+			}
+		})
+	}
+}
+
+func TestSendLimiterDisabled(t *testing.T) {
+	for _, l := range []*sendLimiter{newSendLimiter(0, time.Hour), newSendLimiter(1, 0)} {
+		start := time.Now()
+		for i := 0; i < 100; i++ {
+			l.Wait()
+		}
+		if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+			t.Errorf("disabled limiter (%d per %v) took %v for 100 waits", l.limit, l.window, elapsed)
+		}
+	}
+}
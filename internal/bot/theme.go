@@ -0,0 +1,52 @@
+package bot
+
+import "log"
+
+// Theme element keys, used as MESSAGE_THEME keys
+const (
+	ThemeTitle      = "title"
+	ThemeUses       = "uses"
+	ThemeLink       = "link"
+	ThemeID         = "id"
+	ThemeCrossNiche = "cross_niche"
+	ThemeNew        = "new"
+	ThemeShare      = "share"
+	ThemeMilestone  = "milestone"
+	ThemeGenre      = "genre"
+	ThemeSeasonal   = "seasonal"
+)
+
+// theme maps message elements to the emoji rendered in front of them
+type theme map[string]string
+
+// defaultTheme is the emoji set used when no overrides are configured
+var defaultTheme = theme{
+	ThemeTitle:      "🔥",
+	ThemeUses:       "📊",
+	ThemeLink:       "🔗",
+	ThemeID:         "🆔",
+	ThemeCrossNiche: "🌐",
+	ThemeNew:        "🆕",
+	ThemeShare:      "📤",
+	ThemeMilestone:  "🎯",
+	ThemeGenre:      "🎼",
+	ThemeSeasonal:   "🔁",
+}
+
+// newTheme applies overrides on top of the default theme. Unknown keys are ignored.
+func newTheme(overrides map[string]string) theme {
+	t := make(theme, len(defaultTheme))
+	for key, emoji := range defaultTheme {
+		t[key] = emoji
+	}
+
+	for key, emoji := range overrides {
+		if _, ok := t[key]; !ok {
+			log.Printf("Ignoring unknown message theme key: %s", key)
+			continue
+		}
+		t[key] = emoji
+	}
+
+	return t
+}
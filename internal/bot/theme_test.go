@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+func TestNewTheme(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		want      map[string]string
+	}{
+		{name: "defaults", overrides: nil, want: map[string]string{ThemeTitle: "🔥", ThemeLink: "🔗"}},
+		{name: "override", overrides: map[string]string{ThemeTitle: "⭐"}, want: map[string]string{ThemeTitle: "⭐", ThemeLink: "🔗"}},
+		{name: "unknown key ignored", overrides: map[string]string{"sparkles": "✨"}, want: map[string]string{ThemeTitle: "🔥"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newTheme(tt.overrides)
+			if len(got) != len(defaultTheme) {
+				t.Errorf("theme has %d keys, want %d", len(got), len(defaultTheme))
+			}
+			for key, emoji := range tt.want {
+				if got[key] != emoji {
+					t.Errorf("theme[%s] = %q, want %q", key, got[key], emoji)
+				}
+			}
+		})
+	}
+}
+
+func TestNewThemeLeavesDefaultsAlone(t *testing.T) {
+	newTheme(map[string]string{ThemeTitle: "⭐"})
+	if defaultTheme[ThemeTitle] != "🔥" {
+		t.Errorf("defaultTheme[title] = %q after an override", defaultTheme[ThemeTitle])
+	}
+}
+
+func TestFormatTrendingMessageTheme(t *testing.T) {
+	sounds := []storage.TrendingSound{{Sound: storage.Sound{ID: 1, Title: "gym anthem", URL: "https://www.tiktok.com/music/x-1"}, GrowthPercent: 120}}
+
+	got := formatTrendingMessage("Fitness", sounds, newTheme(map[string]string{ThemeTitle: "⭐", ThemeLink: "👉"}))
+
+	if !strings.HasPrefix(got, "⭐ *Trending Sounds - Fitness*") {
+		t.Errorf("message does not start with the themed title:\n%s", got)
+	}
+	if !strings.Contains(got, "👉") || strings.Contains(got, "🔥") || strings.Contains(got, "🔗") {
+		t.Errorf("message does not use the theme overrides:\n%s", got)
+	}
+}
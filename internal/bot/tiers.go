@@ -0,0 +1,115 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+// growthTier is a named group of sounds growing at least Min percent in a tiered alert
+type growthTier struct {
+	Name string
+	Min  float64
+}
+
+// defaultGrowthTiers is used when ALERT_GROWTH_TIERS is not set
+var defaultGrowthTiers = []growthTier{
+	{Name: "🚀 Exploding", Min: 500},
+	{Name: "📈 Rising", Min: 150},
+	{Name: "🌱 Growing", Min: 0},
+}
+
+// newGrowthTiers builds tiers from "name=min percent" pairs, highest first.
+// An empty config keeps the default tiers.
+func newGrowthTiers(config map[string]string) ([]growthTier, error) {
+	if len(config) == 0 {
+		return defaultGrowthTiers, nil
+	}
+
+	tiers := make([]growthTier, 0, len(config))
+	for name, value := range config {
+		min, err := strconv.ParseFloat(value, 64)
+		if err != nil || min < 0 {
+			return nil, fmt.Errorf("invalid ALERT_GROWTH_TIERS entry %s=%s: expected a non-negative percent", name, value)
+		}
+		tiers = append(tiers, growthTier{Name: name, Min: min})
+	}
+	sort.Slice(tiers, func(i, j int) bool {
+		return tiers[i].Min > tiers[j].Min
+	})
+	return tiers, nil
+}
+
+// bucketByTier groups sounds by the highest tier their growth reaches, keeping their order within a
+// tier. New sounds get their own group, returned separately; sounds below every tier are dropped
+// into the lowest one so nothing detected goes missing.
+func bucketByTier(sounds []storage.TrendingSound, tiers []growthTier) (buckets [][]storage.TrendingSound, newSounds []storage.TrendingSound) {
+	buckets = make([][]storage.TrendingSound, len(tiers))
+	for _, ts := range sounds {
+		if ts.IsNew {
+			newSounds = append(newSounds, ts)
+			continue
+		}
+		tier := len(tiers) - 1
+		for i, t := range tiers {
+			if ts.GrowthPercent >= t.Min {
+				tier = i
+				break
+			}
+		}
+		buckets[tier] = append(buckets[tier], ts)
+	}
+	return buckets, newSounds
+}
+
+// orderByTier reorders sounds as a tiered alert lists them: by tier, then new sounds
+func orderByTier(sounds []storage.TrendingSound, tiers []growthTier) []storage.TrendingSound {
+	buckets, newSounds := bucketByTier(sounds, tiers)
+	ordered := make([]storage.TrendingSound, 0, len(sounds))
+	for _, bucket := range buckets {
+		ordered = append(ordered, bucket...)
+	}
+	return append(ordered, newSounds...)
+}
+
+// tierHeader renders a tier name with its growth range, like "🚀 Exploding (>500%)" or "📈 Rising (150–500%)"
+func tierHeader(tiers []growthTier, i int) string {
+	switch {
+	case i == 0:
+		return fmt.Sprintf("%s (>%.0f%%)", tiers[i].Name, tiers[i].Min)
+	case tiers[i].Min == 0:
+		return fmt.Sprintf("%s (<%.0f%%)", tiers[i].Name, tiers[i-1].Min)
+	default:
+		return fmt.Sprintf("%s (%.0f–%.0f%%)", tiers[i].Name, tiers[i].Min, tiers[i-1].Min)
+	}
+}
+
+// formatTrendingMessageTiered formats trending sounds grouped under growth tier headers,
+// for users who chose /format tiered. Numbering continues across tiers.
+func formatTrendingMessageTiered(categoryName string, sounds []storage.TrendingSound, t theme, tiers []growthTier) string {
+	message := fmt.Sprintf("%s *Trending Sounds - %s*\n\n", t[ThemeTitle], categoryName)
+
+	buckets, newSounds := bucketByTier(sounds, tiers)
+	number := 1
+	for i, bucket := range buckets {
+		if len(bucket) == 0 {
+			continue
+		}
+		message += fmt.Sprintf("*%s*\n\n", tierHeader(tiers, i))
+		for _, ts := range bucket {
+			message += formatSoundEntry(number, ts, categoryName, t)
+			number++
+		}
+	}
+	if len(newSounds) > 0 {
+		message += fmt.Sprintf("*%s Just discovered*\n\n", t[ThemeNew])
+		for _, ts := range newSounds {
+			message += formatSoundEntry(number, ts, categoryName, t)
+			number++
+		}
+	}
+
+	return message
+}
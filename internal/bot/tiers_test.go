@@ -0,0 +1,145 @@
+package bot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+func TestNewGrowthTiers(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]string
+		want    []growthTier
+		wantErr bool
+	}{
+		{name: "defaults", want: defaultGrowthTiers},
+		{
+			name:   "custom tiers sorted highest first",
+			config: map[string]string{"Warm": "100", "Hot": "400"},
+			want:   []growthTier{{Name: "Hot", Min: 400}, {Name: "Warm", Min: 100}},
+		},
+		{name: "not a number", config: map[string]string{"Hot": "lots"}, wantErr: true},
+		{name: "negative", config: map[string]string{"Hot": "-5"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newGrowthTiers(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newGrowthTiers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newGrowthTiers() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderByTier(t *testing.T) {
+	sound := func(title string, growth float64, isNew bool) storage.TrendingSound {
+		return storage.TrendingSound{Sound: storage.Sound{Title: title}, GrowthPercent: growth, IsNew: isNew}
+	}
+	sounds := []storage.TrendingSound{
+		sound("rising", 200, false),
+		sound("fresh", 0, true),
+		sound("exploding", 800, false),
+		sound("growing", 50, false),
+		sound("also exploding", 500, false),
+	}
+
+	tests := []struct {
+		name  string
+		tiers []growthTier
+		want  []string
+	}{
+		{
+			name:  "default tiers",
+			tiers: defaultGrowthTiers,
+			want:  []string{"exploding", "also exploding", "rising", "growing", "fresh"},
+		},
+		{
+			name:  "sounds below every tier go in the lowest",
+			tiers: []growthTier{{Name: "Hot", Min: 600}, {Name: "Warm", Min: 300}},
+			want:  []string{"exploding", "rising", "growing", "also exploding", "fresh"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, ts := range orderByTier(sounds, tt.tiers) {
+				got = append(got, ts.Title)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("orderByTier() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTierHeader(t *testing.T) {
+	tests := []struct {
+		name  string
+		tiers []growthTier
+		i     int
+		want  string
+	}{
+		{name: "top tier", tiers: defaultGrowthTiers, i: 0, want: "🚀 Exploding (>500%)"},
+		{name: "middle tier", tiers: defaultGrowthTiers, i: 1, want: "📈 Rising (150–500%)"},
+		{name: "zero tier", tiers: defaultGrowthTiers, i: 2, want: "🌱 Growing (<150%)"},
+		{name: "custom lowest tier", tiers: []growthTier{{Name: "Hot", Min: 600}, {Name: "Warm", Min: 300}}, i: 1, want: "Warm (300–600%)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tierHeader(tt.tiers, tt.i); got != tt.want {
+				t.Errorf("tierHeader() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatTrendingMessageTiered(t *testing.T) {
+	sounds := []storage.TrendingSound{
+		{Sound: storage.Sound{ID: 1, Title: "rising", URL: "https://a", UsesCount: 1500}, GrowthPercent: 250},
+		{Sound: storage.Sound{ID: 2, Title: "fresh", URL: "https://b", UsesCount: 200}, IsNew: true},
+		{Sound: storage.Sound{ID: 3, Title: "exploding", URL: "https://c", UsesCount: 9000}, GrowthPercent: 900},
+	}
+
+	msg := formatTrendingMessageTiered("Fitness", orderByTier(sounds, defaultGrowthTiers), newTheme(nil), defaultGrowthTiers)
+
+	// Headers and entries appear in tier order, numbered across tiers; empty tiers are left out
+	want := []string{
+		"*🚀 Exploding (>500%)*",
+		`*1. "exploding"*`,
+		"*📈 Rising (150–500%)*",
+		`*2. "rising"*`,
+		"Just discovered*",
+		`*3. "fresh"*`,
+	}
+	last := -1
+	for _, part := range want {
+		i := strings.Index(msg, part)
+		if i < 0 {
+			t.Fatalf("message is missing %q:\n%s", part, msg)
+		}
+		if i < last {
+			t.Errorf("%q is out of order:\n%s", part, msg)
+		}
+		last = i
+	}
+	if strings.Contains(msg, "Growing") {
+		t.Errorf("message has a header for an empty tier:\n%s", msg)
+	}
+}
+
+func TestNewWithAPIInvalidGrowthTiers(t *testing.T) {
+	cfg := newTestConfig(t, map[string]string{"ALERT_GROWTH_TIERS": "Hot=lots"})
+	s := newTestStorage(t)
+	if _, err := NewWithAPI(cfg, s, nil, &fakeAPI{}); err == nil {
+		t.Error("NewWithAPI() error = nil, want invalid tiers rejected")
+	}
+}
@@ -0,0 +1,135 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+// utcOffsetPattern matches UTC offsets like "+3", "UTC-5" or "GMT+05:30"
+var utcOffsetPattern = regexp.MustCompile(`^(?i:UTC|GMT)?([+-])(\d{1,2})(?::?(\d{2}))?$`)
+
+// errInvalidTimezone is returned for timezones that are neither an IANA name nor a UTC offset
+var errInvalidTimezone = errors.New("invalid timezone")
+
+// parseTimezone validates a timezone typed by the user and returns the form stored for them:
+// "UTC", an offset like "UTC+05:30", or an IANA name like "Europe/Moscow"
+func parseTimezone(input string) (string, error) {
+	input = strings.TrimSpace(input)
+	if strings.EqualFold(input, "UTC") || strings.EqualFold(input, "GMT") {
+		return "UTC", nil
+	}
+
+	if m := utcOffsetPattern.FindStringSubmatch(input); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes := 0
+		if m[3] != "" {
+			minutes, _ = strconv.Atoi(m[3])
+		}
+		if hours > 14 || minutes > 59 {
+			return "", errInvalidTimezone
+		}
+		return fmt.Sprintf("UTC%s%02d:%02d", m[1], hours, minutes), nil
+	}
+
+	// "Local" would be the server's zone, which is exactly what a user timezone replaces
+	if input == "" || strings.EqualFold(input, "Local") {
+		return "", errInvalidTimezone
+	}
+	loc, err := time.LoadLocation(input)
+	if err != nil {
+		return "", errInvalidTimezone
+	}
+	return loc.String(), nil
+}
+
+// loadTimezone returns the location for a timezone stored by parseTimezone, empty = UTC
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "UTC" {
+		return time.UTC, nil
+	}
+	if m := utcOffsetPattern.FindStringSubmatch(name); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		offset := hours*3600 + minutes*60
+		if m[1] == "-" {
+			offset = -offset
+		}
+		return time.FixedZone(name, offset), nil
+	}
+	return time.LoadLocation(name)
+}
+
+// UserLocation returns the timezone the user's daily alert cap resets in. Users who haven't set
+// one, or whose stored zone no longer loads, get UTC.
+func UserLocation(user *storage.User) *time.Location {
+	loc, err := loadTimezone(user.Timezone)
+	if err != nil {
+		log.Printf("Error loading timezone %q of user %d, using UTC: %v", user.Timezone, user.TelegramID, err)
+		return time.UTC
+	}
+	return loc
+}
+
+// timezoneName is how a user's timezone is shown to them
+func timezoneName(user *storage.User) string {
+	if user.Timezone == "" {
+		return "UTC"
+	}
+	return user.Timezone
+}
+
+// handleTimezone handles the /timezone [zone|reset] command setting when the user's day starts
+func (b *Bot) handleTimezone(message *tgbotapi.Message) {
+	telegramID := message.From.ID
+
+	user, err := b.storage.GetUser(telegramID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use /start first to register.")
+		b.api.Send(msg)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	const usage = "Usage: /timezone <zone|reset>\n\nUse a name like Europe/Berlin or an offset like UTC+3. Your daily alert limit resets at midnight in this timezone."
+
+	arg := strings.TrimSpace(message.CommandArguments())
+	if arg == "" {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Your timezone: %s.\n\n%s", timezoneName(user), usage))
+		b.api.Send(msg)
+		return
+	}
+
+	timezone := ""
+	if !strings.EqualFold(arg, "reset") {
+		timezone, err = parseTimezone(arg)
+		if err != nil {
+			msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Unknown timezone %q.\n\n%s", arg, usage))
+			b.api.Send(msg)
+			return
+		}
+	}
+
+	if err := b.storage.SetUserTimezone(telegramID, timezone); err != nil {
+		log.Printf("Error setting user timezone: %v", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred. Please try again later.")
+		b.api.Send(msg)
+		return
+	}
+
+	user.Timezone = timezone
+	msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("✅ Your timezone is now %s.", timezoneName(user)))
+	b.api.Send(msg)
+}
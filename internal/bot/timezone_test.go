@@ -0,0 +1,108 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+func TestParseTimezone(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "UTC", want: "UTC"},
+		{input: "gmt", want: "UTC"},
+		{input: "+3", want: "UTC+03:00"},
+		{input: "UTC-5", want: "UTC-05:00"},
+		{input: "GMT+05:30", want: "UTC+05:30"},
+		{input: "utc+0545", want: "UTC+05:45"},
+		{input: "Europe/Berlin", want: "Europe/Berlin"},
+		{input: " Asia/Tokyo ", want: "Asia/Tokyo"},
+		{input: "+15", wantErr: true},
+		{input: "+3:75", wantErr: true},
+		{input: "Local", wantErr: true},
+		{input: "Mars/Olympus", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseTimezone(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTimezone(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseTimezone(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserLocation(t *testing.T) {
+	at := time.Date(2024, 5, 1, 22, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		timezone string
+		wantHour int
+	}{
+		{timezone: "", wantHour: 22},
+		{timezone: "UTC+05:30", wantHour: 4},
+		{timezone: "UTC-03:00", wantHour: 19},
+		{timezone: "Asia/Tokyo", wantHour: 7},
+		{timezone: "Not/AZone", wantHour: 22},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.timezone, func(t *testing.T) {
+			loc := UserLocation(&storage.User{Timezone: tt.timezone})
+			if got := at.In(loc).Hour(); got != tt.wantHour {
+				t.Errorf("hour in %q = %d, want %d", tt.timezone, got, tt.wantHour)
+			}
+		})
+	}
+}
+
+func TestHandleTimezone(t *testing.T) {
+	tests := []struct {
+		name         string
+		current      string
+		text         string
+		want         string
+		wantTimezone string
+	}{
+		{name: "show default", text: "/timezone", want: "Your timezone: UTC."},
+		{name: "show current", current: "Europe/Berlin", text: "/timezone", want: "Your timezone: Europe/Berlin.", wantTimezone: "Europe/Berlin"},
+		{name: "IANA name", text: "/timezone Europe/Berlin", want: "timezone is now Europe/Berlin", wantTimezone: "Europe/Berlin"},
+		{name: "offset", text: "/timezone UTC+3", want: "timezone is now UTC+03:00", wantTimezone: "UTC+03:00"},
+		{name: "reset", current: "Europe/Berlin", text: "/timezone reset", want: "timezone is now UTC"},
+		{name: "unknown", current: "Europe/Berlin", text: "/timezone Mars/Olympus", want: `Unknown timezone "Mars/Olympus"`, wantTimezone: "Europe/Berlin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, s, api := newTestBot(t, nil)
+			addUser(t, s, 1, "fitness")
+			if err := s.SetUserTimezone(1, tt.current); err != nil {
+				t.Fatalf("SetUserTimezone() error: %v", err)
+			}
+
+			b.handleTimezone(commandMessage(1, tt.text))
+
+			texts := api.texts()
+			if len(texts) != 1 || !strings.Contains(texts[0], tt.want) {
+				t.Fatalf("replies = %q, want one containing %q", texts, tt.want)
+			}
+			user, err := s.GetUser(1)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+			if user.Timezone != tt.wantTimezone {
+				t.Errorf("Timezone = %q, want %q", user.Timezone, tt.wantTimezone)
+			}
+		})
+	}
+}
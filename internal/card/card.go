@@ -0,0 +1,151 @@
+package card
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+	"os"
+	"strings"
+	"text/template"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+// DefaultLineTemplate renders one sound per line on the card
+const DefaultLineTemplate = `{{.Index}}. {{.Title}}{{if .Author}} - {{.Author}}{{end}}  {{if .IsNew}}NEW{{else}}+{{printf "%.0f" .GrowthPercent}}%{{end}}`
+
+// Layout controls the card's size, colors, font and line format
+type Layout struct {
+	Width        int
+	Padding      int
+	LineSpacing  int
+	Background   color.RGBA
+	Foreground   color.RGBA
+	Accent       color.RGBA
+	FontPath     string // Optional TTF/OTF font, the built-in bitmap font is used if empty or unloadable
+	FontSize     float64
+	LineTemplate string // text/template executed per sound with Line as data
+}
+
+// DefaultLayout returns the default card layout
+func DefaultLayout() Layout {
+	return Layout{
+		Width:        720,
+		Padding:      32,
+		LineSpacing:  14,
+		Background:   color.RGBA{R: 18, G: 18, B: 24, A: 255},
+		Foreground:   color.RGBA{R: 240, G: 240, B: 240, A: 255},
+		Accent:       color.RGBA{R: 254, G: 44, B: 85, A: 255},
+		FontSize:     20,
+		LineTemplate: DefaultLineTemplate,
+	}
+}
+
+// Line is the data passed to the line template
+type Line struct {
+	Index int
+	storage.TrendingSound
+}
+
+// Render draws a trending-sounds card and returns it PNG-encoded
+func Render(title string, sounds []storage.TrendingSound, layout Layout) ([]byte, error) {
+	tmpl, err := template.New("line").Parse(layout.LineTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid card line template: %w", err)
+	}
+
+	lines := make([]string, 0, len(sounds))
+	for i, ts := range sounds {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, Line{Index: i + 1, TrendingSound: ts}); err != nil {
+			return nil, fmt.Errorf("failed to render card line: %w", err)
+		}
+		lines = append(lines, buf.String())
+	}
+
+	face := loadFace(layout.FontPath, layout.FontSize)
+	defer face.Close()
+
+	lineHeight := face.Metrics().Height.Ceil() + layout.LineSpacing
+	height := 2*layout.Padding + lineHeight*(len(lines)+2)
+
+	img := image.NewRGBA(image.Rect(0, 0, layout.Width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: layout.Background}, image.Point{}, draw.Src)
+
+	// Accent bar under the title
+	barY := layout.Padding + lineHeight
+	draw.Draw(img, image.Rect(layout.Padding, barY, layout.Width-layout.Padding, barY+3), &image.Uniform{C: layout.Accent}, image.Point{}, draw.Src)
+
+	drawer := &font.Drawer{Dst: img, Face: face}
+	y := layout.Padding + face.Metrics().Ascent.Ceil()
+
+	drawer.Src = &image.Uniform{C: layout.Accent}
+	drawer.Dot = fixed.P(layout.Padding, y)
+	drawer.DrawString(fitText(drawer, title, layout.Width-2*layout.Padding))
+
+	drawer.Src = &image.Uniform{C: layout.Foreground}
+	y += 2 * lineHeight
+	for _, line := range lines {
+		drawer.Dot = fixed.P(layout.Padding, y)
+		drawer.DrawString(fitText(drawer, line, layout.Width-2*layout.Padding))
+		y += lineHeight
+	}
+
+	var out bytes.Buffer
+	if err := png.Encode(&out, img); err != nil {
+		return nil, fmt.Errorf("failed to encode card: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// loadFace loads the configured font, falling back to the built-in bitmap font on any failure
+func loadFace(path string, size float64) font.Face {
+	if path == "" {
+		return basicfont.Face7x13
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("Error reading card font %s, using built-in font: %v", path, err)
+		return basicfont.Face7x13
+	}
+
+	parsed, err := opentype.Parse(data)
+	if err != nil {
+		log.Printf("Error parsing card font %s, using built-in font: %v", path, err)
+		return basicfont.Face7x13
+	}
+
+	face, err := opentype.NewFace(parsed, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		log.Printf("Error creating card font face, using built-in font: %v", err)
+		return basicfont.Face7x13
+	}
+	return face
+}
+
+// fitText truncates text with an ellipsis so it fits within maxWidth pixels
+func fitText(drawer *font.Drawer, text string, maxWidth int) string {
+	if drawer.MeasureString(text).Ceil() <= maxWidth {
+		return text
+	}
+
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		candidate := strings.TrimSpace(string(runes)) + "..."
+		if drawer.MeasureString(candidate).Ceil() <= maxWidth {
+			return candidate
+		}
+	}
+	return ""
+}
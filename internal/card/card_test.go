@@ -0,0 +1,106 @@
+package card
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+func trending(titles ...string) []storage.TrendingSound {
+	out := make([]storage.TrendingSound, len(titles))
+	for i, title := range titles {
+		out[i] = storage.TrendingSound{Sound: storage.Sound{Title: title, Author: "author"}, GrowthPercent: 250}
+	}
+	return out
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		sounds   []storage.TrendingSound
+		wantErr  string
+	}{
+		{name: "default template", template: DefaultLineTemplate, sounds: trending("a", "b", "c")},
+		{name: "no sounds", template: DefaultLineTemplate, sounds: nil},
+		{name: "long title is truncated", template: DefaultLineTemplate, sounds: trending(strings.Repeat("long ", 200))},
+		{name: "unparsable template", template: "{{.Title", sounds: trending("a"), wantErr: "invalid card line template"},
+		{name: "unknown field", template: "{{.Nope}}", sounds: trending("a"), wantErr: "failed to render card line"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout := DefaultLayout()
+			layout.LineTemplate = tt.template
+
+			data, err := Render("Trending Sounds - Fitness", tt.sounds, layout)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Render() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render() error: %v", err)
+			}
+
+			img, err := png.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("png.Decode() error: %v", err)
+			}
+			if got := img.Bounds().Dx(); got != layout.Width {
+				t.Errorf("width = %d, want %d", got, layout.Width)
+			}
+			// Title, accent bar and one row per sound
+			lineHeight := basicfont.Face7x13.Metrics().Height.Ceil() + layout.LineSpacing
+			if got, want := img.Bounds().Dy(), 2*layout.Padding+lineHeight*(len(tt.sounds)+2); got != want {
+				t.Errorf("height = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestFitText(t *testing.T) {
+	drawer := &font.Drawer{Face: basicfont.Face7x13}
+
+	tests := []struct {
+		name     string
+		text     string
+		maxWidth int
+		want     string
+	}{
+		{name: "fits", text: "short", maxWidth: 100, want: "short"},
+		{name: "truncated", text: "a long title", maxWidth: 7 * 9, want: "a long..."},
+		{name: "nothing fits", text: "title", maxWidth: 5, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fitText(drawer, tt.text, tt.maxWidth); got != tt.want {
+				t.Errorf("fitText(%q, %d) = %q, want %q", tt.text, tt.maxWidth, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFaceFallsBack(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "font.ttf")
+	if err := os.WriteFile(bad, []byte("not a font"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+
+	for _, path := range []string{"", filepath.Join(dir, "missing.ttf"), bad} {
+		if face := loadFace(path, 20); face != basicfont.Face7x13 {
+			t.Errorf("loadFace(%q) did not fall back to the built-in font", path)
+		}
+	}
+}
@@ -2,9 +2,14 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/robfig/cron/v3"
 )
 
 // Config holds application configuration
@@ -12,18 +17,428 @@ type Config struct {
 	TelegramBotToken string
 	DataDir          string
 	LogLevel         string
+	ParserCacheTTL   time.Duration
+	ParserRateLimit  int           // Requests per minute per host for HTTP parsers, 0 = unlimited
+	APITimeout       time.Duration // HTTP client timeout of the API parser
+	RodTimeout       time.Duration // Page timeout of the browser parser
+	RodMaxPages      int           // Browser pages the rod parser may have open at once
+	AdminIDs         []int64
+	BroadcastChannel int64 // Chat ID of a public channel that gets every newly detected trend, 0 = disabled
+	HostAliases      map[string]string
+	CategoryCrons    map[string]string // Per-category collection cron overrides, keyed by category
+	CategoryQueries  map[string]string // Per-category API query values, keyed by category
+	ParserRoutes     map[string]string // Parser name (api or rod) keyed by category, others use the API parser
+	Regions          []string          // Regions collected for every category, "global" is the worldwide list
+	ParserDebug      bool
+	MockFallback     bool // Serve mock sounds when the API returns none, for local development
+	ParserDumpRaw    bool
+	CrossNiche       bool
+	RepairOnStart    bool
+	ShortWelcome     bool // Greet returning users with niches set briefly instead of the full onboarding
+	MinUsersForAlert int
+	MaxAlertsPerDay  int  // Per user, counted per day in the user's timezone; 0 = unlimited
+	NewSoundsLast    bool // NEW_SOUNDS_POSITION=bottom
+	BackfillCount    int  // Startup collections on an empty database, 0 = disabled
+	BackfillInterval time.Duration
+	BaselineBackdate time.Duration     // Age of the synthetic history point seeded for sounds without history, 0 = disabled
+	InitialCollect   bool              // Collect (or backfill) once shortly after startup
+	InitialDelay     time.Duration     // Wait before the startup collection and alerts
+	MessageTheme     map[string]string // Emoji overrides for alert messages, keyed by element
+	GrowthTiers      map[string]string // Tier name to minimum growth percent for tiered alerts, empty = defaults
+	RequireSustained bool
+	SustainedSamples int
+	EWMAGrowth       bool
+	BoostDuration    time.Duration // How long /boost lowers a user's growth threshold
+	BoostFactor      float64       // Multiplier applied to the growth threshold during /boost, in (0, 1)
+	EWMAAlpha        float64
+	MaxGrowthPercent float64 // Growth treated as a data error and excluded from trends, 0 = no cap
+	DedupStrategy    string  // url, title_author or music_id
+	GenreFilter      string  // Only detect sounds of this genre, empty = all
+	SendRateLimit    int     // Max Telegram messages per SendRateWindow across the bot, 0 = unlimited
+	SendRateWindow   time.Duration
+	MinDataPoints    int           // Sounds a category needs before "no trends" is shown instead of "still gathering data"
+	AlertDedupWindow time.Duration // A sound is flagged as newly trending once system-wide per window, 0 = disabled
+	AlertDedupRetain time.Duration // How long global dedup records are kept, never less than AlertDedupWindow
+	AlertInterval    time.Duration // Min gap between alert messages to the same user within a cycle
+	AlertWorkers     int           // Users alerted in parallel per cycle
+	AlertClaimTTL    time.Duration // Age after which another instance may take over a user's alert claim
+	DetectCooldown   time.Duration // Reuse a category's detection result for this long, 0 = always recompute
+
+	DetectErrorMessage          string // Shown in /trending when detection fails
+	DetectFailureAlertThreshold int    // Consecutive detection failures before admins are alerted, 0 = never
+
+	FreeResultLimit    int // Sounds per niche shown to free users
+	PremiumResultLimit int // Sounds per niche shown to premium users without a /limit override
+
+	PollTimeout        time.Duration // Telegram long-poll timeout, whole seconds
+	PollMaxBackoff     time.Duration // Longest wait between retries when fetching updates fails
+	SkipPendingUpdates bool          // Drop updates queued while the bot was offline
+
+	PaymentProviderToken string // Telegram Payments provider token, empty = free MVP activation
+	PremiumPrice         int    // In the currency's smallest units, e.g. cents
+	PremiumCurrency      string
+
+	CardFontPath     string // Optional TTF/OTF font for /card images
+	CardLineTemplate string // text/template for each sound line on /card images
+
+	ClickTrackingURL  string // Public base URL of the click redirect server, empty = alert links point straight at TikTok
+	ClickTrackingAddr string // Listen address of the click redirect server
 }
 
-// Load loads configuration from environment variables
+// Load loads configuration from environment variables, .env and an optional JSON or YAML CONFIG_FILE
 func Load() (*Config, error) {
 	// Try to load .env file (ignore error if it doesn't exist)
 	_ = godotenv.Load()
 
+	// Values from CONFIG_FILE fill in anything not set via env or .env
+	fileValues = nil
+	defer func() { fileValues = nil }()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		values, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		fileValues = values
+	}
+
 	cfg := &Config{
-		TelegramBotToken: os.Getenv("TELEGRAM_BOT_TOKEN"),
+		TelegramBotToken: getenv("TELEGRAM_BOT_TOKEN"),
 		DataDir:          getEnvOrDefault("DATA_DIR", "./data"),
 		LogLevel:         getEnvOrDefault("LOG_LEVEL", "info"),
+
+		PaymentProviderToken: getenv("PAYMENT_PROVIDER_TOKEN"),
+		PremiumCurrency:      getEnvOrDefault("PREMIUM_CURRENCY", "USD"),
+
+		CardFontPath:     getenv("CARD_FONT_PATH"),
+		CardLineTemplate: getenv("CARD_LINE_TEMPLATE"),
+
+		ClickTrackingURL:  strings.TrimRight(strings.TrimSpace(getenv("CLICK_TRACKING_URL")), "/"),
+		ClickTrackingAddr: getEnvOrDefault("CLICK_TRACKING_ADDR", ":8080"),
+	}
+
+	if cfg.ClickTrackingURL != "" {
+		u, err := url.Parse(cfg.ClickTrackingURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid CLICK_TRACKING_URL %q: expected an absolute http(s) URL", cfg.ClickTrackingURL)
+		}
+	}
+
+	premiumPrice, err := getEnvInt("PREMIUM_PRICE", 499)
+	if err != nil {
+		return nil, err
+	}
+	if premiumPrice <= 0 {
+		return nil, fmt.Errorf("PREMIUM_PRICE must be positive, got %d", premiumPrice)
+	}
+	cfg.PremiumPrice = premiumPrice
+
+	parserCacheTTL, err := getEnvDuration("PARSER_CACHE_TTL", 0)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ParserCacheTTL = parserCacheTTL
+
+	apiTimeout, err := getEnvDuration("API_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if apiTimeout <= 0 {
+		return nil, fmt.Errorf("API_TIMEOUT must be positive, got %s", apiTimeout)
+	}
+	cfg.APITimeout = apiTimeout
+
+	rodTimeout, err := getEnvDuration("ROD_TIMEOUT", 60*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if rodTimeout <= 0 {
+		return nil, fmt.Errorf("ROD_TIMEOUT must be positive, got %s", rodTimeout)
+	}
+	cfg.RodTimeout = rodTimeout
+
+	rodMaxPages, err := getEnvInt("ROD_MAX_PAGES", 3)
+	if err != nil {
+		return nil, err
+	}
+	if rodMaxPages < 1 {
+		return nil, fmt.Errorf("ROD_MAX_PAGES must be at least 1, got %d", rodMaxPages)
+	}
+	cfg.RodMaxPages = rodMaxPages
+
+	parserRateLimit, err := getEnvInt("PARSER_REQUESTS_PER_MINUTE", 30)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ParserRateLimit = parserRateLimit
+
+	adminIDs, err := getEnvInt64List("ADMIN_IDS")
+	if err != nil {
+		return nil, err
+	}
+	cfg.AdminIDs = adminIDs
+
+	if v := getenv("BROADCAST_CHANNEL"); v != "" {
+		channel, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BROADCAST_CHANNEL %q: must be a numeric chat ID: %w", v, err)
+		}
+		cfg.BroadcastChannel = channel
+	}
+
+	hostAliases, err := getEnvMap("CANONICAL_HOST_ALIASES")
+	if err != nil {
+		return nil, err
+	}
+	cfg.HostAliases = hostAliases
+
+	messageTheme, err := getEnvMap("MESSAGE_THEME")
+	if err != nil {
+		return nil, err
+	}
+	cfg.MessageTheme = messageTheme
+
+	growthTiers, err := getEnvMap("ALERT_GROWTH_TIERS")
+	if err != nil {
+		return nil, err
+	}
+	cfg.GrowthTiers = growthTiers
+
+	parserRoutes, err := getEnvMap("PARSER_ROUTES")
+	if err != nil {
+		return nil, err
+	}
+	for category, name := range parserRoutes {
+		if name != "api" && name != "rod" {
+			return nil, fmt.Errorf("invalid PARSER_ROUTES entry %s=%s: expected api or rod", category, name)
+		}
+	}
+	cfg.ParserRoutes = parserRoutes
+
+	regions, err := parseRegions(getEnvOrDefault("REGIONS", "global"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Regions = regions
+	cfg.CategoryCrons = getEnvWithPrefix("CATEGORY_CRON_")
+	for category, spec := range cfg.CategoryCrons {
+		// A bad spec would otherwise leave its category uncollected
+		if _, err := cron.ParseStandard(spec); err != nil {
+			return nil, fmt.Errorf("invalid CATEGORY_CRON_%s %q: %w", category, spec, err)
+		}
+	}
+	cfg.CategoryQueries = getEnvWithPrefix("CATEGORY_QUERY_")
+	cfg.ParserDebug = getEnvBool("PARSER_DEBUG", false)
+	cfg.MockFallback = getEnvBool("PARSER_MOCK_FALLBACK", false)
+	cfg.ParserDumpRaw = getEnvBool("PARSER_DUMP_RAW", false)
+	cfg.CrossNiche = getEnvBool("CROSS_NICHE_DETECTION", false)
+	cfg.RepairOnStart = getEnvBool("REPAIR_ON_START", false)
+	cfg.ShortWelcome = getEnvBool("SHORT_WELCOME_FOR_RETURNING", true)
+	cfg.RequireSustained = getEnvBool("REQUIRE_SUSTAINED_GROWTH", false)
+
+	sustainedSamples, err := getEnvInt("SUSTAINED_SAMPLES", 3)
+	if err != nil {
+		return nil, err
+	}
+	cfg.SustainedSamples = sustainedSamples
+
+	cfg.EWMAGrowth = getEnvBool("EWMA_GROWTH", false)
+	ewmaAlpha, err := getEnvFloat("EWMA_ALPHA", 0.5)
+	if err != nil {
+		return nil, err
+	}
+	if ewmaAlpha <= 0 || ewmaAlpha > 1 {
+		return nil, fmt.Errorf("invalid EWMA_ALPHA %v: must be in (0, 1]", ewmaAlpha)
+	}
+	cfg.EWMAAlpha = ewmaAlpha
+
+	maxGrowth, err := getEnvFloat("MAX_GROWTH_PERCENT", 100000)
+	if err != nil {
+		return nil, err
+	}
+	if maxGrowth < 0 {
+		return nil, fmt.Errorf("invalid MAX_GROWTH_PERCENT %v: must not be negative", maxGrowth)
+	}
+	cfg.MaxGrowthPercent = maxGrowth
+
+	boostDuration, err := getEnvDuration("BOOST_DURATION", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	if boostDuration <= 0 {
+		return nil, fmt.Errorf("BOOST_DURATION must be positive, got %s", boostDuration)
+	}
+	cfg.BoostDuration = boostDuration
+
+	boostFactor, err := getEnvFloat("BOOST_GROWTH_FACTOR", 0.5)
+	if err != nil {
+		return nil, err
+	}
+	if boostFactor <= 0 || boostFactor >= 1 {
+		return nil, fmt.Errorf("invalid BOOST_GROWTH_FACTOR %v: must be between 0 and 1", boostFactor)
+	}
+	cfg.BoostFactor = boostFactor
+
+	switch position := getEnvOrDefault("NEW_SOUNDS_POSITION", "top"); position {
+	case "top":
+	case "bottom":
+		cfg.NewSoundsLast = true
+	default:
+		return nil, fmt.Errorf("invalid NEW_SOUNDS_POSITION %q: expected top or bottom", position)
+	}
+
+	switch strategy := getEnvOrDefault("DEDUP_STRATEGY", "url"); strategy {
+	case "url", "title_author", "music_id":
+		cfg.DedupStrategy = strategy
+	default:
+		return nil, fmt.Errorf("invalid DEDUP_STRATEGY %q: expected url, title_author or music_id", strategy)
+	}
+
+	minUsers, err := getEnvInt("MIN_USERS_FOR_ALERTS", 0)
+	if err != nil {
+		return nil, err
+	}
+	cfg.MinUsersForAlert = minUsers
+
+	// Off by default: a cap silently stops alerts mid-day, so deployments opt into it
+	maxAlertsPerDay, err := getEnvInt("MAX_ALERTS_PER_DAY", 0)
+	if err != nil {
+		return nil, err
+	}
+	cfg.MaxAlertsPerDay = maxAlertsPerDay
+
+	alertInterval, err := getEnvDuration("ALERT_MESSAGE_INTERVAL", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	cfg.AlertInterval = alertInterval
+
+	alertWorkers, err := getEnvInt("ALERT_WORKERS", 4)
+	if err != nil {
+		return nil, err
 	}
+	if alertWorkers < 1 {
+		return nil, fmt.Errorf("ALERT_WORKERS must be at least 1, got %d", alertWorkers)
+	}
+	cfg.AlertWorkers = alertWorkers
+
+	alertClaimTTL, err := getEnvDuration("ALERT_CLAIM_TTL", 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	if alertClaimTTL <= 0 {
+		return nil, fmt.Errorf("ALERT_CLAIM_TTL must be positive, got %s", alertClaimTTL)
+	}
+	cfg.AlertClaimTTL = alertClaimTTL
+
+	freeResultLimit, err := getEnvInt("FREE_RESULT_LIMIT", 5)
+	if err != nil {
+		return nil, err
+	}
+	if freeResultLimit < 1 {
+		return nil, fmt.Errorf("FREE_RESULT_LIMIT must be at least 1, got %d", freeResultLimit)
+	}
+	cfg.FreeResultLimit = freeResultLimit
+
+	premiumResultLimit, err := getEnvInt("PREMIUM_RESULT_LIMIT", 5)
+	if err != nil {
+		return nil, err
+	}
+	if premiumResultLimit < 1 {
+		return nil, fmt.Errorf("PREMIUM_RESULT_LIMIT must be at least 1, got %d", premiumResultLimit)
+	}
+	cfg.PremiumResultLimit = premiumResultLimit
+
+	cfg.GenreFilter = strings.TrimSpace(getenv("GENRE_FILTER"))
+	cfg.DetectErrorMessage = getEnvOrDefault("DETECT_ERROR_MESSAGE", "Couldn't load trends right now, try again shortly.")
+
+	detectFailureThreshold, err := getEnvInt("DETECT_FAILURE_ALERT_THRESHOLD", 3)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DetectFailureAlertThreshold = detectFailureThreshold
+
+	detectCooldown, err := getEnvDuration("DETECT_COOLDOWN", 0)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DetectCooldown = detectCooldown
+
+	minDataPoints, err := getEnvInt("MIN_DATA_POINTS", 5)
+	if err != nil {
+		return nil, err
+	}
+	cfg.MinDataPoints = minDataPoints
+
+	backfillCount, err := getEnvInt("BACKFILL_COLLECTIONS", 0)
+	if err != nil {
+		return nil, err
+	}
+	cfg.BackfillCount = backfillCount
+
+	backfillInterval, err := getEnvDuration("BACKFILL_INTERVAL", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	cfg.BackfillInterval = backfillInterval
+
+	baselineBackdate, err := getEnvDuration("BASELINE_BACKDATE", 0)
+	if err != nil {
+		return nil, err
+	}
+	if baselineBackdate < 0 {
+		return nil, fmt.Errorf("BASELINE_BACKDATE must not be negative, got %s", baselineBackdate)
+	}
+	cfg.BaselineBackdate = baselineBackdate
+
+	cfg.InitialCollect = getEnvBool("RUN_INITIAL_COLLECTION", true)
+	initialDelay, err := getEnvDuration("INITIAL_COLLECTION_DELAY", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if initialDelay < 0 {
+		return nil, fmt.Errorf("INITIAL_COLLECTION_DELAY must not be negative, got %s", initialDelay)
+	}
+	cfg.InitialDelay = initialDelay
+
+	sendRateLimit, err := getEnvInt("SEND_RATE_LIMIT", 30)
+	if err != nil {
+		return nil, err
+	}
+	cfg.SendRateLimit = sendRateLimit
+
+	sendRateWindow, err := getEnvDuration("SEND_RATE_WINDOW", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	cfg.SendRateWindow = sendRateWindow
+
+	alertDedupWindow, err := getEnvDuration("ALERT_DEDUP_WINDOW", 0)
+	if err != nil {
+		return nil, err
+	}
+	cfg.AlertDedupWindow = alertDedupWindow
+
+	alertDedupRetain, err := getEnvDuration("ALERT_DEDUP_RETENTION", 14*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	cfg.AlertDedupRetain = alertDedupRetain
+
+	pollTimeout, err := getEnvDuration("POLL_TIMEOUT", 60*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if pollTimeout < time.Second {
+		return nil, fmt.Errorf("invalid POLL_TIMEOUT %s: must be at least 1s", pollTimeout)
+	}
+	cfg.PollTimeout = pollTimeout
+
+	pollMaxBackoff, err := getEnvDuration("POLL_MAX_BACKOFF", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	cfg.PollMaxBackoff = pollMaxBackoff
+	cfg.SkipPendingUpdates = getEnvBool("SKIP_PENDING_UPDATES", false)
 
 	// Validate required fields
 	if cfg.TelegramBotToken == "" {
@@ -35,8 +450,140 @@ func Load() (*Config, error) {
 
 // getEnvOrDefault returns environment variable value or default if not set
 func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
+	if value := getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+// getEnvDuration parses a duration environment variable like "15m" or returns the default if not set
+func getEnvDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return d, nil
+}
+
+// getEnvFloat parses a float environment variable or returns the default if not set
+func getEnvFloat(key string, defaultValue float64) (float64, error) {
+	value := getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return v, nil
+}
+
+// parseRegions parses a comma-separated region list like "global,US,GB". Region codes are
+// upper-cased, "global" is kept lower-case, and duplicates are dropped.
+func parseRegions(value string) ([]string, error) {
+	var regions []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ",") {
+		region := strings.TrimSpace(part)
+		if region == "" {
+			continue
+		}
+		if strings.EqualFold(region, "global") {
+			region = "global"
+		} else {
+			region = strings.ToUpper(region)
+		}
+		if seen[region] {
+			continue
+		}
+		seen[region] = true
+		regions = append(regions, region)
+	}
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("REGIONS must list at least one region")
+	}
+	return regions, nil
+}
+
+// getEnvInt64List parses a comma-separated list of integers like "123,456"
+func getEnvInt64List(key string) ([]int64, error) {
+	var values []int64
+	for _, part := range strings.Split(getenv(key), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		v, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q: %w", key, part, err)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// getEnvMap parses a comma-separated list of key=value pairs like "a=b,c=d"
+func getEnvMap(key string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, part := range strings.Split(getenv(key), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(part, "=")
+		if !ok || strings.TrimSpace(k) == "" || strings.TrimSpace(v) == "" {
+			return nil, fmt.Errorf("invalid %s entry %q: expected key=value", key, part)
+		}
+		values[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return values, nil
+}
+
+// getEnvWithPrefix collects all environment variables and CONFIG_FILE keys starting with prefix,
+// keyed by the lowercased remainder of the name (CATEGORY_CRON_gaming -> gaming)
+func getEnvWithPrefix(prefix string) map[string]string {
+	values := make(map[string]string)
+	add := func(key, value string) {
+		if !strings.HasPrefix(key, prefix) || value == "" {
+			return
+		}
+		values[strings.ToLower(strings.TrimPrefix(key, prefix))] = value
+	}
+
+	// Environment entries are added last so they win over the file
+	for key, value := range fileValues {
+		add(key, value)
+	}
+	for _, kv := range os.Environ() {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			add(key, value)
+		}
+	}
+	return values
+}
+
+// getEnvBool parses a boolean environment variable or returns the default if not set or invalid
+func getEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvInt parses an integer environment variable or returns the default if not set
+func getEnvInt(key string, defaultValue int) (int, error) {
+	value := getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return v, nil
+}
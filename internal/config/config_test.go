@@ -0,0 +1,1025 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loadWith loads the configuration with a bot token and the given overrides set in the environment
+func loadWith(t *testing.T, env map[string]string) (*Config, error) {
+	t.Helper()
+	t.Setenv("TELEGRAM_BOT_TOKEN", "test-token")
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	return Load()
+}
+
+func TestLoadCategoryCrons(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    map[string]string
+		wantErr string
+	}{
+		{name: "none", env: nil, want: map[string]string{}},
+		{
+			name: "valid overrides",
+			env:  map[string]string{"CATEGORY_CRON_gaming": "0 * * * *", "CATEGORY_CRON_DANCE": "@hourly"},
+			want: map[string]string{"gaming": "0 * * * *", "dance": "@hourly"},
+		},
+		{
+			name:    "invalid spec fails startup",
+			env:     map[string]string{"CATEGORY_CRON_gaming": "every hour"},
+			wantErr: `invalid CATEGORY_CRON_gaming "every hour"`,
+		},
+		{
+			name:    "seconds field is not accepted",
+			env:     map[string]string{"CATEGORY_CRON_gaming": "0 0 * * * *"},
+			wantErr: "invalid CATEGORY_CRON_gaming",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if !reflect.DeepEqual(cfg.CategoryCrons, tt.want) {
+				t.Errorf("CategoryCrons = %v, want %v", cfg.CategoryCrons, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadClickTrackingURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "disabled", value: "", want: ""},
+		{name: "trailing slash is dropped", value: " https://bot.example.com/ ", want: "https://bot.example.com"},
+		{name: "path prefix is kept", value: "http://example.com/bot", want: "http://example.com/bot"},
+		{name: "relative URL", value: "bot.example.com", wantErr: true},
+		{name: "unsupported scheme", value: "ftp://bot.example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, map[string]string{"CLICK_TRACKING_URL": tt.value})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "CLICK_TRACKING_URL") {
+					t.Fatalf("Load() error = %v, want a CLICK_TRACKING_URL error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.ClickTrackingURL != tt.want {
+				t.Errorf("ClickTrackingURL = %q, want %q", cfg.ClickTrackingURL, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMinUsersForAlerts(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: "25", want: 25},
+		{value: "many", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			cfg, err := loadWith(t, map[string]string{"MIN_USERS_FOR_ALERTS": tt.value})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "MIN_USERS_FOR_ALERTS") {
+					t.Fatalf("Load() error = %v, want a MIN_USERS_FOR_ALERTS error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.MinUsersForAlert != tt.want {
+				t.Errorf("MinUsersForAlert = %d, want %d", cfg.MinUsersForAlert, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadNewSoundsPosition(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    bool
+		wantErr bool
+	}{
+		{value: "", want: false},
+		{value: "top", want: false},
+		{value: "bottom", want: true},
+		{value: "middle", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			cfg, err := loadWith(t, map[string]string{"NEW_SOUNDS_POSITION": tt.value})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "NEW_SOUNDS_POSITION") {
+					t.Fatalf("Load() error = %v, want a NEW_SOUNDS_POSITION error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.NewSoundsLast != tt.want {
+				t.Errorf("NewSoundsLast = %v, want %v", cfg.NewSoundsLast, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadBackfill(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		wantCount    int
+		wantInterval time.Duration
+		wantErr      bool
+	}{
+		{name: "defaults", wantCount: 0, wantInterval: 15 * time.Minute},
+		{name: "configured", env: map[string]string{"BACKFILL_COLLECTIONS": "4", "BACKFILL_INTERVAL": "5m"}, wantCount: 4, wantInterval: 5 * time.Minute},
+		{name: "invalid count", env: map[string]string{"BACKFILL_COLLECTIONS": "many"}, wantErr: true},
+		{name: "invalid interval", env: map[string]string{"BACKFILL_INTERVAL": "soon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.BackfillCount != tt.wantCount || cfg.BackfillInterval != tt.wantInterval {
+				t.Errorf("backfill = %d every %s, want %d every %s", cfg.BackfillCount, cfg.BackfillInterval, tt.wantCount, tt.wantInterval)
+			}
+		})
+	}
+}
+
+func TestLoadMessageTheme(t *testing.T) {
+	cfg, err := loadWith(t, map[string]string{"MESSAGE_THEME": "title=⭐,link=👉"})
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	want := map[string]string{"title": "⭐", "link": "👉"}
+	if !reflect.DeepEqual(cfg.MessageTheme, want) {
+		t.Errorf("MessageTheme = %v, want %v", cfg.MessageTheme, want)
+	}
+}
+
+func TestLoadSustainedGrowth(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		wantRequire bool
+		wantSamples int
+		wantErr     bool
+	}{
+		{name: "defaults", wantRequire: false, wantSamples: 3},
+		{name: "enabled", env: map[string]string{"REQUIRE_SUSTAINED_GROWTH": "true", "SUSTAINED_SAMPLES": "5"}, wantRequire: true, wantSamples: 5},
+		{name: "invalid samples", env: map[string]string{"SUSTAINED_SAMPLES": "three"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.RequireSustained != tt.wantRequire || cfg.SustainedSamples != tt.wantSamples {
+				t.Errorf("sustained = %v/%d, want %v/%d", cfg.RequireSustained, cfg.SustainedSamples, tt.wantRequire, tt.wantSamples)
+			}
+		})
+	}
+}
+
+func TestLoadCategoryQueries(t *testing.T) {
+	cfg, err := loadWith(t, map[string]string{"CATEGORY_QUERY_fitness": "sports_fitness", "CATEGORY_QUERY_GAMING": "video_games"})
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	want := map[string]string{"fitness": "sports_fitness", "gaming": "video_games"}
+	if !reflect.DeepEqual(cfg.CategoryQueries, want) {
+		t.Errorf("CategoryQueries = %v, want %v", cfg.CategoryQueries, want)
+	}
+}
+
+func TestLoadPremiumPrice(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		wantPrice    int
+		wantCurrency string
+		wantErr      bool
+	}{
+		{name: "defaults", wantPrice: 499, wantCurrency: "USD"},
+		{name: "configured", env: map[string]string{"PREMIUM_PRICE": "999", "PREMIUM_CURRENCY": "EUR"}, wantPrice: 999, wantCurrency: "EUR"},
+		{name: "zero price", env: map[string]string{"PREMIUM_PRICE": "0"}, wantErr: true},
+		{name: "not a number", env: map[string]string{"PREMIUM_PRICE": "4.99"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.PremiumPrice != tt.wantPrice || cfg.PremiumCurrency != tt.wantCurrency {
+				t.Errorf("price = %d %s, want %d %s", cfg.PremiumPrice, cfg.PremiumCurrency, tt.wantPrice, tt.wantCurrency)
+			}
+		})
+	}
+}
+
+func TestLoadRodMaxPages(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr string
+	}{
+		{value: "", want: 3},
+		{value: "1", want: 1},
+		{value: "6", want: 6},
+		{value: "0", wantErr: "ROD_MAX_PAGES must be at least 1, got 0"},
+		{value: "many", wantErr: "ROD_MAX_PAGES"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			cfg, err := loadWith(t, map[string]string{"ROD_MAX_PAGES": tt.value})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.RodMaxPages != tt.want {
+				t.Errorf("RodMaxPages = %d, want %d", cfg.RodMaxPages, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadDedupStrategy(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: "url"},
+		{value: "url", want: "url"},
+		{value: "title_author", want: "title_author"},
+		{value: "music_id", want: "music_id"},
+		{value: "fuzzy", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			cfg, err := loadWith(t, map[string]string{"DEDUP_STRATEGY": tt.value})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "DEDUP_STRATEGY") {
+					t.Fatalf("Load() error = %v, want a DEDUP_STRATEGY error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.DedupStrategy != tt.want {
+				t.Errorf("DedupStrategy = %q, want %q", cfg.DedupStrategy, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPolling(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		wantTimeout time.Duration
+		wantBackoff time.Duration
+		wantSkip    bool
+		wantErr     string
+	}{
+		{name: "defaults", env: nil, wantTimeout: time.Minute, wantBackoff: time.Minute},
+		{name: "custom", env: map[string]string{"POLL_TIMEOUT": "30s", "POLL_MAX_BACKOFF": "5m", "SKIP_PENDING_UPDATES": "true"}, wantTimeout: 30 * time.Second, wantBackoff: 5 * time.Minute, wantSkip: true},
+		{name: "timeout below a second", env: map[string]string{"POLL_TIMEOUT": "500ms"}, wantErr: "invalid POLL_TIMEOUT"},
+		{name: "unparsable backoff", env: map[string]string{"POLL_MAX_BACKOFF": "soon"}, wantErr: "POLL_MAX_BACKOFF"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.PollTimeout != tt.wantTimeout || cfg.PollMaxBackoff != tt.wantBackoff || cfg.SkipPendingUpdates != tt.wantSkip {
+				t.Errorf("got timeout %s, backoff %s, skip %v; want %s, %s, %v",
+					cfg.PollTimeout, cfg.PollMaxBackoff, cfg.SkipPendingUpdates, tt.wantTimeout, tt.wantBackoff, tt.wantSkip)
+			}
+		})
+	}
+}
+
+func TestLoadGenreFilter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "", want: ""},
+		{value: "Pop", want: "Pop"},
+		{value: "  hip hop ", want: "hip hop"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			cfg, err := loadWith(t, map[string]string{"GENRE_FILTER": tt.value})
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.GenreFilter != tt.want {
+				t.Errorf("GenreFilter = %q, want %q", cfg.GenreFilter, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadParserRateLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    int
+		wantErr bool
+	}{
+		{name: "default", want: 30},
+		{name: "custom", env: map[string]string{"PARSER_REQUESTS_PER_MINUTE": "120"}, want: 120},
+		{name: "unlimited", env: map[string]string{"PARSER_REQUESTS_PER_MINUTE": "0"}, want: 0},
+		{name: "not a number", env: map[string]string{"PARSER_REQUESTS_PER_MINUTE": "fast"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.ParserRateLimit != tt.want {
+				t.Errorf("ParserRateLimit = %d, want %d", cfg.ParserRateLimit, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadAlertDedupWindow(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "disabled by default", want: 0},
+		{name: "custom", env: map[string]string{"ALERT_DEDUP_WINDOW": "6h"}, want: 6 * time.Hour},
+		{name: "unparsable", env: map[string]string{"ALERT_DEDUP_WINDOW": "a while"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.AlertDedupWindow != tt.want {
+				t.Errorf("AlertDedupWindow = %s, want %s", cfg.AlertDedupWindow, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadAlertInterval(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "default", want: time.Second},
+		{name: "custom", env: map[string]string{"ALERT_MESSAGE_INTERVAL": "250ms"}, want: 250 * time.Millisecond},
+		{name: "disabled", env: map[string]string{"ALERT_MESSAGE_INTERVAL": "0s"}, want: 0},
+		{name: "unparsable", env: map[string]string{"ALERT_MESSAGE_INTERVAL": "1 sec"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.AlertInterval != tt.want {
+				t.Errorf("AlertInterval = %s, want %s", cfg.AlertInterval, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadParserTimeouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantAPI time.Duration
+		wantRod time.Duration
+		wantErr string
+	}{
+		{name: "defaults", wantAPI: 30 * time.Second, wantRod: time.Minute},
+		{name: "custom", env: map[string]string{"API_TIMEOUT": "10s", "ROD_TIMEOUT": "2m"}, wantAPI: 10 * time.Second, wantRod: 2 * time.Minute},
+		{name: "zero API timeout", env: map[string]string{"API_TIMEOUT": "0s"}, wantErr: "API_TIMEOUT must be positive"},
+		{name: "negative rod timeout", env: map[string]string{"ROD_TIMEOUT": "-1s"}, wantErr: "ROD_TIMEOUT must be positive"},
+		{name: "unparsable", env: map[string]string{"API_TIMEOUT": "soon"}, wantErr: "API_TIMEOUT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.APITimeout != tt.wantAPI || cfg.RodTimeout != tt.wantRod {
+				t.Errorf("got API %s, rod %s; want %s, %s", cfg.APITimeout, cfg.RodTimeout, tt.wantAPI, tt.wantRod)
+			}
+		})
+	}
+}
+
+func TestLoadAlertClaimTTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    time.Duration
+		wantErr string
+	}{
+		{name: "default", want: 10 * time.Minute},
+		{name: "custom", env: map[string]string{"ALERT_CLAIM_TTL": "30m"}, want: 30 * time.Minute},
+		{name: "zero", env: map[string]string{"ALERT_CLAIM_TTL": "0s"}, wantErr: "ALERT_CLAIM_TTL must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.AlertClaimTTL != tt.want {
+				t.Errorf("AlertClaimTTL = %s, want %s", cfg.AlertClaimTTL, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadDetectCooldown(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "disabled by default", want: 0},
+		{name: "custom", env: map[string]string{"DETECT_COOLDOWN": "30s"}, want: 30 * time.Second},
+		{name: "unparsable", env: map[string]string{"DETECT_COOLDOWN": "half a minute"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.DetectCooldown != tt.want {
+				t.Errorf("DetectCooldown = %s, want %s", cfg.DetectCooldown, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadShortWelcome(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want bool
+	}{
+		{name: "on by default", want: true},
+		{name: "disabled", env: map[string]string{"SHORT_WELCOME_FOR_RETURNING": "false"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.ShortWelcome != tt.want {
+				t.Errorf("ShortWelcome = %v, want %v", cfg.ShortWelcome, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadEWMA(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		wantOn    bool
+		wantAlpha float64
+		wantErr   bool
+	}{
+		{name: "defaults", wantAlpha: 0.5},
+		{name: "enabled", env: map[string]string{"EWMA_GROWTH": "true", "EWMA_ALPHA": "0.3"}, wantOn: true, wantAlpha: 0.3},
+		{name: "alpha of 1", env: map[string]string{"EWMA_ALPHA": "1"}, wantAlpha: 1},
+		{name: "zero alpha", env: map[string]string{"EWMA_ALPHA": "0"}, wantErr: true},
+		{name: "alpha above 1", env: map[string]string{"EWMA_ALPHA": "1.5"}, wantErr: true},
+		{name: "unparsable alpha", env: map[string]string{"EWMA_ALPHA": "half"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.EWMAGrowth != tt.wantOn || cfg.EWMAAlpha != tt.wantAlpha {
+				t.Errorf("EWMA = %v/%v, want %v/%v", cfg.EWMAGrowth, cfg.EWMAAlpha, tt.wantOn, tt.wantAlpha)
+			}
+		})
+	}
+}
+
+func TestLoadMaxGrowthPercent(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    float64
+		wantErr bool
+	}{
+		{name: "default", want: 100000},
+		{name: "custom", env: map[string]string{"MAX_GROWTH_PERCENT": "5000"}, want: 5000},
+		{name: "disabled", env: map[string]string{"MAX_GROWTH_PERCENT": "0"}, want: 0},
+		{name: "negative", env: map[string]string{"MAX_GROWTH_PERCENT": "-1"}, wantErr: true},
+		{name: "unparsable", env: map[string]string{"MAX_GROWTH_PERCENT": "lots"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.MaxGrowthPercent != tt.want {
+				t.Errorf("MaxGrowthPercent = %v, want %v", cfg.MaxGrowthPercent, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadInitialCollection(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		wantOn    bool
+		wantDelay time.Duration
+		wantErr   bool
+	}{
+		{name: "defaults", wantOn: true, wantDelay: 10 * time.Second},
+		{name: "disabled", env: map[string]string{"RUN_INITIAL_COLLECTION": "false"}, wantDelay: 10 * time.Second},
+		{name: "no delay", env: map[string]string{"INITIAL_COLLECTION_DELAY": "0s"}, wantOn: true},
+		{name: "custom delay", env: map[string]string{"INITIAL_COLLECTION_DELAY": "2m"}, wantOn: true, wantDelay: 2 * time.Minute},
+		{name: "negative delay", env: map[string]string{"INITIAL_COLLECTION_DELAY": "-1s"}, wantErr: true},
+		{name: "unparsable delay", env: map[string]string{"INITIAL_COLLECTION_DELAY": "soon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.InitialCollect != tt.wantOn || cfg.InitialDelay != tt.wantDelay {
+				t.Errorf("initial collection = %v after %s, want %v after %s", cfg.InitialCollect, cfg.InitialDelay, tt.wantOn, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func TestLoadBroadcastChannel(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    int64
+		wantErr bool
+	}{
+		{name: "disabled by default"},
+		{name: "channel ID", env: map[string]string{"BROADCAST_CHANNEL": " -1001234567890 "}, want: -1001234567890},
+		{name: "username", env: map[string]string{"BROADCAST_CHANNEL": "@trends"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.BroadcastChannel != tt.want {
+				t.Errorf("BroadcastChannel = %d, want %d", cfg.BroadcastChannel, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadGrowthTiers(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "unset", want: map[string]string{}},
+		{name: "custom", env: map[string]string{"ALERT_GROWTH_TIERS": "Hot=400, Warm=100"}, want: map[string]string{"Hot": "400", "Warm": "100"}},
+		{name: "missing percent", env: map[string]string{"ALERT_GROWTH_TIERS": "Hot"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if !reflect.DeepEqual(cfg.GrowthTiers, tt.want) {
+				t.Errorf("GrowthTiers = %v, want %v", cfg.GrowthTiers, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadAlertDedupRetention(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "default", want: 14 * 24 * time.Hour},
+		{name: "custom", env: map[string]string{"ALERT_DEDUP_RETENTION": "72h"}, want: 72 * time.Hour},
+		{name: "unparsable", env: map[string]string{"ALERT_DEDUP_RETENTION": "two weeks"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.AlertDedupRetain != tt.want {
+				t.Errorf("AlertDedupRetain = %s, want %s", cfg.AlertDedupRetain, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadBoost(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		wantDuration time.Duration
+		wantFactor   float64
+		wantErr      bool
+	}{
+		{name: "defaults", wantDuration: 24 * time.Hour, wantFactor: 0.5},
+		{name: "custom", env: map[string]string{"BOOST_DURATION": "6h", "BOOST_GROWTH_FACTOR": "0.25"}, wantDuration: 6 * time.Hour, wantFactor: 0.25},
+		{name: "zero duration", env: map[string]string{"BOOST_DURATION": "0s"}, wantErr: true},
+		{name: "factor of 1", env: map[string]string{"BOOST_GROWTH_FACTOR": "1"}, wantErr: true},
+		{name: "zero factor", env: map[string]string{"BOOST_GROWTH_FACTOR": "0"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.BoostDuration != tt.wantDuration || cfg.BoostFactor != tt.wantFactor {
+				t.Errorf("boost = %s x%v, want %s x%v", cfg.BoostDuration, cfg.BoostFactor, tt.wantDuration, tt.wantFactor)
+			}
+		})
+	}
+}
+
+func TestLoadParserRoutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "unset", want: map[string]string{}},
+		{name: "routes", env: map[string]string{"PARSER_ROUTES": "gaming=rod,dance=api"}, want: map[string]string{"gaming": "rod", "dance": "api"}},
+		{name: "unknown parser", env: map[string]string{"PARSER_ROUTES": "gaming=selenium"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if !reflect.DeepEqual(cfg.ParserRoutes, tt.want) {
+				t.Errorf("ParserRoutes = %v, want %v", cfg.ParserRoutes, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMockFallback(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want bool
+	}{
+		{name: "off by default"},
+		{name: "enabled", env: map[string]string{"PARSER_MOCK_FALLBACK": "true"}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.MockFallback != tt.want {
+				t.Errorf("MockFallback = %v, want %v", cfg.MockFallback, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRegions(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    []string
+		wantErr bool
+	}{
+		{name: "global by default", want: []string{"global"}},
+		{name: "normalized", env: map[string]string{"REGIONS": "Global, us,gb"}, want: []string{"global", "US", "GB"}},
+		{name: "duplicates dropped", env: map[string]string{"REGIONS": "US,us,,GLOBAL,global"}, want: []string{"US", "global"}},
+		{name: "empty list", env: map[string]string{"REGIONS": " , "}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if !reflect.DeepEqual(cfg.Regions, tt.want) {
+				t.Errorf("Regions = %v, want %v", cfg.Regions, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadAlertWorkers(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    int
+		wantErr bool
+	}{
+		{name: "default", want: 4},
+		{name: "custom", env: map[string]string{"ALERT_WORKERS": "16"}, want: 16},
+		{name: "sequential", env: map[string]string{"ALERT_WORKERS": "1"}, want: 1},
+		{name: "zero", env: map[string]string{"ALERT_WORKERS": "0"}, wantErr: true},
+		{name: "unparsable", env: map[string]string{"ALERT_WORKERS": "many"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.AlertWorkers != tt.want {
+				t.Errorf("AlertWorkers = %d, want %d", cfg.AlertWorkers, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMaxAlertsPerDay(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    int
+		wantErr bool
+	}{
+		{name: "off by default", want: 0},
+		{name: "custom", env: map[string]string{"MAX_ALERTS_PER_DAY": "4"}, want: 4},
+		{name: "unparsable", env: map[string]string{"MAX_ALERTS_PER_DAY": "few"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.MaxAlertsPerDay != tt.want {
+				t.Errorf("MaxAlertsPerDay = %d, want %d", cfg.MaxAlertsPerDay, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadBaselineBackdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "disabled by default", want: 0},
+		{name: "custom", env: map[string]string{"BASELINE_BACKDATE": "24h"}, want: 24 * time.Hour},
+		{name: "negative", env: map[string]string{"BASELINE_BACKDATE": "-1h"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWith(t, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Load() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.BaselineBackdate != tt.want {
+				t.Errorf("BaselineBackdate = %s, want %s", cfg.BaselineBackdate, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileValues holds the CONFIG_FILE values of the Load in progress, keyed like environment variables.
+// getenv falls back to them for keys that aren't set in the environment.
+var fileValues map[string]string
+
+// getenv returns the value of an environment variable, or the CONFIG_FILE value when the
+// variable isn't set, so env vars (and .env) always win over the file
+func getenv(key string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fileValues[key]
+}
+
+// readConfigFile reads a JSON or YAML object of environment-style keys. Files ending in
+// .yaml or .yml are parsed as YAML, anything else as JSON.
+//
+// Values may be strings, numbers, booleans, arrays (joined with commas) or
+// objects (joined as key=value pairs), e.g.:
+//
+//	{"ADMIN_IDS": [123, 456], "CANONICAL_HOST_ALIASES": {"vm.tiktok.com": "www.tiktok.com"}}
+//
+// or in YAML:
+//
+//	ADMIN_IDS: [123, 456]
+//	CANONICAL_HOST_ALIASES:
+//	  vm.tiktok.com: www.tiktok.com
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var raw map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		err = json.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, v := range raw {
+		if key == "" || strings.ContainsAny(key, "= ") {
+			return nil, fmt.Errorf("invalid config file key %q", key)
+		}
+
+		value, err := configValueString(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config file value for %s: %w", key, err)
+		}
+		values[key] = value
+	}
+
+	return values, nil
+}
+
+// configValueString converts a decoded JSON or YAML value to its environment variable form
+func configValueString(raw interface{}) (string, error) {
+	switch v := raw.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := configValueString(item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, ","), nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		parts := make([]string, 0, len(v))
+		for _, k := range keys {
+			s, err := configValueString(v[k])
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, k+"="+s)
+		}
+		return strings.Join(parts, ","), nil
+	case nil:
+		return "", nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", raw)
+	}
+}
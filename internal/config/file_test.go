@@ -0,0 +1,174 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeConfigFile writes content to a file with the given name in a temporary directory
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	const jsonFile = `{
+		"DATA_DIR": "/srv/data",
+		"ADMIN_IDS": [123, 456],
+		"FREE_RESULT_LIMIT": 3,
+		"CROSS_NICHE_DETECTION": true,
+		"CANONICAL_HOST_ALIASES": {"vm.tiktok.com": "www.tiktok.com"},
+		"CATEGORY_CRON_gaming": "0 * * * *"
+	}`
+	const yamlFile = `
+DATA_DIR: /srv/data
+ADMIN_IDS: [123, 456]
+FREE_RESULT_LIMIT: 3
+CROSS_NICHE_DETECTION: true
+CANONICAL_HOST_ALIASES:
+  vm.tiktok.com: www.tiktok.com
+CATEGORY_CRON_gaming: "0 * * * *"
+`
+
+	for _, file := range []struct{ name, content string }{
+		{"config.json", jsonFile},
+		{"config.yaml", yamlFile},
+		{"config.yml", yamlFile},
+	} {
+		t.Run(file.name, func(t *testing.T) {
+			cfg, err := loadWith(t, map[string]string{"CONFIG_FILE": writeConfigFile(t, file.name, file.content)})
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+
+			if cfg.DataDir != "/srv/data" {
+				t.Errorf("DataDir = %q, want /srv/data", cfg.DataDir)
+			}
+			if !reflect.DeepEqual(cfg.AdminIDs, []int64{123, 456}) {
+				t.Errorf("AdminIDs = %v, want [123 456]", cfg.AdminIDs)
+			}
+			if cfg.FreeResultLimit != 3 {
+				t.Errorf("FreeResultLimit = %d, want 3", cfg.FreeResultLimit)
+			}
+			if !cfg.CrossNiche {
+				t.Error("CrossNiche = false, want true")
+			}
+			if got := cfg.HostAliases["vm.tiktok.com"]; got != "www.tiktok.com" {
+				t.Errorf("HostAliases[vm.tiktok.com] = %q, want www.tiktok.com", got)
+			}
+			if got := cfg.CategoryCrons["gaming"]; got != "0 * * * *" {
+				t.Errorf("CategoryCrons[gaming] = %q, want 0 * * * *", got)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFileEnvWins(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", "DATA_DIR: /from/file\nFREE_RESULT_LIMIT: 3\nCATEGORY_CRON_gaming: \"0 * * * *\"\n")
+	cfg, err := loadWith(t, map[string]string{
+		"CONFIG_FILE":          path,
+		"DATA_DIR":             "/from/env",
+		"CATEGORY_CRON_gaming": "@hourly",
+	})
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	if cfg.DataDir != "/from/env" {
+		t.Errorf("DataDir = %q, want the env value", cfg.DataDir)
+	}
+	if cfg.FreeResultLimit != 3 {
+		t.Errorf("FreeResultLimit = %d, want the file value 3", cfg.FreeResultLimit)
+	}
+	if got := cfg.CategoryCrons["gaming"]; got != "@hourly" {
+		t.Errorf("CategoryCrons[gaming] = %q, want the env value", got)
+	}
+}
+
+func TestLoadConfigFileLeavesEnvironmentAlone(t *testing.T) {
+	t.Setenv("DATA_DIR", "")
+	os.Unsetenv("DATA_DIR")
+	path := writeConfigFile(t, "config.json", `{"DATA_DIR": "/from/file"}`)
+
+	if _, err := loadWith(t, map[string]string{"CONFIG_FILE": path}); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if value, ok := os.LookupEnv("DATA_DIR"); ok {
+		t.Errorf("DATA_DIR = %q was set in the environment", value)
+	}
+
+	// A later load without the file doesn't see its values
+	t.Setenv("CONFIG_FILE", "")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.DataDir != "./data" {
+		t.Errorf("DataDir = %q, want the default", cfg.DataDir)
+	}
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{name: "missing file", wantErr: "failed to read config file"},
+		{name: "malformed JSON", file: "config.json", content: `{"DATA_DIR": `, wantErr: "invalid config file"},
+		{name: "malformed YAML", file: "config.yaml", content: "DATA_DIR: [unclosed", wantErr: "invalid config file"},
+		{name: "not an object", file: "config.json", content: `["DATA_DIR"]`, wantErr: "invalid config file"},
+		{name: "bad key", file: "config.json", content: `{"DATA DIR": "x"}`, wantErr: `invalid config file key "DATA DIR"`},
+		{name: "invalid value is validated", file: "config.yaml", content: "FREE_RESULT_LIMIT: 0\n", wantErr: "FREE_RESULT_LIMIT must be at least 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "missing.json")
+			if tt.file != "" {
+				path = writeConfigFile(t, tt.file, tt.content)
+			}
+			_, err := loadWith(t, map[string]string{"CONFIG_FILE": path})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigValueString(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  interface{}
+		want string
+	}{
+		{name: "string", raw: "abc", want: "abc"},
+		{name: "bool", raw: true, want: "true"},
+		{name: "int", raw: 42, want: "42"},
+		{name: "float", raw: 1.5, want: "1.5"},
+		{name: "whole float", raw: 300.0, want: "300"},
+		{name: "list", raw: []interface{}{1, "b"}, want: "1,b"},
+		{name: "map sorted", raw: map[string]interface{}{"b": 2, "a": "x"}, want: "a=x,b=2"},
+		{name: "null", raw: nil, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := configValueString(tt.raw)
+			if err != nil {
+				t.Fatalf("configValueString() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("configValueString(%v) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package detector
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+// ErrNoHistoryAsOf is returned when no history was recorded in the lookback window before a date
+var ErrNoHistoryAsOf = errors.New("no history recorded for that date")
+
+// DetectTrendingAsOf reconstructs what was trending in a category at a past moment.
+// Each sound's uses are taken from the history snapshots in the lookback window ending at asOf:
+// the earliest is the baseline and the latest stands in for the uses count at that time.
+func (d *TrendDetector) DetectTrendingAsOf(category string, asOf time.Time) ([]storage.TrendingSound, error) {
+	criteria := d.criteria
+	from := asOf.Add(-time.Duration(criteria.LookbackHours) * time.Hour)
+
+	sounds, err := d.storage.GetSoundsByCategory(category, 1000)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sounds: %w", err)
+	}
+
+	var trendingSounds []storage.TrendingSound
+	sawHistory := false
+
+	for _, sound := range sounds {
+		series, err := d.storage.GetSoundHistoryBetween(sound.ID, from, asOf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get history range: %w", err)
+		}
+		if len(series) == 0 {
+			continue
+		}
+		sawHistory = true
+
+		oldCount := series[0].UsesCount
+		sound.UsesCount = series[len(series)-1].UsesCount
+
+		if sound.UsesCount < criteria.MinUsesCount || sound.UsesCount > criteria.MaxUsesCount {
+			continue
+		}
+		if criteria.AverageBaseline {
+			if avg, ok := averageBaseline(series); ok {
+				oldCount = avg
+			}
+		}
+		if oldCount == 0 {
+			continue
+		}
+
+		growth := calculateGrowth(oldCount, sound.UsesCount)
+		if growth < criteria.MinGrowth {
+			continue
+		}
+		if criteria.PeakDetection && isDecelerating(series) {
+			continue
+		}
+		if criteria.RequireSustained && !isSustained(series, oldCount, criteria.MinGrowth, criteria.SustainedSamples) {
+			continue
+		}
+
+		trendingSounds = append(trendingSounds, storage.TrendingSound{
+			Sound:         sound,
+			GrowthPercent: growth,
+			OldUsesCount:  oldCount,
+		})
+	}
+
+	if !sawHistory {
+		return nil, ErrNoHistoryAsOf
+	}
+
+	sort.Slice(trendingSounds, func(i, j int) bool {
+		return trendingSounds[i].GrowthPercent > trendingSounds[j].GrowthPercent
+	})
+
+	return trendingSounds, nil
+}
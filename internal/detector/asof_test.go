@@ -0,0 +1,70 @@
+package detector
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+func TestDetectTrendingAsOf(t *testing.T) {
+	s := newTestStorage(t)
+	asOf := time.Now().AddDate(0, 0, -10)
+
+	sounds := make(map[string]*storage.Sound)
+	for _, title := range []string{"then trending", "then flat", "later hit"} {
+		sound := &storage.Sound{Title: title, URL: "https://www.tiktok.com/music/" + title, UsesCount: 100, Category: "fitness"}
+		if err := s.SaveSound(sound); err != nil {
+			t.Fatalf("SaveSound() error: %v", err)
+		}
+		sounds[title] = sound
+	}
+
+	history := &fixedHistory{SQLiteStorage: s, samples: map[int64][]storage.SoundHistory{
+		sounds["then trending"].ID: {
+			sample(1000, asOf.Add(-20*time.Hour)),
+			sample(4000, asOf.Add(-2*time.Hour)),
+			sample(100, time.Now()), // after asOf, ignored
+		},
+		sounds["then flat"].ID: {
+			sample(1000, asOf.Add(-20*time.Hour)),
+			sample(1100, asOf.Add(-2*time.Hour)),
+		},
+		sounds["later hit"].ID: {
+			sample(1000, asOf.Add(time.Hour)),
+			sample(9000, time.Now()),
+		},
+	}}
+	d := New(history)
+
+	tests := []struct {
+		name    string
+		asOf    time.Time
+		want    []string
+		wantErr error
+	}{
+		{name: "past date", asOf: asOf, want: []string{"then trending"}},
+		{name: "before any history", asOf: asOf.AddDate(0, 0, -30), wantErr: ErrNoHistoryAsOf},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.DetectTrendingAsOf("fitness", tt.asOf)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DetectTrendingAsOf() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d trending sounds, want %v", len(got), tt.want)
+			}
+			for i, title := range tt.want {
+				if got[i].Title != title {
+					t.Errorf("trend %d = %q, want %q", i, got[i].Title, title)
+				}
+			}
+			if len(got) > 0 && (got[0].UsesCount != 4000 || got[0].OldUsesCount != 1000 || got[0].GrowthPercent != 300) {
+				t.Errorf("trend = %d uses from %d (+%.0f%%), want 4000 from 1000 (+300%%)", got[0].UsesCount, got[0].OldUsesCount, got[0].GrowthPercent)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package detector
+
+import (
+	"time"
+
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+// cooldownKey identifies a detection that can be answered from a recent result
+type cooldownKey struct {
+	category string
+	limit    int
+	criteria TrendCriteria
+}
+
+// cooldownEntry is a detection result and when it was computed
+type cooldownEntry struct {
+	sounds     []storage.TrendingSound
+	computedAt time.Time
+}
+
+// SetCooldown makes detections repeated within cooldown reuse the previous result for the same
+// category, limit and criteria instead of recomputing. 0 disables reuse.
+func (d *TrendDetector) SetCooldown(cooldown time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.cooldown = cooldown
+	d.recent = make(map[cooldownKey]cooldownEntry)
+}
+
+// recentResult returns a copy of a result computed within the cooldown, if any
+func (d *TrendDetector) recentResult(key cooldownKey) ([]storage.TrendingSound, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cooldown <= 0 {
+		return nil, false
+	}
+	entry, ok := d.recent[key]
+	if !ok || time.Since(entry.computedAt) >= d.cooldown {
+		return nil, false
+	}
+	// Callers reorder and trim results, so never hand out the stored slice
+	return append([]storage.TrendingSound(nil), entry.sounds...), true
+}
+
+// rememberResult stores a fresh result and drops ones past the cooldown
+func (d *TrendDetector) rememberResult(key cooldownKey, sounds []storage.TrendingSound) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cooldown <= 0 {
+		return
+	}
+	now := time.Now()
+	for k, entry := range d.recent {
+		if now.Sub(entry.computedAt) >= d.cooldown {
+			delete(d.recent, k)
+		}
+	}
+	d.recent[key] = cooldownEntry{
+		sounds:     append([]storage.TrendingSound(nil), sounds...),
+		computedAt: now,
+	}
+}
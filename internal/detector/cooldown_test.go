@@ -0,0 +1,109 @@
+package detector
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+// countingStorage counts how often detection loads a category's sounds
+type countingStorage struct {
+	*storage.SQLiteStorage
+	loads atomic.Int64
+}
+
+func (c *countingStorage) GetAllSoundsWithHistory(category string, lookbackHours int) ([]storage.Sound, map[int64]*storage.SoundHistory, error) {
+	c.loads.Add(1)
+	return c.SQLiteStorage.GetAllSoundsWithHistory(category, lookbackHours)
+}
+
+func TestDetectCooldown(t *testing.T) {
+	lowerGrowth := DefaultCriteria()
+	lowerGrowth.MinGrowth = 50
+
+	type call struct {
+		category string
+		limit    int
+		criteria TrendCriteria
+		wait     time.Duration // Sleep before the call
+	}
+	tests := []struct {
+		name      string
+		cooldown  time.Duration
+		calls     []call
+		wantLoads int64
+	}{
+		{
+			name:      "disabled recomputes every time",
+			cooldown:  0,
+			calls:     []call{{category: "fitness", limit: 5}, {category: "fitness", limit: 5}},
+			wantLoads: 2,
+		},
+		{
+			name:      "repeat within the cooldown is reused",
+			cooldown:  time.Minute,
+			calls:     []call{{category: "fitness", limit: 5}, {category: "fitness", limit: 5}, {category: "fitness", limit: 5}},
+			wantLoads: 1,
+		},
+		{
+			name:      "other category, limit or criteria are computed separately",
+			cooldown:  time.Minute,
+			calls:     []call{{category: "fitness", limit: 5}, {category: "gaming", limit: 5}, {category: "fitness", limit: 10}, {category: "fitness", limit: 5, criteria: lowerGrowth}},
+			wantLoads: 4,
+		},
+		{
+			name:      "expired result is recomputed",
+			cooldown:  20 * time.Millisecond,
+			calls:     []call{{category: "fitness", limit: 5}, {category: "fitness", limit: 5, wait: 40 * time.Millisecond}},
+			wantLoads: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &countingStorage{SQLiteStorage: newTestStorage(t)}
+			seedGrowth(t, s.SQLiteStorage, "gym anthem", 1000, 5000, "fitness")
+			d := New(s)
+			d.SetCooldown(tt.cooldown)
+
+			for i, c := range tt.calls {
+				time.Sleep(c.wait)
+				criteria := c.criteria
+				if criteria == (TrendCriteria{}) {
+					criteria = DefaultCriteria()
+				}
+				if _, err := d.DetectTrendingWithCriteria(c.category, c.limit, criteria); err != nil {
+					t.Fatalf("call %d: DetectTrendingWithCriteria() error: %v", i, err)
+				}
+			}
+
+			if got := s.loads.Load(); got != tt.wantLoads {
+				t.Errorf("storage loaded %d times, want %d", got, tt.wantLoads)
+			}
+		})
+	}
+}
+
+func TestDetectCooldownReturnsCopies(t *testing.T) {
+	s := newTestStorage(t)
+	seedGrowth(t, s, "gym anthem", 1000, 5000, "fitness")
+	d := New(s)
+	d.SetCooldown(time.Minute)
+
+	first, err := d.DetectTrendingWithCriteria("fitness", 5, DefaultCriteria())
+	if err != nil || len(first) != 1 {
+		t.Fatalf("DetectTrendingWithCriteria() = %v, %v, want one sound", first, err)
+	}
+	// Callers reorder and edit results; the cached one must not change
+	first[0].Title = "edited"
+
+	second, err := d.DetectTrendingWithCriteria("fitness", 5, DefaultCriteria())
+	if err != nil {
+		t.Fatalf("DetectTrendingWithCriteria() error: %v", err)
+	}
+	if len(second) != 1 || second[0].Title != "gym anthem" {
+		t.Errorf("reused result = %+v, want the original title", second)
+	}
+}
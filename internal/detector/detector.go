@@ -4,61 +4,190 @@ import (
 	"fmt"
 	"log"
 	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/yourusername/trending-sound/internal/storage"
 )
 
 // TrendDetector detects trending sounds based on growth metrics
 type TrendDetector struct {
-	storage storage.Storage
+	storage  storage.Storage
+	criteria TrendCriteria
+
+	mu       sync.Mutex
+	cooldown time.Duration
+	recent   map[cooldownKey]cooldownEntry // Results reused within the cooldown
+	timings  map[string]DetectionTiming    // Latest detection run per category
+	debug    bool
 }
 
 // New creates a new trend detector
 func New(s storage.Storage) *TrendDetector {
 	return &TrendDetector{
-		storage: s,
+		storage:  s,
+		criteria: DefaultCriteria(),
 	}
 }
 
+// SetCriteria replaces the base criteria used by DetectTrending
+func (d *TrendDetector) SetCriteria(criteria TrendCriteria) {
+	d.criteria = criteria
+}
+
+// Criteria returns the base criteria used by DetectTrending
+func (d *TrendDetector) Criteria() TrendCriteria {
+	return d.criteria
+}
+
 // TrendCriteria defines the criteria for a sound to be considered trending
 type TrendCriteria struct {
-	MinUsesCount  int64   // Minimum uses count (default: 500)
-	MaxUsesCount  int64   // Maximum uses count (default: 30000)
-	MinGrowth     float64 // Minimum growth percentage (default: 150%)
-	LookbackHours int     // Hours to look back for comparison (default: 24)
+	MinUsesCount     int64   // Minimum uses count (default: 500)
+	MaxUsesCount     int64   // Maximum uses count (default: 30000)
+	MinGrowth        float64 // Minimum growth percentage (default: 150%)
+	LookbackHours    int     // Hours to look back for comparison (default: 24)
+	PeakDetection    bool    // Reject sounds whose growth is slowing down within the lookback window (default: false)
+	IncludeRank      bool    // Fill in each result's rank within its category (default: true)
+	CrossNiche       bool    // Flag and boost sounds also seen in other categories (default: false)
+	AverageBaseline  bool    // Compare against the average of samples in the lookback window instead of the earliest one (default: false)
+	NewSoundsLast    bool    // Sort new sounds (no history yet) after growing ones instead of before (default: false)
+	RequireSustained bool    // Require MinGrowth over the baseline at each of the last SustainedSamples samples, not just now (default: false)
+	SustainedSamples int     // Consecutive samples checked by RequireSustained (default: 3)
+	Genre            string  // Only consider sounds of this genre, case-insensitive; empty means any (default: "")
+	TagSeasonal      bool    // Tag results whose full history shows a recurring spike (default: true)
+	EWMAGrowth       bool    // Measure growth with recent samples weighted more heavily than older ones (default: false)
+	EWMAAlpha        float64 // Weight of the newest step in EWMAGrowth, in (0, 1]; higher favours recent growth more (default: 0.5)
+	MaxGrowthPercent float64 // Growth above this is treated as a data error and excluded, 0 = no cap (default: 100000%)
+	Region           string  // Only consider sounds seen on this region's trending list within the lookback; empty or "global" means any (default: "")
 }
 
 // DefaultCriteria returns default trend detection criteria
 func DefaultCriteria() TrendCriteria {
 	return TrendCriteria{
-		MinUsesCount:  500,
-		MaxUsesCount:  30000,
-		MinGrowth:     150.0,
-		LookbackHours: 24,
+		MinUsesCount:     500,
+		MaxUsesCount:     30000,
+		MinGrowth:        150.0,
+		LookbackHours:    24,
+		IncludeRank:      true,
+		SustainedSamples: 3,
+		TagSeasonal:      true,
+		EWMAAlpha:        0.5,
+		MaxGrowthPercent: 100000.0,
 	}
 }
 
 // DetectTrending detects trending sounds for a specific category
 func (d *TrendDetector) DetectTrending(category string, limit int) ([]storage.TrendingSound, error) {
-	criteria := DefaultCriteria()
-	return d.DetectTrendingWithCriteria(category, limit, criteria)
+	return d.DetectTrendingWithCriteria(category, limit, d.criteria)
 }
 
 // DetectTrendingWithCriteria detects trending sounds with custom criteria
 func (d *TrendDetector) DetectTrendingWithCriteria(category string, limit int, criteria TrendCriteria) ([]storage.TrendingSound, error) {
+	key := cooldownKey{category: category, limit: limit, criteria: criteria}
+	if sounds, ok := d.recentResult(key); ok {
+		return sounds, nil
+	}
+
+	sounds, _, err := d.timedDetect(category, limit, criteria, nil)
+	if err != nil {
+		return nil, err
+	}
+	d.rememberResult(key, sounds)
+	return sounds, nil
+}
+
+// Reasons recorded in a DetectionTrace
+const (
+	ReasonIncluded           = "included"
+	ReasonNewSound           = "included: new sound"
+	ReasonBelowMinUses       = "below min uses"
+	ReasonAboveMaxUses       = "above max uses"
+	ReasonGenreMismatch      = "other genre"
+	ReasonOtherRegion        = "not trending in region"
+	ReasonNoHistory          = "no history"
+	ReasonInsufficientGrowth = "insufficient growth"
+	ReasonGrowthAboveCap     = "growth above cap, likely data error"
+	ReasonPastPeak           = "past peak"
+	ReasonNotSustained       = "growth not sustained"
+	ReasonLimitCutoff        = "cut by result limit"
+)
+
+// DetectionTrace explains why a sound was included in or excluded from the results
+type DetectionTrace struct {
+	SoundID       int64
+	Title         string
+	UsesCount     int64
+	OldUsesCount  int64
+	GrowthPercent float64
+	Included      bool
+	Reason        string
+}
+
+// ExplainTrending runs detection and also returns a trace for every candidate sound and the run's timing
+func (d *TrendDetector) ExplainTrending(category string, limit int, criteria TrendCriteria) ([]storage.TrendingSound, []DetectionTrace, DetectionTiming, error) {
+	var traces []DetectionTrace
+	trending, timing, err := d.timedDetect(category, limit, criteria, &traces)
+	if err != nil {
+		return nil, nil, timing, err
+	}
+	return trending, traces, timing, nil
+}
+
+// detect implements detection. If traces is not nil, a DetectionTrace is appended for each sound.
+// The counts in timing are filled in; the caller measures the duration.
+func (d *TrendDetector) detect(category string, limit int, criteria TrendCriteria, traces *[]DetectionTrace, timing *DetectionTiming) ([]storage.TrendingSound, error) {
+	record := func(sound storage.Sound, oldCount int64, growth float64, reason string) {
+		if traces == nil {
+			return
+		}
+		*traces = append(*traces, DetectionTrace{
+			SoundID:       sound.ID,
+			Title:         sound.Title,
+			UsesCount:     sound.UsesCount,
+			OldUsesCount:  oldCount,
+			GrowthPercent: growth,
+			Included:      reason == ReasonIncluded || reason == ReasonNewSound,
+			Reason:        reason,
+		})
+	}
+
 	// Get all sounds with their history
 	sounds, historyMap, err := d.storage.GetAllSoundsWithHistory(category, criteria.LookbackHours)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sounds with history: %w", err)
 	}
+	timing.Scanned = len(sounds)
 
 	log.Printf("Analyzing %d sounds for trends in category: %s", len(sounds), category)
 
+	var inRegion map[int64]bool
+	if !storage.IsGlobalRegion(criteria.Region) {
+		since := time.Now().Add(-time.Duration(criteria.LookbackHours) * time.Hour)
+		inRegion, err = d.storage.GetRegionSoundIDs(criteria.Region, since)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get region sounds: %w", err)
+		}
+	}
+
 	var trendingSounds []storage.TrendingSound
 
 	for _, sound := range sounds {
 		// Check if sound meets basic criteria
-		if sound.UsesCount < criteria.MinUsesCount || sound.UsesCount > criteria.MaxUsesCount {
+		if sound.UsesCount < criteria.MinUsesCount {
+			record(sound, 0, 0, ReasonBelowMinUses)
+			continue
+		}
+		if sound.UsesCount > criteria.MaxUsesCount {
+			record(sound, 0, 0, ReasonAboveMaxUses)
+			continue
+		}
+		if criteria.Genre != "" && !strings.EqualFold(sound.Genre, criteria.Genre) {
+			record(sound, 0, 0, ReasonGenreMismatch)
+			continue
+		}
+		if inRegion != nil && !inRegion[sound.ID] {
+			record(sound, 0, 0, ReasonOtherRegion)
 			continue
 		}
 
@@ -66,57 +195,354 @@ func (d *TrendDetector) DetectTrendingWithCriteria(category string, limit int, c
 		history, exists := historyMap[sound.ID]
 		if !exists || history == nil {
 			// No historical data - skip
+			record(sound, 0, 0, ReasonNoHistory)
 			continue
 		}
 
+		timing.Candidates++
+
 		// Calculate growth percentage
 		oldCount := history.UsesCount
+
+		// The full series in the window is loaded at most once, only by the checks that need it
+		var series []storage.SoundHistory
+		seriesLoaded := false
+		loadSeries := func() error {
+			if seriesLoaded {
+				return nil
+			}
+			since := time.Now().Add(-time.Duration(criteria.LookbackHours) * time.Hour)
+			var err error
+			series, err = d.storage.GetSoundHistoryRange(sound.ID, since)
+			if err != nil {
+				return fmt.Errorf("failed to get history range: %w", err)
+			}
+			seriesLoaded = true
+			return nil
+		}
+
+		// Smooth the baseline over all samples in the window
+		if criteria.AverageBaseline {
+			if err := loadSeries(); err != nil {
+				return nil, err
+			}
+			if avg, ok := averageBaseline(series); ok {
+				oldCount = avg
+			}
+		}
 		if oldCount == 0 {
 			// Avoid division by zero - if old count is 0, this is a new sound
 			// We can consider it trending if it has enough uses
 			if sound.UsesCount >= criteria.MinUsesCount {
 				trendingSounds = append(trendingSounds, storage.TrendingSound{
-					Sound:         sound,
-					GrowthPercent: 999.9, // Special marker for new sounds
-					OldUsesCount:  0,
+					Sound:        sound,
+					OldUsesCount: 0,
+					IsNew:        true,
 				})
+				record(sound, 0, 0, ReasonNewSound)
 			}
 			continue
 		}
 
 		growth := calculateGrowth(oldCount, sound.UsesCount)
 
+		// Let the most recent growth count most, so a sound accelerating now beats one that grew days ago
+		if criteria.EWMAGrowth {
+			if err := loadSeries(); err != nil {
+				return nil, err
+			}
+			if ewma, ok := ewmaGrowth(series, sound.UsesCount, criteria.EWMAAlpha); ok {
+				growth = ewma
+			}
+		}
+
 		// Check if growth meets criteria
-		if growth >= criteria.MinGrowth {
-			trendingSounds = append(trendingSounds, storage.TrendingSound{
-				Sound:         sound,
-				GrowthPercent: growth,
-				OldUsesCount:  oldCount,
-			})
+		if growth < criteria.MinGrowth {
+			record(sound, oldCount, growth, ReasonInsufficientGrowth)
+			continue
+		}
+
+		// A scraping glitch can turn hundreds of uses into millions; keep it out of alerts
+		if criteria.MaxGrowthPercent > 0 && growth > criteria.MaxGrowthPercent {
+			log.Printf("Skipping sound %d %q in %s: growth %.0f%% (%d -> %d uses) is above the %.0f%% cap, likely a data error",
+				sound.ID, sound.Title, category, growth, oldCount, sound.UsesCount, criteria.MaxGrowthPercent)
+			record(sound, oldCount, growth, ReasonGrowthAboveCap)
+			continue
+		}
+
+		// Skip sounds that are already past their peak
+		if criteria.PeakDetection {
+			if err := loadSeries(); err != nil {
+				return nil, err
+			}
+			if isDecelerating(series) {
+				record(sound, oldCount, growth, ReasonPastPeak)
+				continue
+			}
 		}
+
+		// Reject one-off spikes: growth must have held over several samples
+		if criteria.RequireSustained {
+			if err := loadSeries(); err != nil {
+				return nil, err
+			}
+			if !isSustained(series, oldCount, criteria.MinGrowth, criteria.SustainedSamples) {
+				record(sound, oldCount, growth, ReasonNotSustained)
+				continue
+			}
+		}
+
+		trendingSounds = append(trendingSounds, storage.TrendingSound{
+			Sound:         sound,
+			GrowthPercent: growth,
+			OldUsesCount:  oldCount,
+		})
+		record(sound, oldCount, growth, ReasonIncluded)
 	}
 
-	// Sort by growth percentage (descending)
+	// Flag sounds that are also collected in other niches
+	if criteria.CrossNiche {
+		since := time.Now().Add(-time.Duration(criteria.LookbackHours) * time.Hour)
+		for i := range trendingSounds {
+			categories, err := d.storage.GetSoundCategories(trendingSounds[i].ID, since)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get sound categories: %w", err)
+			}
+			trendingSounds[i].CrossNiche = len(categories) >= 2
+		}
+	}
+
+	// Sort cross-niche sounds first, then new sounds at the configured end, then by growth percentage (descending)
 	sort.Slice(trendingSounds, func(i, j int) bool {
+		if trendingSounds[i].CrossNiche != trendingSounds[j].CrossNiche {
+			return trendingSounds[i].CrossNiche
+		}
+		if trendingSounds[i].IsNew != trendingSounds[j].IsNew {
+			return trendingSounds[i].IsNew != criteria.NewSoundsLast
+		}
 		return trendingSounds[i].GrowthPercent > trendingSounds[j].GrowthPercent
 	})
 
 	// Limit results
 	if limit > 0 && len(trendingSounds) > limit {
+		for _, ts := range trendingSounds[limit:] {
+			markCutoff(traces, ts.ID)
+		}
 		trendingSounds = trendingSounds[:limit]
 	}
 
+	// Look up category rank only for the sounds we return
+	if criteria.IncludeRank {
+		for i := range trendingSounds {
+			rank, err := d.storage.GetSoundRank(trendingSounds[i].ID, category)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get sound rank: %w", err)
+			}
+			trendingSounds[i].Rank = rank
+		}
+	}
+
+	if criteria.TagSeasonal {
+		if err := d.tagSeasonal(trendingSounds); err != nil {
+			return nil, err
+		}
+	}
+
 	log.Printf("Found %d trending sounds in category: %s", len(trendingSounds), category)
 
 	return trendingSounds, nil
 }
 
+// markCutoff updates the trace of a sound that qualified but fell outside the result limit
+func markCutoff(traces *[]DetectionTrace, soundID int64) {
+	if traces == nil {
+		return
+	}
+	for i := range *traces {
+		if (*traces)[i].SoundID == soundID {
+			(*traces)[i].Included = false
+			(*traces)[i].Reason = ReasonLimitCutoff
+		}
+	}
+}
+
 // calculateGrowth calculates growth percentage
 func calculateGrowth(oldCount, newCount int64) float64 {
 	if oldCount == 0 {
 		return 0
 	}
-	return float64(newCount-oldCount) / float64(oldCount) * 100.0
+	// Convert before subtracting so extreme counts can't overflow int64
+	return (float64(newCount) - float64(oldCount)) / float64(oldCount) * 100.0
+}
+
+// ewmaGrowth returns the exponentially weighted average of the per-sample growth percentages of
+// the series followed by the current uses count, scaled by the number of steps so it is comparable
+// to the growth over the whole window. The newest step has weight alpha, the one before it
+// alpha*(1-alpha) and so on. ok is false if there are fewer than two points or a zero count.
+func ewmaGrowth(series []storage.SoundHistory, current int64, alpha float64) (float64, bool) {
+	counts := make([]int64, 0, len(series)+1)
+	for _, h := range series {
+		counts = append(counts, h.UsesCount)
+	}
+	if len(counts) == 0 || counts[len(counts)-1] != current {
+		counts = append(counts, current)
+	}
+	if len(counts) < 2 || alpha <= 0 || alpha > 1 {
+		return 0, false
+	}
+
+	steps := len(counts) - 1
+	var weighted, totalWeight float64
+	weight := alpha
+	for i := steps; i >= 1; i-- {
+		if counts[i-1] == 0 {
+			return 0, false
+		}
+		weighted += weight * calculateGrowth(counts[i-1], counts[i])
+		totalWeight += weight
+		weight *= 1 - alpha
+	}
+
+	return weighted / totalWeight * float64(steps), true
+}
+
+// averageBaseline returns the average uses count of all samples except the latest one,
+// which is the current snapshot. ok is false if there are fewer than two samples.
+func averageBaseline(series []storage.SoundHistory) (int64, bool) {
+	if len(series) < 2 {
+		return 0, false
+	}
+
+	var total float64
+	baseline := series[:len(series)-1]
+	for _, h := range baseline {
+		total += float64(h.UsesCount)
+	}
+	return int64(total / float64(len(baseline))), true
+}
+
+// isDecelerating reports whether growth in the second half of the series is slower
+// than in the first half. Series with fewer than three samples are never rejected.
+func isDecelerating(series []storage.SoundHistory) bool {
+	if len(series) < 3 {
+		return false
+	}
+
+	first := series[0]
+	last := series[len(series)-1]
+	mid := first.RecordedAt.Add(last.RecordedAt.Sub(first.RecordedAt) / 2)
+
+	// Pick the inner sample closest to the middle of the window
+	pivot := series[1]
+	for _, h := range series[1 : len(series)-1] {
+		if absDuration(h.RecordedAt.Sub(mid)) < absDuration(pivot.RecordedAt.Sub(mid)) {
+			pivot = h
+		}
+	}
+
+	earlyHours := pivot.RecordedAt.Sub(first.RecordedAt).Hours()
+	lateHours := last.RecordedAt.Sub(pivot.RecordedAt).Hours()
+	if earlyHours <= 0 || lateHours <= 0 {
+		return false
+	}
+
+	// Compare uses gained per hour in each sub-window
+	earlyRate := (float64(pivot.UsesCount) - float64(first.UsesCount)) / earlyHours
+	lateRate := (float64(last.UsesCount) - float64(pivot.UsesCount)) / lateHours
+
+	return lateRate < earlyRate
+}
+
+// isSustained reports whether each of the last k samples grew at least minGrowth percent over the baseline.
+// The earliest sample is the baseline itself, so k+1 samples are needed.
+func isSustained(series []storage.SoundHistory, baseline int64, minGrowth float64, k int) bool {
+	if k <= 0 {
+		return true
+	}
+	if baseline <= 0 || len(series) < k+1 {
+		return false
+	}
+
+	for _, h := range series[len(series)-k:] {
+		if calculateGrowth(baseline, h.UsesCount) < minGrowth {
+			return false
+		}
+	}
+	return true
+}
+
+// absDuration returns the absolute value of a duration
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// TrendScoreWindow is the period used to compute user trend scores
+const TrendScoreWindow = 7 * 24 * time.Hour
+
+// GraduateWindow is how far back /graduates looks for sounds crossing the trending ceiling
+const GraduateWindow = 7 * 24 * time.Hour
+
+// Graduates returns sounds that outgrew MaxUsesCount within GraduateWindow, having been tracked below it
+// before, so users can see which trends made it. An empty category means all categories.
+func (d *TrendDetector) Graduates(category string, limit int) ([]storage.Graduate, error) {
+	return d.storage.GetGraduates(category, d.Criteria().MaxUsesCount, time.Now().Add(-GraduateWindow), limit)
+}
+
+// CategoryTrendScore returns the sum of growth percentages for a category over the last week
+func (d *TrendDetector) CategoryTrendScore(category string) (float64, error) {
+	return d.storage.GetCategoryGrowthSum(category, time.Now().Add(-TrendScoreWindow))
+}
+
+// CrossNicheTrend is a sound trending in two or more categories
+type CrossNicheTrend struct {
+	storage.TrendingSound
+	Categories []string
+}
+
+// DetectCrossNiche finds sounds that are trending and were collected in at least two of the given categories
+func (d *TrendDetector) DetectCrossNiche(categories []string) ([]CrossNicheTrend, error) {
+	criteria := d.criteria
+	criteria.CrossNiche = true
+	since := time.Now().Add(-time.Duration(criteria.LookbackHours) * time.Hour)
+
+	seen := make(map[string]bool)
+	var trends []CrossNicheTrend
+
+	for _, category := range categories {
+		trending, err := d.DetectTrendingWithCriteria(category, 0, criteria)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ts := range trending {
+			if !ts.CrossNiche || seen[ts.URL] {
+				continue
+			}
+			seen[ts.URL] = true
+
+			soundCategories, err := d.storage.GetSoundCategories(ts.ID, since)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get sound categories: %w", err)
+			}
+			trends = append(trends, CrossNicheTrend{
+				TrendingSound: ts,
+				Categories:    soundCategories,
+			})
+		}
+	}
+
+	// Most widespread first, then by growth
+	sort.Slice(trends, func(i, j int) bool {
+		if len(trends[i].Categories) != len(trends[j].Categories) {
+			return len(trends[i].Categories) > len(trends[j].Categories)
+		}
+		return trends[i].GrowthPercent > trends[j].GrowthPercent
+	})
+
+	return trends, nil
 }
 
 // AnalyzeTrends provides detailed trend analysis for a category
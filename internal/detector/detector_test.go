@@ -0,0 +1,586 @@
+package detector
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+func TestMain(m *testing.M) {
+	// Storage reads migrations/init.sql relative to the repository root
+	if err := os.Chdir("../.."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func newTestStorage(t *testing.T) *storage.SQLiteStorage {
+	t.Helper()
+	s, err := storage.NewSQLiteStorage(storage.MemoryPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() error: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if err := s.Init(); err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+	return s
+}
+
+// seedGrowth stores a sound that grew from oldUses 23 hours ago to newUses now, collected in each of
+// the given categories the way one URL shows up in several niches
+func seedGrowth(t *testing.T, s *storage.SQLiteStorage, title string, oldUses, newUses int64, categories ...string) *storage.Sound {
+	t.Helper()
+	url := "https://www.tiktok.com/music/" + strings.ReplaceAll(title, " ", "-")
+	sound := &storage.Sound{Title: title, URL: url, UsesCount: oldUses, Category: categories[0]}
+	if err := s.SaveSound(sound); err != nil {
+		t.Fatalf("SaveSound() error: %v", err)
+	}
+	if _, err := s.SeedBaselineHistory(time.Now().Add(-23 * time.Hour)); err != nil {
+		t.Fatalf("SeedBaselineHistory() error: %v", err)
+	}
+	for _, category := range categories {
+		sound = &storage.Sound{Title: title, URL: url, UsesCount: newUses, Category: category}
+		if err := storage.SaveSoundWithHistory(s, sound, storage.DedupByURL); err != nil {
+			t.Fatalf("SaveSoundWithHistory() error: %v", err)
+		}
+	}
+	return sound
+}
+
+// series builds hourly history samples from the given uses counts.
+func series(uses ...int64) []storage.SoundHistory {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	out := make([]storage.SoundHistory, len(uses))
+	for i, u := range uses {
+		out[i] = storage.SoundHistory{UsesCount: u, RecordedAt: start.Add(time.Duration(i) * time.Hour)}
+	}
+	return out
+}
+
+// fixedHistory serves sound history from fixed per-sound samples instead of the database,
+// so tests can place samples at any time
+type fixedHistory struct {
+	*storage.SQLiteStorage
+	samples map[int64][]storage.SoundHistory
+}
+
+func (f *fixedHistory) GetSoundHistoryRange(soundID int64, since time.Time) ([]storage.SoundHistory, error) {
+	return f.GetSoundHistoryBetween(soundID, since, time.Now())
+}
+
+func (f *fixedHistory) GetSoundHistoryBetween(soundID int64, from, to time.Time) ([]storage.SoundHistory, error) {
+	var out []storage.SoundHistory
+	for _, h := range f.samples[soundID] {
+		if !h.RecordedAt.Before(from) && !h.RecordedAt.After(to) {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+// sample is a history sample of uses taken at the given time
+func sample(uses int64, at time.Time) storage.SoundHistory {
+	return storage.SoundHistory{UsesCount: uses, RecordedAt: at}
+}
+
+func TestIsDecelerating(t *testing.T) {
+	tests := []struct {
+		name   string
+		series []storage.SoundHistory
+		want   bool
+	}{
+		{name: "accelerating", series: series(1000, 1200, 1600, 2400, 4000), want: false},
+		{name: "linear", series: series(1000, 2000, 3000, 4000, 5000), want: false},
+		{name: "decelerating", series: series(1000, 2600, 3400, 3800, 4000), want: true},
+		{name: "plateau after spike", series: series(10000, 29000, 29000), want: true},
+		{name: "too few samples", series: series(1000, 5000), want: false},
+		{name: "empty", series: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDecelerating(tt.series); got != tt.want {
+				t.Errorf("isDecelerating() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrossNicheFlagging(t *testing.T) {
+	tests := []struct {
+		name       string
+		crossNiche bool
+		wantFirst  string
+		wantFlag   bool
+	}{
+		{name: "disabled keeps growth order", crossNiche: false, wantFirst: "solo fast", wantFlag: false},
+		{name: "enabled boosts shared sound", crossNiche: true, wantFirst: "shared", wantFlag: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(t)
+			seedGrowth(t, s, "solo fast", 1000, 9000, "lifestyle")
+			seedGrowth(t, s, "shared", 1000, 3000, "fitness", "lifestyle")
+
+			criteria := DefaultCriteria()
+			criteria.CrossNiche = tt.crossNiche
+			got, err := New(s).DetectTrendingWithCriteria("lifestyle", 0, criteria)
+			if err != nil {
+				t.Fatalf("DetectTrendingWithCriteria() error: %v", err)
+			}
+			if len(got) != 2 {
+				t.Fatalf("got %d trending sounds, want 2", len(got))
+			}
+			if got[0].Title != tt.wantFirst {
+				t.Errorf("first = %q, want %q", got[0].Title, tt.wantFirst)
+			}
+			for _, ts := range got {
+				want := tt.wantFlag && ts.Title == "shared"
+				if ts.CrossNiche != want {
+					t.Errorf("%q CrossNiche = %v, want %v", ts.Title, ts.CrossNiche, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDetectCrossNiche(t *testing.T) {
+	s := newTestStorage(t)
+	seedGrowth(t, s, "everywhere", 1000, 3000, "fitness", "lifestyle", "dance")
+	seedGrowth(t, s, "two niches", 1000, 9000, "fitness", "lifestyle")
+	seedGrowth(t, s, "one niche", 1000, 9000, "fitness")
+	seedGrowth(t, s, "flat", 1000, 1100, "gaming", "lifestyle")
+
+	got, err := New(s).DetectCrossNiche([]string{"fitness", "lifestyle", "dance", "gaming"})
+	if err != nil {
+		t.Fatalf("DetectCrossNiche() error: %v", err)
+	}
+
+	want := []struct {
+		title      string
+		categories string
+	}{
+		{"everywhere", "dance,fitness,lifestyle"},
+		{"two niches", "fitness,lifestyle"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d cross-niche trends, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Title != w.title {
+			t.Errorf("trend %d = %q, want %q", i, got[i].Title, w.title)
+		}
+		if categories := strings.Join(got[i].Categories, ","); categories != w.categories {
+			t.Errorf("%q categories = %s, want %s", got[i].Title, categories, w.categories)
+		}
+	}
+}
+
+func TestExplainTrendingReasons(t *testing.T) {
+	s := newTestStorage(t)
+	seedGrowth(t, s, "grower", 1000, 5000, "fitness")
+	seedGrowth(t, s, "second grower", 1000, 4000, "fitness")
+	seedGrowth(t, s, "tiny", 100, 400, "fitness")
+	seedGrowth(t, s, "huge", 20000, 90000, "fitness")
+	seedGrowth(t, s, "slow", 1000, 1500, "fitness")
+	seedGrowth(t, s, "glitch", 1, 25000, "fitness")
+	seedGrowth(t, s, "brand new", 0, 800, "fitness")
+	if err := s.SaveSound(&storage.Sound{Title: "unseen", URL: "https://www.tiktok.com/music/unseen", UsesCount: 2000, Category: "fitness"}); err != nil {
+		t.Fatalf("SaveSound() error: %v", err)
+	}
+
+	criteria := DefaultCriteria()
+	criteria.MinUsesCount = 500
+	criteria.MaxGrowthPercent = 10000
+	_, traces, _, err := New(s).ExplainTrending("fitness", 2, criteria)
+	if err != nil {
+		t.Fatalf("ExplainTrending() error: %v", err)
+	}
+
+	want := map[string]struct {
+		reason   string
+		included bool
+	}{
+		"brand new":     {ReasonNewSound, true},
+		"grower":        {ReasonIncluded, true},
+		"second grower": {ReasonLimitCutoff, false},
+		"tiny":          {ReasonBelowMinUses, false},
+		"huge":          {ReasonAboveMaxUses, false},
+		"slow":          {ReasonInsufficientGrowth, false},
+		"glitch":        {ReasonGrowthAboveCap, false},
+		"unseen":        {ReasonNoHistory, false},
+	}
+	if len(traces) != len(want) {
+		t.Errorf("got %d traces, want %d", len(traces), len(want))
+	}
+	for _, trace := range traces {
+		w, ok := want[trace.Title]
+		if !ok {
+			t.Errorf("unexpected trace for %q", trace.Title)
+			continue
+		}
+		if trace.Reason != w.reason || trace.Included != w.included {
+			t.Errorf("%q: reason = %q included = %v, want %q included = %v", trace.Title, trace.Reason, trace.Included, w.reason, w.included)
+		}
+	}
+}
+
+func TestExplainTrendingGenre(t *testing.T) {
+	s := newTestStorage(t)
+	sound := seedGrowth(t, s, "rock song", 1000, 5000, "fitness")
+	sound.Genre = "Rock"
+	if err := s.UpdateSound(sound); err != nil {
+		t.Fatalf("UpdateSound() error: %v", err)
+	}
+
+	tests := []struct {
+		genre string
+		want  string
+	}{
+		{genre: "", want: ReasonIncluded},
+		{genre: "rock", want: ReasonIncluded},
+		{genre: "pop", want: ReasonGenreMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.genre, func(t *testing.T) {
+			criteria := DefaultCriteria()
+			criteria.Genre = tt.genre
+			_, traces, _, err := New(s).ExplainTrending("fitness", 0, criteria)
+			if err != nil {
+				t.Fatalf("ExplainTrending() error: %v", err)
+			}
+			if len(traces) != 1 || traces[0].Reason != tt.want {
+				t.Errorf("traces = %+v, want one with reason %q", traces, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectGrowthCap(t *testing.T) {
+	s := newTestStorage(t)
+	seedGrowth(t, s, "steady", 1000, 5000, "fitness") // 400%
+	seedGrowth(t, s, "spike", 600, 29000, "fitness")  // about 4733%
+	seedGrowth(t, s, "glitch", 1, 25000, "fitness")   // 2499900%
+
+	tests := []struct {
+		name string
+		cap  float64
+		want []string
+	}{
+		{name: "default cap", cap: DefaultCriteria().MaxGrowthPercent, want: []string{"spike", "steady"}},
+		{name: "low cap", cap: 1000, want: []string{"steady"}},
+		{name: "cap below every sound", cap: 300, want: nil},
+		{name: "no cap", cap: 0, want: []string{"glitch", "spike", "steady"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			criteria := DefaultCriteria()
+			criteria.MaxGrowthPercent = tt.cap
+			sounds, err := New(s).DetectTrendingWithCriteria("fitness", 0, criteria)
+			if err != nil {
+				t.Fatalf("DetectTrendingWithCriteria() error: %v", err)
+			}
+
+			var got []string
+			for _, sound := range sounds {
+				got = append(got, sound.Title)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("trending = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectRegion(t *testing.T) {
+	s := newTestStorage(t)
+	us := seedGrowth(t, s, "us hit", 1000, 5000, "fitness")
+	seedGrowth(t, s, "everywhere else", 1000, 4000, "fitness")
+	if err := s.RecordSoundRegion(us.ID, "US"); err != nil {
+		t.Fatalf("RecordSoundRegion() error: %v", err)
+	}
+
+	tests := []struct {
+		region string
+		want   []string
+	}{
+		{region: "", want: []string{"everywhere else", "us hit"}},
+		{region: "global", want: []string{"everywhere else", "us hit"}},
+		{region: "US", want: []string{"us hit"}},
+		{region: "GB", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.region, func(t *testing.T) {
+			criteria := DefaultCriteria()
+			criteria.Region = tt.region
+			sounds, traces, _, err := New(s).ExplainTrending("fitness", 0, criteria)
+			if err != nil {
+				t.Fatalf("ExplainTrending() error: %v", err)
+			}
+
+			var got []string
+			for _, sound := range sounds {
+				got = append(got, sound.Title)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("trending = %v, want %v", got, tt.want)
+			}
+
+			for _, trace := range traces {
+				if !trace.Included && trace.Reason != ReasonOtherRegion {
+					t.Errorf("%q excluded for %q, want %q", trace.Title, trace.Reason, ReasonOtherRegion)
+				}
+			}
+		})
+	}
+}
+
+func TestCalculateGrowth(t *testing.T) {
+	tests := []struct {
+		old, new int64
+		want     float64
+	}{
+		{1000, 2500, 150},
+		{1000, 1000, 0},
+		{1000, 500, -50},
+		{0, 5000, 0},
+		{1, 1 << 62, float64(1<<62-1) * 100},
+	}
+
+	for _, tt := range tests {
+		if got := calculateGrowth(tt.old, tt.new); got != tt.want {
+			t.Errorf("calculateGrowth(%d, %d) = %v, want %v", tt.old, tt.new, got, tt.want)
+		}
+	}
+}
+
+func TestAverageBaseline(t *testing.T) {
+	tests := []struct {
+		name   string
+		series []storage.SoundHistory
+		want   int64
+		wantOK bool
+	}{
+		{name: "latest sample is excluded", series: series(1000, 2000, 3000, 9000), want: 2000, wantOK: true},
+		{name: "two samples", series: series(1000, 5000), want: 1000, wantOK: true},
+		{name: "one sample", series: series(1000), wantOK: false},
+		{name: "empty", series: nil, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := averageBaseline(tt.series)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("averageBaseline() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestAverageBaselineSmoothsNoise(t *testing.T) {
+	// One anomalous low snapshot at the start of the window makes point growth look explosive
+	noisy := series(100, 2000, 2100, 1900, 2000, 3000)
+	current := int64(3000)
+
+	point := calculateGrowth(noisy[0].UsesCount, current)
+	avg, ok := averageBaseline(noisy)
+	if !ok {
+		t.Fatal("averageBaseline() not ok")
+	}
+	smoothed := calculateGrowth(avg, current)
+
+	if point != 2900 {
+		t.Errorf("point growth = %v, want 2900", point)
+	}
+	if smoothed < 80 || smoothed > 90 {
+		t.Errorf("smoothed growth = %v, want about 85", smoothed)
+	}
+
+	criteria := DefaultCriteria()
+	if point < criteria.MinGrowth || smoothed >= criteria.MinGrowth {
+		t.Errorf("want the spike to trend only without smoothing: point %v, smoothed %v, min %v", point, smoothed, criteria.MinGrowth)
+	}
+}
+
+func TestNewSoundsPosition(t *testing.T) {
+	tests := []struct {
+		name          string
+		newSoundsLast bool
+		want          []string
+	}{
+		{name: "top", newSoundsLast: false, want: []string{"brand new", "fast", "slow"}},
+		{name: "bottom", newSoundsLast: true, want: []string{"fast", "slow", "brand new"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(t)
+			seedGrowth(t, s, "slow", 1000, 3000, "fitness")
+			seedGrowth(t, s, "brand new", 0, 800, "fitness")
+			seedGrowth(t, s, "fast", 1000, 9000, "fitness")
+
+			criteria := DefaultCriteria()
+			criteria.NewSoundsLast = tt.newSoundsLast
+			got, err := New(s).DetectTrendingWithCriteria("fitness", 0, criteria)
+			if err != nil {
+				t.Fatalf("DetectTrendingWithCriteria() error: %v", err)
+			}
+
+			var titles []string
+			for _, ts := range got {
+				titles = append(titles, ts.Title)
+				if isNew := ts.Title == "brand new"; ts.IsNew != isNew {
+					t.Errorf("%q IsNew = %v, want %v", ts.Title, ts.IsNew, isNew)
+				}
+				if ts.IsNew && ts.GrowthPercent != 0 {
+					t.Errorf("new sound GrowthPercent = %v, want 0", ts.GrowthPercent)
+				}
+			}
+			if strings.Join(titles, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("order = %v, want %v", titles, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsSustained(t *testing.T) {
+	tests := []struct {
+		name   string
+		series []storage.SoundHistory
+		k      int
+		want   bool
+	}{
+		{name: "held over every sample", series: series(1000, 3000, 3500, 4000), k: 3, want: true},
+		{name: "one-off spike", series: series(1000, 1100, 1200, 5000), k: 3, want: false},
+		{name: "spike checked alone", series: series(1000, 1100, 1200, 5000), k: 1, want: true},
+		{name: "too few samples", series: series(1000, 5000), k: 3, want: false},
+		{name: "check disabled", series: series(1000), k: 0, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSustained(tt.series, 1000, 150, tt.k); got != tt.want {
+				t.Errorf("isSustained() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireSustained(t *testing.T) {
+	tests := []struct {
+		name       string
+		require    bool
+		samples    int
+		wantReason string
+	}{
+		{name: "disabled", require: false, samples: 3, wantReason: ReasonIncluded},
+		{name: "not enough samples", require: true, samples: 3, wantReason: ReasonNotSustained},
+		{name: "latest sample only", require: true, samples: 1, wantReason: ReasonIncluded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(t)
+			seedGrowth(t, s, "spike", 1000, 5000, "fitness")
+
+			criteria := DefaultCriteria()
+			criteria.RequireSustained = tt.require
+			criteria.SustainedSamples = tt.samples
+			_, traces, _, err := New(s).ExplainTrending("fitness", 5, criteria)
+			if err != nil {
+				t.Fatalf("ExplainTrending() error: %v", err)
+			}
+			if len(traces) != 1 || traces[0].Reason != tt.wantReason {
+				t.Errorf("traces = %+v, want one with reason %q", traces, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestEWMAGrowth(t *testing.T) {
+	tests := []struct {
+		name    string
+		series  []storage.SoundHistory
+		current int64
+		alpha   float64
+		min     float64
+		max     float64
+		wantOK  bool
+	}{
+		// Both grow 1000 -> 2700, i.e. 170% simple growth
+		{name: "front-loaded growth scores below simple growth", series: series(1000, 2000, 2500), current: 2700, alpha: 0.5, min: 70, max: 90, wantOK: true},
+		{name: "back-loaded growth scores above simple growth", series: series(1000, 1100, 1300), current: 2700, alpha: 0.5, min: 190, max: 220, wantOK: true},
+		{name: "current already in series", series: series(1000, 1100, 1300, 2700), current: 2700, alpha: 0.5, min: 190, max: 220, wantOK: true},
+		{name: "alpha 1 uses only the newest step", series: series(1000, 2000), current: 3000, alpha: 1, min: 99.9, max: 100.1, wantOK: true},
+		{name: "single step equals simple growth", series: series(1000), current: 2700, alpha: 0.5, min: 169.9, max: 170.1, wantOK: true},
+		{name: "no samples", current: 2700, alpha: 0.5},
+		{name: "zero count", series: series(0, 1000), current: 2700, alpha: 0.5},
+		{name: "invalid alpha", series: series(1000, 2000), current: 2700, alpha: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ewmaGrowth(tt.series, tt.current, tt.alpha)
+			if ok != tt.wantOK {
+				t.Fatalf("ewmaGrowth() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (got < tt.min || got > tt.max) {
+				t.Errorf("ewmaGrowth() = %.1f, want between %.1f and %.1f", got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestDetectEWMAGrowth(t *testing.T) {
+	s := newTestStorage(t)
+	front := seedGrowth(t, s, "grew early", 1000, 2700, "fitness")
+	back := seedGrowth(t, s, "growing now", 1000, 2700, "fitness")
+
+	now := time.Now()
+	history := &fixedHistory{SQLiteStorage: s, samples: map[int64][]storage.SoundHistory{
+		front.ID: {sample(1000, now.Add(-20*time.Hour)), sample(2000, now.Add(-15*time.Hour)), sample(2500, now.Add(-10*time.Hour)), sample(2700, now)},
+		back.ID:  {sample(1000, now.Add(-20*time.Hour)), sample(1100, now.Add(-15*time.Hour)), sample(1300, now.Add(-10*time.Hour)), sample(2700, now)},
+	}}
+	d := New(history)
+
+	tests := []struct {
+		name string
+		ewma bool
+		want []string
+	}{
+		{name: "simple growth includes both", ewma: false, want: []string{"grew early", "growing now"}},
+		{name: "EWMA keeps only the accelerating sound", ewma: true, want: []string{"growing now"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			criteria := DefaultCriteria()
+			criteria.EWMAGrowth = tt.ewma
+
+			got, err := d.DetectTrendingWithCriteria("fitness", 10, criteria)
+			if err != nil {
+				t.Fatalf("DetectTrendingWithCriteria() error: %v", err)
+			}
+			var titles []string
+			for _, ts := range got {
+				titles = append(titles, ts.Sound.Title)
+			}
+			sort.Strings(titles)
+			if !reflect.DeepEqual(titles, tt.want) {
+				t.Errorf("trending = %v, want %v", titles, tt.want)
+			}
+		})
+	}
+}
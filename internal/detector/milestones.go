@@ -0,0 +1,25 @@
+package detector
+
+// Milestones are the uses counts that trigger a notification for followed sounds
+var Milestones = []int64{10000, 50000, 100000}
+
+// HighestMilestone returns the largest milestone reached by usesCount, or 0 if none
+func HighestMilestone(usesCount int64) int64 {
+	var reached int64
+	for _, m := range Milestones {
+		if usesCount >= m && m > reached {
+			reached = m
+		}
+	}
+	return reached
+}
+
+// CrossedMilestone returns the milestone newly reached when going from the last
+// notified milestone to the current uses count, or 0 if no new milestone was crossed
+func CrossedMilestone(lastMilestone, usesCount int64) int64 {
+	reached := HighestMilestone(usesCount)
+	if reached > lastMilestone {
+		return reached
+	}
+	return 0
+}
@@ -0,0 +1,28 @@
+package detector
+
+import "testing"
+
+func TestCrossedMilestone(t *testing.T) {
+	tests := []struct {
+		name          string
+		lastMilestone int64
+		uses          int64
+		want          int64
+	}{
+		{name: "below first", lastMilestone: 0, uses: 9999, want: 0},
+		{name: "exactly first", lastMilestone: 0, uses: 10000, want: 10000},
+		{name: "already notified", lastMilestone: 10000, uses: 40000, want: 0},
+		{name: "next one", lastMilestone: 10000, uses: 50000, want: 50000},
+		{name: "skipped ones report the highest", lastMilestone: 0, uses: 250000, want: 100000},
+		{name: "past the last", lastMilestone: 100000, uses: 900000, want: 0},
+		{name: "uses dropped", lastMilestone: 50000, uses: 20000, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CrossedMilestone(tt.lastMilestone, tt.uses); got != tt.want {
+				t.Errorf("CrossedMilestone(%d, %d) = %d, want %d", tt.lastMilestone, tt.uses, got, tt.want)
+			}
+		})
+	}
+}
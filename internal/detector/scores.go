@@ -0,0 +1,56 @@
+package detector
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+// ComputeTrendScores computes the current growth, rank by uses and trending flag of every sound in a category
+func (d *TrendDetector) ComputeTrendScores(category string) ([]storage.TrendScore, error) {
+	sounds, historyMap, err := d.storage.GetAllSoundsWithHistory(category, d.criteria.LookbackHours)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sounds with history: %w", err)
+	}
+
+	trending, err := d.DetectTrending(category, 0)
+	if err != nil {
+		return nil, err
+	}
+	trendingByID := make(map[int64]storage.TrendingSound, len(trending))
+	for _, ts := range trending {
+		trendingByID[ts.ID] = ts
+	}
+
+	// Rank is the position by uses within the category, as in GetSoundRank
+	sort.SliceStable(sounds, func(i, j int) bool {
+		return sounds[i].UsesCount > sounds[j].UsesCount
+	})
+
+	now := time.Now()
+	scores := make([]storage.TrendScore, 0, len(sounds))
+	for i, sound := range sounds {
+		// Trending sounds keep the growth detection ranked them by (averaged baseline, EWMA),
+		// so the stored score agrees with what alerts show
+		ts, isTrending := trendingByID[sound.ID]
+		growth := ts.GrowthPercent
+		if !isTrending {
+			if history := historyMap[sound.ID]; history != nil && history.UsesCount > 0 {
+				growth = calculateGrowth(history.UsesCount, sound.UsesCount)
+			}
+		}
+
+		scores = append(scores, storage.TrendScore{
+			SoundID:       sound.ID,
+			Category:      category,
+			GrowthPercent: growth,
+			Rank:          i + 1,
+			Trending:      isTrending,
+			ComputedAt:    now,
+		})
+	}
+
+	return scores, nil
+}
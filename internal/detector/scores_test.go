@@ -0,0 +1,60 @@
+package detector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+func TestComputeTrendScores(t *testing.T) {
+	s := newTestStorage(t)
+	rising := seedGrowth(t, s, "rising", 1000, 5000, "fitness")
+	flat := seedGrowth(t, s, "flat", 8000, 8800, "fitness")
+
+	now := time.Now()
+	history := &fixedHistory{SQLiteStorage: s, samples: map[int64][]storage.SoundHistory{
+		rising.ID: {sample(1000, now.Add(-23*time.Hour)), sample(1500, now.Add(-12*time.Hour)), sample(5000, now)},
+		flat.ID:   {sample(8000, now.Add(-23*time.Hour)), sample(8800, now)},
+	}}
+
+	tests := []struct {
+		name            string
+		averageBaseline bool
+		wantRising      float64
+	}{
+		{name: "point baseline", wantRising: 400},
+		// The averaged baseline (1250) is what detection ranks by, not the oldest sample
+		{name: "averaged baseline", averageBaseline: true, wantRising: 300},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := New(history)
+			criteria := DefaultCriteria()
+			criteria.AverageBaseline = tt.averageBaseline
+			d.SetCriteria(criteria)
+
+			scores, err := d.ComputeTrendScores("fitness")
+			if err != nil {
+				t.Fatalf("ComputeTrendScores() error: %v", err)
+			}
+			byID := make(map[int64]storage.TrendScore, len(scores))
+			for _, score := range scores {
+				byID[score.SoundID] = score
+			}
+			if len(byID) != 2 {
+				t.Fatalf("got %d scores, want 2", len(byID))
+			}
+
+			got := byID[rising.ID]
+			if !got.Trending || got.GrowthPercent != tt.wantRising || got.Rank != 2 {
+				t.Errorf("rising score = %+v, want trending, growth %v, rank 2", got, tt.wantRising)
+			}
+			got = byID[flat.ID]
+			if got.Trending || got.GrowthPercent != 10 || got.Rank != 1 {
+				t.Errorf("flat score = %+v, want not trending, growth 10, rank 1", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package detector
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+const (
+	// SeasonalMinHistory is how much history a sound needs before it is checked for recurring spikes
+	SeasonalMinHistory = 28 * 24 * time.Hour
+	// SeasonalMinCycles is how many full periods the history must cover for a period to be considered
+	SeasonalMinCycles = 3
+	// SeasonalThreshold is the minimum autocorrelation at a lag for the sound to count as seasonal
+	SeasonalThreshold = 0.7
+)
+
+// SeasonalSound is a sound whose daily uses growth repeats at a regular interval
+type SeasonalSound struct {
+	storage.Sound
+	PeriodDays      int     `json:"period_days"`
+	Autocorrelation float64 `json:"autocorrelation"`
+}
+
+// DetectSeasonal finds sounds in a category whose uses spike at regular intervals, e.g. weekly,
+// so a spike from them may be routine rather than a new trend
+func (d *TrendDetector) DetectSeasonal(category string) ([]SeasonalSound, error) {
+	sounds, err := d.storage.GetSoundsByCategory(category, 1000)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sounds: %w", err)
+	}
+
+	var seasonal []SeasonalSound
+	for _, sound := range sounds {
+		series, err := d.storage.GetFullSoundHistory(sound.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get full history: %w", err)
+		}
+
+		period, corr := detectPeriod(series)
+		if period == 0 {
+			continue
+		}
+		seasonal = append(seasonal, SeasonalSound{
+			Sound:           sound,
+			PeriodDays:      period,
+			Autocorrelation: corr,
+		})
+	}
+
+	return seasonal, nil
+}
+
+// detectPeriod returns the period in days with the strongest autocorrelation of daily uses growth
+// and that autocorrelation, or 0 when the history is too short or nothing repeats
+func detectPeriod(series []storage.SoundHistory) (int, float64) {
+	if len(series) < 2 || series[len(series)-1].RecordedAt.Sub(series[0].RecordedAt) < SeasonalMinHistory {
+		return 0, 0
+	}
+
+	daily := dailyGrowth(series)
+	bestLag, bestCorr := 0, 0.0
+	for lag := 2; lag*SeasonalMinCycles <= len(daily); lag++ {
+		if corr := autocorrelation(daily, lag); corr >= SeasonalThreshold && corr > bestCorr {
+			bestLag, bestCorr = lag, corr
+		}
+	}
+
+	return bestLag, bestCorr
+}
+
+// dailyGrowth buckets snapshots by day since the first one and returns the uses gained each day.
+// Days without a snapshot carry the previous count forward, so they show no growth.
+func dailyGrowth(series []storage.SoundHistory) []float64 {
+	start := series[0].RecordedAt
+	days := int(series[len(series)-1].RecordedAt.Sub(start)/(24*time.Hour)) + 1
+
+	// Last uses count seen on each day
+	counts := make([]int64, days)
+	seen := make([]bool, days)
+	for _, h := range series {
+		day := int(h.RecordedAt.Sub(start) / (24 * time.Hour))
+		counts[day] = h.UsesCount
+		seen[day] = true
+	}
+	for day := 1; day < days; day++ {
+		if !seen[day] {
+			counts[day] = counts[day-1]
+		}
+	}
+
+	growth := make([]float64, days-1)
+	for day := 1; day < days; day++ {
+		growth[day-1] = float64(counts[day] - counts[day-1])
+	}
+	return growth
+}
+
+// autocorrelation returns the correlation between values and the same values shifted by lag, in [-1, 1]
+func autocorrelation(values []float64, lag int) float64 {
+	if lag <= 0 || lag >= len(values)-1 {
+		return 0
+	}
+
+	head, tail := values[:len(values)-lag], values[lag:]
+	headMean, tailMean := mean(head), mean(tail)
+
+	var covariance, headVariance, tailVariance float64
+	for i := range head {
+		a, b := head[i]-headMean, tail[i]-tailMean
+		covariance += a * b
+		headVariance += a * a
+		tailVariance += b * b
+	}
+	if headVariance == 0 || tailVariance == 0 {
+		return 0
+	}
+
+	return covariance / math.Sqrt(headVariance*tailVariance)
+}
+
+// mean returns the average of values
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// tagSeasonal marks trending sounds whose full history shows a recurring spike
+func (d *TrendDetector) tagSeasonal(trendingSounds []storage.TrendingSound) error {
+	for i := range trendingSounds {
+		series, err := d.storage.GetFullSoundHistory(trendingSounds[i].ID)
+		if err != nil {
+			return fmt.Errorf("failed to get full history: %w", err)
+		}
+		trendingSounds[i].SeasonalPeriodDays, _ = detectPeriod(series)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package detector
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+func (f *fixedHistory) GetFullSoundHistory(soundID int64) ([]storage.SoundHistory, error) {
+	return f.samples[soundID], nil
+}
+
+// dailySeries builds one sample per day, starting at start, from the uses gained each day
+func dailySeries(start time.Time, growth []int64) []storage.SoundHistory {
+	out := []storage.SoundHistory{sample(1000, start)}
+	uses := int64(1000)
+	for i, g := range growth {
+		uses += g
+		out = append(out, sample(uses, start.Add(time.Duration(i+1)*24*time.Hour)))
+	}
+	return out
+}
+
+// weeklyGrowth gains spike uses every seventh day and base uses on the others
+func weeklyGrowth(days int, base, spike int64) []int64 {
+	growth := make([]int64, days)
+	for i := range growth {
+		growth[i] = base
+		if i%7 == 0 {
+			growth[i] = spike
+		}
+	}
+	return growth
+}
+
+func TestDetectPeriod(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rng := rand.New(rand.NewSource(1))
+	noise := make([]int64, 40)
+	for i := range noise {
+		noise[i] = rng.Int63n(1000)
+	}
+	everyThirdDay := make([]int64, 40)
+	for i := range everyThirdDay {
+		everyThirdDay[i] = 100
+		if i%3 == 0 {
+			everyThirdDay[i] = 900
+		}
+	}
+
+	tests := []struct {
+		name       string
+		series     []storage.SoundHistory
+		wantPeriod int
+	}{
+		{name: "weekly spikes", series: dailySeries(start, weeklyGrowth(40, 100, 2000)), wantPeriod: 7},
+		{name: "every three days", series: dailySeries(start, everyThirdDay), wantPeriod: 3},
+		{name: "steady growth", series: dailySeries(start, weeklyGrowth(40, 100, 100)), wantPeriod: 0},
+		{name: "random growth", series: dailySeries(start, noise), wantPeriod: 0},
+		{name: "too little history", series: dailySeries(start, weeklyGrowth(20, 100, 2000)), wantPeriod: 0},
+		{name: "no history", series: nil, wantPeriod: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			period, corr := detectPeriod(tt.series)
+			if period != tt.wantPeriod {
+				t.Errorf("detectPeriod() period = %d (corr %.2f), want %d", period, corr, tt.wantPeriod)
+			}
+			if period > 0 && corr < SeasonalThreshold {
+				t.Errorf("detectPeriod() corr = %.2f, want at least %.2f", corr, SeasonalThreshold)
+			}
+		})
+	}
+}
+
+func TestDailyGrowth(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	hours := func(h int) time.Time { return start.Add(time.Duration(h) * time.Hour) }
+
+	tests := []struct {
+		name   string
+		series []storage.SoundHistory
+		want   []float64
+	}{
+		{
+			name:   "one sample per day",
+			series: []storage.SoundHistory{sample(100, hours(0)), sample(150, hours(24)), sample(300, hours(48))},
+			want:   []float64{50, 150},
+		},
+		{
+			name:   "last sample of the day counts",
+			series: []storage.SoundHistory{sample(100, hours(0)), sample(120, hours(25)), sample(180, hours(40))},
+			want:   []float64{80},
+		},
+		{
+			name:   "missing day carries forward",
+			series: []storage.SoundHistory{sample(100, hours(0)), sample(400, hours(72))},
+			want:   []float64{0, 0, 300},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dailyGrowth(tt.series); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dailyGrowth() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAutocorrelation(t *testing.T) {
+	alternating := []float64{1, 5, 1, 5, 1, 5, 1, 5}
+
+	tests := []struct {
+		name   string
+		values []float64
+		lag    int
+		want   float64
+	}{
+		{name: "in phase", values: alternating, lag: 2, want: 1},
+		{name: "out of phase", values: alternating, lag: 1, want: -1},
+		{name: "constant", values: []float64{3, 3, 3, 3, 3}, lag: 1, want: 0},
+		{name: "lag too long", values: alternating, lag: 7, want: 0},
+		{name: "zero lag", values: alternating, lag: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := autocorrelation(tt.values, tt.lag); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("autocorrelation(lag %d) = %v, want %v", tt.lag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectSeasonal(t *testing.T) {
+	s := newTestStorage(t)
+	start := time.Now().Add(-40 * 24 * time.Hour)
+
+	weekly := &storage.Sound{Title: "weekly", URL: "https://www.tiktok.com/music/weekly", UsesCount: 1000, Category: "fitness"}
+	steady := &storage.Sound{Title: "steady", URL: "https://www.tiktok.com/music/steady", UsesCount: 1000, Category: "fitness"}
+	for _, sound := range []*storage.Sound{weekly, steady} {
+		if err := s.SaveSound(sound); err != nil {
+			t.Fatalf("SaveSound() error: %v", err)
+		}
+	}
+
+	history := &fixedHistory{SQLiteStorage: s, samples: map[int64][]storage.SoundHistory{
+		weekly.ID: dailySeries(start, weeklyGrowth(39, 100, 2000)),
+		steady.ID: dailySeries(start, weeklyGrowth(39, 100, 100)),
+	}}
+	seasonal, err := New(history).DetectSeasonal("fitness")
+	if err != nil {
+		t.Fatalf("DetectSeasonal() error: %v", err)
+	}
+
+	if len(seasonal) != 1 || seasonal[0].ID != weekly.ID || seasonal[0].PeriodDays != 7 {
+		t.Errorf("DetectSeasonal() = %+v, want only the weekly sound with a 7 day period", seasonal)
+	}
+}
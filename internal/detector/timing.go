@@ -0,0 +1,67 @@
+package detector
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+// DetectionTiming describes one detection run, so operators can see when detection slows as data grows
+type DetectionTiming struct {
+	Category   string
+	Duration   time.Duration
+	Scanned    int // Sounds loaded for the category
+	Candidates int // Sounds within the uses and genre filters that had history to compare
+	Returned   int // Sounds in the result after the limit
+	At         time.Time
+}
+
+// SetDebugLogging logs the timing of every detection run when enabled
+func (d *TrendDetector) SetDebugLogging(enabled bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.debug = enabled
+}
+
+// Timings returns the timing of the latest detection run per category, slowest first
+func (d *TrendDetector) Timings() []DetectionTiming {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	timings := make([]DetectionTiming, 0, len(d.timings))
+	for _, t := range d.timings {
+		timings = append(timings, t)
+	}
+	sort.Slice(timings, func(i, j int) bool {
+		return timings[i].Duration > timings[j].Duration
+	})
+	return timings
+}
+
+// timedDetect runs detect and records how long it took and how many sounds it looked at
+func (d *TrendDetector) timedDetect(category string, limit int, criteria TrendCriteria, traces *[]DetectionTrace) ([]storage.TrendingSound, DetectionTiming, error) {
+	timing := DetectionTiming{Category: category, At: time.Now()}
+	sounds, err := d.detect(category, limit, criteria, traces, &timing)
+	timing.Duration = time.Since(timing.At)
+	if err != nil {
+		return nil, timing, err
+	}
+	timing.Returned = len(sounds)
+
+	d.mu.Lock()
+	if d.timings == nil {
+		d.timings = make(map[string]DetectionTiming)
+	}
+	d.timings[category] = timing
+	debug := d.debug
+	d.mu.Unlock()
+
+	if debug {
+		log.Printf("[detector debug] %s: %s, scanned %d, candidates %d, returned %d",
+			category, timing.Duration, timing.Scanned, timing.Candidates, timing.Returned)
+	}
+
+	return sounds, timing, nil
+}
@@ -0,0 +1,76 @@
+package detector
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDetectionTiming(t *testing.T) {
+	s := newTestStorage(t)
+	seedGrowth(t, s, "grower", 1000, 5000, "fitness")
+	seedGrowth(t, s, "flat", 1000, 1100, "fitness")
+	seedGrowth(t, s, "tiny", 10, 50, "fitness")
+	d := New(s)
+
+	if timings := d.Timings(); len(timings) != 0 {
+		t.Fatalf("Timings() before detection = %+v, want none", timings)
+	}
+
+	if _, err := d.DetectTrending("fitness", 10); err != nil {
+		t.Fatalf("DetectTrending() error: %v", err)
+	}
+	_, _, timing, err := d.ExplainTrending("fitness", 10, d.Criteria())
+	if err != nil {
+		t.Fatalf("ExplainTrending() error: %v", err)
+	}
+
+	// tiny is below the minimum uses, flat has history but doesn't grow enough
+	if timing.Category != "fitness" || timing.Scanned != 3 || timing.Candidates != 2 || timing.Returned != 1 {
+		t.Errorf("timing = %+v, want fitness with 3 scanned, 2 candidates, 1 returned", timing)
+	}
+	if timing.Duration <= 0 || time.Since(timing.At) > time.Minute {
+		t.Errorf("timing duration %s at %s, want a measured recent run", timing.Duration, timing.At)
+	}
+
+	timings := d.Timings()
+	if len(timings) != 1 || timings[0] != timing {
+		t.Errorf("Timings() = %+v, want only the latest run %+v", timings, timing)
+	}
+}
+
+func TestTimingsSlowestFirst(t *testing.T) {
+	d := New(nil)
+	d.timings = map[string]DetectionTiming{
+		"fitness": {Category: "fitness", Duration: 20 * time.Millisecond},
+		"gaming":  {Category: "gaming", Duration: 90 * time.Millisecond},
+		"dance":   {Category: "dance", Duration: 5 * time.Millisecond},
+	}
+
+	var got []string
+	for _, timing := range d.Timings() {
+		got = append(got, timing.Category)
+	}
+	if want := []string{"gaming", "fitness", "dance"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Timings() order = %v, want %v", got, want)
+	}
+}
+
+func TestDetectCooldownNotTimed(t *testing.T) {
+	s := newTestStorage(t)
+	seedGrowth(t, s, "grower", 1000, 5000, "fitness")
+	d := New(s)
+	d.SetCooldown(time.Minute)
+
+	if _, err := d.DetectTrending("fitness", 10); err != nil {
+		t.Fatalf("DetectTrending() error: %v", err)
+	}
+	first := d.Timings()
+
+	if _, err := d.DetectTrending("fitness", 10); err != nil {
+		t.Fatalf("DetectTrending() error: %v", err)
+	}
+	if got := d.Timings(); len(got) != 1 || got[0].At != first[0].At {
+		t.Errorf("Timings() after a reused result = %+v, want the first run %+v", got, first)
+	}
+}
@@ -0,0 +1,104 @@
+package eventbus
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Topics published by the application
+const (
+	TopicCollectionCompleted = "collection.completed"
+)
+
+// Event is a message published on the bus
+type Event struct {
+	Topic    string
+	Category string
+	Time     time.Time
+	Payload  interface{}
+}
+
+// Handler processes events for a subscription
+type Handler func(Event)
+
+// subscriberBuffer is how many events a slow subscriber can fall behind before Publish drops them
+const subscriberBuffer = 64
+
+// Bus is an in-process publish/subscribe bus. Each subscriber runs in its own
+// goroutine, so a slow handler never blocks the publisher.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[string][]chan Event
+	wg          sync.WaitGroup
+	closed      bool
+}
+
+// New creates a new event bus
+func New() *Bus {
+	return &Bus{
+		subscribers: make(map[string][]chan Event),
+	}
+}
+
+// Subscribe registers a handler for a topic
+func (b *Bus) Subscribe(topic string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	ch := make(chan Event, subscriberBuffer)
+	b.subscribers[topic] = append(b.subscribers[topic], ch)
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for event := range ch {
+			handler(event)
+		}
+	}()
+}
+
+// Publish delivers an event to all subscribers of its topic.
+// Events are dropped (and logged) for subscribers whose buffer is full.
+func (b *Bus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return
+	}
+
+	for _, ch := range b.subscribers[event.Topic] {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("Event bus: subscriber for %s is full, dropping event", event.Topic)
+		}
+	}
+}
+
+// Close stops accepting events and waits for subscribers to drain
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	for _, subs := range b.subscribers {
+		for _, ch := range subs {
+			close(ch)
+		}
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+}
@@ -0,0 +1,152 @@
+package eventbus
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder collects the events a subscriber handled
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recorder) handle(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *recorder) categories() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []string
+	for _, e := range r.events {
+		out = append(out, e.Category)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestPublish(t *testing.T) {
+	tests := []struct {
+		name      string
+		subscribe []string // topic of each subscriber
+		publish   []Event
+		want      [][]string // categories each subscriber saw
+	}{
+		{
+			name:      "subscriber runs",
+			subscribe: []string{TopicCollectionCompleted},
+			publish:   []Event{{Topic: TopicCollectionCompleted, Category: "fitness"}},
+			want:      [][]string{{"fitness"}},
+		},
+		{
+			name:      "every subscriber gets the event",
+			subscribe: []string{TopicCollectionCompleted, TopicCollectionCompleted},
+			publish:   []Event{{Topic: TopicCollectionCompleted, Category: "fitness"}, {Topic: TopicCollectionCompleted, Category: "gaming"}},
+			want:      [][]string{{"fitness", "gaming"}, {"fitness", "gaming"}},
+		},
+		{
+			name:      "other topics are not delivered",
+			subscribe: []string{TopicCollectionCompleted, "other"},
+			publish:   []Event{{Topic: "other", Category: "dance"}},
+			want:      [][]string{nil, {"dance"}},
+		},
+		{
+			name:    "no subscribers",
+			publish: []Event{{Topic: TopicCollectionCompleted, Category: "fitness"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := New()
+			recorders := make([]*recorder, len(tt.subscribe))
+			for i, topic := range tt.subscribe {
+				recorders[i] = &recorder{}
+				bus.Subscribe(topic, recorders[i].handle)
+			}
+
+			for _, e := range tt.publish {
+				bus.Publish(e)
+			}
+			bus.Close()
+
+			for i, r := range recorders {
+				if got := r.categories(); !reflect.DeepEqual(got, tt.want[i]) {
+					t.Errorf("subscriber %d saw %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPublishSetsTime(t *testing.T) {
+	bus := New()
+	r := &recorder{}
+	bus.Subscribe(TopicCollectionCompleted, r.handle)
+
+	fixed := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bus.Publish(Event{Topic: TopicCollectionCompleted, Category: "stamped"})
+	bus.Publish(Event{Topic: TopicCollectionCompleted, Category: "fixed", Time: fixed})
+	bus.Close()
+
+	for _, e := range r.events {
+		switch {
+		case e.Category == "stamped" && e.Time.IsZero():
+			t.Error("Publish() left the event time empty")
+		case e.Category == "fixed" && !e.Time.Equal(fixed):
+			t.Errorf("Publish() changed the event time to %v", e.Time)
+		}
+	}
+}
+
+func TestSlowSubscriberDoesNotBlockPublish(t *testing.T) {
+	bus := New()
+	release := make(chan struct{})
+	r := &recorder{}
+	bus.Subscribe(TopicCollectionCompleted, func(e Event) {
+		<-release
+		r.handle(e)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < subscriberBuffer*2; i++ {
+			bus.Publish(Event{Topic: TopicCollectionCompleted})
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish() blocked on a slow subscriber")
+	}
+
+	close(release)
+	bus.Close()
+	if got := len(r.events); got > subscriberBuffer+1 {
+		t.Errorf("slow subscriber handled %d events, want at most %d with the rest dropped", got, subscriberBuffer+1)
+	}
+}
+
+func TestClose(t *testing.T) {
+	bus := New()
+	r := &recorder{}
+	bus.Subscribe(TopicCollectionCompleted, r.handle)
+	bus.Close()
+
+	// Nothing is delivered or registered after Close, and closing twice is safe
+	bus.Publish(Event{Topic: TopicCollectionCompleted, Category: "late"})
+	bus.Subscribe(TopicCollectionCompleted, r.handle)
+	bus.Close()
+
+	if got := r.categories(); got != nil {
+		t.Errorf("events after Close = %v, want none", got)
+	}
+}
@@ -0,0 +1,118 @@
+package parser
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+// CachedParser wraps a Parser and caches results per category for a TTL
+type CachedParser struct {
+	parser  Parser
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// cacheEntry holds cached sounds for a single category
+type cacheEntry struct {
+	sounds    []storage.Sound
+	fetchedAt time.Time
+}
+
+// NewCachedParser creates a caching decorator around an existing parser
+func NewCachedParser(p Parser, ttl time.Duration) *CachedParser {
+	return &CachedParser{
+		parser:  p,
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// FetchTrendingSounds returns cached sounds if still fresh, otherwise fetches from the wrapped parser
+func (c *CachedParser) FetchTrendingSounds(category string) ([]storage.Sound, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[category]
+	c.mu.Unlock()
+
+	if ok && time.Since(entry.fetchedAt) < c.ttl {
+		log.Printf("Parser cache hit for category: %s", category)
+		return copySounds(entry.sounds), nil
+	}
+
+	sounds, err := c.parser.FetchTrendingSounds(category)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[category] = cacheEntry{
+		sounds:    copySounds(sounds),
+		fetchedAt: time.Now(),
+	}
+	c.mu.Unlock()
+
+	return sounds, nil
+}
+
+// FetchRegionalSounds returns a region's cached sounds if still fresh, otherwise fetches them from the wrapped parser
+func (c *CachedParser) FetchRegionalSounds(category, region string) ([]storage.Sound, error) {
+	key := category + "@" + region
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if ok && time.Since(entry.fetchedAt) < c.ttl {
+		log.Printf("Parser cache hit for category: %s, region: %s", category, region)
+		return copySounds(entry.sounds), nil
+	}
+
+	sounds, err := FetchRegion(c.parser, category, region)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = cacheEntry{
+		sounds:    copySounds(sounds),
+		fetchedAt: time.Now(),
+	}
+	c.mu.Unlock()
+
+	return sounds, nil
+}
+
+// FetchSound fetches a single sound from the wrapped parser, uncached
+func (c *CachedParser) FetchSound(url, category string) (*storage.Sound, error) {
+	fetcher, ok := c.parser.(SoundFetcher)
+	if !ok {
+		return nil, ErrFetchUnsupported
+	}
+	return fetcher.FetchSound(url, category)
+}
+
+// Invalidate drops all cached entries
+func (c *CachedParser) Invalidate() {
+	c.mu.Lock()
+	c.entries = make(map[string]cacheEntry)
+	c.mu.Unlock()
+}
+
+// Close invalidates the cache and closes the wrapped parser
+func (c *CachedParser) Close() error {
+	c.Invalidate()
+	return c.parser.Close()
+}
+
+// copySounds returns a copy of the slice so callers can't mutate cached data
+func copySounds(sounds []storage.Sound) []storage.Sound {
+	if sounds == nil {
+		return nil
+	}
+	out := make([]storage.Sound, len(sounds))
+	copy(out, sounds)
+	return out
+}
@@ -0,0 +1,217 @@
+package parser
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+// fakeParser counts fetches per category and returns canned sounds
+type fakeParser struct {
+	mu     sync.Mutex
+	calls  map[string]int
+	err    error
+	closed bool
+}
+
+func newFakeParser() *fakeParser {
+	return &fakeParser{calls: make(map[string]int)}
+}
+
+func (f *fakeParser) FetchTrendingSounds(category string) ([]storage.Sound, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls[category]++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []storage.Sound{{Title: category + " sound", Category: category}}, nil
+}
+
+func (f *fakeParser) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func (f *fakeParser) callCount(category string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[category]
+}
+
+func TestCachedParserHitAndMiss(t *testing.T) {
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		sleep     time.Duration
+		fetches   []string
+		wantCalls map[string]int
+	}{
+		{
+			name:      "repeat fetch is a hit",
+			ttl:       time.Minute,
+			fetches:   []string{"fitness", "fitness", "fitness"},
+			wantCalls: map[string]int{"fitness": 1},
+		},
+		{
+			name:      "categories are cached separately",
+			ttl:       time.Minute,
+			fetches:   []string{"fitness", "gaming", "fitness"},
+			wantCalls: map[string]int{"fitness": 1, "gaming": 1},
+		},
+		{
+			name:      "expired entries are refetched",
+			ttl:       10 * time.Millisecond,
+			sleep:     20 * time.Millisecond,
+			fetches:   []string{"fitness", "fitness"},
+			wantCalls: map[string]int{"fitness": 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeParser()
+			c := NewCachedParser(fake, tt.ttl)
+
+			for i, category := range tt.fetches {
+				if i > 0 && tt.sleep > 0 {
+					time.Sleep(tt.sleep)
+				}
+				sounds, err := c.FetchTrendingSounds(category)
+				if err != nil {
+					t.Fatalf("FetchTrendingSounds(%q) error: %v", category, err)
+				}
+				if len(sounds) != 1 || sounds[0].Category != category {
+					t.Fatalf("FetchTrendingSounds(%q) = %+v", category, sounds)
+				}
+			}
+
+			for category, want := range tt.wantCalls {
+				if got := fake.callCount(category); got != want {
+					t.Errorf("%s fetched %d times, want %d", category, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCachedParserDoesNotCacheErrors(t *testing.T) {
+	fake := newFakeParser()
+	fake.err = errors.New("boom")
+	c := NewCachedParser(fake, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.FetchTrendingSounds("fitness"); err == nil {
+			t.Fatal("expected error from wrapped parser")
+		}
+	}
+	if got := fake.callCount("fitness"); got != 2 {
+		t.Errorf("fetched %d times, want 2", got)
+	}
+}
+
+func TestCachedParserReturnsCopies(t *testing.T) {
+	c := NewCachedParser(newFakeParser(), time.Minute)
+
+	first, _ := c.FetchTrendingSounds("fitness")
+	first[0].Title = "mutated"
+
+	second, _ := c.FetchTrendingSounds("fitness")
+	if second[0].Title == "mutated" {
+		t.Error("cached sounds were mutated through a returned slice")
+	}
+}
+
+func TestCachedParserCloseInvalidates(t *testing.T) {
+	fake := newFakeParser()
+	c := NewCachedParser(fake, time.Minute)
+
+	c.FetchTrendingSounds("fitness")
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if !fake.closed {
+		t.Error("Close() did not close the wrapped parser")
+	}
+
+	c.FetchTrendingSounds("fitness")
+	if got := fake.callCount("fitness"); got != 2 {
+		t.Errorf("fetched %d times after Close, want 2", got)
+	}
+}
+
+func TestCachedParserConcurrent(t *testing.T) {
+	c := NewCachedParser(newFakeParser(), time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			category := []string{"fitness", "gaming"}[i%2]
+			if _, err := c.FetchTrendingSounds(category); err != nil {
+				t.Errorf("FetchTrendingSounds(%q) error: %v", category, err)
+			}
+			if i%5 == 0 {
+				c.Invalidate()
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestCachedParserFetchSound(t *testing.T) {
+	// The fake parser cannot fetch single sounds
+	c := NewCachedParser(newFakeParser(), time.Minute)
+	if _, err := c.FetchSound("https://www.tiktok.com/music/a-1", "fitness"); !errors.Is(err, ErrFetchUnsupported) {
+		t.Errorf("FetchSound() error = %v, want ErrFetchUnsupported", err)
+	}
+
+	api, requests := fakeAPIParser(`{"data":{"title":"a","use_count":10}}`)
+	c = NewCachedParser(api, time.Minute)
+	for i := 0; i < 2; i++ {
+		if _, err := c.FetchSound("https://www.tiktok.com/music/a-1", "fitness"); err != nil {
+			t.Fatalf("FetchSound() error: %v", err)
+		}
+	}
+	if len(*requests) != 2 {
+		t.Errorf("made %d requests, want 2 (single fetches are not cached)", len(*requests))
+	}
+}
+
+func TestCachedParserRegions(t *testing.T) {
+	api, requests := fakeAPIParser(`{"data":{"music_list":[{"title":"a","use_count":5000}]}}`)
+	c := NewCachedParser(api, time.Minute)
+
+	fetches := []struct {
+		region       string
+		wantRequests int
+	}{
+		{region: "US", wantRequests: 1},
+		{region: "US", wantRequests: 1}, // Cached
+		{region: "GB", wantRequests: 2}, // Regions are cached separately
+		{region: "global", wantRequests: 3},
+	}
+	for i, f := range fetches {
+		sounds, err := FetchRegion(c, "fitness", f.region)
+		if err != nil {
+			t.Fatalf("fetch %d: FetchRegion() error: %v", i, err)
+		}
+		if len(sounds) != 1 || sounds[0].Region != f.region {
+			t.Errorf("fetch %d: sounds = %+v, want one tagged %s", i, sounds, f.region)
+		}
+		if got := len(*requests); got != f.wantRequests {
+			t.Errorf("fetch %d: %d requests so far, want %d", i, got, f.wantRequests)
+		}
+	}
+
+	// A wrapped parser without regions can't serve a regional list
+	if _, err := FetchRegion(NewCachedParser(newFakeParser(), time.Minute), "fitness", "US"); !errors.Is(err, ErrRegionUnsupported) {
+		t.Errorf("FetchRegion() without region support error = %v, want %v", err, ErrRegionUnsupported)
+	}
+}
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// maxLoggedBody is how many bytes of a response body are written to the log
+const maxLoggedBody = 512
+
+// LoggingTransport is an http.RoundTripper that logs requests and responses for debugging.
+// If DumpDir is set, full response bodies are also written there for offline analysis.
+type LoggingTransport struct {
+	Next    http.RoundTripper
+	DumpDir string
+}
+
+// RoundTrip logs the request URL, response status and a truncated body
+func (t *LoggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	log.Printf("[parser debug] %s %s", req.Method, req.URL.String())
+
+	start := time.Now()
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		log.Printf("[parser debug] request failed after %s: %v", time.Since(start), err)
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	// Put the body back so the caller can still decode it
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	logged := body
+	if len(logged) > maxLoggedBody {
+		logged = logged[:maxLoggedBody]
+	}
+	log.Printf("[parser debug] status %d in %s, %d bytes: %s", resp.StatusCode, time.Since(start), len(body), logged)
+
+	if t.DumpDir != "" {
+		t.dump(body)
+	}
+
+	return resp, nil
+}
+
+// dump writes a raw response body to a timestamped file in DumpDir
+func (t *LoggingTransport) dump(body []byte) {
+	if err := os.MkdirAll(t.DumpDir, 0755); err != nil {
+		log.Printf("[parser debug] failed to create dump dir: %v", err)
+		return
+	}
+
+	name := filepath.Join(t.DumpDir, fmt.Sprintf("response-%s.json", time.Now().Format("20060102-150405.000")))
+	if err := os.WriteFile(name, body, 0644); err != nil {
+		log.Printf("[parser debug] failed to dump response: %v", err)
+		return
+	}
+	log.Printf("[parser debug] response dumped to %s", name)
+}
@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// captureLog redirects the standard logger into a buffer for the rest of the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buf
+}
+
+func TestLoggingTransport(t *testing.T) {
+	long := strings.Repeat("x", maxLoggedBody*2)
+
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		dump     bool
+		wantLogs []string
+		avoid    string
+	}{
+		{
+			name:     "records request and response",
+			status:   http.StatusOK,
+			body:     `{"sounds":[]}`,
+			wantLogs: []string{"[parser debug] GET ", "/trending?category=fitness", "status 200", `{"sounds":[]}`},
+		},
+		{
+			name:     "error status",
+			status:   http.StatusTooManyRequests,
+			body:     "slow down",
+			wantLogs: []string{"status 429", "slow down"},
+		},
+		{
+			name:     "long body is truncated in the log",
+			status:   http.StatusOK,
+			body:     long,
+			wantLogs: []string{"1024 bytes"},
+			avoid:    long,
+		},
+		{
+			name:     "raw response is dumped",
+			status:   http.StatusOK,
+			body:     `{"ok":true}`,
+			dump:     true,
+			wantLogs: []string{"response dumped to "},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+			defer srv.Close()
+
+			transport := &LoggingTransport{}
+			if tt.dump {
+				transport.DumpDir = t.TempDir()
+			}
+			logs := captureLog(t)
+
+			client := &http.Client{Transport: transport}
+			resp, err := client.Get(srv.URL + "/trending?category=fitness")
+			if err != nil {
+				t.Fatalf("Get() error: %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			if string(body) != tt.body {
+				t.Errorf("caller got body %q, want %q", body, tt.body)
+			}
+			for _, want := range tt.wantLogs {
+				if !strings.Contains(logs.String(), want) {
+					t.Errorf("log missing %q:\n%s", want, logs.String())
+				}
+			}
+			if tt.avoid != "" && strings.Contains(logs.String(), tt.avoid) {
+				t.Errorf("log contains the full body")
+			}
+
+			if tt.dump {
+				files, _ := os.ReadDir(transport.DumpDir)
+				if len(files) != 1 {
+					t.Fatalf("dumped %d files, want 1", len(files))
+				}
+				dumped, _ := os.ReadFile(filepath.Join(transport.DumpDir, files[0].Name()))
+				if string(dumped) != tt.body {
+					t.Errorf("dumped %q, want %q", dumped, tt.body)
+				}
+			}
+		})
+	}
+}
+
+func TestLoggingTransportRequestError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	logs := captureLog(t)
+	client := &http.Client{Transport: &LoggingTransport{}}
+	if _, err := client.Get(url); err == nil {
+		t.Fatal("expected an error from a closed server")
+	}
+	if !strings.Contains(logs.String(), "request failed") {
+		t.Errorf("log missing the failure:\n%s", logs.String())
+	}
+}
@@ -1,6 +1,11 @@
 package parser
 
-import "github.com/yourusername/trending-sound/internal/storage"
+import (
+	"errors"
+	"strings"
+
+	"github.com/yourusername/trending-sound/internal/storage"
+)
 
 // Parser defines the interface for TikTok sound parsing
 type Parser interface {
@@ -11,6 +16,66 @@ type Parser interface {
 	Close() error
 }
 
+// SoundFetcher is implemented by parsers that can fetch a single sound by its URL,
+// which collection uses to track user-nominated sounds
+type SoundFetcher interface {
+	FetchSound(url, category string) (*storage.Sound, error)
+}
+
+// ErrFetchUnsupported is returned by wrappers whose underlying parser cannot fetch single sounds
+var ErrFetchUnsupported = errors.New("parser cannot fetch single sounds")
+
+// RegionalFetcher is implemented by parsers that can fetch a region's trending list.
+// Regions are the codes the source understands, e.g. "US" or "GB".
+type RegionalFetcher interface {
+	FetchRegionalSounds(category, region string) ([]storage.Sound, error)
+}
+
+// ErrRegionUnsupported is returned when a regional list is requested from a parser that only knows the global one
+var ErrRegionUnsupported = errors.New("parser cannot fetch regional trends")
+
+// FetchRegion fetches a category's trending sounds for a region, using the plain global fetch for
+// storage.RegionGlobal. Every returned sound is tagged with the region.
+func FetchRegion(p Parser, category, region string) ([]storage.Sound, error) {
+	var (
+		sounds []storage.Sound
+		err    error
+	)
+	if storage.IsGlobalRegion(region) {
+		region = storage.RegionGlobal
+		sounds, err = p.FetchTrendingSounds(category)
+	} else {
+		fetcher, ok := p.(RegionalFetcher)
+		if !ok {
+			return nil, ErrRegionUnsupported
+		}
+		sounds, err = fetcher.FetchRegionalSounds(category, region)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sounds {
+		sounds[i].Region = region
+	}
+	return sounds, nil
+}
+
+// Source names recorded with sound history so parser outputs can be compared
+const (
+	SourceAPI  = "api"
+	SourceRod  = "rod"
+	SourceMock = "mock"
+)
+
+// MaxUsesCount is the largest believable uses count. Parsed values above it are treated as garbage.
+const MaxUsesCount int64 = 100_000_000_000
+
+// validUsesCount reports whether a parsed uses count is within believable bounds
+func validUsesCount(uses int64) bool {
+	return uses >= 0 && uses <= MaxUsesCount
+}
+
 // Categories supported by the parser
 var Categories = []string{
 	"fitness",
@@ -32,3 +97,48 @@ var CategoryDisplayNames = map[string]string{
 	"lifestyle": "Lifestyle",
 	"gaming":    "Gaming",
 }
+
+// CategoryAliases maps synonyms users type to canonical category keys
+var CategoryAliases = map[string]string{
+	"gym":        "fitness",
+	"workout":    "fitness",
+	"sport":      "fitness",
+	"makeup":     "beauty",
+	"skincare":   "beauty",
+	"funny":      "comedy",
+	"humor":      "comedy",
+	"memes":      "comedy",
+	"finance":    "business",
+	"money":      "business",
+	"marketing":  "business",
+	"technology": "tech",
+	"gadgets":    "tech",
+	"coding":     "tech",
+	"life":       "lifestyle",
+	"vlog":       "lifestyle",
+	"games":      "gaming",
+	"gamer":      "gaming",
+	"esports":    "gaming",
+}
+
+// ResolveCategory returns the canonical category for a category key or alias (case-insensitive)
+func ResolveCategory(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if IsValidCategory(name) {
+		return name, true
+	}
+	if category, ok := CategoryAliases[name]; ok {
+		return category, true
+	}
+	return "", false
+}
+
+// IsValidCategory reports whether the category is one of the supported categories
+func IsValidCategory(category string) bool {
+	for _, c := range Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
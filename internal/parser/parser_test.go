@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIsValidCategory(t *testing.T) {
+	tests := []struct {
+		category string
+		want     bool
+	}{
+		{"fitness", true},
+		{"gaming", true},
+		{"lifestyle", true},
+		{"Fitness", false},
+		{"knitting", false},
+		{"", false},
+		{"fitness ", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			if got := IsValidCategory(tt.category); got != tt.want {
+				t.Errorf("IsValidCategory(%q) = %v, want %v", tt.category, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveCategory(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{"fitness", "fitness", true},
+		{"gym", "fitness", true},
+		{"  Workout ", "fitness", true},
+		{"GAMES", "gaming", true},
+		{"money", "business", true},
+		{"knitting", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ResolveCategory(tt.name)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ResolveCategory(%q) = %q, %v, want %q, %v", tt.name, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCategoryAliasesResolveToCategories(t *testing.T) {
+	for alias, category := range CategoryAliases {
+		if !IsValidCategory(category) {
+			t.Errorf("alias %q maps to unknown category %q", alias, category)
+		}
+		if IsValidCategory(alias) {
+			t.Errorf("alias %q shadows a category key", alias)
+		}
+	}
+}
+
+func TestFetchRegion(t *testing.T) {
+	api, requests := fakeAPIParser(`{"data":{"music_list":[{"title":"a","use_count":5000}]}}`)
+
+	tests := []struct {
+		name       string
+		parser     Parser
+		region     string
+		wantRegion string // Region the sounds are tagged with
+		wantParam  string // region query parameter sent to the API
+		wantErr    error
+	}{
+		{name: "global", parser: api, region: "global", wantRegion: "global"},
+		{name: "empty means global", parser: api, region: "", wantRegion: "global"},
+		{name: "regional list", parser: api, region: "US", wantRegion: "US", wantParam: "US"},
+		{name: "global from a parser without regions", parser: newFakeParser(), region: "global", wantRegion: "global"},
+		{name: "region from a parser without regions", parser: newFakeParser(), region: "US", wantErr: ErrRegionUnsupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(*requests)
+
+			sounds, err := FetchRegion(tt.parser, "fitness", tt.region)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("FetchRegion() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchRegion() error: %v", err)
+			}
+			if len(sounds) != 1 || sounds[0].Region != tt.wantRegion {
+				t.Errorf("FetchRegion() = %+v, want one sound tagged %s", sounds, tt.wantRegion)
+			}
+			if tt.parser == api {
+				if len(*requests) != before+1 {
+					t.Fatalf("made %d requests, want 1", len(*requests)-before)
+				}
+				if got := (*requests)[before].URL.Query().Get("region"); got != tt.wantParam {
+					t.Errorf("region parameter = %q, want %q", got, tt.wantParam)
+				}
+			}
+		})
+	}
+}
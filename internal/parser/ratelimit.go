@@ -0,0 +1,116 @@
+package parser
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HostLimiter spaces HTTP requests per hostname so collection stays under a requests-per-minute budget.
+// A single limiter is shared by all parsers, so concurrent collectors are coordinated.
+type HostLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration        // Minimum spacing between request starts to one host, 0 = unlimited
+	next     map[string]time.Time // Earliest start of the next request per host
+	waiting  map[string]int       // Requests currently waiting per host
+}
+
+// HostRateState is a snapshot of one host's limiter state
+type HostRateState struct {
+	Host     string
+	NextSlot time.Time
+	Waiting  int
+}
+
+// NewHostLimiter creates a limiter allowing requestsPerMinute requests per host; <= 0 disables limiting
+func NewHostLimiter(requestsPerMinute int) *HostLimiter {
+	l := &HostLimiter{
+		next:    make(map[string]time.Time),
+		waiting: make(map[string]int),
+	}
+	if requestsPerMinute > 0 {
+		l.interval = time.Minute / time.Duration(requestsPerMinute)
+	}
+	return l
+}
+
+// Wait blocks until host's next request slot arrives and takes it, or until done is closed.
+// It reports whether a slot was taken. Slots are only taken when they arrive, so a caller
+// that gives up never holds one and the requests behind it are not delayed.
+func (l *HostLimiter) Wait(host string, done <-chan struct{}) bool {
+	if l.interval == 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	l.waiting[host]++
+	l.mu.Unlock()
+
+	defer func() {
+		l.mu.Lock()
+		l.waiting[host]--
+		l.mu.Unlock()
+	}()
+
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		slot := l.next[host]
+		if !slot.After(now) {
+			l.next[host] = now.Add(l.interval)
+			l.mu.Unlock()
+			return true
+		}
+		l.mu.Unlock()
+
+		// Another waiter may take the slot first; then wait for the one after it
+		timer := time.NewTimer(slot.Sub(now))
+		select {
+		case <-timer.C:
+		case <-done:
+			timer.Stop()
+			return false
+		}
+	}
+}
+
+// State returns the limiter state of every host seen so far, sorted by host
+func (l *HostLimiter) State() []HostRateState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	states := make([]HostRateState, 0, len(l.next))
+	for host, slot := range l.next {
+		states = append(states, HostRateState{Host: host, NextSlot: slot, Waiting: l.waiting[host]})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Host < states[j].Host })
+	return states
+}
+
+// RequestsPerMinute returns the configured per-host budget, 0 if unlimited
+func (l *HostLimiter) RequestsPerMinute() int {
+	if l.interval == 0 {
+		return 0
+	}
+	return int(time.Minute / l.interval)
+}
+
+// RateLimitedTransport is an http.RoundTripper that waits for the shared HostLimiter before each request
+type RateLimitedTransport struct {
+	Next    http.RoundTripper
+	Limiter *HostLimiter
+}
+
+// RoundTrip waits for the request host's next slot, then sends the request
+func (t *RateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	if !t.Limiter.Wait(req.URL.Hostname(), req.Context().Done()) {
+		return nil, req.Context().Err()
+	}
+	return next.RoundTrip(req)
+}
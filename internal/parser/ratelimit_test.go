@@ -0,0 +1,129 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHostLimiterSpacing(t *testing.T) {
+	tests := []struct {
+		name     string
+		rpm      int
+		hosts    []string
+		minTotal time.Duration // Minimum time for all waits to finish
+		maxTotal time.Duration
+	}{
+		{name: "one host is spaced", rpm: 600, hosts: []string{"a", "a", "a"}, minTotal: 200 * time.Millisecond, maxTotal: time.Second},
+		{name: "hosts do not block each other", rpm: 600, hosts: []string{"a", "b", "c"}, maxTotal: 50 * time.Millisecond},
+		{name: "disabled limiter", rpm: 0, hosts: []string{"a", "a", "a"}, maxTotal: 50 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewHostLimiter(tt.rpm)
+
+			var wg sync.WaitGroup
+			start := time.Now()
+			for _, host := range tt.hosts {
+				wg.Add(1)
+				go func(host string) {
+					defer wg.Done()
+					if !l.Wait(host, nil) {
+						t.Errorf("Wait(%s) = false, want true", host)
+					}
+				}(host)
+			}
+			wg.Wait()
+
+			elapsed := time.Since(start)
+			if elapsed < tt.minTotal-5*time.Millisecond || elapsed > tt.maxTotal {
+				t.Errorf("waits took %s, want between %s and %s", elapsed, tt.minTotal, tt.maxTotal)
+			}
+		})
+	}
+}
+
+func TestHostLimiterCancelGivesBackSlot(t *testing.T) {
+	l := NewHostLimiter(600) // 100ms apart
+	if !l.Wait("a", nil) {
+		t.Fatal("first Wait() = false, want true")
+	}
+
+	// Give up while waiting for the second slot
+	done := make(chan struct{})
+	close(done)
+	if l.Wait("a", done) {
+		t.Fatal("cancelled Wait() = true, want false")
+	}
+
+	// The next caller gets the second slot, not the one after the cancelled caller's
+	start := time.Now()
+	if !l.Wait("a", nil) {
+		t.Fatal("Wait() after cancel = false, want true")
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("Wait() after cancel took %s, want at most one interval", elapsed)
+	}
+
+	if states := l.State(); len(states) != 1 || states[0].Waiting != 0 {
+		t.Errorf("State() = %+v, want one host with nothing waiting", states)
+	}
+}
+
+func TestHostLimiterState(t *testing.T) {
+	l := NewHostLimiter(60)
+	if got := l.RequestsPerMinute(); got != 60 {
+		t.Errorf("RequestsPerMinute() = %d, want 60", got)
+	}
+	if states := l.State(); len(states) != 0 {
+		t.Errorf("State() before any request = %+v, want empty", states)
+	}
+
+	l.Wait("b.example.com", nil)
+	l.Wait("a.example.com", nil)
+
+	states := l.State()
+	if len(states) != 2 || states[0].Host != "a.example.com" || states[1].Host != "b.example.com" {
+		t.Fatalf("State() = %+v, want both hosts sorted", states)
+	}
+	if wait := time.Until(states[0].NextSlot); wait <= 0 || wait > time.Second {
+		t.Errorf("NextSlot in %s, want about a second", wait)
+	}
+
+	if got := NewHostLimiter(0).RequestsPerMinute(); got != 0 {
+		t.Errorf("disabled RequestsPerMinute() = %d, want 0", got)
+	}
+}
+
+func TestRateLimitedTransport(t *testing.T) {
+	l := NewHostLimiter(60)
+	calls := 0
+	transport := &RateLimitedTransport{
+		Limiter: l,
+		Next: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			calls++
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+		}),
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.example.com/trending", nil)
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("first RoundTrip() error: %v", err)
+	}
+
+	// The second request to the same host has to wait a second; the context ends first
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, "https://api.example.com/trending", nil)
+	if _, err := transport.RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("cancelled RoundTrip() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if calls != 1 {
+		t.Errorf("next transport called %d times, want 1", calls)
+	}
+}
@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+// ErrUnknownParser is returned by NewRoutingParser when a route names a parser that isn't available
+var ErrUnknownParser = errors.New("unknown parser")
+
+// RoutingParser sends each category to the parser configured for it, since some categories
+// scrape better with one source than another. Categories without a route use the fallback.
+type RoutingParser struct {
+	fallback Parser
+	parsers  map[string]Parser // Available parsers by name, e.g. SourceAPI or SourceRod
+	routes   map[string]string // Parser name keyed by category
+}
+
+// NewRoutingParser creates a parser routing categories to the named parsers
+func NewRoutingParser(fallback Parser, parsers map[string]Parser, routes map[string]string) (*RoutingParser, error) {
+	for category, name := range routes {
+		if _, ok := parsers[name]; !ok {
+			return nil, fmt.Errorf("%w %q for category %s", ErrUnknownParser, name, category)
+		}
+	}
+
+	return &RoutingParser{
+		fallback: fallback,
+		parsers:  parsers,
+		routes:   routes,
+	}, nil
+}
+
+// parserFor returns the parser configured for a category, or the fallback
+func (r *RoutingParser) parserFor(category string) Parser {
+	if name, ok := r.routes[category]; ok {
+		return r.parsers[name]
+	}
+	return r.fallback
+}
+
+// FetchTrendingSounds fetches a category from its configured parser
+func (r *RoutingParser) FetchTrendingSounds(category string) ([]storage.Sound, error) {
+	return r.parserFor(category).FetchTrendingSounds(category)
+}
+
+// FetchRegionalSounds fetches a region's list from the category's parser, if it supports regions
+func (r *RoutingParser) FetchRegionalSounds(category, region string) ([]storage.Sound, error) {
+	return FetchRegion(r.parserFor(category), category, region)
+}
+
+// FetchSound fetches a single sound from the category's parser, if it supports that
+func (r *RoutingParser) FetchSound(url, category string) (*storage.Sound, error) {
+	fetcher, ok := r.parserFor(category).(SoundFetcher)
+	if !ok {
+		return nil, ErrFetchUnsupported
+	}
+	return fetcher.FetchSound(url, category)
+}
+
+// Close closes the fallback and every routed parser once
+func (r *RoutingParser) Close() error {
+	closed := map[Parser]bool{r.fallback: true}
+	err := r.fallback.Close()
+	for name, p := range r.parsers {
+		if closed[p] {
+			continue
+		}
+		closed[p] = true
+		if closeErr := p.Close(); closeErr != nil {
+			log.Printf("Error closing %s parser: %v", name, closeErr)
+			if err == nil {
+				err = closeErr
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewRoutingParserUnknownParser(t *testing.T) {
+	fallback := newFakeParser()
+	parsers := map[string]Parser{SourceAPI: fallback}
+
+	_, err := NewRoutingParser(fallback, parsers, map[string]string{"gaming": SourceRod})
+	if !errors.Is(err, ErrUnknownParser) {
+		t.Errorf("NewRoutingParser() error = %v, want %v", err, ErrUnknownParser)
+	}
+}
+
+func TestRoutingParserDispatch(t *testing.T) {
+	api, rod := newFakeParser(), newFakeParser()
+	r, err := NewRoutingParser(api, map[string]Parser{SourceAPI: api, SourceRod: rod}, map[string]string{
+		"gaming": SourceRod,
+		"dance":  SourceAPI,
+	})
+	if err != nil {
+		t.Fatalf("NewRoutingParser() error: %v", err)
+	}
+
+	tests := []struct {
+		category string
+		want     *fakeParser
+		other    *fakeParser
+	}{
+		{category: "gaming", want: rod, other: api},
+		{category: "dance", want: api, other: rod},
+		{category: "fitness", want: api, other: rod}, // No route uses the fallback
+	}
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			sounds, err := r.FetchTrendingSounds(tt.category)
+			if err != nil {
+				t.Fatalf("FetchTrendingSounds() error: %v", err)
+			}
+			if len(sounds) != 1 || sounds[0].Category != tt.category {
+				t.Errorf("FetchTrendingSounds() = %+v, want the %s sound", sounds, tt.category)
+			}
+			if tt.want.callCount(tt.category) != 1 || tt.other.callCount(tt.category) != 0 {
+				t.Errorf("%s fetched by the wrong parser", tt.category)
+			}
+		})
+	}
+}
+
+func TestRoutingParserFetchSound(t *testing.T) {
+	api, _ := fakeAPIParser(`{"data":{"title":"a","use_count":10}}`)
+	rod := newFakeParser()
+	r, err := NewRoutingParser(api, map[string]Parser{SourceAPI: api, SourceRod: rod}, map[string]string{"gaming": SourceRod})
+	if err != nil {
+		t.Fatalf("NewRoutingParser() error: %v", err)
+	}
+
+	if _, err := r.FetchSound("https://www.tiktok.com/music/a-1", "fitness"); err != nil {
+		t.Errorf("FetchSound() through the API parser error: %v", err)
+	}
+	// The fake parser cannot fetch single sounds
+	if _, err := r.FetchSound("https://www.tiktok.com/music/a-1", "gaming"); !errors.Is(err, ErrFetchUnsupported) {
+		t.Errorf("FetchSound() through the fake parser error = %v, want %v", err, ErrFetchUnsupported)
+	}
+}
+
+func TestRoutingParserRegions(t *testing.T) {
+	api, _ := fakeAPIParser(`{"data":{"music_list":[{"title":"a","use_count":5000}]}}`)
+	rod := newFakeParser()
+	r, err := NewRoutingParser(api, map[string]Parser{SourceAPI: api, SourceRod: rod}, map[string]string{"gaming": SourceRod})
+	if err != nil {
+		t.Fatalf("NewRoutingParser() error: %v", err)
+	}
+
+	sounds, err := r.FetchRegionalSounds("fitness", "US")
+	if err != nil {
+		t.Fatalf("FetchRegionalSounds() through the API parser error: %v", err)
+	}
+	if len(sounds) != 1 || sounds[0].Region != "US" {
+		t.Errorf("FetchRegionalSounds() = %+v, want one sound tagged US", sounds)
+	}
+	if _, err := r.FetchRegionalSounds("gaming", "US"); !errors.Is(err, ErrRegionUnsupported) {
+		t.Errorf("FetchRegionalSounds() through the fake parser error = %v, want %v", err, ErrRegionUnsupported)
+	}
+}
+
+func TestRoutingParserClose(t *testing.T) {
+	api, rod := newFakeParser(), newFakeParser()
+	r, err := NewRoutingParser(api, map[string]Parser{SourceAPI: api, SourceRod: rod}, nil)
+	if err != nil {
+		t.Fatalf("NewRoutingParser() error: %v", err)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if !api.closed || !rod.closed {
+		t.Errorf("closed api = %v, rod = %v, want both", api.closed, rod.closed)
+	}
+}
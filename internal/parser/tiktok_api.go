@@ -13,34 +13,86 @@ import (
 
 // APIParser implements Parser using direct API calls
 type APIParser struct {
-	client *http.Client
+	client  *http.Client
+	queries map[string]string // API category values keyed by internal category
+
+	allowMockFallback bool // Serve mock data when the API returns no sounds
 }
 
-// NewAPIParser creates a new API-based parser
-func NewAPIParser() *APIParser {
+// NewAPIParser creates a new API-based parser whose requests give up after timeout
+func NewAPIParser(timeout time.Duration) *APIParser {
 	return &APIParser{
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: timeout,
 		},
 	}
 }
 
+// EnableDebugLogging wraps the HTTP client with a logging transport.
+// If dumpDir is not empty, raw responses are saved there.
+func (p *APIParser) EnableDebugLogging(dumpDir string) {
+	p.client.Transport = &LoggingTransport{
+		Next:    p.client.Transport,
+		DumpDir: dumpDir,
+	}
+}
+
+// SetHostLimiter makes every request wait for the shared per-host rate limiter
+func (p *APIParser) SetHostLimiter(limiter *HostLimiter) {
+	p.client.Transport = &RateLimitedTransport{
+		Next:    p.client.Transport,
+		Limiter: limiter,
+	}
+}
+
+// SetCategoryQueries sets the API category/hashtag value sent for each internal category.
+// Unmapped categories are sent as their raw key.
+func (p *APIParser) SetCategoryQueries(queries map[string]string) {
+	p.queries = queries
+}
+
+// SetMockFallback makes an empty API response return mock sounds instead of none.
+// Meant for local development only: in production it hides a broken API.
+func (p *APIParser) SetMockFallback(allow bool) {
+	p.allowMockFallback = allow
+}
+
+// categoryQuery returns the API category value for an internal category
+func (p *APIParser) categoryQuery(category string) string {
+	if query, ok := p.queries[category]; ok && query != "" {
+		return query
+	}
+	return category
+}
+
 // TikTokAPIResponse represents the API response structure
 // Note: This is a placeholder and needs to be adjusted based on actual API response
 type TikTokAPIResponse struct {
 	Data struct {
 		MusicList []struct {
-			MusicID   string `json:"music_id"`
-			Title     string `json:"title"`
-			Author    string `json:"author"`
-			UseCount  int64  `json:"use_count"`
-			MusicURL  string `json:"music_url"`
+			MusicID  string `json:"music_id"`
+			Title    string `json:"title"`
+			Author   string `json:"author"`
+			UseCount int64  `json:"use_count"`
+			MusicURL string `json:"music_url"`
+			Duration int    `json:"duration"`
+			Genre    string `json:"genre"`
 		} `json:"music_list"`
 	} `json:"data"`
 }
 
-// FetchTrendingSounds fetches trending sounds using TikTok API
+// FetchTrendingSounds fetches the global trending sounds using TikTok API
 func (p *APIParser) FetchTrendingSounds(category string) ([]storage.Sound, error) {
+	return p.fetchTrending(category, storage.RegionGlobal)
+}
+
+// FetchRegionalSounds fetches a region's trending sounds using TikTok API
+func (p *APIParser) FetchRegionalSounds(category, region string) ([]storage.Sound, error) {
+	return p.fetchTrending(category, region)
+}
+
+// fetchTrending fetches trending sounds for a category and region
+func (p *APIParser) fetchTrending(category, region string) ([]storage.Sound, error) {
 	// Note: This endpoint is a placeholder and needs to be adjusted
 	// based on actual TikTok API structure. You may need to:
 	// 1. Add authentication headers
@@ -61,11 +113,14 @@ func (p *APIParser) FetchTrendingSounds(category string) ([]storage.Sound, error
 
 	// Add query parameters if needed
 	q := req.URL.Query()
-	q.Add("category", category)
+	q.Add("category", p.categoryQuery(category))
 	q.Add("count", "50")
+	if !storage.IsGlobalRegion(region) {
+		q.Add("region", region)
+	}
 	req.URL.RawQuery = q.Encode()
 
-	log.Printf("Fetching sounds from API for category: %s", category)
+	log.Printf("Fetching sounds from API for category: %s, region: %s", category, region)
 
 	resp, err := p.client.Do(req)
 	if err != nil {
@@ -87,12 +142,22 @@ func (p *APIParser) FetchTrendingSounds(category string) ([]storage.Sound, error
 	// Convert to storage.Sound
 	var sounds []storage.Sound
 	for _, music := range apiResp.Data.MusicList {
+		if !validUsesCount(music.UseCount) {
+			log.Printf("Skipping sound %q with out-of-range uses count %d", music.Title, music.UseCount)
+			continue
+		}
+
 		sound := storage.Sound{
 			Title:     music.Title,
 			Author:    music.Author,
 			URL:       music.MusicURL,
 			UsesCount: music.UseCount,
+			MusicID:   music.MusicID,
+			Duration:  music.Duration,
+			Genre:     music.Genre,
 			Category:  category,
+			Region:    region,
+			Source:    SourceAPI,
 		}
 
 		// Generate URL if not provided
@@ -104,9 +169,11 @@ func (p *APIParser) FetchTrendingSounds(category string) ([]storage.Sound, error
 	}
 
 	if len(sounds) == 0 {
-		// Return mock data for testing purposes
-		// This should be removed in production
-		return p.getMockData(category), nil
+		if p.allowMockFallback {
+			return p.getMockData(category), nil
+		}
+		log.Printf("WARNING: API returned no sounds for category: %s", category)
+		return nil, nil
 	}
 
 	log.Printf("Successfully fetched %d sounds from API for category: %s", len(sounds), category)
@@ -114,10 +181,79 @@ func (p *APIParser) FetchTrendingSounds(category string) ([]storage.Sound, error
 	return sounds, nil
 }
 
+// TikTokMusicDetailResponse represents the single-sound API response structure
+// Note: This is a placeholder and needs to be adjusted based on actual API response
+type TikTokMusicDetailResponse struct {
+	Data struct {
+		MusicID  string `json:"music_id"`
+		Title    string `json:"title"`
+		Author   string `json:"author"`
+		UseCount int64  `json:"use_count"`
+		Duration int    `json:"duration"`
+		Genre    string `json:"genre"`
+	} `json:"data"`
+}
+
+// FetchSound fetches a single sound by its TikTok music URL
+func (p *APIParser) FetchSound(soundURL, category string) (*storage.Sound, error) {
+	musicID := storage.MusicIDFromURL(soundURL)
+	if musicID == "" {
+		return nil, fmt.Errorf("no music id in URL %q", soundURL)
+	}
+
+	// Note: This endpoint is a placeholder, like the trending one
+	req, err := http.NewRequest("GET", "https://m.tiktok.com/api/music/detail", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 14_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0 Mobile/15E148 Safari/604.1")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Referer", "https://www.tiktok.com/")
+
+	q := req.URL.Query()
+	q.Add("music_id", musicID)
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch from API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var detail TikTokMusicDetailResponse
+	if err := json.NewDecoder(resp.Body).Decode(&detail); err != nil {
+		return nil, fmt.Errorf("failed to decode API response: %w", err)
+	}
+	if detail.Data.Title == "" {
+		return nil, fmt.Errorf("API returned no sound for music id %s", musicID)
+	}
+	if !validUsesCount(detail.Data.UseCount) {
+		return nil, fmt.Errorf("out-of-range uses count %d", detail.Data.UseCount)
+	}
+
+	return &storage.Sound{
+		Title:     detail.Data.Title,
+		Author:    detail.Data.Author,
+		URL:       soundURL,
+		UsesCount: detail.Data.UseCount,
+		MusicID:   musicID,
+		Duration:  detail.Data.Duration,
+		Genre:     detail.Data.Genre,
+		Category:  category,
+		Source:    SourceAPI,
+	}, nil
+}
+
 // getMockData returns mock data for testing
 // This provides realistic trending sounds data for MVP
 func (p *APIParser) getMockData(category string) []storage.Sound {
-	log.Printf("Using mock data for category: %s (MVP mode)", category)
+	log.Printf("WARNING: API returned no sounds for category: %s, using MOCK data (PARSER_MOCK_FALLBACK=true)", category)
 
 	// Category-specific mock sounds
 	mockSounds := map[string][]storage.Sound{
@@ -185,6 +321,7 @@ func (p *APIParser) getMockData(category string) []storage.Sound {
 	// Set category for all sounds
 	for i := range sounds {
 		sounds[i].Category = category
+		sounds[i].Source = SourceMock
 	}
 
 	return sounds
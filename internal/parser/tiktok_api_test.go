@@ -0,0 +1,220 @@
+package parser
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// roundTripFunc serves API requests in tests without a network
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// fakeAPIParser returns an APIParser whose requests are recorded and answered with body
+func fakeAPIParser(body string) (*APIParser, *[]*http.Request) {
+	var (
+		mu       sync.Mutex
+		requests []*http.Request
+	)
+	p := NewAPIParser(time.Second)
+	p.client.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		requests = append(requests, r)
+		mu.Unlock()
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    r,
+		}, nil
+	})
+	return p, &requests
+}
+
+func TestCategoryQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		queries  map[string]string
+		category string
+		want     string
+	}{
+		{name: "mapped", queries: map[string]string{"fitness": "sports_fitness"}, category: "fitness", want: "sports_fitness"},
+		{name: "unmapped", queries: map[string]string{"fitness": "sports_fitness"}, category: "gaming", want: "gaming"},
+		{name: "empty mapping", queries: map[string]string{"fitness": ""}, category: "fitness", want: "fitness"},
+		{name: "no queries", queries: nil, category: "fitness", want: "fitness"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewAPIParser(time.Second)
+			p.SetCategoryQueries(tt.queries)
+			if got := p.categoryQuery(tt.category); got != tt.want {
+				t.Errorf("categoryQuery(%q) = %q, want %q", tt.category, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchTrendingSoundsSendsCategoryQuery(t *testing.T) {
+	p, requests := fakeAPIParser(`{"data":{"music_list":[]}}`)
+	p.SetCategoryQueries(map[string]string{"fitness": "sports_fitness"})
+
+	if _, err := p.FetchTrendingSounds("fitness"); err != nil {
+		t.Fatalf("FetchTrendingSounds() error: %v", err)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("made %d requests, want 1", len(*requests))
+	}
+	if got := (*requests)[0].URL.Query().Get("category"); got != "sports_fitness" {
+		t.Errorf("category parameter = %q, want sports_fitness", got)
+	}
+}
+
+func TestFetchTrendingSoundsSkipsOutOfRangeUses(t *testing.T) {
+	p, _ := fakeAPIParser(`{"data":{"music_list":[
+		{"title":"ok","use_count":5000},
+		{"title":"negative","use_count":-1},
+		{"title":"absurd","use_count":9000000000000000000}
+	]}}`)
+
+	sounds, err := p.FetchTrendingSounds("fitness")
+	if err != nil {
+		t.Fatalf("FetchTrendingSounds() error: %v", err)
+	}
+	if len(sounds) != 1 || sounds[0].Title != "ok" {
+		t.Errorf("sounds = %+v, want only ok", sounds)
+	}
+}
+
+func TestFetchTrendingSoundsMockFallback(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		fallback  bool
+		wantTitle string // Title of the first sound, empty = no sounds
+	}{
+		{name: "empty response without fallback", body: `{"data":{"music_list":[]}}`},
+		{name: "empty response with fallback", body: `{"data":{"music_list":[]}}`, fallback: true, wantTitle: "Workout Motivation Mix"},
+		{name: "real sounds win over the fallback", body: `{"data":{"music_list":[{"title":"real","use_count":5000}]}}`, fallback: true, wantTitle: "real"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := fakeAPIParser(tt.body)
+			p.SetMockFallback(tt.fallback)
+
+			sounds, err := p.FetchTrendingSounds("fitness")
+			if err != nil {
+				t.Fatalf("FetchTrendingSounds() error: %v", err)
+			}
+			if tt.wantTitle == "" {
+				if len(sounds) != 0 {
+					t.Errorf("sounds = %+v, want none", sounds)
+				}
+				return
+			}
+			if len(sounds) == 0 || sounds[0].Title != tt.wantTitle {
+				t.Errorf("sounds = %+v, want %q first", sounds, tt.wantTitle)
+			}
+		})
+	}
+}
+
+func TestFetchTrendingSoundsKeepsMusicID(t *testing.T) {
+	p, _ := fakeAPIParser(`{"data":{"music_list":[{"music_id":"7301","title":"a","use_count":5000}]}}`)
+
+	sounds, err := p.FetchTrendingSounds("fitness")
+	if err != nil {
+		t.Fatalf("FetchTrendingSounds() error: %v", err)
+	}
+	if len(sounds) != 1 || sounds[0].MusicID != "7301" {
+		t.Errorf("sounds = %+v, want one with music_id 7301", sounds)
+	}
+}
+
+func TestFetchSound(t *testing.T) {
+	const soundURL = "https://www.tiktok.com/music/gym-anthem-7301"
+
+	tests := []struct {
+		name    string
+		url     string
+		body    string
+		wantErr bool
+	}{
+		{name: "found", url: soundURL, body: `{"data":{"music_id":"7301","title":"gym anthem","author":"dj","use_count":4200}}`},
+		{name: "no music id in url", url: "https://www.tiktok.com/music/gym-anthem", body: `{}`, wantErr: true},
+		{name: "unknown sound", url: soundURL, body: `{"data":{}}`, wantErr: true},
+		{name: "out-of-range uses", url: soundURL, body: `{"data":{"title":"gym anthem","use_count":-3}}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, requests := fakeAPIParser(tt.body)
+
+			sound, err := p.FetchSound(tt.url, "fitness")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("FetchSound() = %+v, want an error", sound)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchSound() error: %v", err)
+			}
+			if got := (*requests)[0].URL.Query().Get("music_id"); got != "7301" {
+				t.Errorf("music_id parameter = %q, want 7301", got)
+			}
+			if sound.Title != "gym anthem" || sound.UsesCount != 4200 || sound.MusicID != "7301" || sound.URL != soundURL || sound.Category != "fitness" {
+				t.Errorf("FetchSound() = %+v", sound)
+			}
+		})
+	}
+}
+
+func TestFetchTrendingSoundsMetadata(t *testing.T) {
+	p, _ := fakeAPIParser(`{"data":{"music_list":[
+		{"title":"tagged","use_count":5000,"duration":30,"genre":"Pop"},
+		{"title":"bare","use_count":5000}
+	]}}`)
+
+	sounds, err := p.FetchTrendingSounds("fitness")
+	if err != nil {
+		t.Fatalf("FetchTrendingSounds() error: %v", err)
+	}
+	if len(sounds) != 2 {
+		t.Fatalf("got %d sounds, want 2", len(sounds))
+	}
+	if sounds[0].Duration != 30 || sounds[0].Genre != "Pop" {
+		t.Errorf("tagged sound = %+v, want 30s Pop", sounds[0])
+	}
+	if sounds[1].Duration != 0 || sounds[1].Genre != "" {
+		t.Errorf("bare sound = %+v, want no metadata", sounds[1])
+	}
+}
+
+func TestAPIParserTimeout(t *testing.T) {
+	p := NewAPIParser(50 * time.Millisecond)
+	if p.client.Timeout != 50*time.Millisecond {
+		t.Fatalf("client timeout = %s, want 50ms", p.client.Timeout)
+	}
+	// The API never answers; only the timeout ends the request
+	p.client.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		<-r.Context().Done()
+		return nil, r.Context().Err()
+	})
+
+	start := time.Now()
+	if _, err := p.FetchTrendingSounds("fitness"); err == nil {
+		t.Fatal("FetchTrendingSounds() error = nil, want a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("FetchTrendingSounds() took %s, want it to give up after about 50ms", elapsed)
+	}
+}
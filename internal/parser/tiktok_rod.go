@@ -1,48 +1,238 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-rod/rod"
 	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/proto"
 	"github.com/yourusername/trending-sound/internal/storage"
 )
 
+// pingTimeout bounds how long a liveness check waits for the browser to answer
+const pingTimeout = 10 * time.Second
+
+// HealthCheckInterval is how often the browser health check runs once started
+const HealthCheckInterval = 5 * time.Minute
+
+// ErrBrowserNotFound is returned by NewRodParser when no Chrome/Chromium binary is installed
+var ErrBrowserNotFound = errors.New("no Chrome/Chromium browser found")
+
+// ErrParserClosed is returned by Reconnect once Close has been called
+var ErrParserClosed = errors.New("rod parser is closed")
+
+// lookPath locates the browser binary; replaceable to simulate hosts without a browser
+var lookPath = launcher.LookPath
+
+// defaultMaxPages is the cap on simultaneously open pages when none is configured
+const defaultMaxPages = 3
+
 // RodParser implements Parser using rod for browser automation
 type RodParser struct {
-	browser   *rod.Browser
-	failCount int
-	maxFails  int
+	mu          sync.Mutex
+	browser     *rod.Browser
+	openPages   atomic.Int64
+	pageSlots   chan struct{} // Semaphore bounding simultaneously open pages
+	pageTimeout time.Duration
+	failCount   atomic.Int32 // Updated by concurrent fetches
+	maxFails    int32
+	stop        chan struct{} // Closed by Close to end the health check
+	healthDone  chan struct{} // Closed when the health check goroutine has exited
+	closed      bool          // Set by Close; a browser launched afterwards is closed right away
+}
+
+// NewRodParser creates a new rod-based parser allowing at most maxPages open pages at once,
+// each abandoned after pageTimeout. Extra FetchTrendingSounds callers wait for a page to close.
+// maxPages <= 0 uses the default.
+func NewRodParser(maxPages int, pageTimeout time.Duration) (*RodParser, error) {
+	browser, err := launchBrowser()
+	if err != nil {
+		return nil, err
+	}
+	return newRodParser(browser, maxPages, pageTimeout), nil
+}
+
+// newRodParser wraps an already connected browser
+func newRodParser(browser *rod.Browser, maxPages int, pageTimeout time.Duration) *RodParser {
+	if maxPages <= 0 {
+		maxPages = defaultMaxPages
+	}
+
+	return &RodParser{
+		browser:     browser,
+		pageSlots:   make(chan struct{}, maxPages),
+		pageTimeout: pageTimeout,
+		maxFails:    3,
+	}
 }
 
-// NewRodParser creates a new rod-based parser
-func NewRodParser() (*RodParser, error) {
-	// Launch browser
-	u := launcher.New().
+// closeBrowser closes a browser; replaceable so tests can track closes without a real browser
+var closeBrowser = (*rod.Browser).Close
+
+// launchBrowser starts a headless browser and connects to it.
+// It checks for an installed browser first so a missing one is an error rather than a download or panic.
+// Replaceable so tests can launch without a real browser.
+var launchBrowser = func() (*rod.Browser, error) {
+	bin, ok := lookPath()
+	if !ok {
+		log.Printf("Rod parser unavailable: %v (install Chrome/Chromium or use the API parser)", ErrBrowserNotFound)
+		return nil, ErrBrowserNotFound
+	}
+
+	u, err := launcher.New().
+		Bin(bin).
 		Headless(true).
 		Devtools(false).
-		MustLaunch()
+		Launch()
+	if err != nil {
+		return nil, fmt.Errorf("failed to launch browser: %w", err)
+	}
 
-	browser := rod.New().ControlURL(u).MustConnect()
+	browser := rod.New().ControlURL(u)
+	if err := browser.Connect(); err != nil {
+		return nil, fmt.Errorf("failed to connect to browser: %w", err)
+	}
+	return browser, nil
+}
 
-	return &RodParser{
-		browser:   browser,
-		failCount: 0,
-		maxFails:  3,
-	}, nil
+// currentBrowser returns the browser in use, which Reconnect may replace
+func (p *RodParser) currentBrowser() *rod.Browser {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.browser
+}
+
+// openPage waits for a free page slot, then opens a blank page and counts it until closePage is called
+func (p *RodParser) openPage() (*rod.Page, error) {
+	p.pageSlots <- struct{}{}
+
+	page, err := p.currentBrowser().Page(proto.TargetCreateTarget{})
+	if err != nil {
+		<-p.pageSlots
+		return nil, fmt.Errorf("failed to open page: %w", err)
+	}
+	p.openPages.Add(1)
+	return page, nil
+}
+
+// closePage closes a page opened by openPage and frees its slot, logging rather than panicking on failure
+func (p *RodParser) closePage(page *rod.Page) {
+	defer func() { <-p.pageSlots }()
+
+	p.openPages.Add(-1)
+	if err := page.Close(); err != nil {
+		log.Printf("Failed to close browser page: %v", err)
+	}
+}
+
+// OpenPages returns the number of pages currently open, for leak detection
+func (p *RodParser) OpenPages() int64 {
+	return p.openPages.Load()
+}
+
+// Ping checks that the browser still responds to CDP calls
+func (p *RodParser) Ping() error {
+	if _, err := p.currentBrowser().Timeout(pingTimeout).Version(); err != nil {
+		return fmt.Errorf("browser not responding: %w", err)
+	}
+	return nil
+}
+
+// Reconnect closes the current browser and launches a fresh one. It returns ErrParserClosed if
+// Close was called, including while the new browser was launching.
+func (p *RodParser) Reconnect() error {
+	browser, err := launchBrowser()
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		// Close already ran and won't see this browser, so it has to be closed here
+		if err := closeBrowser(browser); err != nil {
+			log.Printf("Failed to close browser launched after Close: %v", err)
+		}
+		return ErrParserClosed
+	}
+	old := p.browser
+	p.browser = browser
+	p.mu.Unlock()
+	p.failCount.Store(0)
+
+	// In-flight fetches still release their pages (and slots) through closePage
+	if old != nil {
+		if err := closeBrowser(old); err != nil {
+			log.Printf("Failed to close old browser: %v", err)
+		}
+	}
+	return nil
+}
+
+// CheckHealth pings the browser, reconnecting if it is unresponsive, and reports page leaks
+func (p *RodParser) CheckHealth() error {
+	// All slots taken between fetches usually means pages are not being closed
+	if open := p.OpenPages(); open >= int64(cap(p.pageSlots)) {
+		log.Printf("Warning: rod parser has %d open pages (cap %d), possible page leak", open, cap(p.pageSlots))
+	}
+
+	if err := p.Ping(); err != nil {
+		log.Printf("Rod browser health check failed: %v, reconnecting", err)
+		if err := p.Reconnect(); err != nil {
+			return fmt.Errorf("failed to reconnect browser: %w", err)
+		}
+	}
+	return nil
+}
+
+// StartHealthCheck runs CheckHealth every interval until Close is called
+func (p *RodParser) StartHealthCheck(interval time.Duration) {
+	p.mu.Lock()
+	if p.stop != nil || p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.stop = make(chan struct{})
+	p.healthDone = make(chan struct{})
+	stop, done := p.stop, p.healthDone
+	p.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := p.CheckHealth(); err != nil {
+					log.Printf("Error checking rod browser health: %v", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
 }
 
 // FetchTrendingSounds fetches trending sounds using browser automation
 func (p *RodParser) FetchTrendingSounds(category string) ([]storage.Sound, error) {
-	page := p.browser.MustPage()
-	defer page.MustClose()
+	page, err := p.openPage()
+	if err != nil {
+		p.failCount.Add(1)
+		return nil, err
+	}
+	defer p.closePage(page)
 
 	// Set timeout
-	page = page.Timeout(60 * time.Second)
+	page = page.Timeout(p.pageTimeout)
 
 	// Navigate to TikTok Creative Center
 	// Note: This URL is a placeholder and needs to be adjusted based on actual TikTok Creative Center structure
@@ -50,16 +240,16 @@ func (p *RodParser) FetchTrendingSounds(category string) ([]storage.Sound, error
 
 	log.Printf("Navigating to %s for category: %s", url, category)
 
-	err := page.Navigate(url)
+	err = page.Navigate(url)
 	if err != nil {
-		p.failCount++
+		p.failCount.Add(1)
 		return nil, fmt.Errorf("failed to navigate: %w", err)
 	}
 
 	// Wait for page to load
 	err = page.WaitLoad()
 	if err != nil {
-		p.failCount++
+		p.failCount.Add(1)
 		return nil, fmt.Errorf("failed to wait for page load: %w", err)
 	}
 
@@ -70,12 +260,12 @@ func (p *RodParser) FetchTrendingSounds(category string) ([]storage.Sound, error
 	// Note: CSS selectors need to be adjusted based on actual TikTok Creative Center HTML structure
 	sounds, err := p.parseSounds(page, category)
 	if err != nil {
-		p.failCount++
+		p.failCount.Add(1)
 		return nil, err
 	}
 
 	// Reset fail count on success
-	p.failCount = 0
+	p.failCount.Store(0)
 
 	return sounds, nil
 }
@@ -130,6 +320,7 @@ func (p *RodParser) extractSoundFromElement(elem *rod.Element, category string)
 
 	sound := &storage.Sound{
 		Category: category,
+		Source:   SourceRod,
 	}
 
 	// Try to extract title
@@ -156,6 +347,20 @@ func (p *RodParser) extractSoundFromElement(elem *rod.Element, category string)
 		}
 	}
 
+	// Try to extract optional metadata
+	durationElem, err := elem.Element("*[class*='duration'], *[class*='length']")
+	if err == nil && durationElem != nil {
+		if durationText, err := durationElem.Text(); err == nil {
+			sound.Duration = parseDurationSeconds(durationText)
+		}
+	}
+	genreElem, err := elem.Element("*[class*='genre']")
+	if err == nil && genreElem != nil {
+		if genre, err := genreElem.Text(); err == nil {
+			sound.Genre = strings.TrimSpace(genre)
+		}
+	}
+
 	// Try to extract URL
 	linkElem, err := elem.Element("a")
 	if err == nil && linkElem != nil {
@@ -177,6 +382,19 @@ func (p *RodParser) extractSoundFromElement(elem *rod.Element, category string)
 	return sound, nil
 }
 
+// parseDurationSeconds parses a duration like "0:30" or "1:05:00" into seconds, returning 0 if unparseable
+func parseDurationSeconds(text string) int {
+	seconds := 0
+	for _, part := range strings.Split(strings.TrimSpace(text), ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds
+}
+
 // parseUsesCount parses uses count from text like "15.2K" or "1.5M"
 func parseUsesCount(text string) int64 {
 	text = strings.TrimSpace(text)
@@ -202,22 +420,41 @@ func parseUsesCount(text string) int64 {
 
 	// Parse the number
 	num, err := strconv.ParseFloat(text, 64)
-	if err != nil {
+	if err != nil || math.IsNaN(num) || math.IsInf(num, 0) {
 		return 0
 	}
 
-	return int64(num * float64(multiplier))
+	// Reject absurd values before converting, float64 -> int64 overflow is undefined
+	uses := num * float64(multiplier)
+	if uses < 0 || uses > float64(MaxUsesCount) {
+		log.Printf("Rejecting out-of-range uses count %q", text)
+		return 0
+	}
+
+	return int64(uses)
 }
 
 // ShouldFallback returns true if the parser has failed too many times
 func (p *RodParser) ShouldFallback() bool {
-	return p.failCount >= p.maxFails
+	return p.failCount.Load() >= p.maxFails
 }
 
-// Close closes the browser
+// Close stops health checks and closes the browser. It waits for a health check in progress,
+// so a reconnect can't leave a browser running after Close returns.
 func (p *RodParser) Close() error {
-	if p.browser != nil {
-		return p.browser.Close()
+	p.mu.Lock()
+	p.closed = true
+	stop, done := p.stop, p.healthDone
+	p.stop = nil
+	browser := p.browser
+	p.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	if browser != nil {
+		return closeBrowser(browser)
 	}
 	return nil
 }
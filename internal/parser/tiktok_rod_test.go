@@ -0,0 +1,228 @@
+package parser
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-rod/rod"
+)
+
+func TestShouldFallback(t *testing.T) {
+	tests := []struct {
+		fails int32
+		want  bool
+	}{
+		{fails: 0, want: false},
+		{fails: 2, want: false},
+		{fails: 3, want: true},
+		{fails: 7, want: true},
+	}
+
+	for _, tt := range tests {
+		p := &RodParser{maxFails: 3}
+		p.failCount.Store(tt.fails)
+		if got := p.ShouldFallback(); got != tt.want {
+			t.Errorf("ShouldFallback() with %d fails = %v, want %v", tt.fails, got, tt.want)
+		}
+	}
+}
+
+func TestFailCountConcurrent(t *testing.T) {
+	p := &RodParser{maxFails: 3}
+
+	// Concurrent fetches count failures while the scheduler checks ShouldFallback
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.failCount.Add(1)
+			p.ShouldFallback()
+		}()
+	}
+	wg.Wait()
+
+	if got := p.failCount.Load(); got != 50 {
+		t.Errorf("failCount = %d, want 50", got)
+	}
+}
+
+func TestStartHealthCheckStopsOnClose(t *testing.T) {
+	p := &RodParser{maxFails: 3}
+
+	p.StartHealthCheck(time.Hour)
+	p.StartHealthCheck(time.Hour) // already running, no second loop
+	if p.stop == nil {
+		t.Fatal("StartHealthCheck() did not start the loop")
+	}
+
+	done := p.healthDone
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if p.stop != nil {
+		t.Error("Close() did not stop the health check")
+	}
+	select {
+	case <-done:
+	default:
+		t.Error("Close() returned before the health check goroutine exited")
+	}
+
+	p.StartHealthCheck(time.Hour)
+	if p.stop != nil {
+		t.Error("StartHealthCheck() started a loop after Close")
+	}
+}
+
+func TestReconnectCloseRace(t *testing.T) {
+	tests := []struct {
+		name       string
+		closeFirst bool // Close runs while the new browser is launching
+		wantErr    error
+	}{
+		{name: "reconnect finishes first", wantErr: nil},
+		{name: "close during launch", closeFirst: true, wantErr: ErrParserClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old, fresh := rod.New(), rod.New()
+
+			var mu sync.Mutex
+			closed := make(map[*rod.Browser]int)
+			origClose, origLaunch := closeBrowser, launchBrowser
+			closeBrowser = func(b *rod.Browser) error {
+				mu.Lock()
+				defer mu.Unlock()
+				closed[b]++
+				return nil
+			}
+			launching, release := make(chan struct{}), make(chan struct{})
+			launchBrowser = func() (*rod.Browser, error) {
+				close(launching)
+				<-release
+				return fresh, nil
+			}
+			t.Cleanup(func() { closeBrowser, launchBrowser = origClose, origLaunch })
+
+			p := newRodParser(old, 1, time.Minute)
+			errc := make(chan error, 1)
+			go func() { errc <- p.Reconnect() }()
+			<-launching
+
+			if tt.closeFirst {
+				if err := p.Close(); err != nil {
+					t.Fatalf("Close() error: %v", err)
+				}
+			}
+			close(release)
+			if err := <-errc; !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Reconnect() error = %v, want %v", err, tt.wantErr)
+			}
+			if !tt.closeFirst {
+				if err := p.Close(); err != nil {
+					t.Fatalf("Close() error: %v", err)
+				}
+			}
+
+			// Every browser is closed exactly once, whichever finished first
+			mu.Lock()
+			defer mu.Unlock()
+			for name, b := range map[string]*rod.Browser{"old": old, "new": fresh} {
+				if closed[b] != 1 {
+					t.Errorf("%s browser closed %d times, want 1", name, closed[b])
+				}
+			}
+		})
+	}
+}
+
+func TestNewRodParserPageCap(t *testing.T) {
+	tests := []struct {
+		maxPages int
+		want     int
+	}{
+		{maxPages: 0, want: defaultMaxPages},
+		{maxPages: -1, want: defaultMaxPages},
+		{maxPages: 1, want: 1},
+		{maxPages: 8, want: 8},
+	}
+
+	for _, tt := range tests {
+		p := newRodParser(nil, tt.maxPages, time.Minute)
+		if got := cap(p.pageSlots); got != tt.want {
+			t.Errorf("newRodParser(%d) page cap = %d, want %d", tt.maxPages, got, tt.want)
+		}
+		if p.pageTimeout != time.Minute {
+			t.Errorf("pageTimeout = %s, want 1m", p.pageTimeout)
+		}
+	}
+}
+
+func TestParseUsesCount(t *testing.T) {
+	tests := []struct {
+		text string
+		want int64
+	}{
+		{"1234", 1234},
+		{"12.5K uses", 12500},
+		{"3M posts", 3000000},
+		{"1.2B", 1200000000},
+		{"100B", MaxUsesCount},
+		{"101B", 0},
+		{"1e300", 0},
+		{"NaN", 0},
+		{"Inf", 0},
+		{"-5K", 0},
+		{"lots", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := parseUsesCount(tt.text); got != tt.want {
+				t.Errorf("parseUsesCount(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRodParserWithoutBrowser(t *testing.T) {
+	orig := lookPath
+	lookPath = func() (string, bool) { return "", false }
+	t.Cleanup(func() { lookPath = orig })
+
+	p, err := NewRodParser(1, time.Second)
+	if !errors.Is(err, ErrBrowserNotFound) {
+		t.Fatalf("NewRodParser() error = %v, want ErrBrowserNotFound", err)
+	}
+	if p != nil {
+		t.Errorf("NewRodParser() = %v, want nil parser", p)
+	}
+}
+
+func TestParseDurationSeconds(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"0:30", 30},
+		{" 1:05 ", 65},
+		{"1:05:00", 3900},
+		{"45", 45},
+		{"", 0},
+		{"0:-5", 0},
+		{"1:xx", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := parseDurationSeconds(tt.text); got != tt.want {
+				t.Errorf("parseDurationSeconds(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
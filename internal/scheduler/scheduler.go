@@ -1,12 +1,22 @@
 package scheduler
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"log"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/robfig/cron/v3"
 	"github.com/yourusername/trending-sound/internal/bot"
+	"github.com/yourusername/trending-sound/internal/config"
 	"github.com/yourusername/trending-sound/internal/detector"
+	"github.com/yourusername/trending-sound/internal/eventbus"
 	"github.com/yourusername/trending-sound/internal/parser"
 	"github.com/yourusername/trending-sound/internal/storage"
 )
@@ -14,42 +24,83 @@ import (
 // Scheduler handles scheduled tasks for data collection and alerts
 type Scheduler struct {
 	cron     *cron.Cron
+	cfg      *config.Config
 	parser   parser.Parser
 	storage  storage.Storage
 	detector *detector.TrendDetector
 	bot      *bot.Bot
+	bus      *eventbus.Bus
 }
 
 // New creates a new scheduler
-func New(p parser.Parser, s storage.Storage, d *detector.TrendDetector, b *bot.Bot) *Scheduler {
+func New(cfg *config.Config, p parser.Parser, s storage.Storage, d *detector.TrendDetector, b *bot.Bot) *Scheduler {
 	return &Scheduler{
 		cron:     cron.New(),
+		cfg:      cfg,
 		parser:   p,
 		storage:  s,
 		detector: d,
 		bot:      b,
+		bus:      eventbus.New(),
 	}
 }
 
+// Bus returns the scheduler's event bus so other components can subscribe
+func (s *Scheduler) Bus() *eventbus.Bus {
+	return s.bus
+}
+
 // Start starts the scheduler
 func (s *Scheduler) Start() {
+	s.subscribe()
+
 	// Collect sounds every 3 hours
-	s.cron.AddFunc("0 */3 * * *", func() {
+	s.cron.AddFunc(defaultCollectionCron, func() {
 		log.Println("Starting scheduled sound collection...")
 		s.CollectSounds()
 	})
 
+	// Categories with their own cron collect separately from the global loop
+	for category, spec := range s.categorySchedules() {
+		if spec == defaultCollectionCron {
+			continue
+		}
+		category := category
+		_, err := s.cron.AddFunc(spec, func() {
+			log.Printf("Starting scheduled collection for category: %s", category)
+			if err := s.ManualCollect(category); err != nil {
+				log.Printf("Error collecting sounds for %s: %v", category, err)
+			}
+		})
+		if err != nil {
+			log.Printf("Invalid cron %q for category %s: %v", spec, category, err)
+			continue
+		}
+		log.Printf("Category %s collects on custom schedule: %s", category, spec)
+	}
+
 	// Send alerts every 6 hours
 	s.cron.AddFunc("0 */6 * * *", func() {
 		log.Println("Starting scheduled alert sending...")
 		s.SendAlerts()
 	})
 
+	// Forget global dedup records past their retention
+	s.cron.AddFunc("30 3 * * *", func() {
+		s.PruneSentAlerts()
+	})
+
+	// Drop premium from users whose paid period ended
+	s.cron.AddFunc("0 * * * *", func() {
+		if err := s.storage.CheckAndExpirePremium(); err != nil {
+			log.Printf("Error expiring premium: %v", err)
+		}
+	})
+
 	// Run initial collection and alert on startup (after a short delay)
 	go func() {
-		time.Sleep(10 * time.Second)
-		log.Println("Running initial sound collection...")
-		s.CollectSounds()
+		time.Sleep(s.cfg.InitialDelay)
+		s.initialCollection()
 
 		// Wait a bit for data to be saved
 		time.Sleep(5 * time.Second)
@@ -61,9 +112,148 @@ func (s *Scheduler) Start() {
 	log.Println("Scheduler started")
 }
 
+// initialCollection seeds baseline history and runs the startup collection or backfill, unless
+// RUN_INITIAL_COLLECTION turned it off
+func (s *Scheduler) initialCollection() {
+	backfill := s.shouldBackfill()
+	s.SeedBaseline()
+	switch {
+	case !s.cfg.InitialCollect:
+		log.Println("Initial sound collection disabled by RUN_INITIAL_COLLECTION")
+	case backfill:
+		s.Backfill()
+	default:
+		log.Println("Running initial sound collection...")
+		s.CollectSounds()
+	}
+}
+
+// shouldBackfill reports whether startup should seed history, which only happens on a database without any
+func (s *Scheduler) shouldBackfill() bool {
+	if s.cfg.BackfillCount <= 0 {
+		return false
+	}
+
+	hasHistory, err := s.storage.HasSoundHistory()
+	if err != nil {
+		log.Printf("Error checking history before backfill: %v", err)
+		return false
+	}
+	if hasHistory {
+		log.Println("Database already has history, skipping backfill")
+		return false
+	}
+	return true
+}
+
+// Backfill runs BackfillCount collections BackfillInterval apart so the detector has a baseline sooner
+func (s *Scheduler) Backfill() {
+	if s.cfg.ParserCacheTTL > 0 && s.cfg.BackfillInterval < s.cfg.ParserCacheTTL {
+		log.Printf("Warning: BACKFILL_INTERVAL %s is shorter than PARSER_CACHE_TTL %s, backfill will record cached data", s.cfg.BackfillInterval, s.cfg.ParserCacheTTL)
+	}
+
+	for i := 1; i <= s.cfg.BackfillCount; i++ {
+		log.Printf("Backfill: empty database, running seed collection %d/%d", i, s.cfg.BackfillCount)
+		s.CollectSounds()
+
+		if i < s.cfg.BackfillCount {
+			time.Sleep(s.cfg.BackfillInterval)
+		}
+	}
+	log.Println("Backfill complete")
+}
+
+// SeedBaseline gives every sound without history a baseline point BaselineBackdate in the past,
+// so sounds imported without history are compared against something on the next collection
+func (s *Scheduler) SeedBaseline() {
+	if s.cfg.BaselineBackdate <= 0 {
+		return
+	}
+
+	seeded, err := s.storage.SeedBaselineHistory(time.Now().Add(-s.cfg.BaselineBackdate))
+	if err != nil {
+		log.Printf("Error seeding baseline history (%d seeded before the error): %v", seeded, err)
+		return
+	}
+	if seeded > 0 {
+		log.Printf("Seeded baseline history for %d sounds, backdated %s", seeded, s.cfg.BaselineBackdate)
+	}
+}
+
+// subscribe registers the scheduler's reactions to collection events
+func (s *Scheduler) subscribe() {
+	// Refresh precomputed trend scores right after fresh data lands for a category
+	s.bus.Subscribe(eventbus.TopicCollectionCompleted, func(e eventbus.Event) {
+		scores, err := s.detector.ComputeTrendScores(e.Category)
+		if err != nil {
+			log.Printf("Error computing trend scores for %s: %v", e.Category, err)
+			return
+		}
+		if err := s.storage.SaveTrendScores(e.Category, scores); err != nil {
+			log.Printf("Error saving trend scores for %s: %v", e.Category, err)
+		}
+	})
+
+	// Alert /instant users as soon as fresh data for one of their niches lands
+	s.bus.Subscribe(eventbus.TopicCollectionCompleted, func(e eventbus.Event) {
+		s.SendInstantAlerts(e.Category)
+	})
+
+	// Feed every newly detected trend to the public channel once fresh data lands
+	if s.cfg.BroadcastChannel != 0 {
+		s.bus.Subscribe(eventbus.TopicCollectionCompleted, func(e eventbus.Event) {
+			s.PostChannelTrends(e.Category)
+		})
+	}
+
+	// Tell followers when a freshly collected sound crosses a milestone
+	s.bus.Subscribe(eventbus.TopicCollectionCompleted, func(e eventbus.Event) {
+		s.CheckMilestones(e.Category)
+	})
+}
+
+// defaultCollectionCron is the collection schedule for categories without an override
+const defaultCollectionCron = "0 */3 * * *"
+
+// fetchDelay is the pause between fetches of a collection run, to avoid rate limiting
+var fetchDelay = 2 * time.Second
+
+// categorySchedules returns the collection cron spec for every category,
+// using per-category overrides from config and the default for the rest
+func (s *Scheduler) categorySchedules() map[string]string {
+	return buildCategorySchedules(parser.Categories, s.cfg.CategoryCrons, defaultCollectionCron)
+}
+
+// buildCategorySchedules maps each category to its cron spec. Overrides for unknown categories are ignored,
+// and categories whose override doesn't parse keep the default so they are still collected.
+func buildCategorySchedules(categories []string, overrides map[string]string, defaultSpec string) map[string]string {
+	schedules := make(map[string]string, len(categories))
+	for _, category := range categories {
+		schedules[category] = defaultSpec
+		spec, ok := overrides[category]
+		if !ok {
+			continue
+		}
+		if _, err := cron.ParseStandard(spec); err != nil {
+			log.Printf("Invalid cron %q for category %s, using the default schedule: %v", spec, category, err)
+			continue
+		}
+		schedules[category] = spec
+	}
+
+	for category := range overrides {
+		if _, ok := schedules[category]; !ok {
+			log.Printf("Ignoring cron override for unknown category: %s", category)
+		}
+	}
+
+	return schedules
+}
+
 // Stop stops the scheduler
 func (s *Scheduler) Stop() {
 	s.cron.Stop()
+	s.bus.Close()
 	log.Println("Scheduler stopped")
 }
 
@@ -71,41 +261,251 @@ func (s *Scheduler) Stop() {
 func (s *Scheduler) CollectSounds() {
 	log.Println("Collecting sounds from all categories...")
 
+	schedules := s.categorySchedules()
 	for _, category := range parser.Categories {
+		// Categories with a custom cron are collected by their own entry
+		if schedules[category] != defaultCollectionCron {
+			continue
+		}
+
 		log.Printf("Collecting sounds for category: %s", category)
 
-		sounds, err := s.parser.FetchTrendingSounds(category)
+		saved, err := s.collectCategory(category)
 		if err != nil {
 			log.Printf("Error fetching sounds for %s: %v", category, err)
 			continue
 		}
+		if saved {
+			s.bus.Publish(eventbus.Event{Topic: eventbus.TopicCollectionCompleted, Category: category})
+		}
+
+		// Small delay between categories to avoid rate limiting
+		time.Sleep(fetchDelay)
+	}
 
-		log.Printf("Fetched %d sounds for category: %s", len(sounds), category)
+	log.Println("Sound collection completed")
+
+	s.CollectNominations()
+}
+
+// collectCategory fetches and saves a category's trending sounds for every configured region.
+// It reports whether any region saved new data, and returns an error only if no region could be fetched.
+func (s *Scheduler) collectCategory(category string) (bool, error) {
+	var (
+		saved    bool
+		fetched  bool
+		firstErr error
+	)
+	for i, region := range s.cfg.Regions {
+		// Small delay between regions to avoid rate limiting
+		if i > 0 {
+			time.Sleep(fetchDelay)
+		}
+
+		sounds, err := parser.FetchRegion(s.parser, category, region)
+		if err != nil {
+			log.Printf("Error fetching sounds for %s in region %s: %v", category, region, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		fetched = true
+
+		log.Printf("Fetched %d sounds for category: %s, region: %s", len(sounds), category, region)
+
+		key := collectionKey(category, region)
+		if s.isStaleCollection(key, sounds) {
+			continue
+		}
 
 		// Save each sound with history
 		for _, sound := range sounds {
-			err := storage.SaveSoundWithHistory(s.storage, &sound)
+			normalizeCategory(&sound, category)
+			err := storage.SaveSoundWithHistory(s.storage, &sound, storage.DedupStrategy(s.cfg.DedupStrategy))
 			if err != nil {
 				log.Printf("Error saving sound %s: %v", sound.Title, err)
 				continue
 			}
 		}
 
-		log.Printf("Successfully saved %d sounds for category: %s", len(sounds), category)
+		log.Printf("Successfully saved %d sounds for category: %s, region: %s", len(sounds), category, region)
+		s.recordCollectionHash(key, sounds)
+		saved = true
+	}
 
-		// Small delay between categories to avoid rate limiting
-		time.Sleep(2 * time.Second)
+	if !fetched {
+		return false, firstErr
 	}
+	return saved, nil
+}
 
-	log.Println("Sound collection completed")
+// collectionKey identifies a category and region for the stale-collection check.
+// The global region uses the bare category, as before regions existed.
+func collectionKey(category, region string) string {
+	if storage.IsGlobalRegion(region) {
+		return category
+	}
+	return category + "@" + region
+}
+
+// CollectNominations fetches user-nominated sounds so they get tracked even outside trending lists.
+// A nomination is marked tracked once its sound is stored, whether fetched here or by regular collection.
+func (s *Scheduler) CollectNominations() {
+	nominations, err := s.storage.GetPendingNominations()
+	if err != nil {
+		log.Printf("Error getting nominations: %v", err)
+		return
+	}
+	if len(nominations) == 0 {
+		return
+	}
+
+	fetcher, canFetch := s.parser.(parser.SoundFetcher)
+	tracked := 0
+	for _, nomination := range nominations {
+		if canFetch {
+			sound, err := fetcher.FetchSound(nomination.URL, nomination.Category)
+			if err != nil {
+				log.Printf("Error fetching nominated sound %s: %v", nomination.URL, err)
+			} else if err := storage.SaveSoundWithHistory(s.storage, sound, storage.DedupStrategy(s.cfg.DedupStrategy)); err != nil {
+				log.Printf("Error saving nominated sound %s: %v", nomination.URL, err)
+			}
+		}
+
+		if _, err := s.storage.GetSoundByURL(nomination.URL); err != nil {
+			continue
+		}
+		if err := s.storage.MarkNominationTracked(nomination.ID); err != nil {
+			log.Printf("Error marking nomination %d tracked: %v", nomination.ID, err)
+			continue
+		}
+		tracked++
+	}
+
+	log.Printf("Nominations: %d of %d pending now tracked", tracked, len(nominations))
 }
 
-// SendAlerts sends trending alerts to all users
+// collectionHash fingerprints a fetched sound set by URL and uses count, independent of order
+func collectionHash(sounds []storage.Sound) string {
+	lines := make([]string, 0, len(sounds))
+	for _, sound := range sounds {
+		lines = append(lines, fmt.Sprintf("%s|%d", storage.CanonicalizeURL(sound.URL), sound.UsesCount))
+	}
+	sort.Strings(lines)
+
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// isStaleCollection reports whether the parser returned exactly the same data as the previous cycle.
+// Saving it would add flat history that hides a stuck parser or endpoint.
+func (s *Scheduler) isStaleCollection(category string, sounds []storage.Sound) bool {
+	previous, err := s.storage.GetSetting(storage.CollectionHashKey(category))
+	if err != nil {
+		log.Printf("Error getting collection hash for %s: %v", category, err)
+		return false
+	}
+
+	if previous != "" && previous == collectionHash(sounds) {
+		log.Printf("Warning: no new data for category %s, fetched sounds are identical to the previous cycle, skipping history", category)
+		return true
+	}
+	return false
+}
+
+// recordCollectionHash stores the hash of the sound set just saved for a category
+func (s *Scheduler) recordCollectionHash(category string, sounds []storage.Sound) {
+	if err := s.storage.SetSetting(storage.CollectionHashKey(category), collectionHash(sounds)); err != nil {
+		log.Printf("Error saving collection hash for %s: %v", category, err)
+	}
+}
+
+// alertSounds returns the sounds to alert a user about for a niche:
+// trending by growth by default, or the most-used sounds in popularity mode
+func (s *Scheduler) alertSounds(user *storage.User, niche string) ([]storage.TrendingSound, error) {
+	limit := s.bot.ResultLimit(user)
+	if user.AlertMode != storage.AlertModePopularity {
+		criteria := s.detector.Criteria()
+
+		// Premium /threshold overrides: per niche, then global
+		thresholds, err := s.storage.GetNicheMinGrowths(user.TelegramID)
+		if err != nil {
+			log.Printf("Error getting thresholds for user %d: %v", user.TelegramID, err)
+		}
+		criteria.MinGrowth = bot.MinGrowthFor(user, thresholds[niche], criteria.MinGrowth)
+		criteria.MinGrowth = bot.BoostedMinGrowth(user, criteria.MinGrowth, s.cfg.BoostFactor, time.Now())
+		criteria.MinUsesCount = bot.MinUsesFor(user, criteria.MinUsesCount, criteria.MaxUsesCount)
+
+		criteria.Region = user.Region
+
+		// Beta users get cross-niche flagging before the flag enables it for everyone
+		if bot.BetaEnabled(user) || s.bot.FeatureEnabled(bot.FeatureCrossNiche) {
+			criteria.CrossNiche = true
+		}
+		return s.detector.DetectTrendingWithCriteria(niche, limit, criteria)
+	}
+
+	sounds, err := s.storage.GetTopSoundsByUses(niche, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ordered by uses, so the position is the sound's rank in the niche
+	top := make([]storage.TrendingSound, 0, len(sounds))
+	for i, sound := range sounds {
+		top = append(top, storage.TrendingSound{Sound: sound, Rank: i + 1})
+	}
+	return top, nil
+}
+
+// CheckMilestones notifies users whose followed sounds in a category crossed a uses milestone
+func (s *Scheduler) CheckMilestones(category string) {
+	follows, err := s.storage.GetFollowedSounds()
+	if err != nil {
+		log.Printf("Error getting followed sounds: %v", err)
+		return
+	}
+
+	for _, f := range follows {
+		if f.Sound.Category != category {
+			continue
+		}
+
+		milestone := detector.CrossedMilestone(f.LastMilestone, f.Sound.UsesCount)
+		if milestone == 0 {
+			continue
+		}
+
+		if err := s.bot.SendMilestoneAlert(f.TelegramID, f.Sound, milestone); err != nil {
+			log.Printf("Error sending milestone alert to user %d: %v", f.TelegramID, err)
+			continue
+		}
+
+		// Record it so the same milestone is never sent twice
+		if err := s.storage.SetFollowMilestone(f.TelegramID, f.Sound.ID, milestone); err != nil {
+			log.Printf("Error saving milestone for user %d: %v", f.TelegramID, err)
+		}
+	}
+}
+
+// SendAlerts sends trending alerts to all users, several users at a time
 func (s *Scheduler) SendAlerts() {
 	log.Println("Sending trending alerts to users...")
 
-	// Get all users
-	users, err := s.storage.GetAllUsers()
+	// Check the global kill-switch first
+	paused, err := s.storage.GetSetting(storage.SettingAlertsPaused)
+	if err != nil {
+		log.Printf("Error checking alerts pause flag: %v", err)
+		return
+	}
+	if paused == "true" {
+		log.Println("Alerts are paused, skipping alert sending")
+		return
+	}
+
+	// Get all users who may receive alerts
+	users, err := s.storage.GetAlertUsers()
 	if err != nil {
 		log.Printf("Error getting users: %v", err)
 		return
@@ -113,62 +513,512 @@ func (s *Scheduler) SendAlerts() {
 
 	log.Printf("Found %d users", len(users))
 
-	alertsSent := 0
+	// Hold off in staging-like environments until enough users signed up
+	if len(users) < s.cfg.MinUsersForAlert {
+		log.Printf("Only %d users, below MIN_USERS_FOR_ALERTS=%d, skipping alert sending", len(users), s.cfg.MinUsersForAlert)
+		return
+	}
 
-	for _, user := range users {
-		niches := bot.GetUserNiches(&user)
-		if len(niches) == 0 {
-			continue
+	// Latency is measured from here, so users late in the loop show when the cycle falls behind
+	cycleStart := time.Now()
+
+	// Sounds flagged in earlier cycles within the window are repeats; ones first flagged
+	// during this cycle are not loaded here, so every user in the cycle still gets them
+	var repeats map[int64]bool
+	windowStart := cycleStart.Add(-s.cfg.AlertDedupWindow)
+	if s.cfg.AlertDedupWindow > 0 {
+		repeats, err = s.storage.GetAlertedSoundsSince(windowStart)
+		if err != nil {
+			log.Printf("Error getting alerted sounds: %v", err)
 		}
+	}
 
-		log.Printf("Sending alerts to user %d for niches: %v", user.TelegramID, niches)
+	workers := s.cfg.AlertWorkers
+	if workers < 1 {
+		workers = 1
+	}
 
-		for _, niche := range niches {
-			// Detect trending sounds for this niche
-			trending, err := s.detector.DetectTrending(niche, 5)
-			if err != nil {
-				log.Printf("Error detecting trends for %s: %v", niche, err)
-				continue
+	// Each user is handled start to finish by one worker, so their alerts still go out in order;
+	// the bot's send limiter is shared by all workers and caps overall throughput
+	queue := make(chan storage.User)
+	var alertsSent atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Spaces messages per recipient; a worker only paces the users it handles
+			pacer := newRecipientPacer(s.cfg.AlertInterval)
+			for user := range queue {
+				alertsSent.Add(int64(s.alertUser(&user, pacer, repeats, cycleStart, windowStart)))
 			}
+		}()
+	}
 
-			if len(trending) == 0 {
-				log.Printf("No trending sounds found for niche: %s", niche)
+	// Users are claimed so instances sharing the database split them instead of alerting anyone
+	// twice; alertUser releases each claim once that user is done. Claiming only as many users as
+	// there are workers keeps claimed users from waiting in line long enough for their claim to expire.
+	for {
+		batch, err := s.storage.ClaimUsersForAlert(workers, s.cfg.AlertClaimTTL, cycleStart)
+		if err != nil {
+			log.Printf("Error claiming users: %v", err)
+			break
+		}
+		if len(batch) == 0 {
+			break
+		}
+		for _, user := range batch {
+			queue <- user
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	log.Printf("Alert sending completed. Sent %d alerts", alertsSent.Load())
+}
+
+// alertUser sends one user's alerts for the cycle, or queues them and sends a digest when one is due.
+// It returns the number of messages sent.
+func (s *Scheduler) alertUser(user *storage.User, pacer *recipientPacer, repeats map[int64]bool, cycleStart, windowStart time.Time) int {
+	defer func() {
+		if err := s.storage.ReleaseUserClaim(user); err != nil {
+			log.Printf("Error releasing claim on user %d: %v", user.TelegramID, err)
+		}
+	}()
+
+	sent := 0
+
+	// Instant users were alerted right after each collection instead
+	if bot.InstantAlertsEnabled(user) {
+		return 0
+	}
+
+	// The user may have waited for a worker past the claim TTL
+	if !s.renewClaim(user) {
+		return 0
+	}
+
+	niches := s.bot.PrioritizeNiches(user, s.bot.AlertNiches(user))
+	if len(niches) == 0 {
+		return 0
+	}
+
+	today := alertDay(user, cycleStart)
+	sentToday := alertsSentOn(user, today)
+
+	// Digest users get this cycle's sounds queued and receive them together when a delivery is due
+	digest := digestInterval(user.DigestFrequency) > 0
+
+	log.Printf("Sending alerts to user %d for niches: %v", user.TelegramID, niches)
+
+	for _, niche := range niches {
+		// Stop once the user's daily cap is reached; it resets at midnight in their timezone
+		if !digest && s.cfg.MaxAlertsPerDay > 0 && sentToday >= s.cfg.MaxAlertsPerDay {
+			log.Printf("User %d reached the daily cap of %d alerts", user.TelegramID, s.cfg.MaxAlertsPerDay)
+			break
+		}
+
+		// Pick sounds for this niche according to the user's alert mode
+		trending, err := s.alertSounds(user, niche)
+		if err != nil {
+			log.Printf("Error detecting trends for %s: %v", niche, err)
+			continue
+		}
+
+		if skipsRepeats(user) {
+			trending = withoutRepeats(trending, repeats)
+		}
+
+		if len(trending) == 0 {
+			log.Printf("No trending sounds found for niche: %s", niche)
+			continue
+		}
+
+		if digest {
+			if err := s.storage.QueueDigestSounds(user.TelegramID, niche, trending, time.Now()); err != nil {
+				log.Printf("Error queueing digest for user %d: %v", user.TelegramID, err)
 				continue
 			}
+			s.markAlerted(user, trending, windowStart)
+			continue
+		}
 
-			// Send alert
-			err = s.bot.SendTrendingAlert(user.TelegramID, niche, trending)
-			if err != nil {
-				log.Printf("Error sending alert to user %d: %v", user.TelegramID, err)
+		// Send alert
+		pacer.wait(user.TelegramID)
+		if !s.renewClaim(user) {
+			return sent
+		}
+		err = s.deliverAlert(user, niche, trending, cycleStart, windowStart, today)
+		pacer.sent(user.TelegramID)
+		if err != nil {
+			log.Printf("Error sending alert to user %d: %v", user.TelegramID, err)
+			continue
+		}
+
+		sent++
+		sentToday++
+	}
+
+	if digest && digestDue(user, cycleStart) {
+		if s.cfg.MaxAlertsPerDay > 0 && sentToday >= s.cfg.MaxAlertsPerDay {
+			log.Printf("User %d reached the daily cap of %d alerts, holding the digest", user.TelegramID, s.cfg.MaxAlertsPerDay)
+			return sent
+		}
+
+		pacer.wait(user.TelegramID)
+		if !s.renewClaim(user) {
+			return sent
+		}
+		delivered, err := s.sendDigest(user, niches)
+		pacer.sent(user.TelegramID)
+		if err != nil {
+			log.Printf("Error sending digest to user %d: %v", user.TelegramID, err)
+			return sent
+		}
+		if !delivered {
+			return sent
+		}
+
+		sent++
+		if err := s.storage.IncrementDailyAlerts(user.TelegramID, today); err != nil {
+			log.Printf("Error counting alert for user %d: %v", user.TelegramID, err)
+		}
+	}
+
+	return sent
+}
+
+// renewClaim extends this instance's claim on the user right before sending them something, since
+// pacing and slow sends can outlast ALERT_CLAIM_TTL. It reports false when the claim expired and
+// another instance took the user over; that instance alerts them instead.
+func (s *Scheduler) renewClaim(user *storage.User) bool {
+	renewed, err := s.storage.RenewUserClaim(user)
+	if err != nil {
+		log.Printf("Error renewing claim on user %d: %v", user.TelegramID, err)
+		return false
+	}
+	if !renewed {
+		log.Printf("Claim on user %d expired and was taken over by another instance, skipping them", user.TelegramID)
+	}
+	return renewed
+}
+
+// deliverAlert sends one niche alert and records it: latency since cycleStart, the user's alert
+// history, the global dedup window and the daily alert count
+func (s *Scheduler) deliverAlert(user *storage.User, niche string, trending []storage.TrendingSound, cycleStart, windowStart time.Time, today string) error {
+	if err := s.bot.SendTrendingAlert(user.TelegramID, niche, trending); err != nil {
+		return err
+	}
+
+	if err := s.storage.SaveAlertLatency(user.TelegramID, niche, time.Since(cycleStart)); err != nil {
+		log.Printf("Error saving alert latency for user %d: %v", user.TelegramID, err)
+	}
+	if err := s.storage.RecordSentAlert(user.TelegramID, niche, trending, time.Now()); err != nil {
+		log.Printf("Error recording alert history for user %d: %v", user.TelegramID, err)
+	}
+
+	s.markAlerted(user, trending, windowStart)
+
+	if err := s.storage.IncrementDailyAlerts(user.TelegramID, today); err != nil {
+		log.Printf("Error counting alert for user %d: %v", user.TelegramID, err)
+	}
+	return nil
+}
+
+// instantRepeatWindow is how long a sound sent to an instant-alerts user is not sent to them again,
+// since every collection would otherwise repeat the niche's still-trending sounds
+const instantRepeatWindow = 24 * time.Hour
+
+// SendInstantAlerts alerts premium users who opted into /instant about a category right after
+// fresh data for it was collected, skipping sounds they got within instantRepeatWindow
+func (s *Scheduler) SendInstantAlerts(category string) {
+	paused, err := s.storage.GetSetting(storage.SettingAlertsPaused)
+	if err != nil {
+		log.Printf("Error checking alerts pause flag: %v", err)
+		return
+	}
+	if paused == "true" {
+		return
+	}
+
+	users, err := s.storage.GetAlertUsers()
+	if err != nil {
+		log.Printf("Error getting users: %v", err)
+		return
+	}
+	if len(users) < s.cfg.MinUsersForAlert {
+		return
+	}
+
+	cycleStart := time.Now()
+
+	var repeats map[int64]bool
+	windowStart := cycleStart.Add(-s.cfg.AlertDedupWindow)
+	if s.cfg.AlertDedupWindow > 0 {
+		repeats, err = s.storage.GetAlertedSoundsSince(windowStart)
+		if err != nil {
+			log.Printf("Error getting alerted sounds: %v", err)
+		}
+	}
+
+	alertsSent := 0
+	for _, user := range users {
+		if !bot.InstantAlertsEnabled(&user) || !slices.Contains(s.bot.AlertNiches(&user), category) {
+			continue
+		}
+		today := alertDay(&user, cycleStart)
+		if s.cfg.MaxAlertsPerDay > 0 && alertsSentOn(&user, today) >= s.cfg.MaxAlertsPerDay {
+			continue
+		}
+
+		trending, err := s.alertSounds(&user, category)
+		if err != nil {
+			log.Printf("Error detecting trends for %s: %v", category, err)
+			continue
+		}
+		if skipsRepeats(&user) {
+			trending = withoutRepeats(trending, repeats)
+		}
+
+		sent, err := s.storage.GetUserAlertedSoundsSince(user.TelegramID, cycleStart.Add(-instantRepeatWindow))
+		if err != nil {
+			log.Printf("Error getting alert history for user %d: %v", user.TelegramID, err)
+			continue
+		}
+		trending = withoutRepeats(trending, sent)
+		if len(trending) == 0 {
+			continue
+		}
+
+		if err := s.deliverAlert(&user, category, trending, cycleStart, windowStart, today); err != nil {
+			log.Printf("Error sending instant alert to user %d: %v", user.TelegramID, err)
+			continue
+		}
+		alertsSent++
+	}
+
+	if alertsSent > 0 {
+		log.Printf("Sent %d instant alerts for %s", alertsSent, category)
+	}
+}
+
+// PostChannelTrends posts a category's trending sounds that were never posted before to BROADCAST_CHANNEL
+func (s *Scheduler) PostChannelTrends(category string) {
+	trending, err := s.detector.DetectTrending(category, s.cfg.PremiumResultLimit)
+	if err != nil {
+		log.Printf("Error detecting trends for channel post in %s: %v", category, err)
+		return
+	}
+
+	posted, err := s.storage.GetChannelPostedSounds()
+	if err != nil {
+		log.Printf("Error getting channel posts: %v", err)
+		return
+	}
+	fresh := withoutRepeats(trending, posted)
+	if len(fresh) == 0 {
+		return
+	}
+
+	if err := s.bot.PostChannelTrends(s.cfg.BroadcastChannel, category, fresh); err != nil {
+		log.Printf("Error posting %s trends to channel %d: %v", category, s.cfg.BroadcastChannel, err)
+		return
+	}
+	if err := s.storage.MarkChannelPosted(soundIDs(fresh), time.Now()); err != nil {
+		log.Printf("Error marking channel posts: %v", err)
+	}
+	log.Printf("Posted %d new %s trends to channel %d", len(fresh), category, s.cfg.BroadcastChannel)
+}
+
+// markAlerted records sounds as flagged for the global dedup window
+func (s *Scheduler) markAlerted(user *storage.User, trending []storage.TrendingSound, windowStart time.Time) {
+	if s.cfg.AlertDedupWindow <= 0 || user.AlertMode == storage.AlertModePopularity {
+		return
+	}
+	if err := s.storage.MarkSoundsAlerted(soundIDs(trending), time.Now(), windowStart); err != nil {
+		log.Printf("Error marking sounds alerted: %v", err)
+	}
+}
+
+// digestSlack lets a digest go out on the alert cycle closest to its due time even when the
+// previous delivery finished a little after its cycle started
+const digestSlack = 30 * time.Minute
+
+// digestInterval returns the time between digests for a frequency, 0 for per-cycle delivery
+func digestInterval(frequency string) time.Duration {
+	switch frequency {
+	case storage.DigestDaily:
+		return 24 * time.Hour
+	case storage.DigestTwiceDaily:
+		return 12 * time.Hour
+	}
+	return 0
+}
+
+// digestDue reports whether a digest user should receive their digest in the cycle starting at now
+func digestDue(user *storage.User, now time.Time) bool {
+	interval := digestInterval(user.DigestFrequency)
+	if interval <= 0 {
+		return false
+	}
+	if user.LastDigestAt.IsZero() {
+		return true
+	}
+	return now.Sub(user.LastDigestAt) >= interval-digestSlack
+}
+
+// sendDigest delivers a user's queued sounds in one message and clears the queue.
+// It reports false when nothing was queued for the user's current niches.
+func (s *Scheduler) sendDigest(user *storage.User, niches []string) (bool, error) {
+	queued, err := s.storage.GetDigestSounds(user.TelegramID)
+	if err != nil {
+		return false, err
+	}
+
+	sentAt := time.Now()
+	hasSounds := false
+	for _, niche := range niches {
+		hasSounds = hasSounds || len(queued[niche]) > 0
+	}
+	if hasSounds {
+		if err := s.bot.SendDigest(user, niches, queued); err != nil {
+			return false, err
+		}
+		for _, niche := range niches {
+			if len(queued[niche]) == 0 {
 				continue
 			}
+			if err := s.storage.RecordSentAlert(user.TelegramID, niche, queued[niche], sentAt); err != nil {
+				log.Printf("Error recording alert history for user %d: %v", user.TelegramID, err)
+			}
+		}
+	}
+
+	// Also clears sounds from niches the user dropped since they were queued
+	if err := s.storage.MarkDigestSent(user.TelegramID, sentAt); err != nil {
+		return hasSounds, err
+	}
+	return hasSounds, nil
+}
+
+// recipientPacer keeps a minimum gap between messages to the same recipient without
+// holding back messages to anyone else
+type recipientPacer struct {
+	interval time.Duration
+	lastSent map[int64]time.Time
+	now      func() time.Time
+	sleep    func(time.Duration)
+}
+
+// newRecipientPacer creates a pacer with the given per-recipient gap, 0 disables pacing
+func newRecipientPacer(interval time.Duration) *recipientPacer {
+	return &recipientPacer{
+		interval: interval,
+		lastSent: make(map[int64]time.Time),
+		now:      time.Now,
+		sleep:    time.Sleep,
+	}
+}
+
+// wait blocks until the interval has passed since the last message to the recipient.
+// Time spent detecting trends since then counts towards the gap.
+func (p *recipientPacer) wait(recipient int64) {
+	last, ok := p.lastSent[recipient]
+	if !ok || p.interval <= 0 {
+		return
+	}
+	if remaining := p.interval - p.now().Sub(last); remaining > 0 {
+		p.sleep(remaining)
+	}
+}
+
+// sent records that a message to the recipient just went out
+func (p *recipientPacer) sent(recipient int64) {
+	p.lastSent[recipient] = p.now()
+}
+
+// PruneSentAlerts deletes global dedup records older than ALERT_DEDUP_RETENTION.
+// Records inside the dedup window are always kept, even with a shorter retention.
+func (s *Scheduler) PruneSentAlerts() {
+	retain := s.cfg.AlertDedupRetain
+	if retain < s.cfg.AlertDedupWindow {
+		retain = s.cfg.AlertDedupWindow
+	}
 
-			alertsSent++
+	pruned, err := s.storage.PruneSentAlerts(time.Now().Add(-retain))
+	if err != nil {
+		log.Printf("Error pruning sent alerts: %v", err)
+		return
+	}
+	log.Printf("Pruned %d sent alert records older than %s", pruned, retain)
+}
+
+// skipsRepeats reports whether the user's alerts leave out sounds already flagged within the global
+// dedup window. Popularity mode never flags sounds, and Premium users can opt into repeats with /repeats.
+func skipsRepeats(user *storage.User) bool {
+	return user.AlertMode != storage.AlertModePopularity && !(user.IsPremium && user.ShowRepeats)
+}
 
-			// Rate limiting: 1 message per second
-			time.Sleep(1 * time.Second)
+// withoutRepeats drops sounds already seen, e.g. flagged within the global dedup window or posted to the channel
+func withoutRepeats(sounds []storage.TrendingSound, repeats map[int64]bool) []storage.TrendingSound {
+	if len(repeats) == 0 {
+		return sounds
+	}
+	fresh := make([]storage.TrendingSound, 0, len(sounds))
+	for _, sound := range sounds {
+		if !repeats[sound.ID] {
+			fresh = append(fresh, sound)
 		}
 	}
+	return fresh
+}
 
-	log.Printf("Alert sending completed. Sent %d alerts", alertsSent)
+// soundIDs returns the IDs of the given sounds
+func soundIDs(sounds []storage.TrendingSound) []int64 {
+	ids := make([]int64, len(sounds))
+	for i, sound := range sounds {
+		ids[i] = sound.ID
+	}
+	return ids
+}
+
+// normalizeCategory defaults a sound's empty category to the category being collected
+func normalizeCategory(sound *storage.Sound, category string) {
+	if strings.TrimSpace(sound.Category) == "" {
+		log.Printf("Parser returned sound %q without category, using %s", sound.Title, category)
+		sound.Category = category
+	}
+}
+
+// dayLayout is the format of the per-user daily alert counter day
+const dayLayout = "2006-01-02"
+
+// alertDay returns the day the user's daily alert cap counts at t, in the user's timezone
+func alertDay(user *storage.User, t time.Time) string {
+	return t.In(bot.UserLocation(user)).Format(dayLayout)
+}
+
+// alertsSentOn returns how many alerts the user got on the given day
+func alertsSentOn(user *storage.User, day string) int {
+	if user.AlertsDay != day {
+		return 0
+	}
+	return user.AlertsToday
 }
 
 // ManualCollect triggers a manual collection for a specific category
 func (s *Scheduler) ManualCollect(category string) error {
 	log.Printf("Manual collection triggered for category: %s", category)
 
-	sounds, err := s.parser.FetchTrendingSounds(category)
+	saved, err := s.collectCategory(category)
 	if err != nil {
 		return err
 	}
-
-	for _, sound := range sounds {
-		err := storage.SaveSoundWithHistory(s.storage, &sound)
-		if err != nil {
-			log.Printf("Error saving sound %s: %v", sound.Title, err)
-		}
+	if !saved {
+		return nil
 	}
 
 	log.Printf("Manual collection completed for category: %s", category)
+	s.bus.Publish(eventbus.Event{Topic: eventbus.TopicCollectionCompleted, Category: category})
 	return nil
 }
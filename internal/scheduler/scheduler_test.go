@@ -0,0 +1,1841 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/trending-sound/internal/bot"
+	"github.com/yourusername/trending-sound/internal/config"
+	"github.com/yourusername/trending-sound/internal/detector"
+	"github.com/yourusername/trending-sound/internal/eventbus"
+	"github.com/yourusername/trending-sound/internal/parser"
+	"github.com/yourusername/trending-sound/internal/storage"
+)
+
+func TestMain(m *testing.M) {
+	// Storage reads migrations/init.sql relative to the repository root
+	if err := os.Chdir("../.."); err != nil {
+		panic(err)
+	}
+	os.Setenv("TELEGRAM_BOT_TOKEN", "test-token")
+	fetchDelay = 0
+	os.Exit(m.Run())
+}
+
+// fakeAPI records messages the bot sends instead of calling Telegram
+type fakeAPI struct {
+	mu   sync.Mutex
+	sent []tgbotapi.Chattable
+
+	delay       time.Duration // How long each Send takes, like a round trip to Telegram
+	inFlight    int
+	maxInFlight int                // Most sends that were in progress at once
+	onSend      func(chatID int64) // Called after each message is sent
+}
+
+func (f *fakeAPI) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	f.mu.Lock()
+	f.inFlight++
+	if f.inFlight > f.maxInFlight {
+		f.maxInFlight = f.inFlight
+	}
+	delay := f.delay
+	f.mu.Unlock()
+
+	time.Sleep(delay)
+
+	f.mu.Lock()
+	f.inFlight--
+	f.sent = append(f.sent, c)
+	id, onSend := len(f.sent), f.onSend
+	f.mu.Unlock()
+
+	if m, ok := c.(tgbotapi.MessageConfig); ok && onSend != nil {
+		onSend(m.ChatID)
+	}
+	return tgbotapi.Message{MessageID: id}, nil
+}
+
+func (f *fakeAPI) Request(tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	return &tgbotapi.APIResponse{Ok: true}, nil
+}
+
+func (f *fakeAPI) GetUpdates(tgbotapi.UpdateConfig) ([]tgbotapi.Update, error) {
+	return nil, nil
+}
+
+// messagesTo returns the text of every message sent to a chat
+func (f *fakeAPI) messagesTo(chatID int64) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []string
+	for _, c := range f.sent {
+		if m, ok := c.(tgbotapi.MessageConfig); ok && m.ChatID == chatID {
+			out = append(out, m.Text)
+		}
+	}
+	return out
+}
+
+// count returns how many messages were sent
+func (f *fakeAPI) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.sent)
+}
+
+// fakeParser returns a fixed set of sounds per category and counts fetches
+type fakeParser struct {
+	mu     sync.Mutex
+	sounds map[string][]storage.Sound
+	calls  int
+}
+
+func (f *fakeParser) FetchTrendingSounds(category string) ([]storage.Sound, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	// Callers modify the returned sounds, so hand out a copy
+	return append([]storage.Sound(nil), f.sounds[category]...), nil
+}
+
+func (f *fakeParser) Close() error {
+	return nil
+}
+
+// newTestScheduler builds a scheduler over in-memory storage and a fake Telegram API.
+// env overrides configuration defaults.
+func newTestScheduler(t *testing.T, env map[string]string) (*Scheduler, *storage.SQLiteStorage, *fakeAPI) {
+	t.Helper()
+	return newTestSchedulerAt(t, env, storage.MemoryPath)
+}
+
+// newTestSchedulerAt is newTestScheduler with the database at dbPath. Tests that alert users in
+// parallel use a file, since the shared in-memory cache fails concurrent writes instead of waiting.
+func newTestSchedulerAt(t *testing.T, env map[string]string, dbPath string) (*Scheduler, *storage.SQLiteStorage, *fakeAPI) {
+	t.Helper()
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load() error: %v", err)
+	}
+
+	s, err := storage.NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() error: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if err := s.Init(); err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+
+	d := detector.New(s)
+	api := &fakeAPI{}
+	b, err := bot.NewWithAPI(cfg, s, d, api)
+	if err != nil {
+		t.Fatalf("NewWithAPI() error: %v", err)
+	}
+	return New(cfg, nil, s, d, b), s, api
+}
+
+// seedTrending saves a sound in category that grew from 1,000 to 5,000 uses since 23 hours ago
+func seedTrending(t *testing.T, s *storage.SQLiteStorage, category, title string) *storage.Sound {
+	t.Helper()
+	now := time.Now()
+	sound := &storage.Sound{
+		Title:     title,
+		Author:    "author",
+		URL:       "https://www.tiktok.com/music/" + strings.ReplaceAll(title, " ", "-"),
+		UsesCount: 1000,
+		Category:  category,
+		CreatedAt: now.Add(-23 * time.Hour),
+		UpdatedAt: now.Add(-23 * time.Hour),
+	}
+	if err := s.SaveSound(sound); err != nil {
+		t.Fatalf("SaveSound() error: %v", err)
+	}
+	if _, err := s.SeedBaselineHistory(now.Add(-23 * time.Hour)); err != nil {
+		t.Fatalf("SeedBaselineHistory() error: %v", err)
+	}
+
+	sound.UsesCount = 5000
+	sound.UpdatedAt = now
+	if err := s.UpdateSound(sound); err != nil {
+		t.Fatalf("UpdateSound() error: %v", err)
+	}
+	if err := s.SaveSoundHistory(sound.ID, sound.UsesCount, ""); err != nil {
+		t.Fatalf("SaveSoundHistory() error: %v", err)
+	}
+	return sound
+}
+
+// addUser registers a user following the given niches
+func addUser(t *testing.T, s *storage.SQLiteStorage, telegramID int64, niches ...string) {
+	t.Helper()
+	if err := s.CreateUser(telegramID); err != nil {
+		t.Fatalf("CreateUser() error: %v", err)
+	}
+	if err := s.UpdateUserNiches(telegramID, bot.SetUserNiches(niches)); err != nil {
+		t.Fatalf("UpdateUserNiches() error: %v", err)
+	}
+}
+
+func TestSendAlertsPauseGating(t *testing.T) {
+	tests := []struct {
+		name     string
+		paused   string
+		wantSent bool
+	}{
+		{name: "never set", paused: "", wantSent: true},
+		{name: "paused", paused: "true", wantSent: false},
+		{name: "resumed", paused: "false", wantSent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sched, s, api := newTestScheduler(t, nil)
+			seedTrending(t, s, "fitness", "gym anthem")
+			addUser(t, s, 1, "fitness")
+			if tt.paused != "" {
+				if err := s.SetSetting(storage.SettingAlertsPaused, tt.paused); err != nil {
+					t.Fatalf("SetSetting() error: %v", err)
+				}
+			}
+
+			sched.SendAlerts()
+
+			if got := len(api.messagesTo(1)) > 0; got != tt.wantSent {
+				t.Errorf("alert sent = %v, want %v", got, tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestSendAlertsMinUsersGating(t *testing.T) {
+	tests := []struct {
+		name     string
+		minUsers string
+		users    int
+		wantSent bool
+	}{
+		{name: "disabled", minUsers: "0", users: 1, wantSent: true},
+		{name: "below threshold", minUsers: "3", users: 2, wantSent: false},
+		{name: "at threshold", minUsers: "3", users: 3, wantSent: true},
+		{name: "above threshold", minUsers: "3", users: 4, wantSent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sched, s, api := newTestScheduler(t, map[string]string{"MIN_USERS_FOR_ALERTS": tt.minUsers})
+			seedTrending(t, s, "fitness", "gym anthem")
+			for id := 1; id <= tt.users; id++ {
+				addUser(t, s, int64(id), "fitness")
+			}
+
+			sched.SendAlerts()
+
+			if got := api.count() > 0; got != tt.wantSent {
+				t.Errorf("alerts sent = %v, want %v", got, tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestBuildCategorySchedules(t *testing.T) {
+	const def = "0 */3 * * *"
+	categories := []string{"fitness", "gaming", "dance"}
+
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		want      map[string]string
+	}{
+		{
+			name:      "no overrides",
+			overrides: nil,
+			want:      map[string]string{"fitness": def, "gaming": def, "dance": def},
+		},
+		{
+			name:      "override one category",
+			overrides: map[string]string{"gaming": "0 * * * *"},
+			want:      map[string]string{"fitness": def, "gaming": "0 * * * *", "dance": def},
+		},
+		{
+			name:      "unknown category ignored",
+			overrides: map[string]string{"knitting": "0 * * * *"},
+			want:      map[string]string{"fitness": def, "gaming": def, "dance": def},
+		},
+		{
+			name:      "invalid spec falls back to the default",
+			overrides: map[string]string{"gaming": "every hour", "dance": "*/30 * * * *"},
+			want:      map[string]string{"fitness": def, "gaming": def, "dance": "*/30 * * * *"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildCategorySchedules(categories, tt.overrides, def)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildCategorySchedules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckMilestones(t *testing.T) {
+	tests := []struct {
+		name          string
+		lastMilestone int64
+		category      string  // category collected, fitness if empty
+		uses          []int64 // uses count before each check
+		wantAlerts    int
+	}{
+		{name: "no milestone yet", uses: []int64{9000}, wantAlerts: 0},
+		{name: "crossed once", uses: []int64{12000}, wantAlerts: 1},
+		{name: "no duplicate on the next run", uses: []int64{12000, 13000}, wantAlerts: 1},
+		{name: "each new milestone", uses: []int64{12000, 60000, 150000}, wantAlerts: 3},
+		{name: "milestone reached before following", lastMilestone: 10000, uses: []int64{20000}, wantAlerts: 0},
+		{name: "other category collected", category: "gaming", uses: []int64{12000}, wantAlerts: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sch, s, api := newTestScheduler(t, nil)
+			const telegramID = 5
+			addUser(t, s, telegramID, "fitness")
+			sound := seedTrending(t, s, "fitness", "followed")
+			if err := s.FollowSound(telegramID, sound.ID, tt.lastMilestone); err != nil {
+				t.Fatalf("FollowSound() error: %v", err)
+			}
+
+			category := tt.category
+			if category == "" {
+				category = "fitness"
+			}
+
+			for _, uses := range tt.uses {
+				sound.UsesCount = uses
+				if err := s.UpdateSound(sound); err != nil {
+					t.Fatalf("UpdateSound() error: %v", err)
+				}
+				sch.CheckMilestones(category)
+			}
+
+			if got := len(api.messagesTo(telegramID)); got != tt.wantAlerts {
+				t.Errorf("sent %d milestone alerts, want %d", got, tt.wantAlerts)
+			}
+		})
+	}
+}
+
+func TestCollectionCompletedChecksMilestones(t *testing.T) {
+	sch, s, api := newTestScheduler(t, nil)
+	const telegramID = 5
+	addUser(t, s, telegramID, "gaming")
+	sound := seedTrending(t, s, "fitness", "followed")
+	if err := s.FollowSound(telegramID, sound.ID, 0); err != nil {
+		t.Fatalf("FollowSound() error: %v", err)
+	}
+	sound.UsesCount = 12000
+	if err := s.UpdateSound(sound); err != nil {
+		t.Fatalf("UpdateSound() error: %v", err)
+	}
+
+	sch.subscribe()
+	sch.Bus().Publish(eventbus.Event{Topic: eventbus.TopicCollectionCompleted, Category: "fitness"})
+	// Closing the bus waits for subscribers to handle the event
+	sch.Bus().Close()
+
+	texts := api.messagesTo(telegramID)
+	if len(texts) != 1 || !strings.Contains(texts[0], "followed") {
+		t.Errorf("messages = %q, want one milestone alert for the followed sound", texts)
+	}
+}
+
+func TestNormalizeCategory(t *testing.T) {
+	tests := []struct {
+		name     string
+		category string
+		want     string
+	}{
+		{name: "empty defaults to collected category", category: "", want: "fitness"},
+		{name: "blank defaults to collected category", category: "  ", want: "fitness"},
+		{name: "set category is kept", category: "gaming", want: "gaming"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sound := storage.Sound{Title: "x", Category: tt.category}
+			normalizeCategory(&sound, "fitness")
+			if sound.Category != tt.want {
+				t.Errorf("Category = %q, want %q", sound.Category, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendAlertsDailyCap(t *testing.T) {
+	// Days are counted in UTC unless the user set a timezone; UTC+14:00 is a day ahead from 10:00 UTC on
+	today := time.Now().UTC().Format(dayLayout)
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format(dayLayout)
+	kiritimati := time.FixedZone("UTC+14:00", 14*3600)
+	zoneToday := time.Now().In(kiritimati).Format(dayLayout)
+	zoneYesterday := time.Now().In(kiritimati).AddDate(0, 0, -1).Format(dayLayout)
+
+	tests := []struct {
+		name      string
+		cap       string
+		timezone  string
+		sentOn    string // day of earlier alerts
+		sentEarly int    // alerts already sent on sentOn
+		want      int
+	}{
+		{name: "disabled", cap: "0", want: 3},
+		{name: "capped", cap: "2", want: 2},
+		{name: "cap already reached today", cap: "2", sentOn: today, sentEarly: 2, want: 0},
+		{name: "partly used today", cap: "2", sentOn: today, sentEarly: 1, want: 1},
+		{name: "resets the next day", cap: "2", sentOn: yesterday, sentEarly: 2, want: 2},
+		{name: "reached today in the user's timezone", cap: "2", timezone: "UTC+14:00", sentOn: zoneToday, sentEarly: 2, want: 0},
+		{name: "resets at the user's midnight", cap: "2", timezone: "UTC+14:00", sentOn: zoneYesterday, sentEarly: 2, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sched, s, api := newTestScheduler(t, map[string]string{
+				"MAX_ALERTS_PER_DAY":     tt.cap,
+				"ALERT_MESSAGE_INTERVAL": "1ms",
+			})
+			niches := []string{"fitness", "gaming", "comedy"}
+			for _, niche := range niches {
+				seedTrending(t, s, niche, niche+" anthem")
+			}
+			addUser(t, s, 1, niches...)
+			if err := s.SetUserTimezone(1, tt.timezone); err != nil {
+				t.Fatalf("SetUserTimezone() error: %v", err)
+			}
+			for i := 0; i < tt.sentEarly; i++ {
+				if err := s.IncrementDailyAlerts(1, tt.sentOn); err != nil {
+					t.Fatalf("IncrementDailyAlerts() error: %v", err)
+				}
+			}
+
+			sched.SendAlerts()
+
+			if got := len(api.messagesTo(1)); got != tt.want {
+				t.Errorf("sent %d alerts, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlertsSentOn(t *testing.T) {
+	user := &storage.User{AlertsDay: "2024-05-01", AlertsToday: 3}
+	if got := alertsSentOn(user, "2024-05-01"); got != 3 {
+		t.Errorf("alertsSentOn(same day) = %d, want 3", got)
+	}
+	if got := alertsSentOn(user, "2024-05-02"); got != 0 {
+		t.Errorf("alertsSentOn(next day) = %d, want 0", got)
+	}
+}
+
+func TestAlertDay(t *testing.T) {
+	// 22:30 UTC is already the next day east of UTC+1:30 and still the same day to the west
+	at := time.Date(2024, 5, 1, 22, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		timezone string
+		want     string
+	}{
+		{timezone: "", want: "2024-05-01"},
+		{timezone: "UTC", want: "2024-05-01"},
+		{timezone: "UTC+03:00", want: "2024-05-02"},
+		{timezone: "UTC-05:00", want: "2024-05-01"},
+		{timezone: "Asia/Tokyo", want: "2024-05-02"},
+		{timezone: "America/Los_Angeles", want: "2024-05-01"},
+		{timezone: "Not/AZone", want: "2024-05-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.timezone, func(t *testing.T) {
+			user := &storage.User{TelegramID: 1, Timezone: tt.timezone}
+			if got := alertDay(user, at); got != tt.want {
+				t.Errorf("alertDay(%q) = %q, want %q", tt.timezone, got, tt.want)
+			}
+		})
+	}
+
+	// The server's own zone doesn't matter: the same instant seen from another zone gives the same day
+	if got := alertDay(&storage.User{Timezone: "Asia/Tokyo"}, at.In(time.FixedZone("server", -10*3600))); got != "2024-05-02" {
+		t.Errorf("alertDay() from a UTC-10 server = %q, want 2024-05-02", got)
+	}
+}
+
+func TestShouldBackfill(t *testing.T) {
+	tests := []struct {
+		name       string
+		count      string
+		hasHistory bool
+		want       bool
+	}{
+		{name: "disabled", count: "0", want: false},
+		{name: "empty database", count: "3", want: true},
+		{name: "existing history", count: "3", hasHistory: true, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sch, s, _ := newTestScheduler(t, map[string]string{"BACKFILL_COLLECTIONS": tt.count})
+			if tt.hasHistory {
+				seedTrending(t, s, "fitness", "gym anthem")
+			}
+
+			if got := sch.shouldBackfill(); got != tt.want {
+				t.Errorf("shouldBackfill() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInitialCollection(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		wantCalls int
+	}{
+		{name: "collects once", env: map[string]string{"BASELINE_BACKDATE": "24h"}, wantCalls: 1},
+		{name: "backfills an empty database", env: map[string]string{"BASELINE_BACKDATE": "24h", "BACKFILL_COLLECTIONS": "2", "BACKFILL_INTERVAL": "1ms"}, wantCalls: 2},
+		{name: "disabled", env: map[string]string{"BASELINE_BACKDATE": "24h", "RUN_INITIAL_COLLECTION": "false", "BACKFILL_COLLECTIONS": "2"}, wantCalls: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sch, s, _ := newTestScheduler(t, tt.env)
+			p := &fakeParser{sounds: map[string][]storage.Sound{
+				"fitness": {{Title: "gym anthem", URL: "https://www.tiktok.com/music/gym-anthem-1", UsesCount: 1000}},
+			}}
+			sch.parser = p
+
+			// Only fitness is left on the global collection, so the test fetches a single category per run
+			sch.cfg.CategoryCrons = make(map[string]string)
+			for _, category := range parser.Categories {
+				if category != "fitness" {
+					sch.cfg.CategoryCrons[category] = "0 0 1 1 *"
+				}
+			}
+
+			// A sound without history is seeded even when the collection is disabled
+			if err := s.SaveSound(&storage.Sound{Title: "imported", URL: "https://www.tiktok.com/music/imported", UsesCount: 800, Category: "dance"}); err != nil {
+				t.Fatalf("SaveSound() error: %v", err)
+			}
+
+			sch.initialCollection()
+
+			if p.calls != tt.wantCalls {
+				t.Errorf("parser called %d times, want %d", p.calls, tt.wantCalls)
+			}
+			hasHistory, err := s.HasSoundHistory()
+			if err != nil {
+				t.Fatalf("HasSoundHistory() error: %v", err)
+			}
+			if !hasHistory {
+				t.Error("no history after the initial collection, want the baseline seeded")
+			}
+		})
+	}
+}
+
+func TestAlertSounds(t *testing.T) {
+
+	tests := []struct {
+		mode string
+		want []string
+	}{
+		{mode: storage.AlertModeGrowth, want: []string{"grower"}},
+		{mode: storage.AlertModePopularity, want: []string{"evergreen", "grower"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			sch, s, _ := newTestScheduler(t, nil)
+			evergreen := &storage.Sound{Title: "evergreen", URL: "https://www.tiktok.com/music/evergreen", UsesCount: 100000, Category: "fitness"}
+			if err := s.SaveSound(evergreen); err != nil {
+				t.Fatalf("SaveSound() error: %v", err)
+			}
+			seedTrending(t, s, "fitness", "grower")
+			addUser(t, s, 1, "fitness")
+			if err := s.SetAlertMode(1, tt.mode); err != nil {
+				t.Fatalf("SetAlertMode() error: %v", err)
+			}
+			user, err := s.GetUser(1)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+
+			got, err := sch.alertSounds(user, "fitness")
+			if err != nil {
+				t.Fatalf("alertSounds() error: %v", err)
+			}
+			var titles []string
+			for i, ts := range got {
+				titles = append(titles, ts.Title)
+				if tt.mode == storage.AlertModePopularity && ts.Rank != i+1 {
+					t.Errorf("%q rank = %d, want %d", ts.Title, ts.Rank, i+1)
+				}
+			}
+			if strings.Join(titles, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("alertSounds() = %v, want %v", titles, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlertSoundsThreshold(t *testing.T) {
+	// The seeded sound grew by 400%
+	tests := []struct {
+		name      string
+		global    float64
+		override  float64
+		wantFound bool
+	}{
+		{name: "default", wantFound: true},
+		{name: "global above growth", global: 500, wantFound: false},
+		{name: "global below growth", global: 300, wantFound: true},
+		{name: "niche override wins over global", global: 500, override: 200, wantFound: true},
+		{name: "niche override above growth", global: 100, override: 450, wantFound: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sch, s, _ := newTestScheduler(t, nil)
+			seedTrending(t, s, "fitness", "grower")
+			addUser(t, s, 1, "fitness")
+			if err := s.SetUserMinGrowth(1, tt.global); err != nil {
+				t.Fatalf("SetUserMinGrowth() error: %v", err)
+			}
+			if err := s.SetNicheMinGrowth(1, "fitness", tt.override); err != nil {
+				t.Fatalf("SetNicheMinGrowth() error: %v", err)
+			}
+			user, err := s.GetUser(1)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+
+			got, err := sch.alertSounds(user, "fitness")
+			if err != nil {
+				t.Fatalf("alertSounds() error: %v", err)
+			}
+			if found := len(got) == 1; found != tt.wantFound {
+				t.Errorf("got %d sounds, want found = %v", len(got), tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestAlertSoundsMinUses(t *testing.T) {
+	// The seeded sound has 5,000 uses
+	tests := []struct {
+		name      string
+		minUses   int64
+		wantFound bool
+	}{
+		{name: "default", wantFound: true},
+		{name: "floor below uses", minUses: 4000, wantFound: true},
+		{name: "floor above uses", minUses: 6000, wantFound: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sch, s, _ := newTestScheduler(t, nil)
+			seedTrending(t, s, "fitness", "grower")
+			addUser(t, s, 1, "fitness")
+			if err := s.SetUserMinUses(1, tt.minUses); err != nil {
+				t.Fatalf("SetUserMinUses() error: %v", err)
+			}
+			user, err := s.GetUser(1)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+
+			got, err := sch.alertSounds(user, "fitness")
+			if err != nil {
+				t.Fatalf("alertSounds() error: %v", err)
+			}
+			if found := len(got) == 1; found != tt.wantFound {
+				t.Errorf("got %d sounds, want found = %v", len(got), tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestAlertSoundsRegion(t *testing.T) {
+	tests := []struct {
+		name   string
+		region string
+		mode   string
+		want   []string
+	}{
+		{name: "global", region: "global", want: []string{"gym anthem", "us hit"}},
+		{name: "own region", region: "US", want: []string{"us hit"}},
+		{name: "region without trends", region: "GB"},
+		{name: "popularity mode is regionless", region: "GB", mode: storage.AlertModePopularity, want: []string{"gym anthem", "us hit"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sch, s, _ := newTestScheduler(t, nil)
+			seedTrending(t, s, "fitness", "gym anthem")
+			us := seedTrending(t, s, "fitness", "us hit")
+			if err := s.RecordSoundRegion(us.ID, "US"); err != nil {
+				t.Fatalf("RecordSoundRegion() error: %v", err)
+			}
+			addUser(t, s, 1, "fitness")
+			if err := s.SetUserRegion(1, tt.region); err != nil {
+				t.Fatalf("SetUserRegion() error: %v", err)
+			}
+			if tt.mode != "" {
+				if err := s.SetAlertMode(1, tt.mode); err != nil {
+					t.Fatalf("SetAlertMode() error: %v", err)
+				}
+			}
+			user, err := s.GetUser(1)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+
+			sounds, err := sch.alertSounds(user, "fitness")
+			if err != nil {
+				t.Fatalf("alertSounds() error: %v", err)
+			}
+			var got []string
+			for _, sound := range sounds {
+				got = append(got, sound.Title)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("alertSounds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlertSoundsBoost(t *testing.T) {
+	// The seeded sound grew by 400%, below the user's 600% threshold unless boosted
+	tests := []struct {
+		name       string
+		boostUntil time.Time
+		wantFound  bool
+	}{
+		{name: "no boost", wantFound: false},
+		{name: "boost active", boostUntil: time.Now().Add(time.Hour), wantFound: true},
+		{name: "boost ended", boostUntil: time.Now().Add(-time.Minute), wantFound: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sch, s, _ := newTestScheduler(t, nil)
+			seedTrending(t, s, "fitness", "grower")
+			addUser(t, s, 1, "fitness")
+			if err := s.SetUserMinGrowth(1, 600); err != nil {
+				t.Fatalf("SetUserMinGrowth() error: %v", err)
+			}
+			if err := s.SetBoostUntil(1, tt.boostUntil); err != nil {
+				t.Fatalf("SetBoostUntil() error: %v", err)
+			}
+			user, err := s.GetUser(1)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+
+			got, err := sch.alertSounds(user, "fitness")
+			if err != nil {
+				t.Fatalf("alertSounds() error: %v", err)
+			}
+			if found := len(got) == 1; found != tt.wantFound {
+				t.Errorf("got %d sounds, want found = %v", len(got), tt.wantFound)
+			}
+		})
+	}
+}
+
+// regionalParser is a fakeParser that also serves regional lists, keyed "category@region"
+type regionalParser struct {
+	fakeParser
+}
+
+func (f *regionalParser) FetchRegionalSounds(category, region string) ([]storage.Sound, error) {
+	return f.FetchTrendingSounds(category + "@" + region)
+}
+
+func TestCollectionKey(t *testing.T) {
+	tests := []struct {
+		region string
+		want   string
+	}{
+		{region: "global", want: "fitness"},
+		{region: "", want: "fitness"},
+		{region: "US", want: "fitness@US"},
+	}
+
+	for _, tt := range tests {
+		if got := collectionKey("fitness", tt.region); got != tt.want {
+			t.Errorf("collectionKey(fitness, %q) = %q, want %q", tt.region, got, tt.want)
+		}
+	}
+}
+
+func TestCollectCategoryRegions(t *testing.T) {
+	sounds := map[string][]storage.Sound{
+		"fitness":    {{Title: "gym anthem", URL: "https://www.tiktok.com/music/gym-anthem-1", UsesCount: 1000}},
+		"fitness@US": {{Title: "us hit", URL: "https://www.tiktok.com/music/us-hit-2", UsesCount: 800}},
+	}
+
+	tests := []struct {
+		name      string
+		parser    parser.Parser
+		wantSaved bool
+		wantUS    []string
+	}{
+		{name: "every region", parser: &regionalParser{fakeParser{sounds: sounds}}, wantSaved: true, wantUS: []string{"https://www.tiktok.com/music/us-hit-2"}},
+		{name: "parser without regions keeps the global list", parser: &fakeParser{sounds: sounds}, wantSaved: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sch, s, _ := newTestScheduler(t, map[string]string{"REGIONS": "global,US"})
+			sch.parser = tt.parser
+
+			saved, err := sch.collectCategory("fitness")
+			if err != nil {
+				t.Fatalf("collectCategory() error: %v", err)
+			}
+			if saved != tt.wantSaved {
+				t.Errorf("collectCategory() saved = %v, want %v", saved, tt.wantSaved)
+			}
+
+			if _, err := s.GetSoundByURL("https://www.tiktok.com/music/gym-anthem-1"); err != nil {
+				t.Errorf("global sound not saved: %v", err)
+			}
+			ids, err := s.GetRegionSoundIDs("US", time.Now().Add(-time.Hour))
+			if err != nil {
+				t.Fatalf("GetRegionSoundIDs() error: %v", err)
+			}
+			var gotUS []string
+			for id := range ids {
+				sound, err := s.GetSoundByID(id)
+				if err != nil {
+					t.Fatalf("GetSoundByID() error: %v", err)
+				}
+				gotUS = append(gotUS, sound.URL)
+			}
+			if !reflect.DeepEqual(gotUS, tt.wantUS) {
+				t.Errorf("US sounds = %v, want %v", gotUS, tt.wantUS)
+			}
+
+			// An unchanged second collection is stale for every region
+			if saved, err := sch.collectCategory("fitness"); err != nil || saved {
+				t.Errorf("repeat collectCategory() = %v, %v, want nothing saved", saved, err)
+			}
+		})
+	}
+
+	// Failing every region is an error
+	sch, _, _ := newTestScheduler(t, map[string]string{"REGIONS": "US"})
+	sch.parser = &fakeParser{sounds: sounds}
+	if _, err := sch.collectCategory("fitness"); !errors.Is(err, parser.ErrRegionUnsupported) {
+		t.Errorf("collectCategory() error = %v, want %v", err, parser.ErrRegionUnsupported)
+	}
+}
+
+func TestCollectionHash(t *testing.T) {
+	a := storage.Sound{URL: "https://www.tiktok.com/music/a-1", UsesCount: 100}
+	b := storage.Sound{URL: "https://www.tiktok.com/music/b-2", UsesCount: 200}
+	base := collectionHash([]storage.Sound{a, b})
+
+	tests := []struct {
+		name   string
+		sounds []storage.Sound
+		same   bool
+	}{
+		{name: "same order", sounds: []storage.Sound{a, b}, same: true},
+		{name: "reordered", sounds: []storage.Sound{b, a}, same: true},
+		{name: "url variant", sounds: []storage.Sound{{URL: "https://www.tiktok.com/music/a-1?lang=en", UsesCount: 100}, b}, same: true},
+		{name: "uses changed", sounds: []storage.Sound{{URL: a.URL, UsesCount: 101}, b}, same: false},
+		{name: "sound missing", sounds: []storage.Sound{a}, same: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same := collectionHash(tt.sounds) == base; same != tt.same {
+				t.Errorf("hash matches = %v, want %v", same, tt.same)
+			}
+		})
+	}
+}
+
+func TestManualCollectSkipsStaleCollection(t *testing.T) {
+	sch, s, _ := newTestScheduler(t, nil)
+	p := &fakeParser{sounds: map[string][]storage.Sound{
+		"fitness": {{Title: "gym anthem", URL: "https://www.tiktok.com/music/gym-anthem-1", UsesCount: 1000}},
+	}}
+	sch.parser = p
+
+	steps := []struct {
+		uses        int64
+		wantHistory int
+	}{
+		{uses: 1000, wantHistory: 1},
+		{uses: 1000, wantHistory: 1}, // identical to the previous cycle, skipped
+		{uses: 1500, wantHistory: 2},
+	}
+	for i, step := range steps {
+		p.sounds["fitness"][0].UsesCount = step.uses
+		if err := sch.ManualCollect("fitness"); err != nil {
+			t.Fatalf("ManualCollect() error: %v", err)
+		}
+
+		sound, err := s.GetSoundByURL("https://www.tiktok.com/music/gym-anthem-1")
+		if err != nil {
+			t.Fatalf("GetSoundByURL() error: %v", err)
+		}
+		history, err := s.GetSoundHistoryRange(sound.ID, time.Now().Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("GetSoundHistoryRange() error: %v", err)
+		}
+		if len(history) != step.wantHistory {
+			t.Errorf("step %d: %d history rows, want %d", i, len(history), step.wantHistory)
+		}
+	}
+}
+
+func TestAlertSoundsBetaCrossNiche(t *testing.T) {
+	tests := []struct {
+		name     string
+		beta     bool
+		flagOn   bool
+		wantFlag bool
+	}{
+		{name: "regular user", beta: false, wantFlag: false},
+		{name: "beta user", beta: true, wantFlag: true},
+		{name: "regular user with the flag on", beta: false, flagOn: true, wantFlag: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sch, s, _ := newTestScheduler(t, nil)
+			// The same sound collected in a second niche
+			shared := seedTrending(t, s, "fitness", "shared")
+			for _, category := range []string{"fitness", "lifestyle"} {
+				seen := &storage.Sound{Title: shared.Title, URL: shared.URL, UsesCount: shared.UsesCount, Category: category}
+				if err := storage.SaveSoundWithHistory(s, seen, storage.DedupByURL); err != nil {
+					t.Fatalf("SaveSoundWithHistory() error: %v", err)
+				}
+			}
+			addUser(t, s, 1, "lifestyle")
+			if err := s.SetBetaEnrolled(1, tt.beta); err != nil {
+				t.Fatalf("SetBetaEnrolled() error: %v", err)
+			}
+			if err := s.SetFeature(bot.FeatureCrossNiche, tt.flagOn); err != nil {
+				t.Fatalf("SetFeature() error: %v", err)
+			}
+			user, err := s.GetUser(1)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+
+			got, err := sch.alertSounds(user, "lifestyle")
+			if err != nil {
+				t.Fatalf("alertSounds() error: %v", err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("got %d sounds, want 1", len(got))
+			}
+			if got[0].CrossNiche != tt.wantFlag {
+				t.Errorf("CrossNiche = %v, want %v", got[0].CrossNiche, tt.wantFlag)
+			}
+		})
+	}
+}
+
+func TestSendAlertsRecordsLatency(t *testing.T) {
+	sched, s, _ := newTestScheduler(t, map[string]string{"ALERT_MESSAGE_INTERVAL": "1ms"})
+	seedTrending(t, s, "fitness", "gym anthem")
+	seedTrending(t, s, "gaming", "boss fight")
+	addUser(t, s, 1, "fitness", "gaming")
+	addUser(t, s, 2, "fitness")
+
+	sched.SendAlerts()
+
+	latencies, err := s.GetAlertLatencies(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetAlertLatencies() error: %v", err)
+	}
+	if len(latencies) != 3 {
+		t.Errorf("recorded %d latencies, want one per delivered alert (3)", len(latencies))
+	}
+	for _, latency := range latencies {
+		if latency < 0 || latency > time.Minute {
+			t.Errorf("latency %s out of range", latency)
+		}
+	}
+}
+
+// fetchingParser is a fakeParser that can also fetch single sounds by URL
+type fetchingParser struct {
+	fakeParser
+	byURL map[string]storage.Sound
+}
+
+func (f *fetchingParser) FetchSound(url, category string) (*storage.Sound, error) {
+	sound, ok := f.byURL[url]
+	if !ok {
+		return nil, fmt.Errorf("no sound at %s", url)
+	}
+	sound.Category = category
+	return &sound, nil
+}
+
+func TestCollectNominations(t *testing.T) {
+	const (
+		fetchable = "https://www.tiktok.com/music/fetchable-1"
+		collected = "https://www.tiktok.com/music/collected-2"
+		missing   = "https://www.tiktok.com/music/missing-3"
+	)
+
+	tests := []struct {
+		name        string
+		canFetch    bool
+		wantPending []string
+	}{
+		{name: "parser fetches sounds", canFetch: true, wantPending: []string{missing}},
+		{name: "parser without single fetches", canFetch: false, wantPending: []string{fetchable, missing}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sch, s, _ := newTestScheduler(t, nil)
+			if tt.canFetch {
+				sch.parser = &fetchingParser{byURL: map[string]storage.Sound{
+					fetchable: {Title: "fetchable", URL: fetchable, UsesCount: 100},
+				}}
+			} else {
+				sch.parser = &fakeParser{}
+			}
+
+			// Regular collection already stored one of the nominated sounds
+			if err := storage.SaveSoundWithHistory(s, &storage.Sound{Title: "collected", URL: collected, UsesCount: 500, Category: "fitness"}, storage.DedupByURL); err != nil {
+				t.Fatalf("SaveSoundWithHistory() error: %v", err)
+			}
+			for _, url := range []string{fetchable, collected, missing} {
+				if err := s.SaveNomination(1, url, "fitness"); err != nil {
+					t.Fatalf("SaveNomination() error: %v", err)
+				}
+			}
+
+			sch.CollectNominations()
+
+			pending, err := s.GetPendingNominations()
+			if err != nil {
+				t.Fatalf("GetPendingNominations() error: %v", err)
+			}
+			var got []string
+			for _, n := range pending {
+				got = append(got, n.URL)
+			}
+			if !reflect.DeepEqual(got, tt.wantPending) {
+				t.Errorf("pending = %v, want %v", got, tt.wantPending)
+			}
+			if tt.canFetch {
+				if sound, err := s.GetSoundByURL(fetchable); err != nil || sound.Category != "fitness" {
+					t.Errorf("GetSoundByURL(fetchable) = %+v, %v, want the stored fitness sound", sound, err)
+				}
+			}
+		})
+	}
+}
+
+func TestWithoutRepeats(t *testing.T) {
+	sounds := []storage.TrendingSound{{Sound: storage.Sound{ID: 1}}, {Sound: storage.Sound{ID: 2}}, {Sound: storage.Sound{ID: 3}}}
+
+	tests := []struct {
+		name    string
+		repeats map[int64]bool
+		want    []int64
+	}{
+		{name: "no repeats", repeats: nil, want: []int64{1, 2, 3}},
+		{name: "some repeats", repeats: map[int64]bool{2: true}, want: []int64{1, 3}},
+		{name: "all repeats", repeats: map[int64]bool{1: true, 2: true, 3: true}, want: []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := soundIDs(withoutRepeats(sounds, tt.repeats))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("withoutRepeats() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkipsRepeats(t *testing.T) {
+	tests := []struct {
+		name string
+		user storage.User
+		want bool
+	}{
+		{name: "free user", user: storage.User{}, want: true},
+		{name: "repeats need premium", user: storage.User{ShowRepeats: true}, want: true},
+		{name: "premium without opt-in", user: storage.User{IsPremium: true}, want: true},
+		{name: "premium opted in", user: storage.User{IsPremium: true, ShowRepeats: true}, want: false},
+		{name: "popularity mode", user: storage.User{AlertMode: storage.AlertModePopularity}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := skipsRepeats(&tt.user); got != tt.want {
+				t.Errorf("skipsRepeats() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendAlertsDedupWindow(t *testing.T) {
+	tests := []struct {
+		name        string
+		window      string
+		premium     bool
+		showRepeats bool
+		mode        string
+		wantSecond  bool // Whether the user is alerted again in the next cycle
+	}{
+		{name: "window disabled", window: "0", wantSecond: true},
+		{name: "repeat dropped", window: "6h", wantSecond: false},
+		{name: "premium opted into repeats", window: "6h", premium: true, showRepeats: true, wantSecond: true},
+		{name: "opt-in needs premium", window: "6h", showRepeats: true, wantSecond: false},
+		{name: "popularity mode ignores the window", window: "6h", mode: storage.AlertModePopularity, wantSecond: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sched, s, api := newTestScheduler(t, map[string]string{
+				"ALERT_DEDUP_WINDOW":     tt.window,
+				"ALERT_MESSAGE_INTERVAL": "1ms",
+			})
+			seedTrending(t, s, "fitness", "gym anthem")
+			// Two users in the same cycle both get a sound first flagged during it
+			for _, id := range []int64{1, 2} {
+				addUser(t, s, id, "fitness")
+			}
+			if err := s.SetPremium(1, tt.premium); err != nil {
+				t.Fatalf("SetPremium() error: %v", err)
+			}
+			if err := s.SetShowRepeats(1, tt.showRepeats); err != nil {
+				t.Fatalf("SetShowRepeats() error: %v", err)
+			}
+			if tt.mode != "" {
+				if err := s.SetAlertMode(1, tt.mode); err != nil {
+					t.Fatalf("SetAlertMode() error: %v", err)
+				}
+			}
+
+			sched.SendAlerts()
+			if len(api.messagesTo(1)) != 1 || len(api.messagesTo(2)) != 1 {
+				t.Fatalf("first cycle sent %d and %d alerts, want one each", len(api.messagesTo(1)), len(api.messagesTo(2)))
+			}
+
+			sched.SendAlerts()
+			if got := len(api.messagesTo(1)) == 2; got != tt.wantSecond {
+				t.Errorf("alerted again in the second cycle = %v, want %v", got, tt.wantSecond)
+			}
+		})
+	}
+}
+
+func TestRecipientPacer(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	type step struct {
+		at        time.Duration // Time since start when the message is due
+		recipient int64
+		wantSleep time.Duration
+	}
+	tests := []struct {
+		name     string
+		interval time.Duration
+		steps    []step
+	}{
+		{
+			name:     "first message is not delayed",
+			interval: time.Second,
+			steps:    []step{{at: 0, recipient: 1}},
+		},
+		{
+			name:     "same recipient waits out the gap",
+			interval: time.Second,
+			steps:    []step{{at: 0, recipient: 1}, {at: 300 * time.Millisecond, recipient: 1, wantSleep: 700 * time.Millisecond}},
+		},
+		{
+			name:     "other recipients are not held back",
+			interval: time.Second,
+			steps:    []step{{at: 0, recipient: 1}, {at: 0, recipient: 2}, {at: 0, recipient: 3}},
+		},
+		{
+			name:     "time already passed counts towards the gap",
+			interval: time.Second,
+			steps:    []step{{at: 0, recipient: 1}, {at: 2 * time.Second, recipient: 1}},
+		},
+		{
+			name:     "disabled",
+			interval: 0,
+			steps:    []step{{at: 0, recipient: 1}, {at: 0, recipient: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newRecipientPacer(tt.interval)
+			now := start
+			var slept time.Duration
+			p.now = func() time.Time { return now }
+			p.sleep = func(d time.Duration) {
+				slept = d
+				now = now.Add(d)
+			}
+
+			for i, s := range tt.steps {
+				if at := start.Add(s.at); at.After(now) {
+					now = at
+				}
+				slept = 0
+				p.wait(s.recipient)
+				p.sent(s.recipient)
+				if slept != s.wantSleep {
+					t.Errorf("step %d: slept %s, want %s", i, slept, s.wantSleep)
+				}
+			}
+		})
+	}
+}
+
+func TestSendAlertsPacesPerRecipient(t *testing.T) {
+	sched, s, api := newTestScheduler(t, map[string]string{"ALERT_MESSAGE_INTERVAL": "200ms", "ALERT_WORKERS": "1"})
+	seedTrending(t, s, "fitness", "gym anthem")
+	seedTrending(t, s, "gaming", "boss theme")
+	for id := int64(1); id <= 5; id++ {
+		addUser(t, s, id, "fitness")
+	}
+	addUser(t, s, 6, "fitness", "gaming")
+
+	start := time.Now()
+	sched.SendAlerts()
+	elapsed := time.Since(start)
+
+	// Only user 6's second alert waits; the five single-niche users don't add a gap each
+	if got := api.count(); got != 7 {
+		t.Errorf("sent %d alerts, want 7", got)
+	}
+	if elapsed < 150*time.Millisecond || elapsed > 600*time.Millisecond {
+		t.Errorf("SendAlerts() took %s, want about one 200ms gap", elapsed)
+	}
+}
+
+func TestSendAlertsClaims(t *testing.T) {
+	tests := []struct {
+		name     string
+		ttl      string
+		claimed  bool // Whether another instance claimed user 1 before the cycle
+		wantSent bool
+	}{
+		{name: "unclaimed", ttl: "10m", wantSent: true},
+		{name: "claimed by another instance", ttl: "10m", claimed: true, wantSent: false},
+		{name: "abandoned claim is taken over", ttl: "200ms", claimed: true, wantSent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sched, s, api := newTestScheduler(t, map[string]string{"ALERT_CLAIM_TTL": tt.ttl})
+			seedTrending(t, s, "fitness", "gym anthem")
+			addUser(t, s, 1, "fitness")
+			addUser(t, s, 2, "fitness")
+			if tt.claimed {
+				claimed, err := s.ClaimUsersForAlert(1, time.Minute, time.Now())
+				if err != nil || len(claimed) != 1 {
+					t.Fatalf("ClaimUsersForAlert() = %v, %v, want user 1", claimed, err)
+				}
+				// Let a short claim expire; the TTL still outlasts handling one user
+				time.Sleep(250 * time.Millisecond)
+			}
+
+			sched.SendAlerts()
+
+			if got := len(api.messagesTo(1)) > 0; got != tt.wantSent {
+				t.Errorf("user 1 alerted = %v, want %v", got, tt.wantSent)
+			}
+			if len(api.messagesTo(2)) != 1 {
+				t.Errorf("user 2 got %d alerts, want 1", len(api.messagesTo(2)))
+			}
+
+			// Claims this instance took are released; another instance's live claim stays. A round
+			// starting now may claim users released before it
+			free, err := s.ClaimUsersForAlert(10, time.Hour, time.Now())
+			if err != nil {
+				t.Fatalf("ClaimUsersForAlert() error: %v", err)
+			}
+			wantFree := 2
+			if tt.claimed && !tt.wantSent {
+				wantFree = 1
+			}
+			if len(free) != wantFree {
+				t.Errorf("%d users free after the cycle, want %d", len(free), wantFree)
+			}
+		})
+	}
+}
+
+func TestSendAlertsClaimsEveryBatch(t *testing.T) {
+	sched, s, api := newTestSchedulerAt(t, map[string]string{"ALERT_MESSAGE_INTERVAL": "1ms", "SEND_RATE_LIMIT": "0"}, filepath.Join(t.TempDir(), "bot.db"))
+	seedTrending(t, s, "fitness", "gym anthem")
+	// Users are claimed ALERT_WORKERS (4) at a time, so this takes several batches
+	users := 4*sched.cfg.AlertWorkers + 2
+	for id := 1; id <= users; id++ {
+		addUser(t, s, int64(id), "fitness")
+	}
+
+	sched.SendAlerts()
+	if got := api.count(); got != users {
+		t.Fatalf("first cycle sent %d alerts, want one to each of %d users", got, users)
+	}
+
+	// Released users are handed out again in the next cycle
+	sched.SendAlerts()
+	if got := api.count(); got != 2*users {
+		t.Errorf("two cycles sent %d alerts, want %d", got, 2*users)
+	}
+}
+
+func TestSendAlertsClaimOutlastsTTL(t *testing.T) {
+	// Four 50ms sends take longer than the 150ms claim TTL
+	tests := []struct {
+		name       string
+		takeOver   bool // Another instance takes user 1 over after the first alert
+		wantAlerts int
+	}{
+		{name: "claim renewed before each send", wantAlerts: 4},
+		{name: "taken over mid-cycle", takeOver: true, wantAlerts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sched, s, api := newTestSchedulerAt(t, map[string]string{
+				"ALERT_CLAIM_TTL":        "150ms",
+				"ALERT_WORKERS":          "1",
+				"ALERT_MESSAGE_INTERVAL": "1ms",
+				"SEND_RATE_LIMIT":        "0",
+			}, filepath.Join(t.TempDir(), "bot.db"))
+			niches := []string{"fitness", "gaming", "comedy", "tech"}
+			for _, niche := range niches {
+				seedTrending(t, s, niche, niche+" anthem")
+			}
+			addUser(t, s, 1, niches...)
+			api.delay = 50 * time.Millisecond
+			roundStart := time.Now()
+
+			var takenOver atomic.Bool
+			if tt.takeOver {
+				api.onSend = func(int64) {
+					if takenOver.Swap(true) {
+						return
+					}
+					// A zero TTL stands in for a claim that expired while this instance was busy
+					if claimed, err := s.ClaimUsersForAlert(1, 0, roundStart); err != nil || len(claimed) != 1 {
+						t.Errorf("take over = %v, %v, want user 1", claimed, err)
+					}
+				}
+			}
+
+			// Another instance keeps looking for abandoned claims throughout the cycle
+			stop := make(chan struct{})
+			polled := make(chan int)
+			go func() {
+				stolen := 0
+				for {
+					select {
+					case <-stop:
+						polled <- stolen
+						return
+					case <-time.After(10 * time.Millisecond):
+					}
+					if takenOver.Load() {
+						continue
+					}
+					claimed, err := s.ClaimUsersForAlert(1, 150*time.Millisecond, roundStart)
+					if err != nil {
+						t.Errorf("ClaimUsersForAlert() error: %v", err)
+					}
+					stolen += len(claimed)
+				}
+			}()
+
+			sched.SendAlerts()
+			close(stop)
+
+			if stolen := <-polled; stolen != 0 {
+				t.Errorf("another instance claimed the user %d times while this one was alerting them", stolen)
+			}
+			if got := len(api.messagesTo(1)); got != tt.wantAlerts {
+				t.Errorf("user 1 got %d alerts, want %d", got, tt.wantAlerts)
+			}
+
+			// A taken-over claim stays with the other instance instead of being released
+			free, err := s.ClaimUsersForAlert(10, time.Hour, time.Now())
+			if err != nil {
+				t.Fatalf("ClaimUsersForAlert() error: %v", err)
+			}
+			if wantFree := !tt.takeOver; (len(free) == 1) != wantFree {
+				t.Errorf("user free after the cycle = %v, want %v", len(free) == 1, wantFree)
+			}
+		})
+	}
+}
+
+func TestSendAlertsSkipsBlocked(t *testing.T) {
+	sched, s, api := newTestScheduler(t, nil)
+	seedTrending(t, s, "fitness", "gym anthem")
+	addUser(t, s, 1, "fitness")
+	addUser(t, s, 2, "fitness")
+	if err := s.SetBlocked(2, true); err != nil {
+		t.Fatalf("SetBlocked() error: %v", err)
+	}
+
+	sched.SendAlerts()
+
+	if len(api.messagesTo(1)) == 0 {
+		t.Error("no alert sent to the unblocked user")
+	}
+	if got := api.messagesTo(2); len(got) != 0 {
+		t.Errorf("blocked user got %q, want nothing", got)
+	}
+}
+
+func TestPruneSentAlerts(t *testing.T) {
+	// The record was flagged 3 days ago
+	tests := []struct {
+		name     string
+		env      map[string]string
+		wantKept bool
+	}{
+		{name: "default retention keeps it", wantKept: true},
+		{name: "past retention", env: map[string]string{"ALERT_DEDUP_RETENTION": "48h"}, wantKept: false},
+		{name: "retention never shorter than the dedup window", env: map[string]string{"ALERT_DEDUP_RETENTION": "48h", "ALERT_DEDUP_WINDOW": "96h"}, wantKept: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sch, s, _ := newTestScheduler(t, tt.env)
+			sound := seedTrending(t, s, "fitness", "gym anthem")
+			flagged := time.Now().Add(-72 * time.Hour)
+			if err := s.MarkSoundsAlerted([]int64{sound.ID}, flagged, flagged.Add(-time.Hour)); err != nil {
+				t.Fatalf("MarkSoundsAlerted() error: %v", err)
+			}
+
+			sch.PruneSentAlerts()
+
+			got, err := s.GetAlertedSoundsSince(flagged.Add(-time.Minute))
+			if err != nil {
+				t.Fatalf("GetAlertedSoundsSince() error: %v", err)
+			}
+			if got[sound.ID] != tt.wantKept {
+				t.Errorf("record kept = %v, want %v", got[sound.ID], tt.wantKept)
+			}
+		})
+	}
+}
+
+func TestSeedBaseline(t *testing.T) {
+	tests := []struct {
+		name        string
+		backdate    string
+		wantHistory bool
+	}{
+		{name: "disabled", backdate: "0s", wantHistory: false},
+		{name: "backdated", backdate: "48h", wantHistory: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sch, s, _ := newTestScheduler(t, map[string]string{"BASELINE_BACKDATE": tt.backdate})
+			sound := &storage.Sound{Title: "imported", URL: "https://www.tiktok.com/music/imported", UsesCount: 2000, Category: "fitness"}
+			if err := s.SaveSound(sound); err != nil {
+				t.Fatalf("SaveSound() error: %v", err)
+			}
+
+			sch.SeedBaseline()
+
+			history, err := s.GetSoundHistoryRange(sound.ID, time.Now().Add(-72*time.Hour))
+			if err != nil {
+				t.Fatalf("GetSoundHistoryRange() error: %v", err)
+			}
+			if got := len(history) == 1; got != tt.wantHistory {
+				t.Fatalf("history = %+v, want seeded %v", history, tt.wantHistory)
+			}
+			if tt.wantHistory {
+				if age := time.Since(history[0].RecordedAt); age < 47*time.Hour || age > 49*time.Hour {
+					t.Errorf("baseline recorded %s ago, want about 48h", age.Round(time.Minute))
+				}
+			}
+		})
+	}
+}
+
+func TestDigestDue(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		frequency string
+		last      time.Time
+		want      bool
+	}{
+		{name: "every cycle", frequency: storage.DigestEvery, want: false},
+		{name: "first digest", frequency: storage.DigestDaily, want: true},
+		{name: "daily too soon", frequency: storage.DigestDaily, last: now.Add(-18 * time.Hour), want: false},
+		{name: "daily due", frequency: storage.DigestDaily, last: now.Add(-24 * time.Hour), want: true},
+		{name: "daily within slack", frequency: storage.DigestDaily, last: now.Add(-24*time.Hour + 10*time.Minute), want: true},
+		{name: "twice daily due", frequency: storage.DigestTwiceDaily, last: now.Add(-12 * time.Hour), want: true},
+		{name: "twice daily too soon", frequency: storage.DigestTwiceDaily, last: now.Add(-6 * time.Hour), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &storage.User{DigestFrequency: tt.frequency, LastDigestAt: tt.last}
+			if got := digestDue(user, now); got != tt.want {
+				t.Errorf("digestDue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendAlertsDigest(t *testing.T) {
+	sch, s, api := newTestScheduler(t, map[string]string{"SEND_RATE_LIMIT": "0", "ALERT_MESSAGE_INTERVAL": "0s"})
+	seedTrending(t, s, "fitness", "gym anthem")
+	seedTrending(t, s, "gaming", "boss theme")
+	addUser(t, s, 1, "fitness", "gaming")
+	addUser(t, s, 2, "fitness", "gaming")
+	if err := s.SetDigestFrequency(1, storage.DigestDaily); err != nil {
+		t.Fatalf("SetDigestFrequency() error: %v", err)
+	}
+
+	// The first digest goes out right away, as one message with a section per niche
+	sch.SendAlerts()
+	digests := api.messagesTo(1)
+	if len(digests) != 1 || !strings.Contains(digests[0], "Your daily digest") ||
+		!strings.Contains(digests[0], "gym anthem") || !strings.Contains(digests[0], "boss theme") {
+		t.Fatalf("first cycle sent %q, want one digest with both niches", digests)
+	}
+	if got := len(api.messagesTo(2)); got != 2 {
+		t.Errorf("per-cycle user got %d messages, want one per niche", got)
+	}
+
+	// Before the next delivery is due, sounds are only queued
+	sch.SendAlerts()
+	if got := len(api.messagesTo(1)); got != 1 {
+		t.Fatalf("digest user has %d messages after a cycle that wasn't due, want 1", got)
+	}
+	queued, err := s.GetDigestSounds(1)
+	if err != nil {
+		t.Fatalf("GetDigestSounds() error: %v", err)
+	}
+	if len(queued["fitness"]) != 1 || len(queued["gaming"]) != 1 {
+		t.Fatalf("queued = %v, want one sound per niche", queued)
+	}
+
+	// A day later the queued and newly detected sounds go out once each
+	user, err := s.GetUser(1)
+	if err != nil {
+		t.Fatalf("GetUser() error: %v", err)
+	}
+	if err := s.MarkDigestSent(1, user.LastDigestAt.Add(-24*time.Hour)); err != nil {
+		t.Fatalf("MarkDigestSent() error: %v", err)
+	}
+	sch.SendAlerts()
+	digests = api.messagesTo(1)
+	if len(digests) != 2 {
+		t.Fatalf("digest user has %d messages, want a second digest", len(digests))
+	}
+	if n := strings.Count(digests[1], "gym anthem"); n != 1 {
+		t.Errorf("second digest lists gym anthem %d times, want once:\n%s", n, digests[1])
+	}
+	if queued, _ := s.GetDigestSounds(1); len(queued) != 0 {
+		t.Errorf("queue after delivery = %v, want empty", queued)
+	}
+}
+
+func TestSendAlertsRecordsHistory(t *testing.T) {
+	tests := []struct {
+		name      string
+		frequency string
+	}{
+		{name: "per-cycle alert", frequency: storage.DigestEvery},
+		{name: "digest", frequency: storage.DigestDaily},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sch, s, _ := newTestScheduler(t, map[string]string{"SEND_RATE_LIMIT": "0"})
+			sound := seedTrending(t, s, "fitness", "gym anthem")
+			addUser(t, s, 1, "fitness")
+			if err := s.SetDigestFrequency(1, tt.frequency); err != nil {
+				t.Fatalf("SetDigestFrequency() error: %v", err)
+			}
+
+			sch.SendAlerts()
+
+			history, err := s.GetUserAlertHistory(1, 10)
+			if err != nil {
+				t.Fatalf("GetUserAlertHistory() error: %v", err)
+			}
+			if len(history) != 1 || history[0].SoundID != sound.ID || history[0].Niche != "fitness" {
+				t.Errorf("history = %+v, want the alerted fitness sound", history)
+			}
+		})
+	}
+}
+
+func TestPostChannelTrends(t *testing.T) {
+	const channel = -100123
+	sch, s, api := newTestScheduler(t, map[string]string{"BROADCAST_CHANNEL": "-100123", "SEND_RATE_LIMIT": "0"})
+	addUser(t, s, 1, "fitness")
+	seedTrending(t, s, "fitness", "gym anthem")
+
+	steps := []struct {
+		name      string
+		seed      string
+		wantPosts int
+		want      string
+		notWant   string
+	}{
+		{name: "first post", wantPosts: 1, want: "gym anthem"},
+		{name: "nothing new", wantPosts: 1},
+		{name: "only the new sound", seed: "cardio beat", wantPosts: 2, want: "cardio beat", notWant: "gym anthem"},
+	}
+	for _, step := range steps {
+		if step.seed != "" {
+			seedTrending(t, s, "fitness", step.seed)
+		}
+
+		sch.PostChannelTrends("fitness")
+
+		posts := api.messagesTo(channel)
+		if len(posts) != step.wantPosts {
+			t.Fatalf("%s: %d channel posts, want %d", step.name, len(posts), step.wantPosts)
+		}
+		last := posts[len(posts)-1]
+		if step.want != "" && !strings.Contains(last, step.want) {
+			t.Errorf("%s: post missing %q in:\n%s", step.name, step.want, last)
+		}
+		if step.notWant != "" && strings.Contains(last, step.notWant) {
+			t.Errorf("%s: post repeats %q in:\n%s", step.name, step.notWant, last)
+		}
+	}
+
+	// Channel posts go to the channel only, not to subscribers
+	if got := api.messagesTo(1); len(got) != 0 {
+		t.Errorf("subscriber got %d messages, want none", len(got))
+	}
+}
+
+func TestSendInstantAlerts(t *testing.T) {
+	tests := []struct {
+		name      string
+		premium   bool
+		instant   bool
+		digest    string
+		paused    bool
+		category  string
+		wantFirst bool
+	}{
+		{name: "premium opted in", premium: true, instant: true, category: "fitness", wantFirst: true},
+		{name: "not opted in", premium: true, category: "fitness"},
+		{name: "instant needs premium", instant: true, category: "fitness"},
+		{name: "other niche", premium: true, instant: true, category: "gaming"},
+		{name: "digest users keep their schedule", premium: true, instant: true, digest: storage.DigestDaily, category: "fitness"},
+		{name: "alerts paused", premium: true, instant: true, paused: true, category: "fitness"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sched, s, api := newTestScheduler(t, map[string]string{"SEND_RATE_LIMIT": "0"})
+			seedTrending(t, s, "fitness", "gym anthem")
+			seedTrending(t, s, "gaming", "boss fight")
+			addUser(t, s, 1, "fitness")
+			if err := s.SetPremium(1, tt.premium); err != nil {
+				t.Fatalf("SetPremium() error: %v", err)
+			}
+			if err := s.SetInstantAlerts(1, tt.instant); err != nil {
+				t.Fatalf("SetInstantAlerts() error: %v", err)
+			}
+			if tt.digest != "" {
+				if err := s.SetDigestFrequency(1, tt.digest); err != nil {
+					t.Fatalf("SetDigestFrequency() error: %v", err)
+				}
+			}
+			if tt.paused {
+				if err := s.SetSetting(storage.SettingAlertsPaused, "true"); err != nil {
+					t.Fatalf("SetSetting() error: %v", err)
+				}
+			}
+
+			sched.SendInstantAlerts(tt.category)
+			if got := len(api.messagesTo(1)) == 1; got != tt.wantFirst {
+				t.Fatalf("instant alert sent = %v, want %v", got, tt.wantFirst)
+			}
+			if !tt.wantFirst {
+				return
+			}
+
+			// The next collection doesn't resend what the user already got
+			sched.SendInstantAlerts(tt.category)
+			if got := len(api.messagesTo(1)); got != 1 {
+				t.Errorf("got %d alerts after a second collection, want the sound only once", got)
+			}
+
+			// A new sound in the niche is sent on its own
+			seedTrending(t, s, "fitness", "cardio beat")
+			sched.SendInstantAlerts(tt.category)
+			messages := api.messagesTo(1)
+			if len(messages) != 2 || !strings.Contains(messages[1], "cardio beat") || strings.Contains(messages[1], "gym anthem") {
+				t.Errorf("messages after a new sound = %q, want a second alert with only cardio beat", messages)
+			}
+
+			// Instant users are left out of the scheduled alert round
+			sched.SendAlerts()
+			if got := len(api.messagesTo(1)); got != 2 {
+				t.Errorf("got %d alerts after the scheduled round, want no scheduled alert", got)
+			}
+		})
+	}
+}
+
+func TestSendAlertsMutedNiches(t *testing.T) {
+	tests := []struct {
+		name        string
+		muted       []string
+		instant     bool
+		wantAlerts  []string // Sound titles alerted, one alert per niche
+		wantNothing bool
+	}{
+		{name: "nothing muted", wantAlerts: []string{"gym anthem", "boss fight"}},
+		{name: "one niche muted", muted: []string{"gaming"}, wantAlerts: []string{"gym anthem"}},
+		{name: "all niches muted", muted: []string{"fitness", "gaming"}, wantNothing: true},
+		{name: "instant alerts skip muted niches", muted: []string{"fitness"}, instant: true, wantNothing: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sch, s, api := newTestScheduler(t, map[string]string{"SEND_RATE_LIMIT": "0", "ALERT_MESSAGE_INTERVAL": "1ms"})
+			seedTrending(t, s, "fitness", "gym anthem")
+			seedTrending(t, s, "gaming", "boss fight")
+			addUser(t, s, 1, "fitness", "gaming")
+			for _, niche := range tt.muted {
+				if err := s.MuteNiche(1, niche); err != nil {
+					t.Fatalf("MuteNiche() error: %v", err)
+				}
+			}
+
+			if tt.instant {
+				if err := s.SetPremium(1, true); err != nil {
+					t.Fatalf("SetPremium() error: %v", err)
+				}
+				if err := s.SetInstantAlerts(1, true); err != nil {
+					t.Fatalf("SetInstantAlerts() error: %v", err)
+				}
+				sch.SendInstantAlerts("fitness")
+			} else {
+				sch.SendAlerts()
+			}
+
+			messages := api.messagesTo(1)
+			if tt.wantNothing {
+				if len(messages) != 0 {
+					t.Errorf("got %d alerts, want none: %q", len(messages), messages)
+				}
+				return
+			}
+			if len(messages) != len(tt.wantAlerts) {
+				t.Fatalf("got %d alerts, want %d: %q", len(messages), len(tt.wantAlerts), messages)
+			}
+			all := strings.Join(messages, "\n")
+			for _, title := range tt.wantAlerts {
+				if !strings.Contains(all, title) {
+					t.Errorf("alerts missing %q in:\n%s", title, all)
+				}
+			}
+		})
+	}
+}
+
+func TestSendAlertsWorkerThroughput(t *testing.T) {
+	const (
+		users = 12
+		delay = 40 * time.Millisecond
+	)
+
+	tests := []struct {
+		name        string
+		workers     string
+		minDuration time.Duration
+		maxDuration time.Duration
+	}{
+		{name: "one worker sends one at a time", workers: "1", minDuration: users * delay},
+		{name: "four workers send in parallel", workers: "4", maxDuration: users * delay / 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sched, s, api := newTestSchedulerAt(t, map[string]string{
+				"ALERT_WORKERS":          tt.workers,
+				"ALERT_MESSAGE_INTERVAL": "1ms",
+				"SEND_RATE_LIMIT":        "0",
+			}, filepath.Join(t.TempDir(), "bot.db"))
+			seedTrending(t, s, "fitness", "gym anthem")
+			for id := int64(1); id <= users; id++ {
+				addUser(t, s, id, "fitness")
+			}
+			api.delay = delay
+
+			start := time.Now()
+			sched.SendAlerts()
+			elapsed := time.Since(start)
+
+			for id := int64(1); id <= users; id++ {
+				if got := len(api.messagesTo(id)); got != 1 {
+					t.Errorf("user %d got %d alerts, want 1", id, got)
+				}
+			}
+			if tt.minDuration > 0 && elapsed < tt.minDuration {
+				t.Errorf("cycle took %s, want at least %s with one send at a time", elapsed, tt.minDuration)
+			}
+			if tt.maxDuration > 0 && elapsed > tt.maxDuration {
+				t.Errorf("cycle took %s, want under %s with parallel workers", elapsed, tt.maxDuration)
+			}
+
+			api.mu.Lock()
+			maxInFlight := api.maxInFlight
+			api.mu.Unlock()
+			if workers, _ := strconv.Atoi(tt.workers); maxInFlight > workers {
+				t.Errorf("%d sends in flight at once, want at most %d", maxInFlight, workers)
+			}
+		})
+	}
+}
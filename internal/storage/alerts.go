@@ -0,0 +1,261 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// LastAlert is the rendered text of the last alert sent to a user for a niche
+type LastAlert struct {
+	TelegramID int64     `json:"telegram_id"`
+	Category   string    `json:"category"`
+	Message    string    `json:"message"`
+	SentAt     time.Time `json:"sent_at"`
+}
+
+// SaveLastAlert stores the rendered alert for a user and niche, replacing the previous one
+func (s *SQLiteStorage) SaveLastAlert(telegramID int64, category, message string) error {
+	query := `
+		INSERT INTO last_alerts (telegram_id, category, message, sent_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(telegram_id, category) DO UPDATE SET message = excluded.message, sent_at = excluded.sent_at
+	`
+	_, err := s.db.Exec(query, telegramID, category, message, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to save last alert: %w", err)
+	}
+	return nil
+}
+
+// GetLastAlert returns the last alert for a user and niche.
+// An empty category returns the most recent alert across all niches.
+func (s *SQLiteStorage) GetLastAlert(telegramID int64, category string) (*LastAlert, error) {
+	query := `
+		SELECT telegram_id, category, message, sent_at
+		FROM last_alerts
+		WHERE telegram_id = ? AND (? = '' OR category = ?)
+		ORDER BY sent_at DESC
+		LIMIT 1
+	`
+	alert := &LastAlert{}
+	err := s.db.QueryRow(query, telegramID, category, category).Scan(
+		&alert.TelegramID,
+		&alert.Category,
+		&alert.Message,
+		&alert.SentAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrAlertNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last alert: %w", err)
+	}
+
+	return alert, nil
+}
+
+// SentAlert is one sound sent to a user in an alert, with the sound's current details
+type SentAlert struct {
+	Niche         string    `json:"niche"`
+	SoundID       int64     `json:"sound_id"`
+	Title         string    `json:"title"`
+	Author        string    `json:"author"`
+	URL           string    `json:"url"`
+	GrowthPercent float64   `json:"growth_percent"` // Growth when the alert was sent
+	SentAt        time.Time `json:"sent_at"`
+}
+
+// RecordSentAlert stores the sounds of an alert delivered to a user for a niche
+func (s *SQLiteStorage) RecordSentAlert(telegramID int64, niche string, sounds []TrendingSound, at time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO sent_alerts (telegram_id, niche, sound_id, growth_percent, sent_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	for _, sound := range sounds {
+		if _, err := tx.Exec(query, telegramID, niche, sound.ID, sound.GrowthPercent, at); err != nil {
+			return fmt.Errorf("failed to record sent alert: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit sent alert: %w", err)
+	}
+	return nil
+}
+
+// GetUserAlertHistory returns up to limit sounds most recently sent to a user, newest first.
+// Sounds deleted since are left out.
+func (s *SQLiteStorage) GetUserAlertHistory(telegramID int64, limit int) ([]SentAlert, error) {
+	query := `
+		SELECT a.niche, a.sound_id, s.title, s.author, s.url, a.growth_percent, a.sent_at
+		FROM sent_alerts a
+		JOIN sounds s ON s.id = a.sound_id
+		WHERE a.telegram_id = ?
+		ORDER BY a.sent_at DESC, a.id DESC
+		LIMIT ?
+	`
+	rows, err := s.readDB.Query(query, telegramID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []SentAlert
+	for rows.Next() {
+		var alert SentAlert
+		if err := rows.Scan(
+			&alert.Niche,
+			&alert.SoundID,
+			&alert.Title,
+			&alert.Author,
+			&alert.URL,
+			&alert.GrowthPercent,
+			&alert.SentAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan alert history: %w", err)
+		}
+		history = append(history, alert)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get alert history: %w", err)
+	}
+
+	return history, nil
+}
+
+// GetUserAlertedSoundsSince returns the IDs of sounds sent to a user since the given time
+func (s *SQLiteStorage) GetUserAlertedSoundsSince(telegramID int64, since time.Time) (map[int64]bool, error) {
+	rows, err := s.db.Query("SELECT sound_id FROM sent_alerts WHERE telegram_id = ? AND sent_at >= ?", telegramID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user alerted sounds: %w", err)
+	}
+	defer rows.Close()
+
+	sent := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan user alerted sound: %w", err)
+		}
+		sent[id] = true
+	}
+
+	return sent, nil
+}
+
+// SaveAlertLatency records how long an alert took from detection to delivery
+func (s *SQLiteStorage) SaveAlertLatency(telegramID int64, category string, latency time.Duration) error {
+	query := `
+		INSERT INTO alert_latencies (telegram_id, category, latency_ms, recorded_at)
+		VALUES (?, ?, ?, ?)
+	`
+	_, err := s.db.Exec(query, telegramID, category, latency.Milliseconds(), time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to save alert latency: %w", err)
+	}
+	return nil
+}
+
+// GetAlertLatencies returns all alert latency samples recorded since the given time
+func (s *SQLiteStorage) GetAlertLatencies(since time.Time) ([]time.Duration, error) {
+	rows, err := s.readDB.Query("SELECT latency_ms FROM alert_latencies WHERE recorded_at >= ?", since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert latencies: %w", err)
+	}
+	defer rows.Close()
+
+	var latencies []time.Duration
+	for rows.Next() {
+		var ms int64
+		if err := rows.Scan(&ms); err != nil {
+			return nil, fmt.Errorf("failed to scan alert latency: %w", err)
+		}
+		latencies = append(latencies, time.Duration(ms)*time.Millisecond)
+	}
+
+	return latencies, nil
+}
+
+// MarkSoundsAlerted records that sounds were flagged as newly trending at the given time.
+// A sound already flagged after windowStart keeps its original time, so the window isn't extended.
+func (s *SQLiteStorage) MarkSoundsAlerted(soundIDs []int64, at, windowStart time.Time) error {
+	query := `
+		INSERT INTO alerted_sounds (sound_id, alerted_at)
+		VALUES (?, ?)
+		ON CONFLICT(sound_id) DO UPDATE SET alerted_at = excluded.alerted_at
+		WHERE alerted_sounds.alerted_at < ?
+	`
+	for _, id := range soundIDs {
+		if _, err := s.db.Exec(query, id, at, windowStart); err != nil {
+			return fmt.Errorf("failed to mark sound alerted: %w", err)
+		}
+	}
+	return nil
+}
+
+// PruneSentAlerts deletes global dedup records of sounds last flagged before olderThan and
+// returns how many were removed. Callers must keep olderThan before the dedup window start.
+func (s *SQLiteStorage) PruneSentAlerts(olderThan time.Time) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM alerted_sounds WHERE alerted_at < ?", olderThan)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune sent alerts: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// MarkChannelPosted records sounds as posted to the broadcast channel
+func (s *SQLiteStorage) MarkChannelPosted(soundIDs []int64, at time.Time) error {
+	for _, id := range soundIDs {
+		if _, err := s.db.Exec("INSERT OR IGNORE INTO channel_posts (sound_id, posted_at) VALUES (?, ?)", id, at); err != nil {
+			return fmt.Errorf("failed to mark sound posted to channel: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetChannelPostedSounds returns the IDs of all sounds already posted to the broadcast channel
+func (s *SQLiteStorage) GetChannelPostedSounds() (map[int64]bool, error) {
+	rows, err := s.db.Query("SELECT sound_id FROM channel_posts")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel posts: %w", err)
+	}
+	defer rows.Close()
+
+	posted := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan channel post: %w", err)
+		}
+		posted[id] = true
+	}
+
+	return posted, nil
+}
+
+// GetAlertedSoundsSince returns the IDs of sounds flagged as newly trending since the given time
+func (s *SQLiteStorage) GetAlertedSoundsSince(since time.Time) (map[int64]bool, error) {
+	rows, err := s.db.Query("SELECT sound_id FROM alerted_sounds WHERE alerted_at >= ?", since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alerted sounds: %w", err)
+	}
+	defer rows.Close()
+
+	alerted := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan alerted sound: %w", err)
+		}
+		alerted[id] = true
+	}
+
+	return alerted, nil
+}
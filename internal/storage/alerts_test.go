@@ -0,0 +1,331 @@
+package storage
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLastAlert(t *testing.T) {
+	s := newTestStorage(t)
+
+	if _, err := s.GetLastAlert(1, ""); !errors.Is(err, ErrAlertNotFound) {
+		t.Fatalf("GetLastAlert() before any alert error = %v, want ErrAlertNotFound", err)
+	}
+
+	for _, a := range []struct{ category, message string }{
+		{"fitness", "first fitness"},
+		{"gaming", "gaming"},
+		{"fitness", "second fitness"}, // replaces the first one
+	} {
+		if err := s.SaveLastAlert(1, a.category, a.message); err != nil {
+			t.Fatalf("SaveLastAlert() error: %v", err)
+		}
+	}
+	if err := s.SaveLastAlert(2, "comedy", "other user"); err != nil {
+		t.Fatalf("SaveLastAlert() error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		telegramID int64
+		category   string
+		want       string
+		wantErr    error
+	}{
+		{name: "latest across niches", telegramID: 1, want: "second fitness"},
+		{name: "replaced per niche", telegramID: 1, category: "fitness", want: "second fitness"},
+		{name: "other niche", telegramID: 1, category: "gaming", want: "gaming"},
+		{name: "niche without alert", telegramID: 1, category: "comedy", wantErr: ErrAlertNotFound},
+		{name: "other user", telegramID: 2, want: "other user"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetLastAlert(tt.telegramID, tt.category)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("GetLastAlert() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetLastAlert() error: %v", err)
+			}
+			if got.Message != tt.want {
+				t.Errorf("GetLastAlert() message = %q, want %q", got.Message, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlertLatencies(t *testing.T) {
+	s := newTestStorage(t)
+
+	for _, latency := range []time.Duration{1500 * time.Millisecond, 3 * time.Second} {
+		if err := s.SaveAlertLatency(1, "fitness", latency); err != nil {
+			t.Fatalf("SaveAlertLatency() error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		since time.Time
+		want  []time.Duration
+	}{
+		{name: "recent", since: time.Now().Add(-time.Hour), want: []time.Duration{1500 * time.Millisecond, 3 * time.Second}},
+		{name: "future window", since: time.Now().Add(time.Hour), want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetAlertLatencies(tt.since)
+			if err != nil {
+				t.Fatalf("GetAlertLatencies() error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("latency %d = %s, want %s", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAlertedSounds(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now()
+	a := addSound(t, s, "fitness", "a", 1000)
+	b := addSound(t, s, "fitness", "b", 1000)
+
+	// a was flagged 2h ago, inside a 6h window
+	if err := s.MarkSoundsAlerted([]int64{a.ID}, now.Add(-2*time.Hour), now.Add(-8*time.Hour)); err != nil {
+		t.Fatalf("MarkSoundsAlerted() error: %v", err)
+	}
+	// Flagging both again now must not move a's time forward
+	windowStart := now.Add(-6 * time.Hour)
+	if err := s.MarkSoundsAlerted([]int64{a.ID, b.ID}, now, windowStart); err != nil {
+		t.Fatalf("MarkSoundsAlerted() error: %v", err)
+	}
+
+	tests := []struct {
+		since time.Time
+		want  map[int64]bool
+	}{
+		{since: now.Add(-3 * time.Hour), want: map[int64]bool{a.ID: true, b.ID: true}},
+		{since: now.Add(-time.Hour), want: map[int64]bool{b.ID: true}},
+		{since: now.Add(time.Minute), want: map[int64]bool{}},
+	}
+	for _, tt := range tests {
+		got, err := s.GetAlertedSoundsSince(tt.since)
+		if err != nil {
+			t.Fatalf("GetAlertedSoundsSince() error: %v", err)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("GetAlertedSoundsSince(%s ago) = %v, want %v", time.Since(tt.since).Round(time.Minute), got, tt.want)
+			continue
+		}
+		for id := range tt.want {
+			if !got[id] {
+				t.Errorf("GetAlertedSoundsSince(%s ago) = %v, want %v", time.Since(tt.since).Round(time.Minute), got, tt.want)
+			}
+		}
+	}
+
+	// Once a's flag is older than the window, it starts a new one
+	if err := s.MarkSoundsAlerted([]int64{a.ID}, now, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("MarkSoundsAlerted() error: %v", err)
+	}
+	if got, _ := s.GetAlertedSoundsSince(now.Add(-time.Minute)); !got[a.ID] {
+		t.Errorf("a not re-flagged after its window ended: %v", got)
+	}
+}
+
+func TestChannelPosts(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now()
+	a := addSound(t, s, "fitness", "a", 1000)
+	b := addSound(t, s, "fitness", "b", 1000)
+
+	if got, err := s.GetChannelPostedSounds(); err != nil || len(got) != 0 {
+		t.Fatalf("GetChannelPostedSounds() = %v, %v, want none", got, err)
+	}
+
+	if err := s.MarkChannelPosted([]int64{a.ID}, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("MarkChannelPosted() error: %v", err)
+	}
+	// Posting a again alongside b is not an error
+	if err := s.MarkChannelPosted([]int64{a.ID, b.ID}, now); err != nil {
+		t.Fatalf("MarkChannelPosted() error: %v", err)
+	}
+
+	got, err := s.GetChannelPostedSounds()
+	if err != nil {
+		t.Fatalf("GetChannelPostedSounds() error: %v", err)
+	}
+	if want := map[int64]bool{a.ID: true, b.ID: true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetChannelPostedSounds() = %v, want %v", got, want)
+	}
+}
+
+func TestPruneSentAlerts(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now()
+	old := addSound(t, s, "fitness", "old", 1000)
+	recent := addSound(t, s, "fitness", "recent", 1000)
+
+	if err := s.MarkSoundsAlerted([]int64{old.ID}, now.Add(-20*24*time.Hour), now.Add(-21*24*time.Hour)); err != nil {
+		t.Fatalf("MarkSoundsAlerted() error: %v", err)
+	}
+	if err := s.MarkSoundsAlerted([]int64{recent.ID}, now.Add(-time.Hour), now.Add(-2*time.Hour)); err != nil {
+		t.Fatalf("MarkSoundsAlerted() error: %v", err)
+	}
+
+	steps := []struct {
+		olderThan  time.Time
+		wantPruned int64
+		wantKept   map[int64]bool
+	}{
+		{olderThan: now.Add(-14 * 24 * time.Hour), wantPruned: 1, wantKept: map[int64]bool{recent.ID: true}},
+		{olderThan: now.Add(-14 * 24 * time.Hour), wantPruned: 0, wantKept: map[int64]bool{recent.ID: true}},
+		{olderThan: now, wantPruned: 1, wantKept: map[int64]bool{}},
+	}
+	for i, step := range steps {
+		pruned, err := s.PruneSentAlerts(step.olderThan)
+		if err != nil {
+			t.Fatalf("step %d: PruneSentAlerts() error: %v", i, err)
+		}
+		if pruned != step.wantPruned {
+			t.Errorf("step %d: pruned %d, want %d", i, pruned, step.wantPruned)
+		}
+		got, err := s.GetAlertedSoundsSince(now.Add(-30 * 24 * time.Hour))
+		if err != nil {
+			t.Fatalf("step %d: GetAlertedSoundsSince() error: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, step.wantKept) {
+			t.Errorf("step %d: kept %v, want %v", i, got, step.wantKept)
+		}
+	}
+}
+
+func TestUserAlertHistory(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now()
+	a := addSound(t, s, "fitness", "a", 1000)
+	b := addSound(t, s, "gaming", "b", 1000)
+
+	if history, err := s.GetUserAlertHistory(1, 10); err != nil || len(history) != 0 {
+		t.Fatalf("GetUserAlertHistory() before any alert = %v, %v, want none", history, err)
+	}
+
+	record := func(telegramID int64, niche string, at time.Time, sounds ...TrendingSound) {
+		t.Helper()
+		if err := s.RecordSentAlert(telegramID, niche, sounds, at); err != nil {
+			t.Fatalf("RecordSentAlert() error: %v", err)
+		}
+	}
+	record(1, "fitness", now.Add(-2*time.Hour), TrendingSound{Sound: *a, GrowthPercent: 200})
+	record(1, "gaming", now.Add(-time.Hour), TrendingSound{Sound: *b, GrowthPercent: 300}, TrendingSound{Sound: *a, GrowthPercent: 250})
+	record(2, "fitness", now, TrendingSound{Sound: *b})
+
+	tests := []struct {
+		limit int
+		want  []string // niche/title, newest first
+	}{
+		{limit: 10, want: []string{"gaming/a", "gaming/b", "fitness/a"}},
+		{limit: 2, want: []string{"gaming/a", "gaming/b"}},
+	}
+	for _, tt := range tests {
+		history, err := s.GetUserAlertHistory(1, tt.limit)
+		if err != nil {
+			t.Fatalf("GetUserAlertHistory() error: %v", err)
+		}
+		var got []string
+		for _, alert := range history {
+			got = append(got, alert.Niche+"/"+alert.Title)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetUserAlertHistory(limit %d) = %v, want %v", tt.limit, got, tt.want)
+		}
+	}
+
+	history, _ := s.GetUserAlertHistory(1, 1)
+	if alert := history[0]; alert.SoundID != a.ID || alert.URL != a.URL || alert.Author != "author" || alert.GrowthPercent != 250 {
+		t.Errorf("newest alert = %+v, want sound a as sent with 250%% growth", alert)
+	}
+}
+
+func TestGetUserAlertedSoundsSince(t *testing.T) {
+	s := newTestStorage(t)
+	for _, id := range []int64{1, 2} {
+		if err := s.CreateUser(id); err != nil {
+			t.Fatalf("CreateUser() error: %v", err)
+		}
+	}
+	now := time.Now()
+	a := addSound(t, s, "fitness", "a", 1000)
+	b := addSound(t, s, "fitness", "b", 1000)
+	c := addSound(t, s, "fitness", "c", 1000)
+	if err := s.RecordSentAlert(1, "fitness", []TrendingSound{{Sound: *a}}, now.Add(-30*time.Hour)); err != nil {
+		t.Fatalf("RecordSentAlert() error: %v", err)
+	}
+	if err := s.RecordSentAlert(1, "fitness", []TrendingSound{{Sound: *b}}, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("RecordSentAlert() error: %v", err)
+	}
+	if err := s.RecordSentAlert(2, "fitness", []TrendingSound{{Sound: *c}}, now); err != nil {
+		t.Fatalf("RecordSentAlert() error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		telegramID int64
+		since      time.Time
+		want       map[int64]bool
+	}{
+		{name: "last day", telegramID: 1, since: now.Add(-24 * time.Hour), want: map[int64]bool{b.ID: true}},
+		{name: "everything", telegramID: 1, since: now.Add(-48 * time.Hour), want: map[int64]bool{a.ID: true, b.ID: true}},
+		{name: "other user", telegramID: 2, since: now.Add(-48 * time.Hour), want: map[int64]bool{c.ID: true}},
+		{name: "nothing yet", telegramID: 3, since: now.Add(-48 * time.Hour), want: map[int64]bool{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetUserAlertedSoundsSince(tt.telegramID, tt.since)
+			if err != nil {
+				t.Fatalf("GetUserAlertedSoundsSince() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetUserAlertedSoundsSince() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeUsersSentAlerts(t *testing.T) {
+	s := newTestStorage(t)
+	for _, id := range []int64{1, 2} {
+		if err := s.CreateUser(id); err != nil {
+			t.Fatalf("CreateUser() error: %v", err)
+		}
+	}
+	now := time.Now()
+	a := addSound(t, s, "fitness", "a", 1000)
+	for _, id := range []int64{1, 2} {
+		if err := s.RecordSentAlert(id, "fitness", []TrendingSound{{Sound: *a}}, now); err != nil {
+			t.Fatalf("RecordSentAlert() error: %v", err)
+		}
+	}
+
+	if err := s.MergeUsers(1, 2); err != nil {
+		t.Fatalf("MergeUsers() error: %v", err)
+	}
+
+	if history, _ := s.GetUserAlertHistory(1, 10); len(history) != 2 {
+		t.Errorf("kept user has %d alerts, want both users' history", len(history))
+	}
+	if history, _ := s.GetUserAlertHistory(2, 10); len(history) != 0 {
+		t.Errorf("duplicate has %d alerts, want none", len(history))
+	}
+}
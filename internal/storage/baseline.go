@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// baselineChunkSize is how many sounds get a baseline row per transaction, so seeding a large
+// import doesn't hold the write lock for the whole table at once
+const baselineChunkSize = 500
+
+// SeedBaselineHistory records each sound's current uses count at recordedAt for every sound
+// that has no history yet, so the first real collection already has a point to compare against.
+// It returns how many baseline rows were written.
+func (s *SQLiteStorage) SeedBaselineHistory(recordedAt time.Time) (int, error) {
+	query := `
+		INSERT INTO sound_history (sound_id, uses_count, recorded_at, source)
+		SELECT s.id, s.uses_count, ?, ''
+		FROM sounds s
+		WHERE s.id > ?
+		  AND NOT EXISTS (SELECT 1 FROM sound_history h WHERE h.sound_id = s.id)
+		ORDER BY s.id
+		LIMIT ?
+		RETURNING sound_id
+	`
+
+	var (
+		seeded int
+		lastID int64
+	)
+	for {
+		n, maxID, err := s.seedBaselineChunk(query, recordedAt, lastID)
+		if err != nil {
+			return seeded, err
+		}
+		seeded += n
+		if n < baselineChunkSize {
+			return seeded, nil
+		}
+		lastID = maxID
+	}
+}
+
+// seedBaselineChunk writes one chunk of baseline rows for sounds after afterID,
+// returning the number written and the highest sound id among them
+func (s *SQLiteStorage) seedBaselineChunk(query string, recordedAt time.Time, afterID int64) (int, int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(query, recordedAt, afterID, baselineChunkSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to seed baseline history: %w", err)
+	}
+
+	var (
+		n     int
+		maxID int64
+	)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("failed to scan baseline history: %w", err)
+		}
+		n++
+		if id > maxID {
+			maxID = id
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, 0, fmt.Errorf("failed to seed baseline history: %w", err)
+	}
+	rows.Close()
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit baseline history: %w", err)
+	}
+	return n, maxID, nil
+}
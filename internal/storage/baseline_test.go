@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSeedBaselineHistory(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now()
+	backdated := now.Add(-48 * time.Hour)
+
+	// More sounds than one chunk, one of them already tracked
+	total := baselineChunkSize*2 + 3
+	var tracked *Sound
+	for i := 0; i < total; i++ {
+		sound := addSound(t, s, "fitness", fmt.Sprintf("sound %d", i), int64(1000+i))
+		if i == 0 {
+			tracked = sound
+		}
+	}
+	addHistory(t, s, tracked.ID, 900, now.Add(-time.Hour))
+
+	seeded, err := s.SeedBaselineHistory(backdated)
+	if err != nil {
+		t.Fatalf("SeedBaselineHistory() error: %v", err)
+	}
+	if seeded != total-1 {
+		t.Errorf("seeded %d sounds, want %d", seeded, total-1)
+	}
+
+	var rows, atBackdate int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM sound_history").Scan(&rows); err != nil {
+		t.Fatalf("count history: %v", err)
+	}
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM sound_history WHERE recorded_at = ? AND sound_id != ?", backdated, tracked.ID).Scan(&atBackdate); err != nil {
+		t.Fatalf("count backdated history: %v", err)
+	}
+	if rows != total || atBackdate != total-1 {
+		t.Errorf("history has %d rows, %d backdated, want %d and %d", rows, atBackdate, total, total-1)
+	}
+
+	// The tracked sound keeps only its real history
+	history, err := s.GetSoundHistoryRange(tracked.ID, now.Add(-72*time.Hour))
+	if err != nil {
+		t.Fatalf("GetSoundHistoryRange() error: %v", err)
+	}
+	if len(history) != 1 || history[0].UsesCount != 900 {
+		t.Errorf("tracked sound history = %+v, want only the 900 sample", history)
+	}
+
+	// Every sound has history now, so seeding again does nothing
+	if seeded, err := s.SeedBaselineHistory(backdated); err != nil || seeded != 0 {
+		t.Errorf("second SeedBaselineHistory() = %d, %v, want 0, nil", seeded, err)
+	}
+}
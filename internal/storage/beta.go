@@ -0,0 +1,21 @@
+package storage
+
+import "fmt"
+
+// SetBetaEnrolled opts a user in or out of beta features
+func (s *SQLiteStorage) SetBetaEnrolled(telegramID int64, enrolled bool) error {
+	_, err := s.db.Exec("UPDATE users SET beta_enrolled = ? WHERE telegram_id = ?", enrolled, telegramID)
+	if err != nil {
+		return fmt.Errorf("failed to set beta enrollment: %w", err)
+	}
+	return nil
+}
+
+// CountBetaUsers returns how many users are enrolled in beta features
+func (s *SQLiteStorage) CountBetaUsers() (int, error) {
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM users WHERE beta_enrolled = 1").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count beta users: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,42 @@
+package storage
+
+import "testing"
+
+func TestBetaEnrollment(t *testing.T) {
+	s := newTestStorage(t)
+	for _, id := range []int64{1, 2, 3} {
+		if err := s.CreateUser(id); err != nil {
+			t.Fatalf("CreateUser() error: %v", err)
+		}
+	}
+
+	steps := []struct {
+		telegramID int64
+		enrolled   bool
+		wantCount  int
+	}{
+		{telegramID: 1, enrolled: true, wantCount: 1},
+		{telegramID: 2, enrolled: true, wantCount: 2},
+		{telegramID: 1, enrolled: true, wantCount: 2}, // enrolling twice counts once
+		{telegramID: 2, enrolled: false, wantCount: 1},
+	}
+	for i, step := range steps {
+		if err := s.SetBetaEnrolled(step.telegramID, step.enrolled); err != nil {
+			t.Fatalf("step %d: SetBetaEnrolled() error: %v", i, err)
+		}
+		user, err := s.GetUser(step.telegramID)
+		if err != nil {
+			t.Fatalf("step %d: GetUser() error: %v", i, err)
+		}
+		if user.BetaEnrolled != step.enrolled {
+			t.Errorf("step %d: BetaEnrolled = %v, want %v", i, user.BetaEnrolled, step.enrolled)
+		}
+		count, err := s.CountBetaUsers()
+		if err != nil {
+			t.Fatalf("step %d: CountBetaUsers() error: %v", i, err)
+		}
+		if count != step.wantCount {
+			t.Errorf("step %d: CountBetaUsers() = %d, want %d", i, count, step.wantCount)
+		}
+	}
+}
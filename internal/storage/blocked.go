@@ -0,0 +1,45 @@
+package storage
+
+import "fmt"
+
+// SetBlocked blocks or unblocks a user. Blocked users are ignored by the bot and get no alerts.
+func (s *SQLiteStorage) SetBlocked(telegramID int64, blocked bool) error {
+	result, err := s.db.Exec("UPDATE users SET blocked = ? WHERE telegram_id = ?", blocked, telegramID)
+	if err != nil {
+		return fmt.Errorf("failed to set blocked: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to set blocked: %w", err)
+	}
+	if updated == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// GetAlertUsers returns the users who may be messaged by alerts and broadcasts, i.e. everyone not blocked
+func (s *SQLiteStorage) GetAlertUsers() ([]User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE blocked = 0
+		ORDER BY created_at DESC
+	`
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var user User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
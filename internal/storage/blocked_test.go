@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSetBlocked(t *testing.T) {
+	s := newTestStorage(t)
+	addAlertUsers(t, s, 3)
+
+	if err := s.SetBlocked(9, true); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("SetBlocked(unknown) error = %v, want %v", err, ErrUserNotFound)
+	}
+
+	steps := []struct {
+		telegramID int64
+		blocked    bool
+		want       []int64 // Alert users after the step
+	}{
+		{telegramID: 2, blocked: true, want: []int64{1, 3}},
+		{telegramID: 3, blocked: true, want: []int64{1}},
+		{telegramID: 2, blocked: false, want: []int64{1, 2}},
+	}
+	for i, step := range steps {
+		if err := s.SetBlocked(step.telegramID, step.blocked); err != nil {
+			t.Fatalf("step %d: SetBlocked() error: %v", i, err)
+		}
+
+		user, err := s.GetUser(step.telegramID)
+		if err != nil {
+			t.Fatalf("step %d: GetUser() error: %v", i, err)
+		}
+		if user.Blocked != step.blocked {
+			t.Errorf("step %d: Blocked = %v, want %v", i, user.Blocked, step.blocked)
+		}
+
+		users, err := s.GetAlertUsers()
+		if err != nil {
+			t.Fatalf("step %d: GetAlertUsers() error: %v", i, err)
+		}
+		if got := claimedIDs(users); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("step %d: alert users = %v, want %v", i, got, step.want)
+		}
+	}
+}
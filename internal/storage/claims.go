@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// ClaimUsersForAlert marks up to batchSize users with niches as claimed and returns them, so that
+// scheduler instances sharing the database never alert the same user at once. A claim older than
+// lockTTL is treated as abandoned by a crashed instance and can be taken over. Users released
+// since roundStart were already handled in this round and are skipped. The claim is a single
+// UPDATE, which SQLite runs atomically, so concurrent callers get disjoint batches. Each returned
+// user's AlertClaimedAt identifies the claim for RenewUserClaim and ReleaseUserClaim.
+func (s *SQLiteStorage) ClaimUsersForAlert(batchSize int, lockTTL time.Duration, roundStart time.Time) ([]User, error) {
+	now := time.Now()
+	query := `
+		UPDATE users SET alert_claimed_at = ?
+		WHERE id IN (
+			SELECT id FROM users
+			WHERE niches NOT IN ('', '[]') AND blocked = 0
+			AND (alert_claimed_at IS NULL OR alert_claimed_at < ?)
+			AND (alert_released_at IS NULL OR alert_released_at < ?)
+			ORDER BY id
+			LIMIT ?
+		)
+		RETURNING ` + userColumns
+	rows, err := s.db.Query(query, now, now.Add(-lockTTL), roundStart, batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var user User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		user.AlertClaimedAt = now
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim users: %w", err)
+	}
+
+	return users, nil
+}
+
+// RenewUserClaim restarts the lock TTL of a claim taken by ClaimUsersForAlert, so a user who waits
+// behind slow sends isn't treated as abandoned. It reports false when the claim was already taken
+// over by another instance or released; the caller must then leave the user alone.
+func (s *SQLiteStorage) RenewUserClaim(user *User) (bool, error) {
+	now := time.Now()
+	result, err := s.db.Exec("UPDATE users SET alert_claimed_at = ? WHERE telegram_id = ? AND alert_claimed_at = ?",
+		now, user.TelegramID, user.AlertClaimedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to renew user claim: %w", err)
+	}
+	renewed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to renew user claim: %w", err)
+	}
+	if renewed == 0 {
+		return false, nil
+	}
+	user.AlertClaimedAt = now
+	return true, nil
+}
+
+// ReleaseUserClaim clears a user's alert claim once the claiming instance is done with them,
+// recording when so the rest of the round skips them. A claim another instance has since taken
+// over is left alone.
+func (s *SQLiteStorage) ReleaseUserClaim(user *User) error {
+	_, err := s.db.Exec("UPDATE users SET alert_claimed_at = NULL, alert_released_at = ? WHERE telegram_id = ? AND alert_claimed_at = ?",
+		time.Now(), user.TelegramID, user.AlertClaimedAt)
+	if err != nil {
+		return fmt.Errorf("failed to release user claim: %w", err)
+	}
+	return nil
+}
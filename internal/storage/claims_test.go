@@ -0,0 +1,267 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// addAlertUsers creates users 1..n following fitness
+func addAlertUsers(t *testing.T, s *SQLiteStorage, n int) {
+	t.Helper()
+	for id := int64(1); id <= int64(n); id++ {
+		if err := s.CreateUser(id); err != nil {
+			t.Fatalf("CreateUser() error: %v", err)
+		}
+		if err := s.UpdateUserNiches(id, `["fitness"]`); err != nil {
+			t.Fatalf("UpdateUserNiches() error: %v", err)
+		}
+	}
+}
+
+// claimedIDs returns the sorted telegram IDs of claimed users
+func claimedIDs(users []User) []int64 {
+	ids := make([]int64, len(users))
+	for i, u := range users {
+		ids[i] = u.TelegramID
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func TestClaimUsersForAlert(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, s *SQLiteStorage, roundStart time.Time)
+		want  []int64
+	}{
+		{
+			name:  "unclaimed users",
+			setup: func(*testing.T, *SQLiteStorage, time.Time) {},
+			want:  []int64{1, 2, 3},
+		},
+		{
+			name: "users without niches or blocked are skipped",
+			setup: func(t *testing.T, s *SQLiteStorage, _ time.Time) {
+				if err := s.UpdateUserNiches(1, "[]"); err != nil {
+					t.Fatalf("UpdateUserNiches() error: %v", err)
+				}
+				if err := s.SetBlocked(2, true); err != nil {
+					t.Fatalf("SetBlocked() error: %v", err)
+				}
+			},
+			want: []int64{3},
+		},
+		{
+			name: "fresh claim is exclusive",
+			setup: func(t *testing.T, s *SQLiteStorage, _ time.Time) {
+				if _, err := s.db.Exec("UPDATE users SET alert_claimed_at = ? WHERE telegram_id = 1", time.Now().Add(-time.Minute)); err != nil {
+					t.Fatalf("claim user: %v", err)
+				}
+			},
+			want: []int64{2, 3},
+		},
+		{
+			name: "expired claim is taken over",
+			setup: func(t *testing.T, s *SQLiteStorage, _ time.Time) {
+				if _, err := s.db.Exec("UPDATE users SET alert_claimed_at = ? WHERE telegram_id = 1", time.Now().Add(-time.Hour)); err != nil {
+					t.Fatalf("claim user: %v", err)
+				}
+			},
+			want: []int64{1, 2, 3},
+		},
+		{
+			name: "released in this round is skipped",
+			setup: func(t *testing.T, s *SQLiteStorage, _ time.Time) {
+				if _, err := s.db.Exec("UPDATE users SET alert_released_at = ? WHERE telegram_id = 2", time.Now()); err != nil {
+					t.Fatalf("release user: %v", err)
+				}
+			},
+			want: []int64{1, 3},
+		},
+		{
+			name: "released in an earlier round is claimed again",
+			setup: func(t *testing.T, s *SQLiteStorage, roundStart time.Time) {
+				if _, err := s.db.Exec("UPDATE users SET alert_released_at = ? WHERE telegram_id = 2", roundStart.Add(-6*time.Hour)); err != nil {
+					t.Fatalf("release user: %v", err)
+				}
+			},
+			want: []int64{1, 2, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(t)
+			addAlertUsers(t, s, 3)
+			roundStart := time.Now().Add(-time.Second)
+			tt.setup(t, s, roundStart)
+
+			users, err := s.ClaimUsersForAlert(10, 10*time.Minute, roundStart)
+			if err != nil {
+				t.Fatalf("ClaimUsersForAlert() error: %v", err)
+			}
+			if got := claimedIDs(users); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("claimed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClaimUsersForAlertBatches(t *testing.T) {
+	s := newTestStorage(t)
+	addAlertUsers(t, s, 5)
+	roundStart := time.Now()
+
+	first, err := s.ClaimUsersForAlert(2, time.Minute, roundStart)
+	if err != nil {
+		t.Fatalf("ClaimUsersForAlert() error: %v", err)
+	}
+	second, err := s.ClaimUsersForAlert(2, time.Minute, roundStart)
+	if err != nil {
+		t.Fatalf("ClaimUsersForAlert() error: %v", err)
+	}
+	if got := append(claimedIDs(first), claimedIDs(second)...); !reflect.DeepEqual(got, []int64{1, 2, 3, 4}) {
+		t.Errorf("batches claimed %v, want 1-4 in two disjoint batches", got)
+	}
+
+	// Releasing the first batch doesn't hand it out again this round
+	for i := range first {
+		if err := s.ReleaseUserClaim(&first[i]); err != nil {
+			t.Fatalf("ReleaseUserClaim() error: %v", err)
+		}
+	}
+	third, err := s.ClaimUsersForAlert(2, time.Minute, roundStart)
+	if err != nil {
+		t.Fatalf("ClaimUsersForAlert() error: %v", err)
+	}
+	if got := claimedIDs(third); !reflect.DeepEqual(got, []int64{5}) {
+		t.Errorf("third batch = %v, want [5]", got)
+	}
+
+	var claimed int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM users WHERE alert_claimed_at IS NOT NULL").Scan(&claimed); err != nil {
+		t.Fatalf("count claims: %v", err)
+	}
+	if claimed != 3 {
+		t.Errorf("%d users still claimed, want 3 after releasing the first batch", claimed)
+	}
+}
+
+func TestRenewUserClaim(t *testing.T) {
+	tests := []struct {
+		name        string
+		takeOver    bool // Another instance takes the claim over after it expires
+		release     bool // The claim was released before renewing
+		wantRenewed bool
+	}{
+		{name: "own claim", wantRenewed: true},
+		{name: "taken over by another instance", takeOver: true},
+		{name: "already released", release: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(t)
+			addAlertUsers(t, s, 1)
+			roundStart := time.Now()
+			claimed, err := s.ClaimUsersForAlert(1, time.Minute, roundStart)
+			if err != nil || len(claimed) != 1 {
+				t.Fatalf("ClaimUsersForAlert() = %v, %v, want user 1", claimed, err)
+			}
+			user := claimed[0]
+			claimedAt := user.AlertClaimedAt
+
+			if tt.takeOver {
+				// A zero TTL makes the claim count as abandoned right away
+				other, err := s.ClaimUsersForAlert(1, 0, roundStart)
+				if err != nil || len(other) != 1 {
+					t.Fatalf("take over = %v, %v, want user 1", other, err)
+				}
+			}
+			if tt.release {
+				if err := s.ReleaseUserClaim(&user); err != nil {
+					t.Fatalf("ReleaseUserClaim() error: %v", err)
+				}
+			}
+
+			renewed, err := s.RenewUserClaim(&user)
+			if err != nil {
+				t.Fatalf("RenewUserClaim() error: %v", err)
+			}
+			if renewed != tt.wantRenewed {
+				t.Fatalf("RenewUserClaim() = %v, want %v", renewed, tt.wantRenewed)
+			}
+			if renewed && !user.AlertClaimedAt.After(claimedAt) {
+				t.Error("RenewUserClaim() did not move the claim time forward")
+			}
+
+			// Releasing a lost claim must not free the user for everyone else
+			if err := s.ReleaseUserClaim(&user); err != nil {
+				t.Fatalf("ReleaseUserClaim() error: %v", err)
+			}
+			var stillClaimed bool
+			if err := s.db.QueryRow("SELECT alert_claimed_at IS NOT NULL FROM users WHERE telegram_id = 1").Scan(&stillClaimed); err != nil {
+				t.Fatalf("read claim: %v", err)
+			}
+			if stillClaimed != tt.takeOver {
+				t.Errorf("claimed after release = %v, want %v", stillClaimed, tt.takeOver)
+			}
+		})
+	}
+}
+
+func TestClaimUsersForAlertConcurrent(t *testing.T) {
+	// A file database, as instances would share; the shared in-memory cache locks whole tables
+	s, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "claims.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() error: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if err := s.Init(); err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+	addAlertUsers(t, s, 40)
+	roundStart := time.Now()
+
+	// Several instances claiming at once never get the same user
+	var (
+		mu      sync.Mutex
+		claimed []int64
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				users, err := s.ClaimUsersForAlert(3, time.Minute, roundStart)
+				if err != nil {
+					t.Errorf("ClaimUsersForAlert() error: %v", err)
+					return
+				}
+				if len(users) == 0 {
+					return
+				}
+				mu.Lock()
+				claimed = append(claimed, claimedIDs(users)...)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[int64]bool)
+	for _, id := range claimed {
+		if seen[id] {
+			t.Errorf("user %d claimed twice", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != 40 {
+		t.Errorf("claimed %d users, want all 40", len(seen))
+	}
+}
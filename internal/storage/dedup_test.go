@@ -0,0 +1,106 @@
+package storage
+
+import "testing"
+
+func TestSaveSoundWithHistoryDedup(t *testing.T) {
+	// Each case saves first and then second; wantSounds is how many rows that leaves
+	tests := []struct {
+		name        string
+		strategy    DedupStrategy
+		first       Sound
+		second      Sound
+		wantSounds  int
+		wantMusicID string
+	}{
+		{
+			name:       "url matches the same url",
+			strategy:   DedupByURL,
+			first:      Sound{Title: "a", Author: "x", URL: "https://www.tiktok.com/music/a-1"},
+			second:     Sound{Title: "a (remix)", Author: "x", URL: "https://www.tiktok.com/music/a-1?lang=en"},
+			wantSounds: 1,
+		},
+		{
+			name:       "url keeps reuploads apart",
+			strategy:   DedupByURL,
+			first:      Sound{Title: "a", Author: "x", URL: "https://www.tiktok.com/music/a-1"},
+			second:     Sound{Title: "a", Author: "x", URL: "https://www.tiktok.com/music/a-2"},
+			wantSounds: 2,
+		},
+		{
+			name:       "title_author merges reuploads",
+			strategy:   DedupByTitleAuthor,
+			first:      Sound{Title: "a", Author: "x", URL: "https://www.tiktok.com/music/a-1"},
+			second:     Sound{Title: "a", Author: "x", URL: "https://www.tiktok.com/music/a-2"},
+			wantSounds: 1,
+		},
+		{
+			name:       "title_author keeps other authors apart",
+			strategy:   DedupByTitleAuthor,
+			first:      Sound{Title: "a", Author: "x", URL: "https://www.tiktok.com/music/a-1"},
+			second:     Sound{Title: "a", Author: "y", URL: "https://www.tiktok.com/music/a-2"},
+			wantSounds: 2,
+		},
+		{
+			name:        "music_id matches across urls",
+			strategy:    DedupByMusicID,
+			first:       Sound{Title: "a", URL: "https://www.tiktok.com/music/a-1", MusicID: "42"},
+			second:      Sound{Title: "b", URL: "https://www.tiktok.com/music/b-2", MusicID: "42"},
+			wantSounds:  1,
+			wantMusicID: "42",
+		},
+		{
+			name:        "music_id falls back to url and keeps the stored id",
+			strategy:    DedupByMusicID,
+			first:       Sound{Title: "a", URL: "https://www.tiktok.com/music/a-1", MusicID: "42"},
+			second:      Sound{Title: "a", URL: "https://www.tiktok.com/music/a-1"},
+			wantSounds:  1,
+			wantMusicID: "42",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(t)
+			first, second := tt.first, tt.second
+			first.Category, second.Category = "fitness", "fitness"
+			first.UsesCount, second.UsesCount = 1000, 2000
+
+			if err := SaveSoundWithHistory(s, &first, tt.strategy); err != nil {
+				t.Fatalf("SaveSoundWithHistory(first) error: %v", err)
+			}
+			if err := SaveSoundWithHistory(s, &second, tt.strategy); err != nil {
+				t.Fatalf("SaveSoundWithHistory(second) error: %v", err)
+			}
+
+			var count int
+			if err := s.db.QueryRow("SELECT COUNT(*) FROM sounds").Scan(&count); err != nil {
+				t.Fatalf("count sounds: %v", err)
+			}
+			if count != tt.wantSounds {
+				t.Errorf("stored %d sounds, want %d", count, tt.wantSounds)
+			}
+
+			if tt.wantSounds == 1 {
+				if second.ID != first.ID {
+					t.Errorf("second save got id %d, want the first sound's %d", second.ID, first.ID)
+				}
+				stored, err := s.GetSoundByID(first.ID)
+				if err != nil {
+					t.Fatalf("GetSoundByID() error: %v", err)
+				}
+				if stored.UsesCount != 2000 || stored.MusicID != tt.wantMusicID {
+					t.Errorf("stored = %+v, want 2000 uses and music_id %q", stored, tt.wantMusicID)
+				}
+			}
+		})
+	}
+}
+
+func TestGetSoundByMusicIDEmpty(t *testing.T) {
+	s := newTestStorage(t)
+	addSound(t, s, "fitness", "no id", 100)
+
+	if _, err := s.GetSoundByMusicID(""); err != ErrSoundNotFound {
+		t.Errorf("GetSoundByMusicID(\"\") error = %v, want ErrSoundNotFound", err)
+	}
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SetDigestFrequency stores how often a user's alerts are delivered
+func (s *SQLiteStorage) SetDigestFrequency(telegramID int64, frequency string) error {
+	_, err := s.db.Exec("UPDATE users SET digest_frequency = ? WHERE telegram_id = ?", frequency, telegramID)
+	if err != nil {
+		return fmt.Errorf("failed to set digest frequency: %w", err)
+	}
+	return nil
+}
+
+// QueueDigestSounds holds sounds for a user's next digest. A sound queued again for the
+// same niche keeps only its latest metrics, so a digest lists each sound once.
+func (s *SQLiteStorage) QueueDigestSounds(telegramID int64, niche string, sounds []TrendingSound, at time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO digest_items (telegram_id, niche, sound_id, payload, queued_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(telegram_id, niche, sound_id) DO UPDATE SET payload = excluded.payload, queued_at = excluded.queued_at
+	`
+	for _, sound := range sounds {
+		payload, err := json.Marshal(sound)
+		if err != nil {
+			return fmt.Errorf("failed to encode digest sound %d: %w", sound.ID, err)
+		}
+		if _, err := tx.Exec(query, telegramID, niche, sound.ID, string(payload), at); err != nil {
+			return fmt.Errorf("failed to queue digest sound %d: %w", sound.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit digest sounds: %w", err)
+	}
+	return nil
+}
+
+// GetDigestSounds returns a user's queued digest sounds keyed by niche, highest growth first
+func (s *SQLiteStorage) GetDigestSounds(telegramID int64) (map[string][]TrendingSound, error) {
+	rows, err := s.db.Query(`SELECT niche, payload FROM digest_items WHERE telegram_id = ? ORDER BY niche`, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get digest sounds: %w", err)
+	}
+	defer rows.Close()
+
+	digest := make(map[string][]TrendingSound)
+	for rows.Next() {
+		var (
+			niche   string
+			payload string
+			sound   TrendingSound
+		)
+		if err := rows.Scan(&niche, &payload); err != nil {
+			return nil, fmt.Errorf("failed to scan digest sound: %w", err)
+		}
+		if err := json.Unmarshal([]byte(payload), &sound); err != nil {
+			return nil, fmt.Errorf("failed to decode digest sound: %w", err)
+		}
+		digest[niche] = append(digest[niche], sound)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get digest sounds: %w", err)
+	}
+
+	for _, sounds := range digest {
+		sort.SliceStable(sounds, func(i, j int) bool {
+			return sounds[i].GrowthPercent > sounds[j].GrowthPercent
+		})
+	}
+	return digest, nil
+}
+
+// MarkDigestSent records a delivered digest: it clears sounds queued up to sentAt and
+// remembers sentAt as the user's last digest time
+func (s *SQLiteStorage) MarkDigestSent(telegramID int64, sentAt time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM digest_items WHERE telegram_id = ? AND queued_at <= ?", telegramID, sentAt); err != nil {
+		return fmt.Errorf("failed to clear digest sounds: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE users SET last_digest_at = ? WHERE telegram_id = ?", sentAt, telegramID); err != nil {
+		return fmt.Errorf("failed to set last digest time: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit digest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// digestTitles returns the queued titles per niche, in digest order
+func digestTitles(digest map[string][]TrendingSound) map[string][]string {
+	titles := make(map[string][]string)
+	for niche, sounds := range digest {
+		for _, ts := range sounds {
+			titles[niche] = append(titles[niche], ts.Title)
+		}
+	}
+	return titles
+}
+
+func TestSetDigestFrequency(t *testing.T) {
+	s := newTestStorage(t)
+	if err := s.CreateUser(1); err != nil {
+		t.Fatalf("CreateUser() error: %v", err)
+	}
+
+	for _, want := range []string{DigestEvery, DigestDaily, DigestTwiceDaily} {
+		if want != DigestEvery {
+			if err := s.SetDigestFrequency(1, want); err != nil {
+				t.Fatalf("SetDigestFrequency(%s) error: %v", want, err)
+			}
+		}
+		user, err := s.GetUser(1)
+		if err != nil {
+			t.Fatalf("GetUser() error: %v", err)
+		}
+		if user.DigestFrequency != want {
+			t.Errorf("DigestFrequency = %q, want %q", user.DigestFrequency, want)
+		}
+	}
+}
+
+func TestQueueDigestSounds(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now()
+	a := addSound(t, s, "fitness", "a", 1000)
+	b := addSound(t, s, "fitness", "b", 1000)
+	c := addSound(t, s, "gaming", "c", 1000)
+
+	queue := func(telegramID int64, niche string, at time.Time, sounds ...TrendingSound) {
+		t.Helper()
+		if err := s.QueueDigestSounds(telegramID, niche, sounds, at); err != nil {
+			t.Fatalf("QueueDigestSounds() error: %v", err)
+		}
+	}
+	queue(1, "fitness", now.Add(-2*time.Hour), TrendingSound{Sound: *a, GrowthPercent: 200}, TrendingSound{Sound: *b, GrowthPercent: 300})
+	// a is detected again with more growth: the digest keeps one entry with the latest metrics
+	queue(1, "fitness", now.Add(-time.Hour), TrendingSound{Sound: *a, GrowthPercent: 500})
+	queue(1, "gaming", now.Add(-time.Hour), TrendingSound{Sound: *c, GrowthPercent: 180})
+	queue(2, "fitness", now, TrendingSound{Sound: *b, GrowthPercent: 300})
+
+	digest, err := s.GetDigestSounds(1)
+	if err != nil {
+		t.Fatalf("GetDigestSounds() error: %v", err)
+	}
+	want := map[string][]string{"fitness": {"a", "b"}, "gaming": {"c"}}
+	if got := digestTitles(digest); !reflect.DeepEqual(got, want) {
+		t.Fatalf("digest = %v, want %v (highest growth first)", got, want)
+	}
+	if got := digest["fitness"][0].GrowthPercent; got != 500 {
+		t.Errorf("a growth = %v, want the latest 500", got)
+	}
+
+	empty, err := s.GetDigestSounds(3)
+	if err != nil {
+		t.Fatalf("GetDigestSounds() error: %v", err)
+	}
+	if len(empty) != 0 {
+		t.Errorf("digest of a user with nothing queued = %v, want empty", empty)
+	}
+}
+
+func TestMarkDigestSent(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now()
+	for _, id := range []int64{1, 2} {
+		if err := s.CreateUser(id); err != nil {
+			t.Fatalf("CreateUser() error: %v", err)
+		}
+	}
+	early := addSound(t, s, "fitness", "early", 1000)
+	late := addSound(t, s, "fitness", "late", 1000)
+
+	if err := s.QueueDigestSounds(1, "fitness", []TrendingSound{{Sound: *early}}, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("QueueDigestSounds() error: %v", err)
+	}
+	// Queued by a cycle that ran while the digest was being sent
+	if err := s.QueueDigestSounds(1, "fitness", []TrendingSound{{Sound: *late}}, now.Add(time.Minute)); err != nil {
+		t.Fatalf("QueueDigestSounds() error: %v", err)
+	}
+	if err := s.QueueDigestSounds(2, "fitness", []TrendingSound{{Sound: *early}}, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("QueueDigestSounds() error: %v", err)
+	}
+
+	if err := s.MarkDigestSent(1, now); err != nil {
+		t.Fatalf("MarkDigestSent() error: %v", err)
+	}
+
+	digest, err := s.GetDigestSounds(1)
+	if err != nil {
+		t.Fatalf("GetDigestSounds() error: %v", err)
+	}
+	if got, want := digestTitles(digest), map[string][]string{"fitness": {"late"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("digest after sending = %v, want %v", got, want)
+	}
+	if other, _ := s.GetDigestSounds(2); len(other["fitness"]) != 1 {
+		t.Errorf("other user's digest = %v, want it untouched", digestTitles(other))
+	}
+
+	user, err := s.GetUser(1)
+	if err != nil {
+		t.Fatalf("GetUser() error: %v", err)
+	}
+	if !user.LastDigestAt.Equal(now) {
+		t.Errorf("LastDigestAt = %s, want %s", user.LastDigestAt, now)
+	}
+}
+
+func TestMergeUsersDigestItems(t *testing.T) {
+	s := newTestStorage(t)
+	for _, id := range []int64{1, 2} {
+		if err := s.CreateUser(id); err != nil {
+			t.Fatalf("CreateUser() error: %v", err)
+		}
+	}
+	now := time.Now()
+	shared := addSound(t, s, "fitness", "shared", 1000)
+	only := addSound(t, s, "fitness", "only", 1000)
+
+	// Both queued shared: the kept user's entry wins; the duplicate's other sound moves over
+	if err := s.QueueDigestSounds(1, "fitness", []TrendingSound{{Sound: *shared, GrowthPercent: 200}}, now); err != nil {
+		t.Fatalf("QueueDigestSounds() error: %v", err)
+	}
+	if err := s.QueueDigestSounds(2, "fitness", []TrendingSound{{Sound: *shared, GrowthPercent: 900}, {Sound: *only, GrowthPercent: 300}}, now); err != nil {
+		t.Fatalf("QueueDigestSounds() error: %v", err)
+	}
+
+	if err := s.MergeUsers(1, 2); err != nil {
+		t.Fatalf("MergeUsers() error: %v", err)
+	}
+
+	digest, err := s.GetDigestSounds(1)
+	if err != nil {
+		t.Fatalf("GetDigestSounds() error: %v", err)
+	}
+	if got, want := digestTitles(digest), map[string][]string{"fitness": {"only", "shared"}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("merged digest = %v, want %v", got, want)
+	}
+	if got := digest["fitness"][1].GrowthPercent; got != 200 {
+		t.Errorf("shared growth = %v, want the kept user's 200", got)
+	}
+	if left, _ := s.GetDigestSounds(2); len(left) != 0 {
+		t.Errorf("duplicate's digest = %v, want it cleared", digestTitles(left))
+	}
+}
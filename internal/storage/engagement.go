@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// NicheEngagement is the number of engagement events a user has for a niche
+type NicheEngagement struct {
+	Category string `json:"category"`
+	Count    int    `json:"count"`
+}
+
+// RecordNicheEngagement records that a user engaged with a niche
+func (s *SQLiteStorage) RecordNicheEngagement(telegramID int64, category string) error {
+	query := `
+		INSERT INTO niche_engagement (telegram_id, category, created_at)
+		VALUES (?, ?, ?)
+	`
+	_, err := s.db.Exec(query, telegramID, category, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record niche engagement: %w", err)
+	}
+	return nil
+}
+
+// RecordAlertClick records engagement with a niche from a tracked alert link. Each (user, sound, niche)
+// link counts once; it reports false when the link was already opened.
+func (s *SQLiteStorage) RecordAlertClick(telegramID, soundID int64, category string) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.Exec(`
+		INSERT OR IGNORE INTO alert_clicks (telegram_id, sound_id, category, created_at)
+		VALUES (?, ?, ?, ?)
+	`, telegramID, soundID, category, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to record alert click: %w", err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check alert click: %w", err)
+	}
+	if inserted == 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO niche_engagement (telegram_id, category, created_at)
+		VALUES (?, ?, ?)
+	`, telegramID, category, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to record niche engagement: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit alert click: %w", err)
+	}
+	return true, nil
+}
+
+// GetUserNicheEngagement returns a user's engagement counts per niche, most engaged first
+func (s *SQLiteStorage) GetUserNicheEngagement(telegramID int64) ([]NicheEngagement, error) {
+	query := `
+		SELECT category, COUNT(*) AS cnt
+		FROM niche_engagement
+		WHERE telegram_id = ?
+		GROUP BY category
+		ORDER BY cnt DESC, category ASC
+	`
+	rows, err := s.db.Query(query, telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get niche engagement: %w", err)
+	}
+	defer rows.Close()
+
+	var engagement []NicheEngagement
+	for rows.Next() {
+		var e NicheEngagement
+		if err := rows.Scan(&e.Category, &e.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan niche engagement: %w", err)
+		}
+		engagement = append(engagement, e)
+	}
+
+	return engagement, nil
+}
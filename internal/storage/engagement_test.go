@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetUserNicheEngagement(t *testing.T) {
+	tests := []struct {
+		name   string
+		events map[int64][]string
+		user   int64
+		want   []NicheEngagement
+	}{
+		{
+			name: "no engagement",
+			user: 1,
+			want: nil,
+		},
+		{
+			name:   "counted per niche, most engaged first",
+			events: map[int64][]string{1: {"gaming", "fitness", "gaming", "dance", "gaming", "fitness"}},
+			user:   1,
+			want:   []NicheEngagement{{"gaming", 3}, {"fitness", 2}, {"dance", 1}},
+		},
+		{
+			name:   "ties ordered by niche",
+			events: map[int64][]string{1: {"gaming", "comedy"}},
+			user:   1,
+			want:   []NicheEngagement{{"comedy", 1}, {"gaming", 1}},
+		},
+		{
+			name:   "other users don't count",
+			events: map[int64][]string{1: {"fitness"}, 2: {"gaming", "gaming"}},
+			user:   1,
+			want:   []NicheEngagement{{"fitness", 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(t)
+			for user, categories := range tt.events {
+				for _, category := range categories {
+					if err := s.RecordNicheEngagement(user, category); err != nil {
+						t.Fatalf("RecordNicheEngagement() error: %v", err)
+					}
+				}
+			}
+
+			got, err := s.GetUserNicheEngagement(tt.user)
+			if err != nil {
+				t.Fatalf("GetUserNicheEngagement() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetUserNicheEngagement() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordAlertClick(t *testing.T) {
+	s := newTestStorage(t)
+
+	clicks := []struct {
+		name       string
+		telegramID int64
+		soundID    int64
+		category   string
+		want       bool
+	}{
+		{name: "first open", telegramID: 1, soundID: 5, category: "fitness", want: true},
+		{name: "same link again", telegramID: 1, soundID: 5, category: "fitness", want: false},
+		{name: "same sound in another niche", telegramID: 1, soundID: 5, category: "gaming", want: true},
+		{name: "another sound", telegramID: 1, soundID: 6, category: "fitness", want: true},
+		{name: "another user", telegramID: 2, soundID: 5, category: "fitness", want: true},
+	}
+	for _, c := range clicks {
+		got, err := s.RecordAlertClick(c.telegramID, c.soundID, c.category)
+		if err != nil {
+			t.Fatalf("%s: RecordAlertClick() error: %v", c.name, err)
+		}
+		if got != c.want {
+			t.Errorf("%s: RecordAlertClick() = %v, want %v", c.name, got, c.want)
+		}
+	}
+
+	got, err := s.GetUserNicheEngagement(1)
+	if err != nil {
+		t.Fatalf("GetUserNicheEngagement() error: %v", err)
+	}
+	want := []NicheEngagement{{"fitness", 2}, {"gaming", 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetUserNicheEngagement() = %v, want %v", got, want)
+	}
+}
+
+func TestMergeUsersAlertClicks(t *testing.T) {
+	s := newTestStorage(t)
+	for _, id := range []int64{1, 2} {
+		if err := s.CreateUser(id); err != nil {
+			t.Fatalf("CreateUser() error: %v", err)
+		}
+	}
+	if _, err := s.RecordAlertClick(2, 5, "fitness"); err != nil {
+		t.Fatalf("RecordAlertClick() error: %v", err)
+	}
+
+	if err := s.MergeUsers(1, 2); err != nil {
+		t.Fatalf("MergeUsers() error: %v", err)
+	}
+
+	// The duplicate's opened link now belongs to the kept user, so opening it again doesn't count
+	counted, err := s.RecordAlertClick(1, 5, "fitness")
+	if err != nil {
+		t.Fatalf("RecordAlertClick() error: %v", err)
+	}
+	if counted {
+		t.Error("RecordAlertClick() counted a link the merged duplicate had already opened")
+	}
+}
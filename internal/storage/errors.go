@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Sentinel errors returned by storage operations. Check them with errors.Is.
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrSoundNotFound   = errors.New("sound not found")
+	ErrDuplicate       = errors.New("duplicate record")
+	ErrEmptyCategory   = errors.New("sound has no category")
+	ErrAlertNotFound   = errors.New("alert not found")
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// isUniqueViolation reports whether err is a SQLite UNIQUE or PRIMARY KEY constraint failure
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique ||
+			sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(s *SQLiteStorage) error
+		want error
+	}{
+		{
+			name: "missing user",
+			run:  func(s *SQLiteStorage) error { _, err := s.GetUser(404); return err },
+			want: ErrUserNotFound,
+		},
+		{
+			name: "existing user",
+			run:  func(s *SQLiteStorage) error { _, err := s.GetUser(1); return err },
+			want: nil,
+		},
+		{
+			name: "duplicate user",
+			run:  func(s *SQLiteStorage) error { return s.CreateUser(1) },
+			want: ErrDuplicate,
+		},
+		{
+			name: "blocking a missing user",
+			run:  func(s *SQLiteStorage) error { return s.SetBlocked(404, true) },
+			want: ErrUserNotFound,
+		},
+		{
+			name: "missing sound by id",
+			run:  func(s *SQLiteStorage) error { _, err := s.GetSoundByID(404); return err },
+			want: ErrSoundNotFound,
+		},
+		{
+			name: "missing sound by url",
+			run: func(s *SQLiteStorage) error {
+				_, err := s.GetSoundByURL("https://www.tiktok.com/music/none-1")
+				return err
+			},
+			want: ErrSoundNotFound,
+		},
+		{
+			name: "missing sound by title and author",
+			run:  func(s *SQLiteStorage) error { _, err := s.GetSoundByTitleAuthor("none", "nobody"); return err },
+			want: ErrSoundNotFound,
+		},
+		{
+			name: "empty music id",
+			run:  func(s *SQLiteStorage) error { _, err := s.GetSoundByMusicID(""); return err },
+			want: ErrSoundNotFound,
+		},
+		{
+			name: "duplicate sound url",
+			run: func(s *SQLiteStorage) error {
+				return s.SaveSound(&Sound{Title: "copy", URL: "https://www.tiktok.com/music/existing-1", Category: "fitness"})
+			},
+			want: ErrDuplicate,
+		},
+		{
+			name: "duplicate follow",
+			run:  func(s *SQLiteStorage) error { return s.FollowSound(1, 1, 0) },
+			want: ErrDuplicate,
+		},
+		{
+			name: "duplicate nomination",
+			run: func(s *SQLiteStorage) error {
+				return s.SaveNomination(1, "https://www.tiktok.com/music/nominated-2?lang=en", "fitness")
+			},
+			want: ErrDuplicate,
+		},
+		{
+			name: "missing last alert",
+			run:  func(s *SQLiteStorage) error { _, err := s.GetLastAlert(1, "gaming"); return err },
+			want: ErrAlertNotFound,
+		},
+		{
+			name: "missing session",
+			run:  func(s *SQLiteStorage) error { _, err := s.GetSession(1, 0); return err },
+			want: ErrSessionNotFound,
+		},
+		{
+			name: "empty category",
+			run: func(s *SQLiteStorage) error {
+				return SaveSoundWithHistory(s, &Sound{Title: "x", URL: "https://www.tiktok.com/music/x-3"}, DedupByURL)
+			},
+			want: ErrEmptyCategory,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(t)
+			if err := s.CreateUser(1); err != nil {
+				t.Fatalf("CreateUser() error: %v", err)
+			}
+			sound := &Sound{Title: "existing", URL: "https://www.tiktok.com/music/existing-1", Category: "fitness"}
+			if err := s.SaveSound(sound); err != nil {
+				t.Fatalf("SaveSound() error: %v", err)
+			}
+			if err := s.FollowSound(1, sound.ID, 0); err != nil {
+				t.Fatalf("FollowSound() error: %v", err)
+			}
+			if err := s.SaveNomination(1, "https://www.tiktok.com/music/nominated-2", "fitness"); err != nil {
+				t.Fatalf("SaveNomination() error: %v", err)
+			}
+
+			err := tt.run(s)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// IsFeatureEnabled reports whether a feature flag is on. Unknown flags are off.
+func (s *SQLiteStorage) IsFeatureEnabled(name string) (bool, error) {
+	var enabled bool
+	err := s.db.QueryRow("SELECT enabled FROM feature_flags WHERE name = ?", name).Scan(&enabled)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get feature flag %s: %w", name, err)
+	}
+	return enabled, nil
+}
+
+// SetFeature turns a feature flag on or off, creating it if needed
+func (s *SQLiteStorage) SetFeature(name string, enabled bool) error {
+	query := `
+		INSERT INTO feature_flags (name, enabled, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET enabled = excluded.enabled, updated_at = excluded.updated_at
+	`
+	_, err := s.db.Exec(query, name, enabled, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to set feature flag %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package storage
+
+import "testing"
+
+func TestFeatureFlags(t *testing.T) {
+	s := newTestStorage(t)
+
+	// init.sql seeds cards on and cross_niche off; unknown flags are off
+	for name, want := range map[string]bool{"cards": true, "cross_niche": false, "unknown": false} {
+		got, err := s.IsFeatureEnabled(name)
+		if err != nil {
+			t.Fatalf("IsFeatureEnabled(%s) error: %v", name, err)
+		}
+		if got != want {
+			t.Errorf("IsFeatureEnabled(%s) = %v, want %v", name, got, want)
+		}
+	}
+
+	steps := []struct {
+		name    string
+		enabled bool
+	}{
+		{name: "cards", enabled: false},
+		{name: "cross_niche", enabled: true},
+		{name: "forecasts", enabled: true}, // Flags are created on first set
+		{name: "forecasts", enabled: false},
+	}
+	for i, step := range steps {
+		if err := s.SetFeature(step.name, step.enabled); err != nil {
+			t.Fatalf("step %d: SetFeature() error: %v", i, err)
+		}
+		got, err := s.IsFeatureEnabled(step.name)
+		if err != nil {
+			t.Fatalf("step %d: IsFeatureEnabled() error: %v", i, err)
+		}
+		if got != step.enabled {
+			t.Errorf("step %d: IsFeatureEnabled(%s) = %v, want %v", i, step.name, got, step.enabled)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// FollowedSound is a user's follow of a sound together with the sound's current data
+type FollowedSound struct {
+	TelegramID    int64 `json:"telegram_id"`
+	LastMilestone int64 `json:"last_milestone"`
+	Sound         Sound `json:"sound"`
+}
+
+// FollowSound makes a user follow a sound. lastMilestone is the highest milestone
+// the sound has already passed, so it isn't notified again.
+func (s *SQLiteStorage) FollowSound(telegramID, soundID, lastMilestone int64) error {
+	query := `
+		INSERT INTO followed_sounds (telegram_id, sound_id, last_milestone, created_at)
+		VALUES (?, ?, ?, ?)
+	`
+	_, err := s.db.Exec(query, telegramID, soundID, lastMilestone, time.Now())
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	if err != nil {
+		return fmt.Errorf("failed to follow sound: %w", err)
+	}
+	return nil
+}
+
+// GetFollowedSounds returns all follows across users with current sound data
+func (s *SQLiteStorage) GetFollowedSounds() ([]FollowedSound, error) {
+	query := `
+		SELECT f.telegram_id, f.last_milestone,
+			s.id, s.title, s.author, s.url, s.uses_count, s.category, s.created_at, s.updated_at
+		FROM followed_sounds f
+		JOIN sounds s ON s.id = f.sound_id
+	`
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get followed sounds: %w", err)
+	}
+	defer rows.Close()
+
+	var follows []FollowedSound
+	for rows.Next() {
+		var f FollowedSound
+		err := rows.Scan(
+			&f.TelegramID,
+			&f.LastMilestone,
+			&f.Sound.ID,
+			&f.Sound.Title,
+			&f.Sound.Author,
+			&f.Sound.URL,
+			&f.Sound.UsesCount,
+			&f.Sound.Category,
+			&f.Sound.CreatedAt,
+			&f.Sound.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan followed sound: %w", err)
+		}
+		follows = append(follows, f)
+	}
+
+	return follows, nil
+}
+
+// SetFollowMilestone records the highest milestone notified for a follow
+func (s *SQLiteStorage) SetFollowMilestone(telegramID, soundID, milestone int64) error {
+	query := `
+		UPDATE followed_sounds
+		SET last_milestone = ?
+		WHERE telegram_id = ? AND sound_id = ?
+	`
+	_, err := s.db.Exec(query, milestone, telegramID, soundID)
+	if err != nil {
+		return fmt.Errorf("failed to set follow milestone: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// Graduate is a sound whose uses crossed the trending ceiling, i.e. a trend that made it
+type Graduate struct {
+	Sound      Sound     `json:"sound"`
+	UsesBefore int64     `json:"uses_before"` // Last recorded uses before the crossing
+	CrossedAt  time.Time `json:"crossed_at"`  // First history sample above the ceiling
+}
+
+// GetGraduates returns sounds whose history first went above maxUses at or after since, having
+// been recorded at or below it before, most recent crossing first. An empty category means all.
+func (s *SQLiteStorage) GetGraduates(category string, maxUses int64, since time.Time, limit int) ([]Graduate, error) {
+	// The crossing sample is joined directly so the driver parses its timestamp, unlike MIN()
+	query := `
+		SELECT s.id, s.title, s.author, s.url, s.uses_count, s.category, s.created_at, s.updated_at,
+			h.recorded_at,
+			(SELECT b.uses_count FROM sound_history b
+			 WHERE b.sound_id = s.id AND b.recorded_at < h.recorded_at
+			 ORDER BY b.recorded_at DESC LIMIT 1)
+		FROM sounds s
+		JOIN sound_history h ON h.sound_id = s.id
+		WHERE h.uses_count > ? AND h.recorded_at >= ? AND (? = '' OR s.category = ?)
+		  AND NOT EXISTS (
+			SELECT 1 FROM sound_history e
+			WHERE e.sound_id = s.id AND e.uses_count > ? AND e.recorded_at < h.recorded_at
+		  )
+		  AND EXISTS (
+			SELECT 1 FROM sound_history b
+			WHERE b.sound_id = s.id AND b.recorded_at < h.recorded_at
+		  )
+		ORDER BY h.recorded_at DESC
+		LIMIT ?
+	`
+	rows, err := s.readDB.Query(query, maxUses, since, category, category, maxUses, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get graduates: %w", err)
+	}
+	defer rows.Close()
+
+	var graduates []Graduate
+	for rows.Next() {
+		var g Graduate
+		err := rows.Scan(
+			&g.Sound.ID,
+			&g.Sound.Title,
+			&g.Sound.Author,
+			&g.Sound.URL,
+			&g.Sound.UsesCount,
+			&g.Sound.Category,
+			&g.Sound.CreatedAt,
+			&g.Sound.UpdatedAt,
+			&g.CrossedAt,
+			&g.UsesBefore,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan graduate: %w", err)
+		}
+		graduates = append(graduates, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get graduates: %w", err)
+	}
+
+	return graduates, nil
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetGraduates(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now()
+	day := 24 * time.Hour
+
+	graduate := addSound(t, s, "fitness", "graduate", 40000)
+	addHistory(t, s, graduate.ID, 20000, now.Add(-3*day))
+	addHistory(t, s, graduate.ID, 25000, now.Add(-2*day))
+	addHistory(t, s, graduate.ID, 35000, now.Add(-day))
+	addHistory(t, s, graduate.ID, 40000, now)
+
+	gaming := addSound(t, s, "gaming", "gaming graduate", 32000)
+	addHistory(t, s, gaming.ID, 28000, now.Add(-3*day))
+	addHistory(t, s, gaming.ID, 32000, now.Add(-2*day))
+
+	// Crossed before the window
+	old := addSound(t, s, "fitness", "old graduate", 60000)
+	addHistory(t, s, old.ID, 20000, now.Add(-12*day))
+	addHistory(t, s, old.ID, 31000, now.Add(-10*day))
+	addHistory(t, s, old.ID, 60000, now.Add(-day))
+
+	// Never tracked below the ceiling
+	big := addSound(t, s, "fitness", "born big", 50000)
+	addHistory(t, s, big.ID, 50000, now.Add(-2*day))
+
+	// Still in the trending range
+	trending := addSound(t, s, "fitness", "still trending", 25000)
+	addHistory(t, s, trending.ID, 10000, now.Add(-2*day))
+	addHistory(t, s, trending.ID, 25000, now)
+
+	tests := []struct {
+		name     string
+		category string
+		limit    int
+		want     []string
+	}{
+		{name: "all niches, latest crossing first", limit: 10, want: []string{"graduate", "gaming graduate"}},
+		{name: "one niche", category: "fitness", limit: 10, want: []string{"graduate"}},
+		{name: "limit", limit: 1, want: []string{"graduate"}},
+		{name: "niche without graduates", category: "dance", limit: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graduates, err := s.GetGraduates(tt.category, 30000, now.Add(-7*day), tt.limit)
+			if err != nil {
+				t.Fatalf("GetGraduates() error: %v", err)
+			}
+			var got []string
+			for _, g := range graduates {
+				got = append(got, g.Sound.Title)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetGraduates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	graduates, _ := s.GetGraduates("fitness", 30000, now.Add(-7*day), 10)
+	if len(graduates) != 1 {
+		t.Fatalf("got %d fitness graduates, want 1", len(graduates))
+	}
+	if g := graduates[0]; g.UsesBefore != 25000 || g.Sound.UsesCount != 40000 || !g.CrossedAt.Equal(now.Add(-day)) {
+		t.Errorf("graduate = %+v, want 25000 before crossing a day ago, 40000 now", g)
+	}
+}
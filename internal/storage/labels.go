@@ -0,0 +1,45 @@
+package storage
+
+import "fmt"
+
+// SetNicheLabel stores a user's custom name for a niche. An empty label removes it.
+func (s *SQLiteStorage) SetNicheLabel(telegramID int64, category, label string) error {
+	if label == "" {
+		_, err := s.db.Exec("DELETE FROM user_niche_labels WHERE telegram_id = ? AND category = ?", telegramID, category)
+		if err != nil {
+			return fmt.Errorf("failed to remove niche label: %w", err)
+		}
+		return nil
+	}
+
+	query := `
+		INSERT INTO user_niche_labels (telegram_id, category, label)
+		VALUES (?, ?, ?)
+		ON CONFLICT(telegram_id, category) DO UPDATE SET label = excluded.label
+	`
+	_, err := s.db.Exec(query, telegramID, category, label)
+	if err != nil {
+		return fmt.Errorf("failed to set niche label: %w", err)
+	}
+	return nil
+}
+
+// GetNicheLabels returns a user's custom niche names keyed by category
+func (s *SQLiteStorage) GetNicheLabels(telegramID int64) (map[string]string, error) {
+	rows, err := s.db.Query("SELECT category, label FROM user_niche_labels WHERE telegram_id = ?", telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get niche labels: %w", err)
+	}
+	defer rows.Close()
+
+	labels := make(map[string]string)
+	for rows.Next() {
+		var category, label string
+		if err := rows.Scan(&category, &label); err != nil {
+			return nil, fmt.Errorf("failed to scan niche label: %w", err)
+		}
+		labels[category] = label
+	}
+
+	return labels, nil
+}
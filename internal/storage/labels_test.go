@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNicheLabels(t *testing.T) {
+	s := newTestStorage(t)
+
+	steps := []struct {
+		telegramID int64
+		category   string
+		label      string
+		want       map[string]string
+	}{
+		{telegramID: 1, category: "fitness", label: "Gym", want: map[string]string{"fitness": "Gym"}},
+		{telegramID: 1, category: "gaming", label: "Games", want: map[string]string{"fitness": "Gym", "gaming": "Games"}},
+		{telegramID: 1, category: "fitness", label: "Lifting", want: map[string]string{"fitness": "Lifting", "gaming": "Games"}},
+		{telegramID: 1, category: "gaming", label: "", want: map[string]string{"fitness": "Lifting"}},
+		{telegramID: 2, category: "fitness", label: "Workout", want: map[string]string{"fitness": "Workout"}},
+	}
+	for i, step := range steps {
+		if err := s.SetNicheLabel(step.telegramID, step.category, step.label); err != nil {
+			t.Fatalf("step %d: SetNicheLabel() error: %v", i, err)
+		}
+		got, err := s.GetNicheLabels(step.telegramID)
+		if err != nil {
+			t.Fatalf("step %d: GetNicheLabels() error: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("step %d: labels = %v, want %v", i, got, step.want)
+		}
+	}
+
+	// User 2's label does not leak into user 1's
+	if got, _ := s.GetNicheLabels(1); got["fitness"] != "Lifting" {
+		t.Errorf("user 1 fitness label = %q, want Lifting", got["fitness"])
+	}
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrSameUser is returned when asked to merge a user into itself
+var ErrSameUser = errors.New("cannot merge a user into itself")
+
+// userRelatedTables lists tables keyed by telegram_id whose rows follow a user on merge
+var userRelatedTables = []string{
+	"niche_engagement",
+	"alert_clicks",
+	"followed_sounds",
+	"last_alerts",
+	"user_niche_labels",
+	"alert_latencies",
+	"nominations",
+	"user_niche_settings",
+	"sessions",
+	"digest_items",
+	"sent_alerts",
+	"muted_niches",
+}
+
+// MergeUsers folds mergeID into keepID and deletes mergeID.
+// Niches are unioned, premium and beta are OR-ed, and related rows move to keepID.
+// When both users have a row with the same key, keepID's row wins.
+func (s *SQLiteStorage) MergeUsers(keepID, mergeID int64) error {
+	if keepID == mergeID {
+		return ErrSameUser
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	keep, keepExpiry, err := getUserForMerge(tx, keepID)
+	if err != nil {
+		return err
+	}
+	merge, mergeExpiry, err := getUserForMerge(tx, mergeID)
+	if err != nil {
+		return err
+	}
+
+	niches, err := mergeNiches(keep.Niches, merge.Niches)
+	if err != nil {
+		return err
+	}
+	isPremium := keep.IsPremium || merge.IsPremium
+	expiry := mergePremiumExpiry(keep.IsPremium, keepExpiry, merge.IsPremium, mergeExpiry)
+
+	query := `
+		UPDATE users
+		SET niches = ?, is_premium = ?, premium_expires_at = ?, beta_enrolled = ?
+		WHERE telegram_id = ?
+	`
+	if _, err := tx.Exec(query, niches, isPremium, expiry, keep.BetaEnrolled || merge.BetaEnrolled, keepID); err != nil {
+		return fmt.Errorf("failed to update merged user: %w", err)
+	}
+
+	for _, table := range userRelatedTables {
+		// OR IGNORE leaves rows that would collide with keepID's, which are then dropped
+		if _, err := tx.Exec(fmt.Sprintf("UPDATE OR IGNORE %s SET telegram_id = ? WHERE telegram_id = ?", table), keepID, mergeID); err != nil {
+			return fmt.Errorf("failed to move %s rows: %w", table, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE telegram_id = ?", table), mergeID); err != nil {
+			return fmt.Errorf("failed to clear %s rows: %w", table, err)
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM users WHERE telegram_id = ?", mergeID); err != nil {
+		return fmt.Errorf("failed to delete merged user: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit user merge: %w", err)
+	}
+	return nil
+}
+
+// getUserForMerge loads a user and its premium expiry inside the merge transaction
+func getUserForMerge(tx *sql.Tx, telegramID int64) (*User, sql.NullTime, error) {
+	user := &User{}
+	var expiry sql.NullTime
+	err := scanUser(tx.QueryRow("SELECT "+userColumns+" FROM users WHERE telegram_id = ?", telegramID), user)
+	if err == sql.ErrNoRows {
+		return nil, expiry, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, expiry, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := tx.QueryRow("SELECT premium_expires_at FROM users WHERE telegram_id = ?", telegramID).Scan(&expiry); err != nil {
+		return nil, expiry, fmt.Errorf("failed to get premium expiry: %w", err)
+	}
+	return user, expiry, nil
+}
+
+// mergeNiches returns the JSON union of two niche lists, keeping the first list's order
+func mergeNiches(keep, merge string) (string, error) {
+	var union []string
+	seen := make(map[string]bool)
+	for _, raw := range []string{keep, merge} {
+		if raw == "" {
+			continue
+		}
+		var niches []string
+		if err := json.Unmarshal([]byte(raw), &niches); err != nil {
+			return "", fmt.Errorf("failed to parse niches %q: %w", raw, err)
+		}
+		for _, niche := range niches {
+			if !seen[niche] {
+				seen[niche] = true
+				union = append(union, niche)
+			}
+		}
+	}
+
+	if len(union) == 0 {
+		return keep, nil
+	}
+	data, err := json.Marshal(union)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode niches: %w", err)
+	}
+	return string(data), nil
+}
+
+// mergePremiumExpiry picks the expiry giving the merged user the longest premium.
+// A premium user without an expiry never expires, so that wins over any date.
+func mergePremiumExpiry(keepPremium bool, keepExpiry sql.NullTime, mergePremium bool, mergeExpiry sql.NullTime) sql.NullTime {
+	switch {
+	case keepPremium && !keepExpiry.Valid, mergePremium && !mergeExpiry.Valid:
+		return sql.NullTime{}
+	case !mergePremium:
+		return keepExpiry
+	case !keepPremium:
+		return mergeExpiry
+	case mergeExpiry.Time.After(keepExpiry.Time):
+		return mergeExpiry
+	default:
+		return keepExpiry
+	}
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMergeUsers(t *testing.T) {
+	s := newTestStorage(t)
+	for _, id := range []int64{1, 2} {
+		if err := s.CreateUser(id); err != nil {
+			t.Fatalf("CreateUser() error: %v", err)
+		}
+	}
+	if err := s.UpdateUserNiches(1, `["fitness","gaming"]`); err != nil {
+		t.Fatalf("UpdateUserNiches() error: %v", err)
+	}
+	if err := s.UpdateUserNiches(2, `["gaming","beauty"]`); err != nil {
+		t.Fatalf("UpdateUserNiches() error: %v", err)
+	}
+	if err := s.SetPremium(2, true); err != nil {
+		t.Fatalf("SetPremium() error: %v", err)
+	}
+	if err := s.SetBetaEnrolled(1, true); err != nil {
+		t.Fatalf("SetBetaEnrolled() error: %v", err)
+	}
+	// Both label fitness: the kept user's label wins; the duplicate's beauty label moves over
+	for _, l := range []struct {
+		id              int64
+		category, label string
+	}{{1, "fitness", "Gym"}, {2, "fitness", "Workout"}, {2, "beauty", "Glow"}} {
+		if err := s.SetNicheLabel(l.id, l.category, l.label); err != nil {
+			t.Fatalf("SetNicheLabel() error: %v", err)
+		}
+	}
+
+	if err := s.MergeUsers(1, 2); err != nil {
+		t.Fatalf("MergeUsers() error: %v", err)
+	}
+
+	user, err := s.GetUser(1)
+	if err != nil {
+		t.Fatalf("GetUser() error: %v", err)
+	}
+	if user.Niches != `["fitness","gaming","beauty"]` || !user.IsPremium || !user.BetaEnrolled {
+		t.Errorf("merged user = %+v, want unioned niches, premium and beta", user)
+	}
+	labels, err := s.GetNicheLabels(1)
+	if err != nil {
+		t.Fatalf("GetNicheLabels() error: %v", err)
+	}
+	if want := map[string]string{"fitness": "Gym", "beauty": "Glow"}; !reflect.DeepEqual(labels, want) {
+		t.Errorf("labels = %v, want %v", labels, want)
+	}
+
+	if _, err := s.GetUser(2); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("GetUser(duplicate) error = %v, want ErrUserNotFound", err)
+	}
+	if labels, _ := s.GetNicheLabels(2); len(labels) != 0 {
+		t.Errorf("duplicate still has labels %v", labels)
+	}
+}
+
+func TestMergeUsersErrors(t *testing.T) {
+	s := newTestStorage(t)
+	if err := s.CreateUser(1); err != nil {
+		t.Fatalf("CreateUser() error: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		keepID, mergeID int64
+		wantErr         error
+	}{
+		{name: "same user", keepID: 1, mergeID: 1, wantErr: ErrSameUser},
+		{name: "unknown duplicate", keepID: 1, mergeID: 9, wantErr: ErrUserNotFound},
+		{name: "unknown kept user", keepID: 9, mergeID: 1, wantErr: ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.MergeUsers(tt.keepID, tt.mergeID); !errors.Is(err, tt.wantErr) {
+				t.Errorf("MergeUsers(%d, %d) error = %v, want %v", tt.keepID, tt.mergeID, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMergeNiches(t *testing.T) {
+	tests := []struct {
+		keep, merge string
+		want        string
+	}{
+		{`["fitness"]`, `["gaming"]`, `["fitness","gaming"]`},
+		{`["fitness","gaming"]`, `["gaming"]`, `["fitness","gaming"]`},
+		{"", `["beauty"]`, `["beauty"]`},
+		{"", "", ""},
+		{"[]", "[]", "[]"},
+	}
+
+	for _, tt := range tests {
+		got, err := mergeNiches(tt.keep, tt.merge)
+		if err != nil || got != tt.want {
+			t.Errorf("mergeNiches(%q, %q) = %q, %v, want %q", tt.keep, tt.merge, got, err, tt.want)
+		}
+	}
+
+	if _, err := mergeNiches("not json", ""); err == nil {
+		t.Error("mergeNiches() with invalid JSON succeeded, want an error")
+	}
+}
+
+func TestMergePremiumExpiry(t *testing.T) {
+	soon := sql.NullTime{Time: time.Now().Add(24 * time.Hour), Valid: true}
+	later := sql.NullTime{Time: time.Now().Add(72 * time.Hour), Valid: true}
+	never := sql.NullTime{}
+
+	tests := []struct {
+		name         string
+		keepPremium  bool
+		keepExpiry   sql.NullTime
+		mergePremium bool
+		mergeExpiry  sql.NullTime
+		want         sql.NullTime
+	}{
+		{name: "neither premium", keepExpiry: never, mergeExpiry: never, want: never},
+		{name: "only kept premium", keepPremium: true, keepExpiry: soon, mergeExpiry: later, want: soon},
+		{name: "only duplicate premium", keepExpiry: soon, mergePremium: true, mergeExpiry: later, want: later},
+		{name: "later expiry wins", keepPremium: true, keepExpiry: later, mergePremium: true, mergeExpiry: soon, want: later},
+		{name: "no expiry never expires", keepPremium: true, keepExpiry: soon, mergePremium: true, mergeExpiry: never, want: never},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergePremiumExpiry(tt.keepPremium, tt.keepExpiry, tt.mergePremium, tt.mergeExpiry)
+			if got != tt.want {
+				t.Errorf("mergePremiumExpiry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
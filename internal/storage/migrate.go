@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// columnMigrations lists columns added after the initial schema in migrations/init.sql.
+// They are applied on every Init and skipped when the column already exists.
+var columnMigrations = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"users", "alert_limit", "INTEGER NOT NULL DEFAULT 0"},
+	{"sound_history", "source", "TEXT NOT NULL DEFAULT ''"},
+	{"users", "alerts_today", "INTEGER NOT NULL DEFAULT 0"},
+	{"users", "alerts_day", "TEXT NOT NULL DEFAULT ''"},
+	{"users", "alert_mode", "TEXT NOT NULL DEFAULT 'growth'"},
+	{"users", "beta_enrolled", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"users", "premium_expires_at", "DATETIME"},
+	{"sounds", "music_id", "TEXT NOT NULL DEFAULT ''"},
+	{"users", "alert_sort", "TEXT NOT NULL DEFAULT 'growth'"},
+	{"sounds", "duration", "INTEGER NOT NULL DEFAULT 0"},
+	{"sounds", "genre", "TEXT NOT NULL DEFAULT ''"},
+	{"users", "min_growth", "REAL NOT NULL DEFAULT 0"},
+	{"users", "show_repeats", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"users", "alert_claimed_at", "DATETIME"},
+	{"users", "alert_format", "TEXT NOT NULL DEFAULT 'detailed'"},
+	{"users", "blocked", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"users", "min_uses", "INTEGER NOT NULL DEFAULT 0"},
+	{"users", "boost_until", "DATETIME"},
+	{"users", "digest_frequency", "TEXT NOT NULL DEFAULT 'every'"},
+	{"users", "last_digest_at", "DATETIME"},
+	{"users", "instant_alerts", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"sounds", "region", "TEXT NOT NULL DEFAULT 'global'"},
+	{"users", "region", "TEXT NOT NULL DEFAULT 'global'"},
+	{"users", "alert_released_at", "DATETIME"},
+	{"users", "timezone", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// migrateColumns adds any missing columns from columnMigrations
+func (s *SQLiteStorage) migrateColumns() error {
+	for _, m := range columnMigrations {
+		exists, err := s.columnExists(m.table, m.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", m.table, m.column, m.definition)
+		if _, err := s.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", m.table, m.column, err)
+		}
+	}
+
+	return nil
+}
+
+// columnExists checks whether a table has the given column
+func (s *SQLiteStorage) columnExists(table, column string) (bool, error) {
+	rows, err := s.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, fmt.Errorf("failed to read table info for %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    int
+			defaultVal interface{}
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &pk); err != nil {
+			return false, fmt.Errorf("failed to scan table info: %w", err)
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+
+	return false, rows.Err()
+}
+
+// SchemaFingerprint hashes every table and index definition in sqlite_master, so operators
+// can compare the deployed schema across environments after migrations
+func (s *SQLiteStorage) SchemaFingerprint() (string, error) {
+	query := `
+		SELECT type, name, COALESCE(sql, '')
+		FROM sqlite_master
+		WHERE type IN ('table', 'index') AND name NOT LIKE 'sqlite_%'
+		ORDER BY type, name
+	`
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return "", fmt.Errorf("failed to read schema: %w", err)
+	}
+	defer rows.Close()
+
+	h := sha256.New()
+	for rows.Next() {
+		var objType, name, definition string
+		if err := rows.Scan(&objType, &name, &definition); err != nil {
+			return "", fmt.Errorf("failed to scan schema entry: %w", err)
+		}
+		fmt.Fprintf(h, "%s|%s|%s\n", objType, name, definition)
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
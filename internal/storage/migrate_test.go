@@ -0,0 +1,87 @@
+package storage
+
+import "testing"
+
+func TestMigrateColumns(t *testing.T) {
+	s := newTestStorage(t)
+
+	// Init already ran once; running it again must skip the existing columns
+	if err := s.Init(); err != nil {
+		t.Fatalf("second Init() error: %v", err)
+	}
+
+	for _, m := range columnMigrations {
+		exists, err := s.columnExists(m.table, m.column)
+		if err != nil {
+			t.Fatalf("columnExists(%s, %s) error: %v", m.table, m.column, err)
+		}
+		if !exists {
+			t.Errorf("column %s.%s missing after Init", m.table, m.column)
+		}
+	}
+
+	if exists, _ := s.columnExists("users", "no_such_column"); exists {
+		t.Error("columnExists() reported a column that doesn't exist")
+	}
+}
+
+func TestSetAlertLimit(t *testing.T) {
+	s := newTestStorage(t)
+	if err := s.CreateUser(1); err != nil {
+		t.Fatalf("CreateUser() error: %v", err)
+	}
+
+	for _, limit := range []int{12, 0} {
+		if err := s.SetAlertLimit(1, limit); err != nil {
+			t.Fatalf("SetAlertLimit(%d) error: %v", limit, err)
+		}
+		user, err := s.GetUser(1)
+		if err != nil {
+			t.Fatalf("GetUser() error: %v", err)
+		}
+		if user.AlertLimit != limit {
+			t.Errorf("AlertLimit = %d, want %d", user.AlertLimit, limit)
+		}
+	}
+}
+
+func TestSchemaFingerprint(t *testing.T) {
+	a := newTestStorage(t)
+	b := newTestStorage(t)
+
+	fa, err := a.SchemaFingerprint()
+	if err != nil {
+		t.Fatalf("SchemaFingerprint() error: %v", err)
+	}
+	if len(fa) != 64 {
+		t.Errorf("fingerprint %q is not a hex SHA-256", fa)
+	}
+	if fb, _ := b.SchemaFingerprint(); fb != fa {
+		t.Errorf("identical schemas fingerprint differently: %s vs %s", fa, fb)
+	}
+
+	// Data doesn't change the fingerprint, schema does
+	addSound(t, a, "fitness", "a", 100)
+	if got, _ := a.SchemaFingerprint(); got != fa {
+		t.Error("fingerprint changed after inserting data")
+	}
+
+	changes := []string{
+		"CREATE INDEX idx_test_drift ON sounds(title)",
+		"ALTER TABLE sounds ADD COLUMN drift TEXT",
+	}
+	prev := fa
+	for _, change := range changes {
+		if _, err := a.db.Exec(change); err != nil {
+			t.Fatalf("%s: %v", change, err)
+		}
+		got, err := a.SchemaFingerprint()
+		if err != nil {
+			t.Fatalf("SchemaFingerprint() error: %v", err)
+		}
+		if got == prev {
+			t.Errorf("fingerprint unchanged after %q", change)
+		}
+		prev = got
+	}
+}
@@ -10,8 +10,13 @@ type Sound struct {
 	URL       string    `json:"url"`
 	UsesCount int64     `json:"uses_count"`
 	Category  string    `json:"category"`
+	MusicID   string    `json:"music_id,omitempty"` // TikTok music_id when the source provides one
+	Duration  int       `json:"duration,omitempty"` // Length in seconds, 0 if unknown
+	Genre     string    `json:"genre,omitempty"`    // Empty if unknown
+	Region    string    `json:"region,omitempty"`   // Region whose trending list the sound was last collected from
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	Source    string    `json:"source,omitempty"` // Parser that produced this data, saved with history only
 }
 
 // SoundHistory tracks historical uses_count for trend detection
@@ -20,20 +25,124 @@ type SoundHistory struct {
 	SoundID    int64     `json:"sound_id"`
 	UsesCount  int64     `json:"uses_count"`
 	RecordedAt time.Time `json:"recorded_at"`
+	Source     string    `json:"source,omitempty"`
 }
 
 // User represents a Telegram bot user
 type User struct {
-	ID         int64     `json:"id"`
-	TelegramID int64     `json:"telegram_id"`
-	Niches     string    `json:"niches"` // JSON array of selected niches
-	IsPremium  bool      `json:"is_premium"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID           int64     `json:"id"`
+	TelegramID   int64     `json:"telegram_id"`
+	Niches       string    `json:"niches"` // JSON array of selected niches
+	IsPremium    bool      `json:"is_premium"`
+	CreatedAt    time.Time `json:"created_at"`
+	AlertLimit   int       `json:"alert_limit"`   // Sounds per alert chosen by premium users, 0 = tier default
+	AlertsToday  int       `json:"alerts_today"`  // Alerts sent on AlertsDay
+	AlertsDay    string    `json:"alerts_day"`    // Day (YYYY-MM-DD) AlertsToday refers to
+	AlertMode    string    `json:"alert_mode"`    // AlertModeGrowth or AlertModePopularity
+	BetaEnrolled bool      `json:"beta_enrolled"` // Opted into experimental features via /beta
+	AlertSort    string    `json:"alert_sort"`    // AlertSortGrowth, AlertSortUses or AlertSortRecent
+	MinGrowth    float64   `json:"min_growth"`    // Global /threshold for alerts in percent, 0 = detector default
+	ShowRepeats  bool      `json:"show_repeats"`  // Premium opt-out of the global alert dedup window
+	AlertFormat  string    `json:"alert_format"`  // AlertFormatDetailed or AlertFormatCompact
+	Blocked      bool      `json:"blocked"`       // Blocked by an admin: ignored by the bot, no alerts
+	MinUses      int64     `json:"min_uses"`      // /minuses floor on a sound's uses for alerts, 0 = detector default
+	BoostUntil   time.Time `json:"boost_until"`   // /boost lowers the growth threshold until then, zero = no boost
+
+	DigestFrequency string    `json:"digest_frequency"` // DigestEvery, DigestTwiceDaily or DigestDaily
+	LastDigestAt    time.Time `json:"last_digest_at"`   // Last digest delivery, zero = never
+	InstantAlerts   bool      `json:"instant_alerts"`   // Premium: alert right after each collection instead of on the alert schedule
+	Region          string    `json:"region"`           // Region whose trends the user gets, RegionGlobal = worldwide
+	Timezone        string    `json:"timezone"`         // Timezone the daily alert cap resets in (IANA name or UTC offset), empty = UTC
+
+	PremiumExpiresAt time.Time `json:"premium_expires_at"` // When paid premium ends, zero = no expiry
+
+	AlertClaimedAt time.Time `json:"-"` // Set by ClaimUsersForAlert: identifies this instance's claim on the user
 }
 
+// Alert modes select what drives a user's alerts
+const (
+	AlertModeGrowth     = "growth"     // Fastest-growing sounds from the trend detector
+	AlertModePopularity = "popularity" // Most-used sounds in the niche
+)
+
+// Alert sort orders control how sounds are ordered within an alert
+const (
+	AlertSortGrowth = "growth" // Fastest-growing first, as detected
+	AlertSortUses   = "uses"   // Most-used first
+	AlertSortRecent = "recent" // Most recently discovered first
+)
+
+// Alert formats control how each sound is rendered in an alert
+const (
+	AlertFormatDetailed = "detailed" // Several lines per sound with all metrics
+	AlertFormatCompact  = "compact"  // One line per sound
+	AlertFormatTiered   = "tiered"   // Detailed, grouped under growth tier headers
+)
+
+// Digest frequencies control how often a user's alerts are delivered
+const (
+	DigestEvery      = "every"       // Every alert cycle, one message per niche
+	DigestTwiceDaily = "twice_daily" // Alerts coalesced into one message every 12 hours
+	DigestDaily      = "daily"       // Alerts coalesced into one message every 24 hours
+)
+
 // TrendingSound represents a sound with growth metrics
 type TrendingSound struct {
 	Sound
 	GrowthPercent float64 `json:"growth_percent"`
 	OldUsesCount  int64   `json:"old_uses_count"`
+	Rank          int     `json:"rank,omitempty"` // Position by uses within the category, 0 if not computed
+	CrossNiche    bool    `json:"cross_niche,omitempty"`
+	IsNew         bool    `json:"is_new,omitempty"` // No history in the lookback window, GrowthPercent is 0
+
+	SeasonalPeriodDays int `json:"seasonal_period_days,omitempty"` // Uses spike every this many days, so the spike may be routine
+}
+
+// Nomination is a sound URL a user submitted for tracking via /nominate
+type Nomination struct {
+	ID         int64     `json:"id"`
+	TelegramID int64     `json:"telegram_id"`
+	URL        string    `json:"url"`
+	Category   string    `json:"category"`
+	Status     string    `json:"status"` // NominationPending or NominationTracked
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CategoryDataStats says how much data a category has for trend detection
+type CategoryDataStats struct {
+	Sounds     int `json:"sounds"`     // Sounds stored in the category
+	Comparable int `json:"comparable"` // Sounds with at least two history samples, so growth can be measured
+}
+
+// Session is a user's conversation state for a multi-step command
+type Session struct {
+	TelegramID int64     `json:"telegram_id"`
+	State      string    `json:"state"`
+	Payload    string    `json:"payload"` // State-specific data collected so far
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TrendScore is a sound's precomputed growth and rank, refreshed after each collection
+type TrendScore struct {
+	SoundID       int64     `json:"sound_id"`
+	Category      string    `json:"category"`
+	GrowthPercent float64   `json:"growth_percent"`
+	Rank          int       `json:"rank"`
+	Trending      bool      `json:"trending"` // Passed detection criteria when computed
+	ComputedAt    time.Time `json:"computed_at"`
+}
+
+// AuthorStat aggregates sounds by author for leaderboards
+type AuthorStat struct {
+	Author     string `json:"author"`
+	SoundCount int    `json:"sound_count"`
+	TotalUses  int64  `json:"total_uses"`
+}
+
+// SourceDiscrepancy shows a sound whose latest uses count differs between parsers
+type SourceDiscrepancy struct {
+	SoundID     int64            `json:"sound_id"`
+	Title       string           `json:"title"`
+	Uses        map[string]int64 `json:"uses"` // Latest uses count per source
+	DiffPercent float64          `json:"diff_percent"`
 }
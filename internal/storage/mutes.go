@@ -0,0 +1,41 @@
+package storage
+
+import "fmt"
+
+// MuteNiche stops alerts for one of a user's niches without unsubscribing from it
+func (s *SQLiteStorage) MuteNiche(telegramID int64, category string) error {
+	_, err := s.db.Exec("INSERT OR IGNORE INTO muted_niches (telegram_id, category) VALUES (?, ?)", telegramID, category)
+	if err != nil {
+		return fmt.Errorf("failed to mute niche: %w", err)
+	}
+	return nil
+}
+
+// UnmuteNiche resumes alerts for a muted niche
+func (s *SQLiteStorage) UnmuteNiche(telegramID int64, category string) error {
+	_, err := s.db.Exec("DELETE FROM muted_niches WHERE telegram_id = ? AND category = ?", telegramID, category)
+	if err != nil {
+		return fmt.Errorf("failed to unmute niche: %w", err)
+	}
+	return nil
+}
+
+// GetMutedNiches returns the categories a user has muted
+func (s *SQLiteStorage) GetMutedNiches(telegramID int64) (map[string]bool, error) {
+	rows, err := s.db.Query("SELECT category FROM muted_niches WHERE telegram_id = ?", telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get muted niches: %w", err)
+	}
+	defer rows.Close()
+
+	muted := make(map[string]bool)
+	for rows.Next() {
+		var category string
+		if err := rows.Scan(&category); err != nil {
+			return nil, fmt.Errorf("failed to scan muted niche: %w", err)
+		}
+		muted[category] = true
+	}
+
+	return muted, nil
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMuteNiche(t *testing.T) {
+	s := newTestStorage(t)
+	if err := s.CreateUser(1); err != nil {
+		t.Fatalf("CreateUser() error: %v", err)
+	}
+
+	steps := []struct {
+		name  string
+		apply func() error
+		want  map[string]bool
+	}{
+		{name: "nothing muted", apply: func() error { return nil }, want: map[string]bool{}},
+		{name: "mute", apply: func() error { return s.MuteNiche(1, "fitness") }, want: map[string]bool{"fitness": true}},
+		{name: "mute twice", apply: func() error { return s.MuteNiche(1, "fitness") }, want: map[string]bool{"fitness": true}},
+		{name: "second niche", apply: func() error { return s.MuteNiche(1, "gaming") }, want: map[string]bool{"fitness": true, "gaming": true}},
+		{name: "unmute", apply: func() error { return s.UnmuteNiche(1, "fitness") }, want: map[string]bool{"gaming": true}},
+		{name: "unmute a niche that isn't muted", apply: func() error { return s.UnmuteNiche(1, "dance") }, want: map[string]bool{"gaming": true}},
+		{name: "other users are separate", apply: func() error { return s.MuteNiche(2, "dance") }, want: map[string]bool{"gaming": true}},
+	}
+	for _, step := range steps {
+		if err := step.apply(); err != nil {
+			t.Fatalf("%s: error: %v", step.name, err)
+		}
+		got, err := s.GetMutedNiches(1)
+		if err != nil {
+			t.Fatalf("%s: GetMutedNiches() error: %v", step.name, err)
+		}
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: muted = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestMergeUsersMutedNiches(t *testing.T) {
+	s := newTestStorage(t)
+	for _, id := range []int64{1, 2} {
+		if err := s.CreateUser(id); err != nil {
+			t.Fatalf("CreateUser() error: %v", err)
+		}
+	}
+	for _, mute := range []struct {
+		telegramID int64
+		category   string
+	}{{1, "fitness"}, {2, "fitness"}, {2, "gaming"}} {
+		if err := s.MuteNiche(mute.telegramID, mute.category); err != nil {
+			t.Fatalf("MuteNiche() error: %v", err)
+		}
+	}
+
+	if err := s.MergeUsers(1, 2); err != nil {
+		t.Fatalf("MergeUsers() error: %v", err)
+	}
+
+	if got, want := mustMutedNiches(t, s, 1), map[string]bool{"fitness": true, "gaming": true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("kept user's mutes = %v, want %v", got, want)
+	}
+	if got := mustMutedNiches(t, s, 2); len(got) != 0 {
+		t.Errorf("duplicate's mutes = %v, want none", got)
+	}
+}
+
+func mustMutedNiches(t *testing.T, s *SQLiteStorage, telegramID int64) map[string]bool {
+	t.Helper()
+	muted, err := s.GetMutedNiches(telegramID)
+	if err != nil {
+		t.Fatalf("GetMutedNiches() error: %v", err)
+	}
+	return muted
+}
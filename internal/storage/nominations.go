@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// Nomination statuses
+const (
+	NominationPending = "pending" // Waiting for a collection cycle to pick the sound up
+	NominationTracked = "tracked" // The sound is stored and tracked like any collected sound
+)
+
+// SaveNomination records a user's nominated sound URL. Nominating a URL twice returns ErrDuplicate.
+func (s *SQLiteStorage) SaveNomination(telegramID int64, url, category string) error {
+	query := `
+		INSERT INTO nominations (telegram_id, url, category, status, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	_, err := s.db.Exec(query, telegramID, CanonicalizeURL(url), category, NominationPending, time.Now())
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save nomination: %w", err)
+	}
+	return nil
+}
+
+// GetPendingNominations returns nominations not yet tracked, oldest first
+func (s *SQLiteStorage) GetPendingNominations() ([]Nomination, error) {
+	query := `
+		SELECT id, telegram_id, url, category, status, created_at
+		FROM nominations
+		WHERE status = ?
+		ORDER BY created_at ASC
+	`
+	rows, err := s.db.Query(query, NominationPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending nominations: %w", err)
+	}
+	defer rows.Close()
+
+	var nominations []Nomination
+	for rows.Next() {
+		var n Nomination
+		if err := rows.Scan(&n.ID, &n.TelegramID, &n.URL, &n.Category, &n.Status, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan nomination: %w", err)
+		}
+		nominations = append(nominations, n)
+	}
+
+	return nominations, nil
+}
+
+// MarkNominationTracked records that a nominated sound is now stored
+func (s *SQLiteStorage) MarkNominationTracked(id int64) error {
+	_, err := s.db.Exec("UPDATE nominations SET status = ? WHERE id = ?", NominationTracked, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark nomination tracked: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNominations(t *testing.T) {
+	s := newTestStorage(t)
+	const url = "https://www.tiktok.com/music/gym-anthem-1"
+
+	if err := s.SaveNomination(1, url+"?lang=en", "fitness"); err != nil {
+		t.Fatalf("SaveNomination() error: %v", err)
+	}
+	// The same sound under a URL variant, even from another user, is a duplicate
+	if err := s.SaveNomination(2, "https://m.tiktok.com/music/gym-anthem-1", "gaming"); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("SaveNomination(duplicate) error = %v, want ErrDuplicate", err)
+	}
+	if err := s.SaveNomination(2, "https://www.tiktok.com/music/other-2", "gaming"); err != nil {
+		t.Fatalf("SaveNomination() error: %v", err)
+	}
+
+	pending, err := s.GetPendingNominations()
+	if err != nil {
+		t.Fatalf("GetPendingNominations() error: %v", err)
+	}
+	if len(pending) != 2 || pending[0].URL != url || pending[0].TelegramID != 1 || pending[0].Status != NominationPending {
+		t.Fatalf("pending = %+v, want the canonical gym anthem first", pending)
+	}
+
+	if err := s.MarkNominationTracked(pending[0].ID); err != nil {
+		t.Fatalf("MarkNominationTracked() error: %v", err)
+	}
+	pending, err = s.GetPendingNominations()
+	if err != nil {
+		t.Fatalf("GetPendingNominations() error: %v", err)
+	}
+	if len(pending) != 1 || pending[0].Category != "gaming" {
+		t.Errorf("pending after tracking = %+v, want only the gaming nomination", pending)
+	}
+}
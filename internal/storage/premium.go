@@ -1,6 +1,10 @@
 package storage
 
-import "time"
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
 
 // SetPremium sets user premium status
 func (s *SQLiteStorage) SetPremium(telegramID int64, isPremium bool) error {
@@ -15,16 +19,60 @@ func (s *SQLiteStorage) SetPremium(telegramID int64, isPremium bool) error {
 
 // SetPremiumExpiry sets when premium expires
 func (s *SQLiteStorage) SetPremiumExpiry(telegramID int64, expiresAt time.Time) error {
-	// Для этого нужно добавить колонку premium_expires_at в таблицу users
-	// Пока просто возвращаем nil
-	// TODO: добавить миграцию для premium_expires_at
+	_, err := s.db.Exec("UPDATE users SET premium_expires_at = ? WHERE telegram_id = ?", expiresAt, telegramID)
+	if err != nil {
+		return fmt.Errorf("failed to set premium expiry: %w", err)
+	}
 	return nil
 }
 
-// CheckAndExpirePremium checks if premium has expired and removes it
+// GrantPremium adds one paid period to a user's premium and returns the new expiry.
+// The period starts at the current expiry if premium is still running, so renewing early
+// doesn't lose days. Premium status and expiry are written together in one transaction.
+func (s *SQLiteStorage) GrantPremium(telegramID int64, period time.Duration) (time.Time, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var expiry sql.NullTime
+	err = tx.QueryRow("SELECT premium_expires_at FROM users WHERE telegram_id = ?", telegramID).Scan(&expiry)
+	if err == sql.ErrNoRows {
+		return time.Time{}, ErrUserNotFound
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get premium expiry: %w", err)
+	}
+
+	start := time.Now()
+	if expiry.Valid && expiry.Time.After(start) {
+		start = expiry.Time
+	}
+	expiresAt := start.Add(period)
+
+	_, err = tx.Exec("UPDATE users SET is_premium = 1, premium_expires_at = ? WHERE telegram_id = ?", expiresAt, telegramID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to grant premium: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return time.Time{}, fmt.Errorf("failed to commit premium grant: %w", err)
+	}
+	return expiresAt, nil
+}
+
+// CheckAndExpirePremium checks if premium has expired and removes it.
+// Users without an expiry (activated for free in MVP mode) keep premium.
 func (s *SQLiteStorage) CheckAndExpirePremium() error {
-	// TODO: реализовать когда добавим premium_expires_at колонку
-	// UPDATE users SET is_premium = 0 WHERE premium_expires_at < NOW()
+	query := `
+		UPDATE users
+		SET is_premium = 0, premium_expires_at = NULL
+		WHERE premium_expires_at IS NOT NULL AND premium_expires_at < ?
+	`
+	if _, err := s.db.Exec(query, time.Now()); err != nil {
+		return fmt.Errorf("failed to expire premium: %w", err)
+	}
 	return nil
 }
 
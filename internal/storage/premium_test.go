@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+// premiumExpiry returns a user's stored premium expiry, invalid if none is set
+func premiumExpiry(t *testing.T, s *SQLiteStorage, telegramID int64) sql.NullTime {
+	t.Helper()
+	var expiry sql.NullTime
+	if err := s.db.QueryRow("SELECT premium_expires_at FROM users WHERE telegram_id = ?", telegramID).Scan(&expiry); err != nil {
+		t.Fatalf("failed to read premium expiry: %v", err)
+	}
+	return expiry
+}
+
+func TestGrantPremium(t *testing.T) {
+	const period = 30 * 24 * time.Hour
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		expiry     *time.Time // existing expiry, nil for none
+		wantExpiry time.Time
+	}{
+		{name: "first purchase", wantExpiry: now.Add(period)},
+		{name: "renewal while active", expiry: ptrTime(now.Add(10 * 24 * time.Hour)), wantExpiry: now.Add(10*24*time.Hour + period)},
+		{name: "after expiry", expiry: ptrTime(now.Add(-5 * 24 * time.Hour)), wantExpiry: now.Add(period)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(t)
+			if err := s.CreateUser(1); err != nil {
+				t.Fatalf("CreateUser() error: %v", err)
+			}
+			if tt.expiry != nil {
+				if err := s.SetPremiumExpiry(1, *tt.expiry); err != nil {
+					t.Fatalf("SetPremiumExpiry() error: %v", err)
+				}
+			}
+
+			got, err := s.GrantPremium(1, period)
+			if err != nil {
+				t.Fatalf("GrantPremium() error: %v", err)
+			}
+			if diff := got.Sub(tt.wantExpiry); diff < -time.Minute || diff > time.Minute {
+				t.Errorf("GrantPremium() = %s, want about %s", got, tt.wantExpiry)
+			}
+
+			user, err := s.GetUser(1)
+			if err != nil {
+				t.Fatalf("GetUser() error: %v", err)
+			}
+			if !user.IsPremium {
+				t.Error("user is not premium after GrantPremium()")
+			}
+			if !user.PremiumExpiresAt.Equal(got) {
+				t.Errorf("PremiumExpiresAt = %s, want %s", user.PremiumExpiresAt, got)
+			}
+			if stored := premiumExpiry(t, s, 1); !stored.Valid || !stored.Time.Equal(got) {
+				t.Errorf("stored expiry = %v, want %s", stored, got)
+			}
+		})
+	}
+}
+
+func TestGrantPremiumUnknownUser(t *testing.T) {
+	s := newTestStorage(t)
+
+	if _, err := s.GrantPremium(1, time.Hour); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("GrantPremium() error = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestCheckAndExpirePremium(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now()
+
+	users := []struct {
+		telegramID  int64
+		expiry      *time.Time
+		wantPremium bool
+	}{
+		{telegramID: 1, expiry: ptrTime(now.Add(-time.Hour)), wantPremium: false},
+		{telegramID: 2, expiry: ptrTime(now.Add(time.Hour)), wantPremium: true},
+		{telegramID: 3, expiry: nil, wantPremium: true}, // free MVP activation never expires
+	}
+	for _, u := range users {
+		if err := s.CreateUser(u.telegramID); err != nil {
+			t.Fatalf("CreateUser() error: %v", err)
+		}
+		if err := s.SetPremium(u.telegramID, true); err != nil {
+			t.Fatalf("SetPremium() error: %v", err)
+		}
+		if u.expiry != nil {
+			if err := s.SetPremiumExpiry(u.telegramID, *u.expiry); err != nil {
+				t.Fatalf("SetPremiumExpiry() error: %v", err)
+			}
+		}
+	}
+
+	if err := s.CheckAndExpirePremium(); err != nil {
+		t.Fatalf("CheckAndExpirePremium() error: %v", err)
+	}
+
+	for _, u := range users {
+		user, err := s.GetUser(u.telegramID)
+		if err != nil {
+			t.Fatalf("GetUser() error: %v", err)
+		}
+		if user.IsPremium != u.wantPremium {
+			t.Errorf("user %d IsPremium = %v, want %v", u.telegramID, user.IsPremium, u.wantPremium)
+		}
+	}
+	if premiumExpiry(t, s, 1).Valid {
+		t.Error("expired user kept their expiry")
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// RegionGlobal is the worldwide trending list, used when no region is configured or chosen
+const RegionGlobal = "global"
+
+// IsGlobalRegion reports whether a region means "no regional filter"
+func IsGlobalRegion(region string) bool {
+	return region == "" || region == RegionGlobal
+}
+
+// RecordSoundRegion records that a sound appeared on a region's trending list
+func (s *SQLiteStorage) RecordSoundRegion(soundID int64, region string) error {
+	query := `
+		INSERT INTO sound_regions (sound_id, region, last_seen_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(sound_id, region) DO UPDATE SET last_seen_at = excluded.last_seen_at
+	`
+	_, err := s.db.Exec(query, soundID, region, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record sound region: %w", err)
+	}
+	return nil
+}
+
+// GetRegionSoundIDs returns the IDs of sounds seen on a region's trending list since the given time
+func (s *SQLiteStorage) GetRegionSoundIDs(region string, since time.Time) (map[int64]bool, error) {
+	rows, err := s.readDB.Query("SELECT sound_id FROM sound_regions WHERE region = ? AND last_seen_at >= ?", region, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get region sounds: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan region sound: %w", err)
+		}
+		ids[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get region sounds: %w", err)
+	}
+
+	return ids, nil
+}
+
+// SetUserRegion stores the region whose trends a user gets
+func (s *SQLiteStorage) SetUserRegion(telegramID int64, region string) error {
+	_, err := s.db.Exec("UPDATE users SET region = ? WHERE telegram_id = ?", region, telegramID)
+	if err != nil {
+		return fmt.Errorf("failed to set user region: %w", err)
+	}
+	return nil
+}
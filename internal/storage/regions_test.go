@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIsGlobalRegion(t *testing.T) {
+	tests := []struct {
+		region string
+		want   bool
+	}{
+		{region: "", want: true},
+		{region: "global", want: true},
+		{region: "US", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := IsGlobalRegion(tt.region); got != tt.want {
+			t.Errorf("IsGlobalRegion(%q) = %v, want %v", tt.region, got, tt.want)
+		}
+	}
+}
+
+func TestSoundRegions(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now()
+
+	// The same sound collected from two lists, and a sound only on the global list
+	both := &Sound{Title: "both", URL: "https://www.tiktok.com/music/both", UsesCount: 1000, Category: "fitness", Region: "US"}
+	if err := SaveSoundWithHistory(s, both, DedupByURL); err != nil {
+		t.Fatalf("SaveSoundWithHistory() error: %v", err)
+	}
+	both.Region = RegionGlobal
+	if err := SaveSoundWithHistory(s, both, DedupByURL); err != nil {
+		t.Fatalf("SaveSoundWithHistory() error: %v", err)
+	}
+	global := &Sound{Title: "global", URL: "https://www.tiktok.com/music/global", UsesCount: 1000, Category: "fitness"}
+	if err := SaveSoundWithHistory(s, global, DedupByURL); err != nil {
+		t.Fatalf("SaveSoundWithHistory() error: %v", err)
+	}
+
+	// A sound that left the GB list a while ago
+	old := addSound(t, s, "fitness", "old", 1000)
+	if _, err := s.db.Exec("INSERT INTO sound_regions (sound_id, region, last_seen_at) VALUES (?, 'GB', ?)", old.ID, now.Add(-72*time.Hour)); err != nil {
+		t.Fatalf("insert sound region: %v", err)
+	}
+
+	tests := []struct {
+		region string
+		want   map[int64]bool
+	}{
+		{region: "US", want: map[int64]bool{both.ID: true}},
+		{region: "global", want: map[int64]bool{both.ID: true, global.ID: true}},
+		{region: "GB", want: map[int64]bool{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.region, func(t *testing.T) {
+			got, err := s.GetRegionSoundIDs(tt.region, now.Add(-24*time.Hour))
+			if err != nil {
+				t.Fatalf("GetRegionSoundIDs() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetRegionSoundIDs(%s) = %v, want %v", tt.region, got, tt.want)
+			}
+		})
+	}
+
+	// The sound keeps the list it was last collected from, and sounds saved without a region are global
+	if got, _ := s.GetSoundByURL(both.URL); got.Region != RegionGlobal {
+		t.Errorf("both region = %q, want %q", got.Region, RegionGlobal)
+	}
+	if got, _ := s.GetSoundByURL(global.URL); got.Region != RegionGlobal {
+		t.Errorf("global region = %q, want %q", got.Region, RegionGlobal)
+	}
+}
+
+func TestSetUserRegion(t *testing.T) {
+	s := newTestStorage(t)
+	if err := s.CreateUser(1); err != nil {
+		t.Fatalf("CreateUser() error: %v", err)
+	}
+
+	user, err := s.GetUser(1)
+	if err != nil {
+		t.Fatalf("GetUser() error: %v", err)
+	}
+	if user.Region != RegionGlobal {
+		t.Errorf("new user region = %q, want %q", user.Region, RegionGlobal)
+	}
+
+	if err := s.SetUserRegion(1, "US"); err != nil {
+		t.Fatalf("SetUserRegion() error: %v", err)
+	}
+	if user, _ := s.GetUser(1); user.Region != "US" {
+		t.Errorf("region = %q, want US", user.Region)
+	}
+}
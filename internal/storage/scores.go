@@ -0,0 +1,64 @@
+package storage
+
+import "fmt"
+
+// SaveTrendScores replaces the precomputed trend scores of a category
+func (s *SQLiteStorage) SaveTrendScores(category string, scores []TrendScore) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Sounds that left the category must not keep a stale score
+	if _, err := tx.Exec("DELETE FROM trend_scores WHERE category = ?", category); err != nil {
+		return fmt.Errorf("failed to clear trend scores: %w", err)
+	}
+
+	query := `
+		INSERT INTO trend_scores (sound_id, category, growth_percent, rank, trending, computed_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(sound_id) DO UPDATE SET
+			category = excluded.category,
+			growth_percent = excluded.growth_percent,
+			rank = excluded.rank,
+			trending = excluded.trending,
+			computed_at = excluded.computed_at
+	`
+	for _, score := range scores {
+		if _, err := tx.Exec(query, score.SoundID, category, score.GrowthPercent, score.Rank, score.Trending, score.ComputedAt); err != nil {
+			return fmt.Errorf("failed to save trend score: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit trend scores: %w", err)
+	}
+	return nil
+}
+
+// GetTrendScores returns the precomputed scores of a category, highest growth first
+func (s *SQLiteStorage) GetTrendScores(category string) ([]TrendScore, error) {
+	query := `
+		SELECT sound_id, category, growth_percent, rank, trending, computed_at
+		FROM trend_scores
+		WHERE category = ?
+		ORDER BY growth_percent DESC
+	`
+	rows, err := s.readDB.Query(query, category)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trend scores: %w", err)
+	}
+	defer rows.Close()
+
+	var scores []TrendScore
+	for rows.Next() {
+		var score TrendScore
+		if err := rows.Scan(&score.SoundID, &score.Category, &score.GrowthPercent, &score.Rank, &score.Trending, &score.ComputedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan trend score: %w", err)
+		}
+		scores = append(scores, score)
+	}
+
+	return scores, nil
+}
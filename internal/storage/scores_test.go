@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSaveTrendScores(t *testing.T) {
+	s := newTestStorage(t)
+	a := addSound(t, s, "fitness", "a", 2000)
+	b := addSound(t, s, "fitness", "b", 1000)
+	now := time.Now()
+
+	first := []TrendScore{
+		{SoundID: a.ID, GrowthPercent: 50, Rank: 1, ComputedAt: now},
+		{SoundID: b.ID, GrowthPercent: 300, Rank: 2, Trending: true, ComputedAt: now},
+	}
+	if err := s.SaveTrendScores("fitness", first); err != nil {
+		t.Fatalf("SaveTrendScores() error: %v", err)
+	}
+
+	got, err := s.GetTrendScores("fitness")
+	if err != nil {
+		t.Fatalf("GetTrendScores() error: %v", err)
+	}
+	if len(got) != 2 || got[0].SoundID != b.ID || !got[0].Trending || got[0].Category != "fitness" {
+		t.Fatalf("GetTrendScores() = %+v, want b first and trending", got)
+	}
+
+	// A later run replaces the category's scores, dropping sounds that left it
+	if err := s.SaveTrendScores("fitness", first[:1]); err != nil {
+		t.Fatalf("SaveTrendScores() error: %v", err)
+	}
+	got, err = s.GetTrendScores("fitness")
+	if err != nil {
+		t.Fatalf("GetTrendScores() error: %v", err)
+	}
+	if len(got) != 1 || got[0].SoundID != a.ID {
+		t.Errorf("GetTrendScores() after replace = %+v, want only a", got)
+	}
+
+	if got, err := s.GetTrendScores("beauty"); err != nil || len(got) != 0 {
+		t.Errorf("GetTrendScores(beauty) = %+v, %v, want none", got, err)
+	}
+}
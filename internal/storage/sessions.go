@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SetSession starts or replaces a user's conversation state
+func (s *SQLiteStorage) SetSession(telegramID int64, state, payload string) error {
+	query := `
+		INSERT INTO sessions (telegram_id, state, payload, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(telegram_id) DO UPDATE SET
+			state = excluded.state,
+			payload = excluded.payload,
+			updated_at = excluded.updated_at
+	`
+	_, err := s.db.Exec(query, telegramID, state, payload, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to set session: %w", err)
+	}
+	return nil
+}
+
+// GetSession returns a user's conversation state. Sessions idle for longer than maxAge
+// are deleted and reported as ErrSessionNotFound.
+func (s *SQLiteStorage) GetSession(telegramID int64, maxAge time.Duration) (*Session, error) {
+	session := &Session{}
+	err := s.db.QueryRow("SELECT telegram_id, state, payload, updated_at FROM sessions WHERE telegram_id = ?", telegramID).
+		Scan(&session.TelegramID, &session.State, &session.Payload, &session.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	if time.Since(session.UpdatedAt) > maxAge {
+		if err := s.ClearSession(telegramID); err != nil {
+			return nil, err
+		}
+		return nil, ErrSessionNotFound
+	}
+
+	return session, nil
+}
+
+// ClearSession ends a user's conversation
+func (s *SQLiteStorage) ClearSession(telegramID int64) error {
+	if _, err := s.db.Exec("DELETE FROM sessions WHERE telegram_id = ?", telegramID); err != nil {
+		return fmt.Errorf("failed to clear session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSessions(t *testing.T) {
+	s := newTestStorage(t)
+
+	if _, err := s.GetSession(1, time.Minute); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("GetSession() without a session error = %v, want ErrSessionNotFound", err)
+	}
+
+	if err := s.SetSession(1, "broadcast", ""); err != nil {
+		t.Fatalf("SetSession() error: %v", err)
+	}
+	// A second SetSession replaces the first
+	if err := s.SetSession(1, "label", "fitness"); err != nil {
+		t.Fatalf("SetSession() error: %v", err)
+	}
+	session, err := s.GetSession(1, time.Minute)
+	if err != nil {
+		t.Fatalf("GetSession() error: %v", err)
+	}
+	if session.TelegramID != 1 || session.State != "label" || session.Payload != "fitness" {
+		t.Errorf("session = %+v, want the replacing state and payload", session)
+	}
+
+	if err := s.ClearSession(1); err != nil {
+		t.Fatalf("ClearSession() error: %v", err)
+	}
+	if _, err := s.GetSession(1, time.Minute); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("GetSession() after clear error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestGetSessionExpired(t *testing.T) {
+	s := newTestStorage(t)
+	if err := s.SetSession(1, "broadcast", ""); err != nil {
+		t.Fatalf("SetSession() error: %v", err)
+	}
+	if _, err := s.db.Exec("UPDATE sessions SET updated_at = ?", time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("backdate session: %v", err)
+	}
+
+	if _, err := s.GetSession(1, 10*time.Minute); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("GetSession() of an idle session error = %v, want ErrSessionNotFound", err)
+	}
+
+	// The idle session was deleted, not just hidden
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM sessions").Scan(&count); err != nil {
+		t.Fatalf("count sessions: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("%d sessions left, want the idle one deleted", count)
+	}
+}
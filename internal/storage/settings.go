@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SettingAlertsPaused is the settings key for the global alert kill-switch
+const SettingAlertsPaused = "alerts_paused"
+
+// CollectionHashKey is the settings key holding the hash of a category's last collected sound set
+func CollectionHashKey(category string) string {
+	return "collection_hash:" + category
+}
+
+// GetSetting returns a setting value, or an empty string if it is not set
+func (s *SQLiteStorage) GetSetting(key string) (string, error) {
+	var value string
+	err := s.db.QueryRow("SELECT value FROM settings WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get setting %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// SetSetting creates or updates a setting value
+func (s *SQLiteStorage) SetSetting(key, value string) error {
+	query := `
+		INSERT INTO settings (key, value, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+	`
+	_, err := s.db.Exec(query, key, value, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to set setting %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package storage
+
+import "testing"
+
+func TestSettings(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   string
+	}{
+		{name: "unset", values: nil, want: ""},
+		{name: "set once", values: []string{"true"}, want: "true"},
+		{name: "overwritten", values: []string{"true", "false"}, want: "false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(t)
+			for _, v := range tt.values {
+				if err := s.SetSetting(SettingAlertsPaused, v); err != nil {
+					t.Fatalf("SetSetting() error: %v", err)
+				}
+			}
+			got, err := s.GetSetting(SettingAlertsPaused)
+			if err != nil {
+				t.Fatalf("GetSetting() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetSetting() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
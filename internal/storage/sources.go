@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// GetSourceDiscrepancies compares the latest uses count each parser source recorded for a sound
+// since the given time, returning sounds seen by 2+ sources whose counts differ by at least minDiffPercent
+func (s *SQLiteStorage) GetSourceDiscrepancies(since time.Time, minDiffPercent float64) ([]SourceDiscrepancy, error) {
+	query := `
+		SELECT s.id, s.title, h.source, h.uses_count
+		FROM sound_history h
+		JOIN sounds s ON s.id = h.sound_id
+		WHERE h.source != '' AND h.recorded_at >= ?
+		  AND h.recorded_at = (
+			SELECT MAX(h2.recorded_at) FROM sound_history h2
+			WHERE h2.sound_id = h.sound_id AND h2.source = h.source
+		  )
+		ORDER BY s.id
+	`
+	rows, err := s.readDB.Query(query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source discrepancies: %w", err)
+	}
+	defer rows.Close()
+
+	var all []SourceDiscrepancy
+	for rows.Next() {
+		var (
+			soundID int64
+			title   string
+			source  string
+			uses    int64
+		)
+		if err := rows.Scan(&soundID, &title, &source, &uses); err != nil {
+			return nil, fmt.Errorf("failed to scan source discrepancy: %w", err)
+		}
+		if len(all) == 0 || all[len(all)-1].SoundID != soundID {
+			all = append(all, SourceDiscrepancy{SoundID: soundID, Title: title, Uses: make(map[string]int64)})
+		}
+		all[len(all)-1].Uses[source] = uses
+	}
+
+	var discrepancies []SourceDiscrepancy
+	for _, d := range all {
+		if len(d.Uses) < 2 {
+			continue
+		}
+		d.DiffPercent = usesSpreadPercent(d.Uses)
+		if d.DiffPercent >= minDiffPercent {
+			discrepancies = append(discrepancies, d)
+		}
+	}
+
+	return discrepancies, nil
+}
+
+// usesSpreadPercent returns how much larger the highest count is than the lowest, in percent
+func usesSpreadPercent(uses map[string]int64) float64 {
+	first := true
+	var lo, hi int64
+	for _, u := range uses {
+		if first || u < lo {
+			lo = u
+		}
+		if first || u > hi {
+			hi = u
+		}
+		first = false
+	}
+	if lo <= 0 {
+		if hi > 0 {
+			return 100
+		}
+		return 0
+	}
+	return (float64(hi) - float64(lo)) / float64(lo) * 100
+}
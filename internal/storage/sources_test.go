@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// addSourceHistory records a history snapshot from a parser source at the given time
+func addSourceHistory(t *testing.T, s *SQLiteStorage, soundID, uses int64, source string, at time.Time) {
+	t.Helper()
+	_, err := s.db.Exec(`INSERT INTO sound_history (sound_id, uses_count, recorded_at, source) VALUES (?, ?, ?, ?)`, soundID, uses, at, source)
+	if err != nil {
+		t.Fatalf("insert history: %v", err)
+	}
+}
+
+func TestGetSourceDiscrepancies(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now()
+
+	disagree := addSound(t, s, "fitness", "disagree", 0)
+	addSourceHistory(t, s, disagree.ID, 1000, "api", now.Add(-2*time.Hour))
+	addSourceHistory(t, s, disagree.ID, 2000, "api", now.Add(-time.Hour)) // latest api sample wins
+	addSourceHistory(t, s, disagree.ID, 1000, "rod", now.Add(-time.Hour))
+
+	agree := addSound(t, s, "fitness", "agree", 0)
+	addSourceHistory(t, s, agree.ID, 1000, "api", now.Add(-time.Hour))
+	addSourceHistory(t, s, agree.ID, 1050, "rod", now.Add(-time.Hour))
+
+	single := addSound(t, s, "fitness", "single source", 0)
+	addSourceHistory(t, s, single.ID, 1000, "api", now.Add(-time.Hour))
+	addSourceHistory(t, s, single.ID, 9000, "", now.Add(-time.Hour)) // untagged history is ignored
+
+	stale := addSound(t, s, "fitness", "stale", 0)
+	addSourceHistory(t, s, stale.ID, 1000, "api", now.Add(-48*time.Hour))
+	addSourceHistory(t, s, stale.ID, 5000, "rod", now.Add(-48*time.Hour))
+
+	tests := []struct {
+		name    string
+		minDiff float64
+		want    []SourceDiscrepancy
+	}{
+		{
+			name:    "only significant disagreements",
+			minDiff: 20,
+			want:    []SourceDiscrepancy{{SoundID: disagree.ID, Title: "disagree", Uses: map[string]int64{"api": 2000, "rod": 1000}, DiffPercent: 100}},
+		},
+		{
+			name:    "every sound seen by two sources",
+			minDiff: 0,
+			want: []SourceDiscrepancy{
+				{SoundID: disagree.ID, Title: "disagree", Uses: map[string]int64{"api": 2000, "rod": 1000}, DiffPercent: 100},
+				{SoundID: agree.ID, Title: "agree", Uses: map[string]int64{"api": 1000, "rod": 1050}, DiffPercent: 5},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetSourceDiscrepancies(now.Add(-24*time.Hour), tt.minDiff)
+			if err != nil {
+				t.Fatalf("GetSourceDiscrepancies() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetSourceDiscrepancies() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsesSpreadPercent(t *testing.T) {
+	tests := []struct {
+		name string
+		uses map[string]int64
+		want float64
+	}{
+		{name: "equal", uses: map[string]int64{"api": 500, "rod": 500}, want: 0},
+		{name: "double", uses: map[string]int64{"api": 500, "rod": 1000}, want: 100},
+		{name: "three sources use extremes", uses: map[string]int64{"a": 100, "b": 150, "c": 400}, want: 300},
+		{name: "zero against positive", uses: map[string]int64{"api": 0, "rod": 10}, want: 100},
+		{name: "all zero", uses: map[string]int64{"api": 0, "rod": 0}, want: 0},
+		{name: "extreme counts don't overflow", uses: map[string]int64{"api": 1, "rod": math.MaxInt64}, want: float64(math.MaxInt64) * 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := usesSpreadPercent(tt.uses); got != tt.want {
+				t.Errorf("usesSpreadPercent(%v) = %v, want %v", tt.uses, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveSoundWithHistoryRecordsSource(t *testing.T) {
+	s := newTestStorage(t)
+	for _, snap := range []struct {
+		source string
+		uses   int64
+	}{{"api", 3000}, {"rod", 1000}} {
+		sound := &Sound{Title: "x", URL: "https://www.tiktok.com/music/x-1", UsesCount: snap.uses, Category: "fitness", Source: snap.source}
+		if err := SaveSoundWithHistory(s, sound, DedupByURL); err != nil {
+			t.Fatalf("SaveSoundWithHistory() error: %v", err)
+		}
+	}
+
+	got, err := s.GetSourceDiscrepancies(time.Now().Add(-time.Hour), 0)
+	if err != nil {
+		t.Fatalf("GetSourceDiscrepancies() error: %v", err)
+	}
+	want := map[string]int64{"api": 3000, "rod": 1000}
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Uses, want) {
+		t.Errorf("GetSourceDiscrepancies() = %+v, want uses %v", got, want)
+	}
+}
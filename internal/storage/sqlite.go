@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -11,12 +12,26 @@ import (
 
 // SQLiteStorage implements Storage interface using SQLite
 type SQLiteStorage struct {
-	db *sql.DB
+	db     *sql.DB // Read-write connection, used for all writes
+	readDB *sql.DB // Read-only pool for listing, history and stats queries
 }
 
+// MemoryPath opens a private in-memory database when passed to NewSQLiteStorage
+const MemoryPath = ":memory:"
+
+// memoryDBs numbers in-memory databases so each storage gets its own
+var memoryDBs atomic.Int64
+
 // NewSQLiteStorage creates a new SQLite storage instance
 func NewSQLiteStorage(dbPath string) (*SQLiteStorage, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+	writeDSN, readDSN := dbPath, "file:"+dbPath+"?mode=ro"
+	if dbPath == MemoryPath {
+		// Both pools must share one named database, or each connection would get its own
+		name := fmt.Sprintf("file:memdb%d?mode=memory&cache=shared", memoryDBs.Add(1))
+		writeDSN, readDSN = name, name+"&_query_only=true"
+	}
+
+	db, err := sql.Open("sqlite3", writeDSN)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -26,7 +41,18 @@ func NewSQLiteStorage(dbPath string) (*SQLiteStorage, error) {
 		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
 	}
 
-	return &SQLiteStorage{db: db}, nil
+	// WAL lets the read-only pool read while a write transaction is open
+	if _, err := db.Exec("PRAGMA journal_mode = WAL"); err != nil {
+		return nil, fmt.Errorf("failed to enable WAL: %w", err)
+	}
+
+	readDB, err := sql.Open("sqlite3", readDSN)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open read-only database: %w", err)
+	}
+
+	return &SQLiteStorage{db: db, readDB: readDB}, nil
 }
 
 // Init initializes the database schema
@@ -42,29 +68,63 @@ func (s *SQLiteStorage) Init() error {
 		return fmt.Errorf("failed to execute migration: %w", err)
 	}
 
+	// Add columns introduced after the initial schema
+	if err := s.migrateColumns(); err != nil {
+		return err
+	}
+
+	// Index columns added above, which init.sql cannot reference on older databases
+	if _, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_sounds_music_id ON sounds(music_id)`); err != nil {
+		return fmt.Errorf("failed to create music_id index: %w", err)
+	}
+
+	// Older collectors could save sounds without a category
+	if _, err := s.BackfillEmptyCategories(); err != nil {
+		return err
+	}
+
+	// Sounds saved before URL canonicalization may be stored under tracking or mobile URLs
+	if _, err := s.CanonicalizeStoredURLs(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-// Close closes the database connection
+// Close closes the read-only pool and the database connection
 func (s *SQLiteStorage) Close() error {
+	if err := s.readDB.Close(); err != nil {
+		return fmt.Errorf("failed to close read-only database: %w", err)
+	}
 	return s.db.Close()
 }
 
 // SaveSound saves a new sound to the database
 func (s *SQLiteStorage) SaveSound(sound *Sound) error {
 	query := `
-		INSERT INTO sounds (title, author, url, uses_count, category, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO sounds (title, author, url, uses_count, category, music_id, duration, genre, region, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
+	region := sound.Region
+	if region == "" {
+		region = RegionGlobal
+	}
 	result, err := s.db.Exec(query,
 		sound.Title,
 		sound.Author,
 		sound.URL,
 		sound.UsesCount,
 		sound.Category,
+		sound.MusicID,
+		sound.Duration,
+		sound.Genre,
+		region,
 		sound.CreatedAt,
 		sound.UpdatedAt,
 	)
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
 	if err != nil {
 		return fmt.Errorf("failed to save sound: %w", err)
 	}
@@ -78,26 +138,103 @@ func (s *SQLiteStorage) SaveSound(sound *Sound) error {
 	return nil
 }
 
-// GetSoundByURL retrieves a sound by its URL
-func (s *SQLiteStorage) GetSoundByURL(url string) (*Sound, error) {
-	query := `
-		SELECT id, title, author, url, uses_count, category, created_at, updated_at
-		FROM sounds
-		WHERE url = ?
-	`
-	sound := &Sound{}
-	err := s.db.QueryRow(query, url).Scan(
+// soundColumns is the column list shared by all sound queries, in scanSound order
+const soundColumns = "id, title, author, url, uses_count, category, music_id, duration, genre, region, created_at, updated_at"
+
+// scanSound scans a row selected with soundColumns
+func scanSound(row rowScanner, sound *Sound) error {
+	return row.Scan(
 		&sound.ID,
 		&sound.Title,
 		&sound.Author,
 		&sound.URL,
 		&sound.UsesCount,
 		&sound.Category,
+		&sound.MusicID,
+		&sound.Duration,
+		&sound.Genre,
+		&sound.Region,
 		&sound.CreatedAt,
 		&sound.UpdatedAt,
 	)
+}
+
+// GetSoundByURL retrieves a sound by its URL
+func (s *SQLiteStorage) GetSoundByURL(url string) (*Sound, error) {
+	query := `
+		SELECT ` + soundColumns + `
+		FROM sounds
+		WHERE url = ?
+	`
+	sound := &Sound{}
+	err := scanSound(s.db.QueryRow(query, CanonicalizeURL(url)), sound)
 	if err == sql.ErrNoRows {
-		return nil, nil
+		return nil, ErrSoundNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sound: %w", err)
+	}
+
+	return sound, nil
+}
+
+// GetSoundByTitleAuthor retrieves the oldest sound with the given title and author
+func (s *SQLiteStorage) GetSoundByTitleAuthor(title, author string) (*Sound, error) {
+	query := `
+		SELECT ` + soundColumns + `
+		FROM sounds
+		WHERE title = ? AND author = ?
+		ORDER BY id ASC
+		LIMIT 1
+	`
+	sound := &Sound{}
+	err := scanSound(s.db.QueryRow(query, title, author), sound)
+	if err == sql.ErrNoRows {
+		return nil, ErrSoundNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sound: %w", err)
+	}
+
+	return sound, nil
+}
+
+// GetSoundByMusicID retrieves a sound by its TikTok music_id
+func (s *SQLiteStorage) GetSoundByMusicID(musicID string) (*Sound, error) {
+	if musicID == "" {
+		return nil, ErrSoundNotFound
+	}
+
+	query := `
+		SELECT ` + soundColumns + `
+		FROM sounds
+		WHERE music_id = ?
+		ORDER BY id ASC
+		LIMIT 1
+	`
+	sound := &Sound{}
+	err := scanSound(s.db.QueryRow(query, musicID), sound)
+	if err == sql.ErrNoRows {
+		return nil, ErrSoundNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sound: %w", err)
+	}
+
+	return sound, nil
+}
+
+// GetSoundByID retrieves a sound by its ID
+func (s *SQLiteStorage) GetSoundByID(id int64) (*Sound, error) {
+	query := `
+		SELECT ` + soundColumns + `
+		FROM sounds
+		WHERE id = ?
+	`
+	sound := &Sound{}
+	err := scanSound(s.db.QueryRow(query, id), sound)
+	if err == sql.ErrNoRows {
+		return nil, ErrSoundNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sound: %w", err)
@@ -109,13 +246,13 @@ func (s *SQLiteStorage) GetSoundByURL(url string) (*Sound, error) {
 // GetSoundsByCategory retrieves sounds by category with a limit
 func (s *SQLiteStorage) GetSoundsByCategory(category string, limit int) ([]Sound, error) {
 	query := `
-		SELECT id, title, author, url, uses_count, category, created_at, updated_at
+		SELECT ` + soundColumns + `
 		FROM sounds
 		WHERE category = ?
 		ORDER BY updated_at DESC
 		LIMIT ?
 	`
-	rows, err := s.db.Query(query, category, limit)
+	rows, err := s.readDB.Query(query, category, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sounds by category: %w", err)
 	}
@@ -124,17 +261,66 @@ func (s *SQLiteStorage) GetSoundsByCategory(category string, limit int) ([]Sound
 	var sounds []Sound
 	for rows.Next() {
 		var sound Sound
-		err := rows.Scan(
-			&sound.ID,
-			&sound.Title,
-			&sound.Author,
-			&sound.URL,
-			&sound.UsesCount,
-			&sound.Category,
-			&sound.CreatedAt,
-			&sound.UpdatedAt,
+		if err := scanSound(rows, &sound); err != nil {
+			return nil, fmt.Errorf("failed to scan sound: %w", err)
+		}
+		sounds = append(sounds, sound)
+	}
+
+	return sounds, nil
+}
+
+// GetTopSoundsByUses returns the most-used sounds in a category
+func (s *SQLiteStorage) GetTopSoundsByUses(category string, limit int) ([]Sound, error) {
+	query := `
+		SELECT ` + soundColumns + `
+		FROM sounds
+		WHERE category = ?
+		ORDER BY uses_count DESC
+		LIMIT ?
+	`
+	rows, err := s.readDB.Query(query, category, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top sounds by uses: %w", err)
+	}
+	defer rows.Close()
+
+	var sounds []Sound
+	for rows.Next() {
+		var sound Sound
+		if err := scanSound(rows, &sound); err != nil {
+			return nil, fmt.Errorf("failed to scan sound: %w", err)
+		}
+		sounds = append(sounds, sound)
+	}
+
+	return sounds, nil
+}
+
+// GetSoundsWithoutRecentHistory returns sounds with no history record in the last sinceHours hours.
+// An empty category checks all sounds.
+func (s *SQLiteStorage) GetSoundsWithoutRecentHistory(category string, sinceHours int) ([]Sound, error) {
+	query := `
+		SELECT ` + soundColumns + `
+		FROM sounds s
+		WHERE (? = '' OR s.category = ?)
+		AND NOT EXISTS (
+			SELECT 1 FROM sound_history h
+			WHERE h.sound_id = s.id AND h.recorded_at >= ?
 		)
-		if err != nil {
+		ORDER BY s.updated_at ASC
+	`
+	since := time.Now().Add(-time.Duration(sinceHours) * time.Hour)
+	rows, err := s.readDB.Query(query, category, category, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sounds without recent history: %w", err)
+	}
+	defer rows.Close()
+
+	var sounds []Sound
+	for rows.Next() {
+		var sound Sound
+		if err := scanSound(rows, &sound); err != nil {
 			return nil, fmt.Errorf("failed to scan sound: %w", err)
 		}
 		sounds = append(sounds, sound)
@@ -143,11 +329,70 @@ func (s *SQLiteStorage) GetSoundsByCategory(category string, limit int) ([]Sound
 	return sounds, nil
 }
 
+// GetSoundRank returns the sound's position within a category ordered by uses_count (1 = most used)
+func (s *SQLiteStorage) GetSoundRank(soundID int64, category string) (int, error) {
+	query := `
+		SELECT (
+			SELECT COUNT(*) FROM sounds o
+			WHERE o.category = ? AND o.uses_count > s.uses_count
+		) + 1
+		FROM sounds s
+		WHERE s.id = ?
+	`
+	var rank int
+	err := s.readDB.QueryRow(query, category, soundID).Scan(&rank)
+	if err == sql.ErrNoRows {
+		return 0, ErrSoundNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get sound rank: %w", err)
+	}
+
+	return rank, nil
+}
+
+// GetTopAuthors returns authors ranked by total uses of their sounds.
+// An empty category means all categories. Sounds without an author are grouped under "Unknown".
+func (s *SQLiteStorage) GetTopAuthors(category string, limit int) ([]AuthorStat, error) {
+	query := `
+		SELECT
+			CASE WHEN TRIM(COALESCE(author, '')) = '' THEN 'Unknown' ELSE TRIM(author) END AS author_name,
+			COUNT(*) AS sound_count,
+			COALESCE(SUM(uses_count), 0) AS total_uses
+		FROM sounds
+		WHERE ? = '' OR category = ?
+		GROUP BY author_name
+		ORDER BY total_uses DESC, sound_count DESC
+		LIMIT ?
+	`
+	rows, err := s.readDB.Query(query, category, category, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top authors: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []AuthorStat
+	for rows.Next() {
+		var stat AuthorStat
+		if err := rows.Scan(&stat.Author, &stat.SoundCount, &stat.TotalUses); err != nil {
+			return nil, fmt.Errorf("failed to scan author stat: %w", err)
+		}
+		stats = append(stats, stat)
+	}
+
+	return stats, nil
+}
+
 // UpdateSound updates an existing sound
 func (s *SQLiteStorage) UpdateSound(sound *Sound) error {
 	query := `
 		UPDATE sounds
-		SET title = ?, author = ?, uses_count = ?, category = ?, updated_at = ?
+		SET title = ?, author = ?, uses_count = ?, category = ?,
+			music_id = COALESCE(NULLIF(?, ''), music_id),
+			duration = COALESCE(NULLIF(?, 0), duration),
+			genre = COALESCE(NULLIF(?, ''), genre),
+			region = COALESCE(NULLIF(?, ''), region),
+			updated_at = ?
 		WHERE id = ?
 	`
 	_, err := s.db.Exec(query,
@@ -155,6 +400,10 @@ func (s *SQLiteStorage) UpdateSound(sound *Sound) error {
 		sound.Author,
 		sound.UsesCount,
 		sound.Category,
+		sound.MusicID,
+		sound.Duration,
+		sound.Genre,
+		sound.Region,
 		sound.UpdatedAt,
 		sound.ID,
 	)
@@ -165,13 +414,54 @@ func (s *SQLiteStorage) UpdateSound(sound *Sound) error {
 	return nil
 }
 
-// SaveSoundHistory saves a sound history record
-func (s *SQLiteStorage) SaveSoundHistory(soundID int64, usesCount int64) error {
+// RecordSoundCategory records that a sound was seen in a category
+func (s *SQLiteStorage) RecordSoundCategory(soundID int64, category string) error {
 	query := `
-		INSERT INTO sound_history (sound_id, uses_count, recorded_at)
+		INSERT INTO sound_categories (sound_id, category, last_seen_at)
 		VALUES (?, ?, ?)
+		ON CONFLICT(sound_id, category) DO UPDATE SET last_seen_at = excluded.last_seen_at
+	`
+	_, err := s.db.Exec(query, soundID, category, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record sound category: %w", err)
+	}
+
+	return nil
+}
+
+// GetSoundCategories returns the categories a sound was seen in since the given time
+func (s *SQLiteStorage) GetSoundCategories(soundID int64, since time.Time) ([]string, error) {
+	query := `
+		SELECT category
+		FROM sound_categories
+		WHERE sound_id = ? AND last_seen_at >= ?
+		ORDER BY category
+	`
+	rows, err := s.db.Query(query, soundID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sound categories: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []string
+	for rows.Next() {
+		var category string
+		if err := rows.Scan(&category); err != nil {
+			return nil, fmt.Errorf("failed to scan sound category: %w", err)
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, nil
+}
+
+// SaveSoundHistory saves a sound history record along with the parser source that produced it
+func (s *SQLiteStorage) SaveSoundHistory(soundID int64, usesCount int64, source string) error {
+	query := `
+		INSERT INTO sound_history (sound_id, uses_count, recorded_at, source)
+		VALUES (?, ?, ?, ?)
 	`
-	_, err := s.db.Exec(query, soundID, usesCount, time.Now())
+	_, err := s.db.Exec(query, soundID, usesCount, time.Now(), source)
 	if err != nil {
 		return fmt.Errorf("failed to save sound history: %w", err)
 	}
@@ -179,6 +469,16 @@ func (s *SQLiteStorage) SaveSoundHistory(soundID int64, usesCount int64) error {
 	return nil
 }
 
+// HasSoundHistory reports whether any history has been recorded yet
+func (s *SQLiteStorage) HasSoundHistory() (bool, error) {
+	var exists bool
+	err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM sound_history)`).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check sound history: %w", err)
+	}
+	return exists, nil
+}
+
 // GetSoundHistoryByTime retrieves sound history from N hours ago
 func (s *SQLiteStorage) GetSoundHistoryByTime(soundID int64, hoursAgo int) (*SoundHistory, error) {
 	cutoffTime := time.Now().Add(-time.Duration(hoursAgo) * time.Hour)
@@ -191,7 +491,7 @@ func (s *SQLiteStorage) GetSoundHistoryByTime(soundID int64, hoursAgo int) (*Sou
 		LIMIT 1
 	`
 	history := &SoundHistory{}
-	err := s.db.QueryRow(query, soundID, cutoffTime).Scan(
+	err := s.readDB.QueryRow(query, soundID, cutoffTime).Scan(
 		&history.ID,
 		&history.SoundID,
 		&history.UsesCount,
@@ -207,6 +507,102 @@ func (s *SQLiteStorage) GetSoundHistoryByTime(soundID int64, hoursAgo int) (*Sou
 	return history, nil
 }
 
+// GetSoundHistoryRange retrieves all history records for a sound since the given time, oldest first
+func (s *SQLiteStorage) GetSoundHistoryRange(soundID int64, since time.Time) ([]SoundHistory, error) {
+	query := `
+		SELECT id, sound_id, uses_count, recorded_at
+		FROM sound_history
+		WHERE sound_id = ? AND recorded_at >= ?
+		ORDER BY recorded_at ASC
+	`
+	rows, err := s.readDB.Query(query, soundID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sound history range: %w", err)
+	}
+	defer rows.Close()
+
+	var history []SoundHistory
+	for rows.Next() {
+		var h SoundHistory
+		err := rows.Scan(
+			&h.ID,
+			&h.SoundID,
+			&h.UsesCount,
+			&h.RecordedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sound history: %w", err)
+		}
+		history = append(history, h)
+	}
+
+	return history, nil
+}
+
+// GetFullSoundHistory returns every history snapshot of a sound, oldest first
+func (s *SQLiteStorage) GetFullSoundHistory(soundID int64) ([]SoundHistory, error) {
+	query := `
+		SELECT id, sound_id, uses_count, recorded_at
+		FROM sound_history
+		WHERE sound_id = ?
+		ORDER BY recorded_at ASC
+	`
+	rows, err := s.readDB.Query(query, soundID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get full sound history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []SoundHistory
+	for rows.Next() {
+		var h SoundHistory
+		err := rows.Scan(
+			&h.ID,
+			&h.SoundID,
+			&h.UsesCount,
+			&h.RecordedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sound history: %w", err)
+		}
+		history = append(history, h)
+	}
+
+	return history, nil
+}
+
+// GetSoundHistoryBetween returns a sound's history recorded between from and to (inclusive), oldest first
+func (s *SQLiteStorage) GetSoundHistoryBetween(soundID int64, from, to time.Time) ([]SoundHistory, error) {
+	query := `
+		SELECT id, sound_id, uses_count, recorded_at
+		FROM sound_history
+		WHERE sound_id = ? AND recorded_at >= ? AND recorded_at <= ?
+		ORDER BY recorded_at ASC
+	`
+	rows, err := s.readDB.Query(query, soundID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sound history between: %w", err)
+	}
+	defer rows.Close()
+
+	var history []SoundHistory
+	for rows.Next() {
+		var h SoundHistory
+		err := rows.Scan(
+			&h.ID,
+			&h.SoundID,
+			&h.UsesCount,
+			&h.RecordedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sound history: %w", err)
+		}
+		history = append(history, h)
+	}
+
+	return history, nil
+}
+
 // GetAllSoundsWithHistory retrieves all sounds and their history for trend detection
 func (s *SQLiteStorage) GetAllSoundsWithHistory(category string, hoursAgo int) ([]Sound, map[int64]*SoundHistory, error) {
 	// Get all sounds in category
@@ -230,6 +626,110 @@ func (s *SQLiteStorage) GetAllSoundsWithHistory(category string, hoursAgo int) (
 	return sounds, historyMap, nil
 }
 
+// GetCategoryGrowthSum returns the sum of growth percentages of all sounds in a category,
+// comparing current uses against the earliest history record since the given time
+func (s *SQLiteStorage) GetCategoryGrowthSum(category string, since time.Time) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM((CAST(uses_count AS REAL) - old_count) * 100.0 / old_count), 0)
+		FROM (
+			SELECT s.uses_count AS uses_count,
+				(SELECT h.uses_count FROM sound_history h
+				 WHERE h.sound_id = s.id AND h.recorded_at >= ?
+				 ORDER BY h.recorded_at ASC LIMIT 1) AS old_count
+			FROM sounds s
+			WHERE s.category = ?
+		)
+		WHERE old_count > 0
+	`
+	var sum float64
+	if err := s.readDB.QueryRow(query, since, category).Scan(&sum); err != nil {
+		return 0, fmt.Errorf("failed to get category growth sum: %w", err)
+	}
+
+	return sum, nil
+}
+
+// GetCategoryDataStats counts a category's sounds and how many have enough history to compare
+func (s *SQLiteStorage) GetCategoryDataStats(category string) (*CategoryDataStats, error) {
+	query := `
+		SELECT COUNT(*),
+			COALESCE(SUM((SELECT COUNT(*) FROM sound_history h WHERE h.sound_id = s.id) >= 2), 0)
+		FROM sounds s
+		WHERE s.category = ?
+	`
+	stats := &CategoryDataStats{}
+	if err := s.readDB.QueryRow(query, category).Scan(&stats.Sounds, &stats.Comparable); err != nil {
+		return nil, fmt.Errorf("failed to get category data stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// GetCategoryLastUpdated returns when a category's sounds were last updated, or the zero time if it has none
+func (s *SQLiteStorage) GetCategoryLastUpdated(category string) (time.Time, error) {
+	// ORDER BY keeps the column type so the driver parses the timestamp, unlike MAX()
+	query := `
+		SELECT updated_at
+		FROM sounds
+		WHERE category = ?
+		ORDER BY updated_at DESC
+		LIMIT 1
+	`
+	var updatedAt time.Time
+	err := s.readDB.QueryRow(query, category).Scan(&updatedAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get category last updated: %w", err)
+	}
+
+	return updatedAt, nil
+}
+
+// userColumns is the column list shared by all user queries, in scanUser order
+const userColumns = "id, telegram_id, niches, is_premium, created_at, alert_limit, alerts_today, alerts_day, alert_mode, beta_enrolled, alert_sort, min_growth, show_repeats, alert_format, blocked, min_uses, boost_until, digest_frequency, last_digest_at, instant_alerts, region, timezone, premium_expires_at"
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser scans a row selected with userColumns
+func scanUser(row rowScanner, user *User) error {
+	// NULL until the user first runs /boost or gets a digest, or without paid premium
+	var boostUntil, lastDigestAt, premiumExpiresAt sql.NullTime
+	err := row.Scan(
+		&user.ID,
+		&user.TelegramID,
+		&user.Niches,
+		&user.IsPremium,
+		&user.CreatedAt,
+		&user.AlertLimit,
+		&user.AlertsToday,
+		&user.AlertsDay,
+		&user.AlertMode,
+		&user.BetaEnrolled,
+		&user.AlertSort,
+		&user.MinGrowth,
+		&user.ShowRepeats,
+		&user.AlertFormat,
+		&user.Blocked,
+		&user.MinUses,
+		&boostUntil,
+		&user.DigestFrequency,
+		&lastDigestAt,
+		&user.InstantAlerts,
+		&user.Region,
+		&user.Timezone,
+		&premiumExpiresAt,
+	)
+	user.BoostUntil = boostUntil.Time
+	user.LastDigestAt = lastDigestAt.Time
+	user.PremiumExpiresAt = premiumExpiresAt.Time
+	return err
+}
+
 // CreateUser creates a new user
 func (s *SQLiteStorage) CreateUser(telegramID int64) error {
 	query := `
@@ -237,6 +737,9 @@ func (s *SQLiteStorage) CreateUser(telegramID int64) error {
 		VALUES (?, '[]', 0, ?)
 	`
 	_, err := s.db.Exec(query, telegramID, time.Now())
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
@@ -247,20 +750,14 @@ func (s *SQLiteStorage) CreateUser(telegramID int64) error {
 // GetUser retrieves a user by Telegram ID
 func (s *SQLiteStorage) GetUser(telegramID int64) (*User, error) {
 	query := `
-		SELECT id, telegram_id, niches, is_premium, created_at
+		SELECT ` + userColumns + `
 		FROM users
 		WHERE telegram_id = ?
 	`
 	user := &User{}
-	err := s.db.QueryRow(query, telegramID).Scan(
-		&user.ID,
-		&user.TelegramID,
-		&user.Niches,
-		&user.IsPremium,
-		&user.CreatedAt,
-	)
+	err := scanUser(s.db.QueryRow(query, telegramID), user)
 	if err == sql.ErrNoRows {
-		return nil, nil
+		return nil, ErrUserNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
@@ -287,7 +784,7 @@ func (s *SQLiteStorage) UpdateUserNiches(telegramID int64, niches string) error
 // GetAllUsers retrieves all users
 func (s *SQLiteStorage) GetAllUsers() ([]User, error) {
 	query := `
-		SELECT id, telegram_id, niches, is_premium, created_at
+		SELECT ` + userColumns + `
 		FROM users
 		ORDER BY created_at DESC
 	`
@@ -300,14 +797,7 @@ func (s *SQLiteStorage) GetAllUsers() ([]User, error) {
 	var users []User
 	for rows.Next() {
 		var user User
-		err := rows.Scan(
-			&user.ID,
-			&user.TelegramID,
-			&user.Niches,
-			&user.IsPremium,
-			&user.CreatedAt,
-		)
-		if err != nil {
+		if err := scanUser(rows, &user); err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
 		users = append(users, user)
@@ -315,3 +805,82 @@ func (s *SQLiteStorage) GetAllUsers() ([]User, error) {
 
 	return users, nil
 }
+
+// SetAlertLimit sets how many sounds a user gets per alert (0 = tier default)
+func (s *SQLiteStorage) SetAlertLimit(telegramID int64, limit int) error {
+	_, err := s.db.Exec("UPDATE users SET alert_limit = ? WHERE telegram_id = ?", limit, telegramID)
+	if err != nil {
+		return fmt.Errorf("failed to set alert limit: %w", err)
+	}
+	return nil
+}
+
+// SetAlertMode stores whether a user's alerts are growth- or popularity-based
+func (s *SQLiteStorage) SetAlertMode(telegramID int64, mode string) error {
+	_, err := s.db.Exec("UPDATE users SET alert_mode = ? WHERE telegram_id = ?", mode, telegramID)
+	if err != nil {
+		return fmt.Errorf("failed to set alert mode: %w", err)
+	}
+	return nil
+}
+
+// SetShowRepeats stores whether a user wants sounds already alerted within the global dedup window
+func (s *SQLiteStorage) SetShowRepeats(telegramID int64, show bool) error {
+	_, err := s.db.Exec("UPDATE users SET show_repeats = ? WHERE telegram_id = ?", show, telegramID)
+	if err != nil {
+		return fmt.Errorf("failed to set show repeats: %w", err)
+	}
+	return nil
+}
+
+// SetInstantAlerts stores whether a user is alerted right after each collection
+func (s *SQLiteStorage) SetInstantAlerts(telegramID int64, instant bool) error {
+	_, err := s.db.Exec("UPDATE users SET instant_alerts = ? WHERE telegram_id = ?", instant, telegramID)
+	if err != nil {
+		return fmt.Errorf("failed to set instant alerts: %w", err)
+	}
+	return nil
+}
+
+// SetAlertFormat stores how sounds are rendered in a user's alerts
+func (s *SQLiteStorage) SetAlertFormat(telegramID int64, format string) error {
+	_, err := s.db.Exec("UPDATE users SET alert_format = ? WHERE telegram_id = ?", format, telegramID)
+	if err != nil {
+		return fmt.Errorf("failed to set alert format: %w", err)
+	}
+	return nil
+}
+
+// SetAlertSort stores how sounds are ordered within a user's alerts
+func (s *SQLiteStorage) SetAlertSort(telegramID int64, order string) error {
+	_, err := s.db.Exec("UPDATE users SET alert_sort = ? WHERE telegram_id = ?", order, telegramID)
+	if err != nil {
+		return fmt.Errorf("failed to set alert sort: %w", err)
+	}
+	return nil
+}
+
+// SetUserTimezone stores the timezone a user's daily alert cap resets in, empty = UTC
+func (s *SQLiteStorage) SetUserTimezone(telegramID int64, timezone string) error {
+	_, err := s.db.Exec("UPDATE users SET timezone = ? WHERE telegram_id = ?", timezone, telegramID)
+	if err != nil {
+		return fmt.Errorf("failed to set user timezone: %w", err)
+	}
+	return nil
+}
+
+// IncrementDailyAlerts counts one more alert sent to the user on the given day (YYYY-MM-DD).
+// The counter restarts at 1 when the day changes.
+func (s *SQLiteStorage) IncrementDailyAlerts(telegramID int64, day string) error {
+	query := `
+		UPDATE users
+		SET alerts_today = CASE WHEN alerts_day = ? THEN alerts_today + 1 ELSE 1 END,
+			alerts_day = ?
+		WHERE telegram_id = ?
+	`
+	_, err := s.db.Exec(query, day, day, telegramID)
+	if err != nil {
+		return fmt.Errorf("failed to increment daily alerts: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,609 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// Init reads migrations/init.sql relative to the repository root
+	if err := os.Chdir("../.."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// newTestStorage opens a fresh in-memory database with the full schema
+func newTestStorage(t *testing.T) *SQLiteStorage {
+	t.Helper()
+	s, err := NewSQLiteStorage(MemoryPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() error: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if err := s.Init(); err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+	return s
+}
+
+func TestMemoryStoragesAreIsolated(t *testing.T) {
+	a := newTestStorage(t)
+	b := newTestStorage(t)
+
+	if err := a.CreateUser(1); err != nil {
+		t.Fatalf("CreateUser() error: %v", err)
+	}
+	if _, err := a.GetUser(1); err != nil {
+		t.Errorf("GetUser() on the same storage error: %v", err)
+	}
+	if _, err := b.GetUser(1); err != ErrUserNotFound {
+		t.Errorf("GetUser() on another storage error = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestReadPool(t *testing.T) {
+	tests := []struct {
+		name string
+		path func(t *testing.T) string
+	}{
+		{name: "file", path: func(t *testing.T) string { return filepath.Join(t.TempDir(), "bot.db") }},
+		{name: "memory", path: func(*testing.T) string { return MemoryPath }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSQLiteStorage(tt.path(t))
+			if err != nil {
+				t.Fatalf("NewSQLiteStorage() error: %v", err)
+			}
+			t.Cleanup(func() { s.Close() })
+			if err := s.Init(); err != nil {
+				t.Fatalf("Init() error: %v", err)
+			}
+
+			// Writes through the main connection are visible to the read pool
+			addSound(t, s, "fitness", "gym anthem", 100)
+			sounds, err := s.GetSoundsByCategory("fitness", 10)
+			if err != nil {
+				t.Fatalf("GetSoundsByCategory() error: %v", err)
+			}
+			if len(sounds) != 1 {
+				t.Errorf("read pool sees %d sounds, want 1", len(sounds))
+			}
+
+			if _, err := s.readDB.Exec(`DELETE FROM sounds`); err == nil {
+				t.Error("read pool accepted a write")
+			}
+		})
+	}
+}
+
+// addSound saves a sound in category with the given current uses count
+func addSound(t *testing.T, s *SQLiteStorage, category, title string, uses int64) *Sound {
+	t.Helper()
+	now := time.Now()
+	sound := &Sound{
+		Title:     title,
+		Author:    "author",
+		URL:       "https://www.tiktok.com/music/" + strings.ReplaceAll(title, " ", "-"),
+		UsesCount: uses,
+		Category:  category,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.SaveSound(sound); err != nil {
+		t.Fatalf("SaveSound() error: %v", err)
+	}
+	return sound
+}
+
+// addHistory records a history snapshot for a sound at the given time
+func addHistory(t *testing.T, s *SQLiteStorage, soundID, uses int64, at time.Time) {
+	t.Helper()
+	_, err := s.db.Exec(`INSERT INTO sound_history (sound_id, uses_count, recorded_at, source) VALUES (?, ?, ?, '')`, soundID, uses, at)
+	if err != nil {
+		t.Fatalf("insert history: %v", err)
+	}
+}
+
+func TestGetCategoryGrowthSum(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now()
+
+	// Doubled since the window start: 100%
+	a := addSound(t, s, "fitness", "a", 2000)
+	addHistory(t, s, a.ID, 500, now.Add(-10*24*time.Hour))
+	addHistory(t, s, a.ID, 1000, now.Add(-6*24*time.Hour))
+	addHistory(t, s, a.ID, 1500, now.Add(-time.Hour))
+
+	// Grew by half: 50%
+	b := addSound(t, s, "fitness", "b", 300)
+	addHistory(t, s, b.ID, 200, now.Add(-2*24*time.Hour))
+
+	// No history in the window, or a zero baseline: ignored
+	c := addSound(t, s, "fitness", "c", 900)
+	addHistory(t, s, c.ID, 100, now.Add(-30*24*time.Hour))
+	d := addSound(t, s, "fitness", "d", 900)
+	addHistory(t, s, d.ID, 0, now.Add(-time.Hour))
+
+	// Other categories don't count
+	e := addSound(t, s, "gaming", "e", 1000)
+	addHistory(t, s, e.ID, 100, now.Add(-time.Hour))
+
+	tests := []struct {
+		category string
+		want     float64
+	}{
+		{"fitness", 150},
+		{"gaming", 900},
+		{"comedy", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			got, err := s.GetCategoryGrowthSum(tt.category, now.Add(-7*24*time.Hour))
+			if err != nil {
+				t.Fatalf("GetCategoryGrowthSum() error: %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("GetCategoryGrowthSum(%q) = %v, want %v", tt.category, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCategoryLastUpdated(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now().Truncate(time.Second)
+
+	for i, age := range []time.Duration{3 * time.Hour, 10 * time.Minute, 2 * time.Hour} {
+		sound := &Sound{Title: "a", URL: fmt.Sprintf("https://www.tiktok.com/music/a-%d", i), UsesCount: 100, Category: "fitness", UpdatedAt: now.Add(-age)}
+		if err := s.SaveSound(sound); err != nil {
+			t.Fatalf("SaveSound() error: %v", err)
+		}
+	}
+
+	got, err := s.GetCategoryLastUpdated("fitness")
+	if err != nil {
+		t.Fatalf("GetCategoryLastUpdated() error: %v", err)
+	}
+	if want := now.Add(-10 * time.Minute); !got.Equal(want) {
+		t.Errorf("GetCategoryLastUpdated(fitness) = %v, want %v", got, want)
+	}
+
+	got, err = s.GetCategoryLastUpdated("gaming")
+	if err != nil || !got.IsZero() {
+		t.Errorf("GetCategoryLastUpdated(gaming) = %v, %v, want the zero time", got, err)
+	}
+}
+
+func TestGetCategoryDataStats(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now()
+
+	// Two samples: comparable
+	a := addSound(t, s, "fitness", "a", 200)
+	addHistory(t, s, a.ID, 100, now.Add(-2*time.Hour))
+	addHistory(t, s, a.ID, 200, now)
+	// One sample: not yet
+	b := addSound(t, s, "fitness", "b", 100)
+	addHistory(t, s, b.ID, 100, now)
+	// No samples
+	addSound(t, s, "fitness", "c", 100)
+	addSound(t, s, "gaming", "d", 100)
+
+	tests := []struct {
+		category string
+		want     CategoryDataStats
+	}{
+		{category: "fitness", want: CategoryDataStats{Sounds: 3, Comparable: 1}},
+		{category: "gaming", want: CategoryDataStats{Sounds: 1, Comparable: 0}},
+		{category: "beauty", want: CategoryDataStats{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			got, err := s.GetCategoryDataStats(tt.category)
+			if err != nil {
+				t.Fatalf("GetCategoryDataStats() error: %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("GetCategoryDataStats(%s) = %+v, want %+v", tt.category, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateSoundKeepsMetadata(t *testing.T) {
+	s := newTestStorage(t)
+	sound := &Sound{Title: "a", URL: "https://www.tiktok.com/music/a-1", UsesCount: 100, Category: "fitness", Duration: 30, Genre: "Pop"}
+	if err := s.SaveSound(sound); err != nil {
+		t.Fatalf("SaveSound() error: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		duration     int
+		genre        string
+		wantDuration int
+		wantGenre    string
+	}{
+		{name: "missing metadata keeps the stored values", wantDuration: 30, wantGenre: "Pop"},
+		{name: "new metadata replaces it", duration: 45, genre: "Rock", wantDuration: 45, wantGenre: "Rock"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update := *sound
+			update.Duration, update.Genre = tt.duration, tt.genre
+			if err := s.UpdateSound(&update); err != nil {
+				t.Fatalf("UpdateSound() error: %v", err)
+			}
+			got, err := s.GetSoundByID(sound.ID)
+			if err != nil {
+				t.Fatalf("GetSoundByID() error: %v", err)
+			}
+			if got.Duration != tt.wantDuration || got.Genre != tt.wantGenre {
+				t.Errorf("stored duration %d, genre %q; want %d, %q", got.Duration, got.Genre, tt.wantDuration, tt.wantGenre)
+			}
+		})
+	}
+}
+
+func TestGetSoundRank(t *testing.T) {
+	s := newTestStorage(t)
+
+	top := addSound(t, s, "fitness", "top", 9000)
+	tiedA := addSound(t, s, "fitness", "tied a", 5000)
+	tiedB := addSound(t, s, "fitness", "tied b", 5000)
+	last := addSound(t, s, "fitness", "last", 10)
+	other := addSound(t, s, "gaming", "other", 1_000_000)
+
+	tests := []struct {
+		name     string
+		soundID  int64
+		category string
+		want     int
+		wantErr  error
+	}{
+		{name: "most used", soundID: top.ID, category: "fitness", want: 1},
+		{name: "ties share a rank", soundID: tiedA.ID, category: "fitness", want: 2},
+		{name: "other tie", soundID: tiedB.ID, category: "fitness", want: 2},
+		{name: "after ties", soundID: last.ID, category: "fitness", want: 4},
+		{name: "other categories don't count", soundID: other.ID, category: "gaming", want: 1},
+		{name: "unknown sound", soundID: 999, category: "fitness", wantErr: ErrSoundNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetSoundRank(tt.soundID, tt.category)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("GetSoundRank() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetSoundRank() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetSoundRank() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetTopAuthors(t *testing.T) {
+	s := newTestStorage(t)
+	for i, snd := range []struct {
+		category, author string
+		uses             int64
+	}{
+		{"fitness", "DJ Pump", 5000},
+		{"fitness", "DJ Pump", 3000},
+		{"fitness", "Solo", 6000},
+		{"fitness", "", 1000},
+		{"fitness", "   ", 500},
+		{"gaming", "Solo", 9000},
+		{"gaming", "Gamer", 100},
+	} {
+		sound := &Sound{
+			Title:     fmt.Sprintf("sound %d", i),
+			Author:    snd.author,
+			URL:       fmt.Sprintf("https://www.tiktok.com/music/sound-%d", i),
+			UsesCount: snd.uses,
+			Category:  snd.category,
+		}
+		if err := s.SaveSound(sound); err != nil {
+			t.Fatalf("SaveSound() error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		category string
+		limit    int
+		want     []AuthorStat
+	}{
+		{
+			name:     "one category, blank authors grouped as Unknown",
+			category: "fitness",
+			limit:    10,
+			want: []AuthorStat{
+				{Author: "DJ Pump", SoundCount: 2, TotalUses: 8000},
+				{Author: "Solo", SoundCount: 1, TotalUses: 6000},
+				{Author: "Unknown", SoundCount: 2, TotalUses: 1500},
+			},
+		},
+		{
+			name:  "all categories",
+			limit: 2,
+			want: []AuthorStat{
+				{Author: "Solo", SoundCount: 2, TotalUses: 15000},
+				{Author: "DJ Pump", SoundCount: 2, TotalUses: 8000},
+			},
+		},
+		{name: "empty category", category: "dance", limit: 10, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetTopAuthors(tt.category, tt.limit)
+			if err != nil {
+				t.Fatalf("GetTopAuthors() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetTopAuthors(%q, %d) = %+v, want %+v", tt.category, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasSoundHistory(t *testing.T) {
+	s := newTestStorage(t)
+
+	hasHistory, err := s.HasSoundHistory()
+	if err != nil {
+		t.Fatalf("HasSoundHistory() error: %v", err)
+	}
+	if hasHistory {
+		t.Error("HasSoundHistory() = true on an empty database")
+	}
+
+	// A sound alone is not history
+	sound := addSound(t, s, "fitness", "gym anthem", 100)
+	if hasHistory, _ := s.HasSoundHistory(); hasHistory {
+		t.Error("HasSoundHistory() = true with sounds but no history")
+	}
+
+	addHistory(t, s, sound.ID, 100, time.Now())
+	if hasHistory, _ := s.HasSoundHistory(); !hasHistory {
+		t.Error("HasSoundHistory() = false after recording history")
+	}
+}
+
+func TestGetTopSoundsByUses(t *testing.T) {
+	s := newTestStorage(t)
+	addSound(t, s, "fitness", "small", 10)
+	addSound(t, s, "fitness", "huge", 1000)
+	addSound(t, s, "fitness", "medium", 100)
+	addSound(t, s, "gaming", "other niche", 5000)
+
+	tests := []struct {
+		limit int
+		want  []string
+	}{
+		{limit: 10, want: []string{"huge", "medium", "small"}},
+		{limit: 2, want: []string{"huge", "medium"}},
+	}
+	for _, tt := range tests {
+		sounds, err := s.GetTopSoundsByUses("fitness", tt.limit)
+		if err != nil {
+			t.Fatalf("GetTopSoundsByUses() error: %v", err)
+		}
+		var titles []string
+		for _, sound := range sounds {
+			titles = append(titles, sound.Title)
+		}
+		if !reflect.DeepEqual(titles, tt.want) {
+			t.Errorf("GetTopSoundsByUses(limit %d) = %v, want %v", tt.limit, titles, tt.want)
+		}
+	}
+}
+
+func TestSetAlertMode(t *testing.T) {
+	s := newTestStorage(t)
+	if err := s.CreateUser(1); err != nil {
+		t.Fatalf("CreateUser() error: %v", err)
+	}
+	if user, _ := s.GetUser(1); user.AlertMode != AlertModeGrowth {
+		t.Errorf("new user AlertMode = %q, want %q", user.AlertMode, AlertModeGrowth)
+	}
+
+	for _, mode := range []string{AlertModePopularity, AlertModeGrowth} {
+		if err := s.SetAlertMode(1, mode); err != nil {
+			t.Fatalf("SetAlertMode() error: %v", err)
+		}
+		if user, _ := s.GetUser(1); user.AlertMode != mode {
+			t.Errorf("AlertMode = %q, want %q", user.AlertMode, mode)
+		}
+	}
+}
+
+func TestSetAlertSort(t *testing.T) {
+	s := newTestStorage(t)
+	if err := s.CreateUser(1); err != nil {
+		t.Fatalf("CreateUser() error: %v", err)
+	}
+	if user, _ := s.GetUser(1); user.AlertSort != AlertSortGrowth {
+		t.Errorf("new user AlertSort = %q, want %q", user.AlertSort, AlertSortGrowth)
+	}
+
+	for _, order := range []string{AlertSortUses, AlertSortRecent, AlertSortGrowth} {
+		if err := s.SetAlertSort(1, order); err != nil {
+			t.Fatalf("SetAlertSort() error: %v", err)
+		}
+		if user, _ := s.GetUser(1); user.AlertSort != order {
+			t.Errorf("AlertSort = %q, want %q", user.AlertSort, order)
+		}
+	}
+}
+
+func TestSetAlertFormat(t *testing.T) {
+	s := newTestStorage(t)
+	if err := s.CreateUser(1); err != nil {
+		t.Fatalf("CreateUser() error: %v", err)
+	}
+	if user, _ := s.GetUser(1); user.AlertFormat != AlertFormatDetailed {
+		t.Errorf("new user AlertFormat = %q, want %q", user.AlertFormat, AlertFormatDetailed)
+	}
+
+	for _, format := range []string{AlertFormatCompact, AlertFormatDetailed} {
+		if err := s.SetAlertFormat(1, format); err != nil {
+			t.Fatalf("SetAlertFormat() error: %v", err)
+		}
+		if user, _ := s.GetUser(1); user.AlertFormat != format {
+			t.Errorf("AlertFormat = %q, want %q", user.AlertFormat, format)
+		}
+	}
+}
+
+func TestGetSoundHistoryBetween(t *testing.T) {
+	s := newTestStorage(t)
+	sound := addSound(t, s, "fitness", "gym anthem", 400)
+	start := time.Now().Add(-10 * time.Hour).Truncate(time.Second)
+	for i, uses := range []int64{100, 200, 300, 400} {
+		addHistory(t, s, sound.ID, uses, start.Add(time.Duration(i)*time.Hour))
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []int64
+	}{
+		{name: "inclusive bounds", from: start.Add(time.Hour), to: start.Add(2 * time.Hour), want: []int64{200, 300}},
+		{name: "everything", from: start.Add(-time.Hour), to: start.Add(5 * time.Hour), want: []int64{100, 200, 300, 400}},
+		{name: "empty window", from: start.Add(10 * time.Hour), to: start.Add(11 * time.Hour), want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history, err := s.GetSoundHistoryBetween(sound.ID, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("GetSoundHistoryBetween() error: %v", err)
+			}
+			var got []int64
+			for _, h := range history {
+				got = append(got, h.UsesCount)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("uses = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetFullSoundHistory(t *testing.T) {
+	s := newTestStorage(t)
+	sound := addSound(t, s, "fitness", "gym anthem", 400)
+	other := addSound(t, s, "fitness", "other", 50)
+	now := time.Now()
+	// Inserted out of order, months apart
+	addHistory(t, s, sound.ID, 300, now.Add(-time.Hour))
+	addHistory(t, s, sound.ID, 100, now.Add(-90*24*time.Hour))
+	addHistory(t, s, sound.ID, 200, now.Add(-30*24*time.Hour))
+	addHistory(t, s, other.ID, 50, now)
+
+	history, err := s.GetFullSoundHistory(sound.ID)
+	if err != nil {
+		t.Fatalf("GetFullSoundHistory() error: %v", err)
+	}
+	var got []int64
+	for _, h := range history {
+		got = append(got, h.UsesCount)
+	}
+	if want := []int64{100, 200, 300}; !reflect.DeepEqual(got, want) {
+		t.Errorf("uses = %v, want %v oldest first", got, want)
+	}
+}
+
+func TestIncrementDailyAlerts(t *testing.T) {
+	s := newTestStorage(t)
+	if err := s.CreateUser(1); err != nil {
+		t.Fatalf("CreateUser() error: %v", err)
+	}
+
+	steps := []struct {
+		day       string
+		wantCount int
+	}{
+		{"2024-05-01", 1},
+		{"2024-05-01", 2},
+		{"2024-05-02", 1}, // a new day restarts the counter
+		{"2024-05-02", 2},
+	}
+	for _, step := range steps {
+		if err := s.IncrementDailyAlerts(1, step.day); err != nil {
+			t.Fatalf("IncrementDailyAlerts() error: %v", err)
+		}
+		user, err := s.GetUser(1)
+		if err != nil {
+			t.Fatalf("GetUser() error: %v", err)
+		}
+		if user.AlertsDay != step.day || user.AlertsToday != step.wantCount {
+			t.Errorf("after increment on %s: day %s count %d, want %s count %d", step.day, user.AlertsDay, user.AlertsToday, step.day, step.wantCount)
+		}
+	}
+}
+
+func TestGetSoundsWithoutRecentHistory(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now()
+
+	fresh := addSound(t, s, "fitness", "fresh", 100)
+	addHistory(t, s, fresh.ID, 100, now.Add(-time.Hour))
+	stale := addSound(t, s, "fitness", "stale", 100)
+	addHistory(t, s, stale.ID, 100, now.Add(-30*time.Hour))
+	addSound(t, s, "fitness", "never collected", 100)
+	otherNiche := addSound(t, s, "gaming", "other niche stale", 100)
+	addHistory(t, s, otherNiche.ID, 100, now.Add(-30*time.Hour))
+
+	tests := []struct {
+		name       string
+		category   string
+		sinceHours int
+		want       []string
+	}{
+		{name: "one category", category: "fitness", sinceHours: 24, want: []string{"stale", "never collected"}},
+		{name: "all categories", sinceHours: 24, want: []string{"stale", "never collected", "other niche stale"}},
+		{name: "wider window", category: "fitness", sinceHours: 48, want: []string{"never collected"}},
+		{name: "category without gaps", category: "comedy", sinceHours: 24, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sounds, err := s.GetSoundsWithoutRecentHistory(tt.category, tt.sinceHours)
+			if err != nil {
+				t.Fatalf("GetSoundsWithoutRecentHistory() error: %v", err)
+			}
+			var got []string
+			for _, sound := range sounds {
+				got = append(got, sound.Title)
+			}
+			sort.Strings(got)
+			want := append([]string(nil), tt.want...)
+			sort.Strings(want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("GetSoundsWithoutRecentHistory(%q, %d) = %v, want %v", tt.category, tt.sinceHours, got, want)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package storage
 
-import "time"
+import (
+	"errors"
+	"strings"
+	"time"
+)
 
 // Storage defines the interface for data persistence
 type Storage interface {
@@ -10,30 +14,175 @@ type Storage interface {
 	// Close closes the database connection
 	Close() error
 
+	// Data integrity operations
+	ValidateData() ([]Anomaly, error)
+	RepairData() error
+	SchemaFingerprint() (string, error)
+
 	// Sound operations
 	SaveSound(sound *Sound) error
 	GetSoundByURL(url string) (*Sound, error)
+	GetSoundByTitleAuthor(title, author string) (*Sound, error)
+	GetSoundByMusicID(musicID string) (*Sound, error)
+	GetSoundByID(id int64) (*Sound, error)
 	GetSoundsByCategory(category string, limit int) ([]Sound, error)
+	GetTopSoundsByUses(category string, limit int) ([]Sound, error)
+	GetSoundsWithoutRecentHistory(category string, sinceHours int) ([]Sound, error)
+	GetSoundRank(soundID int64, category string) (int, error)
+	GetTopAuthors(category string, limit int) ([]AuthorStat, error)
 	UpdateSound(sound *Sound) error
+	RecordSoundCategory(soundID int64, category string) error
+	GetSoundCategories(soundID int64, since time.Time) ([]string, error)
+	RecordSoundRegion(soundID int64, region string) error
+	GetRegionSoundIDs(region string, since time.Time) (map[int64]bool, error)
+	SetUserRegion(telegramID int64, region string) error
 
 	// Sound history operations
-	SaveSoundHistory(soundID int64, usesCount int64) error
+	SaveSoundHistory(soundID int64, usesCount int64, source string) error
+	HasSoundHistory() (bool, error)
+	SeedBaselineHistory(recordedAt time.Time) (int, error)
 	GetSoundHistoryByTime(soundID int64, hoursAgo int) (*SoundHistory, error)
+	GetSoundHistoryRange(soundID int64, since time.Time) ([]SoundHistory, error)
+	GetSoundHistoryBetween(soundID int64, from, to time.Time) ([]SoundHistory, error)
+	GetFullSoundHistory(soundID int64) ([]SoundHistory, error)
 	GetAllSoundsWithHistory(category string, hoursAgo int) ([]Sound, map[int64]*SoundHistory, error)
+	GetCategoryGrowthSum(category string, since time.Time) (float64, error)
+	GetCategoryLastUpdated(category string) (time.Time, error)
+	GetCategoryDataStats(category string) (*CategoryDataStats, error)
+	SaveTrendScores(category string, scores []TrendScore) error
+	GetTrendScores(category string) ([]TrendScore, error)
+	GetSourceDiscrepancies(since time.Time, minDiffPercent float64) ([]SourceDiscrepancy, error)
 
 	// User operations
 	CreateUser(telegramID int64) error
 	GetUser(telegramID int64) (*User, error)
 	UpdateUserNiches(telegramID int64, niches string) error
 	GetAllUsers() ([]User, error)
+	MergeUsers(keepID, mergeID int64) error
 	SetPremium(telegramID int64, isPremium bool) error
+	SetPremiumExpiry(telegramID int64, expiresAt time.Time) error
+	GrantPremium(telegramID int64, period time.Duration) (time.Time, error)
+	CheckAndExpirePremium() error
+	SetAlertLimit(telegramID int64, limit int) error
+	SetAlertMode(telegramID int64, mode string) error
+	SetAlertSort(telegramID int64, order string) error
+	SetAlertFormat(telegramID int64, format string) error
+	SetBetaEnrolled(telegramID int64, enrolled bool) error
+	CountBetaUsers() (int, error)
+	SetUserTimezone(telegramID int64, timezone string) error
+	IncrementDailyAlerts(telegramID int64, day string) error
+
+	// Growth threshold operations
+	SetUserMinGrowth(telegramID int64, minGrowth float64) error
+	SetNicheMinGrowth(telegramID int64, category string, minGrowth float64) error
+	GetNicheMinGrowths(telegramID int64) (map[string]float64, error)
+
+	// Niche label operations
+	SetNicheLabel(telegramID int64, category, label string) error
+	GetNicheLabels(telegramID int64) (map[string]string, error)
+	MuteNiche(telegramID int64, category string) error
+	UnmuteNiche(telegramID int64, category string) error
+	GetMutedNiches(telegramID int64) (map[string]bool, error)
+
+	// Alert operations
+	SaveLastAlert(telegramID int64, category, message string) error
+	GetLastAlert(telegramID int64, category string) (*LastAlert, error)
+	SaveAlertLatency(telegramID int64, category string, latency time.Duration) error
+	GetAlertLatencies(since time.Time) ([]time.Duration, error)
+	MarkSoundsAlerted(soundIDs []int64, at, windowStart time.Time) error
+	GetAlertedSoundsSince(since time.Time) (map[int64]bool, error)
+	PruneSentAlerts(olderThan time.Time) (int64, error)
+	SetShowRepeats(telegramID int64, show bool) error
+	SetInstantAlerts(telegramID int64, instant bool) error
+	ClaimUsersForAlert(batchSize int, lockTTL time.Duration, roundStart time.Time) ([]User, error)
+	RenewUserClaim(user *User) (bool, error)
+	ReleaseUserClaim(user *User) error
+	SetBlocked(telegramID int64, blocked bool) error
+	GetAlertUsers() ([]User, error)
+	SetUserMinUses(telegramID int64, minUses int64) error
+	SetBoostUntil(telegramID int64, until time.Time) error
+	IsFeatureEnabled(name string) (bool, error)
+	SetFeature(name string, enabled bool) error
+	SetDigestFrequency(telegramID int64, frequency string) error
+	QueueDigestSounds(telegramID int64, niche string, sounds []TrendingSound, at time.Time) error
+	GetDigestSounds(telegramID int64) (map[string][]TrendingSound, error)
+	MarkDigestSent(telegramID int64, sentAt time.Time) error
+	RecordSentAlert(telegramID int64, niche string, sounds []TrendingSound, at time.Time) error
+	GetUserAlertHistory(telegramID int64, limit int) ([]SentAlert, error)
+	GetUserAlertedSoundsSince(telegramID int64, since time.Time) (map[int64]bool, error)
+	MarkChannelPosted(soundIDs []int64, at time.Time) error
+	GetChannelPostedSounds() (map[int64]bool, error)
+	GetGraduates(category string, maxUses int64, since time.Time, limit int) ([]Graduate, error)
+
+	// Nomination operations
+	SaveNomination(telegramID int64, url, category string) error
+	GetPendingNominations() ([]Nomination, error)
+	MarkNominationTracked(id int64) error
+
+	// Session operations
+	SetSession(telegramID int64, state, payload string) error
+	GetSession(telegramID int64, maxAge time.Duration) (*Session, error)
+	ClearSession(telegramID int64) error
+
+	// Follow operations
+	FollowSound(telegramID, soundID, lastMilestone int64) error
+	GetFollowedSounds() ([]FollowedSound, error)
+	SetFollowMilestone(telegramID, soundID, milestone int64) error
+
+	// Engagement operations
+	RecordNicheEngagement(telegramID int64, category string) error
+	RecordAlertClick(telegramID, soundID int64, category string) (bool, error)
+	GetUserNicheEngagement(telegramID int64) ([]NicheEngagement, error)
+
+	// Settings operations
+	GetSetting(key string) (string, error)
+	SetSetting(key, value string) error
+}
+
+// DedupStrategy selects how SaveSoundWithHistory recognizes a sound it has already stored
+type DedupStrategy string
+
+const (
+	DedupByURL         DedupStrategy = "url"          // Canonical sound URL (default)
+	DedupByTitleAuthor DedupStrategy = "title_author" // Same title and author
+	DedupByMusicID     DedupStrategy = "music_id"     // TikTok music_id, falling back to URL when missing
+)
+
+// findExistingSound looks up a stored copy of sound using the given strategy
+func findExistingSound(s Storage, sound *Sound, strategy DedupStrategy) (*Sound, error) {
+	switch strategy {
+	case DedupByTitleAuthor:
+		return s.GetSoundByTitleAuthor(sound.Title, sound.Author)
+	case DedupByMusicID:
+		if sound.MusicID != "" {
+			existing, err := s.GetSoundByMusicID(sound.MusicID)
+			if !errors.Is(err, ErrSoundNotFound) {
+				return existing, err
+			}
+		}
+		return s.GetSoundByURL(sound.URL)
+	default:
+		return s.GetSoundByURL(sound.URL)
+	}
 }
 
-// SaveSoundWithHistory is a helper to save sound and its history in one transaction
-func SaveSoundWithHistory(s Storage, sound *Sound) error {
+// SaveSoundWithHistory is a helper to save sound and its history in one transaction.
+// strategy decides which stored sound, if any, the new data belongs to.
+func SaveSoundWithHistory(s Storage, sound *Sound, strategy DedupStrategy) error {
+	// Always store the canonical URL so variants don't create duplicates
+	sound.URL = CanonicalizeURL(sound.URL)
+
+	// A sound without a category can never be found by GetSoundsByCategory
+	if strings.TrimSpace(sound.Category) == "" {
+		return ErrEmptyCategory
+	}
+
 	// Try to get existing sound
-	existing, err := s.GetSoundByURL(sound.URL)
-	if err == nil && existing != nil {
+	existing, err := findExistingSound(s, sound, strategy)
+	if err != nil && !errors.Is(err, ErrSoundNotFound) {
+		return err
+	}
+	if err == nil {
 		// Update existing sound
 		sound.ID = existing.ID
 		sound.CreatedAt = existing.CreatedAt
@@ -56,6 +205,20 @@ func SaveSoundWithHistory(s Storage, sound *Sound) error {
 		sound.ID = created.ID
 	}
 
+	// Remember every category the sound shows up in
+	if err := s.RecordSoundCategory(sound.ID, sound.Category); err != nil {
+		return err
+	}
+
+	// And every region, so detection can be limited to one region's trends
+	region := sound.Region
+	if region == "" {
+		region = RegionGlobal
+	}
+	if err := s.RecordSoundRegion(sound.ID, region); err != nil {
+		return err
+	}
+
 	// Save history record
-	return s.SaveSoundHistory(sound.ID, sound.UsesCount)
+	return s.SaveSoundHistory(sound.ID, sound.UsesCount, sound.Source)
 }
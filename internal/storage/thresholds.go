@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// SetUserMinGrowth stores a user's global minimum growth for alerts. 0 restores the default.
+func (s *SQLiteStorage) SetUserMinGrowth(telegramID int64, minGrowth float64) error {
+	_, err := s.db.Exec("UPDATE users SET min_growth = ? WHERE telegram_id = ?", minGrowth, telegramID)
+	if err != nil {
+		return fmt.Errorf("failed to set user min growth: %w", err)
+	}
+	return nil
+}
+
+// SetUserMinUses stores the minimum uses a sound needs to appear in a user's alerts. 0 restores the default.
+func (s *SQLiteStorage) SetUserMinUses(telegramID int64, minUses int64) error {
+	_, err := s.db.Exec("UPDATE users SET min_uses = ? WHERE telegram_id = ?", minUses, telegramID)
+	if err != nil {
+		return fmt.Errorf("failed to set user min uses: %w", err)
+	}
+	return nil
+}
+
+// SetBoostUntil stores when a user's /boost ends. A zero time ends the boost now.
+func (s *SQLiteStorage) SetBoostUntil(telegramID int64, until time.Time) error {
+	var value interface{}
+	if !until.IsZero() {
+		value = until
+	}
+	_, err := s.db.Exec("UPDATE users SET boost_until = ? WHERE telegram_id = ?", value, telegramID)
+	if err != nil {
+		return fmt.Errorf("failed to set boost: %w", err)
+	}
+	return nil
+}
+
+// SetNicheMinGrowth stores a user's minimum growth for one niche. 0 removes the override.
+func (s *SQLiteStorage) SetNicheMinGrowth(telegramID int64, category string, minGrowth float64) error {
+	if minGrowth == 0 {
+		_, err := s.db.Exec("DELETE FROM user_niche_settings WHERE telegram_id = ? AND category = ?", telegramID, category)
+		if err != nil {
+			return fmt.Errorf("failed to remove niche min growth: %w", err)
+		}
+		return nil
+	}
+
+	query := `
+		INSERT INTO user_niche_settings (telegram_id, category, min_growth)
+		VALUES (?, ?, ?)
+		ON CONFLICT(telegram_id, category) DO UPDATE SET min_growth = excluded.min_growth
+	`
+	_, err := s.db.Exec(query, telegramID, category, minGrowth)
+	if err != nil {
+		return fmt.Errorf("failed to set niche min growth: %w", err)
+	}
+	return nil
+}
+
+// GetNicheMinGrowths returns a user's per-niche minimum growth overrides keyed by category
+func (s *SQLiteStorage) GetNicheMinGrowths(telegramID int64) (map[string]float64, error) {
+	rows, err := s.db.Query("SELECT category, min_growth FROM user_niche_settings WHERE telegram_id = ? AND min_growth > 0", telegramID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get niche min growths: %w", err)
+	}
+	defer rows.Close()
+
+	thresholds := make(map[string]float64)
+	for rows.Next() {
+		var category string
+		var minGrowth float64
+		if err := rows.Scan(&category, &minGrowth); err != nil {
+			return nil, fmt.Errorf("failed to scan niche min growth: %w", err)
+		}
+		thresholds[category] = minGrowth
+	}
+
+	return thresholds, nil
+}
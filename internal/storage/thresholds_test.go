@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNicheMinGrowth(t *testing.T) {
+	s := newTestStorage(t)
+	for _, id := range []int64{1, 2} {
+		if err := s.CreateUser(id); err != nil {
+			t.Fatalf("CreateUser() error: %v", err)
+		}
+	}
+
+	steps := []struct {
+		telegramID int64
+		category   string
+		minGrowth  float64
+		want       map[string]float64
+	}{
+		{telegramID: 1, category: "fitness", minGrowth: 200, want: map[string]float64{"fitness": 200}},
+		{telegramID: 1, category: "gaming", minGrowth: 50, want: map[string]float64{"fitness": 200, "gaming": 50}},
+		{telegramID: 1, category: "fitness", minGrowth: 300, want: map[string]float64{"fitness": 300, "gaming": 50}},
+		{telegramID: 1, category: "gaming", minGrowth: 0, want: map[string]float64{"fitness": 300}},
+		{telegramID: 2, category: "fitness", minGrowth: 120, want: map[string]float64{"fitness": 120}},
+	}
+	for i, step := range steps {
+		if err := s.SetNicheMinGrowth(step.telegramID, step.category, step.minGrowth); err != nil {
+			t.Fatalf("step %d: SetNicheMinGrowth() error: %v", i, err)
+		}
+		got, err := s.GetNicheMinGrowths(step.telegramID)
+		if err != nil {
+			t.Fatalf("step %d: GetNicheMinGrowths() error: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("step %d: thresholds = %v, want %v", i, got, step.want)
+		}
+	}
+}
+
+func TestSetUserMinGrowth(t *testing.T) {
+	s := newTestStorage(t)
+	if err := s.CreateUser(1); err != nil {
+		t.Fatalf("CreateUser() error: %v", err)
+	}
+
+	for _, minGrowth := range []float64{250, 0} {
+		if err := s.SetUserMinGrowth(1, minGrowth); err != nil {
+			t.Fatalf("SetUserMinGrowth(%v) error: %v", minGrowth, err)
+		}
+		user, err := s.GetUser(1)
+		if err != nil {
+			t.Fatalf("GetUser() error: %v", err)
+		}
+		if user.MinGrowth != minGrowth {
+			t.Errorf("MinGrowth = %v, want %v", user.MinGrowth, minGrowth)
+		}
+	}
+}
+
+func TestSetUserMinUses(t *testing.T) {
+	s := newTestStorage(t)
+	if err := s.CreateUser(1); err != nil {
+		t.Fatalf("CreateUser() error: %v", err)
+	}
+
+	for _, minUses := range []int64{5000, 0} {
+		if err := s.SetUserMinUses(1, minUses); err != nil {
+			t.Fatalf("SetUserMinUses(%d) error: %v", minUses, err)
+		}
+		user, err := s.GetUser(1)
+		if err != nil {
+			t.Fatalf("GetUser() error: %v", err)
+		}
+		if user.MinUses != minUses {
+			t.Errorf("MinUses = %d, want %d", user.MinUses, minUses)
+		}
+	}
+}
+
+func TestSetBoostUntil(t *testing.T) {
+	s := newTestStorage(t)
+	if err := s.CreateUser(1); err != nil {
+		t.Fatalf("CreateUser() error: %v", err)
+	}
+
+	until := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	for _, want := range []time.Time{until, {}} {
+		if err := s.SetBoostUntil(1, want); err != nil {
+			t.Fatalf("SetBoostUntil(%s) error: %v", want, err)
+		}
+		user, err := s.GetUser(1)
+		if err != nil {
+			t.Fatalf("GetUser() error: %v", err)
+		}
+		if !user.BoostUntil.Equal(want) {
+			t.Errorf("BoostUntil = %s, want %s", user.BoostUntil, want)
+		}
+	}
+}
+
+func TestMergeUsersNicheMinGrowth(t *testing.T) {
+	s := newTestStorage(t)
+	for _, id := range []int64{1, 2} {
+		if err := s.CreateUser(id); err != nil {
+			t.Fatalf("CreateUser() error: %v", err)
+		}
+	}
+	// Both set fitness: the kept user's value wins; the duplicate's gaming value moves over
+	for _, o := range []struct {
+		id        int64
+		category  string
+		minGrowth float64
+	}{{1, "fitness", 200}, {2, "fitness", 500}, {2, "gaming", 80}} {
+		if err := s.SetNicheMinGrowth(o.id, o.category, o.minGrowth); err != nil {
+			t.Fatalf("SetNicheMinGrowth() error: %v", err)
+		}
+	}
+
+	if err := s.MergeUsers(1, 2); err != nil {
+		t.Fatalf("MergeUsers() error: %v", err)
+	}
+
+	got, err := s.GetNicheMinGrowths(1)
+	if err != nil {
+		t.Fatalf("GetNicheMinGrowths() error: %v", err)
+	}
+	if want := map[string]float64{"fitness": 200, "gaming": 80}; !reflect.DeepEqual(got, want) {
+		t.Errorf("thresholds = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// HostAliases maps alternative TikTok hosts to the canonical host.
+// Operators can extend it via the CANONICAL_HOST_ALIASES config.
+var HostAliases = map[string]string{
+	"tiktok.com":   "www.tiktok.com",
+	"m.tiktok.com": "www.tiktok.com",
+}
+
+// IsTikTokMusicURL reports whether rawURL points at a TikTok sound page, e.g. https://www.tiktok.com/music/name-123
+func IsTikTokMusicURL(rawURL string) bool {
+	u, err := url.Parse(CanonicalizeURL(rawURL))
+	if err != nil || u.Host != "www.tiktok.com" {
+		return false
+	}
+	slug, ok := strings.CutPrefix(u.Path, "/music/")
+	return ok && slug != "" && !strings.Contains(slug, "/")
+}
+
+// MusicIDFromURL extracts the numeric music_id ending a TikTok music URL slug, or "" if there is none
+func MusicIDFromURL(rawURL string) string {
+	u, err := url.Parse(CanonicalizeURL(rawURL))
+	if err != nil {
+		return ""
+	}
+	slug := u.Path[strings.LastIndex(u.Path, "/")+1:]
+	id := slug[strings.LastIndex(slug, "-")+1:]
+	if id == "" || strings.Trim(id, "0123456789") != "" {
+		return ""
+	}
+	return id
+}
+
+// CanonicalizeURL normalizes a sound URL so the same sound always maps to one row:
+// https scheme, lowercase canonical host, no query string, fragment or trailing slash.
+// Unparseable URLs are returned trimmed but otherwise unchanged.
+func CanonicalizeURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	host := strings.ToLower(u.Host)
+	if alias, ok := HostAliases[host]; ok {
+		host = alias
+	}
+
+	u.Scheme = "https"
+	u.Host = host
+	u.RawQuery = ""
+	u.Fragment = ""
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+
+	return u.String()
+}
+
+// soundRelatedTables lists tables keyed by sound_id whose rows follow a sound merged into another
+var soundRelatedTables = []string{
+	"sound_history",
+	"sound_categories",
+	"followed_sounds",
+	"trend_scores",
+	"alerted_sounds",
+	"digest_items",
+	"sent_alerts",
+	"channel_posts",
+	"sound_regions",
+}
+
+// CanonicalizeStoredURLs rewrites sounds saved before URL canonicalization to their canonical URL.
+// Sounds whose URLs collapse to the same canonical URL are merged into the most recently updated one:
+// related rows move to it, and rows that would collide with its own are dropped.
+// It returns how many sounds were rewritten or merged away; a second run changes nothing.
+func (s *SQLiteStorage) CanonicalizeStoredURLs() (int, error) {
+	rows, err := s.db.Query("SELECT id, url FROM sounds ORDER BY updated_at DESC, id DESC")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list sound urls: %w", err)
+	}
+
+	// Sound ids per canonical URL, most recently updated first
+	groups := make(map[string][]int64)
+	var canonical []string
+	stored := make(map[int64]string)
+	for rows.Next() {
+		var (
+			id  int64
+			raw string
+		)
+		if err := rows.Scan(&id, &raw); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan sound url: %w", err)
+		}
+		canon := CanonicalizeURL(raw)
+		if _, ok := groups[canon]; !ok {
+			canonical = append(canonical, canon)
+		}
+		groups[canon] = append(groups[canon], id)
+		stored[id] = raw
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, fmt.Errorf("failed to list sound urls: %w", err)
+	}
+	rows.Close()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	changed := 0
+	for _, canon := range canonical {
+		ids := groups[canon]
+		keepID := ids[0]
+
+		for _, mergeID := range ids[1:] {
+			if err := mergeSoundInto(tx, keepID, mergeID); err != nil {
+				return 0, err
+			}
+			changed++
+		}
+
+		if stored[keepID] != canon {
+			if _, err := tx.Exec("UPDATE sounds SET url = ? WHERE id = ?", canon, keepID); err != nil {
+				return 0, fmt.Errorf("failed to canonicalize sound url: %w", err)
+			}
+			changed++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit url canonicalization: %w", err)
+	}
+	return changed, nil
+}
+
+// mergeSoundInto moves mergeID's related rows to keepID and deletes mergeID.
+// When both sounds have a row with the same key, keepID's row wins.
+func mergeSoundInto(tx *sql.Tx, keepID, mergeID int64) error {
+	for _, table := range soundRelatedTables {
+		// OR IGNORE leaves rows that would collide with keepID's, which are then dropped
+		if _, err := tx.Exec(fmt.Sprintf("UPDATE OR IGNORE %s SET sound_id = ? WHERE sound_id = ?", table), keepID, mergeID); err != nil {
+			return fmt.Errorf("failed to move %s rows: %w", table, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE sound_id = ?", table), mergeID); err != nil {
+			return fmt.Errorf("failed to clear %s rows: %w", table, err)
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM sounds WHERE id = ?", mergeID); err != nil {
+		return fmt.Errorf("failed to delete duplicate sound: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCanonicalizeURL(t *testing.T) {
+	const canonical = "https://www.tiktok.com/music/gym-anthem-123"
+
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{name: "already canonical", url: canonical, want: canonical},
+		{name: "tracking query", url: canonical + "?is_from_webapp=1&sender_device=pc", want: canonical},
+		{name: "mobile host", url: "https://m.tiktok.com/music/gym-anthem-123", want: canonical},
+		{name: "bare host", url: "https://tiktok.com/music/gym-anthem-123", want: canonical},
+		{name: "uppercase host", url: "https://WWW.TikTok.com/music/gym-anthem-123", want: canonical},
+		{name: "http scheme", url: "http://www.tiktok.com/music/gym-anthem-123", want: canonical},
+		{name: "trailing slash", url: canonical + "/", want: canonical},
+		{name: "fragment", url: canonical + "#comments", want: canonical},
+		{name: "surrounding space", url: "  " + canonical + "\n", want: canonical},
+		{name: "everything at once", url: "http://M.TIKTOK.COM/music/gym-anthem-123/?lang=en#top", want: canonical},
+		{name: "not a url", url: "gym anthem", want: "gym anthem"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanonicalizeURL(tt.url); got != tt.want {
+				t.Errorf("CanonicalizeURL(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetSoundByURLVariants(t *testing.T) {
+	s := newTestStorage(t)
+	sound := &Sound{
+		Title:    "gym anthem",
+		URL:      "https://m.tiktok.com/music/gym-anthem-123?lang=en",
+		Category: "fitness",
+	}
+	if err := SaveSoundWithHistory(s, sound, DedupByURL); err != nil {
+		t.Fatalf("SaveSoundWithHistory() error: %v", err)
+	}
+
+	for _, variant := range []string{
+		"https://www.tiktok.com/music/gym-anthem-123",
+		"https://tiktok.com/music/gym-anthem-123/",
+		"http://m.tiktok.com/music/gym-anthem-123?is_from_webapp=1",
+	} {
+		got, err := s.GetSoundByURL(variant)
+		if err != nil {
+			t.Fatalf("GetSoundByURL(%q) error: %v", variant, err)
+		}
+		if got == nil || got.ID != sound.ID {
+			t.Errorf("GetSoundByURL(%q) = %+v, want sound %d", variant, got, sound.ID)
+		}
+	}
+}
+
+func TestCanonicalizeStoredURLs(t *testing.T) {
+	s := newTestStorage(t)
+	now := time.Now()
+
+	// Rows saved before canonicalization, two of them the same sound
+	insert := func(url string, uses int64, updated time.Time) int64 {
+		t.Helper()
+		res, err := s.db.Exec(`INSERT INTO sounds (title, author, url, uses_count, category, created_at, updated_at)
+			VALUES ('gym anthem', 'a', ?, ?, 'fitness', ?, ?)`, url, uses, updated, updated)
+		if err != nil {
+			t.Fatalf("insert sound: %v", err)
+		}
+		id, _ := res.LastInsertId()
+		return id
+	}
+	older := insert("https://m.tiktok.com/music/gym-anthem-123?lang=en", 1000, now.Add(-2*time.Hour))
+	newer := insert("https://www.tiktok.com/music/gym-anthem-123/", 1500, now.Add(-time.Hour))
+	other := insert("https://tiktok.com/music/other-456", 300, now)
+	clean := insert("https://www.tiktok.com/music/clean-789", 50, now)
+
+	addHistory(t, s, older, 1000, now.Add(-2*time.Hour))
+	addHistory(t, s, newer, 1500, now.Add(-time.Hour))
+
+	// Both rows followed by user 1: only one follow survives. User 2 followed only the older row.
+	for _, f := range []struct{ user, sound int64 }{{1, older}, {1, newer}, {2, older}} {
+		if err := s.FollowSound(f.user, f.sound, 0); err != nil {
+			t.Fatalf("FollowSound() error: %v", err)
+		}
+	}
+
+	changed, err := s.CanonicalizeStoredURLs()
+	if err != nil {
+		t.Fatalf("CanonicalizeStoredURLs() error: %v", err)
+	}
+	// older merged away, newer and other rewritten
+	if changed != 3 {
+		t.Errorf("changed = %d, want 3", changed)
+	}
+
+	tests := []struct {
+		url    string
+		wantID int64
+		uses   int64
+	}{
+		{url: "https://www.tiktok.com/music/gym-anthem-123", wantID: newer, uses: 1500},
+		{url: "https://www.tiktok.com/music/other-456", wantID: other, uses: 300},
+		{url: "https://www.tiktok.com/music/clean-789", wantID: clean, uses: 50},
+	}
+	for _, tt := range tests {
+		var id, uses int64
+		err := s.db.QueryRow("SELECT id, uses_count FROM sounds WHERE url = ?", tt.url).Scan(&id, &uses)
+		if err != nil {
+			t.Errorf("stored url %q not found: %v", tt.url, err)
+			continue
+		}
+		if id != tt.wantID || uses != tt.uses {
+			t.Errorf("%s: got sound %d with %d uses, want %d with %d", tt.url, id, uses, tt.wantID, tt.uses)
+		}
+	}
+
+	if _, err := s.GetSoundByID(older); err == nil {
+		t.Error("merged duplicate still exists")
+	}
+
+	history, err := s.GetFullSoundHistory(newer)
+	if err != nil {
+		t.Fatalf("GetFullSoundHistory() error: %v", err)
+	}
+	if len(history) != 2 {
+		t.Errorf("survivor has %d history rows, want both sounds' 2", len(history))
+	}
+
+	follows, err := s.GetFollowedSounds()
+	if err != nil {
+		t.Fatalf("GetFollowedSounds() error: %v", err)
+	}
+	if len(follows) != 2 {
+		t.Errorf("got %d follows, want one per user", len(follows))
+	}
+	for _, f := range follows {
+		if f.Sound.ID != newer {
+			t.Errorf("user %d follows sound %d, want %d", f.TelegramID, f.Sound.ID, newer)
+		}
+	}
+
+	// Running it again is a no-op
+	changed, err = s.CanonicalizeStoredURLs()
+	if err != nil {
+		t.Fatalf("second CanonicalizeStoredURLs() error: %v", err)
+	}
+	if changed != 0 {
+		t.Errorf("second run changed %d sounds, want 0", changed)
+	}
+}
+
+func TestIsTikTokMusicURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://www.tiktok.com/music/gym-anthem-123", true},
+		{"https://m.tiktok.com/music/gym-anthem-123?lang=en", true},
+		{"tiktok.com/music/gym-anthem-123", false},
+		{"https://www.tiktok.com/music/", false},
+		{"https://www.tiktok.com/music/a/b", false},
+		{"https://www.tiktok.com/@someone/video/123", false},
+		{"https://example.com/music/gym-anthem-123", false},
+		{"gym anthem", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := IsTikTokMusicURL(tt.url); got != tt.want {
+				t.Errorf("IsTikTokMusicURL(%q) = %v, want %v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMusicIDFromURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.tiktok.com/music/gym-anthem-7301", "7301"},
+		{"https://www.tiktok.com/music/7301", "7301"},
+		{"https://m.tiktok.com/music/gym-anthem-7301/?lang=en", "7301"},
+		{"https://www.tiktok.com/music/gym-anthem", ""},
+		{"https://www.tiktok.com/music/gym-anthem-", ""},
+		{"https://www.tiktok.com/music/gym-12ab", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := MusicIDFromURL(tt.url); got != tt.want {
+				t.Errorf("MusicIDFromURL(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package storage
+
+import "fmt"
+
+// Anomaly kinds reported by ValidateData
+const (
+	AnomalyNegativeUses    = "negative_uses_count"
+	AnomalyEmptyCategory   = "empty_category"
+	AnomalyOrphanedHistory = "orphaned_history"
+)
+
+// Anomaly describes a class of bad rows found during validation
+type Anomaly struct {
+	Kind  string `json:"kind"`
+	Count int    `json:"count"`
+}
+
+// anomalyChecks maps each anomaly kind to a query counting affected rows
+var anomalyChecks = []struct {
+	kind  string
+	query string
+}{
+	{AnomalyNegativeUses, `
+		SELECT (SELECT COUNT(*) FROM sounds WHERE uses_count < 0)
+		     + (SELECT COUNT(*) FROM sound_history WHERE uses_count < 0)`},
+	{AnomalyEmptyCategory, `SELECT COUNT(*) FROM sounds WHERE TRIM(COALESCE(category, '')) = ''`},
+	{AnomalyOrphanedHistory, `
+		SELECT COUNT(*) FROM sound_history h
+		WHERE NOT EXISTS (SELECT 1 FROM sounds s WHERE s.id = h.sound_id)`},
+}
+
+// backfillCategoriesQuery restores empty sound categories from the most recent
+// non-empty category the sound was collected in
+const backfillCategoriesQuery = `
+	UPDATE sounds
+	SET category = (
+		SELECT sc.category FROM sound_categories sc
+		WHERE sc.sound_id = sounds.id AND TRIM(sc.category) != ''
+		ORDER BY sc.last_seen_at DESC LIMIT 1
+	)
+	WHERE TRIM(COALESCE(category, '')) = ''
+	  AND EXISTS (
+		SELECT 1 FROM sound_categories sc
+		WHERE sc.sound_id = sounds.id AND TRIM(sc.category) != ''
+	)`
+
+// BackfillEmptyCategories fixes sounds saved with a blank category, returning how many were updated
+func (s *SQLiteStorage) BackfillEmptyCategories() (int64, error) {
+	result, err := s.db.Exec(backfillCategoriesQuery)
+	if err != nil {
+		return 0, fmt.Errorf("failed to backfill empty categories: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ValidateData looks for corrupted rows and returns one Anomaly per affected kind
+func (s *SQLiteStorage) ValidateData() ([]Anomaly, error) {
+	var anomalies []Anomaly
+	for _, check := range anomalyChecks {
+		var count int
+		if err := s.db.QueryRow(check.query).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to check %s: %w", check.kind, err)
+		}
+		if count > 0 {
+			anomalies = append(anomalies, Anomaly{Kind: check.kind, Count: count})
+		}
+	}
+	return anomalies, nil
+}
+
+// RepairData fixes the anomalies ValidateData reports:
+// negative counts are reset to zero, empty categories are restored from the
+// most recent category the sound was collected in, and orphaned history is deleted
+func (s *SQLiteStorage) RepairData() error {
+	repairs := []struct {
+		name  string
+		query string
+	}{
+		{"reset negative sound uses", `UPDATE sounds SET uses_count = 0 WHERE uses_count < 0`},
+		{"reset negative history uses", `UPDATE sound_history SET uses_count = 0 WHERE uses_count < 0`},
+		{"restore empty categories", backfillCategoriesQuery},
+		{"delete orphaned history", `
+			DELETE FROM sound_history
+			WHERE NOT EXISTS (SELECT 1 FROM sounds s WHERE s.id = sound_history.sound_id)`},
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin repair: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, repair := range repairs {
+		if _, err := tx.Exec(repair.query); err != nil {
+			return fmt.Errorf("failed to %s: %w", repair.name, err)
+		}
+	}
+
+	return tx.Commit()
+}
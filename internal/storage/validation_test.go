@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// corrupt seeds one of each anomaly ValidateData knows about
+func corrupt(t *testing.T, s *SQLiteStorage) (negative, uncategorized *Sound) {
+	t.Helper()
+	negative = addSound(t, s, "fitness", "negative", 100)
+	uncategorized = addSound(t, s, "gaming", "uncategorized", 100)
+	if err := s.RecordSoundCategory(uncategorized.ID, "gaming"); err != nil {
+		t.Fatalf("RecordSoundCategory() error: %v", err)
+	}
+	addHistory(t, s, negative.ID, -5, time.Now())
+
+	for _, query := range []string{
+		`UPDATE sounds SET uses_count = -1 WHERE title = 'negative'`,
+		`UPDATE sounds SET category = '' WHERE title = 'uncategorized'`,
+	} {
+		if _, err := s.db.Exec(query); err != nil {
+			t.Fatalf("corrupt: %v", err)
+		}
+	}
+
+	// History of a sound that no longer exists can only be written with foreign keys off
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn() error: %v", err)
+	}
+	defer conn.Close()
+	for _, query := range []string{
+		`PRAGMA foreign_keys = OFF`,
+		`INSERT INTO sound_history (sound_id, uses_count, source) VALUES (9999, 10, ''), (9999, 20, '')`,
+		`PRAGMA foreign_keys = ON`,
+	} {
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			t.Fatalf("corrupt: %v", err)
+		}
+	}
+	return negative, uncategorized
+}
+
+func TestValidateData(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt bool
+		want    []Anomaly
+	}{
+		{name: "clean database", want: nil},
+		{
+			name:    "every anomaly",
+			corrupt: true,
+			want: []Anomaly{
+				{Kind: AnomalyNegativeUses, Count: 2},
+				{Kind: AnomalyEmptyCategory, Count: 1},
+				{Kind: AnomalyOrphanedHistory, Count: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(t)
+			addSound(t, s, "dance", "healthy", 500)
+			if tt.corrupt {
+				corrupt(t, s)
+			}
+
+			got, err := s.ValidateData()
+			if err != nil {
+				t.Fatalf("ValidateData() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateData() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepairData(t *testing.T) {
+	s := newTestStorage(t)
+	negative, uncategorized := corrupt(t, s)
+
+	if err := s.RepairData(); err != nil {
+		t.Fatalf("RepairData() error: %v", err)
+	}
+
+	anomalies, err := s.ValidateData()
+	if err != nil {
+		t.Fatalf("ValidateData() error: %v", err)
+	}
+	if len(anomalies) != 0 {
+		t.Errorf("anomalies left after repair: %+v", anomalies)
+	}
+
+	got, err := s.GetSoundByID(negative.ID)
+	if err != nil {
+		t.Fatalf("GetSoundByID() error: %v", err)
+	}
+	if got.UsesCount != 0 {
+		t.Errorf("negative uses repaired to %d, want 0", got.UsesCount)
+	}
+
+	got, err = s.GetSoundByID(uncategorized.ID)
+	if err != nil {
+		t.Fatalf("GetSoundByID() error: %v", err)
+	}
+	if got.Category != "gaming" {
+		t.Errorf("category restored to %q, want gaming", got.Category)
+	}
+}
+
+func TestSaveSoundWithHistoryEmptyCategory(t *testing.T) {
+	tests := []struct {
+		name     string
+		category string
+		wantErr  error
+	}{
+		{name: "empty", category: "", wantErr: ErrEmptyCategory},
+		{name: "blank", category: "  ", wantErr: ErrEmptyCategory},
+		{name: "set", category: "fitness", wantErr: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStorage(t)
+			sound := &Sound{Title: "x", URL: "https://www.tiktok.com/music/x-1", UsesCount: 10, Category: tt.category}
+			if err := SaveSoundWithHistory(s, sound, DedupByURL); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SaveSoundWithHistory() error = %v, want %v", err, tt.wantErr)
+			}
+
+			sounds, err := s.GetSoundsByCategory(tt.category, 10)
+			if err != nil {
+				t.Fatalf("GetSoundsByCategory() error: %v", err)
+			}
+			if saved := len(sounds) > 0; saved != (tt.wantErr == nil) {
+				t.Errorf("sound saved = %v, want %v", saved, tt.wantErr == nil)
+			}
+		})
+	}
+}
+
+func TestBackfillEmptyCategories(t *testing.T) {
+	s := newTestStorage(t)
+	known := addSound(t, s, "gaming", "known", 100)
+	if err := s.RecordSoundCategory(known.ID, "fitness"); err != nil {
+		t.Fatalf("RecordSoundCategory() error: %v", err)
+	}
+	if err := s.RecordSoundCategory(known.ID, "gaming"); err != nil {
+		t.Fatalf("RecordSoundCategory() error: %v", err)
+	}
+	unknown := addSound(t, s, "dance", "unknown", 100)
+	if _, err := s.db.Exec(`UPDATE sounds SET category = '' WHERE id IN (?, ?)`, known.ID, unknown.ID); err != nil {
+		t.Fatalf("clear categories: %v", err)
+	}
+
+	updated, err := s.BackfillEmptyCategories()
+	if err != nil {
+		t.Fatalf("BackfillEmptyCategories() error: %v", err)
+	}
+	if updated != 1 {
+		t.Errorf("updated %d sounds, want 1", updated)
+	}
+
+	tests := []struct {
+		id   int64
+		want string
+	}{
+		{known.ID, "gaming"}, // the most recently seen category
+		{unknown.ID, ""},     // nothing to restore it from
+	}
+	for _, tt := range tests {
+		got, err := s.GetSoundByID(tt.id)
+		if err != nil {
+			t.Fatalf("GetSoundByID() error: %v", err)
+		}
+		if got.Category != tt.want {
+			t.Errorf("sound %d category = %q, want %q", tt.id, got.Category, tt.want)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package version
+
+import "fmt"
+
+// Build information, injected at build time:
+//
+//	go build -ldflags "-X github.com/yourusername/trending-sound/internal/version.Version=v1.2.0 \
+//	  -X github.com/yourusername/trending-sound/internal/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X github.com/yourusername/trending-sound/internal/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+// String returns the build information on one line
+func String() string {
+	return Format(Version, Commit, BuildDate)
+}
+
+// Format renders build information, e.g. "v1.2.0 (commit abc1234, built 2024-05-01T10:00:00Z)"
+func Format(version, commit, buildDate string) string {
+	return fmt.Sprintf("%s (commit %s, built %s)", version, commit, buildDate)
+}
@@ -0,0 +1,27 @@
+package version
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		version, commit, buildDate string
+		want                       string
+	}{
+		{"v1.2.0", "abc1234", "2024-05-01T10:00:00Z", "v1.2.0 (commit abc1234, built 2024-05-01T10:00:00Z)"},
+		{"dev", "unknown", "unknown", "dev (commit unknown, built unknown)"},
+	}
+	for _, tt := range tests {
+		if got := Format(tt.version, tt.commit, tt.buildDate); got != tt.want {
+			t.Errorf("Format() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	defer func(v, c, d string) { Version, Commit, BuildDate = v, c, d }(Version, Commit, BuildDate)
+	Version, Commit, BuildDate = "v2.0.0", "def5678", "2024-06-01"
+
+	if got, want := String(), "v2.0.0 (commit def5678, built 2024-06-01)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
@@ -34,3 +34,181 @@ CREATE TABLE IF NOT EXISTS users (
 );
 
 CREATE INDEX IF NOT EXISTS idx_users_telegram_id ON users(telegram_id);
+
+-- Settings table for runtime key/value flags
+CREATE TABLE IF NOT EXISTS settings (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Per-user niche engagement events (previews, clicks)
+CREATE TABLE IF NOT EXISTS niche_engagement (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    telegram_id INTEGER NOT NULL,
+    category TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_niche_engagement_user ON niche_engagement(telegram_id, category);
+
+-- Tracked alert links a user has opened, so repeat opens of the same link count once
+CREATE TABLE IF NOT EXISTS alert_clicks (
+    telegram_id INTEGER NOT NULL,
+    sound_id INTEGER NOT NULL,
+    category TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (telegram_id, sound_id, category)
+);
+
+-- Categories each sound was collected in (a sound can appear in several niches)
+CREATE TABLE IF NOT EXISTS sound_categories (
+    sound_id INTEGER NOT NULL,
+    category TEXT NOT NULL,
+    last_seen_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (sound_id, category),
+    FOREIGN KEY (sound_id) REFERENCES sounds(id) ON DELETE CASCADE
+);
+
+-- Sounds followed by users for milestone notifications
+CREATE TABLE IF NOT EXISTS followed_sounds (
+    telegram_id INTEGER NOT NULL,
+    sound_id INTEGER NOT NULL,
+    last_milestone INTEGER DEFAULT 0, -- highest milestone already notified
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (telegram_id, sound_id),
+    FOREIGN KEY (sound_id) REFERENCES sounds(id) ON DELETE CASCADE
+);
+
+-- Last rendered alert per user and niche, for /last
+CREATE TABLE IF NOT EXISTS last_alerts (
+    telegram_id INTEGER NOT NULL,
+    category TEXT NOT NULL,
+    message TEXT NOT NULL,
+    sent_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (telegram_id, category)
+);
+
+-- Custom niche names chosen by premium users
+CREATE TABLE IF NOT EXISTS user_niche_labels (
+    telegram_id INTEGER NOT NULL,
+    category TEXT NOT NULL,
+    label TEXT NOT NULL,
+    PRIMARY KEY (telegram_id, category)
+);
+
+CREATE TABLE IF NOT EXISTS muted_niches (
+    telegram_id INTEGER NOT NULL,
+    category TEXT NOT NULL,
+    PRIMARY KEY (telegram_id, category)
+);
+
+-- Delay between the start of an alert cycle and delivery, per alert
+CREATE TABLE IF NOT EXISTS alert_latencies (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    telegram_id INTEGER NOT NULL,
+    category TEXT NOT NULL,
+    latency_ms INTEGER NOT NULL,
+    recorded_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_latencies_recorded ON alert_latencies(recorded_at);
+
+-- Precomputed per-sound trend scores, refreshed after each collection
+CREATE TABLE IF NOT EXISTS trend_scores (
+    sound_id INTEGER PRIMARY KEY,
+    category TEXT NOT NULL,
+    growth_percent REAL NOT NULL DEFAULT 0,
+    rank INTEGER NOT NULL DEFAULT 0,
+    trending BOOLEAN NOT NULL DEFAULT 0,
+    computed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (sound_id) REFERENCES sounds(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_trend_scores_category ON trend_scores(category, growth_percent);
+
+-- Sounds submitted by users via /nominate, fetched by the next collection cycle
+CREATE TABLE IF NOT EXISTS nominations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    telegram_id INTEGER NOT NULL,
+    url TEXT UNIQUE NOT NULL,
+    category TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_nominations_status ON nominations(status);
+
+-- Per-user, per-niche settings overriding the user's global ones
+CREATE TABLE IF NOT EXISTS user_niche_settings (
+    telegram_id INTEGER NOT NULL,
+    category TEXT NOT NULL,
+    min_growth REAL NOT NULL DEFAULT 0,
+    PRIMARY KEY (telegram_id, category)
+);
+
+-- Conversation state for multi-step commands, one active conversation per user
+CREATE TABLE IF NOT EXISTS sessions (
+    telegram_id INTEGER PRIMARY KEY,
+    state TEXT NOT NULL,
+    payload TEXT NOT NULL DEFAULT '',
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- When each sound was last flagged as newly trending to anyone, for the global dedup window
+CREATE TABLE IF NOT EXISTS alerted_sounds (
+    sound_id INTEGER PRIMARY KEY,
+    alerted_at DATETIME NOT NULL,
+    FOREIGN KEY (sound_id) REFERENCES sounds(id) ON DELETE CASCADE
+);
+
+-- Runtime switches for experimental features, toggled by admins without redeploying
+CREATE TABLE IF NOT EXISTS feature_flags (
+    name TEXT PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT 0,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Defaults for known flags; existing values are left alone
+INSERT OR IGNORE INTO feature_flags (name, enabled) VALUES ('cards', 1), ('cross_niche', 0);
+
+-- Alerts held back for users on daily or twice-daily digests, sent together at the next delivery
+CREATE TABLE IF NOT EXISTS digest_items (
+    telegram_id INTEGER NOT NULL,
+    niche TEXT NOT NULL,
+    sound_id INTEGER NOT NULL,
+    payload TEXT NOT NULL, -- JSON TrendingSound as last detected
+    queued_at DATETIME NOT NULL,
+    PRIMARY KEY (telegram_id, niche, sound_id)
+);
+
+-- Every sound sent to a user in an alert or digest, for /alerts
+CREATE TABLE IF NOT EXISTS sent_alerts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    telegram_id INTEGER NOT NULL,
+    niche TEXT NOT NULL,
+    sound_id INTEGER NOT NULL,
+    growth_percent REAL NOT NULL DEFAULT 0,
+    sent_at DATETIME NOT NULL,
+    FOREIGN KEY (sound_id) REFERENCES sounds(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sent_alerts_user ON sent_alerts(telegram_id, sent_at);
+
+-- Sounds already posted to the public BROADCAST_CHANNEL feed, so each is posted once
+CREATE TABLE IF NOT EXISTS channel_posts (
+    sound_id INTEGER PRIMARY KEY,
+    posted_at DATETIME NOT NULL,
+    FOREIGN KEY (sound_id) REFERENCES sounds(id) ON DELETE CASCADE
+);
+
+-- Every region whose trending list a sound appeared on, for region-aware detection
+CREATE TABLE IF NOT EXISTS sound_regions (
+    sound_id INTEGER NOT NULL,
+    region TEXT NOT NULL,
+    last_seen_at DATETIME NOT NULL,
+    PRIMARY KEY (sound_id, region),
+    FOREIGN KEY (sound_id) REFERENCES sounds(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sound_regions_region ON sound_regions(region, last_seen_at);